@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// DefaultBulkWorkers is the number of concurrent requests used by -file operations
+const DefaultBulkWorkers = 4
+
+// BulkConfig holds the configuration for bulk subscribe and unsubscribe
+type BulkConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ChannelIDs    []string
+	Timeout       time.Duration
+	Workers       int       // Concurrent requests (default: DefaultBulkWorkers)
+	Output        io.Writer // Defaults to os.Stdout
+}
+
+// BulkResult is the outcome of one channel in a bulk operation
+type BulkResult struct {
+	ChannelID string
+	Outcome   string // e.g. "subscribed", "already subscribed", "failed"
+	Err       error
+}
+
+// ReadChannelFile reads channel IDs from a file containing either a JSON array of
+// strings or one ID per line. Blank lines and lines starting with # are ignored,
+// and duplicates are dropped. For subscribing, a line can also be an @handle or
+// channel URL.
+func ReadChannelFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading channel file: %w", err)
+	}
+
+	var raw []string
+	content := strings.TrimSpace(string(data))
+	if strings.HasPrefix(content, "[") {
+		if err := json.Unmarshal([]byte(content), &raw); err != nil {
+			return nil, fmt.Errorf("parsing channel file as JSON array: %w", err)
+		}
+	} else {
+		raw = strings.Split(content, "\n")
+	}
+
+	seen := make(map[string]bool)
+	var channelIDs []string
+	for _, line := range raw {
+		id := strings.TrimSpace(line)
+		if id == "" || strings.HasPrefix(id, "#") || seen[id] {
+			continue
+		}
+		seen[id] = true
+		channelIDs = append(channelIDs, id)
+	}
+
+	if len(channelIDs) == 0 {
+		return nil, fmt.Errorf("no channel IDs found in %s", path)
+	}
+	return channelIDs, nil
+}
+
+// BulkSubscribe subscribes to every channel in the config concurrently
+func BulkSubscribe(config BulkConfig) error {
+	return runBulk(config, "subscribe", func(c *client.Client, channelID string) (string, error) {
+		subscribe := c.Subscribe
+		if isChannelHandle(channelID) {
+			subscribe = c.SubscribeByHandle
+		}
+		resp, err := subscribe(channelID)
+		if err != nil {
+			if client.IsAlreadySubscribed(resp) {
+				return "already subscribed", nil
+			}
+			return "failed", err
+		}
+		if resp.ChannelID != "" && resp.ChannelID != channelID {
+			return "subscribed as " + channelLabel(resp, channelID), nil
+		}
+		return "subscribed", nil
+	})
+}
+
+// isChannelHandle reports whether a channel file entry is an @handle or channel URL
+// rather than a channel ID
+func isChannelHandle(entry string) bool {
+	return strings.HasPrefix(entry, "@") || strings.Contains(entry, "youtube.com/")
+}
+
+// BulkUnsubscribe unsubscribes from every channel in the config concurrently
+func BulkUnsubscribe(config BulkConfig) error {
+	return runBulk(config, "unsubscribe", func(c *client.Client, channelID string) (string, error) {
+		err := c.Unsubscribe(channelID)
+		if err != nil {
+			if err.Error() == fmt.Sprintf("not subscribed to channel %s", channelID) {
+				return "not subscribed", nil
+			}
+			return "failed", err
+		}
+		return "unsubscribed", nil
+	})
+}
+
+// runBulk applies op to every channel with a worker pool, prints a per-channel
+// summary in input order and returns an error if any channel failed
+func runBulk(config BulkConfig, action string, op func(c *client.Client, channelID string) (string, error)) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = DefaultBulkWorkers
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+	results := make([]BulkResult, len(config.ChannelIDs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				channelID := config.ChannelIDs[i]
+				outcome, err := op(c, channelID)
+				results[i] = BulkResult{ChannelID: channelID, Outcome: outcome, Err: err}
+			}
+		}()
+	}
+	for i := range config.ChannelIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	fmt.Fprintf(out, "📋 Bulk %s: %d channels\n\n", action, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(out, "❌ %s  %s: %v\n", result.ChannelID, result.Outcome, result.Err)
+			continue
+		}
+		fmt.Fprintf(out, "✅ %s  %s\n", result.ChannelID, result.Outcome)
+	}
+	fmt.Fprintf(out, "\n   Succeeded: %d | Failed: %d\n", len(results)-failed, failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d channels failed to %s", failed, len(results), action)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+func writeChannelFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "channels")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write channel file: %v", err)
+	}
+	return path
+}
+
+func TestReadChannelFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "one_per_line",
+			content:  "UC1\n\n  UC2  \n# comment\nUC1\r\nUC3\n",
+			expected: []string{"UC1", "UC2", "UC3"},
+		},
+		{
+			name:     "json_array",
+			content:  ` ["UC1", "UC2", "UC1"]`,
+			expected: []string{"UC1", "UC2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			channelIDs, err := ReadChannelFile(writeChannelFile(t, tc.content))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(channelIDs, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, channelIDs)
+			}
+		})
+	}
+}
+
+func TestReadChannelFile_Errors(t *testing.T) {
+	if _, err := ReadChannelFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := ReadChannelFile(writeChannelFile(t, `["UC1", 2]`)); err == nil {
+		t.Error("Expected error for invalid JSON array")
+	}
+	if _, err := ReadChannelFile(writeChannelFile(t, "# nothing here\n\n")); err == nil {
+		t.Error("Expected error for file without channel IDs")
+	}
+}
+
+func TestBulkSubscribe(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			prev := atomic.LoadInt32(&maxInFlight)
+			if current <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("handle") == "@SomeCreator" {
+			json.NewEncoder(w).Encode(webhook.APIResponse{Status: "success", ChannelID: "UChandle", ChannelName: "Some Creator"})
+			return
+		}
+		switch r.URL.Query().Get("channel_id") {
+		case "UCconflict":
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(webhook.APIResponse{Status: "conflict", Message: "Already subscribed"})
+		case "UCbroken":
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "Hub unavailable"})
+		default:
+			json.NewEncoder(w).Encode(webhook.APIResponse{Status: "success"})
+		}
+	}))
+	defer server.Close()
+
+	t.Run("all_succeed", func(t *testing.T) {
+		var out bytes.Buffer
+		err := BulkSubscribe(BulkConfig{
+			BaseURL:    server.URL,
+			ChannelIDs: []string{"UC1", "UC2", "UCconflict", "UC3"},
+			Timeout:    5 * time.Second,
+			Workers:    2,
+			Output:     &out,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		output := out.String()
+		for _, expected := range []string{"✅ UC1  subscribed", "✅ UCconflict  already subscribed", "Succeeded: 4 | Failed: 0"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+			}
+		}
+		if strings.Index(output, "UC1") > strings.Index(output, "UC3") {
+			t.Errorf("Expected results in input order, got:\n%s", output)
+		}
+		if max := atomic.LoadInt32(&maxInFlight); max > 2 {
+			t.Errorf("Expected at most 2 concurrent requests, got %d", max)
+		}
+	})
+
+	t.Run("handles", func(t *testing.T) {
+		var out bytes.Buffer
+		err := BulkSubscribe(BulkConfig{
+			BaseURL:    server.URL,
+			ChannelIDs: []string{"UC1", "@SomeCreator"},
+			Timeout:    5 * time.Second,
+			Output:     &out,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "✅ @SomeCreator  subscribed as UChandle (Some Creator)") {
+			t.Errorf("Expected the handle's channel, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("partial_failure", func(t *testing.T) {
+		var out bytes.Buffer
+		err := BulkSubscribe(BulkConfig{
+			BaseURL:    server.URL,
+			ChannelIDs: []string{"UC1", "UCbroken"},
+			Timeout:    5 * time.Second,
+			Output:     &out,
+		})
+		if err == nil || !strings.Contains(err.Error(), "1 of 2 channels failed to subscribe") {
+			t.Fatalf("Expected partial failure error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "❌ UCbroken  failed: server error (500): Hub unavailable") {
+			t.Errorf("Expected failure line, got:\n%s", out.String())
+		}
+	})
+}
+
+func TestBulkUnsubscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			t.Errorf("Expected DELETE method, got %s", r.Method)
+		}
+		switch r.URL.Query().Get("channel_id") {
+		case "UCmissing":
+			w.WriteHeader(http.StatusNotFound)
+		case "UCbroken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := BulkUnsubscribe(BulkConfig{
+		BaseURL:    server.URL,
+		ChannelIDs: []string{"UC1", "UCmissing", "UCbroken"},
+		Timeout:    5 * time.Second,
+		Output:     &out,
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 channels failed to unsubscribe") {
+		t.Fatalf("Expected partial failure error, got %v", err)
+	}
+
+	output := out.String()
+	for _, expected := range []string{"✅ UC1  unsubscribed", "✅ UCmissing  not subscribed", "❌ UCbroken  failed", "Succeeded: 2 | Failed: 1"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
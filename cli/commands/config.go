@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// ConfigCommandConfig holds the configuration for the config command
+type ConfigCommandConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ChannelID     string
+	Timeout       time.Duration
+	Settings      []string  // key=value changes (lease_seconds, filter, sinks); none shows the configuration
+	Format        string    // "table" (default) or "json"
+	Output        io.Writer // Defaults to os.Stdout
+}
+
+// Config shows or changes a channel's lease, notification filter and webhook sinks
+func Config(config ConfigCommandConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "table"
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (available: table, json)", config.Format)
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	var resp *webhook.ChannelConfigResponse
+	var patch webhook.ChannelConfigPatch
+	if len(config.Settings) == 0 {
+		var err error
+		if resp, err = c.GetChannelConfig(config.ChannelID); err != nil {
+			return fmt.Errorf("failed to get channel configuration: %w", err)
+		}
+	} else {
+		var err error
+		if patch, err = parseConfigSettings(config.Settings); err != nil {
+			return err
+		}
+		if resp, err = c.UpdateChannelConfig(config.ChannelID, patch); err != nil {
+			return fmt.Errorf("failed to update channel configuration: %w", err)
+		}
+	}
+
+	if format == "json" {
+		return writeJSON(out, resp)
+	}
+
+	if len(config.Settings) > 0 {
+		fmt.Fprintf(out, "✅ Updated configuration for channel %s\n", config.ChannelID)
+		if patch.LeaseSeconds != nil {
+			fmt.Fprintf(out, "   The new lease is requested at the next renewal; run renew -channel %s -force to apply it now\n", config.ChannelID)
+		}
+	}
+	printChannelConfig(out, resp)
+	return nil
+}
+
+// parseConfigSettings turns key=value settings into a configuration patch. lease_seconds
+// takes seconds, a duration such as 120h, or "default"; filter takes a JSON filter and
+// sinks a JSON list of sinks, either of which can be "none" to remove them.
+func parseConfigSettings(settings []string) (webhook.ChannelConfigPatch, error) {
+	var patch webhook.ChannelConfigPatch
+	seen := make(map[string]bool)
+	for _, setting := range settings {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return patch, fmt.Errorf("invalid setting %q: expected key=value", setting)
+		}
+		if seen[key] {
+			return patch, fmt.Errorf("%s is set more than once", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "lease_seconds":
+			lease, err := parseLeaseSeconds(value)
+			if err != nil {
+				return patch, err
+			}
+			patch.LeaseSeconds = &lease
+		case "filter":
+			filter := webhook.NotificationFilter{}
+			if value != "none" {
+				if err := json.Unmarshal([]byte(value), &filter); err != nil {
+					return patch, fmt.Errorf("filter must be a JSON object or none: %v", err)
+				}
+			}
+			patch.Filter = &filter
+		case "sinks":
+			sinks := []webhook.WebhookSink{}
+			if value != "none" {
+				if err := json.Unmarshal([]byte(value), &sinks); err != nil {
+					return patch, fmt.Errorf("sinks must be a JSON list or none: %v", err)
+				}
+			}
+			patch.Sinks = &sinks
+		default:
+			return patch, fmt.Errorf("unknown setting %q (available: lease_seconds, filter, sinks)", key)
+		}
+	}
+	return patch, nil
+}
+
+// parseLeaseSeconds reads a lease given in seconds or as a duration; "default" is 0
+func parseLeaseSeconds(value string) (int, error) {
+	if value == "default" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return seconds, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration%time.Second != 0 {
+		return 0, fmt.Errorf("lease_seconds must be seconds, a duration such as 120h, or default")
+	}
+	return int(duration / time.Second), nil
+}
+
+// printChannelConfig prints the lease, filter and sinks, one line each
+func printChannelConfig(out io.Writer, resp *webhook.ChannelConfigResponse) {
+	fmt.Fprintf(out, "⚙️  Configuration for channel %s\n", resp.ChannelID)
+
+	lease := "default (SUBSCRIPTION_LEASE_SECONDS)"
+	if resp.LeaseSeconds > 0 {
+		lease = fmt.Sprintf("%d seconds (%s)", resp.LeaseSeconds, time.Duration(resp.LeaseSeconds)*time.Second)
+	}
+	if resp.GrantedLeaseSeconds > 0 {
+		lease += fmt.Sprintf("; hub granted %d seconds", resp.GrantedLeaseSeconds)
+	}
+	fmt.Fprintf(out, "   Lease:   %s\n", lease)
+
+	if resp.Filter.IsEmpty() {
+		fmt.Fprintln(out, "   Filter:  none; every new video is dispatched")
+	} else {
+		filter, _ := json.Marshal(resp.Filter)
+		fmt.Fprintf(out, "   Filter:  %s\n", filter)
+	}
+
+	if len(resp.Sinks) == 0 {
+		fmt.Fprintln(out, "   Sinks:   none")
+	}
+	for i, sink := range resp.Sinks {
+		label := "            "
+		if i == 0 {
+			label = "   Sinks:  "
+		}
+		sinkType := sink.Type
+		if sinkType == "" {
+			sinkType = webhook.SinkTypeWebhook
+		}
+		signed := ""
+		if sink.Signed {
+			signed = " (signed)"
+		}
+		fmt.Fprintf(out, "%s %s %s%s\n", label, sinkType, sink.URL, signed)
+	}
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the CLI's config file, looked for in the home directory
+const ConfigFileName = ".youtube-webhook.yaml"
+
+// FileConfig holds the defaults read from the CLI's config file. The environment
+// variables override them, and flags override both.
+type FileConfig struct {
+	URL           string        `yaml:"url,omitempty"`            // Base URL of the webhook service
+	APIKey        string        `yaml:"api_key,omitempty"`        // API key or ID token for the management API
+	AdminToken    string        `yaml:"admin_token,omitempty"`    // The service's ADMIN_TOKEN, for export, import and restore-backup
+	SigningSecret string        `yaml:"signing_secret,omitempty"` // The service's REQUEST_SIGNING_SECRET
+	Format        string        `yaml:"format,omitempty"`         // Output format for commands that have one
+	Timeout       time.Duration `yaml:"timeout,omitempty"`        // Request timeout, such as 45s
+}
+
+// ConfigInitConfig holds the configuration for the config init command
+type ConfigInitConfig struct {
+	Path   string    // Config file to write
+	URL    string    // Base URL to fill in; empty leaves a placeholder
+	Force  bool      // Overwrite an existing file
+	Output io.Writer // Defaults to os.Stdout
+}
+
+// DefaultConfigPath returns ~/.youtube-webhook.yaml
+func DefaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the home directory: %w", err)
+	}
+	return filepath.Join(home, ConfigFileName), nil
+}
+
+// LoadFileConfig reads the config file at path. Unknown settings are errors, so a
+// misspelt key is not silently ignored. A missing file's error matches os.ErrNotExist.
+func LoadFileConfig(path string) (FileConfig, error) {
+	var config FileConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("reading config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return FileConfig{}, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	if config.Timeout < 0 {
+		return FileConfig{}, fmt.Errorf("parsing config file %s: timeout must not be negative", path)
+	}
+	return config, nil
+}
+
+// starterConfig is the file config init writes; %s is the base URL
+const starterConfig = `# youtube-webhook CLI configuration
+#
+# These are defaults: the YOUTUBE_WEBHOOK_* environment variables override them,
+# and command-line flags override both.
+
+# Base URL of the webhook service
+url: %s
+
+# API key or ID token for the management API
+# api_key: your-api-key
+
+# The service's ADMIN_TOKEN, used by export, import and restore-backup
+# admin_token: your-admin-token
+
+# The service's REQUEST_SIGNING_SECRET; signs requests instead of sending an API key
+# signing_secret: your-signing-secret
+
+# Output format for commands that have one, such as json
+# format: json
+
+# Request timeout
+# timeout: 30s
+`
+
+// ConfigInit writes a starter config file, with owner-only permissions since it may
+// come to hold credentials
+func ConfigInit(config ConfigInitConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	url := config.URL
+	if url == "" {
+		url = "https://your-function.run.app"
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if config.Force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(config.Path, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists; use -force to overwrite it", config.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(file, starterConfig, url); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Fprintf(out, "✅ Wrote %s\n", config.Path)
+	return nil
+}
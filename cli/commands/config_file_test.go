@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("settings", func(t *testing.T) {
+		path := write("full.yaml", "url: https://example.run.app\napi_key: key\nadmin_token: admin\nsigning_secret: secret\nformat: json\ntimeout: 45s\n")
+		config, err := LoadFileConfig(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := FileConfig{URL: "https://example.run.app", APIKey: "key", AdminToken: "admin", SigningSecret: "secret", Format: "json", Timeout: 45 * time.Second}
+		if config != expected {
+			t.Errorf("Expected %+v, got %+v", expected, config)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		config, err := LoadFileConfig(write("empty.yaml", "# nothing set\n"))
+		if err != nil || config != (FileConfig{}) {
+			t.Errorf("Expected an empty config, got %+v, %v", config, err)
+		}
+	})
+
+	t.Run("unknown_setting", func(t *testing.T) {
+		_, err := LoadFileConfig(write("unknown.yaml", "ur1: https://example.run.app\n"))
+		if err == nil || !strings.Contains(err.Error(), "ur1") {
+			t.Errorf("Expected the misspelt setting to be reported, got %v", err)
+		}
+	})
+
+	t.Run("negative_timeout", func(t *testing.T) {
+		if _, err := LoadFileConfig(write("timeout.yaml", "timeout: -1s\n")); err == nil {
+			t.Error("Expected a negative timeout to be rejected")
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := LoadFileConfig(filepath.Join(dir, "missing.yaml"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected os.ErrNotExist, got %v", err)
+		}
+	})
+}
+
+func TestConfigInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+
+	var out bytes.Buffer
+	if err := ConfigInit(ConfigInitConfig{Path: path, URL: "https://example.run.app", Output: &out}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), path) {
+		t.Errorf("Expected the written path to be printed, got %q", out.String())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600, got %v", info.Mode().Perm())
+	}
+
+	// The starter file loads, with only the URL set
+	config, err := LoadFileConfig(path)
+	if err != nil {
+		t.Fatalf("Expected the starter file to load, got %v", err)
+	}
+	if config != (FileConfig{URL: "https://example.run.app"}) {
+		t.Errorf("Expected only the URL to be set, got %+v", config)
+	}
+
+	err = ConfigInit(ConfigInitConfig{Path: path, Output: &out})
+	if err == nil || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("Expected an existing file to be kept without -force, got %v", err)
+	}
+
+	if err := ConfigInit(ConfigInitConfig{Path: path, Force: true, Output: &out}); err != nil {
+		t.Fatalf("Expected -force to overwrite, got %v", err)
+	}
+	if config, _ := LoadFileConfig(path); config.URL != "https://your-function.run.app" {
+		t.Errorf("Expected the placeholder URL, got %q", config.URL)
+	}
+}
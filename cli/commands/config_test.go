@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+func newConfigServer(t *testing.T, patches *[]webhook.ChannelConfigPatch) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/config" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp := webhook.ChannelConfigResponse{
+			Status:              "success",
+			ChannelID:           "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			GrantedLeaseSeconds: 86400,
+			Filter:              &webhook.NotificationFilter{ExcludeShorts: true},
+			Sinks:               []webhook.WebhookSinkInfo{{URL: "https://example.com/hook", Signed: true}},
+		}
+		if r.Method == "PATCH" {
+			var patch webhook.ChannelConfigPatch
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				t.Errorf("Expected JSON config body, got error %v", err)
+			}
+			*patches = append(*patches, patch)
+			if patch.LeaseSeconds != nil {
+				resp.LeaseSeconds = *patch.LeaseSeconds
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestConfig_Get(t *testing.T) {
+	var patches []webhook.ChannelConfigPatch
+	server := newConfigServer(t, &patches)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Config(ConfigCommandConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Timeout:   30 * time.Second,
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(patches) != 0 {
+		t.Errorf("Expected get not to patch, got %d patches", len(patches))
+	}
+
+	for _, want := range []string{"Lease:   default (SUBSCRIPTION_LEASE_SECONDS); hub granted 86400 seconds",
+		`Filter:  {"exclude_shorts":true}`, "Sinks:   webhook https://example.com/hook (signed)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestConfig_Set(t *testing.T) {
+	var patches []webhook.ChannelConfigPatch
+	server := newConfigServer(t, &patches)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Config(ConfigCommandConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Timeout:   30 * time.Second,
+		Settings:  []string{"lease_seconds=120h", "filter=none"},
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(patches) != 1 {
+		t.Fatalf("Expected one patch, got %d", len(patches))
+	}
+	patch := patches[0]
+	if patch.LeaseSeconds == nil || *patch.LeaseSeconds != 432000 {
+		t.Errorf("Expected lease of 432000 seconds, got %v", patch.LeaseSeconds)
+	}
+	if patch.Filter == nil || !patch.Filter.IsEmpty() {
+		t.Errorf("Expected an empty filter to clear it, got %+v", patch.Filter)
+	}
+	if patch.Sinks != nil {
+		t.Errorf("Expected sinks to be left alone, got %+v", patch.Sinks)
+	}
+	for _, want := range []string{"Updated configuration", "renew -channel UCXuqSBlHAE6Xw-yeJA0Tunw -force", "Lease:   432000 seconds (120h0m0s)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestConfig_JSON(t *testing.T) {
+	var patches []webhook.ChannelConfigPatch
+	server := newConfigServer(t, &patches)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Config(ConfigCommandConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Timeout:   30 * time.Second,
+		Format:    "json",
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var resp webhook.ChannelConfigResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil || resp.GrantedLeaseSeconds != 86400 {
+		t.Errorf("Expected JSON configuration, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestParseConfigSettings(t *testing.T) {
+	patch, err := parseConfigSettings([]string{"lease_seconds=default", `sinks=[{"url":"https://example.com/hook"}]`, `filter={"exclude_livestreams":true}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if patch.LeaseSeconds == nil || *patch.LeaseSeconds != 0 {
+		t.Errorf("Expected default lease, got %v", patch.LeaseSeconds)
+	}
+	if patch.Sinks == nil || len(*patch.Sinks) != 1 {
+		t.Errorf("Expected one sink, got %v", patch.Sinks)
+	}
+	if patch.Filter == nil || !patch.Filter.ExcludeLivestreams {
+		t.Errorf("Expected livestream filter, got %+v", patch.Filter)
+	}
+
+	tests := map[string][]string{
+		"missing value":  {"lease_seconds"},
+		"unknown key":    {"color=blue"},
+		"duplicate key":  {"filter=none", "filter=none"},
+		"bad lease":      {"lease_seconds=soon"},
+		"partial second": {"lease_seconds=1500ms"},
+		"bad filter":     {"filter=[1]"},
+		"bad sinks":      {"sinks={}"},
+	}
+	for name, settings := range tests {
+		if _, err := parseConfigSettings(settings); err == nil {
+			t.Errorf("%s: expected an error for %q", name, settings)
+		}
+	}
+}
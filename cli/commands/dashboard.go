@@ -0,0 +1,356 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// DefaultDashboardInterval is how often the dashboard fetches subscriptions by default
+const DefaultDashboardInterval = 10 * time.Second
+
+// dashboardHistorySize is how many of the highlighted channel's notifications are shown
+const dashboardHistorySize = 5
+
+// Terminal control sequences used by the dashboard
+const (
+	clearScreen  = "\033[H\033[2J"
+	hideCursor   = "\033[?25l"
+	showCursor   = "\033[?25h"
+	reverseVideo = "\033[7m"
+	resetStyle   = "\033[0m"
+)
+
+// Keys read by the dashboard that are not single printable characters
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyCtrlC = "ctrl-c"
+)
+
+// DashboardConfig holds the configuration for the dashboard command
+type DashboardConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	Timeout       time.Duration
+	Interval      time.Duration // How often subscriptions are fetched; defaults to DefaultDashboardInterval
+	Input         io.Reader     // Key presses; defaults to os.Stdin, read without echo when it is a terminal
+	Output        io.Writer     // Defaults to os.Stdout
+}
+
+// dashboard is the state of a running dashboard
+type dashboard struct {
+	client  *client.Client
+	baseURL string
+
+	list      *webhook.SubscriptionsListResponse
+	selected  int
+	history   *webhook.HistoryResponse
+	fetchedAt time.Time
+
+	status     string // Outcome of the last action
+	confirming string // Channel waiting for the unsubscribe to be confirmed
+}
+
+// Dashboard shows a live view of every subscription, refreshed every interval, with
+// expirations counting down and the highlighted channel's recent notifications. Keys
+// move the highlight and renew or unsubscribe the highlighted channel.
+func Dashboard(config DashboardConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultDashboardInterval
+	}
+	input := config.Input
+	if input == nil {
+		input = os.Stdin
+		if restore, err := unbufferedTerminal(); err == nil {
+			defer restore()
+		}
+	}
+
+	d := &dashboard{
+		client:  client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret),
+		baseURL: config.BaseURL,
+	}
+	if err := d.refresh(); err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	fmt.Fprint(out, hideCursor)
+	defer fmt.Fprint(out, showCursor)
+
+	done := make(chan struct{})
+	defer close(done)
+	keys := make(chan string)
+	go readKeys(input, keys, done)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	poll := time.NewTicker(interval)
+	defer poll.Stop()
+	clock := time.NewTicker(time.Second)
+	defer clock.Stop()
+
+	for {
+		d.render(out, time.Now())
+		select {
+		case key, ok := <-keys:
+			if !ok || d.handleKey(key) {
+				return nil
+			}
+		case <-interrupts:
+			return nil
+		case <-poll.C:
+			if err := d.refresh(); err != nil {
+				d.status = fmt.Sprintf("⚠️  Refresh failed: %v", err)
+			}
+		case <-clock.C:
+			// Redraw so the countdowns keep moving between fetches
+		}
+	}
+}
+
+// refresh fetches every subscription, soonest expiry first, keeping the highlighted
+// channel highlighted, then the highlighted channel's history
+func (d *dashboard) refresh() error {
+	list, err := fetchSubscriptions(d.client, ListConfig{Sort: "expires"})
+	if err != nil {
+		return err
+	}
+
+	current := d.selectedChannel()
+	d.list, d.selected, d.fetchedAt = list, 0, time.Now()
+	for i, sub := range list.Subscriptions {
+		if sub.ChannelID == current {
+			d.selected = i
+		}
+	}
+	d.loadHistory()
+	return nil
+}
+
+// loadHistory fetches the highlighted channel's recent notifications
+func (d *dashboard) loadHistory() {
+	d.history = nil
+	if channelID := d.selectedChannel(); channelID != "" {
+		// A missing history only leaves the panel empty
+		d.history, _ = d.client.GetHistory(channelID, dashboardHistorySize)
+	}
+}
+
+// selectedChannel returns the highlighted channel, or "" when there are no subscriptions
+func (d *dashboard) selectedChannel() string {
+	if d.list == nil || d.selected >= len(d.list.Subscriptions) {
+		return ""
+	}
+	return d.list.Subscriptions[d.selected].ChannelID
+}
+
+// handleKey acts on a key press, returning true when the dashboard should exit
+func (d *dashboard) handleKey(key string) bool {
+	if d.confirming != "" {
+		channelID := d.confirming
+		d.confirming = ""
+		if key != "y" && key != "Y" {
+			d.status = "Unsubscribe cancelled."
+			return false
+		}
+		if err := d.client.Unsubscribe(channelID); err != nil {
+			d.status = fmt.Sprintf("❌ Unsubscribe of %s failed: %v", channelID, err)
+			return false
+		}
+		d.status = fmt.Sprintf("✅ Unsubscribed from %s", channelID)
+		d.refreshAfterAction()
+		return false
+	}
+
+	switch key {
+	case "q", keyCtrlC:
+		return true
+	case keyUp, "k":
+		if d.selected > 0 {
+			d.selected--
+			d.loadHistory()
+		}
+	case keyDown, "j":
+		if d.list != nil && d.selected < len(d.list.Subscriptions)-1 {
+			d.selected++
+			d.loadHistory()
+		}
+	case "r":
+		if channelID := d.selectedChannel(); channelID != "" {
+			d.renew(channelID)
+		}
+	case "u":
+		if channelID := d.selectedChannel(); channelID != "" {
+			d.confirming = channelID
+			d.status = fmt.Sprintf("Unsubscribe from %s? [y/N]", channelID)
+		}
+	case "R":
+		d.status = ""
+		d.refreshAfterAction()
+	}
+	return false
+}
+
+// renew renews one channel, whatever its expiry, and reports the outcome
+func (d *dashboard) renew(channelID string) {
+	resp, err := d.client.RenewChannels(webhook.RenewRequest{ChannelIDs: []string{channelID}, Force: true})
+	switch {
+	case err != nil:
+		d.status = fmt.Sprintf("❌ Renewal of %s failed: %v", channelID, err)
+	case len(resp.Results) == 0:
+		d.status = fmt.Sprintf("%s was not renewed (pending or quarantined)", channelID)
+	case !resp.Results[0].Success:
+		d.status = fmt.Sprintf("❌ Renewal of %s failed: %s", channelID, resp.Results[0].Message)
+	default:
+		d.status = fmt.Sprintf("✅ Renewal of %s requested; pending hub verification", channelID)
+	}
+	d.refreshAfterAction()
+}
+
+// refreshAfterAction refetches subscriptions so an action's effect shows straight away
+func (d *dashboard) refreshAfterAction() {
+	if err := d.refresh(); err != nil {
+		d.status += fmt.Sprintf(" (refresh failed: %v)", err)
+	}
+}
+
+// render draws the whole dashboard as of now
+func (d *dashboard) render(w io.Writer, now time.Time) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "📺 YouTube Webhook Dashboard  %s  (updated %s)\n\n", d.baseURL, d.fetchedAt.Local().Format("15:04:05"))
+
+	list := d.list
+	fmt.Fprintf(&b, "   Total: %d | Active: %d | Pending: %d | Expired: %d | Quarantined: %d | Healthy: %d | Stale: %d | Broken: %d\n\n",
+		list.Total, list.Active, list.Pending, list.Expired, list.Quarantined, list.Healthy, list.Stale, list.Broken)
+
+	row := "%s%-24s  %-12s  %-14s  %-14s  %-8s  %s"
+	fmt.Fprintf(&b, row+"\n", "  ", "CHANNEL ID", "STATUS", "EXPIRES IN", "RENEWAL IN", "HEALTH", "LAST NOTIFICATION")
+	if len(list.Subscriptions) == 0 {
+		b.WriteString("  No subscriptions found.\n")
+	}
+	for i, sub := range list.Subscriptions {
+		marker := "  "
+		if i == d.selected {
+			marker = "▶ "
+		}
+		line := fmt.Sprintf(row, marker, sub.ChannelID, sub.Status, countdown(sub.ExpiresAt, now, "expired"),
+			countdown(sub.NextRenewalAt, now, "due"), valueOrDash(sub.Indicator), valueOrDash(sub.LastNotificationAt))
+		if i == d.selected {
+			line = reverseVideo + line + resetStyle
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if channelID := d.selectedChannel(); channelID != "" {
+		fmt.Fprintf(&b, "\nRecent notifications for %s:\n", channelID)
+		if d.history == nil || len(d.history.Entries) == 0 {
+			b.WriteString("  None recorded.\n")
+		} else {
+			for _, entry := range d.history.Entries {
+				fmt.Fprintf(&b, "  %s  %-11s  %-40s  %s\n", entry.ReceivedAt.Local().Format("2006-01-02 15:04:05"),
+					entry.VideoID, truncate(entry.Title, 40), entry.Decision)
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%s\n", d.status)
+	b.WriteString("↑/k ↓/j select · r renew · u unsubscribe · R refresh · q quit\n")
+	io.WriteString(w, b.String())
+}
+
+// countdown renders the time left until an RFC 3339 timestamp, or past once it has passed
+func countdown(value string, now time.Time, past string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "-"
+	}
+	left := t.Sub(now).Truncate(time.Second)
+	if left <= 0 {
+		return past
+	}
+	if days := int(left / (24 * time.Hour)); days > 0 {
+		return fmt.Sprintf("%dd %s", days, left%(24*time.Hour))
+	}
+	return left.String()
+}
+
+// readKeys sends the keys read from input until it is exhausted or done is closed
+func readKeys(input io.Reader, keys chan<- string, done <-chan struct{}) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := input.Read(buf)
+		for _, key := range parseKeys(buf[:n]) {
+			select {
+			case keys <- key:
+			case <-done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseKeys splits raw terminal input into key names: arrow keys, Ctrl-C and printable
+// characters. Newlines are dropped so line-buffered input works too.
+func parseKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == 0x1b && i+2 < len(input) && input[i+1] == '[':
+			switch input[i+2] {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			}
+			i += 2
+		case c == 0x03:
+			keys = append(keys, keyCtrlC)
+		case c > ' ' && c < 0x7f:
+			keys = append(keys, string(rune(c)))
+		}
+	}
+	return keys
+}
+
+// unbufferedTerminal makes the terminal on stdin pass on each key press without echoing
+// it, returning a function that restores the previous settings. It fails when stdin is
+// not a terminal or stty is unavailable, leaving keys to be followed by Enter.
+func unbufferedTerminal() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+// stty runs stty against the terminal on stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
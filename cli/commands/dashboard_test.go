@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// newDashboardServer serves two subscriptions and one notification each, recording the
+// renewals and unsubscribes it receives
+func newDashboardServer(t *testing.T) (*httptest.Server, *[]webhook.RenewRequest, *[]string) {
+	var mu sync.Mutex
+	var renewals []webhook.RenewRequest
+	var unsubscribed []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/subscriptions":
+			json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+				Subscriptions: []webhook.SubscriptionInfo{
+					{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "active", ExpiresAt: time.Now().Add(2 * time.Hour).Format(time.RFC3339)},
+					{ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: "active", ExpiresAt: time.Now().Add(72 * time.Hour).Format(time.RFC3339)},
+				},
+				Total:  2,
+				Active: 2,
+			})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/history"):
+			channelID := strings.Split(r.URL.Path, "/")[2]
+			json.NewEncoder(w).Encode(webhook.HistoryResponse{
+				Status:    "success",
+				ChannelID: channelID,
+				Count:     1,
+				Entries:   []webhook.HistoryEntry{{ReceivedAt: time.Now(), VideoID: "video-" + channelID[:4], Title: "Latest Video", Decision: webhook.HistoryDecisionDispatched}},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/renew":
+			var req webhook.RenewRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			renewals = append(renewals, req)
+			json.NewEncoder(w).Encode(webhook.RenewalSummaryResponse{
+				Status:  "success",
+				Results: []webhook.RenewalResult{{ChannelID: req.ChannelIDs[0], Success: true}},
+			})
+		case r.Method == http.MethodDelete && r.URL.Path == "/unsubscribe":
+			unsubscribed = append(unsubscribed, r.URL.Query().Get("channel_id"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, &renewals, &unsubscribed
+}
+
+func TestDashboard_Keys(t *testing.T) {
+	server, renewals, unsubscribed := newDashboardServer(t)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Dashboard(DashboardConfig{
+		BaseURL: server.URL,
+		Timeout: 5 * time.Second,
+		Input:   strings.NewReader("jr" + "un" + "uy" + "q"),
+		Output:  &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := []webhook.RenewRequest{{ChannelIDs: []string{"UC_x5XG1OV2P6uZZ5FSM9Ttw"}, Force: true}}
+	if !reflect.DeepEqual(*renewals, want) {
+		t.Errorf("Expected forced renewal of the highlighted channel, got %+v", *renewals)
+	}
+	if !reflect.DeepEqual(*unsubscribed, []string{"UC_x5XG1OV2P6uZZ5FSM9Ttw"}) {
+		t.Errorf("Expected one confirmed unsubscribe, got %v", *unsubscribed)
+	}
+
+	output := out.String()
+	for _, expected := range []string{
+		"Recent notifications for UC_x5XG1OV2P6uZZ5FSM9Ttw",
+		"video-UC_x",
+		"Renewal of UC_x5XG1OV2P6uZZ5FSM9Ttw requested",
+		"Unsubscribe cancelled.",
+		"Unsubscribed from UC_x5XG1OV2P6uZZ5FSM9Ttw",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q", expected)
+		}
+	}
+	if !strings.HasSuffix(output, showCursor) {
+		t.Errorf("Expected the cursor to be restored on exit")
+	}
+}
+
+func TestDashboard_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "Unauthorized"})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Dashboard(DashboardConfig{BaseURL: server.URL, Timeout: 5 * time.Second, Input: strings.NewReader("q"), Output: &out})
+	if err == nil || !strings.Contains(err.Error(), "failed to list subscriptions") {
+		t.Errorf("Expected list error, got %v", err)
+	}
+}
+
+func TestDashboard_Render(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := &dashboard{
+		baseURL: "https://example.com",
+		list: &webhook.SubscriptionsListResponse{
+			Subscriptions: []webhook.SubscriptionInfo{
+				{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "expired", ExpiresAt: "2026-01-01T11:00:00Z"},
+				{ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: "active", ExpiresAt: "2026-01-03T13:30:15Z", NextRenewalAt: "2026-01-02T13:30:15Z"},
+			},
+			Total: 2, Active: 1, Expired: 1,
+		},
+		selected: 1,
+		status:   "✅ done",
+	}
+
+	var out bytes.Buffer
+	d.render(&out, now)
+	output := out.String()
+
+	for _, expected := range []string{
+		"Total: 2 | Active: 1 | Pending: 0 | Expired: 1",
+		"expired",
+		"2d 1h30m15s",
+		"1d 1h30m15s",
+		reverseVideo + "▶ UC_x5XG1OV2P6uZZ5FSM9Ttw",
+		"None recorded.",
+		"✅ done",
+		"q quit",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"jk", []string{"j", "k"}},
+		{"\x1b[A\x1b[B", []string{keyUp, keyDown}},
+		{"r\n", []string{"r"}},
+		{"\x03", []string{keyCtrlC}},
+		{"\x1b[C", nil},
+	}
+
+	for _, tc := range tests {
+		if got := parseKeys([]byte(tc.input)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseKeys(%q) = %v, expected %v", tc.input, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// DeadLettersConfig holds the configuration for the deadletters command
+type DeadLettersConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ID            string // Shows this dead letter, or replays it with Replay
+	Replay        bool   // Replays ID, or every dead letter when ID is empty
+	Timeout       time.Duration
+	Format        string    // "table" (default) or "json"
+	Output        io.Writer // Defaults to os.Stdout
+}
+
+// deadLetterColumns are the columns of the dead-letter table
+var deadLetterColumns = NewTable([]Column[webhook.DeadLetterSummary]{
+	{Key: "id", Header: "ID", Value: func(d webhook.DeadLetterSummary) string { return d.ID }},
+	{Key: "received", Header: "RECEIVED", Value: func(d webhook.DeadLetterSummary) string {
+		return d.ReceivedAt.Local().Format("2006-01-02 15:04:05")
+	}},
+	{Key: "bytes", Header: "BYTES", Value: func(d webhook.DeadLetterSummary) string { return fmt.Sprint(d.BodyBytes) }},
+	{Key: "replays", Header: "REPLAYS", Value: func(d webhook.DeadLetterSummary) string { return fmt.Sprint(d.ReplayAttempts) }},
+	{Key: "error", Header: "ERROR", Width: 60, Value: func(d webhook.DeadLetterSummary) string { return d.Error }},
+}, []string{"id", "received", "bytes", "replays", "error"})
+
+// DeadLetters lists, shows or replays notifications the service could not parse
+func DeadLetters(config DeadLettersConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "table"
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (available: table, json)", config.Format)
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	switch {
+	case config.Replay:
+		return replayDeadLetters(c, config, out)
+	case config.ID != "":
+		resp, err := c.GetDeadLetter(config.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get dead letter: %w", err)
+		}
+		if format == "json" {
+			return writeJSON(out, resp)
+		}
+		printDeadLetter(out, resp.DeadLetter)
+		return nil
+	}
+
+	resp, err := c.ListDeadLetters()
+	if err != nil {
+		return fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	if format == "json" {
+		return writeJSON(out, resp)
+	}
+
+	if len(resp.DeadLetters) == 0 {
+		fmt.Fprintf(out, "No dead letters (kept for %d days).\n", resp.RetentionDays)
+		return nil
+	}
+	fmt.Fprintf(out, "📭 %d unparseable notifications (kept for %d days, newest first)\n\n", resp.Count, resp.RetentionDays)
+	return deadLetterColumns.Render(out, resp.DeadLetters, nil, "")
+}
+
+// replayDeadLetters replays one dead letter, or every stored one oldest first.
+// Failed replays are reported and stay stored.
+func replayDeadLetters(c *client.Client, config DeadLettersConfig, out io.Writer) error {
+	ids := []string{config.ID}
+	if config.ID == "" {
+		resp, err := c.ListDeadLetters()
+		if err != nil {
+			return fmt.Errorf("failed to list dead letters: %w", err)
+		}
+		entries := resp.DeadLetters
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ReceivedAt.Before(entries[j].ReceivedAt) })
+		ids = ids[:0]
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+	}
+
+	failed := 0
+	for _, id := range ids {
+		resp, err := c.ReplayDeadLetter(id)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "❌ %s: %v\n", id, err)
+			continue
+		}
+		fmt.Fprintf(out, "✅ %s: %s\n", id, resp.Message)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d replays failed", failed, len(ids))
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(out, "No dead letters to replay.")
+	}
+	return nil
+}
+
+// printDeadLetter prints a dead letter's details followed by its raw payload
+func printDeadLetter(out io.Writer, deadLetter *webhook.DeadLetter) {
+	fmt.Fprintf(out, "📭 Dead letter %s\n", deadLetter.ID)
+	fmt.Fprintf(out, "   Received:  %s\n", deadLetter.ReceivedAt.Local().Format("2006-01-02 15:04:05"))
+	if deadLetter.RemoteAddr != "" {
+		fmt.Fprintf(out, "   From:      %s\n", deadLetter.RemoteAddr)
+	}
+	fmt.Fprintf(out, "   Error:     %s\n", deadLetter.Error)
+	if deadLetter.ReplayAttempts > 0 {
+		fmt.Fprintf(out, "   Replays:   %d (last error: %s)\n", deadLetter.ReplayAttempts, deadLetter.LastReplayError)
+	}
+
+	names := make([]string, 0, len(deadLetter.Headers))
+	for name := range deadLetter.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(out, "\nHeaders:")
+	for _, name := range names {
+		fmt.Fprintf(out, "   %s: %s\n", name, deadLetter.Headers[name])
+	}
+
+	fmt.Fprintln(out, "\nBody:")
+	fmt.Fprintln(out, deadLetter.Body)
+	if deadLetter.BodyTruncated {
+		fmt.Fprintln(out, "(truncated; this dead letter cannot be replayed in full)")
+	}
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// newDeadLettersServer serves two dead letters; replaying "broken" fails
+func newDeadLettersServer(t *testing.T, replayed *[]string) *httptest.Server {
+	now := time.Now()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/deadletters":
+			json.NewEncoder(w).Encode(webhook.DeadLetterListResponse{
+				Status:        "success",
+				RetentionDays: 14,
+				Count:         2,
+				DeadLetters: []webhook.DeadLetterSummary{
+					{ID: "newer", ReceivedAt: now, Error: "invalid XML: unexpected EOF", BodyBytes: 13},
+					{ID: "broken", ReceivedAt: now.Add(-time.Hour), Error: "invalid XML: unexpected EOF", BodyBytes: 6, ReplayAttempts: 1},
+				},
+			})
+		case r.Method == "GET" && r.URL.Path == "/deadletters/broken":
+			json.NewEncoder(w).Encode(webhook.DeadLetterResponse{
+				Status: "success",
+				DeadLetter: &webhook.DeadLetter{
+					ID:         "broken",
+					ReceivedAt: now,
+					Headers:    map[string]string{"Content-Type": "application/atom+xml"},
+					Body:       "<feed>",
+					Error:      "invalid XML: unexpected EOF",
+				},
+			})
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/replay"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/deadletters/"), "/replay")
+			*replayed = append(*replayed, id)
+			if id == "broken" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "Replay failed: Invalid XML"})
+				return
+			}
+			json.NewEncoder(w).Encode(webhook.DeadLetterReplayResponse{Status: "success", ID: id, Message: "Successfully triggered workflow"})
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDeadLetters_List(t *testing.T) {
+	var replayed []string
+	server := newDeadLettersServer(t, &replayed)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := DeadLetters(DeadLettersConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Output: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, want := range []string{"2 unparseable notifications", "kept for 14 days", "newer", "broken", "invalid XML"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDeadLetters_Show(t *testing.T) {
+	var replayed []string
+	server := newDeadLettersServer(t, &replayed)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := DeadLetters(DeadLettersConfig{BaseURL: server.URL, ID: "broken", Timeout: 30 * time.Second, Output: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, want := range []string{"Dead letter broken", "Content-Type: application/atom+xml", "<feed>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDeadLetters_ReplayAll(t *testing.T) {
+	var replayed []string
+	server := newDeadLettersServer(t, &replayed)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := DeadLetters(DeadLettersConfig{BaseURL: server.URL, Replay: true, Timeout: 30 * time.Second, Output: &out})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 replays failed") {
+		t.Errorf("Expected one failed replay, got %v", err)
+	}
+
+	if strings.Join(replayed, ",") != "broken,newer" {
+		t.Errorf("Expected oldest dead letter replayed first, got %v", replayed)
+	}
+	if !strings.Contains(out.String(), "✅ newer: Successfully triggered workflow") {
+		t.Errorf("Expected successful replay in output, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "❌ broken") {
+		t.Errorf("Expected failed replay in output, got:\n%s", out.String())
+	}
+}
+
+func TestDeadLetters_ReplayOne(t *testing.T) {
+	var replayed []string
+	server := newDeadLettersServer(t, &replayed)
+	defer server.Close()
+
+	err := DeadLetters(DeadLettersConfig{BaseURL: server.URL, ID: "newer", Replay: true, Timeout: 30 * time.Second, Output: &bytes.Buffer{}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(replayed, ",") != "newer" {
+		t.Errorf("Expected only newer replayed, got %v", replayed)
+	}
+}
@@ -0,0 +1,250 @@
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// Doctor check statuses
+const (
+	DoctorOK      = "ok"
+	DoctorFailed  = "failed"
+	DoctorSkipped = "skipped"
+)
+
+// doctorChannelID is the channel named in the challenge sent to the callback URL. The
+// service echoes unsubscribe challenges for channels it does not track, so the check
+// changes nothing.
+const doctorChannelID = "UCyoutube-webhook-doctor"
+
+// DoctorConfig holds the configuration for the doctor command
+type DoctorConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	Timeout       time.Duration
+	Format        string    // "text" (default) or "json"
+	Output        io.Writer // Defaults to os.Stdout
+}
+
+// DoctorCheck is the outcome of one of the doctor command's checks
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // ok, failed, or skipped when an earlier check failed
+	Message string `json:"message,omitempty"`
+	Fix     string `json:"fix,omitempty"` // What to do about a failed check
+}
+
+// DoctorReport is every check the doctor command made
+type DoctorReport struct {
+	BaseURL string        `json:"base_url"`
+	Healthy bool          `json:"healthy"`
+	Checks  []DoctorCheck `json:"checks"`
+}
+
+// Doctor diagnoses a deployment: whether the service is reachable and accepts the CLI's
+// credentials, whether its configuration, storage and GitHub token work, and whether the
+// hub callback URL echoes verification challenges. It fails when any check does.
+func Doctor(config DoctorConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %q (available: text, json)", config.Format)
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+	report := diagnose(c, config.BaseURL, &http.Client{Timeout: config.Timeout})
+
+	if format == "json" {
+		if err := writeJSON(out, report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(out, report)
+	}
+
+	if !report.Healthy {
+		failed := 0
+		for _, check := range report.Checks {
+			if check.Status == DoctorFailed {
+				failed++
+			}
+		}
+		return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+	}
+	return nil
+}
+
+// diagnose runs every check in order, skipping those that need a failed one. The callback
+// check uses httpClient, which sends no credentials, as the hub does.
+func diagnose(c *client.Client, baseURL string, httpClient *http.Client) DoctorReport {
+	report := DoctorReport{BaseURL: baseURL, Healthy: true}
+	add := func(check DoctorCheck) {
+		if check.Status == DoctorFailed {
+			report.Healthy = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	skip := func(reason string, names ...string) {
+		for _, name := range names {
+			add(DoctorCheck{Name: name, Status: DoctorSkipped, Message: reason})
+		}
+	}
+
+	health, err := c.Health()
+	if err != nil {
+		add(DoctorCheck{Name: "service", Status: DoctorFailed, Message: err.Error(),
+			Fix: "Check -url or YOUTUBE_WEBHOOK_URL is the deployed function's URL, and that the function is running"})
+		skip("the service is unreachable", "credentials", "configuration", "storage", "github", "callback")
+		return report
+	}
+	add(DoctorCheck{Name: "service", Status: DoctorOK,
+		Message: fmt.Sprintf("reachable, up %s", (time.Duration(health.UptimeSeconds) * time.Second).String())})
+
+	diagnostics, err := c.Diagnostics()
+	if err != nil {
+		add(DoctorCheck{Name: "credentials", Status: DoctorFailed, Message: err.Error(), Fix: credentialsFix(err)})
+		skip("the service's diagnostics are unavailable", "configuration", "storage", "github", "callback")
+		return report
+	}
+	add(DoctorCheck{Name: "credentials", Status: DoctorOK, Message: "accepted by the management API"})
+
+	add(componentCheck("configuration", diagnostics.Components["config"], "every required setting is valid",
+		"Set the missing or invalid environment variables on the function and redeploy; GET /config lists them all"))
+	add(componentCheck("storage", diagnostics.Components["storage"], "reachable",
+		"Check SUBSCRIPTION_BUCKET (or the Firestore database) exists and the function's service account can read and write it"))
+	githubOK := "GITHUB_TOKEN accepted"
+	if diagnostics.Repository != "" {
+		githubOK += " for " + diagnostics.Repository
+	}
+	add(componentCheck("github", diagnostics.Components["github"], githubOK,
+		"Set GITHUB_TOKEN to a token that can read the repository and send it dispatches (classic: repo scope; fine-grained: Contents read and write)"))
+	add(checkCallback(httpClient, diagnostics.CallbackURL))
+	return report
+}
+
+// credentialsFix suggests how to fix a failed management API call by its status
+func credentialsFix(err error) string {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "(401)") || strings.Contains(message, "status 401"):
+		return "Set YOUTUBE_WEBHOOK_API_KEY (or api_key in the config file) to one of the service's API_KEYS or an ID token, or YOUTUBE_WEBHOOK_SIGNING_SECRET to its REQUEST_SIGNING_SECRET"
+	case strings.Contains(message, "(403)") || strings.Contains(message, "status 403"):
+		return "Add the ID token's service account to the service's OIDC_ALLOWED_EMAILS"
+	case strings.Contains(message, "(404)") || strings.Contains(message, "status 404"):
+		return "Redeploy the function: this version has no /diagnostics endpoint"
+	}
+	return "Check the service's logs for the failed GET /diagnostics request"
+}
+
+// componentCheck turns one of the service's diagnostics into a doctor check
+func componentCheck(name string, component webhook.ComponentCheck, okMessage, fix string) DoctorCheck {
+	switch component.Status {
+	case webhook.ProbeStatusOK:
+		return DoctorCheck{Name: name, Status: DoctorOK, Message: okMessage}
+	case "":
+		return DoctorCheck{Name: name, Status: DoctorSkipped, Message: "not reported by the service"}
+	}
+	return DoctorCheck{Name: name, Status: DoctorFailed, Message: component.Message, Fix: fix}
+}
+
+// checkCallback sends the callback URL a verification challenge the way the hub does,
+// and checks it is echoed back
+func checkCallback(httpClient *http.Client, callbackURL string) DoctorCheck {
+	check := DoctorCheck{Name: "callback", Status: DoctorFailed}
+	if callbackURL == "" {
+		check.Message = "FUNCTION_URL not set, so the hub has nowhere to send challenges and notifications"
+		check.Fix = "Set FUNCTION_URL to the function's public URL and redeploy"
+		return check
+	}
+
+	challengeBytes := make([]byte, 16)
+	if _, err := rand.Read(challengeBytes); err != nil {
+		check.Message = fmt.Sprintf("generating a challenge: %v", err)
+		return check
+	}
+	challenge := hex.EncodeToString(challengeBytes)
+
+	target, err := url.Parse(callbackURL)
+	if err != nil {
+		check.Message = fmt.Sprintf("FUNCTION_URL %q is not a URL: %v", callbackURL, err)
+		check.Fix = "Set FUNCTION_URL to the function's public URL and redeploy"
+		return check
+	}
+	query := target.Query()
+	query.Set("hub.mode", "unsubscribe")
+	query.Set("hub.topic", "https://www.youtube.com/feeds/videos.xml?channel_id="+doctorChannelID)
+	query.Set("hub.challenge", challenge)
+	target.RawQuery = query.Encode()
+
+	resp, err := httpClient.Get(target.String())
+	if err != nil {
+		check.Message = fmt.Sprintf("%s is unreachable: %v", callbackURL, err)
+		check.Fix = "Make FUNCTION_URL reachable from the internet without credentials; the hub cannot verify subscriptions otherwise"
+		return check
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		check.Message = fmt.Sprintf("reading the response from %s: %v", callbackURL, err)
+		return check
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		check.Message = fmt.Sprintf("%s refused the challenge with status %d", callbackURL, resp.StatusCode)
+		check.Fix = "Allow unauthenticated invocations of the function (roles/run.invoker for allUsers), as the hub sends no credentials"
+	case resp.StatusCode != http.StatusOK || string(body) != challenge:
+		check.Message = fmt.Sprintf("%s answered with status %d without echoing the challenge", callbackURL, resp.StatusCode)
+		check.Fix = "Set FUNCTION_URL to this function's own URL, with no path the function does not serve"
+	default:
+		check.Status = DoctorOK
+		check.Message = fmt.Sprintf("%s echoes verification challenges", callbackURL)
+		check.Fix = ""
+	}
+	return check
+}
+
+// printDoctorReport writes the checks as text, with the fix for each failure
+func printDoctorReport(out io.Writer, report DoctorReport) {
+	fmt.Fprintf(out, "🩺 Checking %s\n\n", report.BaseURL)
+	failed := 0
+	for _, check := range report.Checks {
+		icon := "✅"
+		switch check.Status {
+		case DoctorFailed:
+			icon = "❌"
+			failed++
+		case DoctorSkipped:
+			icon = "⏭️ "
+		}
+		fmt.Fprintf(out, "%s %-14s %s\n", icon, check.Name, check.Message)
+		if check.Fix != "" {
+			fmt.Fprintf(out, "   %-14s → %s\n", "", check.Fix)
+		}
+	}
+
+	fmt.Fprintln(out)
+	if failed == 0 {
+		fmt.Fprintln(out, "✅ No problems found")
+	} else {
+		fmt.Fprintf(out, "❌ %d problem(s) found\n", failed)
+	}
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// newDoctorServer serves the webhook function with every required setting configured and
+// FUNCTION_URL pointing at itself
+func newDoctorServer(t *testing.T) (*httptest.Server, *webhook.Dependencies) {
+	deps := webhook.CreateTestDependencies()
+	webhook.SetDependencies(deps)
+	t.Cleanup(func() { webhook.SetDependencies(nil) })
+
+	server := httptest.NewServer(http.HandlerFunc(webhook.YouTubeWebhook))
+	t.Cleanup(server.Close)
+	t.Setenv("FUNCTION_URL", server.URL)
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("REPO_OWNER", "octo")
+	t.Setenv("REPO_NAME", "site")
+	t.Setenv("SUBSCRIPTION_BUCKET", "bucket")
+	return server, deps
+}
+
+// doctorChecks maps each check's name to its status
+func doctorChecks(report DoctorReport) map[string]string {
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestDoctor(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		server, deps := newDoctorServer(t)
+
+		var out bytes.Buffer
+		err := Doctor(DoctorConfig{BaseURL: server.URL, Timeout: 5 * time.Second, Output: &out})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v, output:\n%s", err, out.String())
+		}
+		for _, want := range []string{"✅ service", "✅ github         GITHUB_TOKEN accepted for octo/site", "echoes verification challenges", "No problems found"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+		if calls := deps.GitHubClient.(*webhook.MockGitHubClient).GetAccessCallCount(); calls != 1 {
+			t.Errorf("Expected one GitHub access check, got %d", calls)
+		}
+	})
+
+	t.Run("github_token_rejected", func(t *testing.T) {
+		server, deps := newDoctorServer(t)
+		deps.GitHubClient.(*webhook.MockGitHubClient).SetAccessError(&webhook.GitHubAPIError{StatusCode: http.StatusUnauthorized})
+
+		var out bytes.Buffer
+		err := Doctor(DoctorConfig{BaseURL: server.URL, Timeout: 5 * time.Second, Output: &out})
+		if err == nil || err.Error() != "1 of 6 checks failed" {
+			t.Errorf("Expected one failed check, got %v", err)
+		}
+		if !strings.Contains(out.String(), "❌ github") || !strings.Contains(out.String(), "→ Set GITHUB_TOKEN") {
+			t.Errorf("Expected the GitHub failure with its fix, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("callback_not_echoing", func(t *testing.T) {
+		server, _ := newDoctorServer(t)
+		elsewhere := httptest.NewServer(http.NotFoundHandler())
+		defer elsewhere.Close()
+		t.Setenv("FUNCTION_URL", elsewhere.URL)
+
+		var out bytes.Buffer
+		if err := Doctor(DoctorConfig{BaseURL: server.URL, Timeout: 5 * time.Second, Format: "json", Output: &out}); err == nil {
+			t.Error("Expected the callback check to fail")
+		}
+		var report DoctorReport
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatalf("Expected JSON output, got %v: %s", err, out.String())
+		}
+		if report.Healthy || doctorChecks(report)["callback"] != DoctorFailed || doctorChecks(report)["github"] != DoctorOK {
+			t.Errorf("Expected only the callback check to fail, got %+v", report.Checks)
+		}
+	})
+
+	t.Run("credentials_rejected", func(t *testing.T) {
+		server, _ := newDoctorServer(t)
+		t.Setenv("API_KEYS", "secret-key")
+
+		var out bytes.Buffer
+		Doctor(DoctorConfig{BaseURL: server.URL, APIKey: "wrong-key", Timeout: 5 * time.Second, Output: &out})
+		if !strings.Contains(out.String(), "❌ credentials") || !strings.Contains(out.String(), "YOUTUBE_WEBHOOK_API_KEY") {
+			t.Errorf("Expected the credentials failure with its fix, got:\n%s", out.String())
+		}
+		if !strings.Contains(out.String(), "⏭️  github") {
+			t.Errorf("Expected the service's checks to be skipped, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("service_unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		var out bytes.Buffer
+		err := Doctor(DoctorConfig{BaseURL: server.URL, Timeout: time.Second, Format: "json", Output: &out})
+		if err == nil {
+			t.Error("Expected an unreachable service to fail")
+		}
+		var report DoctorReport
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatalf("Expected JSON output, got %v: %s", err, out.String())
+		}
+		statuses := doctorChecks(report)
+		if statuses["service"] != DoctorFailed || statuses["callback"] != DoctorSkipped || len(report.Checks) != 6 {
+			t.Errorf("Expected the service check to fail and the rest to be skipped, got %+v", report.Checks)
+		}
+	})
+
+	t.Run("unknown_format", func(t *testing.T) {
+		if err := Doctor(DoctorConfig{BaseURL: "http://127.0.0.1:1", Format: "xml"}); err == nil || !strings.Contains(err.Error(), "unknown format") {
+			t.Errorf("Expected an unknown format error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// FiltersConfig holds the configuration for the filters command
+type FiltersConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ChannelID     string
+	Timeout       time.Duration
+	Filter        *webhook.NotificationFilter // Replaces the channel's filter; nil shows it
+	Clear         bool                        // Removes the channel's filter
+	Output        io.Writer                   // Defaults to os.Stdout
+}
+
+// Filters shows, replaces or clears a channel's notification filter
+func Filters(config FiltersConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	var resp *webhook.FilterResponse
+	var err error
+	switch {
+	case config.Clear:
+		resp, err = c.ClearFilter(config.ChannelID)
+	case config.Filter != nil:
+		resp, err = c.SetFilter(config.ChannelID, *config.Filter)
+	default:
+		resp, err = c.GetFilter(config.ChannelID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to manage filters: %w", err)
+	}
+
+	if config.Clear || config.Filter != nil {
+		fmt.Fprintf(out, "✅ Updated filters for channel %s\n", config.ChannelID)
+	}
+	printFilter(out, resp)
+	return nil
+}
+
+// printFilter prints one line per rule, or a note that every video is dispatched
+func printFilter(out io.Writer, resp *webhook.FilterResponse) {
+	f := resp.Filter
+	if f.IsEmpty() {
+		fmt.Fprintf(out, "ℹ️  No filters for channel %s; every new video is dispatched\n", resp.ChannelID)
+		return
+	}
+
+	fmt.Fprintf(out, "🔎 Filters for channel %s\n", resp.ChannelID)
+	if f.TitlePattern != "" {
+		fmt.Fprintf(out, "   Title pattern:       %s\n", f.TitlePattern)
+	}
+	if len(f.IncludeKeywords) > 0 {
+		fmt.Fprintf(out, "   Include keywords:    %s\n", strings.Join(f.IncludeKeywords, ", "))
+	}
+	if len(f.ExcludeKeywords) > 0 {
+		fmt.Fprintf(out, "   Exclude keywords:    %s\n", strings.Join(f.ExcludeKeywords, ", "))
+	}
+	if f.ExcludeShorts {
+		fmt.Fprintln(out, "   Exclude shorts:      yes")
+	}
+	if f.ExcludeLivestreams {
+		fmt.Fprintln(out, "   Exclude livestreams: yes")
+	}
+}
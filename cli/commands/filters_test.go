@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+func newFiltersServer(t *testing.T, stored *webhook.NotificationFilter) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/filters" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			var filter webhook.NotificationFilter
+			if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+				t.Errorf("Expected JSON filter body, got error %v", err)
+			}
+			stored = &filter
+		case "DELETE":
+			stored = nil
+		}
+		json.NewEncoder(w).Encode(webhook.FilterResponse{
+			Status:    "success",
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Filter:    stored,
+		})
+	}))
+}
+
+func TestFilters_Show(t *testing.T) {
+	server := newFiltersServer(t, &webhook.NotificationFilter{
+		TitlePattern:    "^Episode",
+		ExcludeKeywords: []string{"sponsored", "ad"},
+		ExcludeShorts:   true,
+	})
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Filters(FiltersConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Timeout:   30 * time.Second,
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, want := range []string{"Title pattern:       ^Episode", "Exclude keywords:    sponsored, ad", "Exclude shorts:      yes"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Updated") {
+		t.Errorf("Expected show not to report an update, got:\n%s", out.String())
+	}
+}
+
+func TestFilters_SetAndClear(t *testing.T) {
+	server := newFiltersServer(t, nil)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Filters(FiltersConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Timeout:   30 * time.Second,
+		Filter:    &webhook.NotificationFilter{ExcludeLivestreams: true},
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Updated filters") || !strings.Contains(out.String(), "Exclude livestreams: yes") {
+		t.Errorf("Expected updated filter output, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	err = Filters(FiltersConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Timeout:   30 * time.Second,
+		Clear:     true,
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "No filters for channel") {
+		t.Errorf("Expected cleared filter output, got:\n%s", out.String())
+	}
+}
+
+func TestFilters_NotSubscribed(t *testing.T) {
+	server := newFiltersServer(t, nil)
+	defer server.Close()
+
+	err := Filters(FiltersConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCdQw4w9WgXcQabcdefghijk",
+		Timeout:   30 * time.Second,
+		Output:    &bytes.Buffer{},
+	})
+	if err == nil || !strings.Contains(err.Error(), "not subscribed to channel") {
+		t.Errorf("Expected not subscribed error, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// HistoryConfig holds the configuration for the history command
+type HistoryConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ChannelID     string
+	Limit         int // Most recent notifications to show; 0 shows the whole stored history
+	Timeout       time.Duration
+	Format        string    // "table" (default) or "json"
+	Output        io.Writer // Defaults to os.Stdout
+}
+
+// historyColumns are the columns of the history table
+var historyColumns = NewTable([]Column[webhook.HistoryEntry]{
+	{Key: "received", Header: "RECEIVED", Value: func(e webhook.HistoryEntry) string {
+		return e.ReceivedAt.Local().Format("2006-01-02 15:04:05")
+	}},
+	{Key: "video", Header: "VIDEO ID", Value: func(e webhook.HistoryEntry) string { return e.VideoID }},
+	{Key: "title", Header: "TITLE", Width: 40, Value: func(e webhook.HistoryEntry) string { return e.Title }},
+	{Key: "decision", Header: "DECISION", Value: func(e webhook.HistoryEntry) string { return e.Decision }},
+	{Key: "result", Header: "RESULT", Width: 60, Value: func(e webhook.HistoryEntry) string { return e.Result }},
+}, []string{"received", "video", "title", "decision", "result"})
+
+// History shows the notifications recently processed for a channel
+func History(config HistoryConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "table"
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (available: table, json)", config.Format)
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	resp, err := c.GetHistory(config.ChannelID, config.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to get notification history: %w", err)
+	}
+
+	if format == "json" {
+		return writeJSON(out, resp)
+	}
+
+	if len(resp.Entries) == 0 {
+		fmt.Fprintf(out, "No notifications recorded for channel %s.\n", resp.ChannelID)
+		return nil
+	}
+
+	fmt.Fprintf(out, "📜 Notification history for channel %s (%d, newest first)\n\n", resp.ChannelID, resp.Count)
+	return historyColumns.Render(out, resp.Entries, nil, "")
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+func newHistoryServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/history" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if got := r.URL.Query().Get("limit"); got != "5" {
+			t.Errorf("Expected limit=5, got %q", got)
+		}
+		json.NewEncoder(w).Encode(webhook.HistoryResponse{
+			Status:    "success",
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Count:     2,
+			Entries: []webhook.HistoryEntry{
+				{
+					ReceivedAt: time.Now(),
+					VideoID:    "video2",
+					Title:      "Sponsored Video",
+					Decision:   webhook.HistoryDecisionFiltered,
+					Result:     "Filtered: title contains excluded keyword \"sponsored\" (VideoID: video2)",
+				},
+				{
+					ReceivedAt: time.Now().Add(-time.Hour),
+					VideoID:    "video1",
+					Title:      "First Video",
+					Decision:   webhook.HistoryDecisionDispatched,
+					Result:     "Successfully triggered workflow for new video: video1",
+				},
+			},
+		})
+	}))
+}
+
+func TestHistory_Table(t *testing.T) {
+	server := newHistoryServer(t)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := History(HistoryConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Limit:     5,
+		Timeout:   30 * time.Second,
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{"DECISION", "video2", "filtered", "First Video", "dispatched"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Index(output, "video2") > strings.Index(output, "video1") {
+		t.Errorf("Expected newest notification first, got:\n%s", output)
+	}
+}
+
+func TestHistory_JSON(t *testing.T) {
+	server := newHistoryServer(t)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := History(HistoryConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Limit:     5,
+		Timeout:   30 * time.Second,
+		Format:    "json",
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var resp webhook.HistoryResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected JSON output, got error %v", err)
+	}
+	if len(resp.Entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(resp.Entries))
+	}
+}
+
+func TestHistory_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(webhook.APIResponse{
+			Status:  "error",
+			Message: "Notification history is disabled or not supported by the storage backend",
+		})
+	}))
+	defer server.Close()
+
+	err := History(HistoryConfig{
+		BaseURL:   server.URL,
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Timeout:   30 * time.Second,
+		Output:    &bytes.Buffer{},
+	})
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("Expected server error message, got %v", err)
+	}
+}
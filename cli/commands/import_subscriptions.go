@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// exportChannelID matches a YouTube channel ID, as found in subscription exports
+var exportChannelID = regexp.MustCompile(`^UC[a-zA-Z0-9_-]{22}$`)
+
+// ExportedChannel is one channel found in a subscription export
+type ExportedChannel struct {
+	ChannelID string
+	Title     string
+}
+
+// ImportSubscriptionsConfig holds the configuration for the import-subscriptions command
+type ImportSubscriptionsConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	File          string // Google Takeout subscriptions.csv or an OPML file
+	Timeout       time.Duration
+	Workers       int       // Concurrent requests (default: DefaultBulkWorkers)
+	DryRun        bool      // Only list the channels that would be subscribed
+	Output        io.Writer // Defaults to os.Stdout
+}
+
+// ReadSubscriptionExport reads the channels in a Google Takeout subscriptions.csv or an
+// OPML file, such as YouTube's old subscription manager export. Channels are found by
+// their IDs or channel URLs rather than by column names, which Takeout translates.
+// Duplicates are dropped.
+func ReadSubscriptionExport(path string) ([]ExportedChannel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading subscription export: %w", err)
+	}
+
+	var channels []ExportedChannel
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		channels, err = parseOPML(data)
+	} else {
+		channels, err = parseTakeoutCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	var unique []ExportedChannel
+	for _, channel := range channels {
+		if !seen[channel.ChannelID] {
+			seen[channel.ChannelID] = true
+			unique = append(unique, channel)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("no YouTube channels found in %s", path)
+	}
+	return unique, nil
+}
+
+// parseTakeoutCSV reads subscriptions.csv rows of channel ID, channel URL and title, after
+// any byte order mark. The header row has no channel ID, so it is skipped along with any
+// other such row.
+func parseTakeoutCSV(data []byte) ([]ExportedChannel, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []ExportedChannel
+	for _, record := range records {
+		var channel ExportedChannel
+		for _, field := range record {
+			field = strings.TrimSpace(field)
+			if id := channelIDFromField(field); id != "" {
+				if channel.ChannelID == "" {
+					channel.ChannelID = id
+				}
+			} else if channel.Title == "" {
+				channel.Title = field
+			}
+		}
+		if channel.ChannelID != "" {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+// opmlOutline is an OPML outline, which nests to group feeds
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	HTMLURL  string        `xml:"htmlUrl,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// parseOPML reads the YouTube channel feeds in an OPML file, at any depth
+func parseOPML(data []byte) ([]ExportedChannel, error) {
+	var doc struct {
+		XMLName  xml.Name      `xml:"opml"`
+		Outlines []opmlOutline `xml:"body>outline"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var channels []ExportedChannel
+	var walk func([]opmlOutline)
+	walk = func(outlines []opmlOutline) {
+		for _, outline := range outlines {
+			id := channelIDFromField(outline.XMLURL)
+			if id == "" {
+				id = channelIDFromField(outline.HTMLURL)
+			}
+			if id != "" {
+				title := outline.Title
+				if title == "" {
+					title = outline.Text
+				}
+				channels = append(channels, ExportedChannel{ChannelID: id, Title: title})
+			}
+			walk(outline.Outlines)
+		}
+	}
+	walk(doc.Outlines)
+	return channels, nil
+}
+
+// channelIDFromField returns the channel ID a field holds, directly, as a channel URL
+// (youtube.com/channel/UC...) or as a feed URL (?channel_id=UC...), or "" if none
+func channelIDFromField(field string) string {
+	if exportChannelID.MatchString(field) {
+		return field
+	}
+	parsed, err := url.Parse(field)
+	if err != nil || !strings.Contains(parsed.Host, "youtube.com") {
+		return ""
+	}
+	if id := parsed.Query().Get("channel_id"); exportChannelID.MatchString(id) {
+		return id
+	}
+	if id, ok := strings.CutPrefix(parsed.Path, "/channel/"); ok {
+		id = strings.TrimSuffix(id, "/")
+		if exportChannelID.MatchString(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// ImportSubscriptions subscribes to every channel in a subscription export that the
+// service does not already track, printing what was skipped before the bulk results
+func ImportSubscriptions(config ImportSubscriptionsConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	channels, err := ReadSubscriptionExport(config.File)
+	if err != nil {
+		return err
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+	existing, err := c.ListSubscriptions()
+	if err != nil {
+		return fmt.Errorf("failed to list existing subscriptions: %w", err)
+	}
+	subscribed := make(map[string]bool, len(existing.Subscriptions))
+	for _, sub := range existing.Subscriptions {
+		subscribed[sub.ChannelID] = true
+	}
+
+	var channelIDs []string
+	for _, channel := range channels {
+		if !subscribed[channel.ChannelID] {
+			channelIDs = append(channelIDs, channel.ChannelID)
+		}
+	}
+
+	fmt.Fprintf(out, "📥 Found %d channels in %s\n", len(channels), config.File)
+	fmt.Fprintf(out, "   Already subscribed: %d | To subscribe: %d\n", len(channels)-len(channelIDs), len(channelIDs))
+
+	if config.DryRun {
+		fmt.Fprintln(out)
+		for _, channel := range channels {
+			if subscribed[channel.ChannelID] {
+				continue
+			}
+			if channel.Title != "" {
+				fmt.Fprintf(out, "   %s  %s\n", channel.ChannelID, channel.Title)
+			} else {
+				fmt.Fprintf(out, "   %s\n", channel.ChannelID)
+			}
+		}
+		fmt.Fprintln(out, "\nDry run: nothing was subscribed")
+		return nil
+	}
+	if len(channelIDs) == 0 {
+		fmt.Fprintln(out, "\n✅ Every channel is already subscribed")
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	return BulkSubscribe(BulkConfig{
+		BaseURL:       config.BaseURL,
+		APIKey:        config.APIKey,
+		SigningSecret: config.SigningSecret,
+		ChannelIDs:    channelIDs,
+		Timeout:       config.Timeout,
+		Workers:       config.Workers,
+		Output:        out,
+	})
+}
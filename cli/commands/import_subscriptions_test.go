@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+const (
+	takeoutChannel1 = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	takeoutChannel2 = "UC_x5XG1OV2P6uZZ5FSM9Ttw"
+	takeoutChannel3 = "UCBJycsmduvYEL83R_U4JriQ"
+)
+
+func TestReadSubscriptionExport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []ExportedChannel
+	}{
+		{
+			name: "takeout_csv",
+			content: "\ufeffChannel Id,Channel Url,Channel Title\n" +
+				takeoutChannel1 + ",http://www.youtube.com/channel/" + takeoutChannel1 + ",Linus Tech Tips\n" +
+				takeoutChannel2 + ",http://www.youtube.com/channel/" + takeoutChannel2 + ",\"Google for Developers, Official\"\n" +
+				takeoutChannel1 + ",http://www.youtube.com/channel/" + takeoutChannel1 + ",Linus Tech Tips\n",
+			expected: []ExportedChannel{
+				{ChannelID: takeoutChannel1, Title: "Linus Tech Tips"},
+				{ChannelID: takeoutChannel2, Title: "Google for Developers, Official"},
+			},
+		},
+		{
+			name:     "translated_headers",
+			content:  "Kanal-ID,Kanal-URL,Kanaltitel\n" + takeoutChannel3 + ",http://www.youtube.com/channel/" + takeoutChannel3 + ",Marques Brownlee\n",
+			expected: []ExportedChannel{{ChannelID: takeoutChannel3, Title: "Marques Brownlee"}},
+		},
+		{
+			name: "opml",
+			content: `<?xml version="1.0"?>
+<opml version="1.1">
+  <body>
+    <outline text="YouTube Subscriptions" title="YouTube Subscriptions">
+      <outline text="Linus Tech Tips" title="Linus Tech Tips" type="rss" xmlUrl="https://www.youtube.com/feeds/videos.xml?channel_id=` + takeoutChannel1 + `"/>
+      <outline text="Marques Brownlee" type="rss" htmlUrl="https://www.youtube.com/channel/` + takeoutChannel3 + `/"/>
+      <outline text="Some Blog" type="rss" xmlUrl="https://blog.example.com/feed.xml"/>
+    </outline>
+  </body>
+</opml>`,
+			expected: []ExportedChannel{
+				{ChannelID: takeoutChannel1, Title: "Linus Tech Tips"},
+				{ChannelID: takeoutChannel3, Title: "Marques Brownlee"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			channels, err := ReadSubscriptionExport(writeChannelFile(t, tc.content))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(channels, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, channels)
+			}
+		})
+	}
+}
+
+func TestReadSubscriptionExport_Errors(t *testing.T) {
+	for name, content := range map[string]string{
+		"no_channels": "Channel Id,Channel Url,Channel Title\n",
+		"bad_opml":    "<opml><body><outline",
+		"not_youtube": "<opml><body><outline xmlUrl=\"https://blog.example.com/feed.xml\"/></body></opml>",
+		"bad_csv":     "\"unterminated\n",
+		"invalid_ids": "UC1\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ReadSubscriptionExport(writeChannelFile(t, content)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestImportSubscriptions(t *testing.T) {
+	var mu sync.Mutex
+	var subscribed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/subscriptions":
+			json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+				Subscriptions: []webhook.SubscriptionInfo{{ChannelID: takeoutChannel2, Status: webhook.StatusActive}},
+				Total:         1,
+			})
+		case "/subscribe":
+			mu.Lock()
+			subscribed = append(subscribed, r.URL.Query().Get("channel_id"))
+			mu.Unlock()
+			json.NewEncoder(w).Encode(webhook.APIResponse{Status: "success"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	file := writeChannelFile(t, "Channel Id,Channel Url,Channel Title\n"+
+		takeoutChannel1+",http://www.youtube.com/channel/"+takeoutChannel1+",Linus Tech Tips\n"+
+		takeoutChannel2+",http://www.youtube.com/channel/"+takeoutChannel2+",Google for Developers\n"+
+		takeoutChannel3+",http://www.youtube.com/channel/"+takeoutChannel3+",Marques Brownlee\n")
+
+	t.Run("dry_run", func(t *testing.T) {
+		var out bytes.Buffer
+		err := ImportSubscriptions(ImportSubscriptionsConfig{BaseURL: server.URL, File: file, Timeout: 5 * time.Second, DryRun: true, Output: &out})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, want := range []string{"Found 3 channels", "Already subscribed: 1 | To subscribe: 2", takeoutChannel1 + "  Linus Tech Tips", "nothing was subscribed"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+		if strings.Contains(out.String(), "Google for Developers") || len(subscribed) != 0 {
+			t.Errorf("Expected only the new channels to be listed and none subscribed, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("subscribes_new_channels", func(t *testing.T) {
+		var out bytes.Buffer
+		err := ImportSubscriptions(ImportSubscriptionsConfig{BaseURL: server.URL, File: file, Timeout: 5 * time.Second, Output: &out})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(subscribed) != 2 || strings.Contains(strings.Join(subscribed, ","), takeoutChannel2) {
+			t.Errorf("Expected the two new channels to be subscribed, got %v", subscribed)
+		}
+		if !strings.Contains(out.String(), "Succeeded: 2 | Failed: 0") {
+			t.Errorf("Expected the bulk summary, got:\n%s", out.String())
+		}
+	})
+}
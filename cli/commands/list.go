@@ -1,62 +1,348 @@
 package commands
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"text/tabwriter"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/samsoir/youtube-webhook/cli/client"
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
 )
 
 // ListConfig holds the configuration for the list command
 type ListConfig struct {
-	BaseURL string
-	Timeout time.Duration
-	Format  string // "table" or "json"
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	Timeout       time.Duration
+	Format        string    // "table" (default), "json" or "csv"
+	Columns       []string  // Table columns to show (default: channel,name,status,expires,days_left)
+	Sort          string    // Column to sort by, prefixed with "-" for descending
+	Output        io.Writer // Defaults to os.Stdout
+
+	Status    string // Only list subscriptions in this lifecycle status
+	Limit     int    // List one page of at most this many subscriptions; 0 lists them all
+	PageToken string // Continue from the page token printed by a previous limited list
+
+	Expiring bool          // Only list subscriptions whose lease ends within Within, soonest first
+	Within   time.Duration // Window for Expiring; 0 uses the service's renewal threshold
+}
+
+// listPageSize is the page size used when fetching every subscription
+const listPageSize = 100
+
+// serverSortKeys maps list columns to the sort orders the service supports, so a
+// limited list returns the first subscriptions of the whole list rather than of a page
+var serverSortKeys = map[string]string{
+	"channel":   webhook.SortByChannel,
+	"expires":   webhook.SortByExpiry,
+	"days_left": webhook.SortByExpiry,
+}
+
+// listColumns are the columns available to the list table
+var listColumns = NewTable([]Column[webhook.SubscriptionInfo]{
+	{
+		Key: "channel", Header: "CHANNEL ID", Width: 24,
+		Value: func(s webhook.SubscriptionInfo) string { return s.ChannelID },
+	},
+	{
+		Key: "name", Header: "NAME", Width: 24,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.ChannelName) },
+	},
+	{
+		Key: "type", Header: "TYPE", Width: 8,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.TopicType) },
+	},
+	{
+		Key: "status", Header: "STATUS", Width: 12,
+		Value: func(s webhook.SubscriptionInfo) string {
+			switch s.Status {
+			case webhook.StatusActive, webhook.StatusVerified:
+				return "✅ " + s.Status
+			case webhook.StatusPending:
+				return "⏳ pending"
+			case webhook.StatusExpiring:
+				return "⏰ expiring"
+			case webhook.StatusQuarantined:
+				return "🚫 quarantined"
+			}
+			return "⚠️  expired"
+		},
+	},
+	{
+		Key: "expires", Header: "EXPIRES", Width: 25,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.ExpiresAt) },
+		Less: func(a, b webhook.SubscriptionInfo) bool {
+			return a.DaysUntilExpiry < b.DaysUntilExpiry
+		},
+	},
+	{
+		Key: "days_left", Header: "DAYS LEFT", Width: 9,
+		Value: func(s webhook.SubscriptionInfo) string {
+			if s.ExpiresAt == "" {
+				return "-"
+			}
+			if s.DaysUntilExpiry < 0 {
+				return "expired"
+			}
+			return fmt.Sprintf("%.1f", s.DaysUntilExpiry)
+		},
+		Less: func(a, b webhook.SubscriptionInfo) bool {
+			return a.DaysUntilExpiry < b.DaysUntilExpiry
+		},
+	},
+	{
+		Key: "next_renewal", Header: "NEXT RENEWAL", Width: 25,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.NextRenewalAt) },
+	},
+	{
+		Key: "health", Header: "HEALTH", Width: 8,
+		Value: func(s webhook.SubscriptionInfo) string { return s.Indicator },
+	},
+	{
+		Key: "last_notification", Header: "LAST NOTIFICATION", Width: 25,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.LastNotificationAt) },
+	},
+	{
+		Key: "last_video", Header: "LAST VIDEO", Width: 15,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.LastVideoID) },
+	},
+	{
+		Key: "last_run", Header: "LAST RUN", Width: 60,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.LastRunURL) },
+	},
+	{
+		Key: "hub", Header: "HUB", Width: 45,
+		Value: func(s webhook.SubscriptionInfo) string { return valueOrDash(s.HubURL) },
+	},
+}, []string{"channel", "name", "status", "expires", "days_left"})
+
+// listCSVFields are the columns written by -format csv, covering every field of a subscription
+var listCSVFields = []struct {
+	Header string
+	Value  func(webhook.SubscriptionInfo) string
+}{
+	{"channel_id", func(s webhook.SubscriptionInfo) string { return s.ChannelID }},
+	{"channel_name", func(s webhook.SubscriptionInfo) string { return s.ChannelName }},
+	{"status", func(s webhook.SubscriptionInfo) string { return s.Status }},
+	{"expires_at", func(s webhook.SubscriptionInfo) string { return s.ExpiresAt }},
+	{"days_until_expiry", func(s webhook.SubscriptionInfo) string { return fmt.Sprintf("%.2f", s.DaysUntilExpiry) }},
+	{"lease_seconds", func(s webhook.SubscriptionInfo) string { return strconv.Itoa(s.LeaseSeconds) }},
+	{"renewal_attempts", func(s webhook.SubscriptionInfo) string { return strconv.Itoa(s.RenewalAttempts) }},
+	{"subscribed_at", func(s webhook.SubscriptionInfo) string { return s.SubscribedAt }},
+	{"last_renewal", func(s webhook.SubscriptionInfo) string { return s.LastRenewal }},
+	{"next_renewal_at", func(s webhook.SubscriptionInfo) string { return s.NextRenewalAt }},
+	{"health", func(s webhook.SubscriptionInfo) string { return s.Indicator }},
+	{"health_reasons", func(s webhook.SubscriptionInfo) string { return strings.Join(s.Reasons, "; ") }},
+	{"last_notification_at", func(s webhook.SubscriptionInfo) string { return s.LastNotificationAt }},
+	{"hours_since_notification", func(s webhook.SubscriptionInfo) string {
+		if s.HoursSinceNotification == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", *s.HoursSinceNotification)
+	}},
+	{"last_renewal_succeeded", func(s webhook.SubscriptionInfo) string {
+		if s.LastRenewalSucceeded == nil {
+			return ""
+		}
+		return strconv.FormatBool(*s.LastRenewalSucceeded)
+	}},
+	{"last_video_id", func(s webhook.SubscriptionInfo) string { return s.LastVideoID }},
+	{"last_run_url", func(s webhook.SubscriptionInfo) string { return s.LastRunURL }},
+	{"hub_url", func(s webhook.SubscriptionInfo) string { return s.HubURL }},
+	{"topic_type", func(s webhook.SubscriptionInfo) string { return s.TopicType }},
 }
 
 // List lists all subscriptions
 func List(config ListConfig) error {
-	c := client.NewClient(config.BaseURL, config.Timeout)
-	
-	resp, err := c.ListSubscriptions()
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "table"
+	}
+	if format != "table" && format != "json" && format != "csv" {
+		return fmt.Errorf("unknown format %q (available: table, json, csv)", config.Format)
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	if config.Expiring {
+		return listExpiring(out, c, config, format)
+	}
+
+	resp, err := fetchSubscriptions(c, config)
 	if err != nil {
 		return fmt.Errorf("failed to list subscriptions: %w", err)
 	}
 
+	switch format {
+	case "json":
+		return writeListJSON(out, resp, config.Sort)
+	case "csv":
+		return writeListCSV(out, resp.Subscriptions, config.Sort)
+	}
+
 	// Print summary
-	fmt.Printf("📊 Subscription Summary\n")
-	fmt.Printf("   Total: %d | Active: %d | Expired: %d\n\n", 
-		resp.Total, resp.Active, resp.Expired)
+	fmt.Fprintf(out, "📊 Subscription Summary\n")
+	fmt.Fprintf(out, "   Total: %d | Active: %d | Pending: %d | Expired: %d\n\n",
+		resp.Total, resp.Active, resp.Pending, resp.Expired)
+	if resp.Quarantined > 0 {
+		fmt.Fprintf(out, "   Quarantined: %d (reactivate with POST /subscriptions/{channel_id}/reactivate)\n\n", resp.Quarantined)
+	}
 
 	if len(resp.Subscriptions) == 0 {
-		fmt.Println("No subscriptions found.")
+		fmt.Fprintln(out, "No subscriptions found.")
 		return nil
 	}
 
-	// Print table
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "CHANNEL ID\tSTATUS\tEXPIRES\tDAYS LEFT")
-	fmt.Fprintln(w, "----------\t------\t-------\t---------")
-	
-	for _, sub := range resp.Subscriptions {
-		status := sub.Status
-		if status == "active" {
-			status = "✅ active"
-		} else {
-			status = "⚠️  expired"
-		}
-		
-		daysLeft := fmt.Sprintf("%.1f", sub.DaysUntilExpiry)
-		if sub.DaysUntilExpiry < 0 {
-			daysLeft = "expired"
-		}
-		
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", 
-			sub.ChannelID, status, sub.ExpiresAt, daysLeft)
-	}
-	w.Flush()
-	
+	if err := listColumns.Render(out, resp.Subscriptions, config.Columns, config.Sort); err != nil {
+		return err
+	}
+	if resp.NextPageToken != "" {
+		fmt.Fprintf(out, "\nMore subscriptions available, continue with: -page-token %s\n", resp.NextPageToken)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// listExpiring lists the subscriptions whose lease ends within config.Within from
+// GET /subscriptions/expiring, which returns them soonest first without paging
+func listExpiring(out io.Writer, c *client.Client, config ListConfig, format string) error {
+	if config.Status != "" || config.PageToken != "" {
+		return fmt.Errorf("-expiring cannot be combined with -status or -page-token")
+	}
+
+	resp, err := c.ListExpiringSubscriptions(config.Within, config.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to list expiring subscriptions: %w", err)
+	}
+
+	switch format {
+	case "json":
+		sorted, err := listColumns.Sort(resp.Subscriptions, config.Sort)
+		if err != nil {
+			return err
+		}
+		output := *resp
+		output.Subscriptions = sorted
+		if output.Subscriptions == nil {
+			output.Subscriptions = []webhook.SubscriptionInfo{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	case "csv":
+		return writeListCSV(out, resp.Subscriptions, config.Sort)
+	}
+
+	fmt.Fprintf(out, "⏰ %d subscription(s) expire within %gh (until %s)\n\n", resp.Total, resp.WithinHours, resp.Until)
+	if len(resp.Subscriptions) == 0 {
+		fmt.Fprintln(out, "No subscriptions found.")
+		return nil
+	}
+
+	if err := listColumns.Render(out, resp.Subscriptions, config.Columns, config.Sort); err != nil {
+		return err
+	}
+	if more := resp.Total - len(resp.Subscriptions); more > 0 {
+		fmt.Fprintf(out, "\n%d more expire within the window; raise -limit to list them\n", more)
+	}
+	return nil
+}
+
+// fetchSubscriptions fetches one page when a limit or page token is set, and
+// otherwise every page, returning the combined list
+func fetchSubscriptions(c *client.Client, config ListConfig) (*webhook.SubscriptionsListResponse, error) {
+	opts := client.ListOptions{Status: config.Status, PageToken: config.PageToken}
+	if serverSort, ok := serverSortKeys[strings.TrimPrefix(config.Sort, "-")]; ok {
+		if strings.HasPrefix(config.Sort, "-") {
+			serverSort = "-" + serverSort
+		}
+		opts.Sort = serverSort
+	}
+
+	if config.Limit > 0 || config.PageToken != "" {
+		opts.Limit = config.Limit
+		return c.ListSubscriptionsPage(opts)
+	}
+
+	opts.Limit = listPageSize
+	resp, err := c.ListSubscriptionsPage(opts)
+	if err != nil {
+		return nil, err
+	}
+	for resp.NextPageToken != "" {
+		opts.PageToken = resp.NextPageToken
+		page, err := c.ListSubscriptionsPage(opts)
+		if err != nil {
+			return nil, err
+		}
+		resp.Subscriptions = append(resp.Subscriptions, page.Subscriptions...)
+		resp.NextPageToken = page.NextPageToken
+	}
+	return resp, nil
+}
+
+// writeListJSON writes the full list response as indented JSON for piping into jq
+func writeListJSON(w io.Writer, resp *webhook.SubscriptionsListResponse, sortKey string) error {
+	sorted, err := listColumns.Sort(resp.Subscriptions, sortKey)
+	if err != nil {
+		return err
+	}
+
+	output := *resp
+	output.Subscriptions = sorted
+	if output.Subscriptions == nil {
+		output.Subscriptions = []webhook.SubscriptionInfo{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// writeListCSV writes one row per subscription with a header row of field names
+func writeListCSV(w io.Writer, subscriptions []webhook.SubscriptionInfo, sortKey string) error {
+	sorted, err := listColumns.Sort(subscriptions, sortKey)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	headers := make([]string, len(listCSVFields))
+	for i, field := range listCSVFields {
+		headers[i] = field.Header
+	}
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+
+	for _, sub := range sorted {
+		record := make([]string, len(listCSVFields))
+		for i, field := range listCSVFields {
+			record[i] = field.Value(sub)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// valueOrDash renders empty values as a dash so table columns stay aligned
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -109,4 +112,295 @@ func TestList_ServerError(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for server error, got nil")
 	}
-}
\ No newline at end of file
+}
+func TestList_ColumnsAndSort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+			Subscriptions: []webhook.SubscriptionInfo{
+				{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", ExpiresAt: "2024-01-22T15:30:00Z", DaysUntilExpiry: 0.9, LastVideoID: "abc123", LastRunURL: "https://github.com/o/r/actions/runs/1"},
+				{ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", ExpiresAt: "2024-01-21T15:30:00Z", DaysUntilExpiry: -0.1},
+			},
+			Total: 2,
+		})
+	}))
+	defer server.Close()
+
+	config := ListConfig{
+		BaseURL: server.URL,
+		Timeout: 30 * time.Second,
+		Format:  "table",
+		Columns: []string{"channel", "expires", "next_renewal", "last_video", "health", "last_notification", "last_run"},
+		Sort:    "expires",
+	}
+
+	if err := List(config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	config.Columns = []string{"bogus"}
+	if err := List(config); err == nil {
+		t.Fatal("Expected error for unknown column, got nil")
+	}
+}
+
+func TestList_LifecycleStatuses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+			Subscriptions: []webhook.SubscriptionInfo{
+				{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", ChannelName: "Some Creator", Status: "pending"},
+				{ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: "expiring", ExpiresAt: "2024-01-22T15:30:00Z", DaysUntilExpiry: 0.3},
+				{ChannelID: "UCBJycsmduvYEL83R_U4JriQ", Status: "verified", ExpiresAt: "2024-01-25T15:30:00Z", DaysUntilExpiry: 3.3},
+			},
+			Total:   3,
+			Active:  2,
+			Pending: 1,
+		})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	config := ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Format: "table", Output: &out}
+	if err := List(config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := out.String()
+	for _, expected := range []string{"NAME", "Some Creator", "Pending: 1", "⏳ pending", "⏰ expiring", "✅ verified"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "pending") && strings.Contains(line, "expired") {
+			t.Errorf("Pending subscription without a lease should not show as expired: %q", line)
+		}
+	}
+}
+
+func newListFormatServer() *httptest.Server {
+	hours := 2.5
+	succeeded := true
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+			Subscriptions: []webhook.SubscriptionInfo{
+				{
+					ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "active", ExpiresAt: "2024-01-22T15:30:00Z",
+					DaysUntilExpiry: 0.9, LeaseSeconds: 86400, RenewalAttempts: 1, LastVideoID: "abc,123",
+					NextRenewalAt: "2024-01-22T03:30:00Z",
+					SubscriptionHealth: webhook.SubscriptionHealth{
+						Indicator: "healthy", HoursSinceNotification: &hours, LastRenewalSucceeded: &succeeded,
+					},
+				},
+				{ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: "expired", ExpiresAt: "2024-01-21T15:30:00Z", DaysUntilExpiry: -0.1},
+			},
+			Total:   2,
+			Active:  1,
+			Expired: 1,
+		})
+	}))
+}
+
+func TestList_JSONFormat(t *testing.T) {
+	server := newListFormatServer()
+	defer server.Close()
+
+	var out bytes.Buffer
+	config := ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Format: "json", Sort: "expires", Output: &out}
+	if err := List(config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var resp webhook.SubscriptionsListResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected valid JSON output, got %v:\n%s", err, out.String())
+	}
+	if resp.Total != 2 || len(resp.Subscriptions) != 2 {
+		t.Fatalf("Expected 2 subscriptions, got total=%d len=%d", resp.Total, len(resp.Subscriptions))
+	}
+	if resp.Subscriptions[0].ChannelID != "UC_x5XG1OV2P6uZZ5FSM9Ttw" {
+		t.Errorf("Expected rows sorted by expiry, got %s first", resp.Subscriptions[0].ChannelID)
+	}
+	if resp.Subscriptions[1].LeaseSeconds != 86400 || resp.Subscriptions[1].RenewalAttempts != 1 {
+		t.Errorf("Expected lease_seconds and renewal_attempts in JSON output, got %+v", resp.Subscriptions[1])
+	}
+	if strings.Contains(out.String(), "Subscription Summary") {
+		t.Error("JSON output should not include the table summary")
+	}
+}
+
+func TestList_CSVFormat(t *testing.T) {
+	server := newListFormatServer()
+	defer server.Close()
+
+	var out bytes.Buffer
+	config := ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Format: "csv", Output: &out}
+	if err := List(config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV output, got %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d records", len(records))
+	}
+
+	header := strings.Join(records[0], ",")
+	expectedHeader := "channel_id,channel_name,status,expires_at,days_until_expiry,lease_seconds,renewal_attempts,subscribed_at,last_renewal,next_renewal_at," +
+		"health,health_reasons,last_notification_at,hours_since_notification,last_renewal_succeeded,last_video_id,last_run_url,hub_url,topic_type"
+	if header != expectedHeader {
+		t.Errorf("Unexpected CSV header:\n got %s\nwant %s", header, expectedHeader)
+	}
+
+	row := map[string]string{}
+	for i, name := range records[0] {
+		row[name] = records[1][i]
+	}
+	expected := map[string]string{
+		"channel_id":               "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		"status":                   "active",
+		"lease_seconds":            "86400",
+		"renewal_attempts":         "1",
+		"next_renewal_at":          "2024-01-22T03:30:00Z",
+		"days_until_expiry":        "0.90",
+		"hours_since_notification": "2.50",
+		"last_renewal_succeeded":   "true",
+		"last_video_id":            "abc,123",
+	}
+	for name, value := range expected {
+		if row[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, row[name])
+		}
+	}
+}
+
+func TestList_UnknownFormat(t *testing.T) {
+	config := ListConfig{BaseURL: "http://unused", Timeout: time.Second, Format: "xml"}
+	err := List(config)
+	if err == nil || !strings.Contains(err.Error(), "unknown format") {
+		t.Fatalf("Expected unknown format error, got %v", err)
+	}
+}
+
+func TestList_FormatSortError(t *testing.T) {
+	server := newListFormatServer()
+	defer server.Close()
+
+	for _, format := range []string{"json", "csv"} {
+		config := ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Format: format, Sort: "bogus", Output: &bytes.Buffer{}}
+		if err := List(config); err == nil {
+			t.Errorf("Expected error for unknown sort column with -format %s", format)
+		}
+	}
+}
+
+func TestList_FetchesEveryPage(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+
+		response := webhook.SubscriptionsListResponse{Total: 3, Active: 3}
+		if r.URL.Query().Get("page_token") == "" {
+			response.Subscriptions = []webhook.SubscriptionInfo{{ChannelID: "UC1", Status: "active"}, {ChannelID: "UC2", Status: "active"}}
+			response.NextPageToken = "next"
+		} else {
+			response.Subscriptions = []webhook.SubscriptionInfo{{ChannelID: "UC3", Status: "active"}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := List(ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Format: "json", Status: "active", Sort: "-expires", Output: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 page requests, got %d: %v", len(queries), queries)
+	}
+	if queries[0] != "limit=100&sort=-expiry&status=active" {
+		t.Errorf("Unexpected first page query: %s", queries[0])
+	}
+	if !strings.Contains(queries[1], "page_token=next") {
+		t.Errorf("Expected second request to send the page token, got: %s", queries[1])
+	}
+
+	var resp webhook.SubscriptionsListResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if len(resp.Subscriptions) != 3 || resp.NextPageToken != "" {
+		t.Errorf("Expected all 3 subscriptions without a page token, got %+v", resp)
+	}
+}
+
+func TestList_LimitShowsOnePage(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+			Subscriptions: []webhook.SubscriptionInfo{{ChannelID: "UC1", Status: "active"}},
+			Total:         2,
+			Active:        2,
+			NextPageToken: "b2Zmc2V0OjE",
+		})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := List(ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Limit: 1, Output: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(queries) != 1 || queries[0] != "limit=1" {
+		t.Errorf("Expected a single limited request, got %v", queries)
+	}
+	if !strings.Contains(out.String(), "-page-token b2Zmc2V0OjE") {
+		t.Errorf("Expected the next page token in output, got:\n%s", out.String())
+	}
+}
+
+func TestList_Expiring(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		json.NewEncoder(w).Encode(webhook.ExpiringSubscriptionsResponse{
+			Subscriptions: []webhook.SubscriptionInfo{
+				{ChannelID: "UCsoon", Status: "active", ExpiresAt: "2025-01-22T10:30:00Z", DaysUntilExpiry: 0.1},
+				{ChannelID: "UClater", Status: "active", ExpiresAt: "2025-01-22T14:30:00Z", DaysUntilExpiry: 0.2},
+			},
+			Total:       3,
+			WithinHours: 6,
+			Until:       "2025-01-22T16:00:00Z",
+		})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := List(ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Expiring: true, Within: 6 * time.Hour, Limit: 2, Output: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(requests) != 1 || requests[0] != "/subscriptions/expiring?limit=2&within_hours=6" {
+		t.Errorf("Expected a single expiring request, got %v", requests)
+	}
+	output := out.String()
+	if !strings.Contains(output, "3 subscription(s) expire within 6h") {
+		t.Errorf("Expected the expiring summary, got:\n%s", output)
+	}
+	if strings.Index(output, "UCsoon") > strings.Index(output, "UClater") {
+		t.Errorf("Expected the server's soonest-first order, got:\n%s", output)
+	}
+	if !strings.Contains(output, "1 more expire within the window") {
+		t.Errorf("Expected a note about the subscriptions past the limit, got:\n%s", output)
+	}
+
+	err = List(ListConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Expiring: true, Status: "active"})
+	if err == nil || !strings.Contains(err.Error(), "-expiring cannot be combined") {
+		t.Errorf("Expected -expiring with -status to be rejected, got %v", err)
+	}
+}
@@ -1,55 +1,183 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/samsoir/youtube-webhook/cli/client"
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
 )
 
 // RenewConfig holds the configuration for the renew command
 type RenewConfig struct {
-	BaseURL string
-	Timeout time.Duration
-	Verbose bool
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	Timeout       time.Duration
+	Verbose       bool
+	ChannelIDs    []string  // Only renew these channels
+	Force         bool      // Renew regardless of expiry and failure backoff
+	Format        string    // "table" (default) or "json"
+	Output        io.Writer // Defaults to os.Stdout
+	Progress      io.Writer // Shows a progress bar while the service renews; nil shows none
 }
 
+// renewColumns are the columns of the per-channel results table
+var renewColumns = NewTable([]Column[webhook.RenewalResult]{
+	{Key: "channel", Header: "CHANNEL", Value: func(r webhook.RenewalResult) string { return r.ChannelID }},
+	{Key: "old_expiry", Header: "OLD EXPIRY", Value: func(r webhook.RenewalResult) string { return valueOrDash(r.PreviousExpiresAt) }},
+	{Key: "new_expiry", Header: "NEW EXPIRY", Value: func(r webhook.RenewalResult) string { return valueOrDash(r.RequestedExpiresAt) }},
+	{Key: "attempts", Header: "ATTEMPTS", Value: func(r webhook.RenewalResult) string { return strconv.Itoa(r.AttemptCount) }},
+	{Key: "result", Header: "RESULT", Value: renewResultText}, // Last, so failure reasons are shown in full
+}, []string{"channel", "old_expiry", "new_expiry", "attempts", "result"})
+
 // Renew triggers renewal of expiring subscriptions
 func Renew(config RenewConfig) error {
-	c := client.NewClient(config.BaseURL, config.Timeout)
-	
-	resp, err := c.RenewSubscriptions()
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "table"
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (available: table, json)", config.Format)
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	bar := newRenewProgress(config.Progress)
+	resp, err := c.RenewChannelsStream(webhook.RenewRequest{ChannelIDs: config.ChannelIDs, Force: config.Force}, bar.update)
+	bar.finish()
 	if err != nil {
 		return fmt.Errorf("failed to renew subscriptions: %w", err)
 	}
 
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resp)
+	}
+
 	// Print summary
-	fmt.Printf("🔄 Renewal Summary\n")
-	fmt.Printf("   Checked: %d | Candidates: %d | Succeeded: %d | Failed: %d\n\n",
-		resp.TotalChecked, resp.RenewalsCandidates, 
+	fmt.Fprintf(out, "🔄 Renewal Summary\n")
+	if resp.Forced {
+		fmt.Fprintf(out, "   Forced: renewed regardless of expiry\n")
+	}
+	fmt.Fprintf(out, "   Checked: %d | Candidates: %d | Succeeded: %d | Failed: %d\n\n",
+		resp.TotalChecked, resp.RenewalsCandidates,
 		resp.RenewalsSucceeded, resp.RenewalsFailed)
+	if resp.RenewalsDeferred > 0 {
+		fmt.Fprintf(out, "   Deferred to the next run: %d\n\n", resp.RenewalsDeferred)
+	}
+	if resp.RenewalsBackingOff > 0 {
+		fmt.Fprintf(out, "   Backing off after failures: %d\n\n", resp.RenewalsBackingOff)
+	}
+	if len(resp.Quarantined) > 0 {
+		fmt.Fprintf(out, "🚫 Quarantined (not renewed until reactivated): %s\n\n", strings.Join(resp.Quarantined, ", "))
+	}
+	if len(resp.ExpiryAlerts) > 0 {
+		fmt.Fprintf(out, "🔔 Alerted as expiring without renewal: %s\n\n", strings.Join(resp.ExpiryAlerts, ", "))
+	}
+	if resp.NextRenewalAt != "" {
+		fmt.Fprintf(out, "   Next renewal due: %s\n\n", resp.NextRenewalAt)
+	}
+	if config.Verbose && resp.Report != "" {
+		fmt.Fprintf(out, "   Report: %s\n\n", resp.Report)
+	}
 
 	if len(resp.Results) == 0 {
-		fmt.Println("No subscriptions needed renewal.")
+		fmt.Fprintln(out, "No subscriptions needed renewal.")
 		return nil
 	}
 
-	// Print results if verbose or if there were any failures
+	// Print results, in the order the service renewed them, if verbose or if there were any failures
 	if config.Verbose || resp.RenewalsFailed > 0 {
-		fmt.Println("Results:")
-		for _, result := range resp.Results {
-			if result.Success {
-				fmt.Printf("  ✅ %s - Renewed", result.ChannelID)
-				if result.NewExpiryTime != "" {
-					fmt.Printf(" (expires: %s)", result.NewExpiryTime)
-				}
-				fmt.Println()
-			} else {
-				fmt.Printf("  ❌ %s - Failed: %s\n", 
-					result.ChannelID, result.Message)
-			}
-		}
+		return renewColumns.Render(out, resp.Results, nil, "")
 	}
-	
 	return nil
-}
\ No newline at end of file
+}
+
+// renewResultText describes how a channel's renewal went
+func renewResultText(r webhook.RenewalResult) string {
+	if r.Success {
+		return fmt.Sprintf("✅ renewed in %dms%s, pending hub verification", r.DurationMs, hubRetriesNote(r.HubRetries))
+	}
+	text := fmt.Sprintf("❌ %s", r.Message)
+	if r.Quarantined {
+		text += "; quarantined, reactivate once the cause is fixed"
+	} else if r.NextAttemptAt != "" {
+		text += "; next attempt after " + r.NextAttemptAt
+	}
+	return text
+}
+
+// hubRetriesNote describes the hub retries a renewal needed, if any
+func hubRetriesNote(retries int) string {
+	switch retries {
+	case 0:
+		return ""
+	case 1:
+		return " after 1 hub retry"
+	}
+	return fmt.Sprintf(" after %d hub retries", retries)
+}
+
+// renewProgressWidth is the number of cells in the progress bar
+const renewProgressWidth = 30
+
+// renewProgress redraws a one-line progress bar as the service streams renewal events
+type renewProgress struct {
+	w      io.Writer
+	total  int
+	done   int
+	failed int
+	drawn  bool
+}
+
+// newRenewProgress returns a progress bar drawn on w, which does nothing when w is nil
+func newRenewProgress(w io.Writer) *renewProgress {
+	return &renewProgress{w: w}
+}
+
+// update records an event and redraws the bar
+func (p *renewProgress) update(event webhook.RenewalEvent) {
+	switch event.Type {
+	case webhook.RenewalEventStart:
+		p.total = event.Renewals
+	case webhook.RenewalEventResult:
+		p.done++
+		if event.Result != nil && !event.Result.Success {
+			p.failed++
+		}
+	default:
+		return
+	}
+	if p.w == nil || p.total == 0 {
+		return
+	}
+
+	filled := renewProgressWidth * p.done / p.total
+	if filled > renewProgressWidth {
+		filled = renewProgressWidth
+	}
+	fmt.Fprintf(p.w, "\r[%s%s] %d/%d channels", strings.Repeat("#", filled), strings.Repeat("-", renewProgressWidth-filled), p.done, p.total)
+	if p.failed > 0 {
+		fmt.Fprintf(p.w, ", %d failed", p.failed)
+	}
+	p.drawn = true
+}
+
+// finish ends the bar's line, so later output starts on a fresh one
+func (p *renewProgress) finish() {
+	if p.drawn {
+		fmt.Fprintln(p.w)
+	}
+}
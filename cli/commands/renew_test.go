@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -148,4 +150,142 @@ func TestRenew_ServerError(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for server error, got nil")
 	}
-}
\ No newline at end of file
+}
+func TestRenew_SendsAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "Missing API credentials"})
+			return
+		}
+		json.NewEncoder(w).Encode(webhook.RenewalSummaryResponse{Status: "success"})
+	}))
+	defer server.Close()
+
+	if err := Renew(RenewConfig{BaseURL: server.URL, APIKey: "key-1", Timeout: 60 * time.Second}); err != nil {
+		t.Fatalf("Expected no error with API key, got %v", err)
+	}
+
+	err := Renew(RenewConfig{BaseURL: server.URL, Timeout: 60 * time.Second})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error without API key, got %v", err)
+	}
+}
+
+func TestRenew_SignsRequests(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhook.RequestSignatureHeader)
+		json.NewEncoder(w).Encode(webhook.RenewalSummaryResponse{Status: "success"})
+	}))
+	defer server.Close()
+
+	if err := Renew(RenewConfig{BaseURL: server.URL, SigningSecret: "shared-secret", Timeout: 60 * time.Second}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Errorf("Expected a signed request, got signature %q", signature)
+	}
+}
+
+func TestRenew_SelectedChannels(t *testing.T) {
+	var received webhook.RenewRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(webhook.RenewalSummaryResponse{Status: "success", Forced: received.Force})
+	}))
+	defer server.Close()
+
+	config := RenewConfig{
+		BaseURL:    server.URL,
+		Timeout:    60 * time.Second,
+		ChannelIDs: []string{"UCXuqSBlHAE6Xw-yeJA0Tunw", "UCBJycsmduvYEL83R_U4JriQ"},
+		Force:      true,
+	}
+
+	if err := Renew(config); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !received.Force || len(received.ChannelIDs) != 2 || received.ChannelIDs[1] != "UCBJycsmduvYEL83R_U4JriQ" {
+		t.Errorf("Expected forced renewal of both channels, got %+v", received)
+	}
+}
+
+func TestRenew_ProgressAndTable(t *testing.T) {
+	results := []webhook.RenewalResult{
+		{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Success: true, DurationMs: 120,
+			PreviousExpiresAt: "2025-01-21T14:00:00Z", RequestedExpiresAt: "2025-01-31T10:30:00Z"},
+		{ChannelID: "UCBJycsmduvYEL83R_U4JriQ", Message: "PubSubHubbub renewal failed: boom", AttemptCount: 2,
+			PreviousExpiresAt: "2025-01-21T15:00:00Z", NextAttemptAt: "2025-01-21T11:30:00Z"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != webhook.RenewalStreamContentType {
+			t.Errorf("Expected a streamed renewal, got Accept %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", webhook.RenewalStreamContentType)
+		encoder := json.NewEncoder(w)
+		encoder.Encode(webhook.RenewalEvent{Type: webhook.RenewalEventStart, Renewals: 2})
+		for i := range results {
+			encoder.Encode(webhook.RenewalEvent{Type: webhook.RenewalEventResult, Result: &results[i]})
+		}
+		encoder.Encode(webhook.RenewalEvent{Type: webhook.RenewalEventSummary, Summary: &webhook.RenewalSummaryResponse{
+			Status: "success", RenewalsCandidates: 2, RenewalsSucceeded: 1, RenewalsFailed: 1, Results: results,
+		}})
+	}))
+	defer server.Close()
+
+	t.Run("table", func(t *testing.T) {
+		var out, progress bytes.Buffer
+		err := Renew(RenewConfig{BaseURL: server.URL, Timeout: 60 * time.Second, Output: &out, Progress: &progress})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !strings.HasSuffix(progress.String(), "] 2/2 channels, 1 failed\n") || !strings.Contains(progress.String(), "] 1/2 channels") {
+			t.Errorf("Expected the progress bar to count both renewals, got %q", progress.String())
+		}
+		for _, want := range []string{
+			"CHANNEL", "OLD EXPIRY", "NEW EXPIRY", "ATTEMPTS", "RESULT",
+			"2025-01-21T14:00:00Z  2025-01-31T10:30:00Z",
+			"renewed in 120ms, pending hub verification",
+			"2025-01-21T15:00:00Z  -",
+			"PubSubHubbub renewal failed: boom; next attempt after 2025-01-21T11:30:00Z",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out, progress bytes.Buffer
+		err := Renew(RenewConfig{BaseURL: server.URL, Timeout: 60 * time.Second, Format: "json", Output: &out, Progress: &progress})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var resp webhook.RenewalSummaryResponse
+		if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+			t.Fatalf("Expected JSON output, got %v:\n%s", err, out.String())
+		}
+		if len(resp.Results) != 2 || resp.Results[0].RequestedExpiresAt != "2025-01-31T10:30:00Z" {
+			t.Errorf("Expected both results, got %+v", resp.Results)
+		}
+	})
+
+	t.Run("unknown_format", func(t *testing.T) {
+		err := Renew(RenewConfig{BaseURL: server.URL, Timeout: 60 * time.Second, Format: "csv"})
+		if err == nil || !strings.Contains(err.Error(), "unknown format") {
+			t.Errorf("Expected an unknown format error, got %v", err)
+		}
+	})
+}
+
+func TestHubRetriesNote(t *testing.T) {
+	tests := map[int]string{0: "", 1: " after 1 hub retry", 3: " after 3 hub retries"}
+	for retries, want := range tests {
+		if got := hubRetriesNote(retries); got != want {
+			t.Errorf("hubRetriesNote(%d) = %q, want %q", retries, got, want)
+		}
+	}
+}
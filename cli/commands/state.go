@@ -0,0 +1,315 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats a subscription state file can be written in
+const (
+	StateFormatJSON = "json"
+	StateFormatYAML = "yaml"
+)
+
+// ExportConfig holds the configuration for the export command
+type ExportConfig struct {
+	BaseURL    string
+	AdminToken string // The service's ADMIN_TOKEN
+	Timeout    time.Duration
+	File       string    // Written with owner-only permissions; empty writes the state to Output
+	Format     string    // "json" or "yaml"; defaults to the file's extension, then json
+	Output     io.Writer // Defaults to os.Stdout
+}
+
+// ImportConfig holds the configuration for the import command
+type ImportConfig struct {
+	BaseURL    string
+	AdminToken string // The service's ADMIN_TOKEN
+	Timeout    time.Duration
+	File       string    // State file written by export
+	Format     string    // "json" or "yaml"; defaults to the file's extension, then json
+	Mode       string    // webhook.ImportModeReplace (default) or webhook.ImportModeMerge
+	DryRun     bool      // Only show what the import would change
+	Yes        bool      // Skip the confirmation prompt
+	Input      io.Reader // Answers the confirmation prompt; defaults to os.Stdin
+	Output     io.Writer // Defaults to os.Stdout
+}
+
+// RestoreBackupConfig holds the configuration for the restore-backup command
+type RestoreBackupConfig struct {
+	BaseURL    string
+	AdminToken string // The service's ADMIN_TOKEN
+	Timeout    time.Duration
+	Name       string    // Backup to restore; empty lists the backups
+	DryRun     bool      // Only show what the restore would change
+	Yes        bool      // Skip the confirmation prompt
+	Input      io.Reader // Answers the confirmation prompt; defaults to os.Stdin
+	Output     io.Writer // Defaults to os.Stdout
+}
+
+// stateBackupColumns are the columns of the backup table
+var stateBackupColumns = NewTable([]Column[webhook.StateBackup]{
+	{Key: "name", Header: "NAME", Value: func(b webhook.StateBackup) string { return b.Name }},
+	{Key: "created", Header: "CREATED", Value: func(b webhook.StateBackup) string {
+		return b.CreatedAt.Local().Format("2006-01-02 15:04:05")
+	}},
+	{Key: "subscriptions", Header: "SUBSCRIPTIONS", Value: func(b webhook.StateBackup) string { return fmt.Sprint(b.Subscriptions) }},
+	{Key: "bytes", Header: "BYTES", Value: func(b webhook.StateBackup) string { return fmt.Sprint(b.Bytes) }},
+	{Key: "version", Header: "SCHEMA", Value: func(b webhook.StateBackup) string { return b.Version }},
+}, []string{"name", "created", "subscriptions", "bytes", "version"})
+
+// stateFormat resolves the format of a state file, defaulting to its extension
+func stateFormat(format, file string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			return StateFormatYAML, nil
+		}
+		return StateFormatJSON, nil
+	}
+	if format != StateFormatJSON && format != StateFormatYAML {
+		return "", fmt.Errorf("unknown format %q (available: json, yaml)", format)
+	}
+	return format, nil
+}
+
+// Export downloads the full subscription state, including subscription secrets, so it
+// can be backed up or imported into another deployment
+func Export(config ExportConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	format, err := stateFormat(config.Format, config.File)
+	if err != nil {
+		return err
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAdminToken(config.AdminToken)
+	resp, err := c.ExportState()
+	if err != nil {
+		return fmt.Errorf("failed to export subscription state: %w", err)
+	}
+
+	data, err := encodeState(resp.State, format)
+	if err != nil {
+		return err
+	}
+
+	if config.File == "" {
+		_, err := out.Write(data)
+		return err
+	}
+	if err := os.WriteFile(config.File, data, 0600); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	fmt.Fprintf(out, "💾 Exported %d subscriptions to %s\n", resp.Count, config.File)
+	return nil
+}
+
+// Import uploads a state file written by export. The server validates the state and
+// reports what would change, which is confirmed before anything is saved.
+func Import(config ImportConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	input := config.Input
+	if input == nil {
+		input = os.Stdin
+	}
+
+	format, err := stateFormat(config.Format, config.File)
+	if err != nil {
+		return err
+	}
+	mode := config.Mode
+	if mode == "" {
+		mode = webhook.ImportModeReplace
+	}
+	if mode != webhook.ImportModeReplace && mode != webhook.ImportModeMerge {
+		return fmt.Errorf("unknown mode %q (available: %s, %s)", config.Mode, webhook.ImportModeReplace, webhook.ImportModeMerge)
+	}
+
+	state, err := ReadStateFile(config.File, format)
+	if err != nil {
+		return err
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAdminToken(config.AdminToken)
+	plan, err := c.ImportState(state, mode, true)
+	if err != nil {
+		return fmt.Errorf("failed to validate subscription state: %w", err)
+	}
+
+	fmt.Fprintf(out, "📦 %s: %d subscriptions (%s)\n", config.File, plan.Imported, mode)
+	fmt.Fprintf(out, "   Added: %d | Updated: %d | Removed: %d | Total after import: %d\n",
+		plan.Added, plan.Updated, plan.Removed, plan.Total)
+	if config.DryRun {
+		fmt.Fprintln(out, "Dry run; nothing was imported.")
+		return nil
+	}
+
+	if !config.Yes && !confirm(input, out, "Import and overwrite the stored subscription state?") {
+		fmt.Fprintln(out, "Import cancelled.")
+		return nil
+	}
+
+	result, err := c.ImportState(state, mode, false)
+	if err != nil {
+		return fmt.Errorf("failed to import subscription state: %w", err)
+	}
+	fmt.Fprintf(out, "✅ Imported %d subscriptions (%d stored)\n", result.Imported, result.Total)
+	fmt.Fprintln(out, "   Hub subscriptions are unchanged; run renew -force if the callback URL differs.")
+	return nil
+}
+
+// RestoreBackup lists the subscription state backups the service took on every save,
+// or restores the named one. The server reports what the restore would change, which is
+// confirmed before anything is saved.
+func RestoreBackup(config RestoreBackupConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	input := config.Input
+	if input == nil {
+		input = os.Stdin
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAdminToken(config.AdminToken)
+
+	if config.Name == "" {
+		resp, err := c.ListStateBackups()
+		if err != nil {
+			return fmt.Errorf("failed to list state backups: %w", err)
+		}
+		if len(resp.Backups) == 0 {
+			fmt.Fprintf(out, "No state backups (kept for %d days).\n", resp.RetentionDays)
+			return nil
+		}
+		fmt.Fprintf(out, "🗄️  %d state backups (newest %d kept for %d days, newest first)\n\n", resp.Count, resp.KeepCount, resp.RetentionDays)
+		if err := stateBackupColumns.Render(out, resp.Backups, nil, ""); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "\nRestore one with: restore-backup -name <NAME>")
+		return nil
+	}
+
+	plan, err := c.RestoreStateBackup(config.Name, true)
+	if err != nil {
+		return fmt.Errorf("failed to read state backup: %w", err)
+	}
+
+	fmt.Fprintf(out, "📦 %s: %d subscriptions, taken %s\n", plan.Backup.Name, plan.Imported,
+		plan.Backup.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(out, "   Added: %d | Updated: %d | Removed: %d | Total after restore: %d\n",
+		plan.Added, plan.Updated, plan.Removed, plan.Total)
+	if config.DryRun {
+		fmt.Fprintln(out, "Dry run; nothing was restored.")
+		return nil
+	}
+
+	if !config.Yes && !confirm(input, out, "Restore this backup over the stored subscription state?") {
+		fmt.Fprintln(out, "Restore cancelled.")
+		return nil
+	}
+
+	result, err := c.RestoreStateBackup(config.Name, false)
+	if err != nil {
+		return fmt.Errorf("failed to restore state backup: %w", err)
+	}
+	fmt.Fprintf(out, "✅ Restored %s (%d subscriptions stored)\n", result.Backup.Name, result.Total)
+	fmt.Fprintln(out, "   Hub subscriptions are unchanged; run renew -force to resubscribe restored channels.")
+	return nil
+}
+
+// confirm asks a yes/no question, treating anything but y or yes as no
+func confirm(input io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(input).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// ReadStateFile reads a subscription state written by export in the given format
+func ReadStateFile(path, format string) (*webhook.SubscriptionState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+
+	if format == StateFormatYAML {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing state file as YAML: %w", err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("parsing state file as YAML: %w", err)
+		}
+	}
+
+	var state webhook.SubscriptionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing state file: %w", err)
+	}
+	if state.Subscriptions == nil {
+		return nil, fmt.Errorf("state file has no subscriptions object")
+	}
+	return &state, nil
+}
+
+// encodeState renders a state in the given format. YAML is converted from the JSON
+// encoding so both formats use the same field names.
+func encodeState(state *webhook.SubscriptionState, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding state: %w", err)
+	}
+	if format == StateFormatJSON {
+		return append(data, '\n'), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("encoding state: %w", err)
+	}
+	return yaml.Marshal(yamlNumbers(doc))
+}
+
+// yamlNumbers replaces the json.Numbers in a decoded JSON document with ints or floats,
+// which YAML would otherwise write as quoted strings
+func yamlNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = yamlNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
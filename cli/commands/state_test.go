@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// newStateServer serves the webhook function with in-memory storage holding two
+// subscriptions, accepting "admin-secret" as the admin token
+func newStateServer(t *testing.T) (*httptest.Server, *webhook.Dependencies) {
+	t.Setenv("ADMIN_TOKEN", "admin-secret")
+
+	deps := webhook.CreateTestDependencies()
+	state := &webhook.SubscriptionState{Subscriptions: map[string]*webhook.Subscription{
+		"UCXuqSBlHAE6Xw-yeJA0Tunw": {
+			ChannelID:    "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:       webhook.StatusActive,
+			LeaseSeconds: 1000000,
+			ExpiresAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Secret:       "hub-secret",
+			Repositories: []webhook.RepositoryTarget{{RepoOwner: "octo", RepoName: "site"}},
+		},
+		"UC_x5XG1OV2P6uZZ5FSM9Ttw": {ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: webhook.StatusExpired},
+	}}
+	if err := deps.StorageClient.SaveSubscriptionState(context.Background(), state); err != nil {
+		t.Fatalf("Expected no error seeding state, got %v", err)
+	}
+
+	webhook.SetDependencies(deps)
+	t.Cleanup(func() { webhook.SetDependencies(nil) })
+	return httptest.NewServer(http.HandlerFunc(webhook.YouTubeWebhook)), deps
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	for _, ext := range []string{".json", ".yaml"} {
+		t.Run(strings.TrimPrefix(ext, "."), func(t *testing.T) {
+			server, deps := newStateServer(t)
+			defer server.Close()
+
+			file := filepath.Join(t.TempDir(), "state"+ext)
+			var out bytes.Buffer
+			err := Export(ExportConfig{BaseURL: server.URL, AdminToken: "admin-secret", Timeout: 5 * time.Second, File: file, Output: &out})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !strings.Contains(out.String(), "Exported 2 subscriptions") {
+				t.Errorf("Expected export summary, got %q", out.String())
+			}
+			if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("Expected owner-only state file, got %v %v", info, err)
+			}
+
+			// Restore the export into emptied storage
+			empty := &webhook.SubscriptionState{Subscriptions: map[string]*webhook.Subscription{}}
+			if err := deps.StorageClient.SaveSubscriptionState(context.Background(), empty); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			out.Reset()
+			err = Import(ImportConfig{BaseURL: server.URL, AdminToken: "admin-secret", Timeout: 5 * time.Second, File: file, Yes: true, Output: &out})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !strings.Contains(out.String(), "Added: 2 | Updated: 0 | Removed: 0") {
+				t.Errorf("Expected import plan, got %q", out.String())
+			}
+
+			restored, err := deps.StorageClient.GetSubscription(context.Background(), "UCXuqSBlHAE6Xw-yeJA0Tunw")
+			if err != nil || restored == nil {
+				t.Fatalf("Expected restored subscription, got %v %v", restored, err)
+			}
+			if restored.Secret != "hub-secret" || restored.LeaseSeconds != 1000000 || len(restored.Repositories) != 1 ||
+				!restored.ExpiresAt.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+				t.Errorf("Expected subscription to round-trip, got %+v", restored)
+			}
+		})
+	}
+}
+
+func TestExport_Stdout(t *testing.T) {
+	server, _ := newStateServer(t)
+	defer server.Close()
+
+	var out bytes.Buffer
+	if err := Export(ExportConfig{BaseURL: server.URL, AdminToken: "admin-secret", Timeout: 5 * time.Second, Format: "yaml", Output: &out}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "lease_seconds: 1000000") || !strings.Contains(out.String(), `expires_at: "2026-01-02T03:04:05Z"`) {
+		t.Errorf("Expected YAML state, got %q", out.String())
+	}
+
+	err := Export(ExportConfig{BaseURL: server.URL, AdminToken: "wrong", Timeout: 5 * time.Second, Output: &out})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
+
+func TestImport_Confirmation(t *testing.T) {
+	server, deps := newStateServer(t)
+	defer server.Close()
+	storage := deps.StorageClient.(*webhook.MockStorageClient)
+
+	file := filepath.Join(t.TempDir(), "state.json")
+	content := `{"subscriptions": {"UCBJycsmduvYEL83R_U4JriQ": {"status": "active"}}}`
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := ImportConfig{BaseURL: server.URL, AdminToken: "admin-secret", Timeout: 5 * time.Second, File: file}
+
+	t.Run("declined", func(t *testing.T) {
+		saves := storage.SaveCallCount
+		var out bytes.Buffer
+		config.Input, config.Output = strings.NewReader("n\n"), &out
+		if err := Import(config); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "Removed: 2") || !strings.Contains(out.String(), "Import cancelled.") {
+			t.Errorf("Expected plan and cancellation, got %q", out.String())
+		}
+		if storage.SaveCallCount != saves {
+			t.Errorf("Expected nothing saved")
+		}
+	})
+
+	t.Run("dry_run", func(t *testing.T) {
+		saves := storage.SaveCallCount
+		var out bytes.Buffer
+		dryRun := config
+		dryRun.DryRun, dryRun.Output = true, &out
+		if err := Import(dryRun); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "Dry run") || storage.SaveCallCount != saves {
+			t.Errorf("Expected dry run without saving, got %q", out.String())
+		}
+	})
+
+	t.Run("merge_confirmed", func(t *testing.T) {
+		var out bytes.Buffer
+		merge := config
+		merge.Mode, merge.Input, merge.Output = webhook.ImportModeMerge, strings.NewReader("yes\n"), &out
+		if err := Import(merge); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "Imported 1 subscriptions (3 stored)") {
+			t.Errorf("Expected merge result, got %q", out.String())
+		}
+	})
+}
+
+func TestImport_Errors(t *testing.T) {
+	server, _ := newStateServer(t)
+	defer server.Close()
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name   string
+		config ImportConfig
+		want   string
+	}{
+		{"missing_file", ImportConfig{File: filepath.Join(dir, "missing.json")}, "reading state file"},
+		{"invalid_yaml", ImportConfig{File: write("bad.yaml", "subscriptions: [")}, "parsing state file as YAML"},
+		{"no_subscriptions", ImportConfig{File: write("empty.json", "{}")}, "no subscriptions object"},
+		{"unknown_format", ImportConfig{File: write("ok.json", `{"subscriptions": {}}`), Format: "xml"}, "unknown format"},
+		{"unknown_mode", ImportConfig{File: write("ok.json", `{"subscriptions": {}}`), Mode: "append"}, "unknown mode"},
+		{"rejected_by_server", ImportConfig{File: write("invalid.json", `{"subscriptions": {"bad-id": {"status": "active"}}}`)}, "invalid channel ID format"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.BaseURL, tc.config.AdminToken, tc.config.Timeout = server.URL, "admin-secret", 5*time.Second
+			tc.config.Yes, tc.config.Output = true, &bytes.Buffer{}
+			err := Import(tc.config)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	server, deps := newStateServer(t)
+	defer server.Close()
+	storage := deps.StorageClient.(*webhook.MockStorageClient)
+
+	// Store a backup holding one of the two seeded subscriptions
+	createdAt := time.Now().Add(-time.Hour).UTC()
+	name := "state-" + createdAt.Format("20060102150405") + ".json"
+	backup := `{"subscriptions": {"UCXuqSBlHAE6Xw-yeJA0Tunw": {"channel_id": "UCXuqSBlHAE6Xw-yeJA0Tunw", "status": "active"}}, "metadata": {"version": "2.0"}}`
+	index := `{"entries": [{"name": "` + name + `", "created_at": "` + createdAt.Format(time.RFC3339) + `", "subscriptions": 1, "version": "2.0"}]}`
+	ctx := context.Background()
+	if err := storage.WriteObject(ctx, "subscriptions/backups/"+name, []byte(backup)); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.WriteObject(ctx, "subscriptions/backups/index.json", []byte(index)); err != nil {
+		t.Fatal(err)
+	}
+	config := RestoreBackupConfig{BaseURL: server.URL, AdminToken: "admin-secret", Timeout: 5 * time.Second}
+
+	t.Run("list", func(t *testing.T) {
+		var out bytes.Buffer
+		list := config
+		list.Output = &out
+		if err := RestoreBackup(list); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "1 state backups") || !strings.Contains(out.String(), name) {
+			t.Errorf("Expected backup table, got %q", out.String())
+		}
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		saves := storage.SaveCallCount
+		var out bytes.Buffer
+		declined := config
+		declined.Name, declined.Input, declined.Output = name, strings.NewReader("n\n"), &out
+		if err := RestoreBackup(declined); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "Removed: 1 | Total after restore: 1") || !strings.Contains(out.String(), "Restore cancelled.") {
+			t.Errorf("Expected plan and cancellation, got %q", out.String())
+		}
+		if storage.SaveCallCount != saves {
+			t.Errorf("Expected nothing saved")
+		}
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		var out bytes.Buffer
+		confirmed := config
+		confirmed.Name, confirmed.Input, confirmed.Output = name, strings.NewReader("y\n"), &out
+		if err := RestoreBackup(confirmed); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(out.String(), "Restored "+name+" (1 subscriptions stored)") {
+			t.Errorf("Expected restore result, got %q", out.String())
+		}
+		if sub, _ := storage.GetSubscription(ctx, "UC_x5XG1OV2P6uZZ5FSM9Ttw"); sub != nil {
+			t.Errorf("Expected subscription missing from the backup to be removed, got %+v", sub)
+		}
+	})
+
+	t.Run("unknown_backup", func(t *testing.T) {
+		missing := config
+		missing.Name, missing.Yes, missing.Output = "state-20200101000000.json", true, &bytes.Buffer{}
+		err := RestoreBackup(missing)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
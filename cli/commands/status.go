@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// DefaultStatusWindow is how far back notifications are counted by default
+const DefaultStatusWindow = 24 * time.Hour
+
+// statusEventsLimit caps how many recent notifications are fetched to count them
+const statusEventsLimit = 1000
+
+// StatusConfig holds the configuration for the status command
+type StatusConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	Timeout       time.Duration
+	Window        time.Duration // How far back notifications are counted; defaults to DefaultStatusWindow
+	Format        string        // "text" (default) or "json"
+	Output        io.Writer     // Defaults to os.Stdout
+}
+
+// StatusSummary is the deployment health shown by the status command
+type StatusSummary struct {
+	Service       string                 `json:"service"`
+	UptimeSeconds float64                `json:"uptime_seconds"`
+	Total         int                    `json:"total"`
+	Active        int                    `json:"active"`
+	Expiring      int                    `json:"expiring"`
+	Pending       int                    `json:"pending"`
+	Expired       int                    `json:"expired"`
+	Healthy       int                    `json:"healthy"`
+	Stale         int                    `json:"stale"`
+	Broken        int                    `json:"broken"`
+	Quarantined   []string               `json:"quarantined"`   // Channel IDs
+	LastRenewal   *webhook.RenewalReport `json:"last_renewal"`  // nil when no run has been reported
+	Notifications *int                   `json:"notifications"` // Processed within the window; nil when history is unavailable
+	WindowHours   float64                `json:"notifications_window_hours"`
+}
+
+// Status prints a one-screen summary of the deployment: whether the service is up, how
+// many subscriptions are active, expiring or quarantined, the last renewal run and how
+// many notifications were processed recently
+func Status(config StatusConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	window := config.Window
+	if window <= 0 {
+		window = DefaultStatusWindow
+	}
+
+	format := config.Format
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %q (available: text, json)", config.Format)
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	health, err := c.Health()
+	if err != nil {
+		return fmt.Errorf("service is not healthy: %w", err)
+	}
+	list, err := c.ListSubscriptions()
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	// The report and history are optional on some storage backends, so they are only noted
+	report, err := c.LatestRenewalReport()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: renewal report unavailable: %v\n", err)
+	}
+
+	summary := StatusSummary{
+		Service:       health.Status,
+		UptimeSeconds: health.UptimeSeconds,
+		Total:         list.Total,
+		Active:        list.Active,
+		Pending:       list.Pending,
+		Expired:       list.Expired,
+		Healthy:       list.Healthy,
+		Stale:         list.Stale,
+		Broken:        list.Broken,
+		Quarantined:   []string{},
+		LastRenewal:   report,
+		WindowHours:   window.Hours(),
+	}
+	names := make(map[string]string)
+	for _, sub := range list.Subscriptions {
+		switch sub.Status {
+		case webhook.StatusExpiring:
+			summary.Expiring++
+		case webhook.StatusQuarantined:
+			summary.Quarantined = append(summary.Quarantined, sub.ChannelID)
+			names[sub.ChannelID] = sub.ChannelName
+		}
+	}
+
+	since := time.Now().Add(-window).UTC().Format(time.RFC3339)
+	if events, err := c.GetEvents(client.EventsOptions{Since: since, Limit: statusEventsLimit}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: notification history unavailable: %v\n", err)
+	} else {
+		summary.Notifications = &events.Count
+	}
+
+	if format == "json" {
+		return writeJSON(out, summary)
+	}
+	printStatus(out, config.BaseURL, summary, names, time.Now())
+	return nil
+}
+
+// printStatus writes the summary as text; names maps quarantined channels to their names
+func printStatus(out io.Writer, baseURL string, summary StatusSummary, names map[string]string, now time.Time) {
+	fmt.Fprintf(out, "📊 Status of %s\n", baseURL)
+	fmt.Fprintf(out, "   Service:        %s, up %s\n", summary.Service,
+		(time.Duration(summary.UptimeSeconds) * time.Second).String())
+	fmt.Fprintf(out, "   Subscriptions:  %d total | %d active | %d expiring | %d pending | %d expired | %d quarantined\n",
+		summary.Total, summary.Active, summary.Expiring, summary.Pending, summary.Expired, len(summary.Quarantined))
+	fmt.Fprintf(out, "   Health:         %d healthy | %d stale | %d broken\n", summary.Healthy, summary.Stale, summary.Broken)
+
+	if run := summary.LastRenewal; run != nil {
+		fmt.Fprintf(out, "   Last renewal:   %s (%s ago, %s): %d renewed, %d failed, %d deferred\n",
+			run.StartedAt.Local().Format("2006-01-02 15:04"), now.Sub(run.StartedAt).Truncate(time.Minute),
+			run.Trigger, run.Succeeded, run.Failed, run.Deferred)
+		if run.NextRenewalAt != "" {
+			next := countdown(run.NextRenewalAt, now, "due now")
+			if next != "due now" {
+				next = "in " + next
+			}
+			fmt.Fprintf(out, "   Next renewal:   %s\n", next)
+		}
+	} else {
+		fmt.Fprintf(out, "   Last renewal:   none reported\n")
+	}
+
+	switch {
+	case summary.Notifications == nil:
+		fmt.Fprintf(out, "   Notifications:  unavailable\n")
+	case *summary.Notifications >= statusEventsLimit:
+		fmt.Fprintf(out, "   Notifications:  %d+ in the last %gh\n", statusEventsLimit, summary.WindowHours)
+	default:
+		fmt.Fprintf(out, "   Notifications:  %d in the last %gh\n", *summary.Notifications, summary.WindowHours)
+	}
+
+	if len(summary.Quarantined) > 0 {
+		channels := make([]string, len(summary.Quarantined))
+		for i, channelID := range summary.Quarantined {
+			channels[i] = channelID
+			if name := names[channelID]; name != "" {
+				channels[i] = fmt.Sprintf("%s (%s)", name, channelID)
+			}
+		}
+		fmt.Fprintf(out, "\n🚫 Quarantined (not renewed until reactivated): %s\n", strings.Join(channels, ", "))
+	}
+}
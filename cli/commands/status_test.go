@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// newStatusServer serves the endpoints the status command calls; without a report
+// /renew/report answers 404 as before the first renewal run
+func newStatusServer(t *testing.T, report *webhook.RenewalReport) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			json.NewEncoder(w).Encode(webhook.LivenessResponse{Status: webhook.ProbeStatusOK, UptimeSeconds: 3600})
+		case "/subscriptions":
+			json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+				Subscriptions: []webhook.SubscriptionInfo{
+					{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: webhook.StatusActive},
+					{ChannelID: "UCBJycsmduvYEL83R_U4JriQ", ChannelName: "Marques Brownlee", Status: webhook.StatusQuarantined},
+					{ChannelID: "UCsBjURrPoezykLs9EqgamOA", Status: webhook.StatusExpiring},
+				},
+				Total:       3,
+				Active:      2,
+				Quarantined: 1,
+				Healthy:     2,
+				Broken:      1,
+			})
+		case "/renew/report":
+			if report == nil {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "No renewal run has been reported yet"})
+				return
+			}
+			json.NewEncoder(w).Encode(webhook.RenewalReportResponse{Status: "success", Report: report})
+		case "/events":
+			if r.URL.Query().Get("since") == "" {
+				t.Error("Expected notifications to be counted since the start of the window")
+			}
+			json.NewEncoder(w).Encode(webhook.EventsResponse{Status: "success", Count: 7})
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestStatus_Text(t *testing.T) {
+	server := newStatusServer(t, &webhook.RenewalReport{
+		Trigger:       webhook.RenewalTriggerScheduler,
+		StartedAt:     time.Now().Add(-2 * time.Hour),
+		Succeeded:     12,
+		Failed:        1,
+		NextRenewalAt: time.Now().Add(3 * time.Hour).Format(time.RFC3339),
+	})
+	defer server.Close()
+
+	var out bytes.Buffer
+	if err := Status(StatusConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Output: &out}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Service:        ok, up 1h0m0s",
+		"3 total | 2 active | 1 expiring | 0 pending | 0 expired | 1 quarantined",
+		"2 healthy | 0 stale | 1 broken",
+		"(2h0m0s ago, scheduler): 12 renewed, 1 failed, 0 deferred",
+		"Next renewal:   in 2h59m",
+		"Notifications:  7 in the last 24h",
+		"Quarantined (not renewed until reactivated): Marques Brownlee (UCBJycsmduvYEL83R_U4JriQ)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestStatus_NoRenewalReported(t *testing.T) {
+	server := newStatusServer(t, nil)
+	defer server.Close()
+
+	var out bytes.Buffer
+	if err := Status(StatusConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Output: &out}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Last renewal:   none reported") {
+		t.Errorf("Expected the missing report to be noted, got:\n%s", out.String())
+	}
+}
+
+func TestStatus_JSON(t *testing.T) {
+	server := newStatusServer(t, nil)
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := Status(StatusConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Window: time.Hour, Format: "json", Output: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var summary StatusSummary
+	if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+		t.Fatalf("Expected JSON output, got %v", err)
+	}
+	if summary.Expiring != 1 || len(summary.Quarantined) != 1 || summary.LastRenewal != nil {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if summary.Notifications == nil || *summary.Notifications != 7 || summary.WindowHours != 1 {
+		t.Errorf("Expected 7 notifications in 1 hour, got %+v", summary)
+	}
+}
+
+func TestStatus_Unhealthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := Status(StatusConfig{BaseURL: server.URL, Timeout: 30 * time.Second, Output: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "service is not healthy") {
+		t.Errorf("Expected an unhealthy service error, got %v", err)
+	}
+
+	if err := Status(StatusConfig{BaseURL: server.URL, Format: "yaml"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
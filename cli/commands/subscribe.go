@@ -4,25 +4,40 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/samsoir/youtube-webhook/cli/client"
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
 )
 
 // SubscribeConfig holds the configuration for the subscribe command
 type SubscribeConfig struct {
-	BaseURL   string
-	ChannelID string
-	Timeout   time.Duration
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ChannelID     string
+	Handle        string // An @handle or channel URL the service resolves, used instead of ChannelID
+	PlaylistID    string // A playlist to subscribe to instead of a channel
+	HubURL        string // Hub to subscribe with instead of the service's HUB_URL
+	Timeout       time.Duration
 }
 
-// Subscribe subscribes to a YouTube channel
+// Subscribe subscribes to a YouTube channel or playlist
 func Subscribe(config SubscribeConfig) error {
-	c := client.NewClient(config.BaseURL, config.Timeout)
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
 	
-	resp, err := c.Subscribe(config.ChannelID)
+	resp, err := c.SubscribeWithOptions(client.SubscribeOptions{
+		ChannelID:  config.ChannelID,
+		Handle:     config.Handle,
+		PlaylistID: config.PlaylistID,
+		HubURL:     config.HubURL,
+	})
+	topic, topicID := "channel", config.ChannelID
+	if config.PlaylistID != "" {
+		topic, topicID = "playlist", config.PlaylistID
+	}
 	if err != nil {
 		// Check if we got a conflict response (already subscribed)
-		if resp != nil && resp.Status == "conflict" {
-			fmt.Printf("ℹ️  Already subscribed to channel %s\n", config.ChannelID)
+		if client.IsAlreadySubscribed(resp) {
+			fmt.Printf("ℹ️  Already subscribed to %s %s\n", topic, channelLabel(resp, topicID))
 			if resp.ExpiresAt != "" {
 				fmt.Printf("   Expires: %s\n", resp.ExpiresAt)
 			}
@@ -31,7 +46,10 @@ func Subscribe(config SubscribeConfig) error {
 		return fmt.Errorf("failed to subscribe: %w", err)
 	}
 
-	fmt.Printf("✅ Successfully subscribed to channel %s\n", config.ChannelID)
+	fmt.Printf("✅ Successfully subscribed to %s %s\n", topic, channelLabel(resp, topicID))
+	if config.HubURL != "" {
+		fmt.Printf("   Hub: %s\n", config.HubURL)
+	}
 	if resp.ExpiresAt != "" {
 		fmt.Printf("   Expires: %s\n", resp.ExpiresAt)
 	}
@@ -39,16 +57,30 @@ func Subscribe(config SubscribeConfig) error {
 	return nil
 }
 
+// channelLabel names the channel a subscribe response is about: its ID, followed by
+// its name when the service knows it
+func channelLabel(resp *webhook.APIResponse, channelID string) string {
+	if resp.ChannelID != "" {
+		channelID = resp.ChannelID
+	}
+	if resp.ChannelName != "" {
+		return fmt.Sprintf("%s (%s)", channelID, resp.ChannelName)
+	}
+	return channelID
+}
+
 // UnsubscribeConfig holds the configuration for the unsubscribe command
 type UnsubscribeConfig struct {
-	BaseURL   string
-	ChannelID string
-	Timeout   time.Duration
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ChannelID     string
+	Timeout       time.Duration
 }
 
 // Unsubscribe unsubscribes from a YouTube channel
 func Unsubscribe(config UnsubscribeConfig) error {
-	c := client.NewClient(config.BaseURL, config.Timeout)
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
 	
 	err := c.Unsubscribe(config.ChannelID)
 	if err != nil {
@@ -62,4 +94,26 @@ func Unsubscribe(config UnsubscribeConfig) error {
 
 	fmt.Printf("✅ Successfully unsubscribed from channel %s\n", config.ChannelID)
 	return nil
-}
\ No newline at end of file
+}
+// RestoreConfig holds the configuration for the restore command
+type RestoreConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	ChannelID     string
+	Timeout       time.Duration
+}
+
+// Restore re-subscribes an unsubscribed channel with its previous configuration
+func Restore(config RestoreConfig) error {
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	resp, err := c.RestoreSubscription(config.ChannelID)
+	if err != nil {
+		return fmt.Errorf("failed to restore: %w", err)
+	}
+
+	fmt.Printf("✅ Restored subscription to channel %s with its previous configuration\n", channelLabel(resp, config.ChannelID))
+	fmt.Printf("   Pending hub verification\n")
+	return nil
+}
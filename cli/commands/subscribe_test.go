@@ -42,12 +42,36 @@ func TestSubscribe_Success(t *testing.T) {
 	}
 }
 
+func TestSubscribe_ByHandle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle := r.URL.Query().Get("handle"); handle != "@SomeCreator" {
+			t.Errorf("Expected handle @SomeCreator, got %q", handle)
+		}
+		json.NewEncoder(w).Encode(webhook.APIResponse{
+			Status:      "success",
+			ChannelID:   "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			ChannelName: "Some Creator",
+		})
+	}))
+	defer server.Close()
+
+	err := Subscribe(SubscribeConfig{
+		BaseURL: server.URL,
+		Handle:  "@SomeCreator",
+		Timeout: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
 func TestSubscribe_AlreadySubscribed(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(webhook.APIResponse{
-			Status:    "conflict",
+			Status:    "error",
+			Code:      webhook.ErrCodeConflict,
 			Message:   "Already subscribed",
 			ExpiresAt: "2024-01-22T15:30:00Z",
 		})
@@ -155,4 +179,31 @@ func TestUnsubscribe_ServerError(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for server error, got nil")
 	}
-}
\ No newline at end of file
+}
+func TestRestore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/restore" {
+			t.Errorf("Expected POST /subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/restore, got %s %s", r.Method, r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "success", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+	}))
+	defer server.Close()
+
+	err := Restore(RestoreConfig{BaseURL: server.URL, ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestRestore_NotRemoved(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "No removed subscription to restore for this channel"})
+	}))
+	defer server.Close()
+
+	err := Restore(RestoreConfig{BaseURL: server.URL, ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Timeout: 30 * time.Second})
+	if err == nil {
+		t.Fatal("Expected an error when there is nothing to restore, got nil")
+	}
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Column describes a single column that can be selected for table output
+type Column[T any] struct {
+	Key    string            // Name used with -columns and -sort
+	Header string            // Heading printed above the column
+	Width  int               // Maximum cell width; longer values are truncated (0 = unlimited)
+	Value  func(T) string    // Renders the cell for a row
+	Less   func(a, b T) bool // Optional ordering; defaults to comparing rendered values
+}
+
+// Table renders rows using a selectable, sortable set of columns
+type Table[T any] struct {
+	columns  []Column[T]
+	defaults []string
+}
+
+// NewTable creates a table with the available columns and the keys shown by default
+func NewTable[T any](columns []Column[T], defaults []string) *Table[T] {
+	return &Table[T]{columns: columns, defaults: defaults}
+}
+
+// Keys returns the names of all selectable columns
+func (t *Table[T]) Keys() []string {
+	keys := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		keys[i] = col.Key
+	}
+	return keys
+}
+
+// Render writes rows to w using the selected columns, sorted by sortKey.
+// A sortKey prefixed with "-" sorts in descending order.
+func (t *Table[T]) Render(w io.Writer, rows []T, selected []string, sortKey string) error {
+	if len(selected) == 0 {
+		selected = t.defaults
+	}
+
+	columns := make([]Column[T], 0, len(selected))
+	for _, key := range selected {
+		col, ok := t.lookup(key)
+		if !ok {
+			return fmt.Errorf("unknown column %q (available: %s)", key, strings.Join(t.Keys(), ", "))
+		}
+		columns = append(columns, col)
+	}
+
+	rows, err := t.Sort(rows, sortKey)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	rules := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.Header
+		rules[i] = strings.Repeat("-", len(col.Header))
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	fmt.Fprintln(tw, strings.Join(rules, "\t"))
+
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, col := range columns {
+			cells[i] = truncate(col.Value(row), col.Width)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Sort returns rows ordered by the sortKey column, or rows unchanged when sortKey is empty.
+// A sortKey prefixed with "-" sorts in descending order.
+func (t *Table[T]) Sort(rows []T, sortKey string) ([]T, error) {
+	if sortKey == "" {
+		return rows, nil
+	}
+
+	descending := strings.HasPrefix(sortKey, "-")
+	col, ok := t.lookup(strings.TrimPrefix(sortKey, "-"))
+	if !ok {
+		return nil, fmt.Errorf("unknown sort column %q (available: %s)", sortKey, strings.Join(t.Keys(), ", "))
+	}
+	return sortRows(rows, col, descending), nil
+}
+
+func (t *Table[T]) lookup(key string) (Column[T], bool) {
+	key = strings.TrimSpace(strings.ToLower(key))
+	for _, col := range t.columns {
+		if col.Key == key {
+			return col, true
+		}
+	}
+	return Column[T]{}, false
+}
+
+// sortRows returns a sorted copy of rows ordered by the given column
+func sortRows[T any](rows []T, col Column[T], descending bool) []T {
+	sorted := make([]T, len(rows))
+	copy(sorted, rows)
+
+	less := col.Less
+	if less == nil {
+		less = func(a, b T) bool { return col.Value(a) < col.Value(b) }
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// ParseColumns splits a comma-separated -columns flag value
+func ParseColumns(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	var columns []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			columns = append(columns, part)
+		}
+	}
+	return columns
+}
+
+// truncate shortens value to width runes, marking the cut with an ellipsis
+func truncate(value string, width int) string {
+	runes := []rune(value)
+	if width <= 0 || len(runes) <= width {
+		return value
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
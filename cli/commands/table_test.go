@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tableRow struct {
+	name  string
+	count int
+}
+
+func newTestTable() *Table[tableRow] {
+	return NewTable([]Column[tableRow]{
+		{Key: "name", Header: "NAME", Width: 6, Value: func(r tableRow) string { return r.name }},
+		{
+			Key: "count", Header: "COUNT",
+			Value: func(r tableRow) string { return strings.Repeat("#", r.count) },
+			Less:  func(a, b tableRow) bool { return a.count < b.count },
+		},
+	}, []string{"name"})
+}
+
+func renderLines(t *testing.T, table *Table[tableRow], rows []tableRow, columns []string, sortKey string) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := table.Render(&buf, rows, columns, sortKey); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+func TestTable_DefaultColumns(t *testing.T) {
+	lines := renderLines(t, newTestTable(), []tableRow{{"a", 1}}, nil, "")
+
+	if strings.TrimSpace(lines[0]) != "NAME" {
+		t.Errorf("Expected only the default NAME column, got %q", lines[0])
+	}
+}
+
+func TestTable_SelectedColumns(t *testing.T) {
+	lines := renderLines(t, newTestTable(), []tableRow{{"a", 2}}, []string{"count", "name"}, "")
+
+	if !strings.HasPrefix(lines[0], "COUNT") || !strings.Contains(lines[0], "NAME") {
+		t.Errorf("Expected COUNT then NAME headers, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "##") {
+		t.Errorf("Expected count cell first, got %q", lines[2])
+	}
+}
+
+func TestTable_Sorting(t *testing.T) {
+	rows := []tableRow{{"bravo", 3}, {"alpha", 1}, {"charlie", 2}}
+
+	lines := renderLines(t, newTestTable(), rows, []string{"name"}, "name")
+	if strings.TrimSpace(lines[2]) != "alpha" || strings.TrimSpace(lines[4]) != "charl…" {
+		t.Errorf("Expected ascending name order, got %v", lines)
+	}
+
+	lines = renderLines(t, newTestTable(), rows, []string{"name"}, "-count")
+	if strings.TrimSpace(lines[2]) != "bravo" || strings.TrimSpace(lines[4]) != "alpha" {
+		t.Errorf("Expected descending count order, got %v", lines)
+	}
+
+	if rows[0].name != "bravo" {
+		t.Error("Expected sorting not to modify the input slice")
+	}
+}
+
+func TestTable_UnknownColumns(t *testing.T) {
+	var buf bytes.Buffer
+	table := newTestTable()
+
+	if err := table.Render(&buf, nil, []string{"bogus"}, ""); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+	if err := table.Render(&buf, nil, nil, "bogus"); err == nil {
+		t.Error("Expected error for unknown sort column")
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	testCases := map[string][]string{
+		"":                         nil,
+		"channel":                  {"channel"},
+		" channel, expires ,,last": {"channel", "expires", "last"},
+	}
+
+	for input, expected := range testCases {
+		if got := ParseColumns(input); !reflect.DeepEqual(got, expected) {
+			t.Errorf("ParseColumns(%q) = %v, expected %v", input, got, expected)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	testCases := []struct {
+		value    string
+		width    int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"truncated", 5, "trun…"},
+		{"anything", 0, "anything"},
+		{"ab", 1, "…"},
+	}
+
+	for _, tc := range testCases {
+		if got := truncate(tc.value, tc.width); got != tc.expected {
+			t.Errorf("truncate(%q, %d) = %q, expected %q", tc.value, tc.width, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/samsoir/youtube-webhook/pkg/client"
+)
+
+// DefaultWatchInterval is how often watch polls for new notifications by default, when
+// the service cannot stream them, and how long it waits to reconnect a failed stream
+const DefaultWatchInterval = 10 * time.Second
+
+// watchPageSize is how many events one poll fetches; a full page is followed straight
+// away by the next, so a burst of notifications is never cut short
+const watchPageSize = 100
+
+// WatchConfig holds the configuration for the watch command
+type WatchConfig struct {
+	BaseURL       string
+	APIKey        string // Sent as a bearer token when set
+	SigningSecret string // Signs requests with the service's REQUEST_SIGNING_SECRET when set
+	Timeout       time.Duration
+	ChannelID     string        // Only show this channel's notifications
+	Interval      time.Duration // How often to poll, or wait to reconnect; defaults to DefaultWatchInterval
+	Last          int           // Recent notifications shown before waiting for new ones
+	JSON          bool          // Print each notification as one line of JSON
+	Output        io.Writer     // Defaults to os.Stdout
+	Errors        io.Writer     // Failed polls are reported here; defaults to os.Stderr
+}
+
+// Watch prints notifications as the service processes them, streaming GET /events or
+// polling it on services that cannot stream, until interrupted
+func Watch(config WatchConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return watch(ctx, config)
+}
+
+// watch prints the last few notifications, then every new one until ctx is done
+func watch(ctx context.Context, config WatchConfig) error {
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	errOut := config.Errors
+	if errOut == nil {
+		errOut = os.Stderr
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	c := client.NewClient(config.BaseURL, config.Timeout).WithAPIKey(config.APIKey).WithSigningSecret(config.SigningSecret)
+
+	// The newest event only sets the cursor when no recent notifications are wanted
+	recent, err := c.GetEvents(client.EventsOptions{ChannelID: config.ChannelID, Limit: max(config.Last, 1)})
+	if err != nil {
+		return fmt.Errorf("failed to get notification events: %w", err)
+	}
+
+	if !config.JSON {
+		target := "all channels"
+		if config.ChannelID != "" {
+			target = "channel " + config.ChannelID
+		}
+		fmt.Fprintf(out, "👀 Watching notifications for %s (Ctrl-C to stop)\n\n", target)
+	}
+	if config.Last > 0 {
+		if err := printEvents(out, recent.Events, config.JSON); err != nil {
+			return err
+		}
+	}
+
+	cursor := recent.Cursor
+	for ctx.Err() == nil {
+		cursor, err = c.StreamEvents(ctx, client.EventsOptions{ChannelID: config.ChannelID, Since: cursor}, func(event webhook.NotificationEvent) error {
+			return printEvents(out, []webhook.NotificationEvent{event}, config.JSON)
+		})
+		switch {
+		case errors.Is(err, client.ErrEventStreamUnsupported):
+			return poll(ctx, c, config, cursor, interval, out, errOut)
+		case err != nil && ctx.Err() == nil:
+			// Reconnect after a pause, resuming from the last event received
+			fmt.Fprintf(errOut, "⚠️  Notification stream interrupted: %v\n", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(interval):
+			}
+		}
+		// A stream that ended cleanly is reopened straight away
+	}
+	return nil
+}
+
+// poll prints the events after cursor every interval until ctx is done, for services
+// that cannot stream them
+func poll(ctx context.Context, c *client.Client, config WatchConfig, cursor string, interval time.Duration, out, errOut io.Writer) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			resp, err := c.GetEvents(client.EventsOptions{ChannelID: config.ChannelID, Since: cursor, Limit: watchPageSize})
+			if err != nil {
+				// Keep watching; the next poll picks up from the same cursor
+				fmt.Fprintf(errOut, "⚠️  Failed to get notification events: %v\n", err)
+				break
+			}
+			if err := printEvents(out, resp.Events, config.JSON); err != nil {
+				return err
+			}
+			if resp.Cursor != "" {
+				cursor = resp.Cursor
+			}
+			if resp.Count < watchPageSize {
+				break
+			}
+		}
+	}
+}
+
+// printEvents writes one line per event, as JSON or as text
+func printEvents(out io.Writer, events []webhook.NotificationEvent, asJSON bool) error {
+	encoder := json.NewEncoder(out)
+	for _, event := range events {
+		if asJSON {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(out, "%s  %-24s  %-11s  %-16s  %s\n", event.ReceivedAt.Local().Format("2006-01-02 15:04:05"),
+			event.ChannelID, event.VideoID, event.Decision, event.Title)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// syncBuffer is a bytes.Buffer that can be written by watch while a test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newEventsServer serves one recent event, then one new event after its cursor, then
+// nothing new. caughtUp is closed once a poll has returned nothing new.
+func newEventsServer(t *testing.T) (*httptest.Server, <-chan struct{}) {
+	caughtUp := make(chan struct{})
+	var once sync.Once
+	received := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	event := func(videoID string, at time.Time) webhook.NotificationEvent {
+		return webhook.NotificationEvent{
+			ChannelID:    "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			HistoryEntry: webhook.HistoryEntry{ReceivedAt: at, VideoID: videoID, Title: "Episode " + videoID, Decision: webhook.HistoryDecisionDispatched},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" || r.URL.Query().Get("channel_id") != "UCXuqSBlHAE6Xw-yeJA0Tunw" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp := webhook.EventsResponse{Status: "success", Cursor: r.URL.Query().Get("since")}
+		switch resp.Cursor {
+		case "":
+			resp.Events = []webhook.NotificationEvent{event("old1", received)}
+		case received.Format(time.RFC3339Nano):
+			resp.Events = []webhook.NotificationEvent{event("new1", received.Add(time.Minute))}
+		default:
+			once.Do(func() { close(caughtUp) })
+		}
+		resp.Count = len(resp.Events)
+		if resp.Count > 0 {
+			resp.Cursor = resp.Events[resp.Count-1].ReceivedAt.Format(time.RFC3339Nano)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	return server, caughtUp
+}
+
+func TestWatch(t *testing.T) {
+	for _, asJSON := range []bool{false, true} {
+		name := "text"
+		if asJSON {
+			name = "json"
+		}
+		t.Run(name, func(t *testing.T) {
+			server, caughtUp := newEventsServer(t)
+			defer server.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			var out syncBuffer
+			done := make(chan error, 1)
+			go func() {
+				done <- watch(ctx, WatchConfig{
+					BaseURL:   server.URL,
+					Timeout:   5 * time.Second,
+					ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+					Interval:  10 * time.Millisecond,
+					Last:      5,
+					JSON:      asJSON,
+					Output:    &out,
+				})
+			}()
+
+			select {
+			case <-caughtUp:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for watch to poll for new events")
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			output := out.String()
+			if strings.Count(output, "Episode new1") != 1 || !strings.Contains(output, "Episode old1") {
+				t.Fatalf("Expected the recent and the new event once each, got %q", output)
+			}
+			if strings.Index(output, "old1") > strings.Index(output, "new1") {
+				t.Errorf("Expected events oldest first, got %q", output)
+			}
+			if !asJSON {
+				if !strings.Contains(output, "Watching notifications for channel UCXuqSBlHAE6Xw-yeJA0Tunw") {
+					t.Errorf("Expected watch header, got %q", output)
+				}
+				return
+			}
+			for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+				var event webhook.NotificationEvent
+				if err := json.Unmarshal([]byte(line), &event); err != nil || event.ChannelID == "" {
+					t.Errorf("Expected one JSON event per line, got %q (%v)", line, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWatch_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "Notification history is disabled or not supported by the storage backend"})
+	}))
+	defer server.Close()
+
+	err := watch(context.Background(), WatchConfig{BaseURL: server.URL, Timeout: 5 * time.Second, Output: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "history is disabled") {
+		t.Errorf("Expected server error, got %v", err)
+	}
+}
+
+func TestWatch_Stream(t *testing.T) {
+	received := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	cursor := received.Format(time.RFC3339Nano)
+	resumed := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != webhook.EventStreamContentType {
+			json.NewEncoder(w).Encode(webhook.EventsResponse{Status: "success", Cursor: cursor})
+			return
+		}
+		w.Header().Set("Content-Type", webhook.EventStreamContentType)
+		if r.Header.Get("Last-Event-ID") == cursor {
+			// The first stream ends after one event, as when an instance shuts down
+			fmt.Fprintf(w, "id: %s\nevent: notification\ndata: {\"channel_id\":\"UCXuqSBlHAE6Xw-yeJA0Tunw\",\"video_id\":\"new1\",\"title\":\"Episode new1\"}\n\n",
+				received.Add(time.Minute).Format(time.RFC3339Nano))
+			return
+		}
+		resumed <- r.Header.Get("Last-Event-ID")
+		fmt.Fprintf(w, "id: %s\nevent: notification\ndata: {\"channel_id\":\"UCXuqSBlHAE6Xw-yeJA0Tunw\",\"video_id\":\"new2\",\"title\":\"Episode new2\"}\n\n",
+			received.Add(2*time.Minute).Format(time.RFC3339Nano))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- watch(ctx, WatchConfig{BaseURL: server.URL, Timeout: 5 * time.Second, Interval: time.Hour, Output: &out})
+	}()
+
+	select {
+	case lastEventID := <-resumed:
+		if lastEventID != received.Add(time.Minute).Format(time.RFC3339Nano) {
+			t.Errorf("Expected the stream to resume after the last event shown, got Last-Event-ID %q", lastEventID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for watch to reconnect")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Episode new2") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "Episode new1") || !strings.Contains(output, "Episode new2") {
+		t.Errorf("Expected both streamed events, got %q", output)
+	}
+}
@@ -0,0 +1,15 @@
+// Command lambda runs the webhook function on AWS Lambda behind API Gateway or a
+// function URL. Build it for a provided runtime as an executable named bootstrap.
+package main
+
+import (
+	"log"
+
+	"github.com/samsoir/youtube-webhook/function/adapter"
+)
+
+func main() {
+	if err := adapter.StartLambda(adapter.Handler()); err != nil {
+		log.Fatalf("adapter.StartLambda: %v\n", err)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// completionShells are the shells completion scripts can be generated for, with how to load each
+var completionShells = []struct {
+	name string
+	load string
+}{
+	{"bash", "source <(youtube-webhook completion bash)"},
+	{"zsh", "source <(youtube-webhook completion zsh)"},
+	{"fish", "youtube-webhook completion fish | source"},
+}
+
+// completionCommands are the completion subcommands, one per shell
+func completionCommands() []command {
+	cmds := make([]command, len(completionShells))
+	for i, shell := range completionShells {
+		cmds[i] = command{
+			name:    shell.name,
+			summary: fmt.Sprintf("Print the %s completion script; load it with: %s", shell.name, shell.load),
+			local:   true,
+			setup:   handleCompletion(shell.name),
+		}
+	}
+	return cmds
+}
+
+// handleCompletion returns the setup of the completion subcommand for shell
+func handleCompletion(shell string) func(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	return func(cmd *flag.FlagSet, opts *options) func(args []string) error {
+		return func(args []string) error {
+			return writeCompletion(os.Stdout, shell, registry())
+		}
+	}
+}
+
+// writeCompletion writes the completion script for shell, covering every command in cmds
+func writeCompletion(w io.Writer, shell string, cmds []command) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, cmds)
+	case "zsh":
+		writeZshCompletion(w, cmds)
+	case "fish":
+		writeFishCompletion(w, cmds)
+	default:
+		names := make([]string, len(completionShells))
+		for i, s := range completionShells {
+			names[i] = s.name
+		}
+		return fmt.Errorf("unknown shell %q (available: %s)", shell, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// completionNode is a command, or the top level when path is empty, with what can follow it
+type completionNode struct {
+	path  []command
+	words []command    // Subcommands, or the top-level commands
+	flags []*flag.Flag // The command's flags, or the global flags
+}
+
+// completionNodes lists the top level and every command, in registry order
+func completionNodes(cmds []command) []completionNode {
+	global := flag.NewFlagSet("youtube-webhook", flag.ContinueOnError)
+	globalFlags(global, &options{})
+	top := completionNode{words: cmds}
+	global.VisitAll(func(f *flag.Flag) {
+		top.flags = append(top.flags, f)
+	})
+
+	nodes := []completionNode{top}
+	walkCommands(cmds, nil, func(path []command) {
+		c := path[len(path)-1]
+		nodes = append(nodes, completionNode{path: path, words: c.subcommands, flags: c.flags()})
+	})
+	return nodes
+}
+
+// walkCommands calls fn with the path to every command in cmds and their subcommands
+func walkCommands(cmds []command, parent []command, fn func(path []command)) {
+	for _, c := range cmds {
+		path := append(append([]command(nil), parent...), c)
+		fn(path)
+		walkCommands(c.subcommands, path, fn)
+	}
+}
+
+// valueFlagNames lists, once each, the names of the flags in nodes that take a value
+func valueFlagNames(nodes []completionNode) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, node := range nodes {
+		for _, f := range node.flags {
+			if !isBoolFlag(f) && !seen[f.Name] {
+				seen[f.Name] = true
+				names = append(names, "-"+f.Name)
+			}
+		}
+	}
+	return names
+}
+
+// completionWords lists the names of the commands that can follow a node, adding help at the top level
+func completionWords(node completionNode) []string {
+	words := commandNames(node.words)
+	if len(node.path) == 0 {
+		words = append(words, "help")
+	}
+	return words
+}
+
+// flagNames lists flags as they are typed, such as -url
+func flagNames(flags []*flag.Flag) []string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.Name
+	}
+	return names
+}
+
+// flagValueNames lists the flags of the given value kind ("" for free text) that take a value
+func flagValueNames(flags []*flag.Flag, kind string) []string {
+	var names []string
+	for _, f := range flags {
+		if !isBoolFlag(f) && flagValueKind(f) == kind {
+			names = append(names, "-"+f.Name)
+		}
+	}
+	return names
+}
+
+func writeBashCompletion(w io.Writer, cmds []command) {
+	nodes := completionNodes(cmds)
+
+	fmt.Fprintln(w, "# bash completion for youtube-webhook")
+	fmt.Fprintln(w, "# Load it with: source <(youtube-webhook completion bash)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_youtube_webhook() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `    local cmd="" i`)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    # Find the command typed so far, skipping flags and their values")
+	fmt.Fprintln(w, "    for ((i = 1; i < COMP_CWORD; i++)); do")
+	fmt.Fprintln(w, `        case "${COMP_WORDS[i]}" in`)
+	fmt.Fprintf(w, "        %s) ((i++)) ;;\n", strings.Join(valueFlagNames(nodes), "|"))
+	fmt.Fprintln(w, "        -*) ;;")
+	fmt.Fprintln(w, `        *) cmd="${cmd:+$cmd }${COMP_WORDS[i]}" ;;`)
+	fmt.Fprintln(w, "        esac")
+	fmt.Fprintln(w, "    done")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    # Complete a flag's value, else the command's flags or subcommands")
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	for _, node := range nodes {
+		name := commandName(node.path)
+		if len(node.words) > 0 || len(node.path) == 0 {
+			fmt.Fprintf(w, "    %q)\n", name)
+		} else {
+			// Arguments after a command's flags, such as config set's settings, keep its completions
+			fmt.Fprintf(w, "    %q|%q*)\n", name, name+" ")
+		}
+
+		files, dirs, values := flagValueNames(node.flags, "file"), flagValueNames(node.flags, "dir"), flagValueNames(node.flags, "")
+		if len(files)+len(dirs)+len(values) > 0 {
+			fmt.Fprintln(w, `        case "$prev" in`)
+			if len(files) > 0 {
+				fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(files, "|"))
+			}
+			if len(dirs) > 0 {
+				fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n", strings.Join(dirs, "|"))
+			}
+			if len(values) > 0 {
+				fmt.Fprintf(w, "        %s) return ;;\n", strings.Join(values, "|"))
+			}
+			fmt.Fprintln(w, "        esac")
+		}
+
+		words, flags := completionWords(node), flagNames(node.flags)
+		switch {
+		case len(words) > 0 && len(flags) > 0:
+			fmt.Fprintln(w, `        if [[ "$cur" == -* ]]; then`)
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(flags, " "))
+			fmt.Fprintln(w, "        else")
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(words, " "))
+			fmt.Fprintln(w, "        fi")
+		case len(words) > 0:
+			fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(words, " "))
+		case len(flags) > 0:
+			fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(flags, " "))
+		}
+		fmt.Fprintln(w, "        ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "complete -F _youtube_webhook youtube-webhook")
+}
+
+func writeZshCompletion(w io.Writer, cmds []command) {
+	nodes := completionNodes(cmds)
+
+	fmt.Fprintln(w, "#compdef youtube-webhook")
+	fmt.Fprintln(w, "# zsh completion for youtube-webhook")
+	fmt.Fprintln(w, "# Load it with: source <(youtube-webhook completion zsh)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_youtube_webhook() {")
+	fmt.Fprintln(w, `  local cmd="" i`)
+	fmt.Fprintln(w, "  local -a subcommands flags")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Find the command typed so far, skipping flags and their values")
+	fmt.Fprintln(w, "  for (( i = 2; i < CURRENT; i++ )); do")
+	fmt.Fprintln(w, "    case $words[i] in")
+	fmt.Fprintf(w, "      (%s) (( i++ )) ;;\n", strings.Join(valueFlagNames(nodes), "|"))
+	fmt.Fprintln(w, "      (-*) ;;")
+	fmt.Fprintln(w, `      (*) cmd="${cmd:+$cmd }$words[i]" ;;`)
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "  done")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Complete a flag's value, else the command's flags or subcommands")
+	fmt.Fprintln(w, "  case $cmd in")
+	for _, node := range nodes {
+		name := commandName(node.path)
+		if len(node.words) > 0 || len(node.path) == 0 {
+			fmt.Fprintf(w, "    (%s)\n", zshQuote(name))
+		} else {
+			fmt.Fprintf(w, "    (%s|%s*)\n", zshQuote(name), zshQuote(name+" "))
+		}
+
+		files, dirs, values := flagValueNames(node.flags, "file"), flagValueNames(node.flags, "dir"), flagValueNames(node.flags, "")
+		if len(files)+len(dirs)+len(values) > 0 {
+			fmt.Fprintln(w, "      case $words[CURRENT-1] in")
+			if len(files) > 0 {
+				fmt.Fprintf(w, "        (%s) _files; return ;;\n", strings.Join(files, "|"))
+			}
+			if len(dirs) > 0 {
+				fmt.Fprintf(w, "        (%s) _files -/; return ;;\n", strings.Join(dirs, "|"))
+			}
+			if len(values) > 0 {
+				fmt.Fprintf(w, "        (%s) return ;;\n", strings.Join(values, "|"))
+			}
+			fmt.Fprintln(w, "      esac")
+		}
+
+		var subcommands, flags []string
+		for _, c := range node.words {
+			subcommands = append(subcommands, zshQuote(c.name+":"+c.summary))
+		}
+		if len(node.path) == 0 {
+			subcommands = append(subcommands, zshQuote("help:Show this help message"))
+		}
+		for _, f := range node.flags {
+			flags = append(flags, zshQuote("-"+f.Name+":"+f.Usage))
+		}
+		if len(subcommands) > 0 {
+			fmt.Fprintf(w, "      subcommands=(\n        %s\n      )\n", strings.Join(subcommands, "\n        "))
+		}
+		if len(flags) > 0 {
+			fmt.Fprintf(w, "      flags=(\n        %s\n      )\n", strings.Join(flags, "\n        "))
+		}
+		fmt.Fprintln(w, "      ;;")
+	}
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  if [[ $PREFIX == -* || ${#subcommands} -eq 0 ]]; then")
+	fmt.Fprintln(w, "    _describe -t flags 'flag' flags")
+	fmt.Fprintln(w, "  else")
+	fmt.Fprintln(w, "    _describe -t commands 'youtube-webhook command' subcommands")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "compdef _youtube_webhook youtube-webhook")
+}
+
+func writeFishCompletion(w io.Writer, cmds []command) {
+	fmt.Fprintln(w, "# fish completion for youtube-webhook")
+	fmt.Fprintln(w, "# Load it with: youtube-webhook completion fish | source")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "complete -c youtube-webhook -f")
+
+	for _, node := range completionNodes(cmds) {
+		// The command's words are seen, and none of its subcommands yet
+		var seen []string
+		for _, c := range node.path {
+			seen = append(seen, "__fish_seen_subcommand_from "+c.name)
+		}
+		condition := strings.Join(seen, "; and ")
+		if len(node.path) == 0 {
+			condition = "__fish_use_subcommand"
+		}
+		wordCondition := condition
+		if len(node.path) > 0 && len(node.words) > 0 {
+			wordCondition += "; and not __fish_seen_subcommand_from " + strings.Join(commandNames(node.words), " ")
+		}
+
+		fmt.Fprintln(w)
+		for _, c := range node.words {
+			fmt.Fprintf(w, "complete -c youtube-webhook -n %s -a %s -d %s\n", fishQuote(wordCondition), c.name, fishQuote(c.summary))
+		}
+		if len(node.path) == 0 {
+			fmt.Fprintf(w, "complete -c youtube-webhook -n %s -a help -d %s\n", fishQuote(condition), fishQuote("Show this help message"))
+		}
+		for _, f := range node.flags {
+			line := fmt.Sprintf("complete -c youtube-webhook -n %s -o %s", fishQuote(condition), f.Name)
+			if !isBoolFlag(f) {
+				switch flagValueKind(f) {
+				case "file":
+					line += " -r -F"
+				case "dir":
+					line += " -r -a '(__fish_complete_directories)'"
+				default:
+					line += " -r"
+				}
+			}
+			fmt.Fprintf(w, "%s -d %s\n", line, fishQuote(f.Usage))
+		}
+	}
+}
+
+// zshQuote single-quotes s for zsh
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes s for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	for _, s := range completionShells {
+		shell := s.name
+		t.Run(shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeCompletion(&buf, shell, registry()); err != nil {
+				t.Fatalf("Expected %s completion to be written, got: %v", shell, err)
+			}
+			script := buf.String()
+
+			// Every command, config's subcommands, the flags of renew and the global flags are offered
+			for _, want := range []string{"subscribe", "restore-backup", "completion", "help", "get", "set", "format", "force", "timeout"} {
+				if !strings.Contains(script, want) {
+					t.Errorf("Expected %s completion to offer %q", shell, want)
+				}
+			}
+
+			// Check the script parses, when the shell is installed
+			path, err := exec.LookPath(shell)
+			if err != nil {
+				t.Skipf("%s not installed", shell)
+			}
+			file := filepath.Join(t.TempDir(), "completion."+shell)
+			if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			check := exec.Command(path, "-n", file)
+			if shell == "fish" {
+				check = exec.Command(path, "--no-execute", file)
+			}
+			if output, err := check.CombinedOutput(); err != nil {
+				t.Errorf("Expected %s completion to parse, got: %v, output: %s", shell, err, string(output))
+			}
+		})
+	}
+
+	t.Run("unknown_shell", func(t *testing.T) {
+		err := writeCompletion(&bytes.Buffer{}, "ksh", registry())
+		if err == nil || !strings.Contains(err.Error(), `unknown shell "ksh"`) {
+			t.Errorf("Expected unknown shell error, got: %v", err)
+		}
+	})
+}
+
+func TestCompletion_Bash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+
+	var buf bytes.Buffer
+	if err := writeCompletion(&buf, "bash", registry()); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "completion.bash")
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		words    string
+		expected string
+	}{
+		{name: "command", words: "youtube-webhook res", expected: "restore restore-backup"},
+		{name: "flag", words: "youtube-webhook renew -f", expected: "-force -format"},
+		{name: "action", words: "youtube-webhook config s", expected: "set"},
+		{name: "flag_value", words: "youtube-webhook list -format ", expected: ""},
+		{name: "global_flag", words: "youtube-webhook -f", expected: "-format"},
+		{name: "after_global_flags", words: "youtube-webhook -url https://example.com -format json ren", expected: "renew"},
+		{name: "subcommand_flag", words: "youtube-webhook config set -c", expected: "-channel"},
+		{name: "after_settings", words: "youtube-webhook config set lease_seconds=120h -f", expected: "-format"},
+		{name: "dir_value", words: "youtube-webhook docs man -dir /nonexistent-dir/", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			script := `source "$1"; read -ra COMP_WORDS <<< "$2"; COMP_CWORD=${#COMP_WORDS[@]}; ` +
+				`[[ "$2" == *" " ]] && COMP_WORDS+=("") || COMP_CWORD=$((COMP_CWORD-1)); ` +
+				`_youtube_webhook; echo "${COMPREPLY[*]}"`
+			output, err := exec.Command(bash, "-c", script, "bash", file, tc.words).CombinedOutput()
+			if err != nil {
+				t.Fatalf("Expected completion to run, got: %v, output: %s", err, string(output))
+			}
+			if got := strings.TrimSpace(string(output)); got != tc.expected {
+				t.Errorf("Expected completions %q for %q, got %q", tc.expected, tc.words, got)
+			}
+		})
+	}
+}
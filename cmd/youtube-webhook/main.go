@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/samsoir/youtube-webhook/cli/commands"
+	webhook "github.com/samsoir/youtube-webhook/function"
 )
 
 const (
@@ -14,193 +19,830 @@ const (
 )
 
 func main() {
-	// Define subcommands
-	subscribeCmd := flag.NewFlagSet("subscribe", flag.ExitOnError)
-	unsubscribeCmd := flag.NewFlagSet("unsubscribe", flag.ExitOnError)
-	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
-	renewCmd := flag.NewFlagSet("renew", flag.ExitOnError)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses the global flags, finds the command args name and runs it, returning the exit status
+func run(args []string) int {
+	opts := &options{}
+
+	global := flag.NewFlagSet("youtube-webhook", flag.ContinueOnError)
+	global.Usage = func() { printUsage(os.Stdout) }
+	globalFlags(global, opts)
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	args = global.Args()
+
+	// Check if a command is provided
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return exitUsage
+	}
+	if args[0] == "help" {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	path, args, err := findCommand(registry(), args)
+	if len(path) == 0 {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		printUsage(os.Stdout)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		printGroupUsage(os.Stderr, path)
+		return exitUsage
+	}
+	c := path[len(path)-1]
+
+	// Defaults come from the config file, then the environment; flags override both
+	file, err := loadConfigFile(opts)
+	if err != nil && !c.local {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	opts.baseURL = firstNonEmpty(opts.baseURL, os.Getenv("YOUTUBE_WEBHOOK_URL"), file.URL)
+	opts.signingSecret = firstNonEmpty(os.Getenv("YOUTUBE_WEBHOOK_SIGNING_SECRET"), file.SigningSecret)
+	opts.format = firstNonEmpty(opts.format, file.Format)
+	if opts.timeout == 0 {
+		opts.timeout = file.Timeout
+	}
+	if c.adminToken {
+		opts.token = firstNonEmpty(os.Getenv("YOUTUBE_WEBHOOK_ADMIN_TOKEN"), file.AdminToken)
+	} else {
+		opts.token = firstNonEmpty(os.Getenv("YOUTUBE_WEBHOOK_API_KEY"), file.APIKey)
+	}
+
+	cmd := flag.NewFlagSet(commandName(path), flag.ContinueOnError)
+	cmd.Usage = func() { printCommandUsage(os.Stderr, path, cmd) }
+	runCommand := defineFlags(cmd, c, opts)
+	if err := cmd.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if !c.local && opts.baseURL == "" {
+		err = usagef("-url flag or YOUTUBE_WEBHOOK_URL environment variable is required")
+	} else {
+		err = runCommand(cmd.Args())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if isUsageError(err) {
+			cmd.Usage()
+			return exitUsage
+		}
+		return exitFailure
+	}
+	return exitOK
+}
 
-	// Check if a subcommand is provided
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(1)
+// loadConfigFile resolves the config file's path into opts.configPath and reads it. A
+// missing file is only an error when -config or YOUTUBE_WEBHOOK_CONFIG named it.
+func loadConfigFile(opts *options) (commands.FileConfig, error) {
+	opts.configPath = firstNonEmpty(opts.configPath, os.Getenv("YOUTUBE_WEBHOOK_CONFIG"))
+	named := opts.configPath != ""
+	if !named {
+		path, err := commands.DefaultConfigPath()
+		if err != nil {
+			return commands.FileConfig{}, nil
+		}
+		opts.configPath = path
 	}
 
-	// Get the base URL from environment or flag
-	baseURL := os.Getenv("YOUTUBE_WEBHOOK_URL")
+	file, err := commands.LoadFileConfig(opts.configPath)
+	if errors.Is(err, fs.ErrNotExist) && !named {
+		return commands.FileConfig{}, nil
+	}
+	return file, err
+}
 
-	switch os.Args[1] {
-	case "subscribe":
-		handleSubscribe(subscribeCmd, baseURL)
-	case "unsubscribe":
-		handleUnsubscribe(unsubscribeCmd, baseURL)
-	case "list":
-		handleList(listCmd, baseURL)
-	case "renew":
-		handleRenew(renewCmd, baseURL)
-	case "help", "-h", "--help":
-		printUsage()
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", os.Args[1])
-		printUsage()
-		os.Exit(1)
+// firstNonEmpty returns the first of values that is set
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
 	}
+	return ""
 }
 
-func handleSubscribe(cmd *flag.FlagSet, defaultURL string) {
+func handleSubscribe(cmd *flag.FlagSet, opts *options) func(args []string) error {
 	var (
-		baseURL   = cmd.String("url", defaultURL, "Base URL of the webhook service (env: YOUTUBE_WEBHOOK_URL)")
-		channelID = cmd.String("channel", "", "YouTube channel ID to subscribe to (required)")
-		timeout   = cmd.Duration("timeout", defaultTimeout, "Request timeout")
+		channelID  = cmd.String("channel", "", "YouTube channel ID to subscribe to")
+		handle     = cmd.String("handle", "", "Channel @handle or URL to subscribe to, resolved by the service")
+		playlistID = cmd.String("playlist", "", "YouTube playlist ID to subscribe to instead of a channel")
+		file       = cmd.String("file", "", "File of channel IDs, @handles or channel URLs to subscribe to (one per line or a JSON array)")
+		hubURL     = cmd.String("hub", "", "Hub to subscribe with instead of the service's HUB_URL, such as a self-hosted WebSub hub")
+		workers    = cmd.Int("workers", commands.DefaultBulkWorkers, "Concurrent requests when using -file")
 	)
 
-	cmd.Parse(os.Args[2:])
+	return func(args []string) error {
+		if *channelID == "" && *handle == "" && *playlistID == "" && *file == "" {
+			return usagef("-channel flag is required (or -handle, -playlist, or -file for bulk operations)")
+		}
+
+		if *playlistID != "" && (*channelID != "" || *handle != "" || *file != "") {
+			return usagef("-playlist cannot be used with -channel, -handle or -file")
+		}
+
+		if *channelID != "" && *file != "" {
+			return usagef("-channel and -file cannot be used together")
+		}
+
+		if *handle != "" && (*channelID != "" || *file != "") {
+			return usagef("-handle cannot be used with -channel or -file")
+		}
+
+		if *hubURL != "" && *file != "" {
+			return usagef("-hub cannot be used with -file")
+		}
 
-	if *baseURL == "" {
-		fmt.Fprintln(os.Stderr, "Error: -url flag or YOUTUBE_WEBHOOK_URL environment variable is required")
-		cmd.Usage()
-		os.Exit(1)
+		if *file != "" {
+			return runBulk(commands.BulkSubscribe, *file, opts, *workers)
+		}
+
+		return commands.Subscribe(commands.SubscribeConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Handle:        *handle,
+			PlaylistID:    *playlistID,
+			HubURL:        *hubURL,
+			Timeout:       opts.timeout,
+		})
 	}
+}
+
+func handleUnsubscribe(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		channelID  = cmd.String("channel", "", "YouTube channel ID to unsubscribe from")
+		playlistID = cmd.String("playlist", "", "YouTube playlist ID to unsubscribe from")
+		file       = cmd.String("file", "", "File of channel IDs to unsubscribe from (one per line or a JSON array)")
+		workers    = cmd.Int("workers", commands.DefaultBulkWorkers, "Concurrent requests when using -file")
+	)
+
+	return func(args []string) error {
+		if *channelID == "" && *playlistID == "" && *file == "" {
+			return usagef("-channel flag is required (or -playlist, or -file for bulk operations)")
+		}
+
+		if *channelID != "" && *file != "" {
+			return usagef("-channel and -file cannot be used together")
+		}
+
+		if *playlistID != "" && (*channelID != "" || *file != "") {
+			return usagef("-playlist cannot be used with -channel or -file")
+		}
+		if *playlistID != "" {
+			*channelID = *playlistID
+		}
+
+		if *file != "" {
+			return runBulk(commands.BulkUnsubscribe, *file, opts, *workers)
+		}
 
-	if *channelID == "" {
-		fmt.Fprintln(os.Stderr, "Error: -channel flag is required")
-		cmd.Usage()
-		os.Exit(1)
+		return commands.Unsubscribe(commands.UnsubscribeConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Timeout:       opts.timeout,
+		})
 	}
+}
+
+func handleRestore(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	channelID := cmd.String("channel", "", "YouTube channel ID to restore")
+
+	return func(args []string) error {
+		if *channelID == "" {
+			return usagef("-channel flag is required")
+		}
 
-	config := commands.SubscribeConfig{
-		BaseURL:   *baseURL,
-		ChannelID: *channelID,
-		Timeout:   *timeout,
+		return commands.Restore(commands.RestoreConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Timeout:       opts.timeout,
+		})
 	}
+}
 
-	if err := commands.Subscribe(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+func handleImportSubscriptions(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		file    = cmd.String("file", "", "Google Takeout subscriptions.csv or OPML file of channels to subscribe to")
+		workers = cmd.Int("workers", commands.DefaultBulkWorkers, "Concurrent subscribe requests")
+		dryRun  = cmd.Bool("dry-run", false, "Only list the channels that would be subscribed")
+	)
+
+	return func(args []string) error {
+		if *file == "" {
+			return usagef("-file flag is required")
+		}
+
+		return commands.ImportSubscriptions(commands.ImportSubscriptionsConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			File:          *file,
+			Timeout:       opts.timeout,
+			Workers:       *workers,
+			DryRun:        *dryRun,
+		})
 	}
 }
 
-func handleUnsubscribe(cmd *flag.FlagSet, defaultURL string) {
+// runBulk reads channel IDs from file and applies a bulk command, which fails if any channel fails
+func runBulk(bulk func(commands.BulkConfig) error, file string, opts *options, workers int) error {
+	channelIDs, err := commands.ReadChannelFile(file)
+	if err != nil {
+		return err
+	}
+
+	return bulk(commands.BulkConfig{
+		BaseURL:       opts.baseURL,
+		APIKey:        opts.token,
+		SigningSecret: opts.signingSecret,
+		ChannelIDs:    channelIDs,
+		Timeout:       opts.timeout,
+		Workers:       workers,
+	})
+}
+
+func handleList(cmd *flag.FlagSet, opts *options) func(args []string) error {
 	var (
-		baseURL   = cmd.String("url", defaultURL, "Base URL of the webhook service (env: YOUTUBE_WEBHOOK_URL)")
-		channelID = cmd.String("channel", "", "YouTube channel ID to unsubscribe from (required)")
-		timeout   = cmd.Duration("timeout", defaultTimeout, "Request timeout")
+		format    = cmd.String("format", opts.formatOr("table"), "Output format: table, json or csv")
+		columns   = cmd.String("columns", "", "Comma-separated table columns: channel,name,type,status,expires,days_left,next_renewal,health,last_notification,last_video,last_run")
+		sortBy    = cmd.String("sort", "", "Column to sort by (prefix with - for descending, e.g. -sort -expires)")
+		status    = cmd.String("status", "", "Only list subscriptions in this status: pending, verified, active, expiring, expired or quarantined")
+		limit     = cmd.Int("limit", 0, "List one page of at most this many subscriptions (default: all)")
+		pageToken = cmd.String("page-token", "", "Continue a limited list from the page token it printed")
+		expiring  = cmd.Bool("expiring", false, "Only list subscriptions whose lease ends within -within, soonest first")
+		within    = cmd.Duration("within", 0, "Window for -expiring (default: the service's renewal threshold)")
 	)
 
-	cmd.Parse(os.Args[2:])
+	return func(args []string) error {
+		if *limit < 0 {
+			return usagef("-limit must not be negative")
+		}
+
+		if *within < 0 {
+			return usagef("-within must not be negative")
+		}
+
+		return commands.List(commands.ListConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			Timeout:       opts.timeout,
+			Format:        *format,
+			Columns:       commands.ParseColumns(*columns),
+			Sort:          *sortBy,
 
-	if *baseURL == "" {
-		fmt.Fprintln(os.Stderr, "Error: -url flag or YOUTUBE_WEBHOOK_URL environment variable is required")
-		cmd.Usage()
-		os.Exit(1)
+			Status:    *status,
+			Limit:     *limit,
+			PageToken: *pageToken,
+
+			Expiring: *expiring,
+			Within:   *within,
+		})
 	}
+}
 
-	if *channelID == "" {
-		fmt.Fprintln(os.Stderr, "Error: -channel flag is required")
-		cmd.Usage()
-		os.Exit(1)
+func handleRenew(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		verbose = cmd.Bool("verbose", false, "Show detailed renewal results")
+		channel = cmd.String("channel", "", "Comma-separated channel IDs to renew (default: every expiring subscription)")
+		force   = cmd.Bool("force", false, "Renew regardless of expiry and failure backoff")
+		format  = cmd.String("format", opts.formatOr("table"), "Output format: table or json")
+	)
+
+	return func(args []string) error {
+		config := commands.RenewConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			Timeout:       opts.timeout,
+			Verbose:       *verbose,
+			ChannelIDs:    splitKeywords(*channel),
+			Force:         *force,
+			Format:        *format,
+		}
+		// The progress bar redraws its line, so it is only drawn for people watching
+		if *format != "json" && isTerminal(os.Stderr) {
+			config.Progress = os.Stderr
+		}
+
+		return commands.Renew(config)
 	}
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func handleStatus(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		window = cmd.Duration("window", commands.DefaultStatusWindow, "How far back notifications are counted")
+		format = cmd.String("format", opts.formatOr("text"), "Output format: text or json")
+	)
 
-	config := commands.UnsubscribeConfig{
-		BaseURL:   *baseURL,
-		ChannelID: *channelID,
-		Timeout:   *timeout,
+	return func(args []string) error {
+		return commands.Status(commands.StatusConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			Timeout:       opts.timeout,
+			Window:        *window,
+			Format:        *format,
+		})
 	}
+}
 
-	if err := commands.Unsubscribe(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+func handleDoctor(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	format := cmd.String("format", opts.formatOr("text"), "Output format: text or json")
+
+	return func(args []string) error {
+		return commands.Doctor(commands.DoctorConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			Timeout:       opts.timeout,
+			Format:        *format,
+		})
 	}
 }
 
-func handleList(cmd *flag.FlagSet, defaultURL string) {
+func handleFilters(cmd *flag.FlagSet, opts *options) func(args []string) error {
 	var (
-		baseURL = cmd.String("url", defaultURL, "Base URL of the webhook service (env: YOUTUBE_WEBHOOK_URL)")
-		timeout = cmd.Duration("timeout", defaultTimeout, "Request timeout")
-		format  = cmd.String("format", "table", "Output format (table)")
+		channelID          = cmd.String("channel", "", "YouTube channel ID whose filters to show or change")
+		titlePattern       = cmd.String("title-pattern", "", "Regular expression new video titles must match")
+		include            = cmd.String("include", "", "Comma-separated keywords; titles must contain at least one")
+		exclude            = cmd.String("exclude", "", "Comma-separated keywords; titles containing any are skipped")
+		excludeShorts      = cmd.Bool("exclude-shorts", false, "Skip YouTube Shorts")
+		excludeLivestreams = cmd.Bool("exclude-livestreams", false, "Skip livestreams")
+		clear              = cmd.Bool("clear", false, "Remove all filters for the channel")
 	)
 
-	cmd.Parse(os.Args[2:])
+	return func(args []string) error {
+		if *channelID == "" {
+			return usagef("-channel flag is required")
+		}
+
+		// Any rule flag replaces the channel's whole filter; no rule flags shows it
+		var filter *webhook.NotificationFilter
+		cmd.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "title-pattern", "include", "exclude", "exclude-shorts", "exclude-livestreams":
+				filter = &webhook.NotificationFilter{
+					TitlePattern:       *titlePattern,
+					IncludeKeywords:    splitKeywords(*include),
+					ExcludeKeywords:    splitKeywords(*exclude),
+					ExcludeShorts:      *excludeShorts,
+					ExcludeLivestreams: *excludeLivestreams,
+				}
+			}
+		})
+
+		if *clear && filter != nil {
+			return usagef("-clear cannot be combined with filter flags")
+		}
 
-	if *baseURL == "" {
-		fmt.Fprintln(os.Stderr, "Error: -url flag or YOUTUBE_WEBHOOK_URL environment variable is required")
-		cmd.Usage()
-		os.Exit(1)
+		return commands.Filters(commands.FiltersConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Timeout:       opts.timeout,
+			Filter:        filter,
+			Clear:         *clear,
+		})
 	}
+}
+
+func handleConfigGet(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		channelID = cmd.String("channel", "", "YouTube channel ID whose configuration to show")
+		format    = cmd.String("format", opts.formatOr("table"), "Output format: table or json")
+	)
 
-	config := commands.ListConfig{
-		BaseURL: *baseURL,
-		Timeout: *timeout,
-		Format:  *format,
+	return func(args []string) error {
+		if *channelID == "" {
+			return usagef("-channel flag is required")
+		}
+
+		if len(args) > 0 {
+			return usagef("config get takes no settings; use config set to change them")
+		}
+
+		return commands.Config(commands.ConfigCommandConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Timeout:       opts.timeout,
+			Format:        *format,
+		})
 	}
+}
 
-	if err := commands.List(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+func handleConfigSet(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		channelID = cmd.String("channel", "", "YouTube channel ID whose configuration to change")
+		format    = cmd.String("format", opts.formatOr("table"), "Output format: table or json")
+	)
+	usage := cmd.Usage
+	cmd.Usage = func() {
+		usage()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Settings:")
+		fmt.Fprintln(os.Stderr, "  lease_seconds  Lease to request from the hub: seconds, a duration such as 120h, or default")
+		fmt.Fprintln(os.Stderr, "  filter         Notification filter as JSON, or none")
+		fmt.Fprintln(os.Stderr, "  sinks          Webhook sinks as a JSON list, or none")
+	}
+
+	return func(args []string) error {
+		if *channelID == "" {
+			return usagef("-channel flag is required")
+		}
+
+		if len(args) == 0 {
+			return usagef("config set needs at least one key=value setting")
+		}
+
+		return commands.Config(commands.ConfigCommandConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Timeout:       opts.timeout,
+			Settings:      args,
+			Format:        *format,
+		})
 	}
 }
 
-func handleRenew(cmd *flag.FlagSet, defaultURL string) {
+func handleConfigInit(cmd *flag.FlagSet, opts *options) func(args []string) error {
 	var (
-		baseURL = cmd.String("url", defaultURL, "Base URL of the webhook service (env: YOUTUBE_WEBHOOK_URL)")
-		timeout = cmd.Duration("timeout", 60*time.Second, "Request timeout")
-		verbose = cmd.Bool("verbose", false, "Show detailed renewal results")
+		url   = cmd.String("url", opts.baseURL, "Base URL of the webhook service to write into the file")
+		force = cmd.Bool("force", false, "Overwrite an existing config file")
 	)
 
-	cmd.Parse(os.Args[2:])
+	return func(args []string) error {
+		if opts.configPath == "" {
+			return errors.New("no config file path: set -config or YOUTUBE_WEBHOOK_CONFIG, as the home directory is unknown")
+		}
 
-	if *baseURL == "" {
-		fmt.Fprintln(os.Stderr, "Error: -url flag or YOUTUBE_WEBHOOK_URL environment variable is required")
-		cmd.Usage()
-		os.Exit(1)
+		return commands.ConfigInit(commands.ConfigInitConfig{
+			Path:  opts.configPath,
+			URL:   *url,
+			Force: *force,
+		})
 	}
+}
 
-	config := commands.RenewConfig{
-		BaseURL: *baseURL,
-		Timeout: *timeout,
-		Verbose: *verbose,
+// splitKeywords splits a comma-separated flag value, dropping blank entries
+func splitKeywords(value string) []string {
+	var keywords []string
+	for _, keyword := range strings.Split(value, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
 	}
+	return keywords
+}
 
-	if err := commands.Renew(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-}
-
-func printUsage() {
-	fmt.Println("YouTube Webhook CLI - Manage YouTube PubSubHubbub subscriptions")
-	fmt.Println()
-	fmt.Println("Usage:")
-	fmt.Println("  youtube-webhook <command> [flags]")
-	fmt.Println()
-	fmt.Println("Commands:")
-	fmt.Println("  subscribe    Subscribe to a YouTube channel")
-	fmt.Println("  unsubscribe  Unsubscribe from a YouTube channel")
-	fmt.Println("  list         List all subscriptions")
-	fmt.Println("  renew        Trigger renewal of expiring subscriptions")
-	fmt.Println("  help         Show this help message")
-	fmt.Println()
-	fmt.Println("Environment Variables:")
-	fmt.Println("  YOUTUBE_WEBHOOK_URL  Base URL of the webhook service (can be overridden with -url flag)")
-	fmt.Println()
-	fmt.Println("Examples:")
-	fmt.Println("  # Set the base URL via environment variable")
-	fmt.Println("  export YOUTUBE_WEBHOOK_URL=https://your-function.run.app")
-	fmt.Println()
-	fmt.Println("  # Subscribe to a channel")
-	fmt.Println("  youtube-webhook subscribe -channel UCXuqSBlHAE6Xw-yeJA0Tunw")
-	fmt.Println()
-	fmt.Println("  # List all subscriptions")
-	fmt.Println("  youtube-webhook list")
-	fmt.Println()
-	fmt.Println("  # Unsubscribe from a channel")
-	fmt.Println("  youtube-webhook unsubscribe -channel UCXuqSBlHAE6Xw-yeJA0Tunw")
-	fmt.Println()
-	fmt.Println("  # Renew expiring subscriptions (verbose output)")
-	fmt.Println("  youtube-webhook renew -verbose")
-	fmt.Println()
-	fmt.Println("  # Override the URL for a specific command")
-	fmt.Println("  youtube-webhook list -url https://different-function.run.app")
-	fmt.Println()
-	fmt.Println("Use '<command> -h' for more information about a command.")
-}
\ No newline at end of file
+func handleHistory(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		channelID = cmd.String("channel", "", "YouTube channel ID whose notification history to show")
+		limit     = cmd.Int("limit", 0, "Show only the most recent notifications (0 shows the whole stored history)")
+		format    = cmd.String("format", opts.formatOr("table"), "Output format: table or json")
+	)
+
+	return func(args []string) error {
+		if *channelID == "" {
+			return usagef("-channel flag is required")
+		}
+
+		if *limit < 0 {
+			return usagef("-limit must not be negative")
+		}
+
+		return commands.History(commands.HistoryConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Limit:         *limit,
+			Timeout:       opts.timeout,
+			Format:        *format,
+		})
+	}
+}
+
+func handleDeadLetters(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		id     = cmd.String("id", "", "Dead letter to show, or to replay with -replay")
+		replay = cmd.Bool("replay", false, "Replay the -id dead letter, or every dead letter without -id")
+		format = cmd.String("format", opts.formatOr("table"), "Output format: table or json")
+	)
+
+	return func(args []string) error {
+		return commands.DeadLetters(commands.DeadLettersConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ID:            *id,
+			Replay:        *replay,
+			Timeout:       opts.timeout,
+			Format:        *format,
+		})
+	}
+}
+
+func handleDashboard(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	interval := cmd.Duration("interval", commands.DefaultDashboardInterval, "How often to fetch subscriptions")
+
+	return func(args []string) error {
+		if *interval <= 0 {
+			return usagef("-interval must be positive")
+		}
+
+		return commands.Dashboard(commands.DashboardConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			Interval:      *interval,
+			Timeout:       opts.timeout,
+		})
+	}
+}
+
+func handleWatch(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		channelID = cmd.String("channel", "", "Only show this YouTube channel's notifications")
+		last      = cmd.Int("last", 10, "Recent notifications to show before waiting for new ones")
+		interval  = cmd.Duration("interval", commands.DefaultWatchInterval, "How often to poll for new notifications when the service cannot stream them, and how long to wait before reconnecting")
+		asJSON    = cmd.Bool("json", opts.format == "json", "Print each notification as one line of JSON")
+	)
+
+	return func(args []string) error {
+		if *last < 0 {
+			return usagef("-last must not be negative")
+		}
+
+		if *interval <= 0 {
+			return usagef("-interval must be positive")
+		}
+
+		return commands.Watch(commands.WatchConfig{
+			BaseURL:       opts.baseURL,
+			APIKey:        opts.token,
+			SigningSecret: opts.signingSecret,
+			ChannelID:     *channelID,
+			Last:          *last,
+			Interval:      *interval,
+			JSON:          *asJSON,
+			Timeout:       opts.timeout,
+		})
+	}
+}
+
+func handleExport(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		file   = cmd.String("file", "", "File to write the state to (default: stdout)")
+		format = cmd.String("format", opts.formatOr(""), "State format: json or yaml (default: from the file extension, then json)")
+	)
+
+	return func(args []string) error {
+		return commands.Export(commands.ExportConfig{
+			BaseURL:    opts.baseURL,
+			AdminToken: opts.token,
+			Timeout:    opts.timeout,
+			File:       *file,
+			Format:     *format,
+		})
+	}
+}
+
+func handleImport(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		file   = cmd.String("file", "", "State file written by export")
+		format = cmd.String("format", "", "State format: json or yaml (default: from the file extension, then json)")
+		mode   = cmd.String("mode", webhook.ImportModeReplace, "replace the whole state, or merge into the stored subscriptions")
+		dryRun = cmd.Bool("dry-run", false, "Only show what the import would change")
+		yes    = cmd.Bool("yes", false, "Import without asking for confirmation")
+	)
+
+	return func(args []string) error {
+		if *file == "" {
+			return usagef("-file flag is required")
+		}
+
+		return commands.Import(commands.ImportConfig{
+			BaseURL:    opts.baseURL,
+			AdminToken: opts.token,
+			Timeout:    opts.timeout,
+			File:       *file,
+			Format:     *format,
+			Mode:       *mode,
+			DryRun:     *dryRun,
+			Yes:        *yes,
+		})
+	}
+}
+
+func handleRestoreBackup(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	var (
+		name   = cmd.String("name", "", "Backup to restore, e.g. state-20250121103000.json (default: list the backups)")
+		dryRun = cmd.Bool("dry-run", false, "Only show what the restore would change")
+		yes    = cmd.Bool("yes", false, "Restore without asking for confirmation")
+	)
+
+	return func(args []string) error {
+		return commands.RestoreBackup(commands.RestoreBackupConfig{
+			BaseURL:    opts.baseURL,
+			AdminToken: opts.token,
+			Timeout:    opts.timeout,
+			Name:       *name,
+			DryRun:     *dryRun,
+			Yes:        *yes,
+		})
+	}
+}
+
+// printCommandUsage prints a command's synopsis, summary and flags
+func printCommandUsage(w io.Writer, path []command, cmd *flag.FlagSet) {
+	c := path[len(path)-1]
+	synopsis := "youtube-webhook " + commandName(path) + " [flags]"
+	if c.usage != "" {
+		synopsis += " " + c.usage
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n", synopsis)
+	fmt.Fprintln(w, c.summary)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	cmd.SetOutput(w)
+	cmd.PrintDefaults()
+}
+
+// printGroupUsage prints the subcommands of a command that groups them
+func printGroupUsage(w io.Writer, path []command) {
+	c := path[len(path)-1]
+	fmt.Fprintf(w, "Usage: youtube-webhook %s <subcommand> [flags]\n\n", commandName(path))
+	fmt.Fprintln(w, "Subcommands:")
+	printCommandList(w, c.subcommands, false)
+}
+
+// printCommandList prints commands and their summaries in aligned columns
+func printCommandList(w io.Writer, cmds []command, withHelp bool) {
+	width := len("help")
+	for _, c := range cmds {
+		width = max(width, len(c.name))
+	}
+	for _, c := range cmds {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, c.name, c.summary)
+	}
+	if withHelp {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, "help", "Show this help message")
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "YouTube Webhook CLI - Manage YouTube PubSubHubbub subscriptions")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  youtube-webhook [global flags] <command> [subcommand] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	printCommandList(w, registry(), true)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global Flags (before the command; the command's own flags override them):")
+	global := flag.NewFlagSet("youtube-webhook", flag.ContinueOnError)
+	globalFlags(global, &options{})
+	global.SetOutput(w)
+	global.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Environment Variables:")
+	width := 0
+	for _, v := range environmentVariables {
+		width = max(width, len(v.name))
+	}
+	for _, v := range environmentVariables {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, v.name, v.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit Status:")
+	for _, s := range exitStatuses {
+		fmt.Fprintf(w, "  %d  %s\n", s.status, s.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintln(w, "  # Set the base URL via environment variable")
+	fmt.Fprintln(w, "  export YOUTUBE_WEBHOOK_URL=https://your-function.run.app")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Or keep it, with credentials and a default format, in ~/.youtube-webhook.yaml")
+	fmt.Fprintln(w, "  youtube-webhook config init -url https://your-function.run.app")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Subscribe to a channel")
+	fmt.Fprintln(w, "  youtube-webhook subscribe -channel UCXuqSBlHAE6Xw-yeJA0Tunw")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Subscribe to a channel by its handle or URL (the service needs YOUTUBE_API_KEY)")
+	fmt.Fprintln(w, "  youtube-webhook subscribe -handle @SomeCreator")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Subscribe to a playlist's feed; manage it by its playlist ID like a channel")
+	fmt.Fprintln(w, "  youtube-webhook subscribe -playlist PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Subscribe with a self-hosted WebSub hub instead of the service's default")
+	fmt.Fprintln(w, "  youtube-webhook subscribe -channel UCXuqSBlHAE6Xw-yeJA0Tunw -hub https://hub.example.com/")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Subscribe to every channel in a file (one ID per line or a JSON array)")
+	fmt.Fprintln(w, "  youtube-webhook subscribe -file channels.txt -workers 8")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Subscribe to every channel you follow on YouTube, from a Google Takeout export")
+	fmt.Fprintln(w, "  youtube-webhook import-subscriptions -file subscriptions.csv -dry-run")
+	fmt.Fprintln(w, "  youtube-webhook import-subscriptions -file subscriptions.csv")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # List all subscriptions")
+	fmt.Fprintln(w, "  youtube-webhook list")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Pick table columns and sort by expiry")
+	fmt.Fprintln(w, "  youtube-webhook list -columns channel,expires,last_video -sort expires")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Show the 20 expired subscriptions that lapsed first")
+	fmt.Fprintln(w, "  youtube-webhook list -status expired -sort expires -limit 20")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Show the subscriptions whose lease ends in the next 6 hours")
+	fmt.Fprintln(w, "  youtube-webhook list -expiring -within 6h")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Unsubscribe from a channel")
+	fmt.Fprintln(w, "  youtube-webhook unsubscribe -channel UCXuqSBlHAE6Xw-yeJA0Tunw")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Undo an unsubscribe, keeping the channel's repositories, sinks and filter")
+	fmt.Fprintln(w, "  youtube-webhook restore -channel UCXuqSBlHAE6Xw-yeJA0Tunw")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Renew expiring subscriptions (verbose output)")
+	fmt.Fprintln(w, "  youtube-webhook renew -verbose")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Renew and print the full results as JSON for scripts")
+	fmt.Fprintln(w, "  youtube-webhook renew -format json")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Renew one channel now, however long its lease has left")
+	fmt.Fprintln(w, "  youtube-webhook renew -channel UCXuqSBlHAE6Xw-yeJA0Tunw -force")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Check the deployment at a glance")
+	fmt.Fprintln(w, "  youtube-webhook status")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Find out why notifications are not arriving, with a fix for each problem")
+	fmt.Fprintln(w, "  youtube-webhook doctor")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Only dispatch full-length videos whose titles mention Go")
+	fmt.Fprintln(w, "  youtube-webhook filters -channel UCXuqSBlHAE6Xw-yeJA0Tunw -include go,golang -exclude-shorts")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Ask the hub for a 5-day lease for one channel and drop its filter")
+	fmt.Fprintln(w, "  youtube-webhook config set -channel UCXuqSBlHAE6Xw-yeJA0Tunw lease_seconds=120h filter=none")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Show what happened to a channel's last 10 notifications")
+	fmt.Fprintln(w, "  youtube-webhook history -channel UCXuqSBlHAE6Xw-yeJA0Tunw -limit 10")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Replay every stored unparseable notification")
+	fmt.Fprintln(w, "  youtube-webhook deadletters -replay")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Stream one channel's notifications as JSON lines")
+	fmt.Fprintln(w, "  youtube-webhook watch -channel UCXuqSBlHAE6Xw-yeJA0Tunw -json | jq .video_id")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Watch subscriptions, refreshing every 30 seconds (arrows select, r renews, u unsubscribes, q quits)")
+	fmt.Fprintln(w, "  youtube-webhook dashboard -interval 30s")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Back up every subscription, then restore it into another deployment")
+	fmt.Fprintln(w, "  youtube-webhook export -file subscriptions.yaml")
+	fmt.Fprintln(w, "  youtube-webhook import -file subscriptions.yaml -url https://new-function.run.app")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Roll the subscription state back to an automatic backup")
+	fmt.Fprintln(w, "  youtube-webhook restore-backup")
+	fmt.Fprintln(w, "  youtube-webhook restore-backup -name state-20250121103000.json")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Enable tab completion in bash, and install the man pages")
+	fmt.Fprintln(w, "  source <(youtube-webhook completion bash)")
+	fmt.Fprintln(w, "  youtube-webhook docs man -dir /usr/local/share/man/man1")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Point every command at another deployment, with JSON output where commands have it")
+	fmt.Fprintln(w, "  youtube-webhook -url https://different-function.run.app -format json list")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  # Override the URL for a specific command")
+	fmt.Fprintln(w, "  youtube-webhook list -url https://different-function.run.app")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Use '<command> -h' for more information about a command.")
+}
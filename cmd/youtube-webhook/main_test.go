@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -44,6 +45,11 @@ func TestMain_Help(t *testing.T) {
 			if !strings.Contains(outputStr, "YOUTUBE_WEBHOOK_URL") {
 				t.Error("Expected help output to mention YOUTUBE_WEBHOOK_URL environment variable")
 			}
+
+			// The service's configuration report is for the service, not the CLI
+			if strings.Contains(outputStr, "Configuration has") {
+				t.Errorf("Expected no configuration report from the CLI, got %q", outputStr)
+			}
 		})
 	}
 }
@@ -60,8 +66,8 @@ func TestMain_NoCommand(t *testing.T) {
 		t.Error("Expected command to fail when no subcommand provided")
 	}
 	
-	if cmd.ProcessState.ExitCode() != 1 {
-		t.Errorf("Expected exit code 1, got %d", cmd.ProcessState.ExitCode())
+	if cmd.ProcessState.ExitCode() != exitUsage {
+		t.Errorf("Expected exit code %d, got %d", exitUsage, cmd.ProcessState.ExitCode())
 	}
 	
 	outputStr := string(output)
@@ -82,8 +88,8 @@ func TestMain_UnknownCommand(t *testing.T) {
 		t.Error("Expected command to fail with unknown command")
 	}
 	
-	if cmd.ProcessState.ExitCode() != 1 {
-		t.Errorf("Expected exit code 1, got %d", cmd.ProcessState.ExitCode())
+	if cmd.ProcessState.ExitCode() != exitUsage {
+		t.Errorf("Expected exit code %d, got %d", exitUsage, cmd.ProcessState.ExitCode())
 	}
 	
 	outputStr := string(output)
@@ -149,6 +155,26 @@ func TestMain_Subscribe_MissingFlags(t *testing.T) {
 			args: []string{"subscribe", "-url", "https://example.com"},
 			expectedError: "-channel flag is required",
 		},
+		{
+			name: "channel_and_file",
+			args: []string{"subscribe", "-url", "https://example.com", "-channel", "UCXuqSBlHAE6Xw-yeJA0Tunw", "-file", "channels.txt"},
+			expectedError: "-channel and -file cannot be used together",
+		},
+		{
+			name: "handle_and_channel",
+			args: []string{"subscribe", "-url", "https://example.com", "-channel", "UCXuqSBlHAE6Xw-yeJA0Tunw", "-handle", "@SomeCreator"},
+			expectedError: "-handle cannot be used with -channel or -file",
+		},
+		{
+			name: "playlist_and_channel",
+			args: []string{"subscribe", "-url", "https://example.com", "-channel", "UCXuqSBlHAE6Xw-yeJA0Tunw", "-playlist", "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"},
+			expectedError: "-playlist cannot be used with -channel, -handle or -file",
+		},
+		{
+			name: "hub_with_file",
+			args: []string{"subscribe", "-url", "https://example.com", "-file", "channels.txt", "-hub", "https://hub.example.com/"},
+			expectedError: "-hub cannot be used with -file",
+		},
 	}
 	
 	for _, tc := range testCases {
@@ -168,6 +194,305 @@ func TestMain_Subscribe_MissingFlags(t *testing.T) {
 	}
 }
 
+func TestMain_Filters_InvalidFlags(t *testing.T) {
+	binaryPath := buildCLIBinary(t)
+	defer os.Remove(binaryPath)
+
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "missing_channel",
+			args:          []string{"filters", "-url", "https://example.com"},
+			expectedError: "-channel flag is required",
+		},
+		{
+			name:          "clear_with_rules",
+			args:          []string{"filters", "-url", "https://example.com", "-channel", "UCXuqSBlHAE6Xw-yeJA0Tunw", "-clear", "-exclude-shorts"},
+			expectedError: "-clear cannot be combined with filter flags",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tc.args...)
+			output, err := cmd.CombinedOutput()
+
+			if err == nil {
+				t.Error("Expected command to fail with invalid flags")
+			}
+
+			if !strings.Contains(string(output), tc.expectedError) {
+				t.Errorf("Expected error message '%s', got: %s", tc.expectedError, string(output))
+			}
+		})
+	}
+}
+
+func TestMain_Config_InvalidArgs(t *testing.T) {
+	binaryPath := buildCLIBinary(t)
+	defer os.Remove(binaryPath)
+
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{
+			name:          "missing_action",
+			args:          []string{"config", "-url", "https://example.com"},
+			expectedError: "config needs a get, set or init subcommand",
+		},
+		{
+			name:          "set_without_settings",
+			args:          []string{"config", "set", "-url", "https://example.com", "-channel", "UCXuqSBlHAE6Xw-yeJA0Tunw"},
+			expectedError: "config set needs at least one key=value setting",
+		},
+		{
+			name:          "get_with_settings",
+			args:          []string{"config", "get", "-url", "https://example.com", "-channel", "UCXuqSBlHAE6Xw-yeJA0Tunw", "filter=none"},
+			expectedError: "config get takes no settings",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tc.args...)
+			output, err := cmd.CombinedOutput()
+
+			if err == nil {
+				t.Error("Expected command to fail with invalid arguments")
+			}
+
+			if !strings.Contains(string(output), tc.expectedError) {
+				t.Errorf("Expected error message '%s', got: %s", tc.expectedError, string(output))
+			}
+		})
+	}
+}
+
+func TestMain_Import_MissingFile(t *testing.T) {
+	binaryPath := buildCLIBinary(t)
+	defer os.Remove(binaryPath)
+
+	cmd := exec.Command(binaryPath, "import", "-url", "https://example.com")
+	output, err := cmd.CombinedOutput()
+
+	if err == nil {
+		t.Error("Expected import to fail without -file")
+	}
+
+	if !strings.Contains(string(output), "-file flag is required") {
+		t.Errorf("Expected missing file error, got: %s", string(output))
+	}
+}
+
+func TestMain_GlobalFlags(t *testing.T) {
+	binaryPath := buildCLIBinary(t)
+	defer os.Remove(binaryPath)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/subscriptions" && r.Method == "GET" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{
+				Subscriptions: []webhook.SubscriptionInfo{{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "active"}},
+				Total:         1,
+				Active:        1,
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	t.Run("apply_to_the_command", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "-url", server.URL, "-format", "json", "list")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Expected list with global flags to succeed, got: %v", err)
+		}
+
+		var resp webhook.SubscriptionsListResponse
+		if err := json.Unmarshal(output, &resp); err != nil {
+			t.Fatalf("Expected -format json to print JSON, got: %v, output: %s", err, string(output))
+		}
+		if resp.Total != 1 {
+			t.Errorf("Expected 1 subscription, got %d", resp.Total)
+		}
+	})
+
+	t.Run("command_flags_override", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "-url", "http://127.0.0.1:1", "-format", "json", "list", "-url", server.URL, "-format", "csv")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Expected list to use its own -url, got: %v", err)
+		}
+		if !strings.HasPrefix(string(output), "channel_id,") {
+			t.Errorf("Expected the command's -format csv to win, got: %s", string(output))
+		}
+	})
+
+	t.Run("unknown_global_flag", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "-verbose", "list")
+		cmd.CombinedOutput()
+		if cmd.ProcessState.ExitCode() != exitUsage {
+			t.Errorf("Expected exit code %d, got %d", exitUsage, cmd.ProcessState.ExitCode())
+		}
+	})
+}
+
+func TestMain_ConfigFile(t *testing.T) {
+	binaryPath := buildCLIBinary(t)
+	defer os.Remove(binaryPath)
+
+	// The Authorization header the server was last sent, set by its handler goroutine
+	var authorization atomic.Value
+	authorization.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{})
+	}))
+	defer server.Close()
+
+	home := t.TempDir()
+	config := "url: http://127.0.0.1:1\napi_key: file-key\nformat: json\n"
+	if err := os.WriteFile(filepath.Join(home, ".youtube-webhook.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// env runs the CLI with home as the home directory and no YOUTUBE_WEBHOOK_* variables but extra
+	env := func(extra ...string) []string {
+		vars := []string{"HOME=" + home}
+		for _, v := range os.Environ() {
+			if !strings.HasPrefix(v, "YOUTUBE_WEBHOOK_") && !strings.HasPrefix(v, "HOME=") {
+				vars = append(vars, v)
+			}
+		}
+		return append(vars, extra...)
+	}
+
+	t.Run("file_defaults", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "list")
+		cmd.Env = env()
+		output, _ := cmd.CombinedOutput()
+		if cmd.ProcessState.ExitCode() != exitFailure || !strings.Contains(string(output), "127.0.0.1:1") {
+			t.Errorf("Expected list to call the file's URL, got: %s", string(output))
+		}
+	})
+
+	t.Run("env_overrides_file", func(t *testing.T) {
+		authorization.Store("")
+		cmd := exec.Command(binaryPath, "list")
+		cmd.Env = env("YOUTUBE_WEBHOOK_URL="+server.URL, "YOUTUBE_WEBHOOK_API_KEY=env-key")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Expected list to use YOUTUBE_WEBHOOK_URL, got: %v", err)
+		}
+		if !json.Valid(output) {
+			t.Errorf("Expected the file's format json to apply, got: %s", string(output))
+		}
+		if got := authorization.Load().(string); got != "Bearer env-key" {
+			t.Errorf("Expected the environment's API key, got %q", got)
+		}
+	})
+
+	t.Run("flags_override_env", func(t *testing.T) {
+		authorization.Store("")
+		cmd := exec.Command(binaryPath, "list", "-url", server.URL, "-api-key", "flag-key", "-format", "csv")
+		cmd.Env = env("YOUTUBE_WEBHOOK_URL=http://127.0.0.1:1", "YOUTUBE_WEBHOOK_API_KEY=env-key")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Expected list to use its -url, got: %v", err)
+		}
+		if !strings.HasPrefix(string(output), "channel_id,") {
+			t.Errorf("Expected -format csv to override the file, got: %s", string(output))
+		}
+		if got := authorization.Load().(string); got != "Bearer flag-key" {
+			t.Errorf("Expected the -api-key flag, got %q", got)
+		}
+	})
+
+	t.Run("file_key_used", func(t *testing.T) {
+		authorization.Store("")
+		cmd := exec.Command(binaryPath, "-url", server.URL, "list")
+		cmd.Env = env()
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Expected list to succeed, got: %v, output: %s", err, string(output))
+		}
+		if got := authorization.Load().(string); got != "Bearer file-key" {
+			t.Errorf("Expected the file's API key, got %q", got)
+		}
+	})
+
+	t.Run("missing_named_file", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "-config", filepath.Join(home, "missing.yaml"), "list")
+		cmd.Env = env()
+		output, _ := cmd.CombinedOutput()
+		if cmd.ProcessState.ExitCode() != exitFailure || !strings.Contains(string(output), "missing.yaml") {
+			t.Errorf("Expected a missing -config file to fail, got: %s", string(output))
+		}
+	})
+
+	t.Run("init", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		cmd := exec.Command(binaryPath, "config", "init", "-url", server.URL)
+		cmd.Env = env("YOUTUBE_WEBHOOK_CONFIG=" + path)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Expected config init to succeed, got: %v, output: %s", err, string(output))
+		}
+
+		cmd = exec.Command(binaryPath, "list")
+		cmd.Env = env("YOUTUBE_WEBHOOK_CONFIG=" + path)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("Expected list to use the written URL, got: %v, output: %s", err, string(output))
+		}
+
+		cmd = exec.Command(binaryPath, "-config", path, "config", "init")
+		cmd.Env = env()
+		output, _ := cmd.CombinedOutput()
+		if cmd.ProcessState.ExitCode() != exitFailure || !strings.Contains(string(output), "-force") {
+			t.Errorf("Expected config init to refuse to overwrite, got: %s", string(output))
+		}
+	})
+}
+
+func TestMain_ExitCodes(t *testing.T) {
+	binaryPath := buildCLIBinary(t)
+	defer os.Remove(binaryPath)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{name: "help", args: []string{"list", "-h"}, expected: exitOK},
+		{name: "missing_flag", args: []string{"subscribe", "-url", server.URL}, expected: exitUsage},
+		{name: "unknown_flag", args: []string{"list", "-url", server.URL, "-bogus"}, expected: exitUsage},
+		{name: "bad_flag_value", args: []string{"list", "-url", server.URL, "-limit", "many"}, expected: exitUsage},
+		{name: "missing_subcommand", args: []string{"config", "-url", server.URL}, expected: exitUsage},
+		{name: "service_error", args: []string{"list", "-url", server.URL}, expected: exitFailure},
+		{name: "local_command", args: []string{"completion", "bash"}, expected: exitOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tc.args...)
+			output, _ := cmd.CombinedOutput()
+			if cmd.ProcessState.ExitCode() != tc.expected {
+				t.Errorf("Expected exit code %d, got %d, output: %s", tc.expected, cmd.ProcessState.ExitCode(), string(output))
+			}
+		})
+	}
+}
+
 // TestMain_List tests the list command integration
 func TestMain_List(t *testing.T) {
 	binaryPath := buildCLIBinary(t)
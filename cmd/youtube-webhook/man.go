@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func handleDocsMan(cmd *flag.FlagSet, opts *options) func(args []string) error {
+	dir := cmd.String("dir", ".", "Directory to write the man pages to")
+
+	return func(args []string) error {
+		pages, err := writeManPages(*dir, registry())
+		if err != nil {
+			return err
+		}
+		for _, page := range pages {
+			fmt.Println(page)
+		}
+		return nil
+	}
+}
+
+// writeManPages writes youtube-webhook(1) and a page per command to dir, returning their paths
+func writeManPages(dir string, cmds []command) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var buf bytes.Buffer
+	writeMainManPage(&buf, cmds)
+	path := filepath.Join(dir, "youtube-webhook.1")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	pages := []string{path}
+
+	var err error
+	walkCommands(cmds, nil, func(commandPath []command) {
+		if err != nil {
+			return
+		}
+		buf.Reset()
+		writeCommandManPage(&buf, commandPath)
+		path := filepath.Join(dir, manPageName(commandPath)+".1")
+		if err = os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			err = fmt.Errorf("failed to write %s: %w", path, err)
+			return
+		}
+		pages = append(pages, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// manPageName names a command's man page, such as youtube-webhook-config-set
+func manPageName(path []command) string {
+	return "youtube-webhook-" + strings.Join(commandNames(path), "-")
+}
+
+// manPageRef refers to a command's man page in roff, such as youtube-webhook-list(1) in bold
+func manPageRef(path []command) string {
+	return fmt.Sprintf(`\fB%s\fR(1)`, roffEscape(manPageName(path)))
+}
+
+func writeMainManPage(w io.Writer, cmds []command) {
+	fmt.Fprintln(w, `.TH YOUTUBE\-WEBHOOK 1 "" "youtube-webhook" "YouTube Webhook CLI"`)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintln(w, `youtube\-webhook \- manage YouTube PubSubHubbub subscriptions`)
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintln(w, `.B youtube\-webhook`)
+	fmt.Fprintln(w, `[\fIglobal flags\fR] \fIcommand\fR [\fIsubcommand\fR] [\fIflags\fR]`)
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, "Manages the subscriptions of a deployed YouTube Webhook Service through its management API.")
+	fmt.Fprintln(w, ".SH COMMANDS")
+	writeManCommandList(w, nil, cmds)
+
+	fmt.Fprintln(w, ".SH GLOBAL OPTIONS")
+	fmt.Fprintln(w, "Given before the command, these apply to every command that has them; the command's own flags override them.")
+	global := flag.NewFlagSet("youtube-webhook", flag.ContinueOnError)
+	globalFlags(global, &options{})
+	var flags []*flag.Flag
+	global.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	writeManFlags(w, flags)
+
+	fmt.Fprintln(w, ".SH ENVIRONMENT")
+	for _, v := range environmentVariables {
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".B %s\n", v.name)
+		fmt.Fprintln(w, roffEscape(v.description))
+	}
+	writeManExitStatus(w)
+
+	fmt.Fprintln(w, ".SH SEE ALSO")
+	refs := make([]string, len(cmds))
+	for i, c := range cmds {
+		refs[i] = manPageRef([]command{c})
+	}
+	fmt.Fprintln(w, strings.Join(refs, ",\n"))
+}
+
+func writeCommandManPage(w io.Writer, path []command) {
+	c := path[len(path)-1]
+	name := roffEscape(manPageName(path))
+
+	fmt.Fprintf(w, ".TH %s 1 \"\" \"youtube-webhook\" \"YouTube Webhook CLI\"\n", strings.ToUpper(name))
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintf(w, "%s \\- %s\n", name, roffEscape(c.summary))
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B youtube\\-webhook %s\n", roffEscape(commandName(path)))
+	if len(c.subcommands) > 0 {
+		fmt.Fprintln(w, `\fIsubcommand\fR [\fIflags\fR]`)
+		fmt.Fprintln(w, ".SH COMMANDS")
+		writeManCommandList(w, path, c.subcommands)
+	} else {
+		usage := "[flags]"
+		if c.usage != "" {
+			usage += " " + c.usage
+		}
+		fmt.Fprintln(w, roffEscape(usage))
+		if flags := c.flags(); len(flags) > 0 {
+			fmt.Fprintln(w, ".SH OPTIONS")
+			writeManFlags(w, flags)
+		}
+		writeManExitStatus(w)
+	}
+
+	fmt.Fprintln(w, ".SH SEE ALSO")
+	parent := `\fByoutube\-webhook\fR(1)`
+	if len(path) > 1 {
+		parent = manPageRef(path[:len(path)-1])
+	}
+	fmt.Fprintln(w, parent)
+}
+
+// writeManCommandList lists commands, each referring to its own page
+func writeManCommandList(w io.Writer, parent []command, cmds []command) {
+	for _, c := range cmds {
+		path := append(append([]command(nil), parent...), c)
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".B %s\n", roffEscape(c.name))
+		fmt.Fprintf(w, "%s. See %s.\n", roffEscape(c.summary), manPageRef(path))
+	}
+}
+
+// writeManFlags describes flags, with their value names and defaults
+func writeManFlags(w io.Writer, flags []*flag.Flag) {
+	for _, f := range flags {
+		fmt.Fprintln(w, ".TP")
+		valueName, usage := flag.UnquoteUsage(f)
+		if valueName == "" {
+			fmt.Fprintf(w, "\\fB\\-%s\\fR\n", roffEscape(f.Name))
+		} else {
+			fmt.Fprintf(w, "\\fB\\-%s\\fR \\fI%s\\fR\n", roffEscape(f.Name), roffEscape(valueName))
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "0s" {
+			usage += fmt.Sprintf(" (default: %s)", f.DefValue)
+		}
+		fmt.Fprintln(w, roffEscape(usage))
+	}
+}
+
+func writeManExitStatus(w io.Writer) {
+	fmt.Fprintln(w, ".SH EXIT STATUS")
+	for _, s := range exitStatuses {
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".B %d\n", s.status)
+		fmt.Fprintln(w, roffEscape(s.description))
+	}
+}
+
+// roffEscape escapes text for a man page line, keeping hyphens literal so flags can be copied
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteManPages(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "man1")
+	cmds := registry()
+
+	pages, err := writeManPages(dir, cmds)
+	if err != nil {
+		t.Fatalf("Expected man pages to be written, got: %v", err)
+	}
+	expected := 1
+	walkCommands(cmds, nil, func([]command) { expected++ })
+	if len(pages) != expected {
+		t.Errorf("Expected %d man pages, got %d", expected, len(pages))
+	}
+
+	main, err := os.ReadFile(filepath.Join(dir, "youtube-webhook.1"))
+	if err != nil {
+		t.Fatalf("Expected youtube-webhook.1, got: %v", err)
+	}
+	for _, want := range []string{".TH YOUTUBE\\-WEBHOOK 1", ".B restore\\-backup", "YOUTUBE_WEBHOOK_SIGNING_SECRET", ".SH GLOBAL OPTIONS", ".SH EXIT STATUS", "\\fByoutube\\-webhook\\-renew\\fR(1)"} {
+		if !strings.Contains(string(main), want) {
+			t.Errorf("Expected youtube-webhook.1 to contain %q", want)
+		}
+	}
+
+	renew, err := os.ReadFile(filepath.Join(dir, "youtube-webhook-renew.1"))
+	if err != nil {
+		t.Fatalf("Expected youtube-webhook-renew.1, got: %v", err)
+	}
+	for _, want := range []string{
+		".TH YOUTUBE\\-WEBHOOK\\-RENEW 1",
+		"youtube\\-webhook\\-renew \\- Trigger renewal of expiring subscriptions",
+		"\\fB\\-format\\fR \\fIstring\\fR\nOutput format: table or json (default: table)",
+		"\\fB\\-force\\fR\nRenew regardless of expiry and failure backoff",
+	} {
+		if !strings.Contains(string(renew), want) {
+			t.Errorf("Expected youtube-webhook-renew.1 to contain %q, got:\n%s", want, renew)
+		}
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "youtube-webhook-config.1"))
+	if err != nil {
+		t.Fatalf("Expected youtube-webhook-config.1, got: %v", err)
+	}
+	if !strings.Contains(string(config), "\\fByoutube\\-webhook\\-config\\-set\\fR(1)") {
+		t.Errorf("Expected the config page to refer to its subcommands, got:\n%s", config)
+	}
+
+	set, err := os.ReadFile(filepath.Join(dir, "youtube-webhook-config-set.1"))
+	if err != nil {
+		t.Fatalf("Expected youtube-webhook-config-set.1, got: %v", err)
+	}
+	for _, want := range []string{".B youtube\\-webhook config set\n[flags] key=value...", ".SH EXIT STATUS", "\\fByoutube\\-webhook\\-config\\fR(1)"} {
+		if !strings.Contains(string(set), want) {
+			t.Errorf("Expected youtube-webhook-config-set.1 to contain %q, got:\n%s", want, set)
+		}
+	}
+}
+
+func TestRoffEscape(t *testing.T) {
+	testCases := map[string]string{
+		"Comma-separated":  "Comma\\-separated",
+		`C:\path`:          `C:\epath`,
+		".hidden":          "\\&.hidden",
+		"'quoted'":         "\\&'quoted'",
+		"plain text as is": "plain text as is",
+	}
+	for in, expected := range testCases {
+		if got := roffEscape(in); got != expected {
+			t.Errorf("roffEscape(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/cli/commands"
+)
+
+// command is one youtube-webhook command. Help, shell completions and man pages are
+// generated from the same definitions, so they always match the flags a command parses.
+type command struct {
+	name    string
+	summary string
+	usage   string // Arguments after the flags in the command's synopsis, if any
+
+	// subcommands are nested commands, such as config's get and set; a command with
+	// subcommands only groups them and has no setup of its own
+	subcommands []command
+
+	// local commands run without calling the service, so take no -url, credential or -timeout
+	local bool
+	// adminToken defaults the command's credential to YOUTUBE_WEBHOOK_ADMIN_TOKEN rather than the API key
+	adminToken bool
+	// timeout is the command's default -timeout, when it needs longer than defaultTimeout
+	timeout time.Duration
+
+	// setup defines the command's own flags on cmd, and returns the function that runs it
+	// with the arguments left after its flags. The shared flags are parsed into opts.
+	setup func(cmd *flag.FlagSet, opts *options) func(args []string) error
+}
+
+// options holds the flags the commands share. Given before the command name they act as
+// global flags, and the command's own -url, -timeout and -format override them.
+type options struct {
+	baseURL       string
+	token         string // API key, or the admin token for commands that use it
+	signingSecret string // Signs requests when YOUTUBE_WEBHOOK_SIGNING_SECRET is set; only read from the environment
+	timeout       time.Duration
+	format        string
+	configPath    string // Config file given with the global -config flag
+}
+
+// formatOr returns the global -format, or the command's default when none was given
+func (o *options) formatOr(defaultFormat string) string {
+	if o.format != "" {
+		return o.format
+	}
+	return defaultFormat
+}
+
+// registry returns every command, in the order help lists them
+func registry() []command {
+	return []command{
+		{name: "subscribe", summary: "Subscribe to a YouTube channel or playlist", setup: handleSubscribe},
+		{name: "unsubscribe", summary: "Unsubscribe from a YouTube channel or playlist", setup: handleUnsubscribe},
+		{name: "restore", summary: "Re-subscribe an unsubscribed channel with its previous configuration", setup: handleRestore},
+		{name: "import-subscriptions", summary: "Subscribe to every channel in a Google Takeout subscriptions.csv or OPML file", setup: handleImportSubscriptions},
+		{name: "list", summary: "List all subscriptions", setup: handleList},
+		{name: "renew", summary: "Trigger renewal of expiring subscriptions", timeout: 60 * time.Second, setup: handleRenew},
+		{name: "status", summary: "Summarize the deployment's health, subscriptions and last renewal run", setup: handleStatus},
+		{name: "doctor", summary: "Diagnose the deployment and suggest fixes for what is wrong", setup: handleDoctor},
+		{name: "filters", summary: "Show or change a channel's notification filters", setup: handleFilters},
+		{name: "config", summary: "Show or change a channel's configuration, or write a CLI config file", subcommands: []command{
+			{name: "get", summary: "Show a channel's lease, filter and webhook sinks", setup: handleConfigGet},
+			{name: "set", summary: "Change a channel's lease, filter or webhook sinks", usage: "key=value...", setup: handleConfigSet},
+			{name: "init", summary: "Write a starter CLI config file with default URL, credentials and format", local: true, setup: handleConfigInit},
+		}},
+		{name: "history", summary: "Show the notifications recently processed for a channel", setup: handleHistory},
+		{name: "deadletters", summary: "List, show or replay notifications that could not be parsed", setup: handleDeadLetters},
+		{name: "watch", summary: "Print notifications as the service processes them", setup: handleWatch},
+		{name: "dashboard", summary: "Watch subscriptions live and renew or unsubscribe with a key press", setup: handleDashboard},
+		{name: "export", summary: "Download the full subscription state as JSON or YAML", adminToken: true, setup: handleExport},
+		{name: "import", summary: "Upload a subscription state written by export", adminToken: true, setup: handleImport},
+		{name: "restore-backup", summary: "List the state backups taken on every save, or restore one", adminToken: true, setup: handleRestoreBackup},
+		{name: "completion", summary: "Print a bash, zsh or fish completion script", subcommands: completionCommands()},
+		{name: "docs", summary: "Generate documentation for every command", subcommands: []command{
+			{name: "man", summary: "Write man pages for every command", local: true, setup: handleDocsMan},
+		}},
+	}
+}
+
+// usageError is a mistake in how a command was invoked, rather than a failure to carry it out
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+// usagef returns a usageError, which exits with exitUsage after the command's usage
+func usagef(format string, args ...any) error {
+	return usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// isUsageError reports whether err is a usageError
+func isUsageError(err error) bool {
+	var usage usageError
+	return errors.As(err, &usage)
+}
+
+// findCommand walks args to the command they name, returning it with its path from the
+// top level and the arguments after its name. A group is returned when args stop at it.
+func findCommand(cmds []command, args []string) ([]command, []string, error) {
+	var path []command
+	for len(args) > 0 {
+		c, ok := lookupCommand(cmds, args[0])
+		if !ok {
+			if len(path) == 0 {
+				return nil, nil, usagef("Unknown command: %s", args[0])
+			}
+			break
+		}
+		path = append(path, c)
+		args = args[1:]
+		if len(c.subcommands) == 0 {
+			return path, args, nil
+		}
+		cmds = c.subcommands
+	}
+
+	if len(path) == 0 {
+		return nil, nil, usagef("no command given")
+	}
+	group := path[len(path)-1]
+	return path, args, usagef("%s needs a %s subcommand", commandName(path), joinOr(commandNames(group.subcommands)))
+}
+
+// lookupCommand finds a command by name
+func lookupCommand(cmds []command, name string) (command, bool) {
+	for _, c := range cmds {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// commandName joins a command's path, such as "config set"
+func commandName(path []command) string {
+	return strings.Join(commandNames(path), " ")
+}
+
+// commandNames lists the commands' names
+func commandNames(cmds []command) []string {
+	names := make([]string, len(cmds))
+	for i, c := range cmds {
+		names[i] = c.name
+	}
+	return names
+}
+
+// joinOr joins names as "a, b or c"
+func joinOr(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// defineFlags defines a command's flags on cmd: the shared -url, credential and -timeout
+// flags, defaulting to opts, then the command's own. It returns the command's run function.
+func defineFlags(cmd *flag.FlagSet, c command, opts *options) func(args []string) error {
+	if !c.local {
+		cmd.StringVar(&opts.baseURL, "url", opts.baseURL, "Base URL of the webhook service (env: YOUTUBE_WEBHOOK_URL)")
+		if c.adminToken {
+			cmd.StringVar(&opts.token, "admin-token", opts.token, "The service's ADMIN_TOKEN (env: YOUTUBE_WEBHOOK_ADMIN_TOKEN)")
+		} else {
+			cmd.StringVar(&opts.token, "api-key", opts.token, "API key or ID token for the management API (env: YOUTUBE_WEBHOOK_API_KEY)")
+		}
+
+		timeout := opts.timeout
+		if timeout == 0 {
+			timeout = c.timeout
+		}
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+		cmd.DurationVar(&opts.timeout, "timeout", timeout, "Request timeout")
+	}
+	return c.setup(cmd, opts)
+}
+
+// flags returns the command's flag definitions, with no defaults taken from the environment
+func (c command) flags() []*flag.Flag {
+	if c.setup == nil {
+		return nil
+	}
+	cmd := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	defineFlags(cmd, c, &options{})
+
+	var flags []*flag.Flag
+	cmd.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// globalFlags defines the flags accepted before the command name on cmd
+func globalFlags(cmd *flag.FlagSet, opts *options) {
+	cmd.StringVar(&opts.configPath, "config", "", "CLI config file (env: YOUTUBE_WEBHOOK_CONFIG, default: ~/"+commands.ConfigFileName+")")
+	cmd.StringVar(&opts.baseURL, "url", opts.baseURL, "Base URL of the webhook service for every command (env: YOUTUBE_WEBHOOK_URL)")
+	cmd.DurationVar(&opts.timeout, "timeout", 0, "Request timeout for every command (default: the command's own)")
+	cmd.StringVar(&opts.format, "format", "", "Output format for commands that have one, such as json (default: the command's own)")
+}
+
+// environmentVariables are the variables the CLI reads its defaults from. They override
+// the config file, and flags override them.
+var environmentVariables = []struct {
+	name        string
+	description string
+}{
+	{"YOUTUBE_WEBHOOK_URL", "Base URL of the webhook service (can be overridden with -url flag)"},
+	{"YOUTUBE_WEBHOOK_API_KEY", "API key or ID token for the management API (can be overridden with -api-key flag)"},
+	{"YOUTUBE_WEBHOOK_ADMIN_TOKEN", "The service's ADMIN_TOKEN, used by export, import and restore-backup (can be overridden with -admin-token flag)"},
+	{"YOUTUBE_WEBHOOK_SIGNING_SECRET", "The service's REQUEST_SIGNING_SECRET; signs management requests instead of sending an API key"},
+	{"YOUTUBE_WEBHOOK_CONFIG", "CLI config file to read defaults from instead of ~/" + commands.ConfigFileName + " (can be overridden with -config flag)"},
+}
+
+// Exit statuses, the same for every command
+const (
+	exitOK      = 0 // The command succeeded
+	exitFailure = 1 // The command failed, such as the service returning an error
+	exitUsage   = 2 // The command was invoked wrongly, such as with a missing or unknown flag
+)
+
+// exitStatuses describe the exit statuses for help and man pages
+var exitStatuses = []struct {
+	status      int
+	description string
+}{
+	{exitOK, "The command succeeded"},
+	{exitFailure, "The command failed, such as the service being unreachable or returning an error"},
+	{exitUsage, "The command was invoked wrongly, such as an unknown command, a missing or unknown flag, or a bad flag value"},
+}
+
+// isBoolFlag reports whether f is a switch that takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagValueKind says how to complete a flag's value: "file", "dir", or "" for free text
+func flagValueKind(f *flag.Flag) string {
+	switch f.Name {
+	case "file", "config":
+		return "file"
+	case "dir":
+		return "dir"
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+// Package adapter runs the YouTube webhook function outside Google Cloud Functions: as a
+// plain http.Handler for any HTTP server, and behind AWS API Gateway on AWS Lambda.
+package adapter
+
+import (
+	"net/http"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// Handler returns the webhook function as an http.Handler. It serves every endpoint the
+// Cloud Functions deployment does and reads the same environment variables.
+func Handler() http.Handler {
+	return http.HandlerFunc(webhook.YouTubeWebhook)
+}
@@ -0,0 +1,264 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// lambdaRuntimeVersion is the version of the Lambda runtime API the adapter polls
+const lambdaRuntimeVersion = "2018-06-01"
+
+// APIGatewayRequest is an API Gateway proxy event. It covers payload format 1.0, sent by
+// REST APIs, and 2.0, sent by HTTP APIs and function URLs.
+type APIGatewayRequest struct {
+	Version string `json:"version"`
+
+	// Payload format 1.0
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+
+	// Payload format 2.0
+	RawPath        string   `json:"rawPath"`
+	RawQueryString string   `json:"rawQueryString"`
+	Cookies        []string `json:"cookies"`
+
+	Headers         map[string]string        `json:"headers"`
+	RequestContext  APIGatewayRequestContext `json:"requestContext"`
+	Body            string                   `json:"body"`
+	IsBase64Encoded bool                     `json:"isBase64Encoded"`
+}
+
+// APIGatewayRequestContext is the part of the event's request context the adapter uses
+type APIGatewayRequestContext struct {
+	// Identity is set in payload format 1.0
+	Identity struct {
+		SourceIP string `json:"sourceIp"`
+	} `json:"identity"`
+
+	// HTTP is set in payload format 2.0
+	HTTP struct {
+		Method   string `json:"method"`
+		SourceIP string `json:"sourceIp"`
+	} `json:"http"`
+}
+
+// APIGatewayResponse is a proxy integration response, understood by both payload formats
+type APIGatewayResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// NewRequest converts an API Gateway event into the HTTP request it describes
+func NewRequest(ctx context.Context, event APIGatewayRequest) (*http.Request, error) {
+	method, path, query := event.HTTPMethod, event.Path, ""
+	if event.Version == "2.0" {
+		method, path, query = event.RequestContext.HTTP.Method, event.RawPath, event.RawQueryString
+	} else {
+		values := url.Values{}
+		for key, value := range event.QueryStringParameters {
+			values.Set(key, value)
+		}
+		for key, list := range event.MultiValueQueryStringParameters {
+			values[key] = list
+		}
+		query = values.Encode()
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	body := []byte(event.Body)
+	if event.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(event.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode request body: %v", err)
+		}
+		body = decoded
+	}
+
+	target := path
+	if query != "" {
+		target += "?" + query
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	for key, value := range event.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, list := range event.MultiValueHeaders {
+		req.Header[http.CanonicalHeaderKey(key)] = list
+	}
+	if len(event.Cookies) > 0 {
+		req.Header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+	req.Host = req.Header.Get("Host")
+
+	if sourceIP := event.RequestContext.HTTP.SourceIP; sourceIP != "" {
+		req.RemoteAddr = sourceIP
+	} else {
+		req.RemoteAddr = event.RequestContext.Identity.SourceIP
+	}
+	return req, nil
+}
+
+// Serve runs an API Gateway event through the handler and returns its response. Bodies
+// that are not valid UTF-8 are base64-encoded.
+func Serve(ctx context.Context, h http.Handler, event APIGatewayRequest) (APIGatewayResponse, error) {
+	req, err := NewRequest(ctx, event)
+	if err != nil {
+		return APIGatewayResponse{}, err
+	}
+
+	rec := &responseRecorder{header: http.Header{}}
+	h.ServeHTTP(rec, req)
+
+	response := APIGatewayResponse{
+		StatusCode:        rec.status(),
+		Headers:           map[string]string{},
+		MultiValueHeaders: map[string][]string(rec.header),
+		Body:              rec.body.String(),
+	}
+	for key, list := range rec.header {
+		response.Headers[key] = strings.Join(list, ",")
+	}
+	if !utf8.Valid(rec.body.Bytes()) {
+		response.Body = base64.StdEncoding.EncodeToString(rec.body.Bytes())
+		response.IsBase64Encoded = true
+	}
+	return response, nil
+}
+
+// responseRecorder collects a handler's response for Serve
+type responseRecorder struct {
+	header     http.Header
+	body       bytes.Buffer
+	statusCode int
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	return r.body.Write(p)
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+}
+
+func (r *responseRecorder) status() int {
+	if r.statusCode == 0 {
+		return http.StatusOK
+	}
+	return r.statusCode
+}
+
+// StartLambda serves API Gateway events from the Lambda runtime API with the handler
+// until the runtime API fails. It needs AWS_LAMBDA_RUNTIME_API, which Lambda sets, and
+// runs on the provided (OS-only) runtimes without the AWS SDK.
+func StartLambda(h http.Handler) error {
+	api := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if api == "" {
+		return fmt.Errorf("AWS_LAMBDA_RUNTIME_API is not set; StartLambda must run on AWS Lambda")
+	}
+
+	runtime := &lambdaRuntime{
+		baseURL: "http://" + api + "/" + lambdaRuntimeVersion + "/runtime/invocation/",
+		client:  &http.Client{},
+	}
+	for {
+		if err := runtime.invoke(h); err != nil {
+			return err
+		}
+	}
+}
+
+// lambdaRuntime talks to the Lambda runtime API
+type lambdaRuntime struct {
+	baseURL string
+	client  *http.Client
+}
+
+// invoke waits for the next event, serves it and reports the result. Only failures to
+// reach the runtime API are returned; a bad event is reported to Lambda as an
+// invocation error.
+func (l *lambdaRuntime) invoke(h http.Handler) error {
+	resp, err := l.client.Get(l.baseURL + "next")
+	if err != nil {
+		return fmt.Errorf("failed to fetch the next invocation: %v", err)
+	}
+	payload, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read the next invocation: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("runtime API returned status %d for the next invocation", resp.StatusCode)
+	}
+
+	requestID := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+	ctx := context.Background()
+	if deadline, err := strconv.ParseInt(resp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(deadline))
+		defer cancel()
+	}
+
+	var event APIGatewayRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return l.post(requestID, "error", map[string]string{
+			"errorMessage": fmt.Sprintf("failed to parse API Gateway event: %v", err),
+			"errorType":    "InvalidEvent",
+		})
+	}
+	response, err := Serve(ctx, h, event)
+	if err != nil {
+		return l.post(requestID, "error", map[string]string{
+			"errorMessage": err.Error(),
+			"errorType":    "InvalidEvent",
+		})
+	}
+	return l.post(requestID, "response", response)
+}
+
+// post sends an invocation's response or error to the runtime API
+func (l *lambdaRuntime) post(requestID, kind string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode invocation %s: %v", kind, err)
+	}
+
+	resp, err := l.client.Post(l.baseURL+requestID+"/"+kind, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to post invocation %s: %v", kind, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("runtime API returned status %d for invocation %s", resp.StatusCode, kind)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package adapter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoHandler answers with the request it received
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("X-Echo", "one")
+	w.Header().Add("X-Echo", "two")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"method":    r.Method,
+		"path":      r.URL.Path,
+		"query":     r.URL.RawQuery,
+		"signature": r.Header.Get("X-Hub-Signature"),
+		"cookie":    r.Header.Get("Cookie"),
+		"remote":    r.RemoteAddr,
+		"body":      string(body),
+	})
+})
+
+func TestServe(t *testing.T) {
+	tests := []struct {
+		name  string
+		event APIGatewayRequest
+		want  map[string]string
+	}{
+		{
+			name: "payload_1.0",
+			event: APIGatewayRequest{
+				HTTPMethod:                      "POST",
+				Path:                            "/subscribe",
+				QueryStringParameters:           map[string]string{"channel_id": "UC1"},
+				MultiValueQueryStringParameters: map[string][]string{"channel_id": {"UC1"}},
+				Headers:                         map[string]string{"x-hub-signature": "sha1=abc"},
+				Body:                            "<feed/>",
+				RequestContext: func() (rc APIGatewayRequestContext) {
+					rc.Identity.SourceIP = "203.0.113.1"
+					return
+				}(),
+			},
+			want: map[string]string{"method": "POST", "path": "/subscribe", "query": "channel_id=UC1",
+				"signature": "sha1=abc", "cookie": "", "remote": "203.0.113.1", "body": "<feed/>"},
+		},
+		{
+			name: "payload_2.0",
+			event: APIGatewayRequest{
+				Version:         "2.0",
+				RawPath:         "/subscriptions/UC1",
+				RawQueryString:  "hub.mode=subscribe&hub.topic=x",
+				Cookies:         []string{"a=1", "b=2"},
+				Headers:         map[string]string{"x-hub-signature": "sha1=def"},
+				Body:            base64.StdEncoding.EncodeToString([]byte("<feed/>")),
+				IsBase64Encoded: true,
+				RequestContext: func() (rc APIGatewayRequestContext) {
+					rc.HTTP.Method = "GET"
+					rc.HTTP.SourceIP = "203.0.113.2"
+					return
+				}(),
+			},
+			want: map[string]string{"method": "GET", "path": "/subscriptions/UC1", "query": "hub.mode=subscribe&hub.topic=x",
+				"signature": "sha1=def", "cookie": "a=1; b=2", "remote": "203.0.113.2", "body": "<feed/>"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := Serve(context.Background(), echoHandler, tc.event)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, response.StatusCode)
+			assert.Equal(t, "one,two", response.Headers["X-Echo"])
+			assert.Equal(t, []string{"one", "two"}, response.MultiValueHeaders["X-Echo"])
+			assert.False(t, response.IsBase64Encoded)
+
+			var got map[string]string
+			require.NoError(t, json.Unmarshal([]byte(response.Body), &got))
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("binary_body", func(t *testing.T) {
+		binary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte{0xff, 0xfe})
+		})
+		response, err := Serve(context.Background(), binary, APIGatewayRequest{HTTPMethod: "GET"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.True(t, response.IsBase64Encoded)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe}), response.Body)
+	})
+
+	t.Run("invalid_base64", func(t *testing.T) {
+		_, err := Serve(context.Background(), echoHandler, APIGatewayRequest{HTTPMethod: "POST", Body: "!", IsBase64Encoded: true})
+		assert.Error(t, err)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	webhook.SetDependencies(webhook.CreateTestDependencies())
+	defer webhook.SetDependencies(nil)
+
+	response, err := Serve(context.Background(), Handler(), APIGatewayRequest{HTTPMethod: "GET", Path: "/subscriptions"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, response.Body, `"subscriptions"`)
+}
+
+func TestLambdaRuntime_Invoke(t *testing.T) {
+	posted := map[string]string{}
+	runtimeAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/"+lambdaRuntimeVersion+"/runtime/invocation/")
+		if path == "next" {
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", "req-1")
+			w.Header().Set("Lambda-Runtime-Deadline-Ms", "4102444800000")
+			w.Write([]byte(`{"version":"2.0","rawPath":"/health","requestContext":{"http":{"method":"GET"}}}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		posted[path] = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer runtimeAPI.Close()
+
+	runtime := &lambdaRuntime{
+		baseURL: runtimeAPI.URL + "/" + lambdaRuntimeVersion + "/runtime/invocation/",
+		client:  runtimeAPI.Client(),
+	}
+	require.NoError(t, runtime.invoke(echoHandler))
+
+	var response APIGatewayResponse
+	require.NoError(t, json.Unmarshal([]byte(posted["req-1/response"]), &response))
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Contains(t, response.Body, `"path":"/health"`)
+}
+
+func TestStartLambda_RequiresRuntimeAPI(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "")
+	assert.Error(t, StartLambda(echoHandler))
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/api/idtoken"
+)
+
+// APIKeyHeader is an alternative to the Authorization header for API keys
+const APIKeyHeader = "X-API-Key"
+
+// CallerAnonymous is the caller recorded for management requests while authentication
+// is disabled
+const CallerAnonymous = "anonymous"
+
+// validateIDToken verifies a Google-signed OIDC ID token (replaced in tests)
+var validateIDToken = func(ctx context.Context, token, audience string) (*idtoken.Payload, error) {
+	return idtoken.Validate(ctx, token, audience)
+}
+
+// apiAuthConfig configures authentication of the management endpoints.
+// Management endpoints are open while no API keys, OIDC audience or signing secret is set.
+type apiAuthConfig struct {
+	APIKeys           []string // API_KEYS: comma-separated keys accepted as bearer tokens
+	OIDCAudience      string   // OIDC_AUDIENCE: accept Google-signed ID tokens for this audience
+	OIDCAllowedEmails []string // OIDC_ALLOWED_EMAILS: restrict ID tokens to these identities (empty allows any)
+	SigningSecret     string   // REQUEST_SIGNING_SECRET: accept requests signed with this shared secret
+}
+
+// getAPIAuthConfig reads the management API authentication settings from the environment
+func getAPIAuthConfig() apiAuthConfig {
+	return apiAuthConfig{
+		APIKeys:           splitList(os.Getenv("API_KEYS")),
+		OIDCAudience:      strings.TrimSpace(os.Getenv("OIDC_AUDIENCE")),
+		OIDCAllowedEmails: splitList(os.Getenv("OIDC_ALLOWED_EMAILS")),
+		SigningSecret:     os.Getenv("REQUEST_SIGNING_SECRET"),
+	}
+}
+
+// Enabled reports whether management endpoints require credentials
+func (c apiAuthConfig) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.OIDCAudience != "" || c.SigningSecret != ""
+}
+
+// splitList splits a comma-separated environment value, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// bearerToken returns the request's bearer token, or "" when there is none
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
+
+// isManagementPath reports whether path (without the leading slash) is a management
+// endpoint. Hub verification and notifications (/), metrics and the admin-only debug and
+// state endpoints are not management endpoints.
+func isManagementPath(path string) bool {
+	switch path {
+	case "subscribe", "unsubscribe", "subscriptions", "renew", "renew/report", "sweep", "retry", "outbox", "premieres", "replay", "targets/test", "deadletters", "events", "stats", "config", "diagnostics", "audit":
+		return true
+	}
+	return strings.HasPrefix(path, "subscriptions/") || strings.HasPrefix(path, "deadletters/")
+}
+
+// authorizeAPI checks the request carries a management credential: one of API_KEYS,
+// as a bearer token or in X-API-Key, a Google-signed OIDC ID token for OIDC_AUDIENCE, or
+// an X-Request-Signature made with REQUEST_SIGNING_SECRET. It returns who made the
+// request, as recorded in the audit log. It writes the error response and returns false
+// when the request is not allowed.
+func authorizeAPI(w http.ResponseWriter, r *http.Request) (string, bool) {
+	config := getAPIAuthConfig()
+	if !config.Enabled() {
+		return CallerAnonymous, true
+	}
+
+	if config.SigningSecret != "" && r.Header.Get(RequestSignatureHeader) != "" {
+		if err := verifyRequestSignature(r, config.SigningSecret, currentTime(r.Context())); err != nil {
+			writeUnauthorized(w, r, "Invalid signed request: "+err.Error())
+			return "", false
+		}
+		return signingCaller(config.SigningSecret), true
+	}
+
+	token := r.Header.Get(APIKeyHeader)
+	if token == "" {
+		token = bearerToken(r)
+	}
+	if token == "" {
+		writeUnauthorized(w, r, "Missing API credentials")
+		return "", false
+	}
+
+	for _, key := range config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return apiKeyCaller(key), true
+		}
+	}
+
+	if config.OIDCAudience == "" || r.Header.Get(APIKeyHeader) != "" {
+		writeUnauthorized(w, r, "Invalid API key")
+		return "", false
+	}
+
+	payload, err := validateIDToken(r.Context(), token, config.OIDCAudience)
+	if err != nil {
+		writeUnauthorized(w, r, "Invalid API key or ID token")
+		return "", false
+	}
+	if len(config.OIDCAllowedEmails) > 0 && !emailAllowed(payload, config.OIDCAllowedEmails) {
+		writeError(w, r, http.StatusForbidden, ErrCodeForbidden, "", "ID token identity is not allowed")
+		return "", false
+	}
+	if email, _ := payload.Claims["email"].(string); email != "" {
+		return "oidc:" + email, true
+	}
+	return "oidc:" + payload.Subject, true
+}
+
+// apiKeyCaller identifies an API key without revealing it: the start of its SHA-256
+func apiKeyCaller(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "api_key:" + hex.EncodeToString(sum[:4])
+}
+
+// emailAllowed reports whether the ID token belongs to a verified, allowed identity
+func emailAllowed(payload *idtoken.Payload, allowed []string) bool {
+	email, _ := payload.Claims["email"].(string)
+	verified, _ := payload.Claims["email_verified"].(bool)
+	if email == "" || !verified {
+		return false
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(candidate, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeUnauthorized writes a 401 response with a bearer challenge
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="youtube-webhook"`)
+	writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "", message)
+}
+
+// authorizeAdmin checks the request carries the ADMIN_TOKEN bearer token.
+// It writes the error response and returns false when the request is not allowed;
+// admin endpoints are disabled entirely while ADMIN_TOKEN is unset.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		writeError(w, r, http.StatusForbidden, ErrCodeForbidden, "", "Admin endpoints are disabled (ADMIN_TOKEN not set)")
+		return false
+	}
+
+	provided := bearerToken(r)
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		writeUnauthorized(w, r, "Missing or invalid admin token")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// auditLogObject is where the audit log is stored
+const auditLogObject = "audit/log.json"
+
+// maxAuditEntries caps the audit log; the oldest entries are dropped beyond it
+const maxAuditEntries = 1000
+
+// defaultAuditPageSize is how many entries GET /audit returns when no limit is given
+const defaultAuditPageSize = 100
+
+// Outcomes recorded in the audit log
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// auditMu serialises reads and writes of the audit log within an instance
+var auditMu sync.Mutex
+
+// AuditEntry records one management operation: who asked for what, and how it ended
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Caller     string    `json:"caller"` // "anonymous", "api_key:<hash prefix>" or "oidc:<email>"
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	ChannelID  string    `json:"channel_id,omitempty"`
+	StatusCode int       `json:"status_code"`
+	Outcome    string    `json:"outcome"`
+	Message    string    `json:"message,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+}
+
+// auditLog is the stored log, oldest entry first
+type auditLog struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// AuditLogResponse is one page of the audit log, newest entry first
+type AuditLogResponse struct {
+	Status        string       `json:"status"`
+	Count         int          `json:"count"`
+	Entries       []AuditEntry `json:"entries"`
+	NextPageToken string       `json:"next_page_token,omitempty"` // Set when older entries match
+}
+
+// AuditLog keeps a record of the management operations that change subscriptions or
+// their configuration. Entries older than AUDIT_RETENTION_DAYS, or beyond the newest
+// maxAuditEntries, are dropped when new ones are recorded.
+type AuditLog struct {
+	store     ObjectStorage
+	retention time.Duration
+}
+
+// getAuditRetention returns how long audit entries are kept.
+// AUDIT_RETENTION_DAYS=0 disables the audit log.
+func getAuditRetention() time.Duration {
+	return envConfig().AuditRetention
+}
+
+// NewAuditLog creates an audit log in the given storage.
+// Returns nil (nothing is recorded) when the storage cannot hold objects or the retention is zero.
+func NewAuditLog(storage StorageService, cfg *config.Config) *AuditLog {
+	store, ok := storage.(ObjectStorage)
+	retention := cfg.AuditRetention
+	if !ok || retention == 0 {
+		return nil
+	}
+	return &AuditLog{store: store, retention: retention}
+}
+
+// load reads the log, treating a missing object as empty and dropping expired entries
+func (a *AuditLog) load(ctx context.Context) (*auditLog, error) {
+	log := &auditLog{}
+
+	data, err := a.store.ReadObject(ctx, auditLogObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	if err := json.Unmarshal(data, log); err != nil {
+		return nil, fmt.Errorf("failed to decode audit log: %v", err)
+	}
+
+	cutoff := currentTime(ctx).Add(-a.retention)
+	kept := log.Entries[:0]
+	for _, entry := range log.Entries {
+		if entry.Time.After(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	log.Entries = kept
+	return log, nil
+}
+
+// Record appends an entry to the log, dropping the oldest entries beyond the cap
+func (a *AuditLog) Record(ctx context.Context, entry AuditEntry) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	log, err := a.load(ctx)
+	if err != nil {
+		return err
+	}
+	log.Entries = append(log.Entries, entry)
+	if excess := len(log.Entries) - maxAuditEntries; excess > 0 {
+		log.Entries = log.Entries[excess:]
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit log: %v", err)
+	}
+	if err := a.store.WriteObject(ctx, auditLogObject, data); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
+
+// List returns the entries, newest first, keeping only the channel's when channelID is set
+func (a *AuditLog) List(ctx context.Context, channelID string) ([]AuditEntry, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	log, err := a.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, 0, len(log.Entries))
+	for i := len(log.Entries) - 1; i >= 0; i-- {
+		if channelID == "" || log.Entries[i].ChannelID == channelID {
+			entries = append(entries, log.Entries[i])
+		}
+	}
+	return entries, nil
+}
+
+// callerKey is the context key of the caller withCaller records
+type callerKey struct{}
+
+// withCaller returns a context carrying who made a management request
+func withCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFrom returns who made the request, or "anonymous" when no caller was recorded
+func callerFrom(ctx context.Context) string {
+	if caller, ok := ctx.Value(callerKey{}).(string); ok && caller != "" {
+		return caller
+	}
+	return CallerAnonymous
+}
+
+// audited records every request a handler serves in the audit log: the caller, the
+// endpoint, the channel and the response status and message. The channel is taken from
+// the path or the channel_id parameter, falling back to the response's channel_id so
+// subscriptions by handle are recorded against the resolved channel. Failing to record
+// is logged without affecting the response.
+func audited(handler routeHandler) routeHandler {
+	return func(deps *Dependencies, params pathParams) http.HandlerFunc {
+		next := handler(deps, params)
+		return func(w http.ResponseWriter, r *http.Request) {
+			log := NewAuditLog(deps.StorageClient, deps.config())
+			if log == nil {
+				next(w, r)
+				return
+			}
+
+			rec := &capturingResponseWriter{ResponseWriter: w}
+			next(rec, r)
+
+			var response struct {
+				ChannelID string `json:"channel_id"`
+				Message   string `json:"message"`
+			}
+			if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+				json.Unmarshal(rec.body.Bytes(), &response)
+			}
+
+			entry := AuditEntry{
+				Time:       deps.now(),
+				Caller:     callerFrom(r.Context()),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				ChannelID:  auditChannelID(params, r.URL.Query(), response.ChannelID),
+				StatusCode: rec.status(),
+				Outcome:    AuditOutcomeSuccess,
+				Message:    response.Message,
+				RequestID:  w.Header().Get(RequestIDHeader),
+			}
+			if entry.StatusCode >= http.StatusBadRequest {
+				entry.Outcome = AuditOutcomeFailure
+			}
+			if err := log.Record(r.Context(), entry); err != nil {
+				fmt.Printf("Unable to record audit entry for %s %s: %v\n", r.Method, r.URL.Path, err)
+			}
+		}
+	}
+}
+
+// auditChannelID picks the channel an audited request was about
+func auditChannelID(params pathParams, query url.Values, responseChannelID string) string {
+	if channelID := params["channel_id"]; channelID != "" {
+		return channelID
+	}
+	if channelID := query.Get("channel_id"); channelID != "" {
+		return channelID
+	}
+	return responseChannelID
+}
+
+// handleGetAudit handles GET /audit requests using dependency injection: the recorded
+// management operations, newest first. ?channel_id keeps one channel's, ?limit sets the
+// page size and ?page_token continues from an earlier page.
+func handleGetAudit(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		channelID := query.Get("channel_id")
+		if channelID != "" && !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		limit, offset := defaultAuditPageSize, 0
+		if limitStr := query.Get("limit"); limitStr != "" {
+			if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit < 1 || limit > maxAuditEntries {
+				writeErrorResponse(w, http.StatusBadRequest, channelID,
+					fmt.Sprintf("limit must be between 1 and %d", maxAuditEntries))
+				return
+			}
+		}
+		if token := query.Get("page_token"); token != "" {
+			var err error
+			if offset, err = decodePageToken(token); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, channelID, err.Error())
+				return
+			}
+		}
+
+		log := NewAuditLog(deps.StorageClient, deps.config())
+		if log == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, channelID,
+				"The audit log is disabled or not supported by the storage backend")
+			return
+		}
+
+		entries, err := log.List(r.Context(), channelID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID, err.Error())
+			return
+		}
+
+		response := AuditLogResponse{Status: "success", Entries: []AuditEntry{}}
+		if offset < len(entries) {
+			response.Entries = entries[offset:]
+			if len(response.Entries) > limit {
+				response.Entries = response.Entries[:limit]
+				response.NextPageToken = encodePageToken(offset + limit)
+			}
+		}
+		response.Count = len(response.Entries)
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
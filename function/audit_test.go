@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAuditRetention(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 30 * 24 * time.Hour},
+		{"7", 7 * 24 * time.Hour},
+		{"0", 0},
+		{"-1", 30 * 24 * time.Hour},
+		{"abc", 30 * 24 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("AUDIT_RETENTION_DAYS", tc.value)
+			assert.Equal(t, tc.expected, getAuditRetention())
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	log := NewAuditLog(NewMockStorageClient(), envConfig())
+	require.NotNil(t, log)
+
+	now := time.Now()
+	for _, entry := range []AuditEntry{
+		{Time: now.Add(-40 * 24 * time.Hour), ChannelID: routingChannelID, Path: "/expired"},
+		{Time: now.Add(-time.Minute), ChannelID: routingChannelID, Path: "/subscribe"},
+		{Time: now, ChannelID: eventsOtherChannel, Path: "/unsubscribe"},
+	} {
+		require.NoError(t, log.Record(ctx, entry))
+	}
+
+	entries, err := log.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "entries older than the retention are dropped")
+	assert.Equal(t, "/unsubscribe", entries[0].Path, "newest first")
+
+	entries, err = log.List(ctx, routingChannelID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "/subscribe", entries[0].Path)
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("AUDIT_RETENTION_DAYS", "0")
+		assert.Nil(t, NewAuditLog(NewMockStorageClient(), envConfig()))
+	})
+}
+
+func TestYouTubeWebhook_AuditsManagementOperations(t *testing.T) {
+	setAPIAuthEnv(t, "key-1", "", "")
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer key-1")
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, serve("POST", "/subscribe?channel_id="+routingChannelID).Code)
+	require.Equal(t, http.StatusBadRequest, serve("POST", "/subscribe?channel_id=invalid").Code)
+	require.Equal(t, http.StatusOK, serve("GET", "/subscriptions").Code)
+	serve("DELETE", "/subscriptions/"+routingChannelID+"/filters")
+
+	rec := serve("GET", "/audit?limit=2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page AuditLogResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, 2, page.Count)
+	require.NotEmpty(t, page.NextPageToken)
+
+	failed := page.Entries[1]
+	assert.Equal(t, "/subscribe", failed.Path)
+	assert.Equal(t, http.StatusBadRequest, failed.StatusCode)
+	assert.Equal(t, AuditOutcomeFailure, failed.Outcome)
+	assert.Equal(t, apiKeyCaller("key-1"), failed.Caller)
+	assert.NotContains(t, failed.Caller, "key-1")
+
+	rec = serve("GET", "/audit?limit=2&page_token="+page.NextPageToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	page = AuditLogResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, 1, page.Count, "reads are not audited")
+	assert.Empty(t, page.NextPageToken)
+
+	subscribed := page.Entries[0]
+	assert.Equal(t, "POST", subscribed.Method)
+	assert.Equal(t, routingChannelID, subscribed.ChannelID)
+	assert.Equal(t, AuditOutcomeSuccess, subscribed.Outcome)
+	assert.NotEmpty(t, subscribed.Message)
+	assert.NotEmpty(t, subscribed.RequestID)
+
+	t.Run("channel_filter", func(t *testing.T) {
+		rec := serve("GET", "/audit?channel_id="+routingChannelID)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var page AuditLogResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		require.Equal(t, 2, page.Count)
+		for _, entry := range page.Entries {
+			assert.Equal(t, routingChannelID, entry.ChannelID)
+		}
+	})
+
+	t.Run("invalid_parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve("GET", "/audit?limit=0").Code)
+		assert.Equal(t, http.StatusBadRequest, serve("GET", "/audit?page_token=nope").Code)
+		assert.Equal(t, http.StatusBadRequest, serve("GET", "/audit?channel_id=invalid").Code)
+	})
+
+	t.Run("requires_credentials", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/audit", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// The request deadline is FUNCTION_TIMEOUT_SECONDS after the request arrives. Downstream
+// calls share what is left of it less DEADLINE_RESERVE_MS, which is kept back for storage
+// writes and the response, and a call is not started with less than DEADLINE_MIN_CALL_MS.
+const (
+	defaultDeadlineReserve = 2 * time.Second
+	defaultMinCallBudget   = time.Second
+)
+
+// downstreamCall names a kind of downstream call and the settings that bound how long it takes
+type downstreamCall struct {
+	name     string
+	settings string
+}
+
+// The downstream calls made within a request's deadline budget
+var (
+	callGitHub         = downstreamCall{"GitHub API call", "GITHUB_API_TIMEOUT_SECONDS or GITHUB_MAX_ATTEMPTS"}
+	callHub            = downstreamCall{"hub request", "HUB_REQUEST_TIMEOUT_SECONDS or HUB_MAX_ATTEMPTS"}
+	callRenewal        = downstreamCall{"subscription renewal", "RENEWAL_BATCH_SIZE or HUB_REQUEST_TIMEOUT_SECONDS"}
+	callRunCorrelation = downstreamCall{"workflow run lookup", "GITHUB_RUN_CORRELATION_SECONDS"}
+	callHousekeeping   = downstreamCall{"due retry, premiere and outbox delivery", "DISPATCH_RETRY_MAX_ATTEMPTS or OUTBOX_STALE_MINUTES"}
+)
+
+// BudgetError reports a downstream call that was not made, or not retried, because too
+// little of the request deadline was left for it
+type BudgetError struct {
+	Call      string        // The call that was not made
+	Settings  string        // The settings that bound how long the call takes
+	Remaining time.Duration // What was left of the budget for downstream calls
+	Needed    time.Duration // What the call needed
+}
+
+// Error implements the error interface, saying which settings to change
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%v: %s needs %s but %s is left; raise FUNCTION_TIMEOUT_SECONDS (function_timeout) or lower %s",
+		ErrDeadlineBudget, e.Call, e.Needed.Round(time.Millisecond), max(e.Remaining, 0).Round(time.Millisecond), e.Settings)
+}
+
+// Unwrap returns ErrDeadlineBudget, so errors.Is recognises every budget error
+func (e *BudgetError) Unwrap() error {
+	return ErrDeadlineBudget
+}
+
+// budgetKey is the context key for the time downstream calls must finish by
+type budgetKey struct{}
+
+// getFunctionTimeout returns the platform's timeout for one invocation from
+// FUNCTION_TIMEOUT_SECONDS, or 0 when it is unset
+func getFunctionTimeout() time.Duration {
+	return getSecondsEnv("FUNCTION_TIMEOUT_SECONDS", 0)
+}
+
+// getDeadlineReserve returns how much of the request deadline is kept back from
+// downstream calls, from DEADLINE_RESERVE_MS
+func getDeadlineReserve() time.Duration {
+	return time.Duration(getIntEnv("DEADLINE_RESERVE_MS", int(defaultDeadlineReserve/time.Millisecond))) * time.Millisecond
+}
+
+// getMinCallBudget returns the least time a downstream call is started with, from
+// DEADLINE_MIN_CALL_MS
+func getMinCallBudget() time.Duration {
+	return time.Duration(getIntEnv("DEADLINE_MIN_CALL_MS", int(defaultMinCallBudget/time.Millisecond))) * time.Millisecond
+}
+
+// withRequestBudget sets the request deadline on ctx: FUNCTION_TIMEOUT_SECONDS from now,
+// or an earlier deadline ctx already has. Downstream calls are budgeted to finish
+// DEADLINE_RESERVE_MS before it. Without either deadline ctx is returned unbudgeted.
+func withRequestBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if timeout := getFunctionTimeout(); timeout > 0 {
+		if limit := time.Now().Add(timeout); !ok || limit.Before(deadline) {
+			deadline, ok = limit, true
+		}
+	}
+	if !ok {
+		return ctx, func() {}
+	}
+
+	ctx = context.WithValue(ctx, budgetKey{}, deadline.Add(-getDeadlineReserve()))
+	return context.WithDeadline(ctx, deadline)
+}
+
+// budgetRemaining returns how long downstream calls made with ctx have left. ok is false
+// when the request has no deadline budget.
+func budgetRemaining(ctx context.Context) (remaining time.Duration, ok bool) {
+	end, ok := ctx.Value(budgetKey{}).(time.Time)
+	if !ok {
+		return 0, false
+	}
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline && deadline.Before(end) {
+		end = deadline
+	}
+	return time.Until(end), true
+}
+
+// budgetShare returns an equal share of the remaining budget for one of calls downstream
+// calls still to be made, or a BudgetError when the share is less than
+// DEADLINE_MIN_CALL_MS. The share is 0, unbounded, when the request has no budget.
+func budgetShare(ctx context.Context, call downstreamCall, calls int) (time.Duration, error) {
+	remaining, ok := budgetRemaining(ctx)
+	if !ok {
+		return 0, nil
+	}
+	share := remaining / time.Duration(max(calls, 1))
+	if minimum := getMinCallBudget(); share < minimum {
+		return 0, &BudgetError{
+			Call:      call.name,
+			Settings:  call.settings,
+			Remaining: remaining,
+			Needed:    minimum * time.Duration(max(calls, 1)),
+		}
+	}
+	return share, nil
+}
+
+// withBudgetShare bounds ctx to a share from budgetShare; a share of 0 leaves it as it is
+func withBudgetShare(ctx context.Context, share time.Duration) (context.Context, context.CancelFunc) {
+	if share <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, share)
+}
+
+// budgetCall bounds ctx to an equal share of the remaining budget for one of calls
+// downstream calls, failing with a BudgetError when the share is too small to start one
+func budgetCall(ctx context.Context, call downstreamCall, calls int) (context.Context, context.CancelFunc, error) {
+	share, err := budgetShare(ctx, call, calls)
+	if err != nil {
+		return ctx, func() {}, err
+	}
+	callCtx, cancel := withBudgetShare(ctx, share)
+	return callCtx, cancel, nil
+}
+
+// budgetRetry returns a BudgetError when waiting wait before retrying a call would leave
+// less than DEADLINE_MIN_CALL_MS of the budget for the retry
+func budgetRetry(ctx context.Context, call downstreamCall, wait time.Duration) error {
+	remaining, ok := budgetRemaining(ctx)
+	if !ok {
+		return nil
+	}
+	if needed := wait + getMinCallBudget(); remaining < needed {
+		return &BudgetError{Call: "retried " + call.name, Settings: call.settings, Remaining: remaining, Needed: needed}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exhaustedBudget returns a context whose deadline budget is already too small for any call
+func exhaustedBudget(t *testing.T) context.Context {
+	t.Setenv("FUNCTION_TIMEOUT_SECONDS", "1")
+	t.Setenv("DEADLINE_RESERVE_MS", "500")
+	ctx, cancel := withRequestBudget(t.Context())
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestWithRequestBudget(t *testing.T) {
+	t.Run("no_deadline_is_unbudgeted", func(t *testing.T) {
+		ctx, cancel := withRequestBudget(t.Context())
+		defer cancel()
+
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		share, err := budgetShare(ctx, callGitHub, 100)
+		require.NoError(t, err)
+		assert.Zero(t, share)
+	})
+
+	t.Run("function_timeout_sets_deadline", func(t *testing.T) {
+		t.Setenv("FUNCTION_TIMEOUT_SECONDS", "10")
+		ctx, cancel := withRequestBudget(t.Context())
+		defer cancel()
+
+		deadline, hasDeadline := ctx.Deadline()
+		require.True(t, hasDeadline)
+		assert.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second)
+		remaining, ok := budgetRemaining(ctx)
+		require.True(t, ok)
+		assert.InDelta(t, float64(8*time.Second), float64(remaining), float64(time.Second),
+			"DEADLINE_RESERVE_MS is kept back")
+	})
+
+	t.Run("earlier_deadline_is_kept", func(t *testing.T) {
+		t.Setenv("FUNCTION_TIMEOUT_SECONDS", "60")
+		parent, cancelParent := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancelParent()
+		ctx, cancel := withRequestBudget(parent)
+		defer cancel()
+
+		remaining, ok := budgetRemaining(ctx)
+		require.True(t, ok)
+		assert.InDelta(t, float64(3*time.Second), float64(remaining), float64(time.Second))
+	})
+}
+
+func TestBudgetShare(t *testing.T) {
+	t.Setenv("FUNCTION_TIMEOUT_SECONDS", "10")
+	t.Setenv("DEADLINE_RESERVE_MS", "0")
+	ctx, cancel := withRequestBudget(t.Context())
+	defer cancel()
+
+	share, err := budgetShare(ctx, callGitHub, 4)
+	require.NoError(t, err)
+	assert.InDelta(t, float64(2500*time.Millisecond), float64(share), float64(100*time.Millisecond))
+
+	callCtx, cancelCall, err := budgetCall(ctx, callGitHub, 4)
+	require.NoError(t, err)
+	defer cancelCall()
+	remaining, _ := budgetRemaining(callCtx)
+	assert.LessOrEqual(t, remaining, 2500*time.Millisecond, "a call's share bounds the calls it makes")
+
+	_, err = budgetShare(ctx, callGitHub, 20)
+	var budgetErr *BudgetError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.ErrorIs(t, err, ErrDeadlineBudget)
+	assert.Equal(t, 20*time.Second, budgetErr.Needed)
+	assert.Contains(t, err.Error(), "GitHub API call needs 20s")
+	assert.Contains(t, err.Error(), "raise FUNCTION_TIMEOUT_SECONDS")
+	assert.Contains(t, err.Error(), "GITHUB_MAX_ATTEMPTS")
+}
+
+func TestBudgetRetry(t *testing.T) {
+	t.Setenv("FUNCTION_TIMEOUT_SECONDS", "5")
+	t.Setenv("DEADLINE_RESERVE_MS", "0")
+	ctx, cancel := withRequestBudget(t.Context())
+	defer cancel()
+
+	assert.NoError(t, budgetRetry(ctx, callHub, time.Second))
+	err := budgetRetry(ctx, callHub, 4500*time.Millisecond)
+	assert.ErrorIs(t, err, ErrDeadlineBudget)
+	assert.Contains(t, err.Error(), "retried hub request")
+	assert.NoError(t, budgetRetry(t.Context(), callHub, time.Hour), "unbudgeted requests may wait")
+}
+
+func TestGitHubClient_DeadlineBudget(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := &GitHubClient{Token: "test-token", BaseURL: server.URL, Client: server.Client(),
+		maxAttempts: 3, retryDelay: 4 * time.Second, maxRetryDelay: 10 * time.Second}
+	entry := &Entry{VideoID: "test_video_id"}
+
+	t.Run("retry_past_the_budget_fails_fast", func(t *testing.T) {
+		t.Setenv("FUNCTION_TIMEOUT_SECONDS", "5")
+		t.Setenv("DEADLINE_RESERVE_MS", "2500")
+		ctx, cancel := withRequestBudget(t.Context())
+		defer cancel()
+
+		started := time.Now()
+		err := client.TriggerWorkflow(ctx, "test-owner", "test-repo", entry, nil)
+
+		assert.ErrorIs(t, err, ErrDeadlineBudget)
+		var apiErr *GitHubAPIError
+		require.ErrorAs(t, err, &apiErr, "the last GitHub error is kept")
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+		assert.Less(t, time.Since(started), time.Second, "the retry wait is skipped")
+	})
+
+	t.Run("exhausted_budget_sends_nothing", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		err := client.TriggerWorkflow(exhaustedBudget(t), "test-owner", "test-repo", entry, nil)
+
+		assert.ErrorIs(t, err, ErrDeadlineBudget)
+		assert.Zero(t, atomic.LoadInt32(&requests))
+	})
+}
+
+func TestHandleNotification_DeadlineBudget(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+	post := func(ctx context.Context, deps *Dependencies, videoID string) *httptest.ResponseRecorder {
+		subscribeTestChannels(deps, routingChannelID)
+		now := time.Now().Format(time.RFC3339)
+		notification := fmt.Sprintf(`<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry><yt:videoId>%s</yt:videoId><yt:channelId>%s</yt:channelId><title>Video</title><published>%s</published><updated>%s</updated></entry>
+</feed>`, videoID, routingChannelID, now, now)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(notification)).WithContext(ctx)
+		handleNotification(deps)(rec, req)
+		return rec
+	}
+
+	t.Run("dispatch_is_queued_for_retry", func(t *testing.T) {
+		deps := CreateTestDependencies()
+
+		rec := post(exhaustedBudget(t), deps, "budget001")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "queued for retry")
+		assert.Contains(t, rec.Body.String(), "deadline budget exhausted")
+		assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("unqueued_dispatch_is_unavailable", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).ObjectErrors = map[string]error{dispatchQueueObject: ErrMockSaveFailure}
+
+		rec := post(exhaustedBudget(t), deps, "budget002")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrCodeUnavailable)
+	})
+}
+
+func TestRenewBatch_DeadlineBudget(t *testing.T) {
+	deps := CreateTestDependencies()
+	channelIDs := seedExpiringSubscriptions(t, deps, 3)
+	state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+	require.NoError(t, err)
+
+	results := renewBatch(exhaustedBudget(t), deps, state, channelIDs, 2, 0, nil)
+
+	assert.Empty(t, results, "renewals the budget cannot fit are deferred")
+	assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	for _, channelID := range channelIDs {
+		assert.Zero(t, state.Subscriptions[channelID].RenewalAttempts, "deferred renewals are not failures")
+	}
+}
@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// callbackTokenObject is where the callback path token is stored
+const callbackTokenObject = "callback_token.json"
+
+// Callback path token modes, from CALLBACK_PATH_TOKEN
+const (
+	CallbackTokenOff      = "off"      // The hub calls FUNCTION_URL itself
+	CallbackTokenOn       = "on"       // Subscriptions use FUNCTION_URL/notify/{token}; the root path still works
+	CallbackTokenRequired = "required" // As on, and hub requests to the root path are refused
+)
+
+// callbackPathPrefix starts the callback path carrying the token
+const callbackPathPrefix = "notify/"
+
+// callbackTokenRecheck is how often a wrong token has the stored token read again
+const callbackTokenRecheck = time.Minute
+
+// getCallbackTokenMode returns CALLBACK_PATH_TOKEN, falling back to off for unknown values
+func getCallbackTokenMode() string {
+	return envConfig().CallbackTokenMode
+}
+
+// callbackToken is the stored token
+type callbackToken struct {
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CallbackTokenStore keeps the secret path segment of the hub callback URL. The token
+// is created on first use and never changes, so instances cache it once read.
+type CallbackTokenStore struct {
+	store ObjectStorage
+}
+
+// cachedCallbackToken is a token as read from storage
+type cachedCallbackToken struct {
+	token    string
+	loadedAt time.Time
+}
+
+// callbackTokens caches each storage's token
+var (
+	callbackTokensMu sync.Mutex
+	callbackTokens   = map[ObjectStorage]cachedCallbackToken{}
+)
+
+// NewCallbackTokenStore creates a token store in the given storage.
+// Returns nil when CALLBACK_PATH_TOKEN is off or the storage cannot hold objects.
+func NewCallbackTokenStore(storage StorageService, cfg *config.Config) *CallbackTokenStore {
+	store, ok := storage.(ObjectStorage)
+	if !ok || cfg.CallbackTokenMode == CallbackTokenOff {
+		return nil
+	}
+	return &CallbackTokenStore{store: store}
+}
+
+// Token returns the callback token, creating and storing one when there is none
+func (s *CallbackTokenStore) Token(ctx context.Context) (string, error) {
+	if cached, ok := s.cached(); ok {
+		return cached.token, nil
+	}
+	token, err := s.load(ctx)
+	if !errors.Is(err, ErrObjectNotFound) {
+		return token, err
+	}
+
+	secret, err := generateHubSecret()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(callbackToken{Token: secret[:32], CreatedAt: currentTime(ctx)})
+	if err != nil {
+		return "", err
+	}
+	// Instances creating a token at the same time all use the one created first
+	if err := s.store.CreateObject(ctx, callbackTokenObject, data); err != nil && !errors.Is(err, ErrObjectChanged) {
+		return "", fmt.Errorf("storing callback token: %w", err)
+	}
+	return s.load(ctx)
+}
+
+// Valid reports whether token is the callback token. A mismatch is checked against
+// storage again, at most once a minute, in case the stored token was replaced.
+func (s *CallbackTokenStore) Valid(ctx context.Context, token string) bool {
+	cached, ok := s.cached()
+	if ok && subtle.ConstantTimeCompare([]byte(cached.token), []byte(token)) == 1 {
+		return true
+	}
+	if ok && time.Since(cached.loadedAt) < callbackTokenRecheck {
+		return false
+	}
+	stored, err := s.load(ctx)
+	if err != nil {
+		if !errors.Is(err, ErrObjectNotFound) {
+			fmt.Printf("Unable to load callback token: %v\n", err)
+		}
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(token)) == 1
+}
+
+// cached returns the token this instance last read, if any
+func (s *CallbackTokenStore) cached() (cachedCallbackToken, bool) {
+	callbackTokensMu.Lock()
+	defer callbackTokensMu.Unlock()
+	cached, ok := callbackTokens[s.store]
+	return cached, ok
+}
+
+// load reads the stored token and caches it
+func (s *CallbackTokenStore) load(ctx context.Context) (string, error) {
+	data, err := s.store.ReadObject(ctx, callbackTokenObject)
+	if err != nil {
+		return "", err
+	}
+	var stored callbackToken
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return "", fmt.Errorf("decoding callback token: %w", err)
+	}
+	if stored.Token == "" {
+		return "", fmt.Errorf("decoding callback token: %w", ErrObjectNotFound)
+	}
+
+	callbackTokensMu.Lock()
+	callbackTokens[s.store] = cachedCallbackToken{token: stored.Token, loadedAt: time.Now()}
+	callbackTokensMu.Unlock()
+	return stored.Token, nil
+}
+
+// subscriptionCallback returns the callback URL to subscribe with: FUNCTION_URL, followed
+// by /notify/{token} unless CALLBACK_PATH_TOKEN is off
+func subscriptionCallback(ctx context.Context, deps *Dependencies) (string, error) {
+	callbackURL := deps.config().FunctionURL
+	if callbackURL == "" {
+		callbackURL = "https://default-function-url"
+	}
+	tokens := NewCallbackTokenStore(deps.StorageClient, deps.config())
+	if tokens == nil {
+		return callbackURL, nil
+	}
+	token, err := tokens.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("callback token unavailable: %w", err)
+	}
+	return strings.TrimSuffix(callbackURL, "/") + "/" + callbackPathPrefix + token, nil
+}
+
+// currentCallback returns the callback URL subscriptions are being made with, without
+// creating a callback token: FUNCTION_URL, with the token's path once one is stored
+func currentCallback(ctx context.Context, deps *Dependencies) string {
+	callbackURL := deps.config().FunctionURL
+	tokens := NewCallbackTokenStore(deps.StorageClient, deps.config())
+	if callbackURL == "" || tokens == nil {
+		return callbackURL
+	}
+	token, ok := tokens.cached()
+	if !ok {
+		stored, err := tokens.load(ctx)
+		if err != nil {
+			return callbackURL
+		}
+		token.token = stored
+	}
+	return strings.TrimSuffix(callbackURL, "/") + "/" + callbackPathPrefix + token.token
+}
+
+// resubscribe renews the hub subscription to channelID with sub's hub, secret and
+// lease, at the current callback URL. The callback URL is recorded on sub once the hub
+// accepts.
+func resubscribe(ctx context.Context, deps *Dependencies, channelID string, sub *Subscription) error {
+	secret, err := ensureHubSecret(sub)
+	if err != nil {
+		return err
+	}
+	callbackURL, err := subscriptionCallback(ctx, deps)
+	if err != nil {
+		return err
+	}
+	hubCtx := withCallbackURL(withHubURL(ctx, sub.HubURL), callbackURL)
+	if err := deps.PubSubClient.Subscribe(hubCtx, channelID, secret, sub.RequestedLeaseSeconds); err != nil {
+		return err
+	}
+	sub.CallbackURL = callbackURL
+	return nil
+}
+
+// isHubPath reports whether path, without its leading slash, is where the hub sends
+// verification challenges and notifications
+func isHubPath(path string) bool {
+	path = strings.Trim(path, "/")
+	return path == "" || strings.HasPrefix(path, callbackPathPrefix)
+}
+
+// secretRoute reports whether a route's path carries the callback token, so the path
+// is logged and traced as its pattern
+func secretRoute(matched *route) bool {
+	return matched != nil && strings.Contains(matched.pattern, "{token}")
+}
+
+// hubRoot guards a hub handler at the root path: with CALLBACK_PATH_TOKEN=required the
+// hub must use the callback path, and the root path answers as an unknown endpoint
+func hubRoot(handler func(*Dependencies) http.HandlerFunc) routeHandler {
+	return func(deps *Dependencies, _ pathParams) http.HandlerFunc {
+		if deps.config().CallbackTokenMode == CallbackTokenRequired {
+			return func(w http.ResponseWriter, r *http.Request) {
+				deps.Metrics.RecordCallbackRejected("root")
+				writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "", "Endpoint not found")
+			}
+		}
+		return handler(deps)
+	}
+}
+
+// withCallbackToken guards a hub handler at the callback path: requests whose "{token}"
+// segment is not the callback token answer as an unknown endpoint
+func withCallbackToken(handler func(*Dependencies) http.HandlerFunc) routeHandler {
+	return func(deps *Dependencies, params pathParams) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tokens := NewCallbackTokenStore(deps.StorageClient, deps.config())
+			if tokens == nil || !tokens.Valid(r.Context(), params["token"]) {
+				deps.Metrics.RecordCallbackRejected("token")
+				writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "", "Endpoint not found")
+				return
+			}
+			handler(deps)(w, r)
+		}
+	}
+}
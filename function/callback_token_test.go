@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackTokenStore(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMockStorageClient()
+	assert.Nil(t, NewCallbackTokenStore(storage, envConfig()), "off by default")
+
+	t.Setenv("CALLBACK_PATH_TOKEN", CallbackTokenOn)
+	tokens := NewCallbackTokenStore(storage, envConfig())
+	require.NotNil(t, tokens)
+
+	token, err := tokens.Token(ctx)
+	require.NoError(t, err)
+	assert.Len(t, token, 32)
+	again, err := NewCallbackTokenStore(storage, envConfig()).Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, token, again, "the token is created once")
+
+	_, err = storage.ReadObject(ctx, callbackTokenObject)
+	require.NoError(t, err, "the token is kept in storage")
+
+	assert.True(t, tokens.Valid(ctx, token))
+	assert.False(t, tokens.Valid(ctx, "wrong"))
+	assert.False(t, tokens.Valid(ctx, ""))
+	assert.False(t, NewCallbackTokenStore(NewMockStorageClient(), envConfig()).Valid(ctx, token), "no token is stored yet")
+}
+
+// racingTokenStorage is an instance's view of storage shared with another instance. Its
+// first read of the callback token lets the other instance create one before reporting
+// that there is none, as when both cold-start together.
+type racingTokenStorage struct {
+	*MockStorageClient
+	beforeFirstRead func()
+}
+
+func (r *racingTokenStorage) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	if race := r.beforeFirstRead; race != nil && name == callbackTokenObject {
+		r.beforeFirstRead = nil
+		race()
+		return nil, ErrObjectNotFound
+	}
+	return r.MockStorageClient.ReadObject(ctx, name)
+}
+
+func TestCallbackTokenStore_ConcurrentCreators(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("CALLBACK_PATH_TOKEN", CallbackTokenOn)
+	shared := NewMockStorageClient()
+
+	var other string
+	first := NewCallbackTokenStore(&racingTokenStorage{MockStorageClient: shared, beforeFirstRead: func() {
+		var err error
+		other, err = NewCallbackTokenStore(&racingTokenStorage{MockStorageClient: shared}, envConfig()).Token(ctx)
+		require.NoError(t, err)
+	}}, envConfig())
+
+	token, err := first.Token(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, other)
+	assert.Equal(t, other, token, "the instance that lost the race uses the token created first")
+	assert.True(t, first.Valid(ctx, other))
+}
+
+func TestYouTubeWebhook_CallbackPath(t *testing.T) {
+	const channelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	t.Setenv("FUNCTION_URL", "https://fn.example.com/")
+	t.Setenv("CALLBACK_PATH_TOKEN", CallbackTokenOn)
+
+	var mu sync.Mutex
+	var callbacks []string
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		mu.Lock()
+		callbacks = append(callbacks, r.FormValue("hub.mode")+" "+r.FormValue("hub.callback"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	deps := CreateTestDependencies()
+	deps.PubSubClient = &HTTPPubSubClient{hubURL: hub.URL, callbackURL: "https://fn.example.com", client: &http.Client{Timeout: time.Second}}
+	deps.Metrics = NewMetrics()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+	challenge := func(path string) *httptest.ResponseRecorder {
+		query := url.Values{}
+		query.Set("hub.challenge", "challenge-1")
+		query.Set("hub.mode", "subscribe")
+		query.Set("hub.topic", topicURLFor(channelID))
+		query.Set("hub.lease_seconds", "3600")
+		return serve("GET", path+"?"+query.Encode())
+	}
+
+	require.Equal(t, http.StatusOK, serve("POST", "/subscribe?channel_id="+channelID).Code)
+	token, err := NewCallbackTokenStore(deps.StorageClient, envConfig()).Token(context.Background())
+	require.NoError(t, err)
+	callbackURL := "https://fn.example.com/notify/" + token
+	assert.Equal(t, []string{"subscribe " + callbackURL}, callbacks, "the hub is given the callback path")
+
+	sub, err := deps.StorageClient.GetSubscription(context.Background(), channelID)
+	require.NoError(t, err)
+	assert.Equal(t, callbackURL, sub.CallbackURL)
+
+	t.Run("token_checked", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, challenge("/notify/"+strings.Repeat("0", 32)).Code)
+		assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricCallbacksRejected, "path", "token"))
+
+		rec := challenge("/notify/" + token)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "challenge-1", rec.Body.String())
+	})
+
+	t.Run("root_path_while_migrating", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, challenge("/").Code)
+
+		t.Setenv("CALLBACK_PATH_TOKEN", CallbackTokenRequired)
+		assert.Equal(t, http.StatusNotFound, challenge("/").Code)
+		assert.Equal(t, http.StatusNotFound, serve("POST", "/").Code)
+		assert.Equal(t, 2.0, deps.Metrics.CounterValue(metricCallbacksRejected, "path", "root"))
+		assert.Equal(t, http.StatusOK, challenge("/notify/"+token).Code)
+	})
+
+	t.Run("unsubscribe_uses_subscribed_callback", func(t *testing.T) {
+		// A subscription made before CALLBACK_PATH_TOKEN was turned on
+		sub, err := deps.StorageClient.GetSubscription(context.Background(), channelID)
+		require.NoError(t, err)
+		sub.CallbackURL = "https://fn.example.com"
+		sub.HubURL = hub.URL
+		require.NoError(t, deps.StorageClient.SaveSubscription(context.Background(), sub))
+
+		rec := serve("DELETE", "/unsubscribe?channel_id="+channelID)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, "unsubscribe https://fn.example.com", callbacks[len(callbacks)-1])
+	})
+}
+
+func TestCurrentCallback(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("FUNCTION_URL", "https://fn.example.com")
+	deps := CreateTestDependencies()
+	assert.Equal(t, "https://fn.example.com", currentCallback(ctx, deps))
+
+	t.Setenv("CALLBACK_PATH_TOKEN", CallbackTokenRequired)
+	assert.Equal(t, "https://fn.example.com", currentCallback(ctx, deps), "no token is created")
+
+	token, err := NewCallbackTokenStore(deps.StorageClient, envConfig()).Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "https://fn.example.com/notify/"+token, currentCallback(ctx, deps))
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Bounds on the lease a channel can request from the hub
+const (
+	minLeaseSeconds = 3600   // 1 hour
+	maxLeaseSeconds = 864000 // 10 days, the longest lease the hub grants
+)
+
+// ChannelConfigPatch is the body of PATCH /subscriptions/{channel_id}/config. Only the
+// fields present are changed.
+type ChannelConfigPatch struct {
+	LeaseSeconds *int                `json:"lease_seconds,omitempty"` // 0 goes back to SUBSCRIPTION_LEASE_SECONDS
+	Filter       *NotificationFilter `json:"filter,omitempty"`        // Replaces the filter; {} removes it
+	Sinks        *[]WebhookSink      `json:"sinks,omitempty"`         // Replaces the sinks; [] removes them
+}
+
+// ChannelConfigResponse reports a channel's configuration
+type ChannelConfigResponse struct {
+	Status              string              `json:"status"`
+	ChannelID           string              `json:"channel_id"`
+	LeaseSeconds        int                 `json:"lease_seconds"`         // Requested from the hub; 0 uses SUBSCRIPTION_LEASE_SECONDS
+	GrantedLeaseSeconds int                 `json:"granted_lease_seconds"` // The lease of the last hub verification
+	Filter              *NotificationFilter `json:"filter"`                // null when every new video is dispatched
+	Sinks               []WebhookSinkInfo   `json:"sinks"`
+}
+
+// Validate checks every field of a patch before any of it is applied
+func (p *ChannelConfigPatch) Validate() error {
+	if p.LeaseSeconds != nil {
+		if lease := *p.LeaseSeconds; lease != 0 && (lease < minLeaseSeconds || lease > maxLeaseSeconds) {
+			return fmt.Errorf("lease_seconds must be 0 or between %d and %d", minLeaseSeconds, maxLeaseSeconds)
+		}
+	}
+	if p.Filter != nil {
+		if err := p.Filter.Validate(); err != nil {
+			return err
+		}
+	}
+	if p.Sinks != nil {
+		return validateWebhookSinks(*p.Sinks)
+	}
+	return nil
+}
+
+// Apply changes the subscription's configuration to match the patch
+func (p *ChannelConfigPatch) Apply(sub *Subscription) {
+	if p.LeaseSeconds != nil {
+		sub.RequestedLeaseSeconds = *p.LeaseSeconds
+	}
+	if p.Filter != nil {
+		sub.Filter = p.Filter
+		if p.Filter.IsEmpty() {
+			sub.Filter = nil
+		}
+	}
+	if p.Sinks != nil {
+		sub.Sinks = *p.Sinks
+		if len(sub.Sinks) == 0 {
+			sub.Sinks = nil
+		}
+	}
+}
+
+// channelConfigResponse builds the configuration response for a subscription
+func channelConfigResponse(sub *Subscription) ChannelConfigResponse {
+	return ChannelConfigResponse{
+		Status:              "success",
+		ChannelID:           sub.ChannelID,
+		LeaseSeconds:        sub.RequestedLeaseSeconds,
+		GrantedLeaseSeconds: sub.LeaseSeconds,
+		Filter:              sub.Filter,
+		Sinks:               sinkInfos(sub.Sinks),
+	}
+}
+
+// handleGetChannelConfig handles GET /subscriptions/{channel_id}/config requests using dependency injection
+func handleGetChannelConfig(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := loadChannelSubscription(w, r, deps, channelID)
+		if !ok {
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, channelConfigResponse(sub))
+	}
+}
+
+// handlePatchChannelConfig handles PATCH /subscriptions/{channel_id}/config requests using
+// dependency injection. A new lease is requested from the hub at the next renewal.
+func handlePatchChannelConfig(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		var patch ChannelConfigPatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Request body must be a JSON channel configuration")
+			return
+		}
+		if err := patch.Validate(); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, err.Error())
+			return
+		}
+
+		sub, ok := loadChannelSubscription(w, r, deps, channelID)
+		if !ok {
+			return
+		}
+		patch.Apply(sub)
+		if err := deps.StorageClient.SaveSubscription(r.Context(), sub); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to save subscription state: %v", err))
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, channelConfigResponse(sub))
+	}
+}
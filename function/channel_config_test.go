@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelConfigPatch_Validate(t *testing.T) {
+	lease := func(seconds int) *int { return &seconds }
+	tests := []struct {
+		name  string
+		patch ChannelConfigPatch
+		err   string
+	}{
+		{"empty", ChannelConfigPatch{}, ""},
+		{"default_lease", ChannelConfigPatch{LeaseSeconds: lease(0)}, ""},
+		{"lease_in_bounds", ChannelConfigPatch{LeaseSeconds: lease(maxLeaseSeconds)}, ""},
+		{"lease_too_short", ChannelConfigPatch{LeaseSeconds: lease(60)}, "lease_seconds"},
+		{"lease_too_long", ChannelConfigPatch{LeaseSeconds: lease(maxLeaseSeconds + 1)}, "lease_seconds"},
+		{"invalid_filter", ChannelConfigPatch{Filter: &NotificationFilter{TitlePattern: "("}}, "title_pattern"},
+		{"invalid_sinks", ChannelConfigPatch{Sinks: &[]WebhookSink{{URL: "ftp://example.com"}}}, "sink"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.patch.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestChannelConfigHandlers(t *testing.T) {
+	newDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID:    filterChannelID,
+			Status:       StatusActive,
+			LeaseSeconds: 86400,
+			ExpiresAt:    time.Now().Add(time.Hour),
+			Filter:       &NotificationFilter{ExcludeShorts: true},
+		}))
+		SetDependencies(deps)
+		t.Cleanup(func() { SetDependencies(nil) })
+		return deps
+	}
+	call := func(method, body string) (*httptest.ResponseRecorder, ChannelConfigResponse) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest(method, "/subscriptions/"+filterChannelID+"/config", strings.NewReader(body)))
+		var response ChannelConfigResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	t.Run("get", func(t *testing.T) {
+		newDeps()
+
+		rec, response := call("GET", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 0, response.LeaseSeconds)
+		assert.Equal(t, 86400, response.GrantedLeaseSeconds)
+		require.NotNil(t, response.Filter)
+		assert.True(t, response.Filter.ExcludeShorts)
+		assert.NotNil(t, response.Sinks)
+	})
+
+	t.Run("patch_changes_only_given_fields", func(t *testing.T) {
+		deps := newDeps()
+
+		rec, response := call("PATCH", `{"lease_seconds":432000,"sinks":[{"url":"https://example.com/hook","secret":"s3cret"}]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 432000, response.LeaseSeconds)
+		require.Len(t, response.Sinks, 1)
+		assert.True(t, response.Sinks[0].Signed)
+		assert.NotContains(t, rec.Body.String(), "s3cret")
+
+		stored := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[filterChannelID]
+		assert.Equal(t, 432000, stored.RequestedLeaseSeconds)
+		assert.Equal(t, "s3cret", stored.Sinks[0].Secret)
+		require.NotNil(t, stored.Filter, "filter is kept when not patched")
+		assert.True(t, stored.Filter.ExcludeShorts)
+
+		rec, response = call("PATCH", `{"filter":{},"sinks":[],"lease_seconds":0}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, response.Filter)
+		assert.Empty(t, response.Sinks)
+		stored = deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[filterChannelID]
+		assert.Nil(t, stored.Filter)
+		assert.Nil(t, stored.Sinks)
+		assert.Equal(t, 0, stored.RequestedLeaseSeconds)
+	})
+
+	t.Run("renewal_requests_the_lease", func(t *testing.T) {
+		deps := newDeps()
+
+		rec, _ := call("PATCH", `{"lease_seconds":7200}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("POST", "/renew", strings.NewReader(`{"channel_ids":["`+filterChannelID+`"],"force":true}`)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 7200, deps.PubSubClient.(*MockPubSubClient).GetLastLeaseSeconds())
+	})
+
+	t.Run("invalid_patch_changes_nothing", func(t *testing.T) {
+		deps := newDeps()
+
+		rec, _ := call("PATCH", `{"lease_seconds":432000,"filter":{"title_pattern":"("}}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, 0, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[filterChannelID].RequestedLeaseSeconds)
+
+		rec, _ = call("PATCH", `not json`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		deps := newDeps()
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("PATCH", "/subscriptions/UCabcdefghijklmnopqrstuv/config", strings.NewReader(`{}`)))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/bogus/config", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec, _ = call("PUT", `{}`)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "GET, PATCH, OPTIONS", rec.Header().Get("Allow"))
+
+		deps.StorageClient.(*MockStorageClient).SaveError = ErrMockSaveFailure
+		rec, _ = call("PATCH", `{"lease_seconds":7200}`)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ChannelNameLookup finds the human-readable name of a channel
+type ChannelNameLookup interface {
+	ChannelName(ctx context.Context, channelID string) (string, error)
+}
+
+// ChannelNameFetcher looks channel names up with the YouTube Data API when
+// YOUTUBE_API_KEY is set, and from the channel's public video feed otherwise
+type ChannelNameFetcher struct {
+	API     *YouTubeAPIClient
+	FeedURL string
+	Client  *http.Client
+}
+
+// channelFeed is the part of a channel's video feed that names the channel
+type channelFeed struct {
+	Title  string `xml:"title"`
+	Author Author `xml:"author"`
+}
+
+// NewChannelNameFetcher creates a channel name fetcher. YOUTUBE_FEED_URL overrides the
+// feed location, for tests.
+func NewChannelNameFetcher() *ChannelNameFetcher {
+	feedURL := os.Getenv("YOUTUBE_FEED_URL")
+	if feedURL == "" {
+		feedURL = youtubeFeedURL
+	}
+
+	return &ChannelNameFetcher{
+		API:     NewYouTubeAPIClient(),
+		FeedURL: feedURL,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ChannelName returns the channel's title, or a playlist's when given a playlist ID.
+// Playlists are always looked up from their feed.
+func (f *ChannelNameFetcher) ChannelName(ctx context.Context, channelID string) (string, error) {
+	if f.API != nil && f.API.IsConfigured() && topicTypeOf(channelID) == TopicTypeChannel {
+		_, name, err := f.API.GetChannel(ctx, "id", channelID)
+		return name, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.FeedURL+"?"+topicParam(channelID)+"="+url.QueryEscape(channelID), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch channel feed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrChannelNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("channel feed returned status %d", resp.StatusCode)
+	}
+
+	var feed channelFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return "", fmt.Errorf("failed to parse channel feed: %v", err)
+	}
+	// The feed is titled with the channel or playlist name; a channel's author is the same name
+	if name := strings.TrimSpace(feed.Title); name != "" {
+		return name, nil
+	}
+	return strings.TrimSpace(feed.Author.Name), nil
+}
+
+// getChannelNameRefresh returns how long a looked-up channel name is kept before
+// renewals look it up again, from CHANNEL_NAME_REFRESH_HOURS (default 168, weekly)
+func getChannelNameRefresh() time.Duration {
+	return time.Duration(getIntEnv("CHANNEL_NAME_REFRESH_HOURS", 168)) * time.Hour
+}
+
+// refreshChannelName looks up the subscription's channel name when it has none or the
+// last lookup is older than CHANNEL_NAME_REFRESH_HOURS. Lookup failures are logged and
+// keep the current name.
+func refreshChannelName(ctx context.Context, sub *Subscription, deps *Dependencies) {
+	if deps.ChannelNames == nil {
+		return
+	}
+	now := deps.now()
+	if sub.ChannelName != "" && now.Sub(sub.ChannelNameUpdatedAt) < getChannelNameRefresh() {
+		return
+	}
+
+	name, err := deps.ChannelNames.ChannelName(ctx, sub.ChannelID)
+	if err != nil {
+		fmt.Printf("Unable to look up the name of %s %s: %v\n", sub.Type(), sub.ChannelID, err)
+		return
+	}
+	if name != "" {
+		sub.ChannelName = name
+		sub.ChannelNameUpdatedAt = now
+	}
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubChannelNames answers channel name lookups from a map and counts them
+type stubChannelNames struct {
+	names   map[string]string
+	err     error
+	lookups int
+}
+
+func (s *stubChannelNames) ChannelName(ctx context.Context, channelID string) (string, error) {
+	s.lookups++
+	return s.names[channelID], s.err
+}
+
+func TestChannelNameFetcher(t *testing.T) {
+	t.Run("feed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("channel_id") != resolverTestChannel {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Some Creator</title>
+  <author><name>Some Creator</name></author>
+</feed>`)
+		}))
+		defer server.Close()
+
+		fetcher := &ChannelNameFetcher{API: &YouTubeAPIClient{}, FeedURL: server.URL, Client: server.Client()}
+		name, err := fetcher.ChannelName(context.Background(), resolverTestChannel)
+		require.NoError(t, err)
+		assert.Equal(t, "Some Creator", name)
+
+		_, err = fetcher.ChannelName(context.Background(), "UCabcdefghijklmnopqrstuv")
+		assert.ErrorIs(t, err, ErrChannelNotFound)
+	})
+
+	t.Run("data_api", func(t *testing.T) {
+		server := newChannelsAPIServer(t)
+		defer server.Close()
+
+		api := &YouTubeAPIClient{APIKey: "test-key", BaseURL: server.URL, Client: server.Client()}
+		fetcher := &ChannelNameFetcher{API: api, FeedURL: "http://127.0.0.1:0", Client: server.Client()}
+		name, err := fetcher.ChannelName(context.Background(), resolverTestChannel)
+		require.NoError(t, err)
+		assert.Equal(t, "Some Creator", name)
+	})
+}
+
+func TestRefreshChannelName(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		sub     Subscription
+		err     error
+		lookups int
+		want    string
+	}{
+		{"missing_name", Subscription{ChannelID: resolverTestChannel}, nil, 1, "Some Creator"},
+		{"fresh_name", Subscription{ChannelID: resolverTestChannel, ChannelName: "Old Name", ChannelNameUpdatedAt: now.Add(-time.Hour)}, nil, 0, "Old Name"},
+		{"stale_name", Subscription{ChannelID: resolverTestChannel, ChannelName: "Old Name", ChannelNameUpdatedAt: now.Add(-200 * time.Hour)}, nil, 1, "Some Creator"},
+		{"lookup_fails", Subscription{ChannelID: resolverTestChannel, ChannelName: "Old Name"}, errors.New("feed unavailable"), 1, "Old Name"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			names := &stubChannelNames{names: map[string]string{resolverTestChannel: "Some Creator"}, err: tc.err}
+			deps := CreateTestDependencies()
+			deps.ChannelNames = names
+
+			sub := tc.sub
+			refreshChannelName(context.Background(), &sub, deps)
+			assert.Equal(t, tc.lookups, names.lookups)
+			assert.Equal(t, tc.want, sub.ChannelName)
+		})
+	}
+
+	t.Run("no_lookup", func(t *testing.T) {
+		sub := Subscription{ChannelID: resolverTestChannel}
+		refreshChannelName(context.Background(), &sub, CreateTestDependencies())
+		assert.Empty(t, sub.ChannelName)
+	})
+}
+
+func TestChannelNames_SubscribeAndRenew(t *testing.T) {
+	names := &stubChannelNames{names: map[string]string{resolverTestChannel: "Some Creator"}}
+	deps := CreateTestDependencies()
+	deps.ChannelNames = names
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscribe?channel_id="+resolverTestChannel, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"channel_name":"Some Creator"`)
+
+	// A renewal picks up a renamed channel once the stored name is due a refresh
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID:            resolverTestChannel,
+		ChannelName:          "Some Creator",
+		ChannelNameUpdatedAt: time.Now().Add(-200 * time.Hour),
+		Status:               StatusActive,
+		LeaseSeconds:         86400,
+		ExpiresAt:            time.Now().Add(time.Hour),
+	}))
+	names.names[resolverTestChannel] = "Some Creator Renamed"
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/renew", strings.NewReader(`{"force":true}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	stored := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[resolverTestChannel]
+	assert.Equal(t, "Some Creator Renamed", stored.ChannelName)
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions", nil))
+	assert.Contains(t, rec.Body.String(), `"channel_name":"Some Creator Renamed"`)
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// handlePattern matches a YouTube @handle
+var handlePattern = regexp.MustCompile(`^@[A-Za-z0-9._-]{3,30}$`)
+
+// youTubeHosts are the hosts whose channel URLs can be resolved
+var youTubeHosts = map[string]bool{
+	"youtube.com":     true,
+	"www.youtube.com": true,
+	"m.youtube.com":   true,
+}
+
+// parseChannelRef reads a channel as users write it (a channel ID, an @handle, or a
+// youtube.com/channel/, /@ or /user/ URL) and returns the channels.list filter and value
+// that look it up
+func parseChannelRef(ref string) (string, string, error) {
+	ref = strings.TrimSpace(ref)
+	switch {
+	case validateChannelID(ref):
+		return "id", ref, nil
+	case strings.HasPrefix(ref, "@"):
+		if !handlePattern.MatchString(ref) {
+			return "", "", fmt.Errorf("invalid handle %q", ref)
+		}
+		return "forHandle", ref, nil
+	}
+
+	raw := ref
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !youTubeHosts[strings.ToLower(u.Hostname())] {
+		return "", "", fmt.Errorf("%q is not a channel ID, @handle or YouTube channel URL", ref)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case strings.HasPrefix(segments[0], "@"):
+		return parseChannelRef(segments[0])
+	case len(segments) < 2:
+	case segments[0] == "channel" && validateChannelID(segments[1]):
+		return "id", segments[1], nil
+	case segments[0] == "user":
+		return "forUsername", segments[1], nil
+	case segments[0] == "c":
+		return "", "", fmt.Errorf("custom /c/ URLs cannot be resolved; use the channel's @handle or /channel/ URL")
+	}
+	return "", "", fmt.Errorf("%q is not a YouTube channel URL", ref)
+}
+
+// resolveChannel turns a channel reference into a channel ID and, when it was looked up
+// with the YouTube Data API, the channel's name. Channel IDs and /channel/ URLs resolve
+// without the API. When the reference cannot be resolved, it returns the status code
+// and message to respond with.
+func resolveChannel(ctx context.Context, deps *Dependencies, ref string) (string, string, int, string) {
+	filter, value, err := parseChannelRef(ref)
+	if err != nil {
+		return "", "", http.StatusBadRequest, err.Error()
+	}
+	if filter == "id" {
+		return value, "", 0, ""
+	}
+
+	youtube := NewYouTubeAPIClient()
+	youtube.Client = deps.HTTPClients.Client(httpClientYouTube, youtube.Client.Timeout)
+	if !youtube.IsConfigured() {
+		return "", "", http.StatusNotImplemented, "Set YOUTUBE_API_KEY to subscribe by handle or channel URL"
+	}
+
+	channelID, name, err := youtube.GetChannel(ctx, filter, value)
+	if errors.Is(err, ErrChannelNotFound) {
+		return "", "", http.StatusNotFound, fmt.Sprintf("No YouTube channel found for %s", ref)
+	}
+	if err != nil {
+		return "", "", http.StatusBadGateway, fmt.Sprintf("Failed to resolve channel: %v", err)
+	}
+	return channelID, name, 0, ""
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const resolverTestChannel = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+// newChannelsAPIServer serves channels.list, knowing only resolverTestChannel as
+// @SomeCreator and the legacy user somecreator
+func newChannelsAPIServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/channels", r.URL.Path)
+		assert.Equal(t, "test-key", r.URL.Query().Get("key"))
+		query := r.URL.Query()
+		if query.Get("forHandle") != "@SomeCreator" && query.Get("forUsername") != "somecreator" && query.Get("id") != resolverTestChannel {
+			fmt.Fprint(w, `{"items":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"items":[{"id":%q,"snippet":{"title":"Some Creator"}}]}`, resolverTestChannel)
+	}))
+}
+
+func TestParseChannelRef(t *testing.T) {
+	tests := []struct {
+		ref    string
+		filter string
+		value  string
+	}{
+		{resolverTestChannel, "id", resolverTestChannel},
+		{"@SomeCreator", "forHandle", "@SomeCreator"},
+		{"https://www.youtube.com/@SomeCreator", "forHandle", "@SomeCreator"},
+		{"youtube.com/@SomeCreator/videos", "forHandle", "@SomeCreator"},
+		{"https://m.youtube.com/channel/" + resolverTestChannel, "id", resolverTestChannel},
+		{"https://www.youtube.com/user/somecreator", "forUsername", "somecreator"},
+		{"  @SomeCreator  ", "forHandle", "@SomeCreator"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			filter, value, err := parseChannelRef(tc.ref)
+			require.NoError(t, err)
+			assert.Equal(t, tc.filter, filter)
+			assert.Equal(t, tc.value, value)
+		})
+	}
+
+	for _, ref := range []string{"", "SomeCreator", "@x", "https://example.com/@SomeCreator",
+		"https://www.youtube.com/c/SomeCreator", "https://www.youtube.com/channel/bogus", "https://www.youtube.com/"} {
+		t.Run("invalid_"+ref, func(t *testing.T) {
+			_, _, err := parseChannelRef(ref)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestYouTubeAPIClient_GetChannel(t *testing.T) {
+	server := newChannelsAPIServer(t)
+	defer server.Close()
+
+	client := &YouTubeAPIClient{APIKey: "test-key", BaseURL: server.URL, Client: server.Client()}
+
+	channelID, name, err := client.GetChannel(context.Background(), "forHandle", "@SomeCreator")
+	require.NoError(t, err)
+	assert.Equal(t, resolverTestChannel, channelID)
+	assert.Equal(t, "Some Creator", name)
+
+	_, _, err = client.GetChannel(context.Background(), "forHandle", "@Nobody")
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+}
+
+func TestSubscribe_ByHandle(t *testing.T) {
+	subscribe := func(query string) (*httptest.ResponseRecorder, APIResponse) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscribe?"+query, nil))
+		var response APIResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+	newDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		SetDependencies(deps)
+		t.Cleanup(func() { SetDependencies(nil) })
+		return deps
+	}
+	withAPI := func() {
+		server := newChannelsAPIServer(t)
+		t.Cleanup(server.Close)
+		t.Setenv("YOUTUBE_API_KEY", "test-key")
+		t.Setenv("YOUTUBE_API_BASE_URL", server.URL)
+	}
+
+	t.Run("resolves_handle_and_stores_name", func(t *testing.T) {
+		withAPI()
+		deps := newDeps()
+
+		rec, response := subscribe("handle=" + url.QueryEscape("https://www.youtube.com/@SomeCreator"))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, resolverTestChannel, response.ChannelID)
+		assert.Equal(t, "Some Creator", response.ChannelName)
+
+		stored := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[resolverTestChannel]
+		require.NotNil(t, stored)
+		assert.Equal(t, "Some Creator", stored.ChannelName)
+
+		rec, response = subscribe("handle=%40SomeCreator")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, resolverTestChannel, response.ChannelID)
+	})
+
+	t.Run("channel_id_gets_name", func(t *testing.T) {
+		withAPI()
+		deps := newDeps()
+		deps.ChannelNames = NewChannelNameFetcher()
+
+		rec, _ := subscribe("channel_id=" + resolverTestChannel)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Some Creator", deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[resolverTestChannel].ChannelName)
+	})
+
+	t.Run("unknown_handle", func(t *testing.T) {
+		withAPI()
+		deps := newDeps()
+
+		rec, _ := subscribe("handle=%40Nobody")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("handle_needs_api_key", func(t *testing.T) {
+		t.Setenv("YOUTUBE_API_KEY", "")
+		deps := newDeps()
+
+		rec, response := subscribe("handle=%40SomeCreator")
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+		assert.Contains(t, response.Message, "YOUTUBE_API_KEY")
+
+		rec, _ = subscribe("handle=" + url.QueryEscape("https://www.youtube.com/channel/"+resolverTestChannel))
+		require.Equal(t, http.StatusOK, rec.Code, "channel URLs resolve without the API")
+		assert.Empty(t, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[resolverTestChannel].ChannelName)
+	})
+
+	t.Run("invalid_requests", func(t *testing.T) {
+		newDeps()
+
+		rec, _ := subscribe("handle=" + url.QueryEscape("https://www.youtube.com/c/SomeCreator"))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec, _ = subscribe("handle=%40SomeCreator&channel_id=" + resolverTestChannel)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
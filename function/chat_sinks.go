@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Sink types; webhook sinks receive SinkPayload, chat sinks a formatted message
+const (
+	SinkTypeWebhook = "webhook"
+	SinkTypeDiscord = "discord"
+	SinkTypeSlack   = "slack"
+)
+
+// discordEmbedColor is YouTube red
+const discordEmbedColor = 0xFF0000
+
+// discordMessage is the body POSTed to a Discord webhook
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title     string            `json:"title"`
+	URL       string            `json:"url"`
+	Color     int               `json:"color"`
+	Timestamp string            `json:"timestamp,omitempty"`
+	Author    discordEmbedName  `json:"author"`
+	Image     discordEmbedImage `json:"image"`
+}
+
+type discordEmbedName struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+// slackMessage is the body POSTed to a Slack incoming webhook
+type slackMessage struct {
+	Text   string       `json:"text"` // Fallback for notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type      string            `json:"type"`
+	Text      *slackText        `json:"text,omitempty"`
+	Accessory map[string]string `json:"accessory,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// sinkType returns the sink's type, defaulting to a plain webhook
+func (s WebhookSink) sinkType() string {
+	if s.Type == "" {
+		return SinkTypeWebhook
+	}
+	return s.Type
+}
+
+// label identifies the sink in results, logs and API responses. Chat webhook URLs
+// are credentials, so their final path segment is masked.
+func (s WebhookSink) label() string {
+	if s.sinkType() == SinkTypeWebhook {
+		return s.URL
+	}
+	return s.sinkType() + ":" + s.maskedURL()
+}
+
+// maskedURL returns the sink's URL with the token of chat webhook URLs masked
+func (s WebhookSink) maskedURL() string {
+	if s.sinkType() == SinkTypeWebhook {
+		return s.URL
+	}
+	return maskURLToken(s.URL)
+}
+
+// maskURLToken replaces the last path segment of a URL with ****
+func maskURLToken(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "****"
+	}
+	path := strings.TrimSuffix(parsed.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[:i+1] + "****"
+	}
+	return parsed.Scheme + "://" + parsed.Host + path
+}
+
+// globalSinks returns the chat sinks set with DISCORD_WEBHOOK_URL and SLACK_WEBHOOK_URL,
+// which receive every channel's videos
+func globalSinks() []WebhookSink {
+	var sinks []WebhookSink
+	if discordURL := os.Getenv("DISCORD_WEBHOOK_URL"); discordURL != "" {
+		sinks = append(sinks, WebhookSink{Type: SinkTypeDiscord, URL: discordURL})
+	}
+	if slackURL := os.Getenv("SLACK_WEBHOOK_URL"); slackURL != "" {
+		sinks = append(sinks, WebhookSink{Type: SinkTypeSlack, URL: slackURL})
+	}
+	return sinks
+}
+
+// videoThumbnailURL returns the high quality thumbnail YouTube serves for a video
+func videoThumbnailURL(videoID string) string {
+	return fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", videoID)
+}
+
+// channelDisplay returns the channel's name and URL, falling back to its ID when the
+// notification did not carry an author
+func channelDisplay(entry *Entry) (string, string) {
+	channelURL := entry.Author.URI
+	if channelURL == "" {
+		channelURL = "https://www.youtube.com/channel/" + entry.ChannelID
+	}
+	if entry.Author.Name != "" {
+		return entry.Author.Name, channelURL
+	}
+	return entry.ChannelID, channelURL
+}
+
+// newDiscordMessage formats a video as a Discord message with an embed
+func newDiscordMessage(entry *Entry) discordMessage {
+	channelName, channelURL := channelDisplay(entry)
+	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.VideoID)
+	return discordMessage{
+		Content: fmt.Sprintf("New video from %s", channelName),
+		Embeds: []discordEmbed{{
+			Title:     entry.Title,
+			URL:       videoURL,
+			Color:     discordEmbedColor,
+			Timestamp: entry.Published,
+			Author:    discordEmbedName{Name: channelName, URL: channelURL},
+			Image:     discordEmbedImage{URL: videoThumbnailURL(entry.VideoID)},
+		}},
+	}
+}
+
+// slackEscape escapes the characters Slack treats as markup in text
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// newSlackMessage formats a video as a Slack message with a thumbnail
+func newSlackMessage(entry *Entry) slackMessage {
+	channelName, channelURL := channelDisplay(entry)
+	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.VideoID)
+	return slackMessage{
+		Text: fmt.Sprintf("New video from %s: %s %s", slackEscape(channelName), slackEscape(entry.Title), videoURL),
+		Blocks: []slackBlock{
+			{
+				Type: "section",
+				Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("*<%s|%s>*\nNew video from <%s|%s>",
+					videoURL, slackEscape(entry.Title), channelURL, slackEscape(channelName))},
+				Accessory: map[string]string{
+					"type":      "image",
+					"image_url": videoThumbnailURL(entry.VideoID),
+					"alt_text":  entry.Title,
+				},
+			},
+		},
+	}
+}
+
+// chatSinkBody returns the JSON body for a Discord or Slack sink
+func chatSinkBody(sinkType string, entry *Entry) ([]byte, error) {
+	if sinkType == SinkTypeDiscord {
+		return json.Marshal(newDiscordMessage(entry))
+	}
+	return json.Marshal(newSlackMessage(entry))
+}
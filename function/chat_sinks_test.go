@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chatSinkRecorder captures the bodies posted to a chat webhook
+type chatSinkRecorder struct {
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+}
+
+func (c *chatSinkRecorder) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		c.mu.Lock()
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestChatSinkMessages(t *testing.T) {
+	entry := newSinkTestEntry()
+	entry.Title = "Tips & <Tricks>"
+	entry.Author = Author{Name: "Sink Channel", URI: "https://www.youtube.com/channel/" + sinkChannelID}
+
+	t.Run("discord", func(t *testing.T) {
+		message := newDiscordMessage(entry)
+		assert.Equal(t, "New video from Sink Channel", message.Content)
+		require.Len(t, message.Embeds, 1)
+		assert.Equal(t, "Tips & <Tricks>", message.Embeds[0].Title)
+		assert.Equal(t, "https://www.youtube.com/watch?v=sink123", message.Embeds[0].URL)
+		assert.Equal(t, "https://i.ytimg.com/vi/sink123/hqdefault.jpg", message.Embeds[0].Image.URL)
+		assert.Equal(t, "Sink Channel", message.Embeds[0].Author.Name)
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		message := newSlackMessage(entry)
+		require.Len(t, message.Blocks, 1)
+		assert.Equal(t, "*<https://www.youtube.com/watch?v=sink123|Tips &amp; &lt;Tricks&gt;>*\nNew video from <https://www.youtube.com/channel/"+
+			sinkChannelID+"|Sink Channel>", message.Blocks[0].Text.Text)
+		assert.Equal(t, "https://i.ytimg.com/vi/sink123/hqdefault.jpg", message.Blocks[0].Accessory["image_url"])
+	})
+
+	t.Run("channel_id_without_author", func(t *testing.T) {
+		message := newDiscordMessage(newSinkTestEntry())
+		assert.Equal(t, "New video from "+sinkChannelID, message.Content)
+	})
+}
+
+func TestWebhookSink_Label(t *testing.T) {
+	assert.Equal(t, "https://example.com/hook", WebhookSink{URL: "https://example.com/hook"}.label())
+	assert.Equal(t, "discord:https://discord.com/api/webhooks/123/****",
+		WebhookSink{Type: SinkTypeDiscord, URL: "https://discord.com/api/webhooks/123/secret-token"}.label())
+	assert.Equal(t, "slack:https://hooks.slack.com/services/T000/B000/****",
+		WebhookSink{Type: SinkTypeSlack, URL: "https://hooks.slack.com/services/T000/B000/XXXX"}.label())
+}
+
+func TestWebhookSinkClient_DeliverAll_ChatSinks(t *testing.T) {
+	discord := &chatSinkRecorder{}
+	discordServer := httptest.NewServer(discord.handler(t))
+	defer discordServer.Close()
+	slack := &chatSinkRecorder{}
+	slackServer := httptest.NewServer(slack.handler(t))
+	defer slackServer.Close()
+
+	client := &WebhookSinkClient{Client: &http.Client{Timeout: 5 * time.Second}, MaxAttempts: 1}
+	results := client.DeliverAll(context.Background(), []WebhookSink{
+		{Type: SinkTypeDiscord, URL: discordServer.URL + "/api/webhooks/1/token"},
+		{Type: SinkTypeSlack, URL: slackServer.URL + "/services/T/B/token"},
+	}, newSinkTestEntry())
+
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Delivered)
+	assert.True(t, results[1].Delivered)
+	assert.NotContains(t, results[0].URL, "token", "webhook tokens are masked in results")
+
+	require.Len(t, discord.bodies, 1)
+	assert.Contains(t, discord.bodies[0], "embeds")
+	assert.NotContains(t, discord.bodies[0], "event_type")
+	require.Len(t, slack.bodies, 1)
+	assert.Contains(t, slack.bodies[0], "blocks")
+}
+
+func TestResolveSinks_GlobalChatSinks(t *testing.T) {
+	os.Setenv("DISCORD_WEBHOOK_URL", "https://discord.com/api/webhooks/1/token")
+	os.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/token")
+	defer func() {
+		os.Unsetenv("DISCORD_WEBHOOK_URL")
+		os.Unsetenv("SLACK_WEBHOOK_URL")
+	}()
+	ctx := context.Background()
+
+	t.Run("unconfigured_channel", func(t *testing.T) {
+		sinks := resolveSinks(ctx, NewMockStorageClient(), sinkChannelID)
+		require.Len(t, sinks, 2)
+		assert.Equal(t, SinkTypeDiscord, sinks[0].Type)
+		assert.Equal(t, SinkTypeSlack, sinks[1].Type)
+	})
+
+	t.Run("channel_sinks_come_first_without_duplicates", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{
+			ChannelID: sinkChannelID,
+			Sinks: []WebhookSink{
+				{URL: "https://example.com/hook"},
+				{Type: SinkTypeSlack, URL: "https://hooks.slack.com/services/T/B/token"},
+			},
+		}))
+
+		sinks := resolveSinks(ctx, storage, sinkChannelID)
+		require.Len(t, sinks, 3)
+		assert.Equal(t, "https://example.com/hook", sinks[0].URL)
+		assert.Equal(t, SinkTypeDiscord, sinks[2].Type)
+	})
+}
+
+func TestHandleNotification_DiscordSink(t *testing.T) {
+	discord := &chatSinkRecorder{}
+	server := httptest.NewServer(discord.handler(t))
+	defer server.Close()
+	os.Setenv("DISCORD_WEBHOOK_URL", server.URL+"/api/webhooks/1/token")
+	defer os.Unsetenv("DISCORD_WEBHOOK_URL")
+
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>sink123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Sink Video</title>
+    <author>
+      <name>Sink Channel</name>
+      <uri>https://www.youtube.com/channel/%s</uri>
+    </author>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, sinkChannelID, sinkChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, sinkChannelID)
+	deps.GitHubClient.(*MockGitHubClient).SetConfigured(false)
+
+	rec := httptest.NewRecorder()
+	handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "(webhook sinks: 1/1 delivered)")
+	require.Len(t, discord.bodies, 1)
+	assert.Equal(t, "New video from Sink Channel", discord.bodies[0]["content"])
+}
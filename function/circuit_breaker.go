@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states, as reported by CircuitBreaker.State and counted in
+// youtube_webhook_circuit_breaker_transitions_total
+const (
+	BreakerClosed   = "closed"    // Calls go through
+	BreakerOpen     = "open"      // Calls fail fast
+	BreakerHalfOpen = "half_open" // One trial call goes through to see if the service is back
+)
+
+// CircuitBreaker stops calls to a failing service. After enough consecutive failures
+// it opens, and calls fail at once with a BreakerOpenError instead of each waiting out
+// a timeout. Once the cooldown has passed one trial call is let through; its success
+// closes the breaker and its failure opens it again. Breakers are shared by every
+// request on an instance. A nil breaker lets every call through.
+type CircuitBreaker struct {
+	name        string           // The service, for logs and the breaker metric label
+	unavailable error            // What open-breaker errors wrap
+	isFailure   func(error) bool // Whether an error counts towards opening
+	metrics     *Metrics
+	clock       Clock // Times the cooldown; nil is the system clock
+
+	mu       sync.Mutex
+	failures int       // Consecutive failed calls
+	openedAt time.Time // Zero while closed
+	trial    bool      // A trial call is in flight
+
+	threshold int
+	cooldown  time.Duration
+}
+
+// BreakerOpenError is returned without calling the service while its breaker is open
+type BreakerOpenError struct {
+	Breaker    string        // The service
+	Failures   int           // Consecutive failures that opened the breaker
+	RetryAfter time.Duration // Until a trial call is let through
+	err        error
+}
+
+// Error implements the error interface
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("%v: %d calls in a row failed; retrying in %s", e.err, e.Failures, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap returns the service's unavailable error, such as ErrStorageUnavailable
+func (e *BreakerOpenError) Unwrap() error {
+	return e.err
+}
+
+// newCircuitBreaker creates a closed breaker that opens after failures consecutive
+// failures for cooldown. Returns nil, a disabled breaker, when failures is 0.
+func newCircuitBreaker(name string, unavailable error, failures int, cooldown time.Duration,
+	isFailure func(error) bool, metrics *Metrics) *CircuitBreaker {
+	if failures == 0 {
+		return nil
+	}
+	return &CircuitBreaker{
+		name:        name,
+		unavailable: unavailable,
+		isFailure:   isFailure,
+		metrics:     metrics,
+		threshold:   failures,
+		cooldown:    cooldown,
+	}
+}
+
+// State returns whether the breaker is closed, open or letting a trial call through
+func (b *CircuitBreaker) State() string {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero():
+		return BreakerClosed
+	case b.trial || b.now().Sub(b.openedAt) >= b.cooldown:
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+// now returns the time on the breaker's clock
+func (b *CircuitBreaker) now() time.Time {
+	if b.clock != nil {
+		return b.clock.Now()
+	}
+	return time.Now()
+}
+
+// Check returns a BreakerOpenError while calls would fail fast, without using up the
+// trial call, so callers can avoid starting work that needs the service
+func (b *CircuitBreaker) Check() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openError()
+}
+
+// openError returns the error for a call made now, or nil when it may go through
+func (b *CircuitBreaker) openError() error {
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	wait := b.cooldown - b.now().Sub(b.openedAt)
+	if wait <= 0 && !b.trial {
+		return nil
+	}
+	return &BreakerOpenError{Breaker: b.name, Failures: b.failures, RetryAfter: max(wait, 0), err: b.unavailable}
+}
+
+// allow reports whether a call may go through, claiming the trial call when the
+// cooldown has passed
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.openError(); err != nil {
+		return err
+	}
+	if !b.openedAt.IsZero() {
+		b.trial = true
+		b.transition(BreakerHalfOpen, nil)
+	}
+	return nil
+}
+
+// record counts the outcome of a call let through by allow
+func (b *CircuitBreaker) record(err error) {
+	failed := err != nil && b.isFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen, trial := !b.openedAt.IsZero(), b.trial
+	b.trial = false
+	if !failed {
+		if wasOpen {
+			b.transition(BreakerClosed, nil)
+		}
+		b.failures, b.openedAt = 0, time.Time{}
+		return
+	}
+
+	b.failures++
+	// A call that started before the breaker opened leaves the cooldown as it is
+	if trial || !wasOpen && b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.transition(BreakerOpen, err)
+	}
+}
+
+// transition logs and counts a change of state
+func (b *CircuitBreaker) transition(state string, err error) {
+	b.metrics.RecordBreakerTransition(b.name, state)
+	switch state {
+	case BreakerOpen:
+		fmt.Printf("Circuit breaker for %s opened after %d failed calls; calls fail fast for %s: %v\n",
+			b.name, b.failures, b.cooldown, err)
+	case BreakerHalfOpen:
+		fmt.Printf("Circuit breaker for %s is letting a trial call through\n", b.name)
+	case BreakerClosed:
+		fmt.Printf("Circuit breaker for %s closed: %s is reachable again\n", b.name, b.name)
+	}
+}
+
+// call runs fn through the breaker
+func (b *CircuitBreaker) call(fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// setRetryAfter sets Retry-After to when an open breaker lets calls through again,
+// reporting whether err came from one
+func setRetryAfter(w http.ResponseWriter, err error) bool {
+	var open *BreakerOpenError
+	if !errors.As(err, &open) {
+		return false
+	}
+	seconds := int((open.RetryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	return true
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Clock tells the time. Expiries, renewal thresholds, retention and newness checks all
+// read it, so a fixed clock makes them deterministic.
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator makes the IDs given to requests, and so to their audit entries, and to
+// dead letters
+type IDGenerator interface {
+	NewID() string
+}
+
+// systemClock is the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// randomIDs generates 16 hex digits of randomness per ID
+type randomIDs struct{}
+
+func (randomIDs) NewID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+// FrozenClock is a clock that only moves when it is set or advanced, for tests and for
+// processing a notification as of when it was received
+type FrozenClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFrozenClock creates a clock stopped at now
+func NewFrozenClock(now time.Time) *FrozenClock {
+	return &FrozenClock{now: now}
+}
+
+// Now returns the time the clock is stopped at
+func (c *FrozenClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set stops the clock at now
+func (c *FrozenClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock on by d
+func (c *FrozenClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// OverrideClock has requests tell the time with clock
+func OverrideClock(clock Clock) Override {
+	return func(d *Dependencies) { d.Clock = clock }
+}
+
+// clock returns the injected clock, or the system clock
+func (d *Dependencies) clock() Clock {
+	if d.Clock != nil {
+		return d.Clock
+	}
+	return systemClock{}
+}
+
+// now returns the current time on the dependencies' clock
+func (d *Dependencies) now() time.Time {
+	return d.clock().Now()
+}
+
+// ids returns the injected ID generator, or random IDs
+func (d *Dependencies) ids() IDGenerator {
+	if d.IDs != nil {
+		return d.IDs
+	}
+	return randomIDs{}
+}
+
+// clockKey and idsKey are the context keys for a request's clock and ID generator
+type (
+	clockKey struct{}
+	idsKey   struct{}
+)
+
+// withClock returns ctx carrying the dependencies' clock and ID generator, for the code
+// a request reaches that has its context but not the dependencies
+func (d *Dependencies) withClock(ctx context.Context) context.Context {
+	return context.WithValue(context.WithValue(ctx, clockKey{}, d.clock()), idsKey{}, d.ids())
+}
+
+// currentTime returns the time on the clock ctx carries, or the system time
+func currentTime(ctx context.Context) time.Time {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
+// newID returns an ID from the generator ctx carries, or a random one
+func newID(ctx context.Context) string {
+	if ids, ok := ctx.Value(idsKey{}).(IDGenerator); ok {
+		return ids.NewID()
+	}
+	return randomIDs{}.NewID()
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequentialIDs generates id-1, id-2, ...
+type sequentialIDs struct{ next int }
+
+func (s *sequentialIDs) NewID() string {
+	s.next++
+	return fmt.Sprintf("id-%d", s.next)
+}
+
+func TestFrozenClock(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewFrozenClock(start)
+	assert.Equal(t, start, clock.Now())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+
+	clock.Set(start)
+	assert.Equal(t, start, clock.Now())
+}
+
+func TestCurrentTime(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	deps := CreateTestDependencies()
+	deps.Clock = NewFrozenClock(now)
+	deps.IDs = &sequentialIDs{}
+
+	ctx := deps.withClock(context.Background())
+	assert.Equal(t, now, currentTime(ctx))
+	assert.Equal(t, "id-1", newID(ctx))
+	assert.Equal(t, "20260102T030405Z-id-2", newDeadLetterID(ctx, now))
+
+	assert.WithinDuration(t, time.Now(), currentTime(context.Background()), time.Minute)
+	assert.Len(t, newID(context.Background()), 16)
+}
+
+func TestYouTubeWebhook_InjectedClock(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	deps := newVerificationTestDeps()
+	deps.Clock = NewFrozenClock(now)
+	deps.IDs = &sequentialIDs{}
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, verificationRequest("challenge", "subscribe", verificationTestChannel, "3600"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "id-1", rec.Header().Get(RequestIDHeader), "request IDs come from the generator")
+
+	sub, err := deps.StorageClient.GetSubscription(context.Background(), verificationTestChannel)
+	require.NoError(t, err)
+	assert.Equal(t, now, sub.VerifiedAt)
+	assert.Equal(t, now.Add(time.Hour), sub.ExpiresAt, "the lease runs from the injected clock")
+
+	t.Run("renewal_threshold", func(t *testing.T) {
+		renew := func() RenewalSummaryResponse {
+			rec := httptest.NewRecorder()
+			YouTubeWebhook(rec, httptest.NewRequest("POST", "/renew", nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			var response RenewalSummaryResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			return response
+		}
+
+		deps.Clock = NewFrozenClock(now.Add(-24 * time.Hour))
+		assert.Zero(t, renew().RenewalsCandidates, "a day before expiry is outside the threshold")
+
+		deps.Clock = NewFrozenClock(now)
+		assert.Equal(t, 1, renew().RenewalsCandidates, "an hour before expiry is within it")
+	})
+}
+
+func TestHandleReplayDeadLetter_AsReceived(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	gh := deps.GitHubClient.(*MockGitHubClient)
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	// A notification for a video published just before it arrived, three days ago
+	receivedAt := time.Now().Add(-72 * time.Hour)
+	published := receivedAt.Add(-5 * time.Minute).Format(time.RFC3339)
+	deadLetter := newTestDeadLetter(receivedAt, fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>late123</yt:videoId>
+    <yt:channelId>UCXuqSBlHAE6Xw-yeJA0Tunw</yt:channelId>
+    <title>Late Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, published, published))
+	store := NewDeadLetterStore(deps.StorageClient, envConfig())
+	require.NoError(t, store.Add(context.Background(), deadLetter))
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/deadletters/"+deadLetter.ID+"/replay", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Not a new video", "judged now, the video is too old")
+	assert.Zero(t, gh.GetTriggerCallCount())
+
+	require.NoError(t, store.Add(context.Background(), deadLetter))
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/deadletters/"+deadLetter.ID+"/replay?as_received=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Successfully triggered workflow for new video: late123")
+	assert.Equal(t, 1, gh.GetTriggerCallCount())
+}
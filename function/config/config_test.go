@@ -0,0 +1,138 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a getenv reading from values
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+// valid is a complete Cloud Storage deployment
+func valid() map[string]string {
+	return map[string]string{
+		"FUNCTION_URL":        "https://example.com/webhook",
+		"GITHUB_TOKEN":        "ghp_secret",
+		"REPO_OWNER":          "owner",
+		"REPO_NAME":           "repo",
+		"SUBSCRIPTION_BUCKET": "bucket",
+	}
+}
+
+// find returns the named setting from the report
+func find(t *testing.T, report *Report, name string) Value {
+	for _, value := range report.Settings {
+		if value.Name == name {
+			return value
+		}
+	}
+	t.Fatalf("%s is not in the report", name)
+	return Value{}
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		report := Check(env(valid()))
+		assert.True(t, report.Valid)
+		assert.Empty(t, report.Problems())
+		assert.Len(t, report.Settings, len(Settings))
+		assert.Equal(t, "Configuration OK", report.String())
+		assert.Equal(t, "https://example.com/webhook", find(t, report, "FUNCTION_URL").Value)
+		assert.Equal(t, "gcs", find(t, report, "STORAGE_BACKEND").Default)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		report := Check(env(map[string]string{}))
+		assert.False(t, report.Valid)
+
+		var names []string
+		for _, value := range report.Problems() {
+			assert.Equal(t, "required but not set", value.Problem)
+			names = append(names, value.Name)
+		}
+		assert.Equal(t, []string{"FUNCTION_URL", "GITHUB_TOKEN", "REPO_OWNER", "REPO_NAME", "SUBSCRIPTION_BUCKET"}, names)
+	})
+
+	t.Run("firestore_needs_no_bucket", func(t *testing.T) {
+		values := valid()
+		delete(values, "SUBSCRIPTION_BUCKET")
+		values["STORAGE_BACKEND"] = "firestore"
+		assert.True(t, Check(env(values)).Valid)
+	})
+
+	t.Run("premiere_handling_needs_api_key", func(t *testing.T) {
+		values := valid()
+		values["PREMIERE_HANDLING"] = "delay"
+		report := Check(env(values))
+		assert.False(t, report.Valid)
+		assert.Equal(t, "required but not set", find(t, report, "YOUTUBE_API_KEY").Problem)
+
+		values["YOUTUBE_API_KEY"] = "api-key"
+		assert.True(t, Check(env(values)).Valid)
+	})
+
+	t.Run("secrets_are_redacted", func(t *testing.T) {
+		values := valid()
+		values["SLACK_WEBHOOK_URL"] = "not a url"
+		report := Check(env(values))
+
+		assert.Equal(t, "[redacted]", find(t, report, "GITHUB_TOKEN").Value)
+		assert.Equal(t, "[redacted]", find(t, report, "SLACK_WEBHOOK_URL").Value)
+		assert.Empty(t, find(t, report, "ADMIN_TOKEN").Value)
+		assert.NotContains(t, report.String(), "ghp_secret")
+		assert.NotContains(t, report.String(), "not a url")
+	})
+}
+
+func TestCheck_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		problem string
+	}{
+		{"FUNCTION_URL", "example.com/webhook", "must be an absolute http or https URL"},
+		{"FUNCTION_URL", "ftp://example.com", "must be an absolute http or https URL"},
+		{"STORAGE_BACKEND", "s3", "must be one of gcs, firestore"},
+		{"NEW_VIDEO_STRATEGY", "newest", "must be one of window, first_seen, updated, accept_all"},
+		{"RENEWAL_BATCH_SIZE", "0", "must be greater than zero"},
+		{"RENEWAL_BATCH_SIZE", "ten", "must be a whole number"},
+		{"NOTIFICATION_HISTORY_SIZE", "-1", "must be a whole number"},
+		{"HUB_REQUEST_TIMEOUT_SECONDS", "0", "must be greater than zero"},
+		{"RENEWAL_THRESHOLD_HOURS", "soon", "must be a number"},
+		{"REQUIRE_HUB_SIGNATURE", "yes", `must be "true" or "false"`},
+		{"NOTIFICATION_ALLOWED_IPS", "10.0.0.0/8, example.com", "must be IP addresses or CIDR ranges, separated by commas"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name+"="+tc.value, func(t *testing.T) {
+			values := valid()
+			values[tc.name] = tc.value
+			report := Check(env(values))
+
+			assert.False(t, report.Valid)
+			require.Len(t, report.Problems(), 1)
+			assert.Equal(t, tc.problem, find(t, report, tc.name).Problem)
+			assert.True(t, strings.HasPrefix(report.String(), "Configuration has 1 problem(s):"))
+			assert.Contains(t, report.String(), tc.name+": "+tc.problem)
+		})
+	}
+
+	for name, value := range map[string]string{
+		"NOTIFICATION_HISTORY_SIZE":   "0",
+		"SWEEP_GRACE_HOURS":           "0",
+		"RENEWAL_JITTER_MS":           "0",
+		"HUB_REQUEST_TIMEOUT_SECONDS": "2.5",
+		"STORAGE_BACKEND":             "gcs",
+		"REQUIRE_HUB_SIGNATURE":       "true",
+		"NOTIFICATION_DENIED_IPS":     "203.0.113.7, 10.0.0.0/8,2001:db8::/32",
+	} {
+		values := valid()
+		values[name] = value
+		assert.True(t, Check(env(values)).Valid, "%s=%s", name, value)
+	}
+}
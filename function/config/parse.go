@@ -0,0 +1,217 @@
+package config
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the settings in Config
+const (
+	DefaultLeaseSeconds       = 86400
+	DefaultRenewalThreshold   = 12 * time.Hour
+	DefaultMaxRenewalAttempts = 3
+	DefaultRenewalConcurrency = 4
+	DefaultSweepGracePeriod   = 24 * time.Hour
+	DefaultSweepRetention     = 7 * 24 * time.Hour
+
+	DefaultRecoveryMaxAttempts = 5
+	DefaultRemovedRetention    = 30 * 24 * time.Hour
+	DefaultExpiryAlertWindow   = 24 * time.Hour
+	DefaultHealthStaleAfter    = 7 * 24 * time.Hour
+	DefaultHealthBrokenAfter   = 30 * 24 * time.Hour
+
+	DefaultNotificationMaxBodyBytes = 1 << 20
+	DefaultProcessedVideoTTL        = 24 * time.Hour
+	DefaultNotificationHistorySize  = 100
+	DefaultDeadLetterRetention      = 14 * 24 * time.Hour
+	DefaultDispatchRetryMaxAttempts = 5
+	DefaultOutboxStaleAfter         = 10 * time.Minute
+	DefaultOutboxMaxAttempts        = 5
+	DefaultSinkMaxAttempts          = 3
+
+	DefaultAuditRetention         = 30 * 24 * time.Hour
+	DefaultIdempotencyTTL         = time.Hour
+	DefaultStateBackupCount       = 48
+	DefaultStateBackupRetention   = 30 * 24 * time.Hour
+	DefaultDebugNotificationsSize = 25
+)
+
+// day is the unit of the settings counted in days
+const day = 24 * time.Hour
+
+// Config is the typed configuration of the hub callback, the dispatch repository, the
+// subscription lifecycle, notification handling and the management stores. Unset and
+// invalid values take their defaults.
+type Config struct {
+	FunctionURL       string // Callback URL registered with the hub
+	CallbackTokenMode string // off, on or required
+	RepoOwner         string // Repository dispatched to for channels without routes
+	RepoName          string
+
+	LeaseSeconds        int           // Lease requested from the hub
+	RenewalThreshold    time.Duration // How long before expiry subscriptions are renewed
+	MaxRenewalAttempts  int           // Failed renewals before a subscription is quarantined
+	RenewalBatchSize    int           // Subscriptions renewed per run; 0 renews every candidate
+	RenewalConcurrency  int           // Renewals run at once
+	RenewalJitter       time.Duration // Largest random delay before each renewal after the first
+	SweepGracePeriod    time.Duration // How long after expiry the sweeper still re-subscribes
+	SweepRetention      time.Duration // How long lapsed subscriptions are kept
+	RecoveryMaxAttempts int           // Re-subscribes tried for a subscription the hub stopped delivering; 0 quarantines it
+	RemovedRetention    time.Duration // How long unsubscribed channels can be restored; 0 deletes them on unsubscribe
+	ExpiryAlertWindow   time.Duration // How long before expiry unrenewable subscriptions are alerted about; 0 disables alerts
+	HealthStaleAfter    time.Duration // Quiet time before a subscription is stale
+	HealthBrokenAfter   time.Duration // Quiet time before a subscription is broken
+
+	NotificationMaxBodyBytes    int64          // Largest notification body accepted
+	RequireHubSignature         bool           // Reject unsigned notifications for channels without a secret
+	RequireSubscribedTopic      bool           // Reject notifications whose topic is not a subscription
+	NotificationAllowedIPs      []netip.Prefix // Sources notifications are accepted from, when RestrictNotificationSources
+	RestrictNotificationSources bool           // NOTIFICATION_ALLOWED_IPS is set, so other sources are refused
+	NotificationDeniedIPs       []netip.Prefix // Sources notifications are refused from
+	TrustForwardedFor           bool           // Take the source from the last X-Forwarded-For address
+	ProcessedVideoTTL           time.Duration  // How long processed videos are remembered; 0 disables deduplication
+	NotificationHistorySize     int            // Notifications kept per channel; 0 disables the history
+	DeadLetterRetention         time.Duration  // How long dead letters are kept; 0 disables dead-lettering
+	PremiereHandling            string         // off, track or delay
+	DispatchRetryMaxAttempts    int            // Attempts before a queued dispatch is dropped
+	OutboxStaleAfter            time.Duration  // How long a delivery stays in the outbox before it is made again
+	OutboxMaxAttempts           int            // Deliveries of an outbox entry before it is abandoned
+	SinkMaxAttempts             int            // Attempts of each webhook sink delivery
+	RunCorrelationTimeout       time.Duration  // How long to look for the workflow run a dispatch started; 0 disables it
+
+	AuditRetention            time.Duration // How long audit log entries are kept; 0 disables the audit log
+	IdempotencyTTL            time.Duration // How long Idempotency-Key results are kept; 0 disables idempotency keys
+	StateBackupCount          int           // Subscription state backups kept; 0 disables backups
+	StateBackupRetention      time.Duration // How long subscription state backups are kept
+	DebugNotificationsSize    int           // Raw notifications kept for /debug/notifications
+	DebugNotificationsPersist bool          // Keep the debug buffer in storage
+}
+
+// FromEnv parses the configuration from the process environment
+func FromEnv() (*Config, error) {
+	return Parse(os.Getenv)
+}
+
+// Parse parses the configuration from getenv. The configuration is always usable; the
+// error lists the settings that are missing or invalid, as Check reports them.
+func Parse(getenv func(string) string) (*Config, error) {
+	allowedIPs := getenv("NOTIFICATION_ALLOWED_IPS")
+	cfg := &Config{
+		FunctionURL:       getenv("FUNCTION_URL"),
+		CallbackTokenMode: parseChoice(getenv("CALLBACK_PATH_TOKEN"), []string{"off", "on", "required"}, "off"),
+		RepoOwner:         getenv("REPO_OWNER"),
+		RepoName:          getenv("REPO_NAME"),
+
+		LeaseSeconds:        parseInt(getenv("SUBSCRIPTION_LEASE_SECONDS"), 1, DefaultLeaseSeconds),
+		RenewalThreshold:    parseDuration(getenv("RENEWAL_THRESHOLD_HOURS"), time.Hour, DefaultRenewalThreshold),
+		MaxRenewalAttempts:  parseInt(getenv("MAX_RENEWAL_ATTEMPTS"), 1, DefaultMaxRenewalAttempts),
+		RenewalBatchSize:    parseInt(getenv("RENEWAL_BATCH_SIZE"), 1, 0),
+		RenewalConcurrency:  parseInt(getenv("RENEWAL_CONCURRENCY"), 1, DefaultRenewalConcurrency),
+		RenewalJitter:       parseUnits(getenv("RENEWAL_JITTER_MS"), 0, time.Millisecond, 0),
+		SweepGracePeriod:    parseDuration(getenv("SWEEP_GRACE_HOURS"), time.Hour, DefaultSweepGracePeriod),
+		SweepRetention:      parseUnits(getenv("SWEEP_RETENTION_DAYS"), 1, day, DefaultSweepRetention),
+		RecoveryMaxAttempts: parseInt(getenv("RECOVERY_MAX_ATTEMPTS"), 0, DefaultRecoveryMaxAttempts),
+		RemovedRetention:    parseUnits(getenv("REMOVED_RETENTION_DAYS"), 0, day, DefaultRemovedRetention),
+		ExpiryAlertWindow:   parseUnits(getenv("EXPIRY_ALERT_HOURS"), 0, time.Hour, DefaultExpiryAlertWindow),
+		HealthStaleAfter:    parsePositiveDuration(getenv("HEALTH_STALE_AFTER_HOURS"), time.Hour, DefaultHealthStaleAfter),
+		HealthBrokenAfter:   parsePositiveDuration(getenv("HEALTH_BROKEN_AFTER_HOURS"), time.Hour, DefaultHealthBrokenAfter),
+
+		NotificationMaxBodyBytes:    int64(parseInt(getenv("NOTIFICATION_MAX_BODY_BYTES"), 1, DefaultNotificationMaxBodyBytes)),
+		RequireHubSignature:         getenv("REQUIRE_HUB_SIGNATURE") == "true",
+		RequireSubscribedTopic:      getenv("REQUIRE_SUBSCRIBED_TOPIC") == "true",
+		NotificationAllowedIPs:      parseAddresses(allowedIPs),
+		RestrictNotificationSources: strings.TrimSpace(allowedIPs) != "",
+		NotificationDeniedIPs:       parseAddresses(getenv("NOTIFICATION_DENIED_IPS")),
+		TrustForwardedFor:           getenv("TRUST_FORWARDED_FOR") == "true",
+		ProcessedVideoTTL:           parseUnits(getenv("PROCESSED_VIDEO_TTL_HOURS"), 0, time.Hour, DefaultProcessedVideoTTL),
+		NotificationHistorySize:     parseInt(getenv("NOTIFICATION_HISTORY_SIZE"), 0, DefaultNotificationHistorySize),
+		DeadLetterRetention:         parseUnits(getenv("DEAD_LETTER_RETENTION_DAYS"), 0, day, DefaultDeadLetterRetention),
+		PremiereHandling:            parseChoice(getenv("PREMIERE_HANDLING"), []string{"off", "track", "delay"}, "off"),
+		DispatchRetryMaxAttempts:    parseInt(getenv("DISPATCH_RETRY_MAX_ATTEMPTS"), 1, DefaultDispatchRetryMaxAttempts),
+		OutboxStaleAfter:            parseUnits(getenv("OUTBOX_STALE_MINUTES"), 1, time.Minute, DefaultOutboxStaleAfter),
+		OutboxMaxAttempts:           parseInt(getenv("OUTBOX_MAX_ATTEMPTS"), 1, DefaultOutboxMaxAttempts),
+		SinkMaxAttempts:             parseInt(getenv("SINK_MAX_ATTEMPTS"), 1, DefaultSinkMaxAttempts),
+		RunCorrelationTimeout:       parseUnits(getenv("GITHUB_RUN_CORRELATION_SECONDS"), 1, time.Second, 0),
+
+		AuditRetention:            parseUnits(getenv("AUDIT_RETENTION_DAYS"), 0, day, DefaultAuditRetention),
+		IdempotencyTTL:            parseUnits(getenv("IDEMPOTENCY_TTL_MINUTES"), 0, time.Minute, DefaultIdempotencyTTL),
+		StateBackupCount:          parseInt(getenv("STATE_BACKUP_COUNT"), 0, DefaultStateBackupCount),
+		StateBackupRetention:      parseUnits(getenv("STATE_BACKUP_RETENTION_DAYS"), 1, day, DefaultStateBackupRetention),
+		DebugNotificationsSize:    parseInt(getenv("DEBUG_NOTIFICATIONS_SIZE"), 0, DefaultDebugNotificationsSize),
+		DebugNotificationsPersist: getenv("DEBUG_NOTIFICATIONS_PERSIST") == "true",
+	}
+
+	if report := Check(getenv); !report.Valid {
+		return cfg, errors.New(report.String())
+	}
+	return cfg, nil
+}
+
+// parseInt parses a whole number of at least min, or returns the default
+func parseInt(value string, min, defaultValue int) int {
+	if n, err := strconv.Atoi(value); err == nil && n >= min {
+		return n
+	}
+	return defaultValue
+}
+
+// parseUnits parses a whole number of at least min units, or returns the default
+func parseUnits(value string, min int, unit, defaultValue time.Duration) time.Duration {
+	if n, err := strconv.Atoi(value); err == nil && n >= min {
+		return time.Duration(n) * unit
+	}
+	return defaultValue
+}
+
+// parseDuration parses a non-negative number of units, fractions included, or returns
+// the default
+func parseDuration(value string, unit, defaultValue time.Duration) time.Duration {
+	if n, err := strconv.ParseFloat(value, 64); err == nil && n >= 0 {
+		return time.Duration(n * float64(unit))
+	}
+	return defaultValue
+}
+
+// parsePositiveDuration parses a number of units greater than zero, fractions included,
+// or returns the default
+func parsePositiveDuration(value string, unit, defaultValue time.Duration) time.Duration {
+	if n, err := strconv.ParseFloat(value, 64); err == nil && n > 0 {
+		return time.Duration(n * float64(unit))
+	}
+	return defaultValue
+}
+
+// parseChoice returns value when it is one of choices, or the default
+func parseChoice(value string, choices []string, defaultValue string) string {
+	if slices.Contains(choices, value) {
+		return value
+	}
+	return defaultValue
+}
+
+// parseAddresses parses a comma-separated list of IP addresses and CIDR ranges; an
+// address is a range of its own. Entries that are neither are skipped, as Check
+// reports them.
+func parseAddresses(value string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(item); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := Parse(env(valid()))
+		require.NoError(t, err)
+		assert.Equal(t, &Config{
+			FunctionURL:        "https://example.com/webhook",
+			RepoOwner:          "owner",
+			RepoName:           "repo",
+			LeaseSeconds:       DefaultLeaseSeconds,
+			RenewalThreshold:   DefaultRenewalThreshold,
+			MaxRenewalAttempts: DefaultMaxRenewalAttempts,
+			RenewalConcurrency: DefaultRenewalConcurrency,
+			SweepGracePeriod:   DefaultSweepGracePeriod,
+			SweepRetention:     DefaultSweepRetention,
+			CallbackTokenMode:  "off",
+
+			RecoveryMaxAttempts:      DefaultRecoveryMaxAttempts,
+			RemovedRetention:         DefaultRemovedRetention,
+			ExpiryAlertWindow:        DefaultExpiryAlertWindow,
+			HealthStaleAfter:         DefaultHealthStaleAfter,
+			HealthBrokenAfter:        DefaultHealthBrokenAfter,
+			NotificationMaxBodyBytes: DefaultNotificationMaxBodyBytes,
+			ProcessedVideoTTL:        DefaultProcessedVideoTTL,
+			NotificationHistorySize:  DefaultNotificationHistorySize,
+			DeadLetterRetention:      DefaultDeadLetterRetention,
+			PremiereHandling:         "off",
+			DispatchRetryMaxAttempts: DefaultDispatchRetryMaxAttempts,
+			OutboxStaleAfter:         DefaultOutboxStaleAfter,
+			OutboxMaxAttempts:        DefaultOutboxMaxAttempts,
+			SinkMaxAttempts:          DefaultSinkMaxAttempts,
+			AuditRetention:           DefaultAuditRetention,
+			IdempotencyTTL:           DefaultIdempotencyTTL,
+			StateBackupCount:         DefaultStateBackupCount,
+			StateBackupRetention:     DefaultStateBackupRetention,
+			DebugNotificationsSize:   DefaultDebugNotificationsSize,
+		}, cfg)
+	})
+
+	t.Run("values", func(t *testing.T) {
+		values := valid()
+		values["SUBSCRIPTION_LEASE_SECONDS"] = "43200"
+		values["RENEWAL_THRESHOLD_HOURS"] = "1.5"
+		values["MAX_RENEWAL_ATTEMPTS"] = "5"
+		values["RENEWAL_BATCH_SIZE"] = "10"
+		values["RENEWAL_CONCURRENCY"] = "8"
+		values["RENEWAL_JITTER_MS"] = "250"
+		values["SWEEP_GRACE_HOURS"] = "0"
+		values["SWEEP_RETENTION_DAYS"] = "30"
+
+		cfg, err := Parse(env(values))
+		require.NoError(t, err)
+		assert.Equal(t, 43200, cfg.LeaseSeconds)
+		assert.Equal(t, 90*time.Minute, cfg.RenewalThreshold)
+		assert.Equal(t, 5, cfg.MaxRenewalAttempts)
+		assert.Equal(t, 10, cfg.RenewalBatchSize)
+		assert.Equal(t, 8, cfg.RenewalConcurrency)
+		assert.Equal(t, 250*time.Millisecond, cfg.RenewalJitter)
+		assert.Equal(t, time.Duration(0), cfg.SweepGracePeriod)
+		assert.Equal(t, 30*24*time.Hour, cfg.SweepRetention)
+	})
+
+	t.Run("notification_values", func(t *testing.T) {
+		values := valid()
+		values["NOTIFICATION_ALLOWED_IPS"] = "203.0.113.0/24, 2001:db8::1"
+		values["NOTIFICATION_DENIED_IPS"] = "203.0.113.66"
+		values["REQUIRE_HUB_SIGNATURE"] = "true"
+		values["PROCESSED_VIDEO_TTL_HOURS"] = "0"
+		values["OUTBOX_STALE_MINUTES"] = "15"
+		values["PREMIERE_HANDLING"] = "delay"
+		values["YOUTUBE_API_KEY"] = "key"
+
+		cfg, err := Parse(env(values))
+		require.NoError(t, err)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24"), netip.MustParsePrefix("2001:db8::1/128")}, cfg.NotificationAllowedIPs)
+		assert.True(t, cfg.RestrictNotificationSources)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("203.0.113.66/32")}, cfg.NotificationDeniedIPs)
+		assert.True(t, cfg.RequireHubSignature)
+		assert.Equal(t, time.Duration(0), cfg.ProcessedVideoTTL, "0 disables deduplication")
+		assert.Equal(t, 15*time.Minute, cfg.OutboxStaleAfter)
+		assert.Equal(t, "delay", cfg.PremiereHandling)
+	})
+
+	t.Run("invalid_values_take_defaults", func(t *testing.T) {
+		values := valid()
+		values["MAX_RENEWAL_ATTEMPTS"] = "0"
+		values["RENEWAL_CONCURRENCY"] = "many"
+		values["SWEEP_GRACE_HOURS"] = "-1"
+		values["NOTIFICATION_ALLOWED_IPS"] = "not-an-address"
+		values["PREMIERE_HANDLING"] = "sometimes"
+		delete(values, "FUNCTION_URL")
+
+		cfg, err := Parse(env(values))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "FUNCTION_URL: required but not set")
+		assert.Contains(t, err.Error(), "MAX_RENEWAL_ATTEMPTS: must be greater than zero")
+		assert.Equal(t, DefaultMaxRenewalAttempts, cfg.MaxRenewalAttempts)
+		assert.Equal(t, DefaultRenewalConcurrency, cfg.RenewalConcurrency)
+		assert.Equal(t, DefaultSweepGracePeriod, cfg.SweepGracePeriod)
+		assert.Empty(t, cfg.NotificationAllowedIPs)
+		assert.True(t, cfg.RestrictNotificationSources, "an allow list with no valid entry refuses everything")
+		assert.Equal(t, "off", cfg.PremiereHandling)
+		assert.Empty(t, cfg.FunctionURL)
+	})
+}
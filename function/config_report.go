@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// logConfigReport logs the settings that are missing or invalid, once per instance at
+// cold start, to stderr
+func logConfigReport() {
+	if report := config.Load(); !report.Valid {
+		fmt.Fprintln(os.Stderr, report)
+	}
+}
+
+// handleGetConfig handles GET /config requests: every setting the function reads, with
+// secrets redacted, and what is wrong with the missing or invalid ones
+func handleGetConfig(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, http.StatusOK, config.Load())
+	}
+}
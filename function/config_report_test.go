@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetConfig(t *testing.T) {
+	SetDependencies(CreateTestDependencies())
+	defer SetDependencies(nil)
+
+	t.Setenv("FUNCTION_URL", "https://example.com/webhook")
+	t.Setenv("GITHUB_TOKEN", "ghp_secret")
+	t.Setenv("REPO_OWNER", "owner")
+	t.Setenv("REPO_NAME", "")
+	t.Setenv("SUBSCRIPTION_BUCKET", "bucket")
+	t.Setenv("API_KEYS", "")
+	t.Setenv("OIDC_AUDIENCE", "")
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/config", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "ghp_secret")
+
+	var report config.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.False(t, report.Valid)
+	require.Len(t, report.Problems(), 1)
+	assert.Equal(t, "REPO_NAME", report.Problems()[0].Name)
+
+	t.Run("requires_credentials", func(t *testing.T) {
+		t.Setenv("API_KEYS", "key1")
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/config", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds closing the dependencies when the instance shuts down;
+// Cloud Functions allows 10 seconds after SIGTERM
+const shutdownTimeout = 8 * time.Second
+
+// Container owns a function instance's dependencies. It creates them on first use,
+// shares them between requests and closes them when the instance shuts down.
+//
+// The dependencies are created together rather than each on first use: none of the
+// constructors makes a network call, every request needs the storage client and
+// configuration anyway, and the clients share the metrics, HTTP clients and breakers
+// created alongside them.
+type Container struct {
+	create func() *Dependencies
+
+	mu   sync.Mutex
+	deps *Dependencies
+}
+
+// NewContainer creates a container whose dependencies are made by create on first use
+func NewContainer(create func() *Dependencies) *Container {
+	return &Container{create: create}
+}
+
+// Dependencies returns the container's dependencies, creating them on the first call
+func (c *Container) Dependencies() *Dependencies {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deps == nil {
+		c.deps = c.create()
+	}
+	return c.deps
+}
+
+// Set replaces the container's dependencies without closing the previous ones. Setting
+// nil has the next Dependencies call create them again.
+func (c *Container) Set(deps *Dependencies) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deps = deps
+}
+
+// Close closes the dependencies created or set so far, if any, and forgets them. A
+// later Dependencies call creates new ones.
+func (c *Container) Close(ctx context.Context) error {
+	c.mu.Lock()
+	deps := c.deps
+	c.deps = nil
+	c.mu.Unlock()
+	if deps == nil {
+		return nil
+	}
+	return deps.Close(ctx)
+}
+
+// Close releases what the dependencies hold: the storage backend's client, the event
+// publisher, open event streams, and spans the tracer has not exported yet. Other
+// clients only hold HTTP connections, which need no cleanup. Errors are joined.
+func (d *Dependencies) Close(ctx context.Context) error {
+	var errs []error
+	if d.StorageClient != nil {
+		if err := d.StorageClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing storage: %w", err))
+		}
+	}
+	if closer, ok := d.EventPublisher.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing event publisher: %w", err))
+		}
+	}
+	d.Broadcaster.Close()
+	if tracer, ok := d.Tracer.(interface{ Shutdown(context.Context) error }); ok {
+		if err := tracer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flushing traces: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Override replaces some of the dependencies a request sees
+type Override func(*Dependencies)
+
+// OverrideStorage has requests use storage
+func OverrideStorage(storage StorageService) Override {
+	return func(d *Dependencies) { d.StorageClient = storage }
+}
+
+// OverridePubSub has requests use client for hub requests
+func OverridePubSub(client PubSubClient) Override {
+	return func(d *Dependencies) { d.PubSubClient = client }
+}
+
+// OverrideGitHub has requests use client for GitHub
+func OverrideGitHub(client GitHubClientInterface) Override {
+	return func(d *Dependencies) { d.GitHubClient = client }
+}
+
+// OverrideEventPublisher has requests publish video events with publisher
+func OverrideEventPublisher(publisher EventPublisher) Override {
+	return func(d *Dependencies) { d.EventPublisher = publisher }
+}
+
+// WithOverrides returns a copy of the dependencies with overrides applied, leaving d and
+// the requests using it unchanged. Without overrides d itself is returned.
+func (d *Dependencies) WithOverrides(overrides ...Override) *Dependencies {
+	if len(overrides) == 0 {
+		return d
+	}
+	overridden := *d
+	for _, override := range overrides {
+		override(&overridden)
+	}
+	return &overridden
+}
+
+// overridesKey is the context key for a request's dependency overrides
+type overridesKey struct{}
+
+// WithRequestOverrides returns a context whose requests, served by YouTubeWebhook, see
+// the dependencies with overrides applied on top of any ctx already carries
+func WithRequestOverrides(ctx context.Context, overrides ...Override) context.Context {
+	existing := requestOverrides(ctx)
+	combined := append(existing[:len(existing):len(existing)], overrides...)
+	return context.WithValue(ctx, overridesKey{}, combined)
+}
+
+// requestOverrides returns the overrides the request's context carries
+func requestOverrides(ctx context.Context) []Override {
+	overrides, _ := ctx.Value(overridesKey{}).([]Override)
+	return overrides
+}
+
+// defaultContainer holds the dependencies YouTubeWebhook serves requests with
+var defaultContainer = NewContainer(CreateProductionDependencies)
+
+// GetDependencies returns the instance's dependencies, creating production
+// dependencies on first use.
+func GetDependencies() *Dependencies {
+	return defaultContainer.Dependencies()
+}
+
+// SetDependencies sets the instance's dependencies (primarily for testing); nil has the
+// next request create production dependencies again.
+func SetDependencies(deps *Dependencies) {
+	defaultContainer.Set(deps)
+}
+
+// CloseDependencies closes the instance's dependencies. Call it when the function
+// shuts down outside Cloud Functions, where SIGTERM is not what ends the process.
+func CloseDependencies(ctx context.Context) error {
+	return defaultContainer.Close(ctx)
+}
+
+// closeOnShutdown closes the instance's dependencies when the platform sends SIGTERM,
+// then lets the signal end the process as it would have. Only the function's first
+// request installs it, so programs importing this package keep their own signal
+// handling.
+func closeOnShutdown() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := CloseDependencies(ctx); err != nil {
+			fmt.Printf("Error closing dependencies on shutdown: %v\n", err)
+		}
+		cancel()
+
+		signal.Stop(signals)
+		if process, err := os.FindProcess(os.Getpid()); err == nil {
+			process.Signal(sig)
+		}
+	}()
+}
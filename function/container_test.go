@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closingStorage is mock storage that counts Close calls and fails them with err
+type closingStorage struct {
+	*MockStorageClient
+	closed int
+	err    error
+}
+
+func (s *closingStorage) Close() error {
+	s.closed++
+	return s.err
+}
+
+func TestContainer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creates_once", func(t *testing.T) {
+		created := 0
+		container := NewContainer(func() *Dependencies {
+			created++
+			return CreateTestDependencies()
+		})
+		assert.Zero(t, created, "nothing is created until first use")
+
+		deps := container.Dependencies()
+		assert.Same(t, deps, container.Dependencies())
+		assert.Equal(t, 1, created)
+	})
+
+	t.Run("close_releases_and_forgets", func(t *testing.T) {
+		storage := &closingStorage{MockStorageClient: NewMockStorageClient()}
+		container := NewContainer(func() *Dependencies {
+			deps := CreateTestDependencies()
+			deps.StorageClient = storage
+			return deps
+		})
+		require.NoError(t, container.Close(ctx), "closing before first use does nothing")
+		assert.Zero(t, storage.closed)
+
+		first := container.Dependencies()
+		require.NoError(t, container.Close(ctx))
+		assert.Equal(t, 1, storage.closed)
+		assert.NotSame(t, first, container.Dependencies(), "dependencies are created again after Close")
+	})
+
+	t.Run("close_reports_errors", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient = &closingStorage{MockStorageClient: NewMockStorageClient(), err: errors.New("connection reset")}
+		err := deps.Close(ctx)
+		assert.ErrorContains(t, err, "closing storage: connection reset")
+	})
+}
+
+func TestDependencies_WithOverrides(t *testing.T) {
+	deps := CreateTestDependencies()
+	assert.Same(t, deps, deps.WithOverrides())
+
+	storage := NewMockStorageClient()
+	github := NewMockGitHubClient()
+	overridden := deps.WithOverrides(OverrideStorage(storage), OverrideGitHub(github))
+	assert.Same(t, storage, overridden.StorageClient)
+	assert.Same(t, github, overridden.GitHubClient)
+	assert.Same(t, deps.PubSubClient, overridden.PubSubClient, "other dependencies are shared")
+	assert.NotSame(t, storage, deps.StorageClient, "the original dependencies are unchanged")
+}
+
+func TestYouTubeWebhook_RequestOverrides(t *testing.T) {
+	SetDependencies(CreateTestDependencies())
+	defer SetDependencies(nil)
+
+	storage := NewMockStorageClient()
+	storage.SetState(&SubscriptionState{Subscriptions: map[string]*Subscription{
+		"UCXuqSBlHAE6Xw-yeJA0Tunw": {ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive},
+	}})
+	serve := func(ctx context.Context) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw", nil).WithContext(ctx))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(WithRequestOverrides(context.Background(), OverrideStorage(storage))).Code)
+	assert.Equal(t, http.StatusNotFound, serve(context.Background()).Code, "other requests see the shared storage")
+}
@@ -0,0 +1,438 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// Dead-lettered notifications are stored one object per payload, with an index
+// listing them so they can be found without listing the bucket
+const (
+	deadLetterPrefix      = "deadletter/notifications/"
+	deadLetterIndexObject = "deadletter/index.json"
+)
+
+// Limits on what the dead-letter store keeps. Notifications are accepted from anyone,
+// so the number of stored payloads is capped as well as their age.
+const (
+	maxDeadLetters         = 500
+	maxDeadLetterBodyBytes = 256 * 1024
+)
+
+// deadLetterIDPattern matches the IDs generated by newDeadLetterID
+var deadLetterIDPattern = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}Z-[0-9a-f]+$`)
+
+// DeadLetter is a notification payload that could not be parsed
+type DeadLetter struct {
+	ID              string            `json:"id"`
+	ReceivedAt      time.Time         `json:"received_at"`
+	RemoteAddr      string            `json:"remote_addr,omitempty"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	BodyTruncated   bool              `json:"body_truncated,omitempty"` // Truncated payloads cannot be replayed
+	Error           string            `json:"error"`
+	ReplayAttempts  int               `json:"replay_attempts,omitempty"`
+	LastReplayError string            `json:"last_replay_error,omitempty"`
+}
+
+// DeadLetterSummary describes a dead letter without its payload
+type DeadLetterSummary struct {
+	ID             string    `json:"id"`
+	ReceivedAt     time.Time `json:"received_at"`
+	Error          string    `json:"error"`
+	BodyBytes      int       `json:"body_bytes"`
+	ReplayAttempts int       `json:"replay_attempts,omitempty"`
+}
+
+// summary returns the index entry for the dead letter
+func (d *DeadLetter) summary() DeadLetterSummary {
+	return DeadLetterSummary{
+		ID:             d.ID,
+		ReceivedAt:     d.ReceivedAt,
+		Error:          d.Error,
+		BodyBytes:      len(d.Body),
+		ReplayAttempts: d.ReplayAttempts,
+	}
+}
+
+// deadLetterIndex is the stored index, oldest dead letter first
+type deadLetterIndex struct {
+	Entries []DeadLetterSummary `json:"entries"`
+}
+
+// DeadLetterListResponse lists the stored dead letters, newest first
+type DeadLetterListResponse struct {
+	Status        string              `json:"status"`
+	RetentionDays int                 `json:"retention_days"`
+	Count         int                 `json:"count"`
+	DeadLetters   []DeadLetterSummary `json:"dead_letters"`
+}
+
+// DeadLetterResponse returns one dead letter with its payload
+type DeadLetterResponse struct {
+	Status     string      `json:"status"`
+	DeadLetter *DeadLetter `json:"dead_letter"`
+}
+
+// DeadLetterReplayResponse reports a successful replay
+type DeadLetterReplayResponse struct {
+	Status  string `json:"status"`
+	ID      string `json:"id"`
+	Message string `json:"message"` // What processing the notification did
+}
+
+// DeadLetterStore keeps notifications whose XML could not be parsed so they can be
+// inspected and replayed. Dead letters older than DEAD_LETTER_RETENTION_DAYS, or
+// beyond the newest maxDeadLetters, are deleted when new ones are stored.
+type DeadLetterStore struct {
+	store     ObjectStorage
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+// getDeadLetterRetention returns how long dead letters are kept.
+// DEAD_LETTER_RETENTION_DAYS=0 disables dead-lettering.
+func getDeadLetterRetention() time.Duration {
+	return envConfig().DeadLetterRetention
+}
+
+// NewDeadLetterStore creates a dead-letter store in the given storage.
+// Returns nil (unparseable notifications are dropped) when the storage cannot hold
+// objects or the retention is zero.
+func NewDeadLetterStore(storage StorageService, cfg *config.Config) *DeadLetterStore {
+	store, ok := storage.(ObjectStorage)
+	retention := cfg.DeadLetterRetention
+	if !ok || retention == 0 {
+		return nil
+	}
+	return &DeadLetterStore{store: store, retention: retention}
+}
+
+// newDeadLetterID returns a sortable, unique dead-letter ID, using the ID generator ctx carries
+func newDeadLetterID(ctx context.Context, receivedAt time.Time) string {
+	return receivedAt.UTC().Format("20060102T150405Z") + "-" + newID(ctx)
+}
+
+// deadLetterObject returns the object a dead letter's payload is stored in
+func deadLetterObject(id string) string {
+	return deadLetterPrefix + id + ".json"
+}
+
+// loadIndex reads the index, treating a missing object as empty
+func (s *DeadLetterStore) loadIndex(ctx context.Context) (*deadLetterIndex, error) {
+	index := &deadLetterIndex{}
+
+	data, err := s.store.ReadObject(ctx, deadLetterIndexObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter index: %v", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to decode dead-letter index: %v", err)
+	}
+	return index, nil
+}
+
+// saveIndex writes the index
+func (s *DeadLetterStore) saveIndex(ctx context.Context, index *deadLetterIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead-letter index: %v", err)
+	}
+	if err := s.store.WriteObject(ctx, deadLetterIndexObject, data); err != nil {
+		return fmt.Errorf("failed to write dead-letter index: %v", err)
+	}
+	return nil
+}
+
+// writeDeadLetter stores a dead letter's payload
+func (s *DeadLetterStore) writeDeadLetter(ctx context.Context, deadLetter *DeadLetter) error {
+	data, err := json.Marshal(deadLetter)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %v", err)
+	}
+	if err := s.store.WriteObject(ctx, deadLetterObject(deadLetter.ID), data); err != nil {
+		return fmt.Errorf("failed to write dead letter: %v", err)
+	}
+	return nil
+}
+
+// expired reports whether a dead letter is past the retention period
+func (s *DeadLetterStore) expired(entry DeadLetterSummary, now time.Time) bool {
+	return now.Sub(entry.ReceivedAt) > s.retention
+}
+
+// Add stores a dead letter and deletes the ones that have expired or exceed the cap.
+// Failures to delete old payloads are only logged.
+func (s *DeadLetterStore) Add(ctx context.Context, deadLetter *DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writeDeadLetter(ctx, deadLetter); err != nil {
+		return err
+	}
+
+	index, err := s.loadIndex(ctx)
+	if err != nil {
+		return err
+	}
+	index.Entries = append(index.Entries, deadLetter.summary())
+
+	now := currentTime(ctx)
+	excess := len(index.Entries) - maxDeadLetters
+	var kept []DeadLetterSummary
+	for i, entry := range index.Entries {
+		if i < excess || s.expired(entry, now) {
+			if err := s.store.DeleteObject(ctx, deadLetterObject(entry.ID)); err != nil {
+				fmt.Printf("Unable to delete expired dead letter %s: %v\n", entry.ID, err)
+			}
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	index.Entries = kept
+	return s.saveIndex(ctx, index)
+}
+
+// List returns the dead letters within the retention period, newest first
+func (s *DeadLetterStore) List(ctx context.Context) ([]DeadLetterSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := currentTime(ctx)
+	entries := make([]DeadLetterSummary, 0, len(index.Entries))
+	for i := len(index.Entries) - 1; i >= 0; i-- {
+		if !s.expired(index.Entries[i], now) {
+			entries = append(entries, index.Entries[i])
+		}
+	}
+	return entries, nil
+}
+
+// Get returns a dead letter with its payload
+func (s *DeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	if !deadLetterIDPattern.MatchString(id) {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	data, err := s.store.ReadObject(ctx, deadLetterObject(id))
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter: %v", err)
+	}
+
+	var deadLetter DeadLetter
+	if err := json.Unmarshal(data, &deadLetter); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letter: %v", err)
+	}
+	if s.expired(deadLetter.summary(), currentTime(ctx)) {
+		return nil, ErrDeadLetterNotFound
+	}
+	return &deadLetter, nil
+}
+
+// Remove deletes a dead letter and its index entry
+func (s *DeadLetterStore) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.store.DeleteObject(ctx, deadLetterObject(id)); err != nil {
+		return fmt.Errorf("failed to delete dead letter: %v", err)
+	}
+
+	index, err := s.loadIndex(ctx)
+	if err != nil {
+		return err
+	}
+	var kept []DeadLetterSummary
+	for _, entry := range index.Entries {
+		if entry.ID != id {
+			kept = append(kept, entry)
+		}
+	}
+	index.Entries = kept
+	return s.saveIndex(ctx, index)
+}
+
+// RecordReplayFailure counts a failed replay on the dead letter and its index entry
+func (s *DeadLetterStore) RecordReplayFailure(ctx context.Context, deadLetter *DeadLetter, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadLetter.ReplayAttempts++
+	deadLetter.LastReplayError = message
+	if err := s.writeDeadLetter(ctx, deadLetter); err != nil {
+		return err
+	}
+
+	index, err := s.loadIndex(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range index.Entries {
+		if index.Entries[i].ID == deadLetter.ID {
+			index.Entries[i].ReplayAttempts = deadLetter.ReplayAttempts
+		}
+	}
+	return s.saveIndex(ctx, index)
+}
+
+// deadLetter stores a notification whose XML could not be parsed; failures are only logged
+func (ns *NotificationService) deadLetter(r *http.Request, body []byte, parseErr error) {
+	if ns.DeadLetters == nil || body == nil {
+		return
+	}
+
+	receivedAt := currentTime(r.Context())
+	deadLetter := &DeadLetter{
+		ID:         newDeadLetterID(r.Context(), receivedAt),
+		ReceivedAt: receivedAt,
+		RemoteAddr: r.RemoteAddr,
+		Headers:    redactHeaders(r),
+		Body:       string(body),
+		Error:      parseErr.Error(),
+	}
+	if len(body) > maxDeadLetterBodyBytes {
+		deadLetter.Body = string(body[:maxDeadLetterBodyBytes])
+		deadLetter.BodyTruncated = true
+	}
+
+	if err := ns.DeadLetters.Add(r.Context(), deadLetter); err != nil {
+		fmt.Printf("Unable to store unparseable notification: %v\n", err)
+		return
+	}
+	fmt.Printf("Stored unparseable notification as dead letter %s\n", deadLetter.ID)
+}
+
+// deadLetterStoreOrError returns the dead-letter store, writing the error response when
+// dead-lettering is disabled
+func deadLetterStoreOrError(w http.ResponseWriter, deps *Dependencies) *DeadLetterStore {
+	store := NewDeadLetterStore(deps.StorageClient, deps.config())
+	if store == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, "",
+			"Dead-lettering is disabled or not supported by the storage backend")
+	}
+	return store
+}
+
+// handleListDeadLetters handles GET /deadletters requests using dependency injection
+func handleListDeadLetters(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store := deadLetterStoreOrError(w, deps)
+		if store == nil {
+			return
+		}
+
+		entries, err := store.List(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, DeadLetterListResponse{
+			Status:        "success",
+			RetentionDays: int(store.retention / (24 * time.Hour)),
+			Count:         len(entries),
+			DeadLetters:   entries,
+		})
+	}
+}
+
+// handleGetDeadLetter handles GET /deadletters/{id} requests using dependency injection
+func handleGetDeadLetter(deps *Dependencies, id string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store := deadLetterStoreOrError(w, deps)
+		if store == nil {
+			return
+		}
+
+		deadLetter, ok := loadDeadLetter(w, r, store, id)
+		if !ok {
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, DeadLetterResponse{Status: "success", DeadLetter: deadLetter})
+	}
+}
+
+// handleReplayDeadLetter handles POST /deadletters/{id}/replay requests using dependency injection.
+// The stored payload and headers are processed again as a notification; a dead letter that
+// is processed successfully is deleted, one that fails again is kept. With as_received=true
+// the video is judged new or not as of when the dead letter was received.
+func handleReplayDeadLetter(deps *Dependencies, id string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store := deadLetterStoreOrError(w, deps)
+		if store == nil {
+			return
+		}
+
+		deadLetter, ok := loadDeadLetter(w, r, store, id)
+		if !ok {
+			return
+		}
+
+		replay, err := http.NewRequestWithContext(r.Context(), http.MethodPost, "/", strings.NewReader(deadLetter.Body))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to build replay request: %v", err))
+			return
+		}
+		for name, value := range deadLetter.Headers {
+			if !redactedHeaders[name] {
+				replay.Header.Set(name, value)
+			}
+		}
+		replay.RemoteAddr = deadLetter.RemoteAddr
+
+		replayDeps := deps
+		if r.URL.Query().Get("as_received") == "true" {
+			replayDeps = deps.WithOverrides(OverrideClock(NewFrozenClock(deadLetter.ReceivedAt)))
+		}
+
+		// A replay that fails again is recorded on the existing dead letter, not stored anew
+		service := newNotificationService(replayDeps)
+		service.DeadLetters = nil
+		result, err := service.ProcessNotification(replay)
+		if err != nil {
+			if recordErr := store.RecordReplayFailure(r.Context(), deadLetter, result.Message); recordErr != nil {
+				fmt.Printf("Unable to record replay failure for dead letter %s: %v\n", id, recordErr)
+			}
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "", fmt.Sprintf("Replay failed: %s", result.Message))
+			return
+		}
+
+		if err := store.Remove(r.Context(), id); err != nil {
+			fmt.Printf("Unable to remove replayed dead letter %s: %v\n", id, err)
+		}
+		writeJSONResponse(w, http.StatusOK, DeadLetterReplayResponse{Status: "success", ID: id, Message: result.Message})
+	}
+}
+
+// loadDeadLetter loads the dead letter a request refers to, writing the error response when it cannot
+func loadDeadLetter(w http.ResponseWriter, r *http.Request, store *DeadLetterStore, id string) (*DeadLetter, bool) {
+	deadLetter, err := store.Get(r.Context(), id)
+	if errors.Is(err, ErrDeadLetterNotFound) {
+		writeErrorResponse(w, http.StatusNotFound, "", "Dead letter not found")
+		return nil, false
+	}
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+		return nil, false
+	}
+	return deadLetter, true
+}
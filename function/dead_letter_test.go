@@ -0,0 +1,267 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDeadLetter builds a dead letter received at the given time
+func newTestDeadLetter(receivedAt time.Time, body string) *DeadLetter {
+	return &DeadLetter{
+		ID:         newDeadLetterID(context.Background(), receivedAt),
+		ReceivedAt: receivedAt,
+		Headers:    map[string]string{"Content-Type": "application/atom+xml"},
+		Body:       body,
+		Error:      "invalid XML: XML syntax error on line 1: unexpected EOF",
+	}
+}
+
+func TestGetDeadLetterRetention(t *testing.T) {
+	defer os.Unsetenv("DEAD_LETTER_RETENTION_DAYS")
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 14 * 24 * time.Hour},
+		{"30", 30 * 24 * time.Hour},
+		{"0", 0},
+		{"-1", 14 * 24 * time.Hour},
+		{"invalid", 14 * 24 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		os.Setenv("DEAD_LETTER_RETENTION_DAYS", tc.value)
+		assert.Equal(t, tc.expected, getDeadLetterRetention(), "DEAD_LETTER_RETENTION_DAYS=%q", tc.value)
+	}
+}
+
+func TestNewDeadLetterStore(t *testing.T) {
+	defer os.Unsetenv("DEAD_LETTER_RETENTION_DAYS")
+
+	assert.NotNil(t, NewDeadLetterStore(NewMockStorageClient(), envConfig()))
+
+	os.Setenv("DEAD_LETTER_RETENTION_DAYS", "0")
+	assert.Nil(t, NewDeadLetterStore(NewMockStorageClient(), envConfig()), "zero retention disables dead-lettering")
+}
+
+func TestDeadLetterStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("add_list_get_remove", func(t *testing.T) {
+		store := NewDeadLetterStore(NewMockStorageClient(), envConfig())
+		first := newTestDeadLetter(time.Now().Add(-time.Hour), "<feed>")
+		second := newTestDeadLetter(time.Now(), "<feed><entry>")
+		require.NoError(t, store.Add(ctx, first))
+		require.NoError(t, store.Add(ctx, second))
+
+		entries, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, second.ID, entries[0].ID, "newest first")
+		assert.Equal(t, len("<feed><entry>"), entries[0].BodyBytes)
+
+		loaded, err := store.Get(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "<feed>", loaded.Body)
+		assert.Equal(t, "application/atom+xml", loaded.Headers["Content-Type"])
+
+		require.NoError(t, store.Remove(ctx, first.ID))
+		_, err = store.Get(ctx, first.ID)
+		assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+		entries, err = store.List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("expired_dead_letters_are_deleted", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		store := NewDeadLetterStore(storage, envConfig())
+		stale := newTestDeadLetter(time.Now().Add(-15*24*time.Hour), "<stale>")
+		require.NoError(t, store.Add(ctx, stale))
+		require.NoError(t, store.Add(ctx, newTestDeadLetter(time.Now(), "<fresh>")))
+
+		_, err := storage.ReadObject(ctx, deadLetterObject(stale.ID))
+		assert.ErrorIs(t, err, ErrObjectNotFound)
+		entries, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.NotEqual(t, stale.ID, entries[0].ID)
+	})
+
+	t.Run("oldest_dead_letters_beyond_cap_are_deleted", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		index := &deadLetterIndex{}
+		for i := 0; i < maxDeadLetters; i++ {
+			index.Entries = append(index.Entries, newTestDeadLetter(time.Now().Add(-time.Hour), "<feed>").summary())
+		}
+		oldest := index.Entries[0].ID
+		data, err := json.Marshal(index)
+		require.NoError(t, err)
+		require.NoError(t, storage.WriteObject(ctx, deadLetterIndexObject, data))
+
+		store := NewDeadLetterStore(storage, envConfig())
+		newest := newTestDeadLetter(time.Now(), "<feed>")
+		require.NoError(t, store.Add(ctx, newest))
+
+		entries, err := store.List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, entries, maxDeadLetters)
+		assert.Equal(t, newest.ID, entries[0].ID)
+		for _, entry := range entries {
+			assert.NotEqual(t, oldest, entry.ID)
+		}
+	})
+
+	t.Run("invalid_id_is_not_found", func(t *testing.T) {
+		store := NewDeadLetterStore(NewMockStorageClient(), envConfig())
+		_, err := store.Get(ctx, "../index")
+		assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+	})
+}
+
+func TestHandleNotification_DeadLettersInvalidXML(t *testing.T) {
+	deps := CreateTestDependencies()
+
+	t.Run("invalid_xml_is_stored", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader("<feed><entry>"))
+		req.Header.Set("Content-Type", "application/atom+xml")
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		store := NewDeadLetterStore(deps.StorageClient, envConfig())
+		entries, err := store.List(context.Background())
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Contains(t, entries[0].Error, "invalid XML")
+
+		deadLetter, err := store.Get(context.Background(), entries[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, "<feed><entry>", deadLetter.Body)
+		assert.Equal(t, "application/atom+xml", deadLetter.Headers["Content-Type"])
+		assert.Equal(t, "[redacted]", deadLetter.Headers["Authorization"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		os.Setenv("DEAD_LETTER_RETENTION_DAYS", "0")
+		defer os.Unsetenv("DEAD_LETTER_RETENTION_DAYS")
+
+		deps := CreateTestDependencies()
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader("<feed>")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		_, err := deps.StorageClient.(*MockStorageClient).ReadObject(context.Background(), deadLetterIndexObject)
+		assert.ErrorIs(t, err, ErrObjectNotFound)
+	})
+}
+
+func TestDeadLetterEndpoints(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	gh := deps.GitHubClient.(*MockGitHubClient)
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	ctx := context.Background()
+	store := NewDeadLetterStore(deps.StorageClient, envConfig())
+	broken := newTestDeadLetter(time.Now().Add(-time.Minute), "<feed><entry>")
+	valid := newTestDeadLetter(time.Now(), fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>replay123</yt:videoId>
+    <yt:channelId>UCXuqSBlHAE6Xw-yeJA0Tunw</yt:channelId>
+    <title>Replayed Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, time.Now().Add(-5*time.Minute).Format(time.RFC3339), time.Now().Format(time.RFC3339)))
+	require.NoError(t, store.Add(ctx, broken))
+	require.NoError(t, store.Add(ctx, valid))
+
+	t.Run("list", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/deadletters", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response DeadLetterListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.Count)
+		assert.Equal(t, 14, response.RetentionDays)
+		assert.Equal(t, valid.ID, response.DeadLetters[0].ID)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/deadletters/"+broken.ID, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response DeadLetterResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "<feed><entry>", response.DeadLetter.Body)
+	})
+
+	t.Run("get_missing", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/deadletters/20260101T000000Z-abc", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("replay_failure_keeps_dead_letter", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("POST", "/deadletters/"+broken.ID+"/replay", nil))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Invalid XML")
+
+		deadLetter, err := store.Get(ctx, broken.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, deadLetter.ReplayAttempts)
+		assert.Equal(t, "Invalid XML", deadLetter.LastReplayError)
+
+		entries, err := store.List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "a failed replay is not stored again")
+	})
+
+	t.Run("replay_success_removes_dead_letter", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("POST", "/deadletters/"+valid.ID+"/replay", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response DeadLetterReplayResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Contains(t, response.Message, "Successfully triggered workflow for new video: replay123")
+		assert.Equal(t, 1, gh.GetTriggerCallCount())
+
+		_, err := store.Get(ctx, valid.ID)
+		assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		os.Setenv("DEAD_LETTER_RETENTION_DAYS", "0")
+		defer os.Unsetenv("DEAD_LETTER_RETENTION_DAYS")
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/deadletters", nil))
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+}
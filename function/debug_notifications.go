@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugNotificationsObject is where the buffer is mirrored when persistence is enabled
+const debugNotificationsObject = "debug/notifications.json"
+
+// maxDebugBodyBytes caps how much of each raw body is kept
+const maxDebugBodyBytes = 64 * 1024
+
+// redactedHeaders are never stored in the debug buffer
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+}
+
+// DebugNotification is a raw notification request and how it was handled
+type DebugNotification struct {
+	ReceivedAt    time.Time         `json:"received_at"`
+	RequestID     string            `json:"request_id,omitempty"`
+	RemoteAddr    string            `json:"remote_addr,omitempty"`
+	Headers       map[string]string `json:"headers"`
+	Body          string            `json:"body"`
+	BodyTruncated bool              `json:"body_truncated,omitempty"`
+	StatusCode    int               `json:"status_code"`
+	Outcome       string            `json:"outcome"`
+	Message       string            `json:"message"`
+}
+
+// DebugNotificationsResponse is returned by GET /debug/notifications
+type DebugNotificationsResponse struct {
+	Status        string              `json:"status"`
+	Capacity      int                 `json:"capacity"`
+	Count         int                 `json:"count"`
+	Persisted     bool                `json:"persisted"`
+	Notifications []DebugNotification `json:"notifications"`
+}
+
+// NotificationDebugBuffer is a fixed-size ring buffer of recent raw notifications
+type NotificationDebugBuffer struct {
+	mu       sync.Mutex
+	entries  []DebugNotification
+	next     int
+	full     bool
+	seedOnce sync.Once
+}
+
+// NewNotificationDebugBuffer creates a buffer holding the last capacity notifications
+func NewNotificationDebugBuffer(capacity int) *NotificationDebugBuffer {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &NotificationDebugBuffer{entries: make([]DebugNotification, capacity)}
+}
+
+// Capacity returns the maximum number of notifications kept
+func (b *NotificationDebugBuffer) Capacity() int {
+	return len(b.entries)
+}
+
+// Add records a notification, overwriting the oldest once the buffer is full
+func (b *NotificationDebugBuffer) Add(entry DebugNotification) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.entries) == 0 {
+		return
+	}
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Entries returns the buffered notifications, newest first
+func (b *NotificationDebugBuffer) Entries() []DebugNotification {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+
+	result := make([]DebugNotification, 0, count)
+	for i := 1; i <= count; i++ {
+		result = append(result, b.entries[(b.next-i+len(b.entries))%len(b.entries)])
+	}
+	return result
+}
+
+// seed loads previously persisted notifications, oldest first, into an empty buffer.
+// It only runs once per buffer so a cold instance picks up where others left off.
+func (b *NotificationDebugBuffer) seed(ctx context.Context, store ObjectStorage) {
+	b.seedOnce.Do(func() {
+		data, err := store.ReadObject(ctx, debugNotificationsObject)
+		if err != nil {
+			if !errors.Is(err, ErrObjectNotFound) {
+				fmt.Printf("Unable to load debug notifications: %v\n", err)
+			}
+			return
+		}
+
+		var stored []DebugNotification
+		if err := json.Unmarshal(data, &stored); err != nil {
+			fmt.Printf("Unable to decode debug notifications: %v\n", err)
+			return
+		}
+		for i := len(stored) - 1; i >= 0; i-- {
+			b.Add(stored[i])
+		}
+	})
+}
+
+var (
+	debugBuffer     *NotificationDebugBuffer
+	debugBufferOnce sync.Once
+)
+
+// getDebugBuffer returns the process-wide notification debug buffer
+func getDebugBuffer() *NotificationDebugBuffer {
+	debugBufferOnce.Do(func() {
+		debugBuffer = NewNotificationDebugBuffer(getDebugBufferSize())
+	})
+	return debugBuffer
+}
+
+// getDebugBufferSize returns how many raw notifications to keep (0 disables the buffer)
+func getDebugBufferSize() int {
+	return envConfig().DebugNotificationsSize
+}
+
+// debugPersistenceEnabled reports whether the buffer is mirrored to storage
+func debugPersistenceEnabled() bool {
+	return envConfig().DebugNotificationsPersist
+}
+
+// debugObjectStorage returns the storage used to persist the buffer, or nil when disabled
+func debugObjectStorage(deps *Dependencies) ObjectStorage {
+	if !deps.config().DebugNotificationsPersist {
+		return nil
+	}
+	store, _ := deps.StorageClient.(ObjectStorage)
+	return store
+}
+
+// captureNotificationBody tees the request body so the raw bytes can be recorded
+// after the notification has been processed. Returns nil when the buffer is disabled.
+func captureNotificationBody(r *http.Request, buffer *NotificationDebugBuffer) *limitedBuffer {
+	if buffer.Capacity() == 0 || r.Body == nil {
+		return nil
+	}
+
+	captured := &limitedBuffer{limit: maxDebugBodyBytes}
+	r.Body = io.NopCloser(io.TeeReader(r.Body, captured))
+	return captured
+}
+
+// redactHeaders flattens the request headers for storage, hiding credentials
+func redactHeaders(r *http.Request) map[string]string {
+	headers := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		if redactedHeaders[name] {
+			headers[name] = "[redacted]"
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// newDebugNotification builds the buffer entry for a processed notification request
+func newDebugNotification(r *http.Request, requestID string, body *limitedBuffer, statusCode int, result *NotificationResult) DebugNotification {
+	entry := DebugNotification{
+		ReceivedAt:    currentTime(r.Context()),
+		RequestID:     requestID,
+		RemoteAddr:    r.RemoteAddr,
+		Headers:       redactHeaders(r),
+		Body:          body.String(),
+		BodyTruncated: body.truncated,
+		StatusCode:    statusCode,
+	}
+	if result != nil {
+		entry.Outcome = result.Status
+		entry.Message = result.Message
+	}
+	return entry
+}
+
+// recordDebugNotification adds an entry to the buffer and, when enabled,
+// mirrors the buffer to storage. Storage errors are only logged.
+func recordDebugNotification(ctx context.Context, deps *Dependencies, buffer *NotificationDebugBuffer, entry DebugNotification) {
+	store := debugObjectStorage(deps)
+	if store != nil {
+		buffer.seed(ctx, store)
+	}
+	buffer.Add(entry)
+
+	if store == nil {
+		return
+	}
+	data, err := json.Marshal(buffer.Entries())
+	if err != nil {
+		fmt.Printf("Unable to encode debug notifications: %v\n", err)
+		return
+	}
+	if err := store.WriteObject(ctx, debugNotificationsObject, data); err != nil {
+		fmt.Printf("Unable to persist debug notifications: %v\n", err)
+	}
+}
+
+// handleGetDebugNotifications handles admin-only GET /debug/notifications requests
+func handleGetDebugNotifications(deps *Dependencies, buffer *NotificationDebugBuffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r) {
+			return
+		}
+
+		store := debugObjectStorage(deps)
+		if store != nil {
+			buffer.seed(r.Context(), store)
+		}
+
+		notifications := buffer.Entries()
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err := strconv.Atoi(limitStr)
+			if err != nil || limit < 1 {
+				writeErrorResponse(w, http.StatusBadRequest, "", "limit must be a positive integer")
+				return
+			}
+			if limit < len(notifications) {
+				notifications = notifications[:limit]
+			}
+		}
+
+		writeJSONResponse(w, http.StatusOK, DebugNotificationsResponse{
+			Status:        "success",
+			Capacity:      buffer.Capacity(),
+			Count:         len(notifications),
+			Persisted:     store != nil,
+			Notifications: notifications,
+		})
+	}
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := l.limit - l.buf.Len(); remaining < len(p) {
+		l.truncated = true
+		if remaining > 0 {
+			l.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return l.buf.Write(p)
+}
+
+func (l *limitedBuffer) String() string {
+	return l.buf.String()
+}
@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationDebugBuffer(t *testing.T) {
+	t.Run("keeps_newest_first_and_wraps", func(t *testing.T) {
+		buffer := NewNotificationDebugBuffer(3)
+		for i := 1; i <= 5; i++ {
+			buffer.Add(DebugNotification{Message: fmt.Sprintf("n%d", i)})
+		}
+
+		entries := buffer.Entries()
+		require.Len(t, entries, 3)
+		assert.Equal(t, "n5", entries[0].Message)
+		assert.Equal(t, "n4", entries[1].Message)
+		assert.Equal(t, "n3", entries[2].Message)
+	})
+
+	t.Run("partially_filled", func(t *testing.T) {
+		buffer := NewNotificationDebugBuffer(3)
+		buffer.Add(DebugNotification{Message: "only"})
+
+		entries := buffer.Entries()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "only", entries[0].Message)
+	})
+
+	t.Run("zero_capacity_disables", func(t *testing.T) {
+		buffer := NewNotificationDebugBuffer(-1)
+		buffer.Add(DebugNotification{Message: "ignored"})
+
+		assert.Equal(t, 0, buffer.Capacity())
+		assert.Empty(t, buffer.Entries())
+		assert.Nil(t, captureNotificationBody(httptest.NewRequest("POST", "/", strings.NewReader("x")), buffer))
+	})
+}
+
+func TestGetDebugBufferSize(t *testing.T) {
+	defer os.Unsetenv("DEBUG_NOTIFICATIONS_SIZE")
+
+	os.Unsetenv("DEBUG_NOTIFICATIONS_SIZE")
+	assert.Equal(t, 25, getDebugBufferSize())
+
+	os.Setenv("DEBUG_NOTIFICATIONS_SIZE", "0")
+	assert.Equal(t, 0, getDebugBufferSize())
+
+	os.Setenv("DEBUG_NOTIFICATIONS_SIZE", "invalid")
+	assert.Equal(t, 25, getDebugBufferSize())
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := &limitedBuffer{limit: 5}
+	n, err := buf.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = buf.Write([]byte("defgh"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "abcde", buf.String())
+	assert.True(t, buf.truncated)
+}
+
+func TestNewDebugNotification_RedactsHeaders(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/atom+xml")
+	body := &limitedBuffer{limit: 10}
+	body.Write([]byte("<feed/>"))
+
+	entry := newDebugNotification(req, "req-1", body, http.StatusOK, &NotificationResult{Status: "success", Message: "ok"})
+
+	assert.Equal(t, "[redacted]", entry.Headers["Authorization"])
+	assert.Equal(t, "application/atom+xml", entry.Headers["Content-Type"])
+	assert.Equal(t, "<feed/>", entry.Body)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "success", entry.Outcome)
+}
+
+func TestHandleNotification_RecordsDebugEntry(t *testing.T) {
+	deps := CreateTestDependencies()
+	buffer := getDebugBuffer()
+	before := len(buffer.Entries())
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("not xml"))
+	rec := httptest.NewRecorder()
+	rec.Header().Set(RequestIDHeader, "debug-req")
+	handleNotification(deps)(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	entries := buffer.Entries()
+	require.NotEmpty(t, entries)
+	if buffer.Capacity() > before {
+		assert.Len(t, entries, before+1)
+	}
+	assert.Equal(t, "not xml", entries[0].Body)
+	assert.Equal(t, http.StatusBadRequest, entries[0].StatusCode)
+	assert.Equal(t, "Invalid XML", entries[0].Message)
+	assert.Equal(t, "debug-req", entries[0].RequestID)
+}
+
+func TestHandleGetDebugNotifications(t *testing.T) {
+	os.Setenv("ADMIN_TOKEN", "admin-secret")
+	defer os.Unsetenv("ADMIN_TOKEN")
+
+	get := func(deps *Dependencies, buffer *NotificationDebugBuffer, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		handleGetDebugNotifications(deps, buffer)(rec, req)
+		return rec
+	}
+
+	t.Run("returns_entries_with_limit", func(t *testing.T) {
+		buffer := NewNotificationDebugBuffer(5)
+		buffer.Add(DebugNotification{Message: "first"})
+		buffer.Add(DebugNotification{Message: "second"})
+
+		rec := get(CreateTestDependencies(), buffer, "/debug/notifications?limit=1")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response DebugNotificationsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 5, response.Capacity)
+		assert.Equal(t, 1, response.Count)
+		assert.Equal(t, "second", response.Notifications[0].Message)
+		assert.False(t, response.Persisted)
+	})
+
+	t.Run("invalid_limit", func(t *testing.T) {
+		rec := get(CreateTestDependencies(), NewNotificationDebugBuffer(5), "/debug/notifications?limit=0")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("requires_admin_token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handleGetDebugNotifications(CreateTestDependencies(), NewNotificationDebugBuffer(5))(rec, httptest.NewRequest("GET", "/debug/notifications", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("routed", func(t *testing.T) {
+		SetDependencies(CreateTestDependencies())
+		defer SetDependencies(nil)
+
+		req := httptest.NewRequest("GET", "/debug/notifications", nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestDebugNotifications_Persistence(t *testing.T) {
+	os.Setenv("DEBUG_NOTIFICATIONS_PERSIST", "true")
+	defer os.Unsetenv("DEBUG_NOTIFICATIONS_PERSIST")
+	ctx := context.Background()
+
+	t.Run("mirrors_and_seeds_from_storage", func(t *testing.T) {
+		deps := CreateTestDependencies()
+
+		first := NewNotificationDebugBuffer(5)
+		recordDebugNotification(ctx, deps, first, DebugNotification{Message: "from instance one"})
+
+		// A cold instance starts empty and picks up the persisted entries
+		second := NewNotificationDebugBuffer(5)
+		recordDebugNotification(ctx, deps, second, DebugNotification{Message: "from instance two"})
+
+		entries := second.Entries()
+		require.Len(t, entries, 2)
+		assert.Equal(t, "from instance two", entries[0].Message)
+		assert.Equal(t, "from instance one", entries[1].Message)
+	})
+
+	t.Run("tolerates_storage_errors", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.LoadError = ErrMockLoadFailure
+		storage.SaveError = ErrMockSaveFailure
+
+		buffer := NewNotificationDebugBuffer(5)
+		recordDebugNotification(ctx, deps, buffer, DebugNotification{Message: "kept in memory"})
+
+		assert.Len(t, buffer.Entries(), 1)
+	})
+
+	t.Run("ignores_corrupt_object", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		require.NoError(t, storage.WriteObject(ctx, debugNotificationsObject, []byte("not json")))
+
+		buffer := NewNotificationDebugBuffer(5)
+		buffer.seed(ctx, storage)
+		assert.Empty(t, buffer.Entries())
+	})
+}
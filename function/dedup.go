@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// processedVideosObject is where the processed-video registry is stored
+const processedVideosObject = "dedup/processed_videos.json"
+
+// ProcessedVideoRegistry remembers which videos have already been dispatched so
+// redelivered notifications don't trigger duplicate workflow runs. Entries expire
+// after the TTL. The registry is shared through storage, but checks are not atomic
+// across instances: two deliveries racing on different instances can both dispatch.
+type ProcessedVideoRegistry struct {
+	store ObjectStorage
+	ttl   time.Duration
+	mu    sync.Mutex
+}
+
+// processedVideos is the stored registry: video ID to when it was processed
+type processedVideos struct {
+	Videos map[string]time.Time `json:"videos"`
+}
+
+// getProcessedVideoTTL returns how long processed videos are remembered.
+// PROCESSED_VIDEO_TTL_HOURS=0 disables deduplication.
+func getProcessedVideoTTL() time.Duration {
+	return envConfig().ProcessedVideoTTL
+}
+
+// NewProcessedVideoRegistry creates a registry in the given storage.
+// Returns nil (deduplication disabled) when the storage cannot hold objects or the TTL is zero.
+func NewProcessedVideoRegistry(storage StorageService, cfg *config.Config) *ProcessedVideoRegistry {
+	store, ok := storage.(ObjectStorage)
+	ttl := cfg.ProcessedVideoTTL
+	if !ok || ttl == 0 {
+		return nil
+	}
+	return &ProcessedVideoRegistry{store: store, ttl: ttl}
+}
+
+// load reads the registry, treating a missing object as empty
+func (r *ProcessedVideoRegistry) load(ctx context.Context) (*processedVideos, error) {
+	registry := &processedVideos{Videos: make(map[string]time.Time)}
+
+	data, err := r.store.ReadObject(ctx, processedVideosObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed videos: %v", err)
+	}
+	if err := json.Unmarshal(data, registry); err != nil {
+		return nil, fmt.Errorf("failed to decode processed videos: %v", err)
+	}
+	if registry.Videos == nil {
+		registry.Videos = make(map[string]time.Time)
+	}
+	return registry, nil
+}
+
+// WasProcessed reports whether the video was processed within the TTL
+func (r *ProcessedVideoRegistry) WasProcessed(ctx context.Context, videoID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registry, err := r.load(ctx)
+	if err != nil {
+		return false, err
+	}
+	processedAt, ok := registry.Videos[videoID]
+	return ok && currentTime(ctx).Sub(processedAt) < r.ttl, nil
+}
+
+// MarkProcessed records the video as processed and prunes expired entries
+func (r *ProcessedVideoRegistry) MarkProcessed(ctx context.Context, videoID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registry, err := r.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := currentTime(ctx)
+	for id, processedAt := range registry.Videos {
+		if now.Sub(processedAt) >= r.ttl {
+			delete(registry.Videos, id)
+		}
+	}
+	registry.Videos[videoID] = now
+
+	data, err := json.Marshal(registry)
+	if err != nil {
+		return fmt.Errorf("failed to encode processed videos: %v", err)
+	}
+	if err := r.store.WriteObject(ctx, processedVideosObject, data); err != nil {
+		return fmt.Errorf("failed to write processed videos: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProcessedVideoTTL(t *testing.T) {
+	defer os.Unsetenv("PROCESSED_VIDEO_TTL_HOURS")
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 24 * time.Hour},
+		{"48", 48 * time.Hour},
+		{"0", 0},
+		{"-1", 24 * time.Hour},
+		{"invalid", 24 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		os.Setenv("PROCESSED_VIDEO_TTL_HOURS", tc.value)
+		assert.Equal(t, tc.expected, getProcessedVideoTTL(), "PROCESSED_VIDEO_TTL_HOURS=%q", tc.value)
+	}
+}
+
+func TestNewProcessedVideoRegistry(t *testing.T) {
+	defer os.Unsetenv("PROCESSED_VIDEO_TTL_HOURS")
+
+	assert.NotNil(t, NewProcessedVideoRegistry(NewMockStorageClient(), envConfig()))
+
+	os.Setenv("PROCESSED_VIDEO_TTL_HOURS", "0")
+	assert.Nil(t, NewProcessedVideoRegistry(NewMockStorageClient(), envConfig()), "zero TTL disables deduplication")
+}
+
+func TestProcessedVideoRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("marks_and_detects_videos", func(t *testing.T) {
+		registry := NewProcessedVideoRegistry(NewMockStorageClient(), envConfig())
+
+		processed, err := registry.WasProcessed(ctx, "video1")
+		require.NoError(t, err)
+		assert.False(t, processed)
+
+		require.NoError(t, registry.MarkProcessed(ctx, "video1"))
+
+		processed, err = registry.WasProcessed(ctx, "video1")
+		require.NoError(t, err)
+		assert.True(t, processed)
+
+		processed, err = registry.WasProcessed(ctx, "video2")
+		require.NoError(t, err)
+		assert.False(t, processed)
+	})
+
+	t.Run("expired_entries_are_ignored_and_pruned", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		stale, err := json.Marshal(processedVideos{Videos: map[string]time.Time{
+			"old":    time.Now().Add(-48 * time.Hour),
+			"recent": time.Now().Add(-time.Hour),
+		}})
+		require.NoError(t, err)
+		require.NoError(t, storage.WriteObject(ctx, processedVideosObject, stale))
+
+		registry := NewProcessedVideoRegistry(storage, envConfig())
+		processed, err := registry.WasProcessed(ctx, "old")
+		require.NoError(t, err)
+		assert.False(t, processed)
+
+		require.NoError(t, registry.MarkProcessed(ctx, "new"))
+
+		data, err := storage.ReadObject(ctx, processedVideosObject)
+		require.NoError(t, err)
+		var stored processedVideos
+		require.NoError(t, json.Unmarshal(data, &stored))
+		assert.NotContains(t, stored.Videos, "old")
+		assert.Contains(t, stored.Videos, "recent")
+		assert.Contains(t, stored.Videos, "new")
+	})
+
+	t.Run("storage_errors", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		registry := NewProcessedVideoRegistry(storage, envConfig())
+
+		storage.LoadError = ErrMockLoadFailure
+		_, err := registry.WasProcessed(ctx, "video1")
+		assert.Error(t, err)
+		assert.Error(t, registry.MarkProcessed(ctx, "video1"))
+
+		storage.LoadError = nil
+		storage.SaveError = ErrMockSaveFailure
+		assert.Error(t, registry.MarkProcessed(ctx, "video1"))
+	})
+
+	t.Run("corrupt_registry", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		require.NoError(t, storage.WriteObject(ctx, processedVideosObject, []byte("not json")))
+
+		_, err := NewProcessedVideoRegistry(storage, envConfig()).WasProcessed(ctx, "video1")
+		assert.Error(t, err)
+	})
+}
+
+func TestHandleNotification_Deduplication(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>dup123</yt:videoId>
+    <yt:channelId>UCXuqSBlHAE6Xw-yeJA0Tunw</yt:channelId>
+    <title>Redelivered Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	post := func(deps *Dependencies) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+		return rec
+	}
+
+	t.Run("redelivery_is_skipped", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		gh := deps.GitHubClient.(*MockGitHubClient)
+
+		first := post(deps)
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Contains(t, first.Body.String(), "Successfully triggered workflow")
+
+		second := post(deps)
+		assert.Equal(t, http.StatusOK, second.Code)
+		assert.Equal(t, "Duplicate skipped: video already processed (VideoID: dup123)", second.Body.String())
+		assert.Equal(t, 1, gh.GetTriggerCallCount())
+	})
+
+	t.Run("queued_dispatch_is_not_redispatched", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		gh.SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+
+		first := post(deps)
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Contains(t, first.Body.String(), "queued for retry")
+
+		gh.SetTriggerError(nil)
+		second := post(deps)
+		assert.Equal(t, "Duplicate skipped: video already processed (VideoID: dup123)", second.Body.String())
+		assert.Equal(t, 1, gh.GetTriggerCallCount())
+	})
+
+	t.Run("unqueued_failed_dispatch_is_redelivered", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		storage := deps.StorageClient.(*MockStorageClient)
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		gh.SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+		storage.ObjectErrors = map[string]error{dispatchQueueObject: ErrMockSaveFailure}
+
+		assert.Equal(t, http.StatusInternalServerError, post(deps).Code)
+
+		gh.SetTriggerError(nil)
+		storage.ObjectErrors = nil
+		assert.Equal(t, http.StatusOK, post(deps).Code)
+		assert.Equal(t, 2, gh.GetTriggerCallCount())
+	})
+
+	t.Run("registry_failure_does_not_block_dispatch", func(t *testing.T) {
+		failing := NewMockStorageClient()
+		failing.LoadError = ErrMockLoadFailure
+		gh := NewMockGitHubClient()
+		ns := &NotificationService{
+			VideoProcessor: NewVideoProcessor(),
+			GitHubClient:   gh,
+			Registry:       NewProcessedVideoRegistry(failing, envConfig()),
+			RepoOwner:      "test-owner",
+			RepoName:       "test-repo",
+		}
+
+		for i := 0; i < 2; i++ {
+			result, err := ns.ProcessNotification(httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+			require.NoError(t, err)
+			assert.Equal(t, "success", result.Status)
+		}
+		assert.Equal(t, 2, gh.GetTriggerCallCount())
+	})
+
+	t.Run("disabled_with_zero_ttl", func(t *testing.T) {
+		os.Setenv("PROCESSED_VIDEO_TTL_HOURS", "0")
+		defer os.Unsetenv("PROCESSED_VIDEO_TTL_HOURS")
+
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		gh := deps.GitHubClient.(*MockGitHubClient)
+
+		post(deps)
+		post(deps)
+		assert.Equal(t, 2, gh.GetTriggerCallCount())
+	})
+}
+
+func TestHandleNotification_MultipleEntries(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+
+	now := time.Now()
+	entry := func(videoID string) string {
+		return fmt.Sprintf(`
+  <entry>
+    <yt:videoId>%s</yt:videoId>
+    <yt:channelId>UCXuqSBlHAE6Xw-yeJA0Tunw</yt:channelId>
+    <title>Batched Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>`, videoID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+	}
+	notification := `<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">` +
+		entry("batch1") + entry("batch2") + `
+</feed>`
+
+	post := func(deps *Dependencies) (*httptest.ResponseRecorder, NotificationResult) {
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+		var result NotificationResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result), rec.Body.String())
+		return rec, result
+	}
+
+	t.Run("every_entry_is_dispatched", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		gh := deps.GitHubClient.(*MockGitHubClient)
+
+		rec, result := post(deps)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "success", result.Status)
+		assert.Equal(t, "Processed 2 entries", result.Message)
+		require.Len(t, result.Entries, 2)
+		assert.Equal(t, "batch1", result.Entries[0].VideoID)
+		assert.Equal(t, "batch2", result.Entries[1].VideoID)
+		assert.Equal(t, 2, gh.GetTriggerCallCount())
+
+		// Redelivery skips both entries
+		_, result = post(deps)
+		require.Len(t, result.Entries, 2)
+		assert.Equal(t, "Duplicate skipped: video already processed (VideoID: batch1)", result.Entries[0].Message)
+		assert.Equal(t, "Duplicate skipped: video already processed (VideoID: batch2)", result.Entries[1].Message)
+		assert.Equal(t, 2, gh.GetTriggerCallCount())
+	})
+
+	t.Run("failed_entry_fails_the_notification", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		storage := deps.StorageClient.(*MockStorageClient)
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		gh.SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+		storage.SaveError = ErrMockSaveFailure
+
+		rec, result := post(deps)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "error", result.Status)
+		assert.Equal(t, "2 of 2 entries failed", result.Message)
+		require.Len(t, result.Entries, 2)
+		assert.Equal(t, "error", result.Entries[0].Status)
+	})
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import "context"
+
+// Statuses of a video's delivery to one sink
+const (
+	DeliveryDelivered      = "delivered"
+	DeliveryFailed         = "failed"
+	DeliveryQueuedForRetry = "queued_for_retry" // GitHub dispatches only; POST /retry delivers them later
+)
+
+// deliveryTargetPubSub is the target reported for video events
+const deliveryTargetPubSub = "video events"
+
+// SinkDelivery reports the delivery of a new video to one of the sinks it fans out to
+type SinkDelivery struct {
+	Type     string `json:"type"`   // github, webhook, discord, slack or pubsub
+	Target   string `json:"target"` // Repository, sink URL with chat tokens masked, or "video events"
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts,omitempty"` // Set for webhook and chat sinks, which retry
+	Error    string `json:"error,omitempty"`
+}
+
+// newSinkDelivery reports a delivery that succeeded when err is nil and failed otherwise
+func newSinkDelivery(sinkType, target string, err error) SinkDelivery {
+	if err != nil {
+		return SinkDelivery{Type: sinkType, Target: target, Status: DeliveryFailed, Error: err.Error()}
+	}
+	return SinkDelivery{Type: sinkType, Target: target, Status: DeliveryDelivered}
+}
+
+// deliveriesFailed reports whether any delivery failed. Dispatches queued for retry
+// have not failed.
+func deliveriesFailed(deliveries []SinkDelivery) bool {
+	for _, delivery := range deliveries {
+		if delivery.Status == DeliveryFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// partiallyDelivered reports whether a notification that was processed without error
+// still failed to reach some sink, for any of its entries
+func (r *NotificationResult) partiallyDelivered() bool {
+	if deliveriesFailed(r.Deliveries) {
+		return true
+	}
+	for _, entry := range r.Entries {
+		if deliveriesFailed(entry.Deliveries) {
+			return true
+		}
+	}
+	return false
+}
+
+// fanOut delivers a new video to every sink other than GitHub: the channel's webhook and
+// chat sinks, concurrently, then the video event topic. It returns the webhook sink
+// summary for the notification message and a delivery per sink. Failures are reported
+// but never fail the notification.
+func (ns *NotificationService) fanOut(ctx context.Context, entry *Entry) (string, []SinkDelivery) {
+	summary, deliveries := ns.deliverSinks(ctx, entry)
+	if ns.EventPublisher != nil {
+		deliveries = append(deliveries, newSinkDelivery(replayTargetPubSub, deliveryTargetPubSub, ns.publishEvent(ctx, entry)))
+	}
+	return summary, deliveries
+}
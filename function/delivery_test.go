@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveriesFailed(t *testing.T) {
+	assert.False(t, deliveriesFailed(nil))
+	assert.False(t, deliveriesFailed([]SinkDelivery{
+		{Type: TargetTypeGitHub, Status: DeliveryDelivered},
+		{Type: TargetTypeGitHub, Status: DeliveryQueuedForRetry},
+	}), "queued dispatches have not failed")
+	assert.True(t, deliveriesFailed([]SinkDelivery{
+		{Type: TargetTypeGitHub, Status: DeliveryDelivered},
+		newSinkDelivery("pubsub", deliveryTargetPubSub, fmt.Errorf("topic not found")),
+	}))
+}
+
+func TestHandleNotification_FanOut(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+
+	notification := func(videoIDs ...string) string {
+		var entries strings.Builder
+		now := time.Now()
+		for _, videoID := range videoIDs {
+			fmt.Fprintf(&entries, `
+  <entry>
+    <yt:videoId>%s</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Fan-out Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>`, videoID, sinkChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+		}
+		return `<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">` + entries.String() + `
+</feed>`
+	}
+	post := func(deps *Dependencies, body string) *httptest.ResponseRecorder {
+		subscribeTestChannels(deps, sinkChannelID)
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("every_sink_delivered", func(t *testing.T) {
+		rec := post(CreateTestDependencies(), notification("fanout0001"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Successfully triggered workflow for new video: fanout0001", rec.Body.String())
+	})
+
+	t.Run("failed_sink_reports_multi_status", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.EventPublisher.(*MockEventPublisher).SetPublishError(fmt.Errorf("topic not found"))
+
+		rec := post(deps, notification("fanout0002"))
+
+		require.Equal(t, http.StatusMultiStatus, rec.Code)
+		var result NotificationResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, []SinkDelivery{
+			{Type: "pubsub", Target: deliveryTargetPubSub, Status: DeliveryFailed, Error: "topic not found"},
+			{Type: TargetTypeGitHub, Target: "test-owner/test-repo", Status: DeliveryDelivered},
+		}, result.Deliveries)
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount(), "other sinks still receive the video")
+	})
+
+	t.Run("queued_dispatch_is_not_a_failure", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+
+		rec := post(deps, notification("fanout0003"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "queued for retry")
+	})
+
+	t.Run("batch_reports_each_entry", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.EventPublisher.(*MockEventPublisher).SetPublishError(fmt.Errorf("topic not found"))
+
+		rec := post(deps, notification("fanout0004", "fanout0005"))
+
+		require.Equal(t, http.StatusMultiStatus, rec.Code)
+		var result NotificationResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		require.Len(t, result.Entries, 2)
+		for _, entry := range result.Entries {
+			assert.True(t, deliveriesFailed(entry.Deliveries), entry.VideoID)
+		}
+	})
+}
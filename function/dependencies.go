@@ -1,59 +1,85 @@
 package webhook
 
-import "sync"
+import (
+	"github.com/samsoir/youtube-webhook/function/config"
+	"go.opentelemetry.io/otel/trace"
+)
 
 // Dependencies holds all the external dependencies for the webhook service.
 type Dependencies struct {
-	StorageClient StorageService       // Use proper storage interface
-	PubSubClient  PubSubClient
-	GitHubClient  GitHubClientInterface
-}
-
-var (
-	globalDependencies *Dependencies
-	dependenciesMutex  sync.RWMutex
-	dependenciesOnce   sync.Once
-)
+	StorageClient  StorageService // Use proper storage interface
+	PubSubClient   PubSubClient
+	GitHubClient   GitHubClientInterface
+	EventPublisher EventPublisher    // Optional; nil publishes no video events
+	Metrics        *Metrics          // Optional; nil records nothing
+	HTTPClients    *HTTPClients      // Optional; nil gives each caller its own HTTP client
+	ChannelNames   ChannelNameLookup // Optional; nil leaves channel names unset
+	Config         *config.Config    // Optional; nil reads the environment on each use
+	Tracer         trace.Tracer      // Optional; nil traces nothing
+	Clock          Clock             // Optional; nil uses the system clock
+	IDs            IDGenerator       // Optional; nil generates random IDs
+	RequestLog     *RequestLogger    // Optional; nil logs no requests
 
-// GetDependencies returns the global dependencies instance.
-// Creates production dependencies if none exist.
-func GetDependencies() *Dependencies {
-	// Use sync.Once to ensure thread-safe initialization
-	dependenciesOnce.Do(func() {
-		if globalDependencies == nil {
-			globalDependencies = CreateProductionDependencies()
-		}
-	})
-
-	// Now safe to read without race condition
-	dependenciesMutex.RLock()
-	defer dependenciesMutex.RUnlock()
-	return globalDependencies
-}
-
-// SetDependencies sets the global dependencies (primarily for testing).
-func SetDependencies(deps *Dependencies) {
-	dependenciesMutex.Lock()
-	defer dependenciesMutex.Unlock()
-	globalDependencies = deps
-	// Reset the Once so it can be used again if needed
-	dependenciesOnce = sync.Once{}
+	HubBreaker         *CircuitBreaker          // Optional; the breaker PubSubClient goes through, checked before renewals start
+	NotificationStages *NotificationStages      // Optional; nil processes notifications with the built-in stages only
+	StorageBackfill    *StorageBackfill         // Optional; nil drops history and processed videos storage could not take
+	Broadcaster        *NotificationBroadcaster // Optional; nil streams no events
 }
 
 // CreateProductionDependencies creates dependencies for production use.
 func CreateProductionDependencies() *Dependencies {
+	metrics := NewMetrics()
+	clients := NewHTTPClients()
+
+	hub := NewHTTPPubSubClient()
+	hub.client = clients.Client(httpClientHub, hub.client.Timeout)
+	github := NewGitHubClient()
+	github.Client = clients.Client(httpClientGitHub, github.Client.Timeout)
+	channelNames := NewChannelNameFetcher()
+	channelNames.Client = clients.Client(httpClientYouTube, channelNames.Client.Timeout)
+	channelNames.API.Client = channelNames.Client
+
+	// Problems were logged at cold start by logConfigReport; invalid values take defaults
+	cfg, _ := config.FromEnv()
+
+	// Storage and hub calls fail fast while their breakers are open
+	storage := guardStorage(instrumentStorage(NewStorageService(), metrics), NewStorageBreaker(metrics))
+	hubBreaker := NewHubBreaker(metrics)
+	pubsub := guardPubSub(instrumentPubSub(hub, metrics), hubBreaker)
+
 	return &Dependencies{
-		StorageClient: NewCloudStorageService(), // Use real Cloud Storage with caching
-		PubSubClient:  NewHTTPPubSubClient(),    // Use real HTTP PubSub client
-		GitHubClient:  NewGitHubClient(),        // Use real GitHub client
+		StorageClient:  storage,             // Cloud Storage or Firestore, per STORAGE_BACKEND
+		PubSubClient:   pubsub,              // Use real HTTP PubSub client
+		GitHubClient:   github,              // Use real GitHub client
+		EventPublisher: NewEventPublisher(), // Cloud Pub/Sub when EVENTS_PUBSUB_TOPIC is set
+		Metrics:        metrics,
+		HTTPClients:    clients,
+		ChannelNames:   channelNames, // YouTube Data API, or the channel's feed without YOUTUBE_API_KEY
+		Config:         cfg,
+		Tracer:         NewTracer(), // Cloud Trace when TRACE_SAMPLE_RATIO is set
+		RequestLog:     NewRequestLogger(),
+
+		HubBreaker:      hubBreaker,
+		StorageBackfill: NewStorageBackfill(),         // Writes storage could not take are made once it is back
+		Broadcaster:     NewNotificationBroadcaster(), // Feeds GET /events streams
+	}
+}
+
+// config returns the injected configuration, or parses it from the environment
+func (d *Dependencies) config() *config.Config {
+	if d.Config != nil {
+		return d.Config
 	}
+	return envConfig()
 }
 
 // CreateTestDependencies creates dependencies for testing.
 func CreateTestDependencies() *Dependencies {
 	return &Dependencies{
-		StorageClient: NewMockStorageClient(),  // Mock for testing only
-		PubSubClient:  NewMockPubSubClient(),   // Mock for testing only  
-		GitHubClient:  NewMockGitHubClient(),   // Mock for testing only
+		StorageClient:  NewMockStorageClient(),  // Mock for testing only
+		PubSubClient:   NewMockPubSubClient(),   // Mock for testing only
+		GitHubClient:   NewMockGitHubClient(),   // Mock for testing only
+		EventPublisher: NewMockEventPublisher(), // Mock for testing only
+		Metrics:        NewMetrics(),
 	}
 }
@@ -1,9 +1,12 @@
 package webhook
 
 import (
+	"context"
 	"os"
 	"sync"
 	"testing"
+
+	"github.com/samsoir/youtube-webhook/function/config"
 )
 
 func TestCreateProductionDependencies(t *testing.T) {
@@ -33,26 +36,78 @@ func TestCreateProductionDependencies(t *testing.T) {
 		t.Error("GitHubClient is nil")
 	}
 
-	// Verify types
-	if _, ok := deps.StorageClient.(*CloudStorageService); !ok {
+	// Verify types; storage and hub clients are wrapped for metrics, and storage in a circuit breaker
+	guarded, ok := deps.StorageClient.(*breakerObjectStorage)
+	if !ok {
+		t.Fatal("StorageClient is not guarded by a circuit breaker with ObjectStorage")
+	}
+	storage, ok := guarded.StorageService.(*instrumentedObjectStorage)
+	if !ok {
+		t.Fatal("StorageClient is not instrumented with ObjectStorage")
+	}
+	if deps.StorageBackfill == nil {
+		t.Error("StorageBackfill is nil")
+	}
+	if _, ok := storage.StorageService.(*CloudStorageService); !ok {
 		t.Error("StorageClient is not CloudStorageService")
 	}
 
-	if _, ok := deps.PubSubClient.(*HTTPPubSubClient); !ok {
+	guardedPubSub, ok := deps.PubSubClient.(*breakerPubSub)
+	if !ok {
+		t.Fatal("PubSubClient is not behind a circuit breaker")
+	}
+	if guardedPubSub.breaker != deps.HubBreaker {
+		t.Error("PubSubClient does not use the hub breaker")
+	}
+	pubsub, ok := guardedPubSub.PubSubClient.(*instrumentedPubSub)
+	if !ok {
+		t.Fatal("PubSubClient is not instrumented")
+	}
+	if _, ok := pubsub.PubSubClient.(*HTTPPubSubClient); !ok {
 		t.Error("PubSubClient is not HTTPPubSubClient")
 	}
 
+	if deps.Metrics == nil {
+		t.Error("Metrics is nil")
+	}
+
 	if _, ok := deps.GitHubClient.(*GitHubClient); !ok {
 		t.Error("GitHubClient is not GitHubClient")
 	}
+
+	if deps.EventPublisher != nil {
+		t.Error("EventPublisher should be nil without EVENTS_PUBSUB_TOPIC")
+	}
+
+	if deps.Config == nil {
+		t.Error("Config is nil")
+	}
+}
+
+func TestDependencies_Config(t *testing.T) {
+	t.Setenv("MAX_RENEWAL_ATTEMPTS", "5")
+
+	deps := CreateTestDependencies()
+	if got := deps.config().MaxRenewalAttempts; got != 5 {
+		t.Errorf("Without Config, expected MAX_RENEWAL_ATTEMPTS 5 from the environment, got %d", got)
+	}
+
+	deps.Config = &config.Config{MaxRenewalAttempts: 2}
+	if got := deps.config().MaxRenewalAttempts; got != 2 {
+		t.Errorf("Expected the injected MaxRenewalAttempts 2, got %d", got)
+	}
+
+	// Renewals use the injected limit rather than the environment
+	sub := &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", RenewalAttempts: 2}
+	result := renewSubscription(context.Background(), sub.ChannelID, sub, newSweepTestState(sub), deps)
+	if result.Success || result.Message != "Max renewal attempts (2) exceeded" {
+		t.Errorf("Expected the renewal to stop at 2 attempts, got %+v", result)
+	}
 }
 
 func TestGetDependencies_CreatesProductionDependencies(t *testing.T) {
 	// Reset global state
-	dependenciesMutex.Lock()
-	globalDependencies = nil
-	dependenciesOnce = sync.Once{}
-	dependenciesMutex.Unlock()
+	SetDependencies(nil)
 
 	// Set required environment variables
 	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
@@ -69,7 +124,11 @@ func TestGetDependencies_CreatesProductionDependencies(t *testing.T) {
 	}
 
 	// Should be production dependencies
-	if _, ok := deps.StorageClient.(*CloudStorageService); !ok {
+	if guarded, ok := deps.StorageClient.(*breakerObjectStorage); !ok {
+		t.Error("Expected storage guarded by a circuit breaker, got different type")
+	} else if storage, ok := guarded.StorageService.(*instrumentedObjectStorage); !ok {
+		t.Error("Expected instrumented storage, got different type")
+	} else if _, ok := storage.StorageService.(*CloudStorageService); !ok {
 		t.Error("Expected CloudStorageService, got different type")
 	}
 
@@ -92,10 +151,7 @@ func TestGetDependencies_ReturnsExistingDependencies(t *testing.T) {
 	}
 
 	// Reset global state for other tests
-	dependenciesMutex.Lock()
-	globalDependencies = nil
-	dependenciesOnce = sync.Once{}
-	dependenciesMutex.Unlock()
+	SetDependencies(nil)
 }
 
 func TestSetDependencies(t *testing.T) {
@@ -118,18 +174,12 @@ func TestSetDependencies(t *testing.T) {
 	}
 
 	// Reset global state for other tests
-	dependenciesMutex.Lock()
-	globalDependencies = nil
-	dependenciesOnce = sync.Once{}
-	dependenciesMutex.Unlock()
+	SetDependencies(nil)
 }
 
 func TestDependencies_ConcurrentAccess(t *testing.T) {
 	// Reset global state
-	dependenciesMutex.Lock()
-	globalDependencies = nil
-	dependenciesOnce = sync.Once{}
-	dependenciesMutex.Unlock()
+	SetDependencies(nil)
 
 	// Set required environment variables
 	os.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
@@ -163,8 +213,5 @@ func TestDependencies_ConcurrentAccess(t *testing.T) {
 	}
 
 	// Reset global state for other tests
-	dependenciesMutex.Lock()
-	globalDependencies = nil
-	dependenciesOnce = sync.Once{}
-	dependenciesMutex.Unlock()
+	SetDependencies(nil)
 }
\ No newline at end of file
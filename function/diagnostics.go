@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// diagnosticsTimeout bounds the storage and GitHub checks made by /diagnostics
+const diagnosticsTimeout = 15 * time.Second
+
+// DiagnosticsResponse is returned by GET /diagnostics
+type DiagnosticsResponse struct {
+	Status      string                    `json:"status"` // ok, or error when any component failed
+	Timestamp   string                    `json:"timestamp"`
+	CallbackURL string                    `json:"callback_url,omitempty"` // Where the hub sends challenges: FUNCTION_URL, or its callback path
+	Repository  string                    `json:"repository,omitempty"`   // REPO_OWNER/REPO_NAME, checked with GITHUB_TOKEN
+	Components  map[string]ComponentCheck `json:"components"`
+}
+
+// handleGetDiagnostics handles GET /diagnostics requests using dependency injection.
+// Unlike /readyz it makes a GitHub API call to check the token is accepted, so it is a
+// management endpoint rather than a probe. Failures are reported in the body with a 200.
+func handleGetDiagnostics(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), diagnosticsTimeout)
+		defer cancel()
+
+		cfg := deps.config()
+		response := DiagnosticsResponse{
+			Status:      ProbeStatusOK,
+			Timestamp:   deps.now().Format(timeFormat()),
+			CallbackURL: currentCallback(ctx, deps),
+			Components: map[string]ComponentCheck{
+				"config":       checkConfigReport(config.Load()),
+				"storage":      checkStorage(ctx, deps.StorageClient),
+				"github":       checkGitHubAccess(ctx, deps.GitHubClient, cfg.RepoOwner, cfg.RepoName),
+				"function_url": checkConfigured(cfg.FunctionURL != "", "FUNCTION_URL not set"),
+			},
+		}
+		if cfg.RepoOwner != "" && cfg.RepoName != "" {
+			response.Repository = cfg.RepoOwner + "/" + cfg.RepoName
+		}
+		for _, check := range response.Components {
+			if check.Status != ProbeStatusOK {
+				response.Status = ProbeStatusError
+			}
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+// checkConfigReport reports the settings that are missing or invalid
+func checkConfigReport(report *config.Report) ComponentCheck {
+	problems := report.Problems()
+	if len(problems) == 0 {
+		return ComponentCheck{Status: ProbeStatusOK}
+	}
+	messages := make([]string, len(problems))
+	for i, problem := range problems {
+		messages[i] = problem.Name + ": " + problem.Problem
+	}
+	return ComponentCheck{Status: ProbeStatusError, Message: strings.Join(messages, "; ")}
+}
+
+// checkGitHubAccess checks GITHUB_TOKEN is accepted, and can see the default repository
+// when one is configured, explaining the GitHub responses operators most often hit
+func checkGitHubAccess(ctx context.Context, github GitHubClientInterface, repoOwner, repoName string) ComponentCheck {
+	if github == nil || !github.IsConfigured() {
+		return ComponentCheck{Status: ProbeStatusError, Message: "GITHUB_TOKEN not set"}
+	}
+
+	err := github.CheckAccess(ctx, repoOwner, repoName)
+	if err == nil {
+		return ComponentCheck{Status: ProbeStatusOK}
+	}
+
+	repo := repoOwner + "/" + repoName
+	var apiErr *GitHubAPIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized:
+		return ComponentCheck{Status: ProbeStatusError, Message: "GitHub rejected GITHUB_TOKEN (401); it may have expired or been revoked"}
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden:
+		return ComponentCheck{Status: ProbeStatusError, Message: fmt.Sprintf("GITHUB_TOKEN is not allowed to read %s (403)", repo)}
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return ComponentCheck{Status: ProbeStatusError, Message: fmt.Sprintf("repository %s was not found, or GITHUB_TOKEN cannot see it (404)", repo)}
+	}
+	return ComponentCheck{Status: ProbeStatusError, Message: err.Error()}
+}
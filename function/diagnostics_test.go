@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDiagnostics(t *testing.T) {
+	diagnostics := func(t *testing.T, deps *Dependencies) DiagnosticsResponse {
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/diagnostics", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response DiagnosticsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response
+	}
+
+	// setenv configures every required setting
+	setenv := func(t *testing.T) {
+		t.Setenv("FUNCTION_URL", "https://example.run.app")
+		t.Setenv("GITHUB_TOKEN", "test-token")
+		t.Setenv("REPO_OWNER", "octo")
+		t.Setenv("REPO_NAME", "site")
+		t.Setenv("SUBSCRIPTION_BUCKET", "bucket")
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		setenv(t)
+		deps := CreateTestDependencies()
+
+		response := diagnostics(t, deps)
+
+		assert.Equal(t, ProbeStatusOK, response.Status)
+		assert.Equal(t, "https://example.run.app", response.CallbackURL)
+		assert.Equal(t, "octo/site", response.Repository)
+		for _, name := range []string{"config", "storage", "github", "function_url"} {
+			assert.Equal(t, ProbeStatusOK, response.Components[name].Status, name)
+		}
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetAccessCallCount())
+	})
+
+	t.Run("github_token_rejected", func(t *testing.T) {
+		setenv(t)
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetAccessError(&GitHubAPIError{StatusCode: http.StatusUnauthorized})
+
+		response := diagnostics(t, deps)
+
+		assert.Equal(t, ProbeStatusError, response.Status)
+		assert.Contains(t, response.Components["github"].Message, "rejected GITHUB_TOKEN")
+	})
+
+	t.Run("repository_not_found", func(t *testing.T) {
+		setenv(t)
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetAccessError(&GitHubAPIError{StatusCode: http.StatusNotFound})
+
+		response := diagnostics(t, deps)
+
+		assert.Contains(t, response.Components["github"].Message, "repository octo/site was not found")
+	})
+
+	t.Run("misconfigured", func(t *testing.T) {
+		setenv(t)
+		t.Setenv("FUNCTION_URL", "")
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetConfigured(false)
+		deps.StorageClient.(*MockStorageClient).LoadError = errors.New("bucket unreachable")
+
+		response := diagnostics(t, deps)
+
+		assert.Equal(t, ProbeStatusError, response.Status)
+		assert.Empty(t, response.CallbackURL)
+		assert.Contains(t, response.Components["config"].Message, "FUNCTION_URL: required but not set")
+		assert.Equal(t, "GITHUB_TOKEN not set", response.Components["github"].Message)
+		assert.Equal(t, "FUNCTION_URL not set", response.Components["function_url"].Message)
+		assert.Contains(t, response.Components["storage"].Message, "bucket unreachable")
+		assert.Zero(t, deps.GitHubClient.(*MockGitHubClient).GetAccessCallCount())
+	})
+
+	t.Run("requires_credentials", func(t *testing.T) {
+		t.Setenv("API_KEYS", "secret-key")
+		SetDependencies(CreateTestDependencies())
+		defer SetDependencies(nil)
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/diagnostics", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
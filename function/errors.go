@@ -1,10 +1,73 @@
 package webhook
 
-import "errors"
+import werrors "github.com/samsoir/youtube-webhook/function/errors"
+
+// The function's errors are defined in package errors; they are repeated here so code
+// in this package and its callers can refer to them as before.
+
+// Notification body errors
+var (
+	ErrBodyRead     = werrors.ErrBodyRead
+	ErrBodyTooLarge = werrors.ErrBodyTooLarge
+	ErrInvalidXML   = werrors.ErrInvalidXML
+)
+
+// XMLError is a notification body that could not be decoded
+type XMLError = werrors.XMLError
 
 // Video processing errors
 var (
-	ErrInvalidEntry     = errors.New("invalid entry")
-	ErrMissingVideoID   = errors.New("missing video ID")
-	ErrMissingChannelID = errors.New("missing channel ID")
+	ErrInvalidEntry     = werrors.ErrInvalidEntry
+	ErrMissingVideoID   = werrors.ErrMissingVideoID
+	ErrMissingChannelID = werrors.ErrMissingChannelID
+	ErrInvalidChannelID = werrors.ErrInvalidChannelID
+	ErrInvalidTimestamp = werrors.ErrInvalidTimestamp
+	ErrChannelMismatch  = werrors.ErrChannelMismatch
+)
+
+// Notification signature errors
+var (
+	ErrMissingSignature    = werrors.ErrMissingSignature
+	ErrInvalidSignature    = werrors.ErrInvalidSignature
+	ErrUnverifiedSignature = werrors.ErrUnverifiedSignature
+)
+
+// Notification source errors, for notifications from outside NOTIFICATION_ALLOWED_IPS or
+// for topics without a subscription
+var (
+	ErrSourceNotAllowed  = werrors.ErrSourceNotAllowed
+	ErrUnsubscribedTopic = werrors.ErrUnsubscribedTopic
 )
+
+// Storage errors
+var (
+	ErrObjectNotFound       = werrors.ErrObjectNotFound
+	ErrObjectNotModified    = werrors.ErrObjectNotModified
+	ErrObjectChanged        = werrors.ErrObjectChanged
+	ErrSubscriptionNotFound = werrors.ErrSubscriptionNotFound
+	ErrStorageUnavailable   = werrors.ErrStorageUnavailable
+	ErrStateBackupNotFound  = werrors.ErrStateBackupNotFound // A backup does not exist or has been pruned
+	ErrDeadLetterNotFound   = werrors.ErrDeadLetterNotFound  // A dead letter does not exist or has expired
+)
+
+// Subscription state schema errors
+var (
+	ErrSchemaTooNew   = werrors.ErrSchemaTooNew
+	ErrSchemaOutdated = werrors.ErrSchemaOutdated
+	ErrSchemaUnknown  = werrors.ErrSchemaUnknown
+)
+
+// YouTube Data API errors, returned when the API does not know a video or channel
+var (
+	ErrVideoNotFound   = werrors.ErrVideoNotFound
+	ErrChannelNotFound = werrors.ErrChannelNotFound
+)
+
+// GitHub dispatch errors
+var ErrDispatchPayloadTooLarge = werrors.ErrDispatchPayloadTooLarge
+
+// ErrHubUnavailable is returned without calling the hub while its circuit breaker is open
+var ErrHubUnavailable = werrors.ErrHubUnavailable
+
+// Deadline budget errors
+var ErrDeadlineBudget = werrors.ErrDeadlineBudget
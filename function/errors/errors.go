@@ -0,0 +1,92 @@
+// Package errors defines the errors the webhook function returns, so callers branch on
+// them with errors.Is and errors.As rather than on their text. Errors are wrapped with
+// %w as they are passed up, and handlers map them to HTTP status codes.
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Notification body errors
+var (
+	ErrBodyRead     = errors.New("failed to read request body")
+	ErrBodyTooLarge = errors.New("notification body too large")
+	ErrInvalidXML   = errors.New("invalid XML")
+)
+
+// Video processing errors
+var (
+	ErrInvalidEntry     = errors.New("invalid entry")
+	ErrMissingVideoID   = errors.New("missing video ID")
+	ErrMissingChannelID = errors.New("missing channel ID")
+	ErrInvalidChannelID = errors.New("invalid channel ID")
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+	ErrChannelMismatch  = errors.New("channel mismatch")
+)
+
+// Notification signature errors
+var (
+	ErrMissingSignature    = errors.New("missing hub signature")
+	ErrInvalidSignature    = errors.New("invalid hub signature")
+	ErrUnverifiedSignature = errors.New("unable to verify signature")
+)
+
+// Notification source errors
+var (
+	ErrSourceNotAllowed  = errors.New("notification source not allowed")
+	ErrUnsubscribedTopic = errors.New("notification topic not subscribed")
+)
+
+// Storage errors
+var (
+	ErrObjectNotFound       = errors.New("object not found")
+	ErrObjectNotModified    = errors.New("object not modified")
+	ErrObjectChanged        = errors.New("object changed")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrStorageUnavailable   = errors.New("storage unavailable") // Storage's circuit breaker is open
+	ErrStateBackupNotFound  = errors.New("state backup not found")
+	ErrDeadLetterNotFound   = errors.New("dead letter not found")
+)
+
+// Subscription state schema errors
+var (
+	ErrSchemaTooNew   = errors.New("subscription state schema is newer than this build")
+	ErrSchemaOutdated = errors.New("refusing to write an outdated subscription state schema")
+	ErrSchemaUnknown  = errors.New("unknown subscription state schema version")
+)
+
+// YouTube Data API errors
+var (
+	ErrVideoNotFound   = errors.New("video not found")
+	ErrChannelNotFound = errors.New("channel not found")
+)
+
+// GitHub dispatch errors
+var ErrDispatchPayloadTooLarge = errors.New("dispatch payload too large")
+
+// ErrHubUnavailable is returned without calling the hub while its circuit breaker is open
+var ErrHubUnavailable = errors.New("hub unavailable")
+
+// Deadline budget errors
+var ErrDeadlineBudget = errors.New("deadline budget exhausted")
+
+// XMLError is a notification body that could not be decoded. It matches ErrInvalidXML.
+type XMLError struct {
+	Err error // The decoder's error
+}
+
+// Error implements the error interface
+func (e *XMLError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrInvalidXML, e.Err)
+}
+
+// Unwrap returns the decoder's error
+func (e *XMLError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrInvalidXML
+func (e *XMLError) Is(target error) bool {
+	return target == ErrInvalidXML
+}
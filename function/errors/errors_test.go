@@ -0,0 +1,38 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMLError(t *testing.T) {
+	err := fmt.Errorf("notification: %w", &XMLError{Err: io.ErrUnexpectedEOF})
+
+	assert.EqualError(t, err, "notification: invalid XML: unexpected EOF")
+	assert.ErrorIs(t, err, ErrInvalidXML)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.NotErrorIs(t, err, ErrInvalidEntry)
+
+	var xmlErr *XMLError
+	require.True(t, errors.As(err, &xmlErr))
+	assert.Equal(t, io.ErrUnexpectedEOF, xmlErr.Err)
+}
+
+func TestSentinelsAreDistinct(t *testing.T) {
+	sentinels := []error{
+		ErrBodyRead, ErrBodyTooLarge, ErrInvalidXML, ErrInvalidEntry, ErrMissingSignature,
+		ErrInvalidSignature, ErrUnverifiedSignature, ErrObjectNotFound, ErrSubscriptionNotFound,
+		ErrStorageUnavailable, ErrHubUnavailable, ErrDeadlineBudget, ErrSourceNotAllowed, ErrUnsubscribedTopic,
+	}
+	for i, err := range sentinels {
+		wrapped := fmt.Errorf("context: %w", err)
+		for j, other := range sentinels {
+			assert.Equal(t, i == j, errors.Is(wrapped, other), "%v is %v", err, other)
+		}
+	}
+}
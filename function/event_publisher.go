@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// VideoEventType identifies video events published to Cloud Pub/Sub
+const VideoEventType = "youtube.video.published"
+
+// EventPublisher publishes an event for each accepted video notification
+type EventPublisher interface {
+	PublishVideoEvent(ctx context.Context, entry *Entry) error
+}
+
+// VideoEvent is the JSON message body published for a new video
+type VideoEvent struct {
+	EventType   string `json:"event_type"`
+	VideoID     string `json:"video_id"`
+	ChannelID   string `json:"channel_id"`
+	PlaylistID  string `json:"playlist_id,omitempty"` // Set when the video came from a playlist's feed
+	Title       string `json:"title"`
+	Published   string `json:"published"`
+	Updated     string `json:"updated"`
+	VideoURL    string `json:"video_url"`
+	DispatchID  string `json:"dispatch_id"`
+	Environment string `json:"environment,omitempty"`
+	EmittedAt   string `json:"emitted_at"`
+}
+
+// newVideoEvent builds the event published for a video entry
+func newVideoEvent(ctx context.Context, entry *Entry) VideoEvent {
+	return VideoEvent{
+		EventType:   VideoEventType,
+		VideoID:     entry.VideoID,
+		ChannelID:   entry.ChannelID,
+		PlaylistID:  entry.PlaylistID,
+		Title:       entry.Title,
+		Published:   entry.Published,
+		Updated:     entry.Updated,
+		VideoURL:    fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.VideoID),
+		DispatchID:  dispatchID(entry),
+		Environment: os.Getenv("ENVIRONMENT"),
+		EmittedAt:   currentTime(ctx).UTC().Format(time.RFC3339),
+	}
+}
+
+// CloudPubSubPublisher publishes video events to a Cloud Pub/Sub topic
+type CloudPubSubPublisher struct {
+	topic   string
+	options []option.ClientOption
+
+	initOnce sync.Once
+	service  *pubsub.Service
+	initErr  error
+}
+
+// NewEventPublisher returns a Cloud Pub/Sub publisher for EVENTS_PUBSUB_TOPIC, or nil when
+// the variable is unset. The topic is either a full "projects/{project}/topics/{topic}" name
+// or a topic ID in GOOGLE_CLOUD_PROJECT.
+func NewEventPublisher() EventPublisher {
+	topic := eventsTopicName(os.Getenv("EVENTS_PUBSUB_TOPIC"), os.Getenv("GOOGLE_CLOUD_PROJECT"))
+	if topic == "" {
+		return nil
+	}
+	return NewCloudPubSubPublisher(topic)
+}
+
+// NewCloudPubSubPublisher creates a publisher for a fully qualified topic name
+func NewCloudPubSubPublisher(topic string, opts ...option.ClientOption) *CloudPubSubPublisher {
+	return &CloudPubSubPublisher{topic: topic, options: opts}
+}
+
+// eventsTopicName qualifies a topic ID with the project; "" when no topic is configured
+func eventsTopicName(topic, projectID string) string {
+	topic = strings.TrimSpace(topic)
+	if topic == "" || strings.HasPrefix(topic, "projects/") {
+		return topic
+	}
+	if projectID == "" {
+		fmt.Printf("EVENTS_PUBSUB_TOPIC %q needs GOOGLE_CLOUD_PROJECT or a full projects/.../topics/... name; video events disabled\n", topic)
+		return ""
+	}
+	return fmt.Sprintf("projects/%s/topics/%s", projectID, topic)
+}
+
+// initialize creates the Pub/Sub client on first use
+func (p *CloudPubSubPublisher) initialize(ctx context.Context) error {
+	p.initOnce.Do(func() {
+		service, err := pubsub.NewService(ctx, p.options...)
+		if err != nil {
+			p.initErr = fmt.Errorf("failed to create pubsub client: %v", err)
+			return
+		}
+		p.service = service
+	})
+	return p.initErr
+}
+
+// PublishVideoEvent publishes the entry as a JSON VideoEvent. The event type, channel and
+// video IDs are also set as message attributes so subscriptions can filter on them.
+func (p *CloudPubSubPublisher) PublishVideoEvent(ctx context.Context, entry *Entry) error {
+	if err := p.initialize(ctx); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(newVideoEvent(ctx, entry))
+	if err != nil {
+		return fmt.Errorf("failed to marshal video event: %v", err)
+	}
+
+	message := &pubsub.PubsubMessage{
+		Data: base64.StdEncoding.EncodeToString(data),
+		Attributes: map[string]string{
+			"event_type": VideoEventType,
+			"channel_id": entry.ChannelID,
+			"video_id":   entry.VideoID,
+		},
+	}
+	request := &pubsub.PublishRequest{Messages: []*pubsub.PubsubMessage{message}}
+	if _, err := p.service.Projects.Topics.Publish(p.topic, request).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %v", p.topic, err)
+	}
+	return nil
+}
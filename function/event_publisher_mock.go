@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+)
+
+// MockEventPublisher implements EventPublisher for testing.
+type MockEventPublisher struct {
+	mu           sync.RWMutex
+	publishError error
+	published    []*Entry
+}
+
+// NewMockEventPublisher creates a new mock event publisher.
+func NewMockEventPublisher() *MockEventPublisher {
+	return &MockEventPublisher{}
+}
+
+// PublishVideoEvent records the entry and returns the configured error.
+func (m *MockEventPublisher) PublishVideoEvent(ctx context.Context, entry *Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.published = append(m.published, entry)
+	return m.publishError
+}
+
+// SetPublishError sets the error to return from PublishVideoEvent.
+func (m *MockEventPublisher) SetPublishError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publishError = err
+}
+
+// GetPublishedEntries returns every entry passed to PublishVideoEvent in order.
+func (m *MockEventPublisher) GetPublishedEntries() []*Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*Entry(nil), m.published...)
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+func TestEventsTopicName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		topic    string
+		project  string
+		expected string
+	}{
+		{"unset", "", "my-project", ""},
+		{"topic_id", "video-events", "my-project", "projects/my-project/topics/video-events"},
+		{"full_name", "projects/other/topics/video-events", "my-project", "projects/other/topics/video-events"},
+		{"topic_id_without_project", "video-events", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, eventsTopicName(tc.topic, tc.project))
+		})
+	}
+}
+
+func TestNewEventPublisher(t *testing.T) {
+	defer os.Unsetenv("EVENTS_PUBSUB_TOPIC")
+
+	os.Unsetenv("EVENTS_PUBSUB_TOPIC")
+	assert.Nil(t, NewEventPublisher(), "no publisher without a topic")
+
+	os.Setenv("EVENTS_PUBSUB_TOPIC", "projects/my-project/topics/video-events")
+	publisher, ok := NewEventPublisher().(*CloudPubSubPublisher)
+	require.True(t, ok)
+	assert.Equal(t, "projects/my-project/topics/video-events", publisher.topic)
+}
+
+func TestCloudPubSubPublisher_PublishVideoEvent(t *testing.T) {
+	entry := newSinkTestEntry()
+
+	t.Run("publishes_structured_event", func(t *testing.T) {
+		var path string
+		var request pubsub.PublishRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			json.NewEncoder(w).Encode(pubsub.PublishResponse{MessageIds: []string{"1"}})
+		}))
+		defer server.Close()
+
+		publisher := NewCloudPubSubPublisher("projects/my-project/topics/video-events",
+			option.WithEndpoint(server.URL), option.WithoutAuthentication())
+		require.NoError(t, publisher.PublishVideoEvent(context.Background(), entry))
+
+		assert.Equal(t, "/v1/projects/my-project/topics/video-events:publish", path)
+		require.Len(t, request.Messages, 1)
+		message := request.Messages[0]
+		assert.Equal(t, map[string]string{
+			"event_type": VideoEventType,
+			"channel_id": entry.ChannelID,
+			"video_id":   entry.VideoID,
+		}, message.Attributes)
+
+		data, err := base64.StdEncoding.DecodeString(message.Data)
+		require.NoError(t, err)
+		var event VideoEvent
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, VideoEventType, event.EventType)
+		assert.Equal(t, entry.VideoID, event.VideoID)
+		assert.Equal(t, entry.Title, event.Title)
+		assert.Equal(t, "https://www.youtube.com/watch?v=sink123", event.VideoURL)
+		assert.Equal(t, dispatchID(entry), event.DispatchID)
+		assert.NotEmpty(t, event.EmittedAt)
+	})
+
+	t.Run("reports_publish_failure", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		publisher := NewCloudPubSubPublisher("projects/my-project/topics/video-events",
+			option.WithEndpoint(server.URL), option.WithoutAuthentication())
+		err := publisher.PublishVideoEvent(context.Background(), entry)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to publish to projects/my-project/topics/video-events")
+	})
+}
+
+func TestNotificationService_PublishesVideoEvents(t *testing.T) {
+	newService := func(publisher EventPublisher, github *MockGitHubClient) *NotificationService {
+		return &NotificationService{
+			VideoProcessor: NewVideoProcessor(),
+			GitHubClient:   github,
+			EventPublisher: publisher,
+			Metrics:        NewMetrics(),
+			RepoOwner:      "test-owner",
+			RepoName:       "test-repo",
+		}
+	}
+	notification := func() *http.Request {
+		now := time.Now()
+		body := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>pubsub123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Pub/Sub Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, sinkChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+		return httptest.NewRequest("POST", "/", strings.NewReader(body))
+	}
+
+	t.Run("published_alongside_dispatch", func(t *testing.T) {
+		publisher := NewMockEventPublisher()
+		github := NewMockGitHubClient()
+
+		result, err := newService(publisher, github).ProcessNotification(notification())
+		require.NoError(t, err)
+		assert.Contains(t, result.Message, "Successfully triggered workflow")
+		require.Len(t, publisher.GetPublishedEntries(), 1)
+		assert.Equal(t, "pubsub123", publisher.GetPublishedEntries()[0].VideoID)
+		assert.Equal(t, 1, github.GetTriggerCallCount())
+	})
+
+	t.Run("publish_failure_does_not_fail_notification", func(t *testing.T) {
+		publisher := NewMockEventPublisher()
+		publisher.SetPublishError(errors.New("topic not found"))
+		github := NewMockGitHubClient()
+
+		result, err := newService(publisher, github).ProcessNotification(notification())
+		require.NoError(t, err)
+		assert.Equal(t, "success", result.Status)
+		assert.Equal(t, 1, github.GetTriggerCallCount())
+	})
+
+	t.Run("published_without_github", func(t *testing.T) {
+		publisher := NewMockEventPublisher()
+		github := NewMockGitHubClient()
+		github.SetConfigured(false)
+
+		_, err := newService(publisher, github).ProcessNotification(notification())
+		require.NoError(t, err)
+		assert.Len(t, publisher.GetPublishedEntries(), 1)
+	})
+}
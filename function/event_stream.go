@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventStreamContentType is the Accept value that makes GET /events stream notification
+// events as server-sent events
+const EventStreamContentType = "text/event-stream"
+
+// NotificationEventType names the server-sent events carrying a NotificationEvent
+const NotificationEventType = "notification"
+
+// defaultEventsHeartbeat is how often an idle event stream sends a comment when
+// EVENTS_HEARTBEAT_SECONDS is unset
+const defaultEventsHeartbeat = 15 * time.Second
+
+// eventIDTimeFormat is RFC 3339 with a fixed width, so event IDs sort as strings in
+// the order they were received
+const eventIDTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// eventSequence numbers the events this instance records, telling apart those received
+// at the same time, such as the entries of one notification
+var eventSequence atomic.Uint64
+
+// eventSubscriberBuffer is how many events a stream may fall behind by before it is
+// ended; the client reconnects with its cursor and catches up from the history
+const eventSubscriberBuffer = 64
+
+// getEventsHeartbeat returns how often idle event streams send a heartbeat, from
+// EVENTS_HEARTBEAT_SECONDS
+func getEventsHeartbeat() time.Duration {
+	return getSecondsEnv("EVENTS_HEARTBEAT_SECONDS", defaultEventsHeartbeat)
+}
+
+// NotificationBroadcaster passes the notification events this instance records to the
+// event streams open on it. Streams only see notifications processed by the instance
+// serving them. A nil *NotificationBroadcaster is valid and broadcasts nothing.
+type NotificationBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan NotificationEvent]struct{}
+	closed      bool
+}
+
+// NewNotificationBroadcaster creates a broadcaster without subscribers
+func NewNotificationBroadcaster() *NotificationBroadcaster {
+	return &NotificationBroadcaster{subscribers: make(map[chan NotificationEvent]struct{})}
+}
+
+// Publish passes event to every subscriber. Subscribers too far behind to take it are
+// dropped, closing their channel, so publishing never waits on a slow client.
+func (b *NotificationBroadcaster) Publish(event NotificationEvent) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now on, and a
+// function that unsubscribes it. The channel is closed when the subscriber falls behind
+// or the broadcaster is closed.
+func (b *NotificationBroadcaster) Subscribe() (<-chan NotificationEvent, func()) {
+	events := make(chan NotificationEvent, eventSubscriberBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(events)
+		return events, func() {}
+	}
+	b.subscribers[events] = struct{}{}
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[events]; ok {
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Close ends every subscription, so open streams finish when the instance shuts down
+func (b *NotificationBroadcaster) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for events := range b.subscribers {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+// wantsEventStream reports whether the client asked for GET /events as a stream
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), EventStreamContentType)
+}
+
+// newEventID returns the ID of an event received at receivedAt: the time followed by
+// this instance's next sequence number, unique and increasing with each event
+func newEventID(receivedAt time.Time) string {
+	return fmt.Sprintf("%s-%010d", receivedAt.UTC().Format(eventIDTimeFormat), eventSequence.Add(1))
+}
+
+// eventID returns the server-sent event ID of a notification event. Events recorded
+// before they were given IDs fall back to their received_at time, which sorts before
+// the IDs of any other event received at the same time.
+func eventID(event NotificationEvent) string {
+	if event.ID != "" {
+		return event.ID
+	}
+	return event.ReceivedAt.UTC().Format(eventIDTimeFormat)
+}
+
+// validEventID reports whether id is an event ID generated by newEventID
+func validEventID(id string) bool {
+	at, sequence, ok := strings.Cut(id, "Z-")
+	if !ok {
+		return false
+	}
+	if _, err := time.Parse(eventIDTimeFormat, at+"Z"); err != nil {
+		return false
+	}
+	_, err := strconv.ParseUint(sequence, 10, 64)
+	return err == nil
+}
+
+// eventCursor is where an event stream resumes: after the event with ID lastID or,
+// without one, after the events received by since
+type eventCursor struct {
+	lastID string
+	since  time.Time
+}
+
+// IsZero reports whether the stream starts with the next event, without a backlog
+func (c eventCursor) IsZero() bool {
+	return c.lastID == "" && c.since.IsZero()
+}
+
+// after reports whether event comes after the cursor
+func (c eventCursor) after(event NotificationEvent) bool {
+	if c.lastID != "" {
+		return eventID(event) > c.lastID
+	}
+	return event.ReceivedAt.After(c.since)
+}
+
+// getEventCursor returns where an event stream resumes: after the Last-Event-ID a
+// reconnecting client sends, or after since. A Last-Event-ID holding a time, as sent
+// by streams before events had their own IDs, is taken as since.
+func getEventCursor(r *http.Request, since time.Time) (eventCursor, error) {
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		return eventCursor{since: since}, nil
+	}
+	if validEventID(lastID) {
+		return eventCursor{lastID: lastID}, nil
+	}
+	at, err := time.Parse(time.RFC3339Nano, lastID)
+	return eventCursor{since: at}, err
+}
+
+// eventStream writes notification events as server-sent events, each with its event
+// ID, the cursor a client resumes from
+type eventStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	replayed   map[string]bool // IDs of the backlog events sent, which live events may repeat
+}
+
+// startEventStream commits a 200 response and returns a stream for its events
+func startEventStream(w http.ResponseWriter) (*eventStream, error) {
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	stream := &eventStream{w: w, controller: http.NewResponseController(w), replayed: make(map[string]bool)}
+	return stream, stream.flush()
+}
+
+// replay writes an event from the backlog, remembering it so it is not sent again live
+func (s *eventStream) replay(event NotificationEvent) error {
+	s.replayed[eventID(event)] = true
+	return s.write(event)
+}
+
+// send writes a live event, unless the backlog already sent it. Events published
+// concurrently may arrive out of ID order, so live events are not compared with the
+// cursor.
+func (s *eventStream) send(event NotificationEvent) error {
+	if s.replayed[eventID(event)] {
+		return nil
+	}
+	return s.write(event)
+}
+
+func (s *eventStream) write(event NotificationEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "id: %s\nevent: %s\ndata: %s\n\n", eventID(event), NotificationEventType, data); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// heartbeat writes a comment, keeping idle connections from being closed by proxies
+func (s *eventStream) heartbeat() error {
+	if _, err := fmt.Fprint(s.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *eventStream) flush() error {
+	if err := s.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// streamEvents answers GET /events with a stream: the events recorded after cursor, if
+// one is given, then each event as this instance records it, until the client goes
+// away, the request's deadline budget runs out or the instance shuts down. Clients
+// reconnect with Last-Event-ID to resume.
+func streamEvents(ctx context.Context, deps *Dependencies, w http.ResponseWriter, channelID string, cursor eventCursor) {
+	if deps.Broadcaster == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, channelID, "Event streaming is not enabled")
+		return
+	}
+
+	// Subscribe before reading the backlog, so no event falls between the two
+	events, unsubscribe := deps.Broadcaster.Subscribe()
+	defer unsubscribe()
+
+	var backlog []NotificationEvent
+	if history := NewNotificationHistory(deps.StorageClient, deps.config()); history != nil && !cursor.IsZero() {
+		channelIDs, err := eventChannels(ctx, deps, channelID)
+		if err == nil {
+			backlog, err = history.Events(ctx, channelIDs, cursor.since, 0)
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID, err.Error())
+			return
+		}
+	}
+
+	stream, err := startEventStream(w)
+	for _, event := range backlog {
+		if err == nil && cursor.after(event) {
+			err = stream.replay(event)
+		}
+	}
+
+	// End the stream cleanly before the platform ends the request
+	var expired <-chan time.Time
+	if remaining, ok := budgetRemaining(ctx); ok {
+		budget := time.NewTimer(remaining)
+		defer budget.Stop()
+		expired = budget.C
+	}
+
+	heartbeat := time.NewTicker(getEventsHeartbeat())
+	defer heartbeat.Stop()
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			return
+		case <-heartbeat.C:
+			err = stream.heartbeat()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if channelID == "" || event.ChannelID == channelID {
+				err = stream.send(event)
+			}
+		}
+	}
+	fmt.Printf("Event stream ended: %v\n", err)
+}
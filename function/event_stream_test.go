@@ -0,0 +1,263 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationBroadcaster(t *testing.T) {
+	var nilBroadcaster *NotificationBroadcaster
+	nilBroadcaster.Publish(NotificationEvent{})
+	nilBroadcaster.Close()
+
+	t.Run("passes_events_to_subscribers", func(t *testing.T) {
+		b := NewNotificationBroadcaster()
+		first, cancelFirst := b.Subscribe()
+		second, cancelSecond := b.Subscribe()
+		defer cancelSecond()
+
+		b.Publish(NotificationEvent{ChannelID: historyTestChannel})
+		assert.Equal(t, historyTestChannel, (<-first).ChannelID)
+		assert.Equal(t, historyTestChannel, (<-second).ChannelID)
+
+		cancelFirst()
+		cancelFirst()
+		_, ok := <-first
+		assert.False(t, ok, "unsubscribing closes the channel")
+		b.Publish(NotificationEvent{ChannelID: eventsOtherChannel})
+		assert.Equal(t, eventsOtherChannel, (<-second).ChannelID)
+	})
+
+	t.Run("drops_slow_subscribers", func(t *testing.T) {
+		b := NewNotificationBroadcaster()
+		events, cancel := b.Subscribe()
+		defer cancel()
+
+		for i := 0; i <= eventSubscriberBuffer; i++ {
+			b.Publish(NotificationEvent{})
+		}
+		received := 0
+		for range events {
+			received++
+		}
+		assert.Equal(t, eventSubscriberBuffer, received, "buffered events are kept, then the channel is closed")
+	})
+
+	t.Run("close_ends_subscriptions", func(t *testing.T) {
+		b := NewNotificationBroadcaster()
+		events, cancel := b.Subscribe()
+		b.Close()
+		cancel()
+		_, ok := <-events
+		assert.False(t, ok)
+
+		late, _ := b.Subscribe()
+		_, ok = <-late
+		assert.False(t, ok, "subscribing after Close ends at once")
+	})
+}
+
+func TestRecordHistory_PublishesEvents(t *testing.T) {
+	broadcaster := NewNotificationBroadcaster()
+	events, cancel := broadcaster.Subscribe()
+	defer cancel()
+
+	// Published even without a history to append to
+	ns := &NotificationService{Broadcaster: broadcaster}
+	entry := &Entry{VideoID: "video1", ChannelID: historyTestChannel, Title: "Live Video"}
+	ns.recordHistory(context.Background(), entry, HistoryDecisionDispatched, &NotificationResult{Message: "done"})
+
+	event := <-events
+	assert.Equal(t, historyTestChannel, event.ChannelID)
+	assert.Equal(t, "video1", event.VideoID)
+	assert.Equal(t, HistoryDecisionDispatched, event.Decision)
+	assert.Equal(t, "done", event.Result)
+}
+
+// sseEvent is a server-sent event read back from a stream
+type sseEvent struct {
+	id    string
+	event NotificationEvent
+}
+
+// readSSEEvents reads n events from a stream, skipping heartbeats
+func readSSEEvents(t *testing.T, scanner *bufio.Scanner, n int) []sseEvent {
+	var events []sseEvent
+	var current sseEvent
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			assert.Equal(t, NotificationEventType, strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.event))
+		case line == "" && current.id != "":
+			events = append(events, current)
+			current = sseEvent{}
+		}
+	}
+	require.Len(t, events, n, "stream ended early: %v", scanner.Err())
+	return events
+}
+
+func TestHandleGetEvents_Stream(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.Broadcaster = NewNotificationBroadcaster()
+	start := seedEvents(t, deps)
+	server := httptest.NewServer(handleGetEvents(deps))
+	defer server.Close()
+
+	open := func(t *testing.T, query string, header http.Header) *bufio.Scanner {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events"+query, nil)
+		require.NoError(t, err)
+		req.Header = header
+		req.Header.Set("Accept", EventStreamContentType)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, EventStreamContentType, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+		return bufio.NewScanner(resp.Body)
+	}
+
+	t.Run("replays_after_last_event_id_then_streams", func(t *testing.T) {
+		header := http.Header{"Last-Event-ID": {start.Format(time.RFC3339Nano)}}
+		scanner := open(t, "", header)
+
+		events := readSSEEvents(t, scanner, 2)
+		assert.Equal(t, "video2", events[0].event.VideoID)
+		assert.Equal(t, start.Add(time.Minute).Format(eventIDTimeFormat), events[0].id, "entries without IDs fall back to received_at")
+		assert.Equal(t, "video3", events[1].event.VideoID)
+
+		// An event already replayed is not sent twice
+		deps.Broadcaster.Publish(NotificationEvent{ChannelID: historyTestChannel,
+			HistoryEntry: HistoryEntry{ReceivedAt: start.Add(2 * time.Minute), VideoID: "video3"}})
+		deps.Broadcaster.Publish(NotificationEvent{ChannelID: eventsOtherChannel,
+			HistoryEntry: HistoryEntry{ReceivedAt: start.Add(3 * time.Minute), VideoID: "video4"}})
+		events = readSSEEvents(t, scanner, 1)
+		assert.Equal(t, "video4", events[0].event.VideoID)
+		assert.Equal(t, eventsOtherChannel, events[0].event.ChannelID)
+	})
+
+	t.Run("filters_by_channel", func(t *testing.T) {
+		scanner := open(t, "?channel_id="+historyTestChannel, http.Header{})
+
+		// Wait for the stream to subscribe before publishing
+		require.Eventually(t, func() bool {
+			deps.Broadcaster.mu.Lock()
+			defer deps.Broadcaster.mu.Unlock()
+			return len(deps.Broadcaster.subscribers) == 1
+		}, time.Second, time.Millisecond)
+
+		deps.Broadcaster.Publish(NotificationEvent{ChannelID: eventsOtherChannel,
+			HistoryEntry: HistoryEntry{ReceivedAt: start.Add(4 * time.Minute), VideoID: "other"}})
+		deps.Broadcaster.Publish(NotificationEvent{ChannelID: historyTestChannel,
+			HistoryEntry: HistoryEntry{ReceivedAt: start.Add(5 * time.Minute), VideoID: "mine"}})
+		events := readSSEEvents(t, scanner, 1)
+		assert.Equal(t, "mine", events[0].event.VideoID, "no backlog without a cursor, and other channels are skipped")
+	})
+
+	t.Run("sends_live_events_out_of_order", func(t *testing.T) {
+		header := http.Header{"Last-Event-ID": {start.Add(5 * time.Minute).Format(time.RFC3339Nano)}}
+		scanner := open(t, "", header)
+		require.Eventually(t, func() bool {
+			deps.Broadcaster.mu.Lock()
+			defer deps.Broadcaster.mu.Unlock()
+			return len(deps.Broadcaster.subscribers) == 1
+		}, time.Second, time.Millisecond)
+
+		// Concurrent notifications can be published in a different order than received
+		deps.Broadcaster.Publish(NotificationEvent{ChannelID: historyTestChannel,
+			HistoryEntry: HistoryEntry{ReceivedAt: start.Add(7 * time.Minute), VideoID: "later"}})
+		deps.Broadcaster.Publish(NotificationEvent{ChannelID: historyTestChannel,
+			HistoryEntry: HistoryEntry{ReceivedAt: start.Add(6 * time.Minute), VideoID: "earlier"}})
+		events := readSSEEvents(t, scanner, 2)
+		assert.Equal(t, "later", events[0].event.VideoID)
+		assert.Equal(t, "earlier", events[1].event.VideoID, "a live event is not dropped for being older than the last one sent")
+	})
+
+	t.Run("resumes_after_the_exact_event", func(t *testing.T) {
+		// The entries of one notification share received_at
+		history := NewNotificationHistory(deps.StorageClient, envConfig())
+		receivedAt := start.Add(10 * time.Minute)
+		first, second := newEventID(receivedAt), newEventID(receivedAt)
+		require.NotEqual(t, first, second)
+		for id, videoID := range map[string]string{first: "batch1", second: "batch2"} {
+			require.NoError(t, history.Append(context.Background(), historyTestChannel,
+				HistoryEntry{ID: id, ReceivedAt: receivedAt, VideoID: videoID}))
+		}
+
+		scanner := open(t, "", http.Header{"Last-Event-ID": {first}})
+		events := readSSEEvents(t, scanner, 1)
+		assert.Equal(t, "batch2", events[0].event.VideoID)
+		assert.Equal(t, second, events[0].id)
+	})
+
+	t.Run("sends_heartbeats", func(t *testing.T) {
+		t.Setenv("EVENTS_HEARTBEAT_SECONDS", "0.01")
+		scanner := open(t, "", http.Header{})
+		require.True(t, scanner.Scan())
+		assert.Equal(t, ": heartbeat", scanner.Text())
+	})
+
+	t.Run("ends_when_the_broadcaster_closes", func(t *testing.T) {
+		closing := CreateTestDependencies()
+		closing.Broadcaster = NewNotificationBroadcaster()
+		closing.Broadcaster.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Accept", EventStreamContentType)
+		rec := httptest.NewRecorder()
+		handleGetEvents(closing)(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+}
+
+func TestHandleGetEvents_StreamErrors(t *testing.T) {
+	serve := func(deps *Dependencies, lastEventID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Accept", EventStreamContentType)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		rec := httptest.NewRecorder()
+		handleGetEvents(deps)(rec, req)
+		return rec
+	}
+
+	deps := CreateTestDependencies()
+	assert.Equal(t, http.StatusNotImplemented, serve(deps, "").Code, "no broadcaster")
+
+	deps.Broadcaster = NewNotificationBroadcaster()
+	assert.Equal(t, http.StatusBadRequest, serve(deps, "42").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(deps, "2026-10-16T09:00:00.000000000Z-x").Code)
+}
+
+func TestNewEventID(t *testing.T) {
+	receivedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	first, second := newEventID(receivedAt), newEventID(receivedAt)
+
+	assert.True(t, strings.HasPrefix(first, "2026-10-16T07:00:00.000000000Z-"))
+	assert.Less(t, first, second, "IDs increase with each event")
+	assert.Less(t, second, newEventID(receivedAt.Add(time.Nanosecond)))
+	assert.Less(t, eventID(NotificationEvent{HistoryEntry: HistoryEntry{ReceivedAt: receivedAt}}), first,
+		"entries without IDs sort before the others received at the same time")
+	assert.True(t, validEventID(first))
+	assert.False(t, validEventID(receivedAt.Format(time.RFC3339Nano)))
+}
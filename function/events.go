@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// defaultEventsLimit is how many events GET /events returns when no limit is given
+const defaultEventsLimit = 100
+
+// NotificationEvent is a processed notification together with the channel it was for
+type NotificationEvent struct {
+	ChannelID string `json:"channel_id"`
+	HistoryEntry
+}
+
+// EventsResponse lists processed notifications across channels, oldest first
+type EventsResponse struct {
+	Status string              `json:"status"`
+	Count  int                 `json:"count"`
+	Events []NotificationEvent `json:"events"`
+	Cursor string              `json:"cursor,omitempty"` // Pass as since to fetch only later events
+}
+
+// Events merges the histories of channelIDs, oldest first. With a non-zero since only
+// events received after it are returned and the oldest limit are kept, so a caller
+// polling with the last event's time sees every event; otherwise the newest limit are.
+func (h *NotificationHistory) Events(ctx context.Context, channelIDs []string, since time.Time, limit int) ([]NotificationEvent, error) {
+	events := make([]NotificationEvent, 0)
+	for _, channelID := range channelIDs {
+		entries, err := h.List(ctx, channelID, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if since.IsZero() || entry.ReceivedAt.After(since) {
+				events = append(events, NotificationEvent{ChannelID: channelID, HistoryEntry: entry})
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if first, second := eventID(events[i]), eventID(events[j]); first != second {
+			return first < second
+		}
+		return events[i].ChannelID < events[j].ChannelID
+	})
+
+	if limit > 0 && len(events) > limit {
+		if since.IsZero() {
+			events = events[len(events)-limit:]
+		} else {
+			events = events[:limit]
+		}
+	}
+	return events, nil
+}
+
+// handleGetEvents handles GET /events requests using dependency injection: the
+// notifications processed for every subscribed channel, or ?channel_id's, merged into
+// one feed. ?since returns only those received after an RFC 3339 time, and ?limit caps
+// how many are returned. Clients accepting text/event-stream get the events as a stream
+// instead; see streamEvents.
+func handleGetEvents(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		channelID := query.Get("channel_id")
+		if channelID != "" && !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		var since time.Time
+		if sinceStr := query.Get("since"); sinceStr != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339Nano, sinceStr); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, channelID, "since must be an RFC 3339 time")
+				return
+			}
+		}
+
+		limit := defaultEventsLimit
+		if limitStr := query.Get("limit"); limitStr != "" {
+			if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit < 1 {
+				writeErrorResponse(w, http.StatusBadRequest, channelID, "limit must be a positive integer")
+				return
+			}
+		}
+
+		if wantsEventStream(r) {
+			cursor, err := getEventCursor(r, since)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, channelID, "Last-Event-ID must be an event ID")
+				return
+			}
+			streamEvents(ctx, deps, w, channelID, cursor)
+			return
+		}
+
+		history := NewNotificationHistory(deps.StorageClient, deps.config())
+		if history == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, channelID,
+				"Notification history is disabled or not supported by the storage backend")
+			return
+		}
+
+		channelIDs, err := eventChannels(ctx, deps, channelID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+
+		events, err := history.Events(ctx, channelIDs, since, limit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID, err.Error())
+			return
+		}
+
+		response := EventsResponse{Status: "success", Count: len(events), Events: events}
+		if len(events) > 0 {
+			response.Cursor = events[len(events)-1].ReceivedAt.Format(time.RFC3339Nano)
+		} else if !since.IsZero() {
+			response.Cursor = since.Format(time.RFC3339Nano)
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+// eventChannels returns the channels GET /events reports on: channelID, or every
+// subscribed channel when it is empty
+func eventChannels(ctx context.Context, deps *Dependencies, channelID string) ([]string, error) {
+	if channelID != "" {
+		return []string{channelID}, nil
+	}
+	state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load subscription state from storage: %v", err)
+	}
+	channelIDs := make([]string, 0, len(state.Subscriptions))
+	for id := range state.Subscriptions {
+		channelIDs = append(channelIDs, id)
+	}
+	return channelIDs, nil
+}
@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eventsOtherChannel = "UC_x5XG1OV2P6uZZ5FSM9Ttw"
+
+// seedEvents records three notifications a minute apart, alternating between two
+// subscribed channels, and returns when the first was received
+func seedEvents(t *testing.T, deps *Dependencies) time.Time {
+	ctx := context.Background()
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{
+		historyTestChannel: {ChannelID: historyTestChannel, Status: StatusActive},
+		eventsOtherChannel: {ChannelID: eventsOtherChannel, Status: StatusActive},
+	}}
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(ctx, state))
+
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	history := NewNotificationHistory(deps.StorageClient, envConfig())
+	for i, channelID := range []string{historyTestChannel, eventsOtherChannel, historyTestChannel} {
+		require.NoError(t, history.Append(ctx, channelID, HistoryEntry{
+			ReceivedAt: start.Add(time.Duration(i) * time.Minute),
+			VideoID:    []string{"video1", "video2", "video3"}[i],
+			Decision:   HistoryDecisionDispatched,
+		}))
+	}
+	return start
+}
+
+func TestNotificationHistory_Events(t *testing.T) {
+	ctx := context.Background()
+	deps := CreateTestDependencies()
+	start := seedEvents(t, deps)
+	history := NewNotificationHistory(deps.StorageClient, envConfig())
+	channels := []string{historyTestChannel, eventsOtherChannel}
+
+	videoIDs := func(events []NotificationEvent) []string {
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.VideoID
+		}
+		return ids
+	}
+
+	t.Run("merges_oldest_first", func(t *testing.T) {
+		events, err := history.Events(ctx, channels, time.Time{}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"video1", "video2", "video3"}, videoIDs(events))
+		assert.Equal(t, eventsOtherChannel, events[1].ChannelID)
+	})
+
+	t.Run("limit_keeps_newest_without_since", func(t *testing.T) {
+		events, err := history.Events(ctx, channels, time.Time{}, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"video2", "video3"}, videoIDs(events))
+	})
+
+	t.Run("since_keeps_oldest_after_it", func(t *testing.T) {
+		events, err := history.Events(ctx, channels, start, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"video2"}, videoIDs(events))
+	})
+
+	t.Run("entries_received_together_keep_their_order", func(t *testing.T) {
+		batch := NewNotificationHistory(NewMockStorageClient(), envConfig())
+		receivedAt := start.Add(time.Hour)
+		for _, videoID := range []string{"first", "second", "third"} {
+			require.NoError(t, batch.Append(ctx, historyTestChannel,
+				HistoryEntry{ID: newEventID(receivedAt), ReceivedAt: receivedAt, VideoID: videoID}))
+		}
+		events, err := batch.Events(ctx, []string{historyTestChannel}, time.Time{}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first", "second", "third"}, videoIDs(events))
+	})
+
+	t.Run("one_channel", func(t *testing.T) {
+		events, err := history.Events(ctx, []string{eventsOtherChannel}, time.Time{}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"video2"}, videoIDs(events))
+	})
+}
+
+func TestEventsEndpoint(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+	start := seedEvents(t, deps)
+
+	get := func(query url.Values) (*httptest.ResponseRecorder, EventsResponse) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/events?"+query.Encode(), nil))
+		var response EventsResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		}
+		return rec, response
+	}
+
+	t.Run("lists_every_channel", func(t *testing.T) {
+		rec, response := get(url.Values{})
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 3, response.Count)
+		assert.Equal(t, "video1", response.Events[0].VideoID)
+		assert.Equal(t, start.Add(2*time.Minute).Format(time.RFC3339Nano), response.Cursor)
+	})
+
+	t.Run("polls_with_cursor", func(t *testing.T) {
+		rec, response := get(url.Values{"limit": {"1"}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "video3", response.Events[0].VideoID)
+
+		rec, response = get(url.Values{"since": {response.Cursor}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, response.Events)
+		assert.Equal(t, start.Add(2*time.Minute).Format(time.RFC3339Nano), response.Cursor, "cursor is kept when nothing is new")
+	})
+
+	t.Run("channel_and_since", func(t *testing.T) {
+		rec, response := get(url.Values{"channel_id": {historyTestChannel}, "since": {start.Format(time.RFC3339)}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, 1, response.Count)
+		assert.Equal(t, "video3", response.Events[0].VideoID)
+		assert.Equal(t, historyTestChannel, response.Events[0].ChannelID)
+	})
+
+	t.Run("invalid_parameters", func(t *testing.T) {
+		for _, query := range []url.Values{
+			{"channel_id": {"bad-id"}},
+			{"since": {"yesterday"}},
+			{"limit": {"0"}},
+		} {
+			rec, _ := get(query)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query.Encode())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		os.Setenv("NOTIFICATION_HISTORY_SIZE", "0")
+		defer os.Unsetenv("NOTIFICATION_HISTORY_SIZE")
+
+		rec, _ := get(url.Values{})
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+}
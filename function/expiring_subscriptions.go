@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ExpiringSubscriptionsResponse is returned by GET /subscriptions/expiring
+type ExpiringSubscriptionsResponse struct {
+	Subscriptions []SubscriptionInfo `json:"subscriptions"` // Soonest expiry first
+	Total         int                `json:"total"`         // Subscriptions expiring within the window, before ?limit
+	WithinHours   float64            `json:"within_hours"`
+	Until         string             `json:"until"` // End of the window
+}
+
+// expiringSubscriptions returns the renewable subscriptions whose lease ends between now
+// and until, soonest expiry first. Pending, expired, quarantined and recovering
+// subscriptions are left out, since a renewal does not act on them.
+func expiringSubscriptions(state *SubscriptionState, now, until time.Time) []*Subscription {
+	var expiring []*Subscription
+	for _, sub := range state.Subscriptions {
+		switch sub.Status {
+		case StatusPending, StatusExpired, StatusQuarantined:
+			continue
+		}
+		if sub.ExpiresAt.IsZero() || sub.ExpiresAt.Before(now) || sub.ExpiresAt.After(until) || recovering(sub) {
+			continue
+		}
+		expiring = append(expiring, sub)
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		if !expiring[i].ExpiresAt.Equal(expiring[j].ExpiresAt) {
+			return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt)
+		}
+		return expiring[i].ChannelID < expiring[j].ChannelID
+	})
+	return expiring
+}
+
+// handleGetExpiringSubscriptions handles GET /subscriptions/expiring requests using
+// dependency injection: the subscriptions whose lease ends within ?within_hours (default
+// RENEWAL_THRESHOLD_HOURS), soonest first, so schedulers can renew just in time without
+// listing every subscription. ?limit caps how many are returned.
+func handleGetExpiringSubscriptions(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		threshold := deps.config().RenewalThreshold
+
+		within := threshold
+		if withinStr := query.Get("within_hours"); withinStr != "" {
+			hours, err := strconv.ParseFloat(withinStr, 64)
+			if err != nil || hours <= 0 || math.IsInf(hours, 0) || math.IsNaN(hours) {
+				writeErrorResponse(w, http.StatusBadRequest, "", "within_hours must be a positive number")
+				return
+			}
+			within = time.Duration(hours * float64(time.Hour))
+		}
+
+		limit := 0
+		if limitStr := query.Get("limit"); limitStr != "" {
+			n, err := strconv.Atoi(limitStr)
+			if err != nil || n < 1 || n > maxSubscriptionsPageSize {
+				writeErrorResponse(w, http.StatusBadRequest, "", fmt.Sprintf("limit must be between 1 and %d", maxSubscriptionsPageSize))
+				return
+			}
+			limit = n
+		}
+
+		state, err := deps.StorageClient.LoadSubscriptionState(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "",
+				fmt.Sprintf("Unable to load subscription state from storage: %v", err))
+			return
+		}
+
+		now := deps.now()
+		until := now.Add(within)
+		expiring := expiringSubscriptions(state, now, until)
+
+		response := ExpiringSubscriptionsResponse{
+			Subscriptions: make([]SubscriptionInfo, 0, len(expiring)),
+			Total:         len(expiring),
+			WithinHours:   within.Hours(),
+			Until:         until.Format(timeFormat()),
+		}
+		if limit > 0 && len(expiring) > limit {
+			expiring = expiring[:limit]
+		}
+		thresholds := healthThresholds(deps.config())
+		for _, sub := range expiring {
+			response.Subscriptions = append(response.Subscriptions, buildSubscriptionInfo(sub, now, thresholds, threshold))
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getExpiring serves GET /subscriptions/expiring with query against the given subscriptions
+func getExpiring(t *testing.T, query string, subs ...*Subscription) (*httptest.ResponseRecorder, ExpiringSubscriptionsResponse) {
+	t.Helper()
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(subs...))
+
+	rec := httptest.NewRecorder()
+	handleGetExpiringSubscriptions(deps)(rec, httptest.NewRequest("GET", "/subscriptions/expiring"+query, nil))
+
+	var response ExpiringSubscriptionsResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	}
+	return rec, response
+}
+
+func TestHandleGetExpiringSubscriptions(t *testing.T) {
+	now := time.Now()
+	subs := []*Subscription{
+		{ChannelID: "UClater", Status: StatusActive, ExpiresAt: now.Add(10 * time.Hour)},
+		{ChannelID: "UCsoon", Status: StatusVerified, ExpiresAt: now.Add(2 * time.Hour)},
+		{ChannelID: "UCtied", Status: StatusActive, ExpiresAt: now.Add(10 * time.Hour)},
+		{ChannelID: "UCfar", Status: StatusActive, ExpiresAt: now.Add(72 * time.Hour)},
+		{ChannelID: "UCpending", Status: StatusPending},
+		{ChannelID: "UClapsed", Status: StatusActive, ExpiresAt: now.Add(-time.Hour)},
+		{ChannelID: "UCquarantined", Status: StatusQuarantined, ExpiresAt: now.Add(time.Hour)},
+		{ChannelID: "UCrecovering", Status: StatusActive, ExpiresAt: now.Add(time.Hour),
+			Recovery: &RecoveryRecord{Reason: RecoveryReasonLapsed, StartedAt: now}},
+	}
+
+	channels := func(response ExpiringSubscriptionsResponse) []string {
+		var ids []string
+		for _, info := range response.Subscriptions {
+			ids = append(ids, info.ChannelID)
+		}
+		return ids
+	}
+
+	t.Run("default_window", func(t *testing.T) {
+		rec, response := getExpiring(t, "", subs...)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"UCsoon", "UClater", "UCtied"}, channels(response), "soonest first, ties by channel")
+		assert.Equal(t, 3, response.Total)
+		assert.Equal(t, 12.0, response.WithinHours)
+		assert.NotEmpty(t, response.Until)
+	})
+
+	t.Run("within_hours", func(t *testing.T) {
+		rec, response := getExpiring(t, "?within_hours=96", subs...)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"UCsoon", "UClater", "UCtied", "UCfar"}, channels(response))
+	})
+
+	t.Run("fractional_hours", func(t *testing.T) {
+		rec, response := getExpiring(t, "?within_hours=2.5", subs...)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"UCsoon"}, channels(response))
+		assert.Equal(t, 2.5, response.WithinHours)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		rec, response := getExpiring(t, "?limit=2", subs...)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"UCsoon", "UClater"}, channels(response))
+		assert.Equal(t, 3, response.Total, "total counts the whole window")
+	})
+
+	t.Run("none", func(t *testing.T) {
+		rec, response := getExpiring(t, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"subscriptions":[]`)
+		assert.Zero(t, response.Total)
+	})
+
+	for _, query := range []string{"?within_hours=0", "?within_hours=-1", "?within_hours=abc", "?within_hours=Inf", "?limit=0", "?limit=501"} {
+		t.Run("invalid"+query, func(t *testing.T) {
+			rec, _ := getExpiring(t, query, subs...)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestYouTubeWebhook_ExpiringRoute(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(
+		&Subscription{ChannelID: routingChannelID, Status: StatusActive, ExpiresAt: time.Now().Add(time.Hour)}))
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/expiring", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "not routed as a channel ID")
+
+	var response ExpiringSubscriptionsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Subscriptions, 1)
+	assert.Equal(t, routingChannelID, response.Subscriptions[0].ChannelID)
+}
@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Limits on filter rules stored with a subscription
+const (
+	maxFilterKeywords     = 20
+	maxTitlePatternLength = 256
+)
+
+// livestreamTitlePattern matches titles that announce a live broadcast. Hub notifications
+// carry no broadcast metadata, so livestream exclusion is best-effort.
+var livestreamTitlePattern = regexp.MustCompile(`(?i)(\blive ?stream(ing)?\b|\[live\]|\(live\)|🔴)`)
+
+// NotificationFilter decides which of a channel's new videos trigger downstream workflows
+type NotificationFilter struct {
+	TitlePattern       string   `json:"title_pattern,omitempty"`    // Title must match this regular expression
+	IncludeKeywords    []string `json:"include_keywords,omitempty"` // Title must contain at least one (case-insensitive)
+	ExcludeKeywords    []string `json:"exclude_keywords,omitempty"` // Title must contain none (case-insensitive)
+	ExcludeShorts      bool     `json:"exclude_shorts,omitempty"`
+	ExcludeLivestreams bool     `json:"exclude_livestreams,omitempty"`
+}
+
+// FilterResponse reports a channel's notification filter
+type FilterResponse struct {
+	Status    string              `json:"status"`
+	ChannelID string              `json:"channel_id"`
+	Filter    *NotificationFilter `json:"filter"` // null when every new video is dispatched
+}
+
+// IsEmpty reports whether the filter has no rules
+func (f *NotificationFilter) IsEmpty() bool {
+	return f == nil || (f.TitlePattern == "" && len(f.IncludeKeywords) == 0 && len(f.ExcludeKeywords) == 0 &&
+		!f.ExcludeShorts && !f.ExcludeLivestreams)
+}
+
+// Validate checks a filter before it is stored
+func (f *NotificationFilter) Validate() error {
+	if len(f.TitlePattern) > maxTitlePatternLength {
+		return fmt.Errorf("title_pattern must be at most %d characters", maxTitlePatternLength)
+	}
+	if _, err := regexp.Compile(f.TitlePattern); err != nil {
+		return fmt.Errorf("invalid title_pattern: %v", err)
+	}
+	if err := validateKeywords("include_keywords", f.IncludeKeywords); err != nil {
+		return err
+	}
+	return validateKeywords("exclude_keywords", f.ExcludeKeywords)
+}
+
+func validateKeywords(name string, keywords []string) error {
+	if len(keywords) > maxFilterKeywords {
+		return fmt.Errorf("at most %d %s are allowed", maxFilterKeywords, name)
+	}
+	for _, keyword := range keywords {
+		if strings.TrimSpace(keyword) == "" {
+			return fmt.Errorf("%s must not contain empty keywords", name)
+		}
+	}
+	return nil
+}
+
+// Rejects returns why the entry is filtered out, or "" if it should be dispatched
+func (f *NotificationFilter) Rejects(entry *Entry) string {
+	if f.IsEmpty() {
+		return ""
+	}
+	title := strings.ToLower(entry.Title)
+
+	if f.ExcludeShorts && isShort(entry) {
+		return "shorts are excluded"
+	}
+	if f.ExcludeLivestreams && isLivestream(entry) {
+		return "livestreams are excluded"
+	}
+	for _, keyword := range f.ExcludeKeywords {
+		if strings.Contains(title, strings.ToLower(strings.TrimSpace(keyword))) {
+			return fmt.Sprintf("title contains excluded keyword %q", keyword)
+		}
+	}
+	if len(f.IncludeKeywords) > 0 && !containsAny(title, f.IncludeKeywords) {
+		return "title contains none of the required keywords"
+	}
+	if f.TitlePattern != "" {
+		// Patterns are validated before they are stored
+		if pattern, err := regexp.Compile(f.TitlePattern); err == nil && !pattern.MatchString(entry.Title) {
+			return fmt.Sprintf("title does not match %q", f.TitlePattern)
+		}
+	}
+	return ""
+}
+
+// isShort reports whether the entry is a YouTube Short
+func isShort(entry *Entry) bool {
+	return strings.Contains(entry.Link.Href, "/shorts/") || strings.Contains(strings.ToLower(entry.Title), "#shorts")
+}
+
+// isLivestream reports whether the entry looks like a live broadcast
+func isLivestream(entry *Entry) bool {
+	return strings.Contains(entry.Link.Href, "/live/") || livestreamTitlePattern.MatchString(entry.Title)
+}
+
+func containsAny(title string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(title, strings.ToLower(strings.TrimSpace(keyword))) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRejection returns why the channel's filter rejects the entry, or "" to dispatch it.
+// Filters that cannot be loaded are logged and the entry is dispatched.
+func filterRejection(ctx context.Context, storage StorageService, entry *Entry) string {
+	if storage == nil {
+		return ""
+	}
+	sub, err := storage.GetSubscription(ctx, entry.SubscriptionID())
+	if err != nil && !errors.Is(err, ErrSubscriptionNotFound) {
+		fmt.Printf("Unable to load notification filter for %s, dispatching: %v\n", entry.SubscriptionID(), err)
+	}
+	if sub == nil {
+		return ""
+	}
+	return sub.Filter.Rejects(entry)
+}
+
+// handleGetFilter handles GET /subscriptions/{channel_id}/filters requests using dependency injection
+func handleGetFilter(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := loadChannelSubscription(w, r, deps, channelID)
+		if !ok {
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, FilterResponse{Status: "success", ChannelID: channelID, Filter: sub.Filter})
+	}
+}
+
+// handleSetFilter handles PUT /subscriptions/{channel_id}/filters requests using dependency injection.
+// The body replaces the channel's filter; an empty filter removes it.
+func handleSetFilter(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		var filter NotificationFilter
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Request body must be a JSON notification filter")
+			return
+		}
+		if err := filter.Validate(); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, err.Error())
+			return
+		}
+
+		sub, ok := loadChannelSubscription(w, r, deps, channelID)
+		if !ok {
+			return
+		}
+		sub.Filter = &filter
+		if filter.IsEmpty() {
+			sub.Filter = nil
+		}
+		saveFilter(w, r, deps, sub)
+	}
+}
+
+// handleDeleteFilter handles DELETE /subscriptions/{channel_id}/filters requests using dependency injection
+func handleDeleteFilter(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := loadChannelSubscription(w, r, deps, channelID)
+		if !ok {
+			return
+		}
+		sub.Filter = nil
+		saveFilter(w, r, deps, sub)
+	}
+}
+
+// loadChannelSubscription loads the subscription a per-channel request reads or changes,
+// writing the error response when it cannot
+func loadChannelSubscription(w http.ResponseWriter, r *http.Request, deps *Dependencies, channelID string) (*Subscription, bool) {
+	if !validateTopicID(channelID) {
+		writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+		return nil, false
+	}
+
+	sub, err := deps.StorageClient.GetSubscription(r.Context(), channelID)
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		writeErrorResponse(w, http.StatusNotFound, channelID, "Subscription not found for this channel")
+		return nil, false
+	}
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, channelID,
+			fmt.Sprintf("Failed to load subscription state: %v", err))
+		return nil, false
+	}
+	return sub, true
+}
+
+// saveFilter stores the subscription and responds with its filter
+func saveFilter(w http.ResponseWriter, r *http.Request, deps *Dependencies, sub *Subscription) {
+	if err := deps.StorageClient.SaveSubscription(r.Context(), sub); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, sub.ChannelID,
+			fmt.Sprintf("Failed to save subscription state: %v", err))
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, FilterResponse{Status: "success", ChannelID: sub.ChannelID, Filter: sub.Filter})
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const filterChannelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+func TestNotificationFilter_Rejects(t *testing.T) {
+	watch := Link{Href: "https://www.youtube.com/watch?v=abc123"}
+
+	testCases := []struct {
+		name     string
+		filter   *NotificationFilter
+		entry    Entry
+		rejected bool
+	}{
+		{"nil_filter", nil, Entry{Title: "Anything #shorts"}, false},
+		{"empty_filter", &NotificationFilter{}, Entry{Title: "Anything"}, false},
+		{"short_by_hashtag", &NotificationFilter{ExcludeShorts: true}, Entry{Title: "Quick tip #Shorts", Link: watch}, true},
+		{"short_by_link", &NotificationFilter{ExcludeShorts: true}, Entry{Title: "Quick tip", Link: Link{Href: "https://www.youtube.com/shorts/abc123"}}, true},
+		{"regular_video_not_short", &NotificationFilter{ExcludeShorts: true}, Entry{Title: "Full tutorial", Link: watch}, false},
+		{"livestream_title", &NotificationFilter{ExcludeLivestreams: true}, Entry{Title: "🔴 Building a compiler"}, true},
+		{"livestream_word", &NotificationFilter{ExcludeLivestreams: true}, Entry{Title: "Friday Livestream: Q&A"}, true},
+		{"livestream_link", &NotificationFilter{ExcludeLivestreams: true}, Entry{Title: "Q&A", Link: Link{Href: "https://www.youtube.com/live/abc123"}}, true},
+		{"not_a_livestream", &NotificationFilter{ExcludeLivestreams: true}, Entry{Title: "Delivering packages"}, false},
+		{"excluded_keyword", &NotificationFilter{ExcludeKeywords: []string{"Sponsored"}}, Entry{Title: "sponsored review"}, true},
+		{"include_keyword_match", &NotificationFilter{IncludeKeywords: []string{"go", "rust"}}, Entry{Title: "Learning Rust"}, false},
+		{"include_keyword_missing", &NotificationFilter{IncludeKeywords: []string{"go", "rust"}}, Entry{Title: "Python tips"}, true},
+		{"title_pattern_match", &NotificationFilter{TitlePattern: `^Episode \d+`}, Entry{Title: "Episode 42: Filters"}, false},
+		{"title_pattern_miss", &NotificationFilter{TitlePattern: `^Episode \d+`}, Entry{Title: "Bonus: Filters"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := tc.filter.Rejects(&tc.entry)
+			if tc.rejected {
+				assert.NotEmpty(t, reason)
+			} else {
+				assert.Empty(t, reason)
+			}
+		})
+	}
+}
+
+func TestNotificationFilter_Validate(t *testing.T) {
+	tooMany := make([]string, maxFilterKeywords+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("keyword-%d", i)
+	}
+
+	testCases := []struct {
+		name        string
+		filter      NotificationFilter
+		expectError bool
+	}{
+		{"empty", NotificationFilter{}, false},
+		{"valid", NotificationFilter{TitlePattern: `(?i)episode`, ExcludeKeywords: []string{"ad"}, ExcludeShorts: true}, false},
+		{"invalid_pattern", NotificationFilter{TitlePattern: `(unclosed`}, true},
+		{"pattern_too_long", NotificationFilter{TitlePattern: strings.Repeat("a", maxTitlePatternLength+1)}, true},
+		{"blank_keyword", NotificationFilter{IncludeKeywords: []string{"go", " "}}, true},
+		{"too_many_keywords", NotificationFilter{ExcludeKeywords: tooMany}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.filter.Validate()
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFilterHandlers(t *testing.T) {
+	newDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: filterChannelID}))
+		return deps
+	}
+	call := func(deps *Dependencies, method, body string) (*httptest.ResponseRecorder, FilterResponse) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest(method, "/subscriptions/"+filterChannelID+"/filters", strings.NewReader(body)))
+		var response FilterResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	t.Run("set_get_delete", func(t *testing.T) {
+		deps := newDeps()
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		rec, response := call(deps, "PUT", `{"title_pattern":"^Episode","exclude_shorts":true}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, response.Filter)
+		assert.True(t, response.Filter.ExcludeShorts)
+
+		stored := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[filterChannelID]
+		require.NotNil(t, stored.Filter)
+		assert.Equal(t, "^Episode", stored.Filter.TitlePattern)
+
+		rec, response = call(deps, "GET", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, response.Filter)
+		assert.Equal(t, "^Episode", response.Filter.TitlePattern)
+
+		rec, response = call(deps, "DELETE", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, response.Filter)
+		assert.Nil(t, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[filterChannelID].Filter)
+	})
+
+	t.Run("empty_filter_clears", func(t *testing.T) {
+		deps := newDeps()
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		call(deps, "PUT", `{"exclude_livestreams":true}`)
+		rec, response := call(deps, "PUT", `{}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, response.Filter)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		deps := newDeps()
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		rec, _ := call(deps, "PUT", `{"title_pattern":"("}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec, _ = call(deps, "PUT", `not json`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec, _ = call(deps, "POST", `{}`)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+		rec = httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/UCabcdefghijklmnopqrstuv/filters", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("DELETE", "/subscriptions/bogus/filters", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		deps.StorageClient.(*MockStorageClient).SaveError = ErrMockSaveFailure
+		rec, _ = call(deps, "PUT", `{"exclude_shorts":true}`)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestHandleNotification_Filters(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	notify := func(deps *Dependencies, videoID, title, link string) *httptest.ResponseRecorder {
+		now := time.Now()
+		body := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>%s</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>%s</title>
+    <link rel="alternate" href="%s"/>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, videoID, filterChannelID, title, link, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return rec
+	}
+
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID: filterChannelID,
+		Filter:    &NotificationFilter{ExcludeShorts: true, ExcludeKeywords: []string{"sponsored"}},
+	}))
+	gh := deps.GitHubClient.(*MockGitHubClient)
+
+	rec := notify(deps, "short1", "Quick tip", "https://www.youtube.com/shorts/short1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Filtered: shorts are excluded (VideoID: short1)", rec.Body.String())
+
+	rec = notify(deps, "ad1", "Sponsored unboxing", "https://www.youtube.com/watch?v=ad1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Filtered: title contains excluded keyword")
+	assert.Equal(t, 0, gh.GetTriggerCallCount())
+
+	rec = notify(deps, "video1", "Full tutorial", "https://www.youtube.com/watch?v=video1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Successfully triggered workflow")
+	assert.Equal(t, 1, gh.GetTriggerCallCount())
+}
@@ -0,0 +1,509 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreStorageService stores each subscription as its own Firestore document,
+// so single-channel updates don't rewrite the whole state
+type FirestoreStorageService struct {
+	client     *firestore.Client
+	projectID  string
+	database   string
+	collection string
+
+	// Initialization
+	initOnce sync.Once
+	initErr  error
+}
+
+// NewFirestoreStorageService creates a Firestore storage service configured from the environment.
+// The project comes from GOOGLE_CLOUD_PROJECT, the database from FIRESTORE_DATABASE and the
+// collection from FIRESTORE_COLLECTION (default "subscriptions").
+func NewFirestoreStorageService() *FirestoreStorageService {
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if projectID == "" {
+		projectID = firestore.DetectProjectID
+	}
+
+	database := os.Getenv("FIRESTORE_DATABASE")
+	if database == "" {
+		database = firestore.DefaultDatabaseID
+	}
+
+	collection := os.Getenv("FIRESTORE_COLLECTION")
+	if collection == "" {
+		collection = "subscriptions"
+	}
+
+	return &FirestoreStorageService{
+		projectID:  projectID,
+		database:   database,
+		collection: collection,
+	}
+}
+
+// initialize creates the Firestore client on first use
+func (f *FirestoreStorageService) initialize(ctx context.Context) error {
+	f.initOnce.Do(func() {
+		if f.client != nil {
+			return
+		}
+		client, err := firestore.NewClientWithDatabase(ctx, f.projectID, f.database)
+		if err != nil {
+			f.initErr = fmt.Errorf("failed to create firestore client: %v", err)
+			return
+		}
+		f.client = client
+	})
+	return f.initErr
+}
+
+// subscriptions returns the collection holding one document per channel
+func (f *FirestoreStorageService) subscriptions() *firestore.CollectionRef {
+	return f.client.Collection(f.collection)
+}
+
+// removed returns the collection holding one document per unsubscribed channel kept for restoring
+func (f *FirestoreStorageService) removed() *firestore.CollectionRef {
+	return f.client.Collection(f.collection + "_removed")
+}
+
+// metadataDoc returns the document holding the state metadata
+func (f *FirestoreStorageService) metadataDoc() *firestore.DocumentRef {
+	return f.client.Collection(f.collection + "_meta").Doc("state")
+}
+
+// LoadSubscriptionState reads every subscription document
+func (f *FirestoreStorageService) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
+	if err := f.initialize(ctx); err != nil {
+		return nil, err
+	}
+
+	state := &SubscriptionState{Subscriptions: make(map[string]*Subscription)}
+
+	iter := f.subscriptions().Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subscriptions: %v", err)
+		}
+
+		subscription, err := documentToSubscription(doc.Data())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode subscription %s: %v", doc.Ref.ID, err)
+		}
+		state.Subscriptions[doc.Ref.ID] = subscription
+	}
+
+	removedDocs, err := f.removed().Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list removed subscriptions: %v", err)
+	}
+	for _, doc := range removedDocs {
+		var removed RemovedSubscription
+		if err := fromDocument(doc.Data(), &removed); err != nil {
+			return nil, fmt.Errorf("failed to decode removed subscription %s: %v", doc.Ref.ID, err)
+		}
+		if state.Removed == nil {
+			state.Removed = make(map[string]*RemovedSubscription)
+		}
+		state.Removed[doc.Ref.ID] = &removed
+	}
+
+	meta, err := f.metadataDoc().Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, fmt.Errorf("failed to get state metadata: %v", err)
+	}
+	if err == nil {
+		if version, ok := meta.Data()["version"].(string); ok && version != "" {
+			state.Metadata.Version = version
+		}
+		if updated, ok := meta.Data()["last_updated"].(time.Time); ok {
+			state.Metadata.LastUpdated = updated
+		}
+	}
+	if state.Metadata.Version == "" && len(state.Subscriptions) == 0 && len(state.Removed) == 0 {
+		// Nothing stored yet, so there is nothing to migrate
+		state.Metadata.Version = CurrentSchemaVersion
+	}
+
+	// Bring documents written by older builds up to the current schema
+	if _, err := migrateSubscriptionState(state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// SaveSubscriptionState writes every subscription and deletes documents no longer in the state
+func (f *FirestoreStorageService) SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	state.Metadata.LastUpdated = currentTime(ctx)
+	if err := stampSchemaVersion(state); err != nil {
+		return err
+	}
+
+	existing, err := f.subscriptions().Select().Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions: %v", err)
+	}
+
+	writer := f.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for _, doc := range existing {
+		if _, keep := state.Subscriptions[doc.Ref.ID]; !keep {
+			job, err := writer.Delete(doc.Ref)
+			if err != nil {
+				writer.End()
+				return fmt.Errorf("failed to delete subscription %s: %v", doc.Ref.ID, err)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	for channelID, subscription := range state.Subscriptions {
+		data, err := subscriptionToDocument(subscription)
+		if err != nil {
+			writer.End()
+			return fmt.Errorf("failed to encode subscription %s: %v", channelID, err)
+		}
+		job, err := writer.Set(f.subscriptions().Doc(channelID), data)
+		if err != nil {
+			writer.End()
+			return fmt.Errorf("failed to save subscription %s: %v", channelID, err)
+		}
+		jobs = append(jobs, job)
+	}
+	removedJobs, err := f.writeRemoved(ctx, writer, state.Removed)
+	if err != nil {
+		writer.End()
+		return err
+	}
+	jobs = append(jobs, removedJobs...)
+	job, err := writer.Set(f.metadataDoc(), map[string]interface{}{
+		"version":      state.Metadata.Version,
+		"last_updated": state.Metadata.LastUpdated,
+	})
+	if err != nil {
+		writer.End()
+		return fmt.Errorf("failed to save state metadata: %v", err)
+	}
+	jobs = append(jobs, job)
+	writer.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return fmt.Errorf("failed to save subscription state: %v", err)
+		}
+	}
+
+	// Keep a copy of every saved state for restoring
+	backupSavedState(ctx, f, state)
+	return nil
+}
+
+// writeRemoved queues writes of the removed subscriptions and deletes of the removed
+// documents no longer kept
+func (f *FirestoreStorageService) writeRemoved(ctx context.Context, writer *firestore.BulkWriter,
+	removed map[string]*RemovedSubscription) ([]*firestore.BulkWriterJob, error) {
+	existing, err := f.removed().Select().Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list removed subscriptions: %v", err)
+	}
+
+	var jobs []*firestore.BulkWriterJob
+	for _, doc := range existing {
+		if _, keep := removed[doc.Ref.ID]; !keep {
+			job, err := writer.Delete(doc.Ref)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete removed subscription %s: %v", doc.Ref.ID, err)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	for channelID, entry := range removed {
+		data, err := toDocument(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode removed subscription %s: %v", channelID, err)
+		}
+		job, err := writer.Set(f.removed().Doc(channelID), data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save removed subscription %s: %v", channelID, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// GetSubscription reads a single subscription document
+func (f *FirestoreStorageService) GetSubscription(ctx context.Context, channelID string) (*Subscription, error) {
+	if err := f.initialize(ctx); err != nil {
+		return nil, err
+	}
+
+	doc, err := f.subscriptions().Doc(channelID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %v", err)
+	}
+	return documentToSubscription(doc.Data())
+}
+
+// SaveSubscription writes a single subscription document
+func (f *FirestoreStorageService) SaveSubscription(ctx context.Context, subscription *Subscription) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	data, err := subscriptionToDocument(subscription)
+	if err != nil {
+		return fmt.Errorf("failed to encode subscription: %v", err)
+	}
+	if _, err := f.subscriptions().Doc(subscription.ChannelID).Set(ctx, data); err != nil {
+		return fmt.Errorf("failed to save subscription: %v", err)
+	}
+	return nil
+}
+
+// DeleteSubscription deletes a single subscription document
+func (f *FirestoreStorageService) DeleteSubscription(ctx context.Context, channelID string) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	if _, err := f.subscriptions().Doc(channelID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete subscription: %v", err)
+	}
+	return nil
+}
+
+// StreamSubscriptions reads the subscription documents one at a time, by channel ID
+func (f *FirestoreStorageService) StreamSubscriptions(ctx context.Context, fn func(*Subscription) error) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	var version string
+	meta, err := f.metadataDoc().Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("failed to get state metadata: %v", err)
+	}
+	if err == nil {
+		version, _ = meta.Data()["version"].(string)
+	}
+
+	iter := f.subscriptions().Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list subscriptions: %v", err)
+		}
+
+		subscription, err := documentToSubscription(doc.Data())
+		if err != nil {
+			return fmt.Errorf("failed to decode subscription %s: %v", doc.Ref.ID, err)
+		}
+		// Bring documents written by older builds up to the current schema
+		if subscription, err = migrateSubscription(doc.Ref.ID, subscription, version); err != nil {
+			return err
+		}
+		if err := fn(subscription); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadObject reads an auxiliary object stored alongside the subscriptions
+func (f *FirestoreStorageService) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	if err := f.initialize(ctx); err != nil {
+		return nil, err
+	}
+
+	doc, err := f.client.Collection(f.collection + "_objects").Doc(objectDocumentID(name)).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %v", err)
+	}
+
+	data, ok := doc.Data()["data"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("object %s has no data", name)
+	}
+	return data, nil
+}
+
+// WriteObject writes an auxiliary object stored alongside the subscriptions
+func (f *FirestoreStorageService) WriteObject(ctx context.Context, name string, data []byte) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	doc := f.client.Collection(f.collection + "_objects").Doc(objectDocumentID(name))
+	if _, err := doc.Set(ctx, map[string]interface{}{"name": name, "data": data}); err != nil {
+		return fmt.Errorf("failed to put object: %v", err)
+	}
+	return nil
+}
+
+// CreateObject writes an auxiliary object stored alongside the subscriptions unless it
+// exists
+func (f *FirestoreStorageService) CreateObject(ctx context.Context, name string, data []byte) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	doc := f.client.Collection(f.collection + "_objects").Doc(objectDocumentID(name))
+	_, err := doc.Create(ctx, map[string]interface{}{"name": name, "data": data})
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrObjectChanged
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create object: %v", err)
+	}
+	return nil
+}
+
+// ReadObjectGeneration reads an auxiliary object stored alongside the subscriptions,
+// with its update time as its generation
+func (f *FirestoreStorageService) ReadObjectGeneration(ctx context.Context, name string) ([]byte, int64, error) {
+	if err := f.initialize(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	doc, err := f.client.Collection(f.collection + "_objects").Doc(objectDocumentID(name)).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, 0, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get object: %v", err)
+	}
+
+	data, ok := doc.Data()["data"].([]byte)
+	if !ok {
+		return nil, 0, fmt.Errorf("object %s has no data", name)
+	}
+	return data, doc.UpdateTime.UnixNano(), nil
+}
+
+// WriteObjectIfGeneration writes an auxiliary object stored alongside the
+// subscriptions while it was last updated at generation
+func (f *FirestoreStorageService) WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error {
+	if generation == 0 {
+		return f.CreateObject(ctx, name, data)
+	}
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	doc := f.client.Collection(f.collection + "_objects").Doc(objectDocumentID(name))
+	_, err := doc.Update(ctx, []firestore.Update{{Path: "name", Value: name}, {Path: "data", Value: data}},
+		firestore.LastUpdateTime(time.Unix(0, generation)))
+	if code := status.Code(err); code == codes.FailedPrecondition || code == codes.NotFound {
+		return ErrObjectChanged
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put object: %v", err)
+	}
+	return nil
+}
+
+// DeleteObject removes an auxiliary object stored alongside the subscriptions
+func (f *FirestoreStorageService) DeleteObject(ctx context.Context, name string) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	if _, err := f.client.Collection(f.collection + "_objects").Doc(objectDocumentID(name)).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete object: %v", err)
+	}
+	return nil
+}
+
+// Ping checks the Firestore database can be read
+func (f *FirestoreStorageService) Ping(ctx context.Context) error {
+	if err := f.initialize(ctx); err != nil {
+		return err
+	}
+
+	if _, err := f.metadataDoc().Get(ctx); err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("failed to reach firestore: %v", err)
+	}
+	return nil
+}
+
+// Close closes the Firestore client
+func (f *FirestoreStorageService) Close() error {
+	if f.client != nil {
+		return f.client.Close()
+	}
+	return nil
+}
+
+// subscriptionToDocument converts a subscription into Firestore fields named
+// after its JSON tags, so documents match the GCS state file format
+func subscriptionToDocument(subscription *Subscription) (map[string]interface{}, error) {
+	return toDocument(subscription)
+}
+
+// documentToSubscription converts Firestore fields back into a subscription
+func documentToSubscription(fields map[string]interface{}) (*Subscription, error) {
+	var subscription Subscription
+	if err := fromDocument(fields, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// toDocument converts a value to Firestore fields using its JSON field names
+func toDocument(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// fromDocument converts Firestore fields back into the value v points to
+func fromDocument(fields map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// objectDocumentID maps an object path to a valid document ID ("/" is not allowed)
+func objectDocumentID(name string) string {
+	return strings.ReplaceAll(name, "/", "__")
+}
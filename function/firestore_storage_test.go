@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFirestoreStorageService(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		os.Unsetenv("GOOGLE_CLOUD_PROJECT")
+		os.Unsetenv("FIRESTORE_DATABASE")
+		os.Unsetenv("FIRESTORE_COLLECTION")
+
+		service := NewFirestoreStorageService()
+		assert.Equal(t, firestore.DetectProjectID, service.projectID)
+		assert.Equal(t, firestore.DefaultDatabaseID, service.database)
+		assert.Equal(t, "subscriptions", service.collection)
+		assert.NoError(t, service.Close())
+	})
+
+	t.Run("from_environment", func(t *testing.T) {
+		os.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+		os.Setenv("FIRESTORE_DATABASE", "webhook-db")
+		os.Setenv("FIRESTORE_COLLECTION", "yt_subscriptions")
+		defer func() {
+			os.Unsetenv("GOOGLE_CLOUD_PROJECT")
+			os.Unsetenv("FIRESTORE_DATABASE")
+			os.Unsetenv("FIRESTORE_COLLECTION")
+		}()
+
+		service := NewFirestoreStorageService()
+		assert.Equal(t, "my-project", service.projectID)
+		assert.Equal(t, "webhook-db", service.database)
+		assert.Equal(t, "yt_subscriptions", service.collection)
+	})
+}
+
+func TestFirestoreDocumentConversion(t *testing.T) {
+	now := time.Date(2025, 1, 21, 10, 30, 0, 0, time.UTC)
+	original := &Subscription{
+		ChannelID:          "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Status:             "active",
+		LeaseSeconds:       86400,
+		SubscribedAt:       now,
+		ExpiresAt:          now.Add(24 * time.Hour),
+		LastNotificationAt: now,
+		LastRunID:          12345678901,
+		Secret:             "secret",
+	}
+
+	fields, err := subscriptionToDocument(original)
+	require.NoError(t, err)
+	assert.Equal(t, "UCXuqSBlHAE6Xw-yeJA0Tunw", fields["channel_id"], "fields use the JSON names")
+	assert.Equal(t, "2025-01-22T10:30:00Z", fields["expires_at"])
+
+	restored, err := documentToSubscription(fields)
+	require.NoError(t, err)
+	assert.Equal(t, original, restored)
+}
+
+func TestObjectDocumentID(t *testing.T) {
+	assert.Equal(t, "debug__notifications.json", objectDocumentID("debug/notifications.json"))
+	assert.Equal(t, "plain", objectDocumentID("plain"))
+}
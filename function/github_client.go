@@ -2,11 +2,20 @@ package webhook
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // GitHubClient handles GitHub API interactions
@@ -14,8 +23,63 @@ type GitHubClient struct {
 	Token   string
 	BaseURL string
 	Client  *http.Client
+
+	// Retries of transient GitHub errors; a zero maxAttempts makes a single attempt
+	maxAttempts   int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+}
+
+// defaultGitHubAPITimeout bounds each GitHub API call unless GITHUB_API_TIMEOUT_SECONDS is set
+const defaultGitHubAPITimeout = 30 * time.Second
+
+// getSecondsEnv reads a whole number of seconds from an environment variable,
+// falling back to defaultValue when it is unset or not a positive number
+func getSecondsEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	if seconds, err := time.ParseDuration(value + "s"); err == nil && seconds > 0 {
+		return seconds
+	}
+	return defaultValue
+}
+
+// Transient GitHub errors are retried with exponential backoff unless GITHUB_MAX_ATTEMPTS,
+// GITHUB_RETRY_DELAY_MS or GITHUB_MAX_RETRY_DELAY_SECONDS say otherwise
+const (
+	defaultGitHubMaxAttempts   = 3
+	defaultGitHubRetryDelay    = 500 * time.Millisecond
+	defaultGitHubMaxRetryDelay = 30 * time.Second
+)
+
+// GitHubAPIError is a non-2xx response from the GitHub API
+type GitHubAPIError struct {
+	StatusCode int
+	Retries    int       // Transient errors retried before this response
+	RetryAt    time.Time // When GitHub said the request can be retried, if it did
 }
 
+func (e *GitHubAPIError) Error() string {
+	message := fmt.Sprintf("GitHub API returned status %d", e.StatusCode)
+	if e.Retries > 0 {
+		message += fmt.Sprintf(" (after %d retries)", e.Retries)
+	}
+	if !e.RetryAt.IsZero() {
+		message += fmt.Sprintf("; retry after %s", e.RetryAt.UTC().Format(time.RFC3339))
+	}
+	return message
+}
+
+// maxDispatchPayloadLength is the most characters GitHub accepts in a repository_dispatch
+// client_payload or in a workflow_dispatch's inputs; larger requests fail with 422
+const maxDispatchPayloadLength = 65535
+
+// maxDispatchTitleLength is the longest video title sent with a dispatch, in characters
+const maxDispatchTitleLength = 1024
+
 // NewGitHubClient creates a new GitHub API client
 func NewGitHubClient() *GitHubClient {
 	token := os.Getenv("GITHUB_TOKEN")
@@ -27,7 +91,11 @@ func NewGitHubClient() *GitHubClient {
 	return &GitHubClient{
 		Token:   token,
 		BaseURL: baseURL,
-		Client:  &http.Client{Timeout: 30 * time.Second},
+		Client:  &http.Client{Timeout: getSecondsEnv("GITHUB_API_TIMEOUT_SECONDS", defaultGitHubAPITimeout)},
+
+		maxAttempts:   getIntEnv("GITHUB_MAX_ATTEMPTS", defaultGitHubMaxAttempts),
+		retryDelay:    time.Duration(getIntEnv("GITHUB_RETRY_DELAY_MS", int(defaultGitHubRetryDelay/time.Millisecond))) * time.Millisecond,
+		maxRetryDelay: getSecondsEnv("GITHUB_MAX_RETRY_DELAY_SECONDS", defaultGitHubMaxRetryDelay),
 	}
 }
 
@@ -36,8 +104,9 @@ func (gc *GitHubClient) IsConfigured() bool {
 	return gc.Token != ""
 }
 
-// TriggerWorkflow sends a repository dispatch event to trigger a GitHub workflow
-func (gc *GitHubClient) TriggerWorkflow(repoOwner, repoName string, entry *Entry) error {
+// TriggerWorkflow sends a repository dispatch event to trigger a GitHub workflow.
+// extra holds the route's fields added to the video fields in client_payload.
+func (gc *GitHubClient) TriggerWorkflow(ctx context.Context, repoOwner, repoName string, entry *Entry, extra map[string]interface{}) error {
 	if gc.Token == "" || repoOwner == "" || repoName == "" {
 		return fmt.Errorf("missing required parameters for GitHub workflow trigger")
 	}
@@ -46,50 +115,295 @@ func (gc *GitHubClient) TriggerWorkflow(repoOwner, repoName string, entry *Entry
 
 	// Create dispatch payload
 	dispatch := GitHubDispatch{
-		EventType: "youtube-video-published",
-		ClientPayload: map[string]interface{}{
-			"video_id":    entry.VideoID,
-			"channel_id":  entry.ChannelID,
-			"title":       entry.Title,
-			"published":   entry.Published,
-			"updated":     entry.Updated,
-			"video_url":   fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.VideoID),
-			"environment": environment,
-		},
+		EventType:     "youtube-video-published",
+		ClientPayload: make(map[string]interface{}),
+	}
+	for field, value := range videoFields(entry, environment) {
+		// GitHub accepts at most 10 client_payload properties, all of them used with the
+		// route's ref and payload; playlist_id is only sent as a workflow input
+		if field == "playlist_id" {
+			continue
+		}
+		dispatch.ClientPayload[field] = value
+	}
+	for field, value := range extra {
+		dispatch.ClientPayload[field] = value
+	}
+	if err := checkDispatchPayload("client_payload", dispatch.ClientPayload); err != nil {
+		return err
+	}
+
+	return gc.sendDispatch(ctx, repoOwner, repoName, dispatch)
+}
+
+// SendTestDispatch sends a clearly-labeled test event so operators can check that
+// the target repository accepts dispatches without triggering the real workflow
+func (gc *GitHubClient) SendTestDispatch(ctx context.Context, repoOwner, repoName string) error {
+	if gc.Token == "" || repoOwner == "" || repoName == "" {
+		return fmt.Errorf("missing required parameters for GitHub test dispatch")
+	}
+
+	dispatch := GitHubDispatch{
+		EventType:     TestEventType,
+		ClientPayload: sampleTestPayload(ctx),
+	}
+
+	return gc.sendDispatch(ctx, repoOwner, repoName, dispatch)
+}
+
+// CheckAccess makes a read-only API call to check GITHUB_TOKEN is accepted: a lookup of
+// the repository when one is given, otherwise of the token's rate limit, which does not
+// count against it
+func (gc *GitHubClient) CheckAccess(ctx context.Context, repoOwner, repoName string) error {
+	if gc.Token == "" {
+		return fmt.Errorf("missing required parameters for GitHub access check")
 	}
 
-	return gc.sendDispatch(repoOwner, repoName, dispatch)
+	url := gc.BaseURL + "/rate_limit"
+	if repoOwner != "" && repoName != "" {
+		url = fmt.Sprintf("%s/repos/%s/%s", gc.BaseURL, repoOwner, repoName)
+	}
+	resp, err := gc.send(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DispatchWorkflow runs a workflow that has a workflow_dispatch trigger on the given ref
+func (gc *GitHubClient) DispatchWorkflow(ctx context.Context, repoOwner, repoName, workflow, ref string, inputs map[string]string) error {
+	if gc.Token == "" || repoOwner == "" || repoName == "" || workflow == "" || ref == "" {
+		return fmt.Errorf("missing required parameters for GitHub workflow dispatch")
+	}
+
+	if err := checkDispatchPayload("inputs", inputs); err != nil {
+		return err
+	}
+
+	dispatch := WorkflowDispatch{Ref: ref, Inputs: inputs}
+	url := fmt.Sprintf("%s/repos/%s/%s/actions/workflows/%s/dispatches", gc.BaseURL, repoOwner, repoName, workflow)
+	return gc.postJSON(ctx, url, dispatch)
+}
+
+// checkDispatchPayload returns ErrDispatchPayloadTooLarge when payload, encoded as JSON,
+// is longer than GitHub accepts, so the dispatch fails with the size rather than an
+// opaque 422 from GitHub
+func checkDispatchPayload(name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	if length := utf8.RuneCount(data); length > maxDispatchPayloadLength {
+		return fmt.Errorf("%w: %s is %d characters, GitHub accepts at most %d",
+			ErrDispatchPayloadTooLarge, name, length, maxDispatchPayloadLength)
+	}
+	return nil
 }
 
 // sendDispatch performs the actual HTTP request to GitHub API
-func (gc *GitHubClient) sendDispatch(repoOwner, repoName string, dispatch GitHubDispatch) error {
+func (gc *GitHubClient) sendDispatch(ctx context.Context, repoOwner, repoName string, dispatch GitHubDispatch) error {
+	return gc.postJSON(ctx, fmt.Sprintf("%s/repos/%s/%s/dispatches", gc.BaseURL, repoOwner, repoName), dispatch)
+}
+
+// postJSON sends an authenticated POST to the GitHub API and checks for a 2xx response.
+// The request is abandoned when ctx is cancelled.
+func (gc *GitHubClient) postJSON(ctx context.Context, url string, payload interface{}) error {
 	// Marshal to JSON
-	jsonData, err := json.Marshal(dispatch)
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %v", err)
 	}
 
-	// Create HTTP request
-	url := fmt.Sprintf("%s/repos/%s/%s/dispatches", gc.BaseURL, repoOwner, repoName)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	resp, err := gc.send(ctx, "POST", url, jsonData)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// send makes an authenticated request to the GitHub API, returning the 2xx response for
+// the caller to read and close. 429, 502, 503 and 504 responses, and 403s that are rate
+// limits rather than missing permissions, are retried with exponential backoff and
+// jitter, waiting at least as long as GitHub's Retry-After or X-RateLimit-Reset asks. A
+// wait longer than maxRetryDelay ends the retries. Calls and retries that would not
+// finish within the request's deadline budget fail with a BudgetError instead.
+func (gc *GitHubClient) send(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	delay := gc.retryDelay
+	for attempt := 1; ; attempt++ {
+		if _, err := budgetShare(ctx, callGitHub, 1); err != nil {
+			return nil, err
+		}
+
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", gc.Token))
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+		resp, err := gc.Client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %v", err)
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		now := currentTime(ctx)
+		retryAfter := gitHubRetryAfter(resp, now)
+		failure := &GitHubAPIError{StatusCode: resp.StatusCode, Retries: attempt - 1}
+		if retryAfter > 0 {
+			failure.RetryAt = now.Add(retryAfter)
+		}
+		if !retryableGitHubResponse(resp) || attempt >= gc.maxAttempts || retryAfter > gc.maxRetryDelay {
+			return nil, failure
+		}
+
+		// Full delay halved plus up to half again, so clients backing off together spread out
+		wait := delay/2 + rand.N(delay/2+1)
+		if wait < retryAfter {
+			wait = retryAfter
+		}
+		if err := budgetRetry(ctx, callGitHub, wait); err != nil {
+			return nil, fmt.Errorf("%w; %w", failure, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w; retry cancelled: %v", failure, ctx.Err())
+		case <-time.After(wait):
+		}
+		delay *= 2
+		if delay > gc.maxRetryDelay {
+			delay = gc.maxRetryDelay
+		}
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", gc.Token))
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+// retryableGitHubResponse reports whether a GitHub error response is worth retrying:
+// a transient server error, or a rate limit. GitHub answers both exhausted rate limits
+// and abuse detection (secondary rate limits) with 403 or 429, telling them apart from
+// other 403s with X-RateLimit-Remaining: 0 or a Retry-After.
+func retryableGitHubResponse(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
 
-	// Send request
-	resp, err := gc.Client.Do(req)
+// gitHubRetryAfter returns how long GitHub asked to wait before retrying: its
+// Retry-After, or when the rate limit is used up, the time until X-RateLimit-Reset.
+// Returns 0 when it asked for neither.
+func gitHubRetryAfter(resp *http.Response, now time.Time) time.Duration {
+	if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now); retryAfter > 0 {
+		return retryAfter
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return 0
+	}
+	if wait := time.Unix(reset, 0).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// WorkflowRun is the subset of a GitHub Actions workflow run used for correlation
+type WorkflowRun struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	DisplayTitle string `json:"display_title"`
+	Status       string `json:"status"`
+	HTMLURL      string `json:"html_url"`
+}
+
+// workflowRunsResponse is the body returned by the list workflow runs API
+type workflowRunsResponse struct {
+	WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+}
+
+// videoFields returns the video fields sent with each dispatch, keyed by payload name.
+// They are the client_payload of repository_dispatch events, less playlist_id, and the
+// values workflow_dispatch inputs are mapped from. playlist_id is empty unless the video
+// came from a playlist's feed.
+func videoFields(entry *Entry, environment string) map[string]string {
+	return map[string]string{
+		"video_id":    entry.VideoID,
+		"channel_id":  entry.ChannelID,
+		"playlist_id": entry.PlaylistID,
+		"title":       dispatchTitle(entry),
+		"published":   entry.Published,
+		"updated":     entry.Updated,
+		"video_url":   fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.VideoID),
+		"environment": environment,
+		"dispatch_id": dispatchID(entry),
+	}
+}
+
+// dispatchTitle returns the entry's title cut to maxDispatchTitleLength characters,
+// ending in an ellipsis when cut. The cut depends only on the title, so retries and
+// replays of a dispatch send the same payload.
+func dispatchTitle(entry *Entry) string {
+	length := utf8.RuneCountInString(entry.Title)
+	if length <= maxDispatchTitleLength {
+		return entry.Title
+	}
+	fmt.Printf("Truncating title of video %s from %d to %d characters for dispatch\n",
+		entry.VideoID, length, maxDispatchTitleLength)
+	return string([]rune(entry.Title)[:maxDispatchTitleLength-1]) + "…"
+}
+
+// dispatchID derives the idempotency key sent with each dispatch.
+// The same notification always yields the same key.
+func dispatchID(entry *Entry) string {
+	sum := sha256.Sum256([]byte(entry.ChannelID + "|" + entry.VideoID + "|" + entry.Updated))
+	return hex.EncodeToString(sum[:8])
+}
+
+// FindDispatchRun looks for the workflow run started by the dispatch with the given ID.
+// Runs are matched on their display title, so the workflow must include
+// client_payload.dispatch_id in its run-name. Returns nil when no run matches yet.
+func (gc *GitHubClient) FindDispatchRun(ctx context.Context, repoOwner, repoName, dispatchID string, since time.Time) (*WorkflowRun, error) {
+	if gc.Token == "" || repoOwner == "" || repoName == "" || dispatchID == "" {
+		return nil, fmt.Errorf("missing required parameters for GitHub run lookup")
+	}
+
+	query := url.Values{}
+	query.Set("event", "repository_dispatch")
+	query.Set("created", ">="+since.UTC().Format(time.RFC3339))
+	runsURL := fmt.Sprintf("%s/repos/%s/%s/actions/runs?%s", gc.BaseURL, repoOwner, repoName, query.Encode())
+
+	resp, err := gc.send(ctx, "GET", runsURL, nil)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	var runs workflowRunsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&runs); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow runs: %v", err)
 	}
 
-	return nil
+	for i := range runs.WorkflowRuns {
+		run := runs.WorkflowRuns[i]
+		if strings.Contains(run.DisplayTitle, dispatchID) || strings.Contains(run.Name, dispatchID) {
+			return &run, nil
+		}
+	}
+	return nil, nil
 }
@@ -1,10 +1,19 @@
 package webhook
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
 // GitHubClientInterface defines the interface for GitHub API operations.
+// Every call is cancelled when its context is.
 type GitHubClientInterface interface {
-	TriggerWorkflow(repoOwner, repoName string, entry *Entry) error
+	TriggerWorkflow(ctx context.Context, repoOwner, repoName string, entry *Entry, extra map[string]interface{}) error
+	DispatchWorkflow(ctx context.Context, repoOwner, repoName, workflow, ref string, inputs map[string]string) error
+	SendTestDispatch(ctx context.Context, repoOwner, repoName string) error
+	FindDispatchRun(ctx context.Context, repoOwner, repoName, dispatchID string, since time.Time) (*WorkflowRun, error)
+	CheckAccess(ctx context.Context, repoOwner, repoName string) error
 	IsConfigured() bool
 }
 
@@ -17,6 +26,19 @@ type MockGitHubClient struct {
 	lastRepoOwner    string
 	lastRepoName     string
 	lastEntry        *Entry
+	lastExtra        map[string]interface{}
+	testCallCount    int
+	dispatchRun      *WorkflowRun
+	findRunError     error
+	findRunCallCount int
+	triggeredRepos   []string
+	workflowCalls    int
+	lastWorkflow     string
+	lastRef          string
+	lastInputs       map[string]string
+	repoErrors       map[string]error
+	accessError      error
+	accessCallCount  int
 }
 
 // NewMockGitHubClient creates a new mock GitHub client.
@@ -27,7 +49,7 @@ func NewMockGitHubClient() *MockGitHubClient {
 }
 
 // TriggerWorkflow simulates triggering a GitHub workflow.
-func (m *MockGitHubClient) TriggerWorkflow(repoOwner, repoName string, entry *Entry) error {
+func (m *MockGitHubClient) TriggerWorkflow(ctx context.Context, repoOwner, repoName string, entry *Entry, extra map[string]interface{}) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -35,10 +57,68 @@ func (m *MockGitHubClient) TriggerWorkflow(repoOwner, repoName string, entry *En
 	m.lastRepoOwner = repoOwner
 	m.lastRepoName = repoName
 	m.lastEntry = entry
+	m.lastExtra = extra
+	repo := repoOwner + "/" + repoName
+	m.triggeredRepos = append(m.triggeredRepos, repo)
 
+	if err, ok := m.repoErrors[repo]; ok {
+		return err
+	}
 	return m.triggerError
 }
 
+// DispatchWorkflow simulates running a workflow with a workflow_dispatch trigger.
+func (m *MockGitHubClient) DispatchWorkflow(ctx context.Context, repoOwner, repoName, workflow, ref string, inputs map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.workflowCalls++
+	m.lastRepoOwner = repoOwner
+	m.lastRepoName = repoName
+	m.lastWorkflow = workflow
+	m.lastRef = ref
+	m.lastInputs = inputs
+	repo := repoOwner + "/" + repoName
+	m.triggeredRepos = append(m.triggeredRepos, repo)
+
+	if err, ok := m.repoErrors[repo]; ok {
+		return err
+	}
+	return m.triggerError
+}
+
+// SendTestDispatch simulates sending a test dispatch event.
+func (m *MockGitHubClient) SendTestDispatch(ctx context.Context, repoOwner, repoName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.testCallCount++
+	m.lastRepoOwner = repoOwner
+	m.lastRepoName = repoName
+
+	return m.triggerError
+}
+
+// FindDispatchRun simulates looking up the workflow run for a dispatch.
+func (m *MockGitHubClient) FindDispatchRun(ctx context.Context, repoOwner, repoName, dispatchID string, since time.Time) (*WorkflowRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.findRunCallCount++
+	return m.dispatchRun, m.findRunError
+}
+
+// CheckAccess simulates checking the token against a repository.
+func (m *MockGitHubClient) CheckAccess(ctx context.Context, repoOwner, repoName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.accessCallCount++
+	m.lastRepoOwner = repoOwner
+	m.lastRepoName = repoName
+	return m.accessError
+}
+
 // IsConfigured returns whether the GitHub client is configured.
 func (m *MockGitHubClient) IsConfigured() bool {
 	m.mu.RLock()
@@ -60,6 +140,35 @@ func (m *MockGitHubClient) SetConfigured(configured bool) {
 	m.isConfigured = configured
 }
 
+// SetAccessError sets the error to return from CheckAccess.
+func (m *MockGitHubClient) SetAccessError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accessError = err
+}
+
+// GetAccessCallCount returns the number of CheckAccess calls.
+func (m *MockGitHubClient) GetAccessCallCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.accessCallCount
+}
+
+// SetDispatchRun sets the run and error returned from FindDispatchRun.
+func (m *MockGitHubClient) SetDispatchRun(run *WorkflowRun, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dispatchRun = run
+	m.findRunError = err
+}
+
+// GetFindRunCallCount returns the number of FindDispatchRun calls.
+func (m *MockGitHubClient) GetFindRunCallCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.findRunCallCount
+}
+
 // GetTriggerCallCount returns the number of TriggerWorkflow calls.
 func (m *MockGitHubClient) GetTriggerCallCount() int {
 	m.mu.RLock()
@@ -67,6 +176,44 @@ func (m *MockGitHubClient) GetTriggerCallCount() int {
 	return m.triggerCallCount
 }
 
+// GetTestCallCount returns the number of SendTestDispatch calls.
+func (m *MockGitHubClient) GetTestCallCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.testCallCount
+}
+
+// GetWorkflowCallCount returns the number of DispatchWorkflow calls.
+func (m *MockGitHubClient) GetWorkflowCallCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.workflowCalls
+}
+
+// GetLastWorkflowDispatch returns the workflow, ref and inputs of the last DispatchWorkflow call.
+func (m *MockGitHubClient) GetLastWorkflowDispatch() (string, string, map[string]string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastWorkflow, m.lastRef, m.lastInputs
+}
+
+// SetRepositoryTriggerError makes TriggerWorkflow and DispatchWorkflow fail for a single owner/name repository.
+func (m *MockGitHubClient) SetRepositoryTriggerError(repo string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.repoErrors == nil {
+		m.repoErrors = make(map[string]error)
+	}
+	m.repoErrors[repo] = err
+}
+
+// GetTriggeredRepositories returns the owner/name of every TriggerWorkflow and DispatchWorkflow call in order.
+func (m *MockGitHubClient) GetTriggeredRepositories() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.triggeredRepos...)
+}
+
 // GetLastEntry returns the last entry passed to TriggerWorkflow.
 func (m *MockGitHubClient) GetLastEntry() *Entry {
 	m.mu.RLock()
@@ -74,6 +221,13 @@ func (m *MockGitHubClient) GetLastEntry() *Entry {
 	return m.lastEntry
 }
 
+// GetLastExtra returns the extra client_payload fields last passed to TriggerWorkflow.
+func (m *MockGitHubClient) GetLastExtra() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastExtra
+}
+
 // Reset resets the mock to initial state.
 func (m *MockGitHubClient) Reset() {
 	m.mu.Lock()
@@ -85,4 +239,17 @@ func (m *MockGitHubClient) Reset() {
 	m.lastRepoOwner = ""
 	m.lastRepoName = ""
 	m.lastEntry = nil
+	m.lastExtra = nil
+	m.testCallCount = 0
+	m.dispatchRun = nil
+	m.findRunError = nil
+	m.findRunCallCount = 0
+	m.triggeredRepos = nil
+	m.repoErrors = nil
+	m.workflowCalls = 0
+	m.lastWorkflow = ""
+	m.lastRef = ""
+	m.lastInputs = nil
+	m.accessError = nil
+	m.accessCallCount = 0
 }
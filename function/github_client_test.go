@@ -1,13 +1,18 @@
 package webhook
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -145,7 +150,7 @@ func TestGitHubClient_TriggerWorkflow_MissingParameters(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			client.Token = tc.token
-			err := client.TriggerWorkflow(tc.repoOwner, tc.repoName, entry)
+			err := client.TriggerWorkflow(context.Background(), tc.repoOwner, tc.repoName, entry, nil)
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "missing required parameters")
 		})
@@ -183,7 +188,7 @@ func TestGitHubClient_TriggerWorkflow_Success(t *testing.T) {
 		Updated:   time.Now().Format(time.RFC3339),
 	}
 
-	err := client.TriggerWorkflow("test-owner", "test-repo", entry)
+	err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 	assert.NoError(t, err)
 }
 
@@ -209,7 +214,7 @@ func TestGitHubClient_TriggerWorkflow_HTTPError(t *testing.T) {
 		Updated:   time.Now().Format(time.RFC3339),
 	}
 
-	err := client.TriggerWorkflow("test-owner", "test-repo", entry)
+	err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "GitHub API returned status 400")
 }
@@ -229,7 +234,7 @@ func TestGitHubClient_TriggerWorkflow_NetworkError(t *testing.T) {
 		Updated:   time.Now().Format(time.RFC3339),
 	}
 
-	err := client.TriggerWorkflow("test-owner", "test-repo", entry)
+	err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to send request")
 }
@@ -249,7 +254,7 @@ func TestGitHubClient_TriggerWorkflow_InvalidURL(t *testing.T) {
 		Updated:   time.Now().Format(time.RFC3339),
 	}
 
-	err := client.TriggerWorkflow("test-owner", "test-repo", entry)
+	err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to create request")
 }
@@ -289,7 +294,7 @@ func TestGitHubClient_TriggerWorkflow_PayloadValidation(t *testing.T) {
 		Updated:   time.Now().Format(time.RFC3339),
 	}
 
-	err := client.TriggerWorkflow("test-owner", "test-repo", entry)
+	err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 	require.NoError(t, err)
 
 	// Validate payload content
@@ -299,6 +304,247 @@ func TestGitHubClient_TriggerWorkflow_PayloadValidation(t *testing.T) {
 	assert.Contains(t, receivedPayload, "Test Video")
 	assert.Contains(t, receivedPayload, "https://www.youtube.com/watch?v=test_video_id")
 	assert.Contains(t, receivedPayload, "test") // environment
+	assert.Contains(t, receivedPayload, `"dispatch_id":"`+dispatchID(entry)+`"`)
+}
+
+func TestGitHubClient_TriggerWorkflow_RouteFields(t *testing.T) {
+	var received GitHubDispatch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{Token: "test-token", BaseURL: server.URL, Client: &http.Client{Timeout: 30 * time.Second}}
+	target := RepositoryTarget{RepoOwner: "test-owner", RepoName: "test-repo", Ref: "staging", Payload: map[string]string{"stage": "staging"}}
+
+	err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", &Entry{VideoID: "test_video_id"}, target.clientPayloadExtra())
+	require.NoError(t, err)
+
+	assert.Equal(t, "test_video_id", received.ClientPayload["video_id"])
+	assert.Equal(t, "staging", received.ClientPayload["ref"])
+	assert.Equal(t, map[string]interface{}{"stage": "staging"}, received.ClientPayload["payload"])
+	assert.LessOrEqual(t, len(received.ClientPayload), 10, "GitHub accepts at most 10 top-level client_payload properties")
+}
+
+func TestGitHubClient_DispatchWorkflow(t *testing.T) {
+	var received WorkflowDispatch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/repos/test-owner/test-repo/actions/workflows/publish.yml/dispatches", r.URL.Path)
+		assert.Equal(t, "token test-token", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{Token: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	err := client.DispatchWorkflow(context.Background(), "test-owner", "test-repo", "publish.yml", "main", map[string]string{"video": "test_video_id"})
+	require.NoError(t, err)
+	assert.Equal(t, "main", received.Ref)
+	assert.Equal(t, map[string]string{"video": "test_video_id"}, received.Inputs)
+
+	err = client.DispatchWorkflow(context.Background(), "test-owner", "test-repo", "publish.yml", "", nil)
+	assert.ErrorContains(t, err, "missing required parameters")
+}
+
+func TestGitHubClient_CheckAccess(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "token test-token", r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/repos/test-owner/private-repo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{Token: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	require.NoError(t, client.CheckAccess(context.Background(), "test-owner", "test-repo"))
+	require.NoError(t, client.CheckAccess(context.Background(), "", ""))
+	assert.Equal(t, []string{"/repos/test-owner/test-repo", "/rate_limit"}, paths)
+
+	var apiErr *GitHubAPIError
+	err := client.CheckAccess(context.Background(), "test-owner", "private-repo")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	client.Token = ""
+	assert.ErrorContains(t, client.CheckAccess(context.Background(), "test-owner", "test-repo"), "missing required parameters")
+}
+
+func TestGitHubClient_PayloadSize(t *testing.T) {
+	var requests int32
+	var received GitHubDispatch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	client := &GitHubClient{Token: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	t.Run("long_titles_are_truncated", func(t *testing.T) {
+		entry := &Entry{VideoID: "test_video_id", Title: strings.Repeat("é", 5000)}
+		require.NoError(t, client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil))
+
+		title := received.ClientPayload["title"].(string)
+		assert.Equal(t, maxDispatchTitleLength, utf8.RuneCountInString(title))
+		assert.True(t, strings.HasSuffix(title, "…"))
+		assert.Equal(t, title, dispatchTitle(entry), "the same title is always cut the same way")
+	})
+
+	t.Run("oversized_payloads_are_refused", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		payload := map[string]string{}
+		for i := 0; i < maxPayloadFields; i++ {
+			payload[fmt.Sprintf("field%d", i)] = strings.Repeat("\x01", maxPayloadValueLength) // Escaped as \u0001
+		}
+		extra := map[string]interface{}{"payload": payload}
+
+		err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", &Entry{VideoID: "test_video_id"}, extra)
+		assert.ErrorIs(t, err, ErrDispatchPayloadTooLarge)
+		assert.ErrorContains(t, err, "client_payload is")
+
+		err = client.DispatchWorkflow(context.Background(), "test-owner", "test-repo", "publish.yml", "main", payload)
+		assert.ErrorIs(t, err, ErrDispatchPayloadTooLarge)
+		assert.Zero(t, atomic.LoadInt32(&requests), "nothing is sent to GitHub")
+	})
+}
+
+func TestGitHubClient_Retries(t *testing.T) {
+	// respond answers each request with the next of responses, repeating the last
+	respond := func(responses ...func(w http.ResponseWriter)) (*GitHubClient, *int32) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := int(atomic.AddInt32(&requests, 1))
+			if n > len(responses) {
+				n = len(responses)
+			}
+			responses[n-1](w)
+		}))
+		t.Cleanup(server.Close)
+		client := &GitHubClient{Token: "test-token", BaseURL: server.URL, Client: server.Client(),
+			maxAttempts: 3, retryDelay: time.Millisecond, maxRetryDelay: time.Second}
+		return client, &requests
+	}
+	status := func(code int, headers ...string) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			for i := 0; i+1 < len(headers); i += 2 {
+				w.Header().Set(headers[i], headers[i+1])
+			}
+			w.WriteHeader(code)
+		}
+	}
+	dispatch := func(client *GitHubClient) error {
+		return client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", &Entry{VideoID: "test_video_id"}, nil)
+	}
+	past := fmt.Sprintf("%d", time.Now().Add(-time.Minute).Unix())
+
+	t.Run("transient_errors_are_retried", func(t *testing.T) {
+		client, requests := respond(status(http.StatusBadGateway), status(http.StatusNoContent))
+		require.NoError(t, dispatch(client))
+		assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	})
+
+	t.Run("rate_limits_are_retried", func(t *testing.T) {
+		client, requests := respond(
+			status(http.StatusForbidden, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", past),
+			status(http.StatusForbidden, "Retry-After", "0"), // Abuse detection
+			status(http.StatusNoContent))
+		require.NoError(t, dispatch(client))
+		assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+	})
+
+	t.Run("permission_errors_are_not_retried", func(t *testing.T) {
+		client, requests := respond(status(http.StatusForbidden, "X-RateLimit-Remaining", "4999"))
+		assert.EqualError(t, dispatch(client), "GitHub API returned status 403")
+		assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("gives_up_after_max_attempts", func(t *testing.T) {
+		client, requests := respond(status(http.StatusServiceUnavailable))
+		err := dispatch(client)
+		var apiErr *GitHubAPIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Equal(t, 2, apiErr.Retries)
+		assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+	})
+
+	t.Run("long_waits_are_not_retried", func(t *testing.T) {
+		reset := fmt.Sprintf("%d", time.Now().Add(time.Hour).Unix())
+		client, requests := respond(status(http.StatusForbidden, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", reset))
+		err := dispatch(client)
+		assert.ErrorContains(t, err, "GitHub API returned status 403; retry after")
+		assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("run_lookups_are_retried", func(t *testing.T) {
+		client, requests := respond(status(http.StatusTooManyRequests, "Retry-After", "0"),
+			func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"workflow_runs": []}`)) })
+		run, err := client.FindDispatchRun(context.Background(), "test-owner", "test-repo", "abc123", time.Now())
+		require.NoError(t, err)
+		assert.Nil(t, run)
+		assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	})
+
+	t.Run("configured_from_environment", func(t *testing.T) {
+		t.Setenv("GITHUB_MAX_ATTEMPTS", "5")
+		t.Setenv("GITHUB_RETRY_DELAY_MS", "100")
+		t.Setenv("GITHUB_MAX_RETRY_DELAY_SECONDS", "60")
+		client := NewGitHubClient()
+		assert.Equal(t, 5, client.maxAttempts)
+		assert.Equal(t, 100*time.Millisecond, client.retryDelay)
+		assert.Equal(t, time.Minute, client.maxRetryDelay)
+	})
+}
+
+func TestNewGitHubClient_Timeout(t *testing.T) {
+	defer os.Unsetenv("GITHUB_API_TIMEOUT_SECONDS")
+
+	os.Setenv("GITHUB_API_TIMEOUT_SECONDS", "5")
+	assert.Equal(t, 5*time.Second, NewGitHubClient().Client.Timeout)
+
+	os.Setenv("GITHUB_API_TIMEOUT_SECONDS", "not-a-number")
+	assert.Equal(t, defaultGitHubAPITimeout, NewGitHubClient().Client.Timeout)
+
+	os.Setenv("GITHUB_API_TIMEOUT_SECONDS", "0")
+	assert.Equal(t, defaultGitHubAPITimeout, NewGitHubClient().Client.Timeout)
+}
+
+func TestGitHubClient_ContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // Don't answer until the test ends; the caller's deadline must end the request
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &GitHubClient{Token: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	t.Run("dispatch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := client.TriggerWorkflow(ctx, "test-owner", "test-repo", &Entry{VideoID: "test_video_id"}, nil)
+		assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("run_lookup", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.FindDispatchRun(ctx, "test-owner", "test-repo", "abcdef0123456789", time.Now())
+		assert.ErrorContains(t, err, context.Canceled.Error())
+	})
 }
 
 func TestGitHubClient_sendDispatch_ErrorCases(t *testing.T) {
@@ -320,7 +566,7 @@ func TestGitHubClient_sendDispatch_ErrorCases(t *testing.T) {
 
 		// Use invalid URL to test other error paths
 		client.BaseURL = "ht tp://invalid"
-		err := client.sendDispatch("owner", "repo", dispatch)
+		err := client.sendDispatch(context.Background(), "owner", "repo", dispatch)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create request")
 	})
@@ -356,7 +602,7 @@ func TestGitHubClient_EdgeCases(t *testing.T) {
 
 		for i := 0; i < numRequests; i++ {
 			go func() {
-				errors <- client.TriggerWorkflow("test-owner", "test-repo", entry)
+				errors <- client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 			}()
 		}
 
@@ -388,7 +634,7 @@ func TestGitHubClient_EdgeCases(t *testing.T) {
 			Updated:   "",
 		}
 
-		err := client.TriggerWorkflow("test-owner", "test-repo", entry)
+		err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 		assert.NoError(t, err)
 	})
 }
@@ -398,8 +644,8 @@ func TestMockGitHubClient_Reset(t *testing.T) {
 	mock := NewMockGitHubClient()
 	
 	// Add some data to the mock
-	_ = mock.TriggerWorkflow("test-owner", "test-repo", &Entry{VideoID: "test1", Title: "Test 1"})
-	_ = mock.TriggerWorkflow("test-owner", "test-repo", &Entry{VideoID: "test2", Title: "Test 2"})
+	_ = mock.TriggerWorkflow(context.Background(), "test-owner", "test-repo", &Entry{VideoID: "test1", Title: "Test 1"}, nil)
+	_ = mock.TriggerWorkflow(context.Background(), "test-owner", "test-repo", &Entry{VideoID: "test2", Title: "Test 2"}, nil)
 	
 	// Verify data exists
 	assert.Equal(t, 2, mock.GetTriggerCallCount())
@@ -412,3 +658,63 @@ func TestMockGitHubClient_Reset(t *testing.T) {
 	assert.Equal(t, 0, mock.GetTriggerCallCount())
 	assert.Nil(t, mock.GetLastEntry())
 }
+
+func TestGitHubClient_FindDispatchRun(t *testing.T) {
+	var receivedQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedQuery = r.URL.RawQuery
+		if r.URL.Path != "/repos/test-owner/test-repo/actions/runs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"workflow_runs": [
+			{"id": 1, "name": "Publish", "display_title": "Publish 0000000000000000", "html_url": "https://github.com/runs/1"},
+			{"id": 2, "name": "Publish", "display_title": "Publish abcdef0123456789", "html_url": "https://github.com/runs/2"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{
+		Token:   "test-token",
+		BaseURL: server.URL,
+		Client:  &http.Client{Timeout: 30 * time.Second},
+	}
+	since := time.Date(2025, 1, 21, 12, 0, 0, 0, time.UTC)
+
+	t.Run("matches_on_dispatch_id", func(t *testing.T) {
+		run, err := client.FindDispatchRun(context.Background(), "test-owner", "test-repo", "abcdef0123456789", since)
+		require.NoError(t, err)
+		require.NotNil(t, run)
+		assert.Equal(t, int64(2), run.ID)
+		assert.Equal(t, "https://github.com/runs/2", run.HTMLURL)
+		assert.Contains(t, receivedQuery, "event=repository_dispatch")
+		assert.Contains(t, receivedQuery, "created=%3E%3D2025-01-21T12%3A00%3A00Z")
+	})
+
+	t.Run("no_match", func(t *testing.T) {
+		run, err := client.FindDispatchRun(context.Background(), "test-owner", "test-repo", "ffffffffffffffff", since)
+		assert.NoError(t, err)
+		assert.Nil(t, run)
+	})
+
+	t.Run("http_error", func(t *testing.T) {
+		_, err := client.FindDispatchRun(context.Background(), "other", "repo", "abcdef0123456789", since)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "404")
+	})
+
+	t.Run("missing_parameters", func(t *testing.T) {
+		_, err := client.FindDispatchRun(context.Background(), "test-owner", "test-repo", "", since)
+		assert.Error(t, err)
+	})
+}
+
+func TestDispatchID(t *testing.T) {
+	entry := &Entry{VideoID: "v1", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Updated: "2025-01-21T12:00:00Z"}
+
+	id := dispatchID(entry)
+	assert.Len(t, id, 16)
+	assert.Equal(t, id, dispatchID(&Entry{VideoID: "v1", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Updated: "2025-01-21T12:00:00Z"}))
+	assert.NotEqual(t, id, dispatchID(&Entry{VideoID: "v2", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Updated: "2025-01-21T12:00:00Z"}))
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -39,7 +40,7 @@ func TestTriggerGitHubWorkflow_ErrorPaths(t *testing.T) {
 			setEnvOrUnset("GITHUB_API_BASE_URL", originalBaseURL)
 		}()
 
-		err := triggerGitHubWorkflow(entry)
+		err := triggerFromEnv(entry)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create request")
 	})
@@ -52,7 +53,7 @@ func TestTriggerGitHubWorkflow_ErrorPaths(t *testing.T) {
 			Client:  &http.Client{Timeout: 100 * time.Millisecond}, // Very short timeout
 		}
 
-		err := client.TriggerWorkflow("test-owner", "test-repo", entry)
+		err := client.TriggerWorkflow(context.Background(), "test-owner", "test-repo", entry, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to send request")
 	})
@@ -82,7 +83,7 @@ func TestTriggerGitHubWorkflow_ErrorPaths(t *testing.T) {
 			setEnvOrUnset("GITHUB_API_BASE_URL", originalBaseURL)
 		}()
 
-		err := triggerGitHubWorkflow(entry)
+		err := triggerFromEnv(entry)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "GitHub API returned status 400")
 	})
@@ -116,7 +117,7 @@ func TestTriggerGitHubWorkflow_JSONMarshalError(t *testing.T) {
 
 	// Normal struct should marshal fine, so this test primarily exercises the happy path
 	// The JSON marshal error is very hard to trigger with valid structs
-	err := triggerGitHubWorkflow(entry)
+	err := triggerFromEnv(entry)
 	// Will likely get a network error or success, but exercises the marshal code path
 	assert.NotNil(t, err) // Expected due to invalid GitHub credentials
 }
@@ -149,7 +150,7 @@ func TestTriggerGitHubWorkflow_AllErrorPaths(t *testing.T) {
 		}()
 
 		// This will exercise the marshal path (won't actually fail marshal with valid structs)
-		err := triggerGitHubWorkflow(entry)
+		err := triggerFromEnv(entry)
 		// Expected to fail with network/auth error, but exercises marshal code path
 		assert.NotNil(t, err)
 	})
@@ -186,7 +187,7 @@ func TestTriggerGitHubWorkflow_AllErrorPaths(t *testing.T) {
 			setEnvOrUnset("GITHUB_API_BASE_URL", originalBaseURL)
 		}()
 
-		err := triggerGitHubWorkflow(entry)
+		err := triggerFromEnv(entry)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "GitHub API returned status 500")
 	})
@@ -200,3 +201,9 @@ func setEnvOrUnset(key, value string) {
 		os.Setenv(key, value)
 	}
 }
+
+// triggerFromEnv dispatches entry with a GitHubClient configured from the environment,
+// to the repository named by REPO_OWNER and REPO_NAME
+func triggerFromEnv(entry *Entry) error {
+	return NewGitHubClient().TriggerWorkflow(context.Background(), os.Getenv("REPO_OWNER"), os.Getenv("REPO_NAME"), entry, nil)
+}
@@ -2,86 +2,150 @@ package webhook
 
 import (
 	"context"
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
 )
 
 // handleSubscribe handles POST /subscribe requests using dependency injection.
+// ?playlist_id subscribes to a playlist's feed instead of a channel's.
 func handleSubscribe(deps *Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		// Get and validate channel_id parameter
+		// Get and validate channel_id parameter, or the handle or channel URL to resolve
 		channelID := r.URL.Query().Get("channel_id")
-		if channelID == "" {
+		handle := r.URL.Query().Get("handle")
+		playlistID := r.URL.Query().Get("playlist_id")
+		if channelID == "" && handle == "" && playlistID == "" {
 			writeErrorResponse(w, http.StatusBadRequest, "", "channel_id parameter is required")
 			return
 		}
+		if channelID != "" && handle != "" {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "channel_id and handle cannot both be given")
+			return
+		}
+		if playlistID != "" && (channelID != "" || handle != "") {
+			writeErrorResponse(w, http.StatusBadRequest, playlistID, "playlist_id cannot be given with channel_id or handle")
+			return
+		}
+		if playlistID != "" && !validatePlaylistID(playlistID) {
+			writeErrorResponse(w, http.StatusBadRequest, playlistID,
+				"Invalid playlist ID format. Must be a playlist ID such as PL followed by 32 alphanumeric characters")
+			return
+		}
 
 		// Validate channel ID format
-		if !validateChannelID(channelID) {
+		if channelID != "" && !validateChannelID(channelID) {
 			writeErrorResponse(w, http.StatusBadRequest, channelID,
 				"Invalid channel ID format. Must be UC followed by 22 alphanumeric characters")
 			return
 		}
 
-		// Load current subscription state using injected storage client
-		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
-		if err != nil {
+		// An alternative hub, such as a self-hosted WebSub hub, can be given per subscription
+		hubURL := r.URL.Query().Get("hub_url")
+		if hubURL != "" {
+			if err := validateHubURL(hubURL); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, channelID, err.Error())
+				return
+			}
+		}
+
+		// Playlists are subscribed by ID; channels are resolved, which may look them up
+		var channelName string
+		if playlistID != "" {
+			channelID = playlistID
+		} else {
+			ref := channelID
+			if handle != "" {
+				ref = handle
+			}
+			var statusCode int
+			var message string
+			channelID, channelName, statusCode, message = resolveChannel(ctx, deps, ref)
+			if statusCode != 0 {
+				writeErrorResponse(w, statusCode, "", message)
+				return
+			}
+		}
+
+		// Check if already subscribed using injected storage client
+		existing, err := deps.StorageClient.GetSubscription(ctx, channelID)
+		if err != nil && !errors.Is(err, ErrSubscriptionNotFound) {
 			writeErrorResponse(w, http.StatusInternalServerError, channelID,
 				fmt.Sprintf("Failed to load subscription state: %v", err))
 			return
 		}
-
-		// Check if already subscribed
-		if existing, exists := state.Subscriptions[channelID]; exists {
-			// Return conflict response with existing expiration
+		if existing != nil {
+			// Return the conflict envelope with the existing expiration
 			response := APIResponse{
-				Status:    "conflict",
-				ChannelID: channelID,
-				Message:   "Already subscribed to this channel",
-				ExpiresAt: existing.ExpiresAt.Format(time.RFC3339),
+				Status:      "error",
+				Code:        ErrCodeConflict,
+				ChannelID:   channelID,
+				ChannelName: existing.ChannelName,
+				Message:     fmt.Sprintf("Already subscribed to this %s", topicTypeOf(channelID)),
+				ExpiresAt:   formatOptionalTime(existing.ExpiresAt),
+				RequestID:   w.Header().Get(RequestIDHeader),
 			}
+			w.Header().Set("Content-Type", "application/json")
 			writeJSONResponse(w, http.StatusConflict, response)
 			return
 		}
 
+		// Generate the secret the hub will use to sign notifications
+		secret, err := generateHubSecret()
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID, err.Error())
+			return
+		}
+
+		// The callback carries the callback token when CALLBACK_PATH_TOKEN is set
+		callbackURL, err := subscriptionCallback(ctx, deps)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID, err.Error())
+			return
+		}
+
 		// Make PubSubHubbub subscription request using injected client
-		if err := deps.PubSubClient.Subscribe(channelID); err != nil {
-			writeErrorResponse(w, http.StatusBadGateway, channelID,
-				fmt.Sprintf("PubSubHubbub subscription failed: %v", err))
+		hubCtx, hubRetries := withHubRetries(withCallbackURL(withHubURL(r.Context(), hubURL), callbackURL))
+		if err := deps.PubSubClient.Subscribe(hubCtx, channelID, secret, 0); err != nil {
+			writeHubError(w, channelID, "PubSubHubbub subscription failed", err)
 			return
 		}
 
 		// Create subscription record
-		callbackURL := os.Getenv("FUNCTION_URL")
-		if callbackURL == "" {
-			callbackURL = "https://default-function-url"
-		}
-		topicURL := fmt.Sprintf("https://www.youtube.com/feeds/videos.xml?channel_id=%s", channelID)
-		now := time.Now()
-		expiresAt := now.Add(24 * time.Hour)
+		now := deps.now()
 
+		// The lease and expiry are unknown until the hub verifies the subscription
 		subscription := &Subscription{
 			ChannelID:       channelID,
-			TopicURL:        topicURL,
+			ChannelName:     channelName,
+			TopicType:       topicTypeOf(channelID),
+			TopicURL:        topicURLFor(channelID),
 			CallbackURL:     callbackURL,
-			Status:          "active",
-			LeaseSeconds:    86400,
 			SubscribedAt:    now,
-			ExpiresAt:       expiresAt,
-			LastRenewal:     now,
 			RenewalAttempts: 0,
 			HubResponse:     "202 Accepted",
+			Secret:          secret,
+			HubURL:          hubURL,
+		}
+		if subscription.HubURL == "" {
+			subscription.HubURL = getHubURL()
 		}
+		if channelName != "" {
+			subscription.ChannelNameUpdatedAt = now
+		}
+		refreshChannelName(ctx, subscription, deps)
+		markPending(subscription, now)
 
-		// Store subscription state using injected storage client
-		state.Subscriptions[channelID] = subscription
-		if err := deps.StorageClient.SaveSubscriptionState(ctx, state); err != nil {
+		// Store subscription using injected storage client
+		if err := deps.StorageClient.SaveSubscription(ctx, subscription); err != nil {
 			writeErrorResponse(w, http.StatusInternalServerError, channelID,
 				fmt.Sprintf("Failed to save subscription state: %v", err))
 			return
@@ -89,58 +153,62 @@ func handleSubscribe(deps *Dependencies) http.HandlerFunc {
 
 		// Return success response
 		response := APIResponse{
-			Status:    "success",
-			ChannelID: channelID,
-			Message:   "Subscription initiated",
-			ExpiresAt: expiresAt.Format(time.RFC3339),
+			Status:      "success",
+			ChannelID:   channelID,
+			ChannelName: subscription.ChannelName,
+			Message:     "Subscription initiated; pending hub verification",
+			HubRetries:  *hubRetries,
 		}
 		writeJSONResponse(w, http.StatusOK, response)
 	}
 }
 
 // handleUnsubscribe handles DELETE /unsubscribe requests using dependency injection.
+// The subscription is kept in the removed section of the state, recording when and by
+// whom it was removed, until REMOVED_RETENTION_DAYS have passed.
 func handleUnsubscribe(deps *Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		// Get and validate channel_id parameter
+		// Get and validate channel_id parameter, or playlist_id for playlist subscriptions
 		channelID := r.URL.Query().Get("channel_id")
+		if channelID == "" {
+			channelID = r.URL.Query().Get("playlist_id")
+		}
 		if channelID == "" {
 			writeErrorResponse(w, http.StatusBadRequest, "", "channel_id parameter is required")
 			return
 		}
 
 		// Validate channel ID format
-		if !validateChannelID(channelID) {
+		if !validateTopicID(channelID) {
 			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
 			return
 		}
 
-		// Load current subscription state using injected storage client
-		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+		// Check if subscription exists using injected storage client
+		existing, err := deps.StorageClient.GetSubscription(ctx, channelID)
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, channelID,
+				"Subscription not found for this channel")
+			return
+		}
 		if err != nil {
 			writeErrorResponse(w, http.StatusInternalServerError, channelID,
 				fmt.Sprintf("Failed to load subscription state: %v", err))
 			return
 		}
 
-		// Check if subscription exists
-		if _, exists := state.Subscriptions[channelID]; !exists {
-			writeErrorResponse(w, http.StatusNotFound, channelID,
-				"Subscription not found for this channel")
-			return
-		}
-
-		// Make PubSubHubbub unsubscribe request using injected client
-		if err := deps.PubSubClient.Unsubscribe(channelID); err != nil {
-			writeErrorResponse(w, http.StatusBadGateway, channelID,
-				fmt.Sprintf("PubSubHubbub unsubscribe failed: %v", err))
+		// Make PubSubHubbub unsubscribe request using injected client, from the callback
+		// the hub knows the subscription by
+		hubCtx := withCallbackURL(withHubURL(r.Context(), existing.HubURL), existing.CallbackURL)
+		if err := deps.PubSubClient.Unsubscribe(hubCtx, channelID); err != nil {
+			writeHubError(w, channelID, "PubSubHubbub unsubscribe failed", err)
 			return
 		}
 
-		// Remove from subscription state
-		delete(state.Subscriptions, channelID)
-		if err := deps.StorageClient.SaveSubscriptionState(ctx, state); err != nil {
+		// Remove the subscription, keeping it restorable for REMOVED_RETENTION_DAYS
+		if err := removeSubscription(ctx, deps, existing, callerFrom(ctx)); err != nil {
 			writeErrorResponse(w, http.StatusInternalServerError, channelID,
 				fmt.Sprintf("Failed to save subscription state: %v", err))
 			return
@@ -151,72 +219,166 @@ func handleUnsubscribe(deps *Dependencies) http.HandlerFunc {
 	}
 }
 
-// handleUnsubscribe is a compatibility wrapper that uses the refactored function.
-
 // handleRenewSubscriptions handles POST /renew requests using dependency injection.
+// Subscriptions due for renewal are renewed soonest expiry first, RENEWAL_CONCURRENCY at
+// a time, at most RENEWAL_BATCH_SIZE per run with up to RENEWAL_JITTER_MS between hub
+// requests; the rest are deferred to the next run. The summary says when the next
+// subscription falls due. Subscriptions the hub stopped delivering are re-subscribed
+// with backoff (see recoverSubscriptions). A RenewRequest body can limit the run
+// to some channels or force renewal regardless of expiry. Clients accepting
+// application/x-ndjson receive each result as it finishes, followed by the summary.
+// Subscriptions are streamed from storage and only those the run can act on are held;
+// only the ones it changed are saved. A report of every run is written to storage.
 func handleRenewSubscriptions(deps *Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
+		renewReq, err := parseRenewRequest(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "", err.Error())
+			return
+		}
+
+		trigger, job := renewalTrigger(r)
+		report := &RenewalReport{
+			RunID:        w.Header().Get(RequestIDHeader),
+			Trigger:      trigger,
+			SchedulerJob: job,
+			StartedAt:    deps.now(),
+			BatchSize:    deps.config().RenewalBatchSize,
+			Concurrency:  deps.config().RenewalConcurrency,
+			Forced:       renewReq.Force,
+			Channels:     renewReq.ChannelIDs,
+		}
 
-		// Load current subscription state using injected storage client
-		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+		// Stream the stored subscriptions, keeping only those this run can act on
+		threshold := deps.config().RenewalThreshold
+		workingSet, err := loadRenewalWorkingSet(ctx, deps.StorageClient, renewReq, deps.now(), threshold,
+			deps.config().ExpiryAlertWindow)
 		if err != nil {
 			writeErrorResponse(w, http.StatusInternalServerError, "",
 				fmt.Sprintf("Failed to load subscription state: %v", err))
 			return
 		}
+		state := workingSet.state
+
+		var unknown []string
+		for _, channelID := range renewReq.ChannelIDs {
+			if _, exists := state.Subscriptions[channelID]; !exists {
+				unknown = append(unknown, channelID)
+			}
+		}
+		if len(unknown) > 0 {
+			writeErrorResponse(w, http.StatusNotFound, "",
+				fmt.Sprintf("Not subscribed to channels: %s", strings.Join(unknown, ", ")))
+			return
+		}
 
 		// Find subscriptions that need renewal
-		renewalThreshold := getRenewalThreshold()
-		now := time.Now()
+		candidates, waiting := renewalCandidates(state, deps.now(), threshold, renewReq)
+		batch := candidates
+		if report.BatchSize > 0 && len(batch) > report.BatchSize {
+			batch = batch[:report.BatchSize]
+		}
+
+		// Renewals would only fail fast while the hub's circuit breaker is open
+		if err := deps.HubBreaker.Check(); err != nil && len(batch) > 0 {
+			setRetryAfter(w, err)
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodeUnavailable, "",
+				fmt.Sprintf("Renewals deferred: %v", err))
+			return
+		}
+
+		var stream *renewalStream
+		var progress func(RenewalResult)
+		if wantsRenewalStream(r) {
+			stream = startRenewalStream(w)
+			stream.send(RenewalEvent{Type: RenewalEventStart, Renewals: len(batch)})
+			progress = func(result RenewalResult) {
+				stream.send(RenewalEvent{Type: RenewalEventResult, Result: &result})
+			}
+		}
 
-		var renewalResults []RenewalResult
+		renewalResults := renewBatch(ctx, deps, state, batch, report.Concurrency, deps.config().RenewalJitter, progress)
+
+		// Re-subscribe the subscriptions the hub stopped delivering; runs limited to some
+		// channels leave them alone
+		var recoveries []RecoveryResult
+		if len(renewReq.ChannelIDs) == 0 {
+			recoveries = recoverSubscriptions(ctx, deps, state, deps.now())
+		}
 		var successCount, failureCount int
+		for _, result := range renewalResults {
+			if result.Success {
+				successCount++
+			} else {
+				failureCount++
+			}
+		}
 
-		for channelID, subscription := range state.Subscriptions {
-			timeUntilExpiry := subscription.ExpiresAt.Sub(now)
-
-			// Check if subscription needs renewal
-			if timeUntilExpiry <= renewalThreshold {
-				result := renewSubscription(ctx, channelID, subscription, state, deps)
-				renewalResults = append(renewalResults, result)
-
-				if result.Success {
-					successCount++
-				} else {
-					failureCount++
-					// Increment failure count for monitoring
-					subscription.RenewalAttempts++
-				}
+		// Warn about subscriptions that will lapse because they cannot be renewed
+		var expiryAlerts []string
+		if alerter := NewExpiryAlerter(newSinkClient(deps), deps.config()); alerter != nil {
+			if expiryAlerts, err = alerter.Check(ctx, state, deps.now()); err != nil {
+				fmt.Printf("Unable to send expiry alert: %v\n", err)
 			}
 		}
 
-		// Save updated state if there were any changes
-		if len(renewalResults) > 0 {
-			if err := deps.StorageClient.SaveSubscriptionState(ctx, state); err != nil {
-				writeErrorResponse(w, http.StatusInternalServerError, "",
-					fmt.Sprintf("Failed to save subscription state: %v", err))
+		// Save the subscriptions the run changed
+		if err := workingSet.save(ctx, deps.StorageClient); err != nil {
+			message := fmt.Sprintf("Failed to save subscription state: %v", err)
+			if stream != nil {
+				stream.send(RenewalEvent{Type: RenewalEventError, Message: message})
 				return
 			}
+			writeErrorResponse(w, http.StatusInternalServerError, "", message)
+			return
 		}
 
 		// Return renewal summary
 		response := RenewalSummaryResponse{
 			Status:             "success",
-			TotalChecked:       len(state.Subscriptions),
-			RenewalsCandidates: len(renewalResults),
+			TotalChecked:       workingSet.total,
+			RenewalsCandidates: len(candidates),
 			RenewalsSucceeded:  successCount,
 			RenewalsFailed:     failureCount,
+			RenewalsDeferred:   len(candidates) - len(renewalResults),
+			RenewalsBackingOff: waiting,
+			Quarantined:        quarantinedChannels(state),
 			Results:            renewalResults,
+			Forced:             renewReq.Force,
+			NextRenewalAt:      formatOptionalTime(workingSet.nextRenewal(deps.now(), threshold)),
+			ExpiryAlerts:       expiryAlerts,
+			Recoveries:         recoveries,
 		}
 
+		report.FinishedAt = deps.now()
+		report.TotalChecked = response.TotalChecked
+		report.Candidates = response.RenewalsCandidates
+		report.Succeeded = successCount
+		report.Failed = failureCount
+		report.Deferred = response.RenewalsDeferred
+		report.BackingOff = waiting
+		report.Quarantined = response.Quarantined
+		report.Results = renewalResults
+		report.NextRenewalAt = response.NextRenewalAt
+		report.ExpiryAlerts = expiryAlerts
+		report.Recoveries = recoveries
+		// A lost report does not undo the renewals, so it is only logged
+		if response.Report, err = saveRenewalReport(ctx, deps.StorageClient, report); err != nil {
+			fmt.Printf("Unable to save renewal report: %v\n", err)
+		}
+
+		if stream != nil {
+			stream.send(RenewalEvent{Type: RenewalEventSummary, Summary: &response})
+			return
+		}
 		writeJSONResponse(w, http.StatusOK, response)
 	}
 }
 
 // renewSubscription attempts to renew a single subscription using dependency injection.
 func renewSubscription(ctx context.Context, channelID string, subscription *Subscription, state *SubscriptionState, deps *Dependencies) RenewalResult {
-	maxAttempts := getMaxRenewalAttempts()
+	maxAttempts := deps.config().MaxRenewalAttempts
 
 	// Check if we've exceeded max attempts
 	if subscription.RenewalAttempts >= maxAttempts {
@@ -229,56 +391,139 @@ func renewSubscription(ctx context.Context, channelID string, subscription *Subs
 	}
 
 	// Attempt to renew the subscription using injected PubSub client
-	err := deps.PubSubClient.Subscribe(channelID)
+	hubCtx, hubRetries := withHubRetries(ctx)
+	err := resubscribe(hubCtx, deps, channelID, subscription)
+	deps.Metrics.RecordRenewal("renew", err)
 	if err != nil {
 		return RenewalResult{
 			ChannelID:    channelID,
 			Success:      false,
 			Message:      fmt.Sprintf("PubSubHubbub renewal failed: %v", err),
 			AttemptCount: subscription.RenewalAttempts + 1,
+			HubRetries:   *hubRetries,
 		}
 	}
 
-	// Update subscription data
-	subscription.LastRenewal = time.Now()
-	subscription.ExpiresAt = time.Now().Add(time.Duration(getLeaseSeconds()) * time.Second)
+	// Update subscription data. The current lease stays in force until the hub
+	// verifies the renewal and reports the new lease.
+	subscription.LastRenewal = deps.now()
 	subscription.RenewalAttempts = 0
+	subscription.NextRenewalAt = time.Time{}
+	recordRenewalOutcome(subscription, nil)
+	refreshChannelName(ctx, subscription, deps)
 
+	leaseSeconds := subscription.RequestedLeaseSeconds
+	if leaseSeconds <= 0 {
+		leaseSeconds = getLeaseSeconds()
+	}
 	return RenewalResult{
-		ChannelID:     channelID,
-		Success:       true,
-		Message:       "Successfully renewed subscription",
-		AttemptCount:  0,
-		NewExpiryTime: subscription.ExpiresAt.Format(time.RFC3339),
+		ChannelID:          channelID,
+		Success:            true,
+		Message:            "Successfully renewed subscription; pending hub verification",
+		AttemptCount:       0,
+		HubRetries:         *hubRetries,
+		RequestedExpiresAt: formatOptionalTime(subscription.LastRenewal.Add(time.Duration(leaseSeconds) * time.Second)),
 	}
 }
 
-// handleRenewSubscriptions is a compatibility wrapper that uses the refactored function.
-
 // handleNotification handles POST / requests (YouTube notifications) using dependency injection.
 func handleNotification(deps *Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Create notification service with injected dependencies
-		notificationService := &NotificationService{
-			VideoProcessor: NewVideoProcessor(),
-			GitHubClient:   deps.GitHubClient,
-			RepoOwner:      os.Getenv("REPO_OWNER"),
-			RepoName:       os.Getenv("REPO_NAME"),
+		// Notifications from addresses the source lists refuse are turned away unread
+		if err := checkNotificationSource(r, deps.config()); err != nil {
+			fmt.Printf("Refusing notification: %v\n", err)
+			deps.Metrics.RecordNotificationRejected("source")
+			deps.Metrics.RecordNotification(http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden, ErrCodeSourceNotAllowed, "", "Notification source not allowed")
+			return
+		}
+
+		notificationService := newNotificationService(deps)
+
+		// Oversized bodies are cut off while being read and answered with 413
+		maxBodyBytes := deps.config().NotificationMaxBodyBytes
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		}
+
+		// Keep the raw request for GET /debug/notifications
+		buffer := getDebugBuffer()
+		captured := captureNotificationBody(r, buffer)
+
+		// Backfill writes storage could not take, retry earlier failed dispatches whose
+		// backoff has elapsed, dispatch held premieres that went live, and deliver again
+		// those abandoned mid-dispatch. They get at most half the deadline budget,
+		// leaving the rest for this notification.
+		if housekeeping, cancel, err := budgetCall(r.Context(), callHousekeeping, 2); err != nil {
+			fmt.Printf("Skipping due deliveries: %v\n", err)
+		} else {
+			notificationService.flushBackfill(housekeeping)
+			notificationService.retryDueDispatches(housekeeping)
+			notificationService.releaseDuePremieres(housekeeping)
+			notificationService.reconcileStaleOutbox(housekeeping)
+			cancel()
 		}
 
 		result, err := notificationService.ProcessNotification(r)
+
+		statusCode := http.StatusOK
+		errCode := ""
+		var details map[string]interface{}
 		if err != nil {
-			if result.Message == "Failed to read request body" || result.Message == "Invalid XML" {
-				w.WriteHeader(http.StatusBadRequest)
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-			}
-			if _, writeErr := w.Write([]byte(result.Message)); writeErr != nil {
-				fmt.Printf("Error writing response: %v\n", writeErr)
+			var xmlErr *XMLError
+			switch {
+			case errors.Is(err, ErrBodyRead):
+				statusCode, errCode = http.StatusBadRequest, ErrCodeBodyReadFailed
+			case errors.Is(err, ErrBodyTooLarge):
+				statusCode, errCode = http.StatusRequestEntityTooLarge, ErrCodeBodyTooLarge
+				details = map[string]interface{}{"max_bytes": maxBodyBytes}
+			case errors.As(err, &xmlErr):
+				statusCode, errCode = http.StatusBadRequest, ErrCodeInvalidXML
+				details = map[string]interface{}{"reason": xmlErr.Err.Error()}
+			case errors.Is(err, ErrInvalidEntry):
+				statusCode, errCode = http.StatusBadRequest, ErrCodeInvalidEntry
+			case errors.Is(err, ErrMissingSignature), errors.Is(err, ErrInvalidSignature):
+				statusCode, errCode = http.StatusForbidden, ErrCodeInvalidSignature
+			case errors.Is(err, ErrUnsubscribedTopic):
+				statusCode, errCode = http.StatusForbidden, ErrCodeUnknownTopic
+				deps.Metrics.RecordNotificationRejected("topic")
+			case errors.Is(err, ErrDeadlineBudget):
+				// The hub delivers the notification again later
+				statusCode, errCode = http.StatusServiceUnavailable, ErrCodeUnavailable
+			default:
+				statusCode, errCode = http.StatusInternalServerError, ErrCodeInternal
 			}
+		}
+
+		// Notifications processed without error that still failed to reach some sink are
+		// answered with 207 and each sink's delivery status
+		multiStatus := err == nil && result.partiallyDelivered()
+		if multiStatus {
+			statusCode = http.StatusMultiStatus
+		}
+
+		deps.Metrics.RecordNotification(statusCode)
+
+		if captured != nil {
+			entry := newDebugNotification(r, w.Header().Get(RequestIDHeader), captured, statusCode, result)
+			recordDebugNotification(r.Context(), deps, buffer, entry)
+		}
+
+		// Notifications with several entries report each entry's result
+		if len(result.Entries) > 0 || multiStatus {
+			result.Code = errCode
+			result.RequestID = w.Header().Get(RequestIDHeader)
+			w.Header().Set("Content-Type", "application/json")
+			writeJSONResponse(w, statusCode, result)
 			return
 		}
 
+		if err != nil {
+			writeErrorDetails(w, r, statusCode, errCode, "", result.Message, details)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte(result.Message)); err != nil {
 			fmt.Printf("Error writing response: %v\n", err)
@@ -286,95 +531,421 @@ func handleNotification(deps *Dependencies) http.HandlerFunc {
 	}
 }
 
+// newSinkClient creates a webhook sink client using the shared sink HTTP client
+func newSinkClient(deps *Dependencies) *WebhookSinkClient {
+	sinks := NewWebhookSinkClient()
+	sinks.Client = deps.HTTPClients.Client(httpClientSinks, sinks.Client.Timeout)
+	sinks.MaxAttempts = deps.config().SinkMaxAttempts
+	return sinks
+}
+
+// newNotificationService creates a notification service with injected dependencies
+func newNotificationService(deps *Dependencies) *NotificationService {
+	cfg := deps.config()
+	service := &NotificationService{
+		VideoProcessor: newNotificationVideoProcessor(deps.Metrics, deps.clock()),
+		GitHubClient:   deps.GitHubClient,
+		StorageClient:  deps.StorageClient,
+		SinkClient:     newSinkClient(deps),
+		EventPublisher: deps.EventPublisher,
+		Metrics:        deps.Metrics,
+		Registry:       NewProcessedVideoRegistry(deps.StorageClient, cfg),
+		RetryQueue:     NewDispatchRetryQueue(deps.StorageClient, cfg),
+		History:        NewNotificationHistory(deps.StorageClient, cfg),
+		Broadcaster:    deps.Broadcaster,
+		DeadLetters:    NewDeadLetterStore(deps.StorageClient, cfg),
+		Outbox:         NewDispatchOutbox(deps.StorageClient, cfg),
+		Backfill:       deps.StorageBackfill,
+		Stages:         deps.NotificationStages,
+		Config:         cfg,
+		RepoOwner:      cfg.RepoOwner,
+		RepoName:       cfg.RepoName,
+	}
+	service.YouTubeAPI, service.Premieres = newPremiereHandling(deps.StorageClient, cfg.PremiereHandling)
+	return service
+}
+
+// config returns the injected configuration, or parses it from the environment
+func (ns *NotificationService) config() *config.Config {
+	if ns.Config != nil {
+		return ns.Config
+	}
+	return envConfig()
+}
+
 // NotificationService is a version of NotificationService that uses dependency injection.
 type NotificationService struct {
-	VideoProcessor *VideoProcessor
+	VideoProcessor VideoProcessorInterface
 	GitHubClient   GitHubClientInterface
-	RepoOwner      string
+	StorageClient  StorageService           // Optional; records notification activity when set
+	SinkClient     *WebhookSinkClient       // Optional; delivers to per-channel webhook sinks when set
+	EventPublisher EventPublisher           // Optional; publishes video events to Cloud Pub/Sub when set
+	Metrics        *Metrics                 // Optional; counts dispatches when set
+	Registry       *ProcessedVideoRegistry  // Optional; skips videos already dispatched when set
+	RetryQueue     *DispatchRetryQueue      // Optional; queues failed GitHub dispatches for retry when set
+	History        *NotificationHistory     // Optional; logs what was done with each notification when set
+	Broadcaster    *NotificationBroadcaster // Optional; passes each notification to open event streams when set
+	DeadLetters    *DeadLetterStore         // Optional; keeps unparseable notifications for replay when set
+	YouTubeAPI     *YouTubeAPIClient        // Optional; looks up premiere and live-stream state when set
+	Premieres      *PremiereTracker         // Optional; holds upcoming premieres until they go live when set
+	Outbox         *DispatchOutbox          // Optional; records deliveries in progress for reconciliation when set
+	Backfill       *StorageBackfill         // Optional; keeps history and processed videos storage could not take when set
+	Stages         *NotificationStages      // Optional; customises the notification pipeline when set
+	Config         *config.Config           // Optional; settings are parsed from the environment when nil
+	RepoOwner      string                   // Default repository for channels without routes
 	RepoName       string
+
+	pipelineOnce sync.Once
+	pipeline     *notificationPipeline
 }
 
 // NotificationResult represents the result of processing a notification
 type NotificationResult struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string         `json:"status"`
+	Code       string         `json:"code,omitempty"` // Set when the notification failed
+	Message    string         `json:"message"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Deliveries []SinkDelivery `json:"deliveries,omitempty"` // Set when a new video was fanned out to sinks
+	Entries    []EntryResult  `json:"entries,omitempty"`    // Set for notifications with more than one entry
+}
+
+// EntryResult is the result of processing one entry of a notification with several
+type EntryResult struct {
+	VideoID    string         `json:"video_id"`
+	Status     string         `json:"status"`
+	Message    string         `json:"message"`
+	Deliveries []SinkDelivery `json:"deliveries,omitempty"`
 }
 
-// ProcessNotification handles the complete notification processing workflow.
+// ProcessNotification handles the complete notification processing workflow by
+// publishing the request on the service's notification pipeline
 func (ns *NotificationService) ProcessNotification(r *http.Request) (*NotificationResult, error) {
-	// Parse the incoming XML notification
-	entry, err := ns.parseNotification(r)
-	if err != nil {
-		// Map specific error messages to match original behavior
-		var message string
-		if err.Error() == "failed to read request body" {
-			message = "Failed to read request body"
-		} else if err.Error() == "invalid XML" {
-			message = "Invalid XML"
-		} else {
-			message = err.Error()
-		}
-		return &NotificationResult{
-			Status:  "error",
-			Message: message,
-		}, err
+	return ns.notificationPipeline().process(r)
+}
+
+// notificationPipeline returns the service's pipeline, building it on first use
+func (ns *NotificationService) notificationPipeline() *notificationPipeline {
+	ns.pipelineOnce.Do(func() {
+		ns.pipeline = newNotificationPipeline(ns)
+	})
+	return ns.pipeline
+}
+
+// recordActivity records a notification for its subscription's health
+func (ns *NotificationService) recordActivity(ctx context.Context, entry *Entry) {
+	recordNotification(ctx, ns.StorageClient, entry)
+}
+
+// filterNotNew stops entries the video processor does not consider new videos
+func (ns *NotificationService) filterNotNew(ctx context.Context, entry *Entry) *NotificationResult {
+	if ns.VideoProcessor.IsNewVideo(entry) {
+		return nil
 	}
+	return ns.recordHistory(ctx, entry, HistoryDecisionNotNew, &NotificationResult{
+		Status:  "success",
+		Message: fmt.Sprintf("Skipped: Not a new video (VideoID: %s)", entry.VideoID),
+	})
+}
 
-	// Handle empty notifications
-	if entry == nil {
-		return &NotificationResult{
-			Status:  "success",
-			Message: "Empty notification (no entry found)",
-		}, nil
+// filterDuplicate stops videos already handled for an earlier delivery of the same entry
+func (ns *NotificationService) filterDuplicate(ctx context.Context, entry *Entry) *NotificationResult {
+	if !ns.alreadyProcessed(ctx, entry) {
+		return nil
 	}
+	return ns.recordHistory(ctx, entry, HistoryDecisionDuplicate, &NotificationResult{
+		Status:  "success",
+		Message: fmt.Sprintf("Duplicate skipped: video already processed (VideoID: %s)", entry.VideoID),
+	})
+}
 
-	// Check if it's a new video
-	if !ns.VideoProcessor.IsNewVideo(entry) {
-		return &NotificationResult{
-			Status:  "success",
-			Message: fmt.Sprintf("Skipped: Not a new video (VideoID: %s)", entry.VideoID),
-		}, nil
+// filterChannel applies the channel's filter before anything is dispatched
+func (ns *NotificationService) filterChannel(ctx context.Context, entry *Entry) *NotificationResult {
+	reason := filterRejection(ctx, ns.StorageClient, entry)
+	if reason == "" {
+		return nil
 	}
+	ns.markProcessed(ctx, entry)
+	return ns.recordHistory(ctx, entry, HistoryDecisionFiltered, &NotificationResult{
+		Status:  "success",
+		Message: fmt.Sprintf("Filtered: %s (VideoID: %s)", reason, entry.VideoID),
+	})
+}
+
+// deliverEntry fans a new video out to the sinks and dispatches it to every repository
+// routed for its channel
+func (ns *NotificationService) deliverEntry(ctx context.Context, entry *Entry) (*NotificationResult, error) {
+	// Fan out to the webhook and chat sinks and the event topic; failures are reported
+	// per sink but never fail the notification
+	sinkSummary, deliveries := ns.fanOut(ctx, entry)
 
 	// Check GitHub configuration
 	if !ns.GitHubClient.IsConfigured() {
-		return &NotificationResult{
-			Status:  "success",
-			Message: fmt.Sprintf("New video detected but GitHub token not configured (VideoID: %s)", entry.VideoID),
-		}, nil
+		ns.markProcessed(ctx, entry)
+		return ns.recordHistory(ctx, entry, HistoryDecisionNotDispatched, &NotificationResult{
+			Status:     "success",
+			Message:    withSinkSummary(fmt.Sprintf("New video detected but GitHub token not configured (VideoID: %s)", entry.VideoID), sinkSummary),
+			Deliveries: deliveries,
+		}), nil
+	}
+
+	// Trigger the GitHub workflow in every repository routed for this channel
+	targets := resolveRepositories(ctx, ns.StorageClient, entry.SubscriptionID(),
+		RepositoryTarget{RepoOwner: ns.RepoOwner, RepoName: ns.RepoName})
+	dispatchedAt := currentTime(ctx)
+	var dispatched []RepositoryTarget
+	var failedTargets []RepositoryTarget
+	var failedErrs []error
+	var failures []string
+	for i, target := range targets {
+		// Each repository gets an equal share of what is left of the deadline budget;
+		// those it cannot cover fail fast and are queued for retry
+		dispatchCtx, cancel, err := budgetCall(ctx, callGitHub, len(targets)-i)
+		if err == nil {
+			err = dispatchToRepository(dispatchCtx, ns.GitHubClient, target, entry)
+		}
+		cancel()
+		ns.Metrics.RecordDispatch("github", err)
+		deliveries = append(deliveries, newSinkDelivery(TargetTypeGitHub, target.String(), err))
+		if err != nil {
+			failedTargets = append(failedTargets, target)
+			failedErrs = append(failedErrs, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", target, err))
+			continue
+		}
+		dispatched = append(dispatched, target)
+	}
+	if len(failedTargets) > 0 && !ns.queueRetries(ctx, entry, failedTargets, failedErrs) {
+		message := fmt.Sprintf("Failed to trigger GitHub workflow: %v", failedErrs[0])
+		if len(targets) > 1 {
+			message = fmt.Sprintf("Failed to trigger GitHub workflow in %d of %d repositories: %s",
+				len(failures), len(targets), strings.Join(failures, "; "))
+		}
+		return ns.recordHistory(ctx, entry, HistoryDecisionFailed, &NotificationResult{
+			Status:     "error",
+			Message:    withSinkSummary(message, sinkSummary),
+			Deliveries: deliveries,
+		}), failedErrs[0]
 	}
 
-	// Trigger GitHub workflow
-	if err := ns.GitHubClient.TriggerWorkflow(ns.RepoOwner, ns.RepoName, entry); err != nil {
-		return &NotificationResult{
-			Status:  "error",
-			Message: fmt.Sprintf("Failed to trigger GitHub workflow: %v", err),
-		}, err
+	// Every repository was either dispatched or queued for retry
+	ns.markProcessed(ctx, entry)
+	for i := range deliveries {
+		if deliveries[i].Type == TargetTypeGitHub && deliveries[i].Status == DeliveryFailed {
+			deliveries[i].Status = DeliveryQueuedForRetry
+		}
 	}
 
-	return &NotificationResult{
-		Status:  "success",
-		Message: fmt.Sprintf("Successfully triggered workflow for new video: %s", entry.VideoID),
-	}, nil
+	if len(dispatched) == 0 {
+		message := fmt.Sprintf("GitHub dispatch failed and was queued for retry (VideoID: %s): %v", entry.VideoID, failedErrs[0])
+		if len(targets) > 1 {
+			message = fmt.Sprintf("GitHub dispatch failed in %d of %d repositories and was queued for retry (VideoID: %s): %s",
+				len(failures), len(targets), entry.VideoID, strings.Join(failures, "; "))
+		}
+		return ns.recordHistory(ctx, entry, HistoryDecisionQueuedForRetry, &NotificationResult{
+			Status:     "success",
+			Message:    withSinkSummary(message, sinkSummary),
+			Deliveries: deliveries,
+		}), nil
+	}
+
+	message := fmt.Sprintf("Successfully triggered workflow for new video: %s", entry.VideoID)
+	if len(dispatched) > 1 {
+		message += fmt.Sprintf(" in %d repositories", len(dispatched))
+	}
+	if len(failedTargets) > 0 {
+		message += fmt.Sprintf("; %d of %d repositories queued for retry: %s",
+			len(failures), len(targets), strings.Join(failures, "; "))
+	}
+	// Runs are correlated in the first repository only; LastRunURL holds a single run
+	if run := ns.correlateRun(ctx, dispatched[0], entry, dispatchedAt); run != nil {
+		message += fmt.Sprintf(" (run: %s)", run.HTMLURL)
+	}
+
+	return ns.recordHistory(ctx, entry, HistoryDecisionDispatched, &NotificationResult{
+		Status:     "success",
+		Message:    withSinkSummary(message, sinkSummary),
+		Deliveries: deliveries,
+	}), nil
+}
+
+// alreadyProcessed reports whether the video was dispatched for an earlier delivery,
+// including those waiting to be backfilled. Registry failures are logged and the
+// notification is processed anyway.
+func (ns *NotificationService) alreadyProcessed(ctx context.Context, entry *Entry) bool {
+	if ns.Registry == nil {
+		return false
+	}
+	if ns.Backfill != nil && ns.Backfill.WasProcessed(entry.VideoID) {
+		return true
+	}
+	processed, err := ns.Registry.WasProcessed(ctx, entry.VideoID)
+	if err != nil {
+		fmt.Printf("Unable to check processed videos for %s: %v\n", entry.VideoID, err)
+		return false
+	}
+	return processed
+}
+
+// markProcessed records the video so redeliveries are skipped. Failures are logged and,
+// with a backfill, the video is recorded once storage is back.
+func (ns *NotificationService) markProcessed(ctx context.Context, entry *Entry) {
+	if ns.Registry == nil {
+		return
+	}
+	if err := ns.Registry.MarkProcessed(ctx, entry.VideoID); err != nil {
+		fmt.Printf("Unable to record processed video %s: %v\n", entry.VideoID, err)
+		if ns.Backfill != nil {
+			ns.Backfill.deferProcessed(entry.VideoID)
+		}
+	}
+}
+
+// queueRetries records failed dispatches in the retry queue. It reports whether every
+// failure was queued; when it was not, the notification fails so the hub redelivers it.
+func (ns *NotificationService) queueRetries(ctx context.Context, entry *Entry, targets []RepositoryTarget, errs []error) bool {
+	if ns.RetryQueue == nil {
+		return false
+	}
+	for i, target := range targets {
+		if err := ns.RetryQueue.Enqueue(ctx, entry, target, errs[i]); err != nil {
+			fmt.Printf("Unable to queue dispatch of %s to %s for retry: %v\n", entry.VideoID, target, err)
+			return false
+		}
+	}
+	return true
+}
+
+// retryDueDispatches retries queued dispatches whose backoff has elapsed; failures are only logged
+func (ns *NotificationService) retryDueDispatches(ctx context.Context) {
+	if ns.RetryQueue == nil || !ns.GitHubClient.IsConfigured() {
+		return
+	}
+	summary, err := ns.RetryQueue.RetryDue(ctx, ns.GitHubClient, ns.Metrics, false)
+	if err != nil {
+		fmt.Printf("Unable to retry queued dispatches: %v\n", err)
+		return
+	}
+	if summary.Attempted > 0 {
+		fmt.Printf("Retried %d queued dispatches: %d succeeded, %d failed, %d dead-lettered\n",
+			summary.Attempted, summary.Succeeded, summary.Failed, summary.DeadLettered)
+	}
+}
+
+// deliverSinks posts the entry to the channel's webhook sinks and returns a delivery
+// summary and a delivery per sink. Returns "" when no sinks are configured.
+func (ns *NotificationService) deliverSinks(ctx context.Context, entry *Entry) (string, []SinkDelivery) {
+	if ns.SinkClient == nil {
+		return "", nil
+	}
+	sinks := resolveSinks(ctx, ns.StorageClient, entry.SubscriptionID())
+	if len(sinks) == 0 {
+		return "", nil
+	}
+	results := ns.SinkClient.DeliverAll(ctx, sinks, entry)
+	deliveries := make([]SinkDelivery, len(results))
+	for i, result := range results {
+		ns.Metrics.RecordDispatch("sink", result.Err)
+		deliveries[i] = newSinkDelivery(sinks[i].sinkType(), redactSinks(sinks[i : i+1])[0].URL, result.Err)
+		deliveries[i].Attempts = result.Attempts
+	}
+	return summarizeSinkResults(entry, results), deliveries
+}
+
+// publishEvent publishes the entry to the event publisher, which must be configured
+func (ns *NotificationService) publishEvent(ctx context.Context, entry *Entry) error {
+	err := ns.EventPublisher.PublishVideoEvent(ctx, entry)
+	ns.Metrics.RecordDispatch("pubsub", err)
+	if err != nil {
+		fmt.Printf("Unable to publish video event for %s: %v\n", entry.VideoID, err)
+	}
+	return err
 }
 
-// parseNotification parses the XML notification from the request body.
-func (ns *NotificationService) parseNotification(r *http.Request) (*Entry, error) {
+// withSinkSummary appends the webhook sink summary to a notification message
+func withSinkSummary(message, sinkSummary string) string {
+	if sinkSummary == "" {
+		return message
+	}
+	return fmt.Sprintf("%s (%s)", message, sinkSummary)
+}
+
+// correlateRun finds and records the workflow run started by a dispatch when correlation is enabled.
+// Only repository_dispatch runs are looked up. Lookup failures are logged; the dispatch itself already succeeded.
+func (ns *NotificationService) correlateRun(ctx context.Context, target RepositoryTarget, entry *Entry, dispatchedAt time.Time) *WorkflowRun {
+	timeout := ns.config().RunCorrelationTimeout
+	if timeout == 0 || target.Mode == DispatchModeWorkflow {
+		return nil
+	}
+	// Polling stops in time to answer within the deadline budget
+	share, err := budgetShare(ctx, callRunCorrelation, 1)
+	if err != nil {
+		fmt.Printf("Not looking up the workflow run for video %s: %v\n", entry.VideoID, err)
+		return nil
+	}
+	if share > 0 && share < timeout {
+		timeout = share
+	}
+
+	run, err := correlateWorkflowRun(ctx, ns.GitHubClient, target.RepoOwner, target.RepoName, dispatchID(entry), dispatchedAt, timeout)
+	if err != nil {
+		fmt.Printf("Unable to find workflow run for video %s: %v\n", entry.VideoID, err)
+		return nil
+	}
+	if run == nil {
+		fmt.Printf("No workflow run found for video %s within %s\n", entry.VideoID, timeout)
+		return nil
+	}
+
+	recordWorkflowRun(ctx, ns.StorageClient, entry, run)
+	return run
+}
+
+// parseNotification reads and decodes the XML notification from the request body. The
+// raw body is returned for signature verification, and with invalid XML for dead-lettering.
+func (ns *NotificationService) parseNotification(r *http.Request) (*AtomFeed, []byte, error) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read request body")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, nil, fmt.Errorf("%w: the limit is %d bytes", ErrBodyTooLarge, tooLarge.Limit)
+		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrBodyRead, err)
 	}
 
 	var feed AtomFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
-		return nil, fmt.Errorf("invalid XML")
+	if err := decodeNotificationXML(body, &feed); err != nil {
+		return nil, body, &XMLError{Err: err}
 	}
+	return &feed, body, nil
+}
 
-	if feed.Entry == nil {
-		return nil, nil
+// validateNotification checks a parsed notification's entries, attributes those from a
+// playlist's feed to the playlist, and rejects notifications not signed with the
+// subscription's hub.secret
+func (ns *NotificationService) validateNotification(r *http.Request, body []byte, feed *AtomFeed) error {
+	if err := ns.VideoProcessor.ValidateFeed(feed); err != nil {
+		if errors.Is(err, ErrInvalidEntry) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrInvalidEntry, err)
 	}
 
-	return feed.Entry, nil
-}
+	// Entries from a playlist's feed are routed by the playlist's subscription
+	if playlistID := feedPlaylistID(feed); playlistID != "" {
+		for _, entry := range feed.Entries {
+			entry.PlaylistID = playlistID
+		}
+	}
+
+	cfg := ns.config()
+	if err := checkNotificationTopic(r.Context(), ns.StorageClient, r, feed, cfg.RequireSubscribedTopic); err != nil {
+		return err
+	}
 
-// handleNotification is a compatibility wrapper that uses the refactored function.
+	// Every entry is for the same channel or playlist
+	err := checkNotificationSignature(r.Context(), ns.StorageClient, feed.Entries[0].SubscriptionID(), r.Header.Get(HubSignatureHeader), body, cfg.RequireHubSignature)
+	if err != nil && !errors.Is(err, ErrMissingSignature) && !errors.Is(err, ErrInvalidSignature) {
+		return fmt.Errorf("%w: %v", ErrUnverifiedSignature, err)
+	}
+	return err
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +14,7 @@ import (
 func TestHandleNotification_Success(t *testing.T) {
 	// Create test dependencies
 	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UC1234567890123456789012")
 	mockGitHub := deps.GitHubClient.(*MockGitHubClient)
 	mockGitHub.SetConfigured(true)
 
@@ -33,18 +35,18 @@ func TestHandleNotification_Success(t *testing.T) {
 <feed xmlns:yt="http://www.youtube.com/xml/schemas/2015"
       xmlns="http://www.w3.org/2005/Atom">
   <link rel="hub" href="https://pubsubhubbub.appspot.com"/>
-  <link rel="self" href="https://www.youtube.com/xml/feeds/videos.xml?channel_id=UC123456789012345678901"/>
+  <link rel="self" href="https://www.youtube.com/xml/feeds/videos.xml?channel_id=UC1234567890123456789012"/>
   <title>YouTube video feed</title>
   <updated>%s</updated>
   <entry>
     <id>yt:video:test123</id>
     <yt:videoId>test123</yt:videoId>
-    <yt:channelId>UC123456789012345678901</yt:channelId>
+    <yt:channelId>UC1234567890123456789012</yt:channelId>
     <title>Test Video</title>
     <link rel="alternate" href="http://www.youtube.com/watch?v=test123"/>
     <author>
       <name>Test Channel</name>
-      <uri>http://www.youtube.com/channel/UC123456789012345678901</uri>
+      <uri>http://www.youtube.com/channel/UC1234567890123456789012</uri>
     </author>
     <published>%s</published>
     <updated>%s</updated>
@@ -86,6 +88,7 @@ func TestHandleNotification_Success(t *testing.T) {
 func TestHandleNotification_GitHubNotConfigured(t *testing.T) {
 	// Create test dependencies with unconfigured GitHub
 	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UC1234567890123456789012")
 	mockGitHub := deps.GitHubClient.(*MockGitHubClient)
 	mockGitHub.SetConfigured(false)
 
@@ -108,7 +111,7 @@ func TestHandleNotification_GitHubNotConfigured(t *testing.T) {
   <entry>
     <id>yt:video:test123</id>
     <yt:videoId>test123</yt:videoId>
-    <yt:channelId>UC123456789012345678901</yt:channelId>
+    <yt:channelId>UC1234567890123456789012</yt:channelId>
     <title>Test Video</title>
     <published>%s</published>
     <updated>%s</updated>
@@ -157,9 +160,15 @@ func TestHandleNotification_InvalidXML(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
 	}
 
-	body := rec.Body.String()
-	if body != "Invalid XML" {
-		t.Errorf("Expected 'Invalid XML', got: %s", body)
+	var envelope APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("Expected JSON error envelope, got: %s", rec.Body.String())
+	}
+	if envelope.Code != ErrCodeInvalidXML || envelope.Message != "Invalid XML" {
+		t.Errorf("Expected invalid_xml envelope, got: %s", rec.Body.String())
+	}
+	if reason, _ := envelope.Details["reason"].(string); reason == "" {
+		t.Errorf("Expected the parse error in details, got: %s", rec.Body.String())
 	}
 }
 
@@ -196,6 +205,7 @@ func TestHandleNotification_EmptyNotification(t *testing.T) {
 func TestHandleNotification_NotNewVideo(t *testing.T) {
 	// Create test dependencies
 	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UC1234567890123456789012")
 
 	// Create test XML for old video (published more than 1 hour ago)
 	oldTime := time.Now().Add(-2 * time.Hour)
@@ -208,7 +218,7 @@ func TestHandleNotification_NotNewVideo(t *testing.T) {
   <entry>
     <id>yt:video:test123</id>
     <yt:videoId>test123</yt:videoId>
-    <yt:channelId>UC123456789012345678901</yt:channelId>
+    <yt:channelId>UC1234567890123456789012</yt:channelId>
     <title>Test Video</title>
     <published>%s</published>
     <updated>%s</updated>
@@ -237,6 +247,7 @@ func TestHandleNotification_NotNewVideo(t *testing.T) {
 func TestHandleNotification_GitHubTriggerError(t *testing.T) {
 	// Create test dependencies
 	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UC1234567890123456789012")
 	mockGitHub := deps.GitHubClient.(*MockGitHubClient)
 	mockGitHub.SetConfigured(true)
 	mockGitHub.SetTriggerError(fmt.Errorf("GitHub API error"))
@@ -260,7 +271,7 @@ func TestHandleNotification_GitHubTriggerError(t *testing.T) {
   <entry>
     <id>yt:video:test123</id>
     <yt:videoId>test123</yt:videoId>
-    <yt:channelId>UC123456789012345678901</yt:channelId>
+    <yt:channelId>UC1234567890123456789012</yt:channelId>
     <title>Test Video</title>
     <published>%s</published>
     <updated>%s</updated>
@@ -275,14 +286,14 @@ func TestHandleNotification_GitHubTriggerError(t *testing.T) {
 	handler := handleNotification(deps)
 	handler(rec, req)
 
-	// Verify response
-	if rec.Code != http.StatusInternalServerError {
-		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	// Verify the failed dispatch is queued for retry rather than failing the notification
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
 	}
 
 	body := rec.Body.String()
-	if !strings.Contains(body, "Failed to trigger GitHub workflow") {
-		t.Errorf("Expected GitHub workflow error, got: %s", body)
+	if !strings.Contains(body, "GitHub dispatch failed and was queued for retry") {
+		t.Errorf("Expected queued dispatch message, got: %s", body)
 	}
 }
 
@@ -304,9 +315,12 @@ func TestHandleNotification_ReadBodyError(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
 	}
 
-	body := rec.Body.String()
-	if body != "Failed to read request body" {
-		t.Errorf("Expected 'Failed to read request body', got: %s", body)
+	var envelope APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("Expected JSON error envelope, got: %s", rec.Body.String())
+	}
+	if envelope.Code != ErrCodeBodyReadFailed || envelope.Message != "Failed to read request body" {
+		t.Errorf("Expected body_read_failed envelope, got: %s", rec.Body.String())
 	}
 }
 
@@ -324,6 +338,7 @@ func (fr *testFailingReader) Close() error {
 func TestNotificationService_ProcessNotification_ThreadSafety(t *testing.T) {
 	// Create test dependencies
 	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UC1234567890123456789012")
 	mockGitHub := deps.GitHubClient.(*MockGitHubClient)
 	mockGitHub.SetConfigured(true)
 
@@ -340,18 +355,21 @@ func TestNotificationService_ProcessNotification_ThreadSafety(t *testing.T) {
 	published := now.Add(-10 * time.Minute).Format(time.RFC3339)
 	updated := now.Add(-9 * time.Minute).Format(time.RFC3339)
 
-	testXML := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+	// Distinct video IDs so duplicate suppression doesn't collapse the calls
+	testXML := func(videoID string) string {
+		return fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
 <feed xmlns:yt="http://www.youtube.com/xml/schemas/2015"
       xmlns="http://www.w3.org/2005/Atom">
   <entry>
-    <id>yt:video:test123</id>
-    <yt:videoId>test123</yt:videoId>
-    <yt:channelId>UC123456789012345678901</yt:channelId>
+    <id>yt:video:%s</id>
+    <yt:videoId>%s</yt:videoId>
+    <yt:channelId>UC1234567890123456789012</yt:channelId>
     <title>Test Video</title>
     <published>%s</published>
     <updated>%s</updated>
   </entry>
-</feed>`, published, updated)
+</feed>`, videoID, videoID, published, updated)
+	}
 
 	// Test concurrent access
 	const numGoroutines = 10
@@ -360,8 +378,8 @@ func TestNotificationService_ProcessNotification_ThreadSafety(t *testing.T) {
 	handler := handleNotification(deps)
 
 	for i := 0; i < numGoroutines; i++ {
-		go func() {
-			req := httptest.NewRequest("POST", "/", strings.NewReader(testXML))
+		go func(i int) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(testXML(fmt.Sprintf("test%d", i))))
 			rec := httptest.NewRecorder()
 			handler(rec, req)
 
@@ -369,7 +387,7 @@ func TestNotificationService_ProcessNotification_ThreadSafety(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
 			}
 			done <- true
-		}()
+		}(i)
 	}
 
 	// Wait for all goroutines to complete
@@ -405,7 +423,7 @@ func TestHandleNotification_DependencyInjection(t *testing.T) {
   <entry>
     <id>yt:video:test123</id>
     <yt:videoId>test123</yt:videoId>
-    <yt:channelId>UC123456789012345678901</yt:channelId>
+    <yt:channelId>UC1234567890123456789012</yt:channelId>
     <title>Test Video</title>
     <published>%s</published>
     <updated>%s</updated>
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// Subscription health indicators
+const (
+	HealthHealthy = "healthy"
+	HealthStale   = "stale"
+	HealthBroken  = "broken"
+)
+
+// Renewal outcomes recorded on each subscription
+const (
+	RenewalStatusSuccess = "success"
+	RenewalStatusFailed  = "failed"
+)
+
+// HealthThresholds controls when a quiet subscription is considered stale or broken
+type HealthThresholds struct {
+	StaleAfter  time.Duration
+	BrokenAfter time.Duration
+}
+
+// SubscriptionHealth is the computed health of a single subscription
+type SubscriptionHealth struct {
+	Indicator              string   `json:"health"`
+	Reasons                []string `json:"health_reasons,omitempty"`
+	LastNotificationAt     string   `json:"last_notification_at,omitempty"`
+	HoursSinceNotification *float64 `json:"hours_since_notification,omitempty"`
+	LastRenewalSucceeded   *bool    `json:"last_renewal_succeeded,omitempty"`
+}
+
+// getHealthThresholds returns the staleness thresholds from the environment
+func getHealthThresholds() HealthThresholds {
+	return healthThresholds(envConfig())
+}
+
+// healthThresholds returns the staleness thresholds configured in cfg
+func healthThresholds(cfg *config.Config) HealthThresholds {
+	return HealthThresholds{StaleAfter: cfg.HealthStaleAfter, BrokenAfter: cfg.HealthBrokenAfter}
+}
+
+// computeSubscriptionHealth derives the health indicator for a subscription.
+//
+// A subscription is broken when its lease has lapsed, its last renewal failed, or it has
+// been silent for longer than BrokenAfter. It is stale when it has been silent for longer
+// than StaleAfter. Subscriptions that have never received a notification are measured
+// from when they were subscribed.
+func computeSubscriptionHealth(sub *Subscription, now time.Time, thresholds HealthThresholds) SubscriptionHealth {
+	health := SubscriptionHealth{Indicator: HealthHealthy}
+
+	quietSince := sub.SubscribedAt
+	if !sub.LastNotificationAt.IsZero() {
+		quietSince = sub.LastNotificationAt
+		health.LastNotificationAt = sub.LastNotificationAt.Format(timeFormat())
+		hours := now.Sub(sub.LastNotificationAt).Hours()
+		health.HoursSinceNotification = &hours
+	}
+
+	if sub.LastRenewalStatus != "" {
+		succeeded := sub.LastRenewalStatus == RenewalStatusSuccess
+		health.LastRenewalSucceeded = &succeeded
+	}
+
+	if !sub.ExpiresAt.IsZero() && sub.ExpiresAt.Before(now) {
+		health.Reasons = append(health.Reasons, "lease expired")
+	}
+	if sub.Status == StatusQuarantined {
+		health.Reasons = append(health.Reasons,
+			fmt.Sprintf("quarantined after %d failed renewals", sub.RenewalAttempts))
+	}
+	if sub.LastRenewalStatus == RenewalStatusFailed {
+		reason := "last renewal failed"
+		if sub.LastRenewalError != "" {
+			reason = fmt.Sprintf("last renewal failed: %s", sub.LastRenewalError)
+		}
+		health.Reasons = append(health.Reasons, reason)
+	}
+
+	quietFor := time.Duration(0)
+	if !quietSince.IsZero() {
+		quietFor = now.Sub(quietSince)
+	}
+
+	switch {
+	case quietFor > thresholds.BrokenAfter:
+		health.Reasons = append(health.Reasons,
+			fmt.Sprintf("no notifications for %.0f hours", quietFor.Hours()))
+		health.Indicator = HealthBroken
+	case len(health.Reasons) > 0:
+		health.Indicator = HealthBroken
+	case quietFor > thresholds.StaleAfter:
+		health.Reasons = append(health.Reasons,
+			fmt.Sprintf("no notifications for %.0f hours", quietFor.Hours()))
+		health.Indicator = HealthStale
+	}
+
+	return health
+}
+
+// recordRenewalOutcome stores the result of the most recent renewal attempt on the subscription
+func recordRenewalOutcome(sub *Subscription, err error) {
+	if err != nil {
+		sub.LastRenewalStatus = RenewalStatusFailed
+		sub.LastRenewalError = err.Error()
+		return
+	}
+	sub.LastRenewalStatus = RenewalStatusSuccess
+	sub.LastRenewalError = ""
+}
+
+// recordNotification marks the channel's subscription as having received a notification.
+// Failures are logged rather than returned so notification processing is never blocked.
+func recordNotification(ctx context.Context, storage StorageService, entry *Entry) {
+	if storage == nil || entry == nil || entry.SubscriptionID() == "" {
+		return
+	}
+
+	sub, err := storage.GetSubscription(ctx, entry.SubscriptionID())
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Unable to record notification for %s: %v\n", entry.SubscriptionID(), err)
+		return
+	}
+	sub.LastNotificationAt = currentTime(ctx)
+	markNotified(sub)
+	if entry.VideoID != "" && entry.VideoID != sub.LastVideoID {
+		sub.LastVideoID = entry.VideoID
+		sub.LastRunID = 0
+		sub.LastRunURL = ""
+	}
+
+	if err := storage.SaveSubscription(ctx, sub); err != nil {
+		fmt.Printf("Unable to record notification for %s: %v\n", entry.SubscriptionID(), err)
+	}
+}
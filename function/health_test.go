@@ -0,0 +1,296 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeSubscriptionHealth(t *testing.T) {
+	now := time.Now()
+	thresholds := HealthThresholds{StaleAfter: 24 * time.Hour, BrokenAfter: 72 * time.Hour}
+
+	testCases := []struct {
+		name      string
+		sub       *Subscription
+		indicator string
+	}{
+		{
+			name: "recent_notification_is_healthy",
+			sub: &Subscription{
+				SubscribedAt:       now.Add(-100 * time.Hour),
+				ExpiresAt:          now.Add(10 * time.Hour),
+				LastNotificationAt: now.Add(-1 * time.Hour),
+				LastRenewalStatus:  RenewalStatusSuccess,
+			},
+			indicator: HealthHealthy,
+		},
+		{
+			name: "new_subscription_without_notifications_is_healthy",
+			sub: &Subscription{
+				SubscribedAt: now.Add(-1 * time.Hour),
+				ExpiresAt:    now.Add(10 * time.Hour),
+			},
+			indicator: HealthHealthy,
+		},
+		{
+			name: "quiet_channel_is_stale",
+			sub: &Subscription{
+				SubscribedAt:       now.Add(-100 * time.Hour),
+				ExpiresAt:          now.Add(10 * time.Hour),
+				LastNotificationAt: now.Add(-30 * time.Hour),
+			},
+			indicator: HealthStale,
+		},
+		{
+			name: "silent_channel_is_broken",
+			sub: &Subscription{
+				SubscribedAt: now.Add(-100 * time.Hour),
+				ExpiresAt:    now.Add(10 * time.Hour),
+			},
+			indicator: HealthBroken,
+		},
+		{
+			name: "failed_renewal_is_broken",
+			sub: &Subscription{
+				SubscribedAt:       now.Add(-2 * time.Hour),
+				ExpiresAt:          now.Add(10 * time.Hour),
+				LastNotificationAt: now.Add(-1 * time.Hour),
+				LastRenewalStatus:  RenewalStatusFailed,
+				LastRenewalError:   "hub returned 500",
+			},
+			indicator: HealthBroken,
+		},
+		{
+			name: "expired_lease_is_broken",
+			sub: &Subscription{
+				SubscribedAt:       now.Add(-2 * time.Hour),
+				ExpiresAt:          now.Add(-1 * time.Hour),
+				LastNotificationAt: now.Add(-1 * time.Hour),
+			},
+			indicator: HealthBroken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			health := computeSubscriptionHealth(tc.sub, now, thresholds)
+			assert.Equal(t, tc.indicator, health.Indicator)
+			if tc.indicator == HealthHealthy {
+				assert.Empty(t, health.Reasons)
+			} else {
+				assert.NotEmpty(t, health.Reasons)
+			}
+		})
+	}
+
+	t.Run("reports_time_since_notification_and_renewal_outcome", func(t *testing.T) {
+		sub := &Subscription{
+			SubscribedAt:       now.Add(-10 * time.Hour),
+			ExpiresAt:          now.Add(10 * time.Hour),
+			LastNotificationAt: now.Add(-2 * time.Hour),
+			LastRenewalStatus:  RenewalStatusFailed,
+			LastRenewalError:   "hub returned 500",
+		}
+		health := computeSubscriptionHealth(sub, now, thresholds)
+
+		require.NotNil(t, health.HoursSinceNotification)
+		assert.InDelta(t, 2.0, *health.HoursSinceNotification, 0.01)
+		require.NotNil(t, health.LastRenewalSucceeded)
+		assert.False(t, *health.LastRenewalSucceeded)
+		assert.Contains(t, strings.Join(health.Reasons, ","), "hub returned 500")
+	})
+}
+
+func TestGetHealthThresholds(t *testing.T) {
+	os.Unsetenv("HEALTH_STALE_AFTER_HOURS")
+	os.Unsetenv("HEALTH_BROKEN_AFTER_HOURS")
+	thresholds := getHealthThresholds()
+	assert.Equal(t, 7*24*time.Hour, thresholds.StaleAfter)
+	assert.Equal(t, 30*24*time.Hour, thresholds.BrokenAfter)
+
+	os.Setenv("HEALTH_STALE_AFTER_HOURS", "48")
+	os.Setenv("HEALTH_BROKEN_AFTER_HOURS", "bogus")
+	defer func() {
+		os.Unsetenv("HEALTH_STALE_AFTER_HOURS")
+		os.Unsetenv("HEALTH_BROKEN_AFTER_HOURS")
+	}()
+	thresholds = getHealthThresholds()
+	assert.Equal(t, 48*time.Hour, thresholds.StaleAfter)
+	assert.Equal(t, 30*24*time.Hour, thresholds.BrokenAfter)
+}
+
+func TestGetSubscriptions_HealthCounts(t *testing.T) {
+	deps := CreateTestDependencies()
+	now := time.Now()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(
+		&Subscription{
+			ChannelID:          "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			SubscribedAt:       now.Add(-time.Hour),
+			ExpiresAt:          now.Add(10 * time.Hour),
+			LastNotificationAt: now.Add(-time.Hour),
+		},
+		&Subscription{
+			ChannelID:    "UC_x5XG1OV2P6uZZ5FSM9Ttw",
+			SubscribedAt: now.Add(-48 * time.Hour),
+			ExpiresAt:    now.Add(-time.Hour),
+		},
+	))
+
+	req := httptest.NewRequest("GET", "/subscriptions", nil)
+	w := httptest.NewRecorder()
+	handleGetSubscriptions(deps)(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response SubscriptionsListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	assert.Equal(t, 1, response.Healthy)
+	assert.Equal(t, 1, response.Broken)
+	assert.Contains(t, w.Body.String(), `"health":"healthy"`)
+	assert.Contains(t, w.Body.String(), `"health":"broken"`)
+}
+
+func TestGetSubscription_Detail(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	now := time.Now()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID:          "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		SubscribedAt:       now.Add(-time.Hour),
+		ExpiresAt:          now.Add(10 * time.Hour),
+		LastNotificationAt: now.Add(-time.Hour),
+		LastRenewalStatus:  RenewalStatusSuccess,
+	}))
+
+	t.Run("found", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw", nil)
+		w := httptest.NewRecorder()
+		YouTubeWebhook(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response SubscriptionDetailResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, HealthHealthy, response.Indicator)
+		require.NotNil(t, response.LastRenewalSucceeded)
+		assert.True(t, *response.LastRenewalSucceeded)
+		require.NotNil(t, response.Subscription)
+		assert.Equal(t, "UCXuqSBlHAE6Xw-yeJA0Tunw", response.Subscription.ChannelID)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/subscriptions/UC_x5XG1OV2P6uZZ5FSM9Ttw", nil)
+		w := httptest.NewRecorder()
+		YouTubeWebhook(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid_channel", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/subscriptions/not-a-channel", nil)
+		w := httptest.NewRecorder()
+		YouTubeWebhook(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("storage_error", func(t *testing.T) {
+		deps.StorageClient.(*MockStorageClient).LoadError = ErrMockLoadFailure
+		defer func() { deps.StorageClient.(*MockStorageClient).LoadError = nil }()
+
+		req := httptest.NewRequest("GET", "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw", nil)
+		w := httptest.NewRecorder()
+		YouTubeWebhook(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestRecordNotification(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("updates_known_subscription", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}))
+
+		recordNotification(ctx, storage, &Entry{VideoID: "v1", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+
+		saved := storage.GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"]
+		assert.False(t, saved.LastNotificationAt.IsZero())
+		assert.Equal(t, "v1", saved.LastVideoID)
+	})
+
+	t.Run("new_video_clears_previous_run", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", LastVideoID: "v1", LastRunID: 5, LastRunURL: "https://github.com/runs/5",
+		}))
+
+		recordNotification(ctx, storage, &Entry{VideoID: "v1", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+		assert.Equal(t, int64(5), storage.GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"].LastRunID)
+
+		recordNotification(ctx, storage, &Entry{VideoID: "v2", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+		saved := storage.GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"]
+		assert.Equal(t, int64(0), saved.LastRunID)
+		assert.Empty(t, saved.LastRunURL)
+	})
+
+	t.Run("ignores_unknown_channel", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		recordNotification(ctx, storage, &Entry{VideoID: "v1", ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw"})
+		assert.Equal(t, 0, storage.SaveCallCount)
+	})
+
+	t.Run("tolerates_storage_errors", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}))
+		storage.SaveError = ErrMockSaveFailure
+		recordNotification(ctx, storage, &Entry{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+
+		storage.LoadError = ErrMockLoadFailure
+		recordNotification(ctx, storage, &Entry{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+	})
+
+	t.Run("nil_inputs", func(t *testing.T) {
+		recordNotification(ctx, nil, &Entry{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+		recordNotification(ctx, NewMockStorageClient(), nil)
+	})
+}
+
+func TestRecordRenewalOutcome(t *testing.T) {
+	sub := &Subscription{}
+
+	recordRenewalOutcome(sub, errors.New("hub down"))
+	assert.Equal(t, RenewalStatusFailed, sub.LastRenewalStatus)
+	assert.Equal(t, "hub down", sub.LastRenewalError)
+
+	recordRenewalOutcome(sub, nil)
+	assert.Equal(t, RenewalStatusSuccess, sub.LastRenewalStatus)
+	assert.Empty(t, sub.LastRenewalError)
+}
+
+func TestRenewal_RecordsOutcome(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(fmt.Errorf("hub down"))
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	req := httptest.NewRequest("POST", "/renew", nil)
+	w := httptest.NewRecorder()
+	handleRenewSubscriptions(deps)(w, req)
+
+	saved := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"]
+	assert.Equal(t, RenewalStatusFailed, saved.LastRenewalStatus)
+	assert.Contains(t, saved.LastRenewalError, "hub down")
+}
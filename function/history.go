@@ -0,0 +1,260 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// historyObjectPrefix is where each channel's notification history is stored
+const historyObjectPrefix = "history/"
+
+// Decisions recorded in the notification history
+const (
+	HistoryDecisionNotNew         = "not_new"
+	HistoryDecisionDuplicate      = "duplicate"
+	HistoryDecisionFiltered       = "filtered"
+	HistoryDecisionNotDispatched  = "not_dispatched" // GitHub token not configured
+	HistoryDecisionDispatched     = "dispatched"
+	HistoryDecisionQueuedForRetry = "queued_for_retry"
+	HistoryDecisionFailed         = "failed"
+	HistoryDecisionReplayed       = "replayed"      // Re-dispatched with POST /replay
+	HistoryDecisionPremiereHeld   = "premiere_held" // Upcoming premiere held until it goes live
+)
+
+// HistoryEntry records what was done with one processed notification
+type HistoryEntry struct {
+	ID         string         `json:"id,omitempty"` // Event ID, unique even among entries received together
+	ReceivedAt time.Time      `json:"received_at"`
+	VideoID    string         `json:"video_id"`
+	Title      string         `json:"title"`
+	Published  string         `json:"published,omitempty"`
+	Updated    string         `json:"updated,omitempty"`
+	Decision   string         `json:"decision"`
+	Result     string         `json:"result"`               // Dispatch result, as reported to the hub
+	Deliveries []SinkDelivery `json:"deliveries,omitempty"` // Status of each sink the video was fanned out to
+
+	LiveBroadcastContent string `json:"live_broadcast_content,omitempty"` // Premiere state, when PREMIERE_HANDLING is enabled
+	HeldAt               string `json:"held_at,omitempty"`                // When dispatch was held for the premiere to go live
+
+	ChannelID string `json:"channel_id,omitempty"` // Channel that published the video, recorded in playlists' histories
+}
+
+// entry rebuilds the notification entry a history entry was recorded for in the
+// history of channelID, a channel or playlist
+func (e *HistoryEntry) entry(channelID string) *Entry {
+	entry := &Entry{
+		VideoID:   e.VideoID,
+		ChannelID: channelID,
+		Title:     e.Title,
+		Published: e.Published,
+		Updated:   e.Updated,
+		Link:      Link{Href: "https://www.youtube.com/watch?v=" + e.VideoID},
+	}
+	if topicTypeOf(channelID) == TopicTypePlaylist {
+		entry.ChannelID = e.ChannelID
+		entry.PlaylistID = channelID
+	}
+	return entry
+}
+
+// notificationHistory is one channel's stored history, oldest entry first
+type notificationHistory struct {
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// HistoryResponse lists a channel's processed notifications, newest first
+type HistoryResponse struct {
+	Status    string         `json:"status"`
+	ChannelID string         `json:"channel_id"`
+	Count     int            `json:"count"`
+	Entries   []HistoryEntry `json:"entries"`
+}
+
+// NotificationHistory keeps an append-only log of the notifications processed for
+// each channel, capped at NOTIFICATION_HISTORY_SIZE entries per channel.
+type NotificationHistory struct {
+	store ObjectStorage
+	size  int
+	mu    sync.Mutex
+}
+
+// getNotificationHistorySize returns how many notifications are kept per channel.
+// NOTIFICATION_HISTORY_SIZE=0 disables the history.
+func getNotificationHistorySize() int {
+	return envConfig().NotificationHistorySize
+}
+
+// NewNotificationHistory creates a notification history in the given storage.
+// Returns nil (no history is kept) when the storage cannot hold objects or the size is zero.
+func NewNotificationHistory(storage StorageService, cfg *config.Config) *NotificationHistory {
+	store, ok := storage.(ObjectStorage)
+	size := cfg.NotificationHistorySize
+	if !ok || size == 0 {
+		return nil
+	}
+	return &NotificationHistory{store: store, size: size}
+}
+
+// historyObject returns the object a channel's history is stored in
+func historyObject(channelID string) string {
+	return historyObjectPrefix + channelID + ".json"
+}
+
+// load reads a channel's history, treating a missing object as empty
+func (h *NotificationHistory) load(ctx context.Context, channelID string) (*notificationHistory, error) {
+	history := &notificationHistory{}
+
+	data, err := h.store.ReadObject(ctx, historyObject(channelID))
+	if errors.Is(err, ErrObjectNotFound) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification history: %v", err)
+	}
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("failed to decode notification history: %v", err)
+	}
+	return history, nil
+}
+
+// Append adds an entry to the channel's history, dropping the oldest entries beyond the cap
+func (h *NotificationHistory) Append(ctx context.Context, channelID string, entry HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history, err := h.load(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	history.Entries = append(history.Entries, entry)
+	if excess := len(history.Entries) - h.size; excess > 0 {
+		history.Entries = history.Entries[excess:]
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification history: %v", err)
+	}
+	if err := h.store.WriteObject(ctx, historyObject(channelID), data); err != nil {
+		return fmt.Errorf("failed to write notification history: %v", err)
+	}
+	return nil
+}
+
+// List returns up to limit of the channel's entries, newest first. A limit of zero returns them all.
+func (h *NotificationHistory) List(ctx context.Context, channelID string, limit int) ([]HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history, err := h.load(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]HistoryEntry, 0, len(history.Entries))
+	for i := len(history.Entries) - 1; i >= 0; i-- {
+		entries = append(entries, history.Entries[i])
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// Find returns the most recent entry for a video in the channel's history, or nil when
+// the video is not in it
+func (h *NotificationHistory) Find(ctx context.Context, channelID, videoID string) (*HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history, err := h.load(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(history.Entries) - 1; i >= 0; i-- {
+		if history.Entries[i].VideoID == videoID {
+			return &history.Entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// recordHistory appends the decision taken for an entry to its channel's or playlist's
+// history, passes it to open event streams and returns the result unchanged. Failures
+// are logged and, with a backfill, the entry is appended once storage is back.
+func (ns *NotificationService) recordHistory(ctx context.Context, entry *Entry, decision string, result *NotificationResult) *NotificationResult {
+	receivedAt := currentTime(ctx)
+	historyEntry := HistoryEntry{
+		ID:         newEventID(receivedAt),
+		ReceivedAt: receivedAt,
+		VideoID:    entry.VideoID,
+		Title:      entry.Title,
+		Published:  entry.Published,
+		Updated:    entry.Updated,
+		Decision:   decision,
+		Result:     result.Message,
+		Deliveries: result.Deliveries,
+
+		LiveBroadcastContent: entry.LiveBroadcastContent,
+		HeldAt:               formatOptionalTime(entry.HeldAt),
+	}
+	if entry.PlaylistID != "" {
+		historyEntry.ChannelID = entry.ChannelID
+	}
+	ns.Broadcaster.Publish(NotificationEvent{ChannelID: entry.SubscriptionID(), HistoryEntry: historyEntry})
+
+	if ns.History == nil {
+		return result
+	}
+	if err := ns.History.Append(ctx, entry.SubscriptionID(), historyEntry); err != nil {
+		fmt.Printf("Unable to record notification history for %s: %v\n", entry.SubscriptionID(), err)
+		if ns.Backfill != nil {
+			ns.Backfill.deferHistory(entry.SubscriptionID(), historyEntry)
+		}
+	}
+	return result
+}
+
+// handleGetHistory handles GET /subscriptions/{channel_id}/history requests using dependency injection.
+// ?limit=N returns only the N most recent notifications.
+func handleGetHistory(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		limit := 0
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit < 1 {
+				writeErrorResponse(w, http.StatusBadRequest, channelID, "limit must be a positive integer")
+				return
+			}
+		}
+
+		history := NewNotificationHistory(deps.StorageClient, deps.config())
+		if history == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, channelID,
+				"Notification history is disabled or not supported by the storage backend")
+			return
+		}
+
+		entries, err := history.List(r.Context(), channelID, limit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID, err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, HistoryResponse{
+			Status:    "success",
+			ChannelID: channelID,
+			Count:     len(entries),
+			Entries:   entries,
+		})
+	}
+}
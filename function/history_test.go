@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const historyTestChannel = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+func TestGetNotificationHistorySize(t *testing.T) {
+	defer os.Unsetenv("NOTIFICATION_HISTORY_SIZE")
+
+	testCases := []struct {
+		value    string
+		expected int
+	}{
+		{"", 100},
+		{"25", 25},
+		{"0", 0},
+		{"-1", 100},
+		{"invalid", 100},
+	}
+
+	for _, tc := range testCases {
+		os.Setenv("NOTIFICATION_HISTORY_SIZE", tc.value)
+		assert.Equal(t, tc.expected, getNotificationHistorySize(), "NOTIFICATION_HISTORY_SIZE=%q", tc.value)
+	}
+}
+
+func TestNewNotificationHistory(t *testing.T) {
+	defer os.Unsetenv("NOTIFICATION_HISTORY_SIZE")
+
+	assert.NotNil(t, NewNotificationHistory(NewMockStorageClient(), envConfig()))
+
+	os.Setenv("NOTIFICATION_HISTORY_SIZE", "0")
+	assert.Nil(t, NewNotificationHistory(NewMockStorageClient(), envConfig()), "zero size disables the history")
+}
+
+func TestNotificationHistory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("lists_newest_first", func(t *testing.T) {
+		history := NewNotificationHistory(NewMockStorageClient(), envConfig())
+		for _, videoID := range []string{"video1", "video2", "video3"} {
+			require.NoError(t, history.Append(ctx, historyTestChannel, HistoryEntry{VideoID: videoID}))
+		}
+
+		entries, err := history.List(ctx, historyTestChannel, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "video3", entries[0].VideoID)
+		assert.Equal(t, "video1", entries[2].VideoID)
+
+		entries, err = history.List(ctx, historyTestChannel, 2)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "video2", entries[1].VideoID)
+	})
+
+	t.Run("drops_oldest_entries_beyond_cap", func(t *testing.T) {
+		os.Setenv("NOTIFICATION_HISTORY_SIZE", "2")
+		defer os.Unsetenv("NOTIFICATION_HISTORY_SIZE")
+
+		history := NewNotificationHistory(NewMockStorageClient(), envConfig())
+		for _, videoID := range []string{"video1", "video2", "video3"} {
+			require.NoError(t, history.Append(ctx, historyTestChannel, HistoryEntry{VideoID: videoID}))
+		}
+
+		entries, err := history.List(ctx, historyTestChannel, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "video3", entries[0].VideoID)
+		assert.Equal(t, "video2", entries[1].VideoID)
+	})
+
+	t.Run("channels_are_kept_apart", func(t *testing.T) {
+		history := NewNotificationHistory(NewMockStorageClient(), envConfig())
+		require.NoError(t, history.Append(ctx, historyTestChannel, HistoryEntry{VideoID: "video1"}))
+
+		entries, err := history.List(ctx, "UCother", 0)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestHandleNotification_RecordsHistory(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	notification := func(videoID, title string, published time.Time) string {
+		return fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>%s</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>%s</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, videoID, historyTestChannel, title, published.Format(time.RFC3339), time.Now().Format(time.RFC3339))
+	}
+
+	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, historyTestChannel)
+	gh := deps.GitHubClient.(*MockGitHubClient)
+	post := func(body string) {
+		handleNotification(deps)(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	}
+
+	post(notification("new1", "First Video", time.Now().Add(-5*time.Minute)))
+	post(notification("new1", "First Video", time.Now().Add(-5*time.Minute)))
+	post(notification("old1", "Old Video", time.Now().Add(-48*time.Hour)))
+	gh.SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+	post(notification("new2", "Second Video", time.Now().Add(-5*time.Minute)))
+
+	entries, err := NewNotificationHistory(deps.StorageClient, envConfig()).List(context.Background(), historyTestChannel, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, "new2", entries[0].VideoID)
+	assert.Equal(t, HistoryDecisionQueuedForRetry, entries[0].Decision)
+	assert.Contains(t, entries[0].Deliveries, SinkDelivery{Type: TargetTypeGitHub, Target: "test-owner/test-repo",
+		Status: DeliveryQueuedForRetry, Error: "GitHub API returned status 502"})
+	assert.Empty(t, entries[1].Deliveries, "videos that are not new are not fanned out")
+	assert.Equal(t, HistoryDecisionNotNew, entries[1].Decision)
+	assert.Equal(t, HistoryDecisionDuplicate, entries[2].Decision)
+	assert.Equal(t, "First Video", entries[3].Title)
+	assert.Equal(t, HistoryDecisionDispatched, entries[3].Decision)
+	assert.Contains(t, entries[3].Result, "Successfully triggered workflow")
+	assert.Contains(t, entries[3].Deliveries, SinkDelivery{Type: TargetTypeGitHub, Target: "test-owner/test-repo", Status: DeliveryDelivered})
+	assert.False(t, entries[3].ReceivedAt.IsZero())
+}
+
+func TestHistoryEndpoint(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	history := NewNotificationHistory(deps.StorageClient, envConfig())
+	for _, videoID := range []string{"video1", "video2"} {
+		require.NoError(t, history.Append(context.Background(), historyTestChannel,
+			HistoryEntry{VideoID: videoID, Decision: HistoryDecisionDispatched}))
+	}
+
+	t.Run("lists_history", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/"+historyTestChannel+"/history", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response HistoryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, historyTestChannel, response.ChannelID)
+		assert.Equal(t, 2, response.Count)
+		assert.Equal(t, "video2", response.Entries[0].VideoID)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/"+historyTestChannel+"/history?limit=1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response HistoryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Count)
+	})
+
+	t.Run("invalid_limit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/"+historyTestChannel+"/history?limit=0", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("invalid_channel", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/bad-id/history", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		os.Setenv("NOTIFICATION_HISTORY_SIZE", "0")
+		defer os.Unsetenv("NOTIFICATION_HISTORY_SIZE")
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions/"+historyTestChannel+"/history", nil))
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+}
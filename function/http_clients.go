@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Names of the shared HTTP clients; each has its own timeout but all share one transport
+const (
+	httpClientGitHub  = "github"
+	httpClientHub     = "hub"
+	httpClientSinks   = "sinks"
+	httpClientYouTube = "youtube"
+	httpClientTargets = "targets"
+)
+
+// HTTPClients lazily creates the HTTP clients used for outbound calls. The clients share
+// one transport, so idle connections are reused across invocations on a warm instance
+// instead of a new TLS handshake being made for every request.
+type HTTPClients struct {
+	transport *http.Transport
+	mu        sync.Mutex
+	clients   map[string]*http.Client
+}
+
+// getIntEnv reads a non-negative integer from an environment variable, falling back to
+// defaultValue when it is unset or invalid
+func getIntEnv(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	var parsed int
+	if _, err := fmt.Sscanf(value, "%d", &parsed); err == nil && parsed >= 0 {
+		return parsed
+	}
+	return defaultValue
+}
+
+// NewHTTPClients creates a client registry whose transport keeps up to HTTP_MAX_IDLE_CONNS
+// idle connections (default 100), HTTP_MAX_IDLE_CONNS_PER_HOST per host (default 10), each
+// for HTTP_IDLE_CONN_TIMEOUT_SECONDS (default 90).
+func NewHTTPClients() *HTTPClients {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = getIntEnv("HTTP_MAX_IDLE_CONNS", 100)
+	transport.MaxIdleConnsPerHost = getIntEnv("HTTP_MAX_IDLE_CONNS_PER_HOST", 10)
+	transport.IdleConnTimeout = getSecondsEnv("HTTP_IDLE_CONN_TIMEOUT_SECONDS", 90*time.Second)
+
+	return &HTTPClients{
+		transport: transport,
+		clients:   make(map[string]*http.Client),
+	}
+}
+
+// Client returns the shared client with the given name, creating it with timeout on first
+// use. A nil registry returns a new, unshared client.
+func (c *HTTPClients) Client(name string, timeout time.Duration) *http.Client {
+	if c == nil {
+		return &http.Client{Timeout: timeout}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, ok := c.clients[name]
+	if !ok {
+		client = &http.Client{Transport: c.transport, Timeout: timeout}
+		c.clients[name] = client
+	}
+	return client
+}
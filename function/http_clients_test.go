@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClients_TransportSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		clients := NewHTTPClients()
+		assert.Equal(t, 100, clients.transport.MaxIdleConns)
+		assert.Equal(t, 10, clients.transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 90*time.Second, clients.transport.IdleConnTimeout)
+	})
+
+	t.Run("from_environment", func(t *testing.T) {
+		os.Setenv("HTTP_MAX_IDLE_CONNS", "20")
+		os.Setenv("HTTP_MAX_IDLE_CONNS_PER_HOST", "4")
+		os.Setenv("HTTP_IDLE_CONN_TIMEOUT_SECONDS", "30")
+		defer func() {
+			os.Unsetenv("HTTP_MAX_IDLE_CONNS")
+			os.Unsetenv("HTTP_MAX_IDLE_CONNS_PER_HOST")
+			os.Unsetenv("HTTP_IDLE_CONN_TIMEOUT_SECONDS")
+		}()
+
+		clients := NewHTTPClients()
+		assert.Equal(t, 20, clients.transport.MaxIdleConns)
+		assert.Equal(t, 4, clients.transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 30*time.Second, clients.transport.IdleConnTimeout)
+	})
+
+	t.Run("invalid_values_use_defaults", func(t *testing.T) {
+		os.Setenv("HTTP_MAX_IDLE_CONNS", "-1")
+		os.Setenv("HTTP_IDLE_CONN_TIMEOUT_SECONDS", "soon")
+		defer func() {
+			os.Unsetenv("HTTP_MAX_IDLE_CONNS")
+			os.Unsetenv("HTTP_IDLE_CONN_TIMEOUT_SECONDS")
+		}()
+
+		clients := NewHTTPClients()
+		assert.Equal(t, 100, clients.transport.MaxIdleConns)
+		assert.Equal(t, 90*time.Second, clients.transport.IdleConnTimeout)
+	})
+}
+
+func TestHTTPClients_Client(t *testing.T) {
+	t.Run("reuses_clients_by_name", func(t *testing.T) {
+		clients := NewHTTPClients()
+
+		github := clients.Client(httpClientGitHub, 30*time.Second)
+		assert.Same(t, github, clients.Client(httpClientGitHub, 5*time.Second))
+		assert.Equal(t, 30*time.Second, github.Timeout, "first timeout wins")
+
+		sinks := clients.Client(httpClientSinks, 10*time.Second)
+		assert.NotSame(t, github, sinks)
+		assert.Equal(t, 10*time.Second, sinks.Timeout)
+		assert.Same(t, github.Transport, sinks.Transport, "clients share one transport")
+	})
+
+	t.Run("nil_registry", func(t *testing.T) {
+		var clients *HTTPClients
+
+		client := clients.Client(httpClientGitHub, 30*time.Second)
+		require.NotNil(t, client)
+		assert.Equal(t, 30*time.Second, client.Timeout)
+		assert.NotSame(t, client, clients.Client(httpClientGitHub, 30*time.Second))
+	})
+}
+
+func TestNewNotificationService_SharedSinkClient(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.HTTPClients = NewHTTPClients()
+
+	first := newNotificationService(deps)
+	second := newNotificationService(deps)
+	assert.Same(t, first.SinkClient.Client, second.SinkClient.Client)
+	assert.IsType(t, &http.Transport{}, first.SinkClient.Client.Transport)
+}
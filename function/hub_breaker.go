@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Hub requests fail fast for HUB_BREAKER_COOLDOWN_SECONDS once HUB_BREAKER_FAILURES
+// requests in a row have failed
+const (
+	defaultHubBreakerFailures = 5
+	defaultHubBreakerCooldown = 60 * time.Second
+)
+
+// NewHubBreaker creates the circuit breaker around the PubSubHubbub hub. It opens after
+// HUB_BREAKER_FAILURES consecutive failures (default 5), failing hub requests with
+// ErrHubUnavailable for HUB_BREAKER_COOLDOWN_SECONDS (default 60).
+// HUB_BREAKER_FAILURES=0 disables it, returning nil.
+func NewHubBreaker(metrics *Metrics) *CircuitBreaker {
+	return newCircuitBreaker("hub", ErrHubUnavailable,
+		getIntEnv("HUB_BREAKER_FAILURES", defaultHubBreakerFailures),
+		getSecondsEnv("HUB_BREAKER_COOLDOWN_SECONDS", defaultHubBreakerCooldown),
+		hubFailure, metrics)
+}
+
+// hubFailure reports whether a hub error counts towards opening the breaker: the hub
+// could not be reached, timed out, or answered with a transient error. A hub that
+// rejects a request is up, and calls the caller cancelled or had no budget for say
+// nothing about it.
+func hubFailure(err error) bool {
+	var hubErr *HubError
+	if errors.As(err, &hubErr) {
+		return hubErr.StatusCode >= http.StatusInternalServerError || hubErr.StatusCode == http.StatusTooManyRequests
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrDeadlineBudget) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
+
+// breakerPubSub sends every hub request through a CircuitBreaker
+type breakerPubSub struct {
+	PubSubClient
+	breaker *CircuitBreaker
+}
+
+// guardPubSub wraps client so hub requests go through breaker; a nil breaker returns
+// client unchanged
+func guardPubSub(client PubSubClient, breaker *CircuitBreaker) PubSubClient {
+	if breaker == nil {
+		return client
+	}
+	return &breakerPubSub{PubSubClient: client, breaker: breaker}
+}
+
+func (p *breakerPubSub) Subscribe(ctx context.Context, channelID, secret string, leaseSeconds int) error {
+	return p.breaker.call(func() error {
+		return p.PubSubClient.Subscribe(ctx, channelID, secret, leaseSeconds)
+	})
+}
+
+func (p *breakerPubSub) Unsubscribe(ctx context.Context, channelID string) error {
+	return p.breaker.call(func() error {
+		return p.PubSubClient.Unsubscribe(ctx, channelID)
+	})
+}
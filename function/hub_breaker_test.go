@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"hub_error_5xx", &HubError{StatusCode: http.StatusBadGateway}, true},
+		{"rate_limited", fmt.Errorf("subscribe: %w", &HubError{StatusCode: http.StatusTooManyRequests}), true},
+		{"rejected", &HubError{StatusCode: http.StatusBadRequest}, false},
+		{"timed_out", context.DeadlineExceeded, true},
+		{"cancelled", context.Canceled, false},
+		{"no_budget", &BudgetError{Call: callHub.name}, false},
+		{"other", errors.New("invalid callback"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hubFailure(tt.err))
+		})
+	}
+}
+
+func TestHubBreaker(t *testing.T) {
+	t.Setenv("HUB_BREAKER_FAILURES", "2")
+	hubDown := &HubError{StatusCode: http.StatusBadGateway, Body: "bad gateway"}
+	newDeps := func() (*Dependencies, *MockPubSubClient) {
+		deps := CreateTestDependencies()
+		deps.Metrics = NewMetrics()
+		deps.HubBreaker = NewHubBreaker(deps.Metrics)
+		hub := deps.PubSubClient.(*MockPubSubClient)
+		deps.PubSubClient = guardPubSub(hub, deps.HubBreaker)
+		return deps, hub
+	}
+	subscribe := func(deps *Dependencies, channelID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handleSubscribe(deps)(rec, httptest.NewRequest("POST", "/subscribe?channel_id="+channelID, nil))
+		return rec
+	}
+
+	t.Run("subscribe_fails_fast_while_open", func(t *testing.T) {
+		deps, hub := newDeps()
+		hub.SetSubscribeError(hubDown)
+
+		for i := 0; i < 2; i++ {
+			rec := subscribe(deps, fmt.Sprintf("UC%022d", i))
+			assert.Equal(t, http.StatusBadGateway, rec.Code)
+			assert.Empty(t, rec.Header().Get("Retry-After"))
+		}
+		assert.Equal(t, BreakerOpen, deps.HubBreaker.State())
+		assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricBreakerTransitions, "breaker", "hub", "state", BreakerOpen))
+
+		rec := subscribe(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+		assert.Contains(t, rec.Body.String(), ErrCodeUnavailable)
+		assert.Equal(t, 2, hub.GetSubscribeCount(), "the hub is not called while the breaker is open")
+	})
+
+	t.Run("trial_call_closes", func(t *testing.T) {
+		deps, hub := newDeps()
+		deps.HubBreaker.cooldown = 20 * time.Millisecond
+		hub.SetSubscribeError(hubDown)
+		for i := 0; i < 2; i++ {
+			subscribe(deps, fmt.Sprintf("UC%022d", i))
+		}
+
+		time.Sleep(25 * time.Millisecond)
+		hub.SetSubscribeError(nil)
+		require.Equal(t, http.StatusOK, subscribe(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw").Code)
+		assert.Equal(t, BreakerClosed, deps.HubBreaker.State())
+		for _, state := range []string{BreakerOpen, BreakerHalfOpen, BreakerClosed} {
+			assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricBreakerTransitions, "breaker", "hub", "state", state), state)
+		}
+	})
+
+	t.Run("rejections_do_not_open", func(t *testing.T) {
+		deps, hub := newDeps()
+		hub.SetSubscribeError(&HubError{StatusCode: http.StatusBadRequest})
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusBadGateway, subscribe(deps, fmt.Sprintf("UC%022d", i)).Code)
+		}
+		assert.Equal(t, BreakerClosed, deps.HubBreaker.State())
+	})
+
+	t.Run("renewals_are_deferred_while_open", func(t *testing.T) {
+		deps, hub := newDeps()
+		seedExpiringSubscriptions(t, deps, 2)
+		deps.HubBreaker.record(hubDown)
+		deps.HubBreaker.record(hubDown)
+
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+		assert.Contains(t, rec.Body.String(), "Renewals deferred")
+		assert.Zero(t, hub.GetSubscribeCount())
+	})
+}
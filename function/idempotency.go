@@ -0,0 +1,264 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader carries a client-chosen key that makes a subscribe or
+// unsubscribe request safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayHeader is set on responses replayed from an earlier request
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+// idempotencyKeysObject is where the results of requests with idempotency keys are stored
+const idempotencyKeysObject = "idempotency/keys.json"
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLockTimeout is how long a request that never finished (its instance
+// stopped mid-request) keeps its key reserved
+const idempotencyLockTimeout = 5 * time.Minute
+
+// idempotencyMu serialises reads and writes of the stored keys within an instance. As
+// with the processed-video registry, two instances racing on the same key can both run.
+var idempotencyMu sync.Mutex
+
+// idempotencyRecord is a request made with an idempotency key and, once it finished,
+// its response
+type idempotencyRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+	Done        bool      `json:"done"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+}
+
+// idempotencyKeys is the stored set of keys
+type idempotencyKeys struct {
+	Keys map[string]*idempotencyRecord `json:"keys"`
+}
+
+// getIdempotencyTTL returns how long the results of requests with idempotency keys are
+// kept, from IDEMPOTENCY_TTL_MINUTES (default 60). 0 disables idempotency keys.
+func getIdempotencyTTL() time.Duration {
+	return envConfig().IdempotencyTTL
+}
+
+// idempotent makes a handler honour the Idempotency-Key header. The first request with
+// a key runs and its response is stored; repeats of the same request within the TTL get
+// that response back without running the handler, and repeats while it is still
+// running get 409. Reusing a key for a different request is rejected with 422. 5xx
+// responses are not stored, so a retry after a server error runs again. Requests
+// without the header, storage that cannot hold objects and a zero TTL skip all of this.
+func idempotent(handler func(*Dependencies) http.HandlerFunc) func(*Dependencies) http.HandlerFunc {
+	return func(deps *Dependencies) http.HandlerFunc {
+		next := handler(deps)
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			store, ok := deps.StorageClient.(ObjectStorage)
+			ttl := deps.config().IdempotencyTTL
+			if key == "" || !ok || ttl == 0 {
+				next(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, "",
+					fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+				return
+			}
+
+			fingerprint, err := requestFingerprint(r)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, "", err.Error())
+				return
+			}
+
+			existing, err := reserveIdempotencyKey(r.Context(), store, ttl, key, fingerprint)
+			if err != nil {
+				// Without the stored keys the request still runs, as it would without a key
+				fmt.Printf("Unable to check idempotency key: %v\n", err)
+				next(w, r)
+				return
+			}
+			switch {
+			case existing == nil:
+			case existing.Fingerprint != fingerprint:
+				writeError(w, r, http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyReused, "",
+					fmt.Sprintf("%s was already used for a different request", IdempotencyKeyHeader))
+				return
+			case !existing.Done:
+				writeError(w, r, http.StatusConflict, ErrCodeIdempotencyInProgress, "",
+					fmt.Sprintf("A request with this %s is still in progress", IdempotencyKeyHeader))
+				return
+			default:
+				if existing.ContentType != "" {
+					w.Header().Set("Content-Type", existing.ContentType)
+				}
+				w.Header().Set(IdempotentReplayHeader, "true")
+				w.Header().Set("Cache-Control", "no-store")
+				w.WriteHeader(existing.StatusCode)
+				w.Write(existing.Body)
+				return
+			}
+
+			rec := &capturingResponseWriter{ResponseWriter: w}
+			next(rec, r)
+			if err := completeIdempotencyKey(r.Context(), store, ttl, key, rec); err != nil {
+				fmt.Printf("Unable to store idempotency key result: %v\n", err)
+			}
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path, query and body, so a key
+// reused for a different request can be told apart from a retry. The body is restored
+// for the handler.
+func requestFingerprint(r *http.Request) (string, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrBodyRead, err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.Query().Encode())
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// loadIdempotencyKeys reads the stored keys, treating a missing object as empty and
+// dropping expired keys and abandoned reservations
+func loadIdempotencyKeys(ctx context.Context, store ObjectStorage, ttl time.Duration) (*idempotencyKeys, error) {
+	keys := &idempotencyKeys{Keys: make(map[string]*idempotencyRecord)}
+
+	data, err := store.ReadObject(ctx, idempotencyKeysObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency keys: %v", err)
+	}
+	if err := json.Unmarshal(data, keys); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency keys: %v", err)
+	}
+	if keys.Keys == nil {
+		keys.Keys = make(map[string]*idempotencyRecord)
+	}
+
+	now := currentTime(ctx)
+	for key, record := range keys.Keys {
+		age := now.Sub(record.CreatedAt)
+		if age >= ttl || (!record.Done && age >= idempotencyLockTimeout) {
+			delete(keys.Keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// saveIdempotencyKeys writes the stored keys
+func saveIdempotencyKeys(ctx context.Context, store ObjectStorage, keys *idempotencyKeys) error {
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency keys: %v", err)
+	}
+	if err := store.WriteObject(ctx, idempotencyKeysObject, data); err != nil {
+		return fmt.Errorf("failed to write idempotency keys: %v", err)
+	}
+	return nil
+}
+
+// reserveIdempotencyKey returns the key's record when it is in use. Otherwise it
+// reserves the key for this request and returns nil.
+func reserveIdempotencyKey(ctx context.Context, store ObjectStorage, ttl time.Duration, key, fingerprint string) (*idempotencyRecord, error) {
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+
+	keys, err := loadIdempotencyKeys(ctx, store, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := keys.Keys[key]; ok {
+		return existing, nil
+	}
+
+	keys.Keys[key] = &idempotencyRecord{Fingerprint: fingerprint, CreatedAt: currentTime(ctx)}
+	return nil, saveIdempotencyKeys(ctx, store, keys)
+}
+
+// completeIdempotencyKey stores the response of the request that reserved the key, or
+// releases the key when the response was a server error
+func completeIdempotencyKey(ctx context.Context, store ObjectStorage, ttl time.Duration, key string, rec *capturingResponseWriter) error {
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+
+	keys, err := loadIdempotencyKeys(ctx, store, ttl)
+	if err != nil {
+		return err
+	}
+	record, ok := keys.Keys[key]
+	if !ok {
+		return nil
+	}
+
+	if rec.status() >= http.StatusInternalServerError {
+		delete(keys.Keys, key)
+	} else {
+		record.Done = true
+		record.StatusCode = rec.status()
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.Body = rec.body.Bytes()
+	}
+	return saveIdempotencyKeys(ctx, store, keys)
+}
+
+// capturingResponseWriter passes a response through while keeping a copy of its status
+// and body
+type capturingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (c *capturingResponseWriter) WriteHeader(statusCode int) {
+	if c.statusCode == 0 {
+		c.statusCode = statusCode
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *capturingResponseWriter) Write(p []byte) (int, error) {
+	if c.statusCode == 0 {
+		c.statusCode = http.StatusOK
+	}
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+// Unwrap lets writeJSONResponse find the negotiated response format
+func (c *capturingResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// status returns the response's status code
+func (c *capturingResponseWriter) status() int {
+	if c.statusCode == 0 {
+		return http.StatusOK
+	}
+	return c.statusCode
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreadableObjectStorage fails every object read but keeps the subscription state
+type unreadableObjectStorage struct {
+	*MockStorageClient
+}
+
+func (u *unreadableObjectStorage) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	return nil, ErrMockLoadFailure
+}
+
+func TestGetIdempotencyTTL(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", time.Hour},
+		{"10", 10 * time.Minute},
+		{"0", 0},
+		{"-1", time.Hour},
+		{"invalid", time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Setenv("IDEMPOTENCY_TTL_MINUTES", tc.value)
+		assert.Equal(t, tc.expected, getIdempotencyTTL(), "IDEMPOTENCY_TTL_MINUTES=%q", tc.value)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	const channelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+	send := func(method, target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, req)
+		return rec
+	}
+	newDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		SetDependencies(deps)
+		t.Cleanup(func() { SetDependencies(nil) })
+		return deps
+	}
+
+	t.Run("repeat_is_replayed", func(t *testing.T) {
+		deps := newDeps()
+		hub := deps.PubSubClient.(*MockPubSubClient)
+
+		first := send("POST", "/subscribe?channel_id="+channelID, "key-1")
+		require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+		assert.Empty(t, first.Header().Get(IdempotentReplayHeader))
+
+		second := send("POST", "/subscribe?channel_id="+channelID, "key-1")
+		assert.Equal(t, http.StatusOK, second.Code, "a repeat is not a 409 conflict")
+		assert.Equal(t, "true", second.Header().Get(IdempotentReplayHeader))
+		assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, 1, hub.GetSubscribeCount())
+
+		// Without a key the request runs again
+		assert.Equal(t, http.StatusConflict, send("POST", "/subscribe?channel_id="+channelID, "").Code)
+	})
+
+	t.Run("unsubscribe_is_replayed", func(t *testing.T) {
+		deps := newDeps()
+		hub := deps.PubSubClient.(*MockPubSubClient)
+		require.Equal(t, http.StatusOK, send("POST", "/subscribe?channel_id="+channelID, "").Code)
+
+		assert.Equal(t, http.StatusNoContent, send("DELETE", "/unsubscribe?channel_id="+channelID, "key-2").Code)
+		assert.Equal(t, http.StatusNoContent, send("DELETE", "/unsubscribe?channel_id="+channelID, "key-2").Code)
+		assert.Equal(t, 1, hub.GetUnsubscribeCount())
+	})
+
+	t.Run("key_reused_for_another_request", func(t *testing.T) {
+		newDeps()
+		require.Equal(t, http.StatusOK, send("POST", "/subscribe?channel_id="+channelID, "key-3").Code)
+
+		rec := send("POST", "/subscribe?channel_id=UCabcdefghijklmnopqrstuv", "key-3")
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrCodeIdempotencyKeyReused)
+	})
+
+	t.Run("request_in_progress", func(t *testing.T) {
+		deps := newDeps()
+		req := httptest.NewRequest("POST", "/subscribe?channel_id="+channelID, nil)
+		fingerprint, err := requestFingerprint(req)
+		require.NoError(t, err)
+		existing, err := reserveIdempotencyKey(context.Background(), deps.StorageClient.(ObjectStorage), time.Hour, "key-4", fingerprint)
+		require.NoError(t, err)
+		require.Nil(t, existing)
+
+		rec := send("POST", "/subscribe?channel_id="+channelID, "key-4")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrCodeIdempotencyInProgress)
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("server_errors_are_not_stored", func(t *testing.T) {
+		deps := newDeps()
+		hub := deps.PubSubClient.(*MockPubSubClient)
+		hub.SetSubscribeError(fmt.Errorf("hub unavailable"))
+
+		assert.GreaterOrEqual(t, send("POST", "/subscribe?channel_id="+channelID, "key-5").Code, 500)
+
+		hub.SetSubscribeError(nil)
+		rec := send("POST", "/subscribe?channel_id="+channelID, "key-5")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get(IdempotentReplayHeader))
+		assert.Equal(t, 2, hub.GetSubscribeCount())
+	})
+
+	t.Run("storage_failure_runs_the_request", func(t *testing.T) {
+		deps := newDeps()
+		deps.StorageClient = &unreadableObjectStorage{deps.StorageClient.(*MockStorageClient)}
+
+		send("POST", "/subscribe?channel_id="+channelID, "key-6")
+		assert.Equal(t, 1, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("IDEMPOTENCY_TTL_MINUTES", "0")
+		deps := newDeps()
+
+		require.Equal(t, http.StatusOK, send("POST", "/subscribe?channel_id="+channelID, "key-7").Code)
+		assert.Equal(t, http.StatusConflict, send("POST", "/subscribe?channel_id="+channelID, "key-7").Code)
+		assert.Equal(t, 1, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("key_too_long", func(t *testing.T) {
+		newDeps()
+		long := make([]byte, maxIdempotencyKeyLength+1)
+		for i := range long {
+			long[i] = 'k'
+		}
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/subscribe?channel_id="+channelID, string(long)).Code)
+	})
+}
+
+func TestLoadIdempotencyKeys_Expiry(t *testing.T) {
+	storage := NewMockStorageClient()
+	ctx := context.Background()
+	now := time.Now()
+
+	keys := &idempotencyKeys{Keys: map[string]*idempotencyRecord{
+		"fresh":     {Done: true, CreatedAt: now.Add(-time.Minute)},
+		"expired":   {Done: true, CreatedAt: now.Add(-2 * time.Hour)},
+		"running":   {CreatedAt: now.Add(-time.Minute)},
+		"abandoned": {CreatedAt: now.Add(-idempotencyLockTimeout)},
+	}}
+	require.NoError(t, saveIdempotencyKeys(ctx, storage, keys))
+
+	loaded, err := loadIdempotencyKeys(ctx, storage, time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Keys, 2)
+	assert.Contains(t, loaded.Keys, "fresh")
+	assert.Contains(t, loaded.Keys, "running")
+}
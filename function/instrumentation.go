@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// instrumentedStorage records the latency of every storage operation
+type instrumentedStorage struct {
+	StorageService
+	metrics *Metrics
+}
+
+// instrumentedObjectStorage also forwards ObjectStorage for backends that support it
+type instrumentedObjectStorage struct {
+	*instrumentedStorage
+	objects ObjectStorage
+}
+
+// instrumentStorage wraps storage so its operations are timed. The wrapper keeps
+// the ObjectStorage capability of the underlying backend.
+func instrumentStorage(storage StorageService, metrics *Metrics) StorageService {
+	wrapped := &instrumentedStorage{StorageService: storage, metrics: metrics}
+	if objects, ok := storage.(ObjectStorage); ok {
+		return &instrumentedObjectStorage{instrumentedStorage: wrapped, objects: objects}
+	}
+	return wrapped
+}
+
+func (s *instrumentedStorage) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
+	start := time.Now()
+	state, err := s.StorageService.LoadSubscriptionState(ctx)
+	s.metrics.ObserveStorage("load_state", time.Since(start), err)
+	return state, err
+}
+
+func (s *instrumentedStorage) SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error {
+	start := time.Now()
+	err := s.StorageService.SaveSubscriptionState(ctx, state)
+	s.metrics.ObserveStorage("save_state", time.Since(start), err)
+	return err
+}
+
+func (s *instrumentedStorage) GetSubscription(ctx context.Context, channelID string) (*Subscription, error) {
+	start := time.Now()
+	sub, err := s.StorageService.GetSubscription(ctx, channelID)
+	// A missing subscription is an answer, not a storage failure
+	observed := err
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		observed = nil
+	}
+	s.metrics.ObserveStorage("get_subscription", time.Since(start), observed)
+	return sub, err
+}
+
+func (s *instrumentedStorage) SaveSubscription(ctx context.Context, subscription *Subscription) error {
+	start := time.Now()
+	err := s.StorageService.SaveSubscription(ctx, subscription)
+	s.metrics.ObserveStorage("save_subscription", time.Since(start), err)
+	return err
+}
+
+func (s *instrumentedStorage) DeleteSubscription(ctx context.Context, channelID string) error {
+	start := time.Now()
+	err := s.StorageService.DeleteSubscription(ctx, channelID)
+	s.metrics.ObserveStorage("delete_subscription", time.Since(start), err)
+	return err
+}
+
+func (s *instrumentedStorage) StreamSubscriptions(ctx context.Context, fn func(*Subscription) error) error {
+	start := time.Now()
+	err := s.StorageService.StreamSubscriptions(ctx, fn)
+	s.metrics.ObserveStorage("stream_subscriptions", time.Since(start), err)
+	return err
+}
+
+func (s *instrumentedObjectStorage) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	start := time.Now()
+	data, err := s.objects.ReadObject(ctx, name)
+	observed := err
+	if errors.Is(err, ErrObjectNotFound) {
+		observed = nil
+	}
+	s.metrics.ObserveStorage("read_object", time.Since(start), observed)
+	return data, err
+}
+
+func (s *instrumentedObjectStorage) WriteObject(ctx context.Context, name string, data []byte) error {
+	start := time.Now()
+	err := s.objects.WriteObject(ctx, name, data)
+	s.metrics.ObserveStorage("write_object", time.Since(start), err)
+	return err
+}
+
+func (s *instrumentedObjectStorage) CreateObject(ctx context.Context, name string, data []byte) error {
+	start := time.Now()
+	err := s.objects.CreateObject(ctx, name, data)
+	// An object that already exists is an answer, not a storage failure
+	observed := err
+	if errors.Is(err, ErrObjectChanged) {
+		observed = nil
+	}
+	s.metrics.ObserveStorage("create_object", time.Since(start), observed)
+	return err
+}
+
+func (s *instrumentedObjectStorage) ReadObjectGeneration(ctx context.Context, name string) ([]byte, int64, error) {
+	start := time.Now()
+	data, generation, err := s.objects.ReadObjectGeneration(ctx, name)
+	observed := err
+	if errors.Is(err, ErrObjectNotFound) {
+		observed = nil
+	}
+	s.metrics.ObserveStorage("read_object", time.Since(start), observed)
+	return data, generation, err
+}
+
+func (s *instrumentedObjectStorage) WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error {
+	start := time.Now()
+	err := s.objects.WriteObjectIfGeneration(ctx, name, data, generation)
+	// An object changed by another writer is an answer, not a storage failure
+	observed := err
+	if errors.Is(err, ErrObjectChanged) {
+		observed = nil
+	}
+	s.metrics.ObserveStorage("write_object", time.Since(start), observed)
+	return err
+}
+
+func (s *instrumentedObjectStorage) DeleteObject(ctx context.Context, name string) error {
+	start := time.Now()
+	err := s.objects.DeleteObject(ctx, name)
+	s.metrics.ObserveStorage("delete_object", time.Since(start), err)
+	return err
+}
+
+// instrumentedPubSub records the latency of every hub request
+type instrumentedPubSub struct {
+	PubSubClient
+	metrics *Metrics
+}
+
+// instrumentPubSub wraps client so hub requests are timed
+func instrumentPubSub(client PubSubClient, metrics *Metrics) PubSubClient {
+	return &instrumentedPubSub{PubSubClient: client, metrics: metrics}
+}
+
+func (p *instrumentedPubSub) Subscribe(ctx context.Context, channelID, secret string, leaseSeconds int) error {
+	start := time.Now()
+	err := p.PubSubClient.Subscribe(ctx, channelID, secret, leaseSeconds)
+	p.metrics.ObserveHubRequest("subscribe", time.Since(start), err)
+	return err
+}
+
+func (p *instrumentedPubSub) Unsubscribe(ctx context.Context, channelID string) error {
+	start := time.Now()
+	err := p.PubSubClient.Unsubscribe(ctx, channelID)
+	p.metrics.ObserveHubRequest("unsubscribe", time.Since(start), err)
+	return err
+}
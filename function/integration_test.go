@@ -112,7 +112,7 @@ func TestHandlers_EndToEnd(t *testing.T) {
 		finalState := deps.StorageClient.(*MockStorageClient).GetState()
 		sub := finalState.Subscriptions[channelID]
 		assert.NotNil(t, sub)
-		assert.True(t, sub.ExpiresAt.After(expiringTime))
+		assert.True(t, sub.ExpiresAt.Equal(expiringTime), "Lease is extended when the hub verifies the renewal")
 		assert.Equal(t, 0, sub.RenewalAttempts)
 	})
 }
@@ -38,8 +38,23 @@ func TestIntegrationWorkflows_FullSubscriptionLifecycle(t *testing.T) {
 		state := deps.StorageClient.(*MockStorageClient).GetState()
 		assert.Contains(t, state.Subscriptions, channelID)
 		sub := state.Subscriptions[channelID]
-		assert.Equal(t, "active", sub.Status)
+		assert.Equal(t, StatusPending, sub.Status)
+		assert.True(t, sub.ExpiresAt.IsZero(), "Expiry is unknown until the hub verifies")
 		assert.Equal(t, channelID, sub.ChannelID)
+		assert.NotEmpty(t, sub.Secret)
+		assert.Equal(t, sub.Secret, deps.PubSubClient.(*MockPubSubClient).GetLastSecret())
+	})
+
+	// Step 1b: The hub verifies the subscription and reports the lease it granted
+	t.Run("HubVerifiesSubscription", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, verificationRequest("challenge", "subscribe", channelID, "432000"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[channelID]
+		assert.Equal(t, StatusVerified, sub.Status)
+		assert.Equal(t, 432000, sub.LeaseSeconds)
+		assert.WithinDuration(t, time.Now().Add(5*24*time.Hour), sub.ExpiresAt, time.Minute)
 	})
 
 	// Step 2: Get subscriptions - should show our new subscription
@@ -62,7 +77,7 @@ func TestIntegrationWorkflows_FullSubscriptionLifecycle(t *testing.T) {
 		assert.Len(t, subscriptions, 1)
 		sub := subscriptions[0].(map[string]interface{})
 		assert.Equal(t, channelID, sub["channel_id"])
-		assert.Equal(t, "active", sub["status"])
+		assert.Equal(t, StatusVerified, sub["status"])
 	})
 
 	// Step 3: Process a new video notification
@@ -82,7 +97,9 @@ func TestIntegrationWorkflows_FullSubscriptionLifecycle(t *testing.T) {
 			</entry>
 		</feed>`, channelID, published, updated)
 
+		// The hub signs notifications with the secret sent at subscribe time
 		req := httptest.NewRequest("POST", "/", strings.NewReader(xmlPayload))
+		signNotification(req, deps, channelID, xmlPayload)
 		w := httptest.NewRecorder()
 
 		handler := handleNotification(deps)
@@ -98,6 +115,10 @@ func TestIntegrationWorkflows_FullSubscriptionLifecycle(t *testing.T) {
 		assert.Equal(t, "integration_test_video", entry.VideoID)
 		assert.Equal(t, "Integration Test Video", entry.Title)
 		assert.Equal(t, channelID, entry.ChannelID)
+
+		// The first delivered notification makes the subscription active
+		sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[channelID]
+		assert.Equal(t, StatusActive, sub.Status)
 	})
 
 	// Step 4: Renew subscriptions - should renew our subscription
@@ -130,7 +151,16 @@ func TestIntegrationWorkflows_FullSubscriptionLifecycle(t *testing.T) {
 		finalState := deps.StorageClient.(*MockStorageClient).GetState()
 		renewedSub := finalState.Subscriptions[channelID]
 		assert.Equal(t, 0, renewedSub.RenewalAttempts) // Should reset on success
-		assert.True(t, renewedSub.ExpiresAt.After(sub.ExpiresAt)) // Should extend
+		assert.True(t, renewedSub.ExpiresAt.Equal(sub.ExpiresAt)) // Extended only once the hub verifies
+
+		// The hub's verification of the renewal extends the lease
+		w = httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, verificationRequest("challenge", "subscribe", channelID, "432000"))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		verifiedSub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[channelID]
+		assert.True(t, verifiedSub.ExpiresAt.After(sub.ExpiresAt))
+		assert.Equal(t, StatusActive, verifiedSub.Status)
 	})
 
 	// Step 5: Unsubscribe from channel
@@ -192,6 +222,11 @@ func TestIntegrationWorkflows_MultipleChannelManagement(t *testing.T) {
 			handler(w, req)
 
 			assert.Equal(t, http.StatusOK, w.Code)
+
+			// Hub confirms the subscription
+			w = httptest.NewRecorder()
+			handleVerificationChallenge(deps)(w, verificationRequest("challenge", "subscribe", channelID, "86400"))
+			assert.Equal(t, http.StatusOK, w.Code)
 		})
 	}
 
@@ -244,6 +279,7 @@ func TestIntegrationWorkflows_MultipleChannelManagement(t *testing.T) {
 			</feed>`, i+1, channelID, i+1, published, updated)
 
 			req := httptest.NewRequest("POST", "/", strings.NewReader(xmlPayload))
+			signNotification(req, deps, channelID, xmlPayload)
 			w := httptest.NewRecorder()
 
 			handler := handleNotification(deps)
@@ -390,24 +426,26 @@ func TestIntegrationWorkflows_ErrorRecoveryAndResilience(t *testing.T) {
 		</feed>`, now.Add(-10*time.Minute).Format(time.RFC3339), now.Add(-9*time.Minute).Format(time.RFC3339))
 
 		req1 := httptest.NewRequest("POST", "/", strings.NewReader(xmlPayload))
+		signNotification(req1, deps, "UC2345678901234567890123", xmlPayload)
 		w1 := httptest.NewRecorder()
 
 		notificationHandler := handleNotification(deps)
 		notificationHandler(w1, req1)
 
-		assert.Equal(t, http.StatusInternalServerError, w1.Code)
-		assert.Contains(t, w1.Body.String(), "Failed to trigger GitHub workflow")
+		assert.Equal(t, http.StatusOK, w1.Code)
+		assert.Contains(t, w1.Body.String(), "queued for retry")
 
-		// Clear the error and retry with same notification
+		// Clear the error and drain the retry queue
 		mockGitHub.SetTriggerError(nil)
 
-		req2 := httptest.NewRequest("POST", "/", strings.NewReader(xmlPayload))
 		w2 := httptest.NewRecorder()
-
-		notificationHandler(w2, req2)
+		handleRetryDispatches(deps)(w2, httptest.NewRequest("POST", "/retry?all=true", nil))
 
 		assert.Equal(t, http.StatusOK, w2.Code)
-		assert.Contains(t, w2.Body.String(), "Successfully triggered workflow")
+		var summary RetrySummaryResponse
+		require.NoError(t, json.Unmarshal(w2.Body.Bytes(), &summary))
+		assert.Equal(t, 1, summary.Succeeded)
+		assert.Equal(t, 0, summary.Pending)
 	})
 }
 
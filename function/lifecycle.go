@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+)
+
+// Subscription lifecycle states. Pending, verified, active, expired and quarantined are
+// stored on the subscription; expiring is derived from the lease when reporting status.
+const (
+	StatusPending     = "pending"     // Subscribe request sent; waiting for the hub to verify
+	StatusVerified    = "verified"    // Hub verified the subscription; no notification received yet
+	StatusActive      = "active"      // Hub verified the subscription and is delivering notifications
+	StatusExpiring    = "expiring"    // Lease ends within the renewal threshold
+	StatusExpired     = "expired"     // Lease lapsed, or the hub never verified the subscription
+	StatusQuarantined = "quarantined" // Renewal failed MAX_RENEWAL_ATTEMPTS times or the hub denied it; not retried until reactivated
+)
+
+// verificationTimeout is how long a pending subscription waits for the hub's
+// verification before the sweeper gives up on it
+const verificationTimeout = time.Hour
+
+// subscriptionStatus returns the lifecycle state to report for a subscription
+func subscriptionStatus(sub *Subscription, now time.Time, renewalThreshold time.Duration) string {
+	switch {
+	case sub.Status == StatusPending:
+		return StatusPending
+	case sub.Status == StatusQuarantined:
+		return StatusQuarantined
+	case sub.Status == StatusExpired || sub.ExpiresAt.Before(now):
+		return StatusExpired
+	case sub.ExpiresAt.Sub(now) <= renewalThreshold:
+		return StatusExpiring
+	case sub.Status == StatusVerified:
+		return StatusVerified
+	default:
+		return StatusActive
+	}
+}
+
+// markPending records that a subscribe request was sent and the hub has yet to verify it
+func markPending(sub *Subscription, now time.Time) {
+	sub.Status = StatusPending
+	sub.LastRenewal = now
+}
+
+// verificationOverdue reports whether a pending subscription has waited too long for the hub
+func verificationOverdue(sub *Subscription, now time.Time) bool {
+	return sub.Status == StatusPending && now.Sub(sub.LastRenewal) > verificationTimeout
+}
+
+// markVerified records the hub's confirmation of a subscription. The expiry is taken
+// from the lease the hub granted; when leaseSeconds is missing or invalid the existing
+// expiry is kept, falling back to the requested lease for a first verification.
+func markVerified(sub *Subscription, leaseSeconds string, now time.Time) {
+	if sub.Status != StatusActive {
+		sub.Status = StatusVerified
+	}
+	sub.VerifiedAt = now
+	completeRecovery(sub, now)
+
+	var lease int
+	if _, err := fmt.Sscanf(leaseSeconds, "%d", &lease); err != nil || lease <= 0 {
+		if !sub.ExpiresAt.IsZero() {
+			return
+		}
+		lease = sub.RequestedLeaseSeconds
+		if lease <= 0 {
+			lease = getLeaseSeconds()
+		}
+	}
+	sub.LeaseSeconds = lease
+	sub.ExpiresAt = now.Add(time.Duration(lease) * time.Second)
+}
+
+// markDenied quarantines a subscription the hub denied, recording its reason as the
+// renewal error. The hub delivers nothing for a denied subscription, so the lease ends
+// now, and the sweeper's retention counts from the denial.
+func markDenied(sub *Subscription, reason string, now time.Time) {
+	sub.Status = StatusQuarantined
+	sub.QuarantinedAt = now
+	sub.NextRenewalAt = time.Time{}
+	if sub.ExpiresAt.IsZero() || sub.ExpiresAt.After(now) {
+		sub.ExpiresAt = now
+	}
+	recordRenewalOutcome(sub, fmt.Errorf("hub denied the subscription: %s", reason))
+}
+
+// markNotified promotes a verified subscription to active once notifications arrive
+func markNotified(sub *Subscription) {
+	if sub.Status == StatusVerified {
+		sub.Status = StatusActive
+	}
+}
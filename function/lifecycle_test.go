@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionStatus(t *testing.T) {
+	now := time.Now()
+	threshold := 12 * time.Hour
+
+	testCases := []struct {
+		name     string
+		sub      Subscription
+		expected string
+	}{
+		{"pending", Subscription{Status: StatusPending}, StatusPending},
+		{"pending_resubscribe_after_lapse", Subscription{Status: StatusPending, ExpiresAt: now.Add(-time.Hour)}, StatusPending},
+		{"verified", Subscription{Status: StatusVerified, ExpiresAt: now.Add(48 * time.Hour)}, StatusVerified},
+		{"active", Subscription{Status: StatusActive, ExpiresAt: now.Add(48 * time.Hour)}, StatusActive},
+		{"legacy_without_state", Subscription{ExpiresAt: now.Add(48 * time.Hour)}, StatusActive},
+		{"expiring", Subscription{Status: StatusActive, ExpiresAt: now.Add(6 * time.Hour)}, StatusExpiring},
+		{"lapsed", Subscription{Status: StatusActive, ExpiresAt: now.Add(-time.Minute)}, StatusExpired},
+		{"stored_expired", Subscription{Status: StatusExpired, ExpiresAt: now.Add(48 * time.Hour)}, StatusExpired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, subscriptionStatus(&tc.sub, now, threshold))
+		})
+	}
+}
+
+func TestMarkVerified(t *testing.T) {
+	t.Run("records_granted_lease", func(t *testing.T) {
+		sub := &Subscription{Status: StatusPending}
+		markVerified(sub, "3600", time.Now())
+
+		assert.Equal(t, StatusVerified, sub.Status)
+		assert.Equal(t, 3600, sub.LeaseSeconds)
+		assert.Equal(t, sub.VerifiedAt.Add(time.Hour), sub.ExpiresAt)
+	})
+
+	t.Run("keeps_active_subscriptions_active", func(t *testing.T) {
+		sub := &Subscription{Status: StatusActive, ExpiresAt: time.Now().Add(time.Hour)}
+		markVerified(sub, "86400", time.Now())
+
+		assert.Equal(t, StatusActive, sub.Status)
+		assert.Equal(t, 86400, sub.LeaseSeconds)
+	})
+
+	t.Run("missing_lease_uses_requested_lease", func(t *testing.T) {
+		sub := &Subscription{Status: StatusPending, RequestedLeaseSeconds: 7200}
+		markVerified(sub, "", time.Now())
+
+		assert.Equal(t, 7200, sub.LeaseSeconds)
+		assert.Equal(t, sub.VerifiedAt.Add(2*time.Hour), sub.ExpiresAt)
+	})
+
+	t.Run("revives_expired_subscriptions", func(t *testing.T) {
+		sub := &Subscription{Status: StatusExpired, ExpiresAt: time.Now().Add(-time.Hour)}
+		markVerified(sub, "86400", time.Now())
+
+		assert.Equal(t, StatusVerified, sub.Status)
+		assert.True(t, sub.ExpiresAt.After(time.Now()))
+	})
+}
+
+func TestMarkNotified(t *testing.T) {
+	verified := &Subscription{Status: StatusVerified}
+	markNotified(verified)
+	assert.Equal(t, StatusActive, verified.Status)
+
+	pending := &Subscription{Status: StatusPending}
+	markNotified(pending)
+	assert.Equal(t, StatusPending, pending.Status, "notifications do not replace hub verification")
+}
+
+func TestLifecycle_PendingSubscriptions(t *testing.T) {
+	channelID := "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+	t.Run("renewal_skips_pending", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID:   channelID,
+			Status:      StatusPending,
+			LastRenewal: time.Now(),
+		}))
+
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("sweep_waits_for_verification", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID:   channelID,
+			Status:      StatusPending,
+			LastRenewal: time.Now().Add(-time.Minute),
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 0, response.Lapsed)
+		assert.Equal(t, StatusPending, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[channelID].Status)
+	})
+
+	t.Run("sweep_expires_unverified", func(t *testing.T) {
+		t.Setenv("RECOVERY_MAX_ATTEMPTS", "0") // Otherwise the subscription is recovered
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID:   channelID,
+			Status:      StatusPending,
+			LastRenewal: time.Now().Add(-verificationTimeout - time.Minute),
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 1, response.Expired)
+		require.Len(t, response.Results, 1)
+		assert.Contains(t, response.Results[0].Message, "did not verify")
+
+		saved := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[channelID]
+		assert.Equal(t, StatusExpired, saved.Status)
+		assert.False(t, saved.ExpiresAt.IsZero(), "retention is measured from when verification was given up on")
+	})
+
+	t.Run("listed_as_pending", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID:    channelID,
+			Status:       StatusPending,
+			SubscribedAt: time.Now(),
+			LastRenewal:  time.Now(),
+		}))
+
+		rec := httptest.NewRecorder()
+		handleGetSubscriptions(deps)(rec, httptest.NewRequest("GET", "/subscriptions", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var response SubscriptionsListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Pending)
+		assert.Equal(t, 0, response.Active)
+		require.Len(t, response.Subscriptions, 1)
+		assert.Equal(t, StatusPending, response.Subscriptions[0].Status)
+		assert.Empty(t, response.Subscriptions[0].ExpiresAt)
+		assert.Equal(t, HealthHealthy, response.Subscriptions[0].Indicator)
+	})
+}
@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metric names exposed on GET /metrics in the Prometheus text format
+const (
+	metricNotificationsReceived = "youtube_webhook_notifications_received_total"
+	metricVideosDispatched      = "youtube_webhook_videos_dispatched_total"
+	metricHubRequestDuration    = "youtube_webhook_hub_request_duration_seconds"
+	metricRenewals              = "youtube_webhook_renewals_total"
+	metricStorageDuration       = "youtube_webhook_storage_operation_duration_seconds"
+	metricHubCallbacks          = "youtube_webhook_hub_callbacks_total"
+	metricShadowDecisions       = "youtube_webhook_shadow_decisions_total"
+	metricBreakerTransitions    = "youtube_webhook_circuit_breaker_transitions_total"
+	metricCallbacksRejected     = "youtube_webhook_callbacks_rejected_total"
+	metricNotificationsRejected = "youtube_webhook_notifications_rejected_total"
+)
+
+// latencyBuckets are the histogram upper bounds in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metricHelp describes each metric family
+var metricHelp = map[string]string{
+	metricNotificationsReceived: "Hub notifications received, by HTTP status returned.",
+	metricVideosDispatched:      "New videos dispatched, by target type and result.",
+	metricHubRequestDuration:    "Latency of PubSubHubbub hub requests, by mode and result.",
+	metricRenewals:              "Subscription renewals, by source and result.",
+	metricStorageDuration:       "Latency of subscription storage operations, by operation and result.",
+	metricHubCallbacks:          "Hub verifications and denials received, by mode and result.",
+	metricShadowDecisions:       "New video decisions of the shadow candidate compared with the active strategy, by candidate and result.",
+	metricBreakerTransitions:    "Circuit breaker state changes, by breaker and the state entered.",
+	metricCallbacksRejected:     "Hub requests refused for not using the callback token, by the path they were sent to.",
+	metricNotificationsRejected: "Notifications refused for their source address or topic, by reason.",
+}
+
+// histogram holds cumulative bucket counts for one label set
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// Metrics is an in-process registry of counters and histograms. Each function
+// instance keeps its own values, which Prometheus aggregates across instances.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	mu         sync.Mutex
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counters:   make(map[string]map[string]float64),
+		histograms: make(map[string]map[string]*histogram),
+	}
+}
+
+// RecordNotification counts a hub notification by the HTTP status it was answered with
+func (m *Metrics) RecordNotification(statusCode int) {
+	m.inc(metricNotificationsReceived, "status", strconv.Itoa(statusCode))
+}
+
+// RecordDispatch counts a video dispatched to a target ("github", "sink" or "pubsub")
+func (m *Metrics) RecordDispatch(target string, err error) {
+	m.inc(metricVideosDispatched, "target", target, "result", resultLabel(err))
+}
+
+// RecordRenewal counts a renewal attempt from the given source ("renew" or "sweep")
+func (m *Metrics) RecordRenewal(source string, err error) {
+	m.inc(metricRenewals, "source", source, "result", resultLabel(err))
+}
+
+// RecordHubCallback counts a verification or denial from the hub by its mode
+// ("subscribe", "unsubscribe" or "denied") and what was done with it
+func (m *Metrics) RecordHubCallback(mode, result string) {
+	m.inc(metricHubCallbacks, "mode", mode, "result", result)
+}
+
+// RecordShadowDecision counts a shadow candidate's new video decision by whether it
+// agreed with the active strategy ("agree", "disagree" or "error")
+func (m *Metrics) RecordShadowDecision(candidate, result string) {
+	m.inc(metricShadowDecisions, "candidate", candidate, "result", result)
+}
+
+// RecordCallbackRejected counts a request to a hub path refused because it did not carry
+// the callback token: sent to the "root" path, or with a wrong "token"
+func (m *Metrics) RecordCallbackRejected(path string) {
+	m.inc(metricCallbacksRejected, "path", path)
+}
+
+// RecordNotificationRejected counts a notification refused for its "source" address or
+// its "topic"
+func (m *Metrics) RecordNotificationRejected(reason string) {
+	m.inc(metricNotificationsRejected, "reason", reason)
+}
+
+// RecordBreakerTransition counts a circuit breaker entering state
+func (m *Metrics) RecordBreakerTransition(breaker, state string) {
+	m.inc(metricBreakerTransitions, "breaker", breaker, "state", state)
+}
+
+// ObserveHubRequest records the latency of a hub request ("subscribe" or "unsubscribe")
+func (m *Metrics) ObserveHubRequest(mode string, duration time.Duration, err error) {
+	m.observe(metricHubRequestDuration, duration, "mode", mode, "result", resultLabel(err))
+}
+
+// ObserveStorage records the latency of a storage operation
+func (m *Metrics) ObserveStorage(operation string, duration time.Duration, err error) {
+	m.observe(metricStorageDuration, duration, "operation", operation, "result", resultLabel(err))
+}
+
+// resultLabel maps an error to the "result" label value
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// labelKey renders label name/value pairs in exposition format
+func labelKey(labels ...string) string {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *Metrics) inc(name string, labels ...string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series, ok := m.counters[name]
+	if !ok {
+		series = make(map[string]float64)
+		m.counters[name] = series
+	}
+	series[labelKey(labels...)]++
+}
+
+func (m *Metrics) observe(name string, duration time.Duration, labels ...string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series, ok := m.histograms[name]
+	if !ok {
+		series = make(map[string]*histogram)
+		m.histograms[name] = series
+	}
+	key := labelKey(labels...)
+	h, ok := series[key]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		series[key] = h
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// CounterValue returns the current value of a counter series (used by tests)
+func (m *Metrics) CounterValue(name string, labels ...string) float64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[name][labelKey(labels...)]
+}
+
+// HistogramCount returns the number of observations in a histogram series (used by tests)
+func (m *Metrics) HistogramCount(name string, labels ...string) uint64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok := m.histograms[name][labelKey(labels...)]; ok {
+		return h.count
+	}
+	return 0
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	for _, name := range sortedKeys(m.counters) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, metricHelp[name], name)
+		series := m.counters[name]
+		for _, key := range sortedKeys(series) {
+			fmt.Fprintf(&b, "%s{%s} %s\n", name, key, formatFloat(series[key]))
+		}
+	}
+	for _, name := range sortedKeys(m.histograms) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, metricHelp[name], name)
+		series := m.histograms[name]
+		for _, key := range sortedKeys(series) {
+			h := series[key]
+			for i, bound := range latencyBuckets {
+				fmt.Fprintf(&b, "%s_bucket{%s,le=%q} %d\n", name, key, formatFloat(bound), h.buckets[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, h.count)
+			fmt.Fprintf(&b, "%s_sum{%s} %s\n", name, key, formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_count{%s} %d\n", name, key, h.count)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// handleGetMetrics handles GET /metrics requests using dependency injection.
+func handleGetMetrics(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Metrics == nil {
+			writeErrorResponse(w, http.StatusNotFound, "", "Metrics are not enabled")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		if err := deps.Metrics.WritePrometheus(w); err != nil {
+			fmt.Printf("Error writing metrics: %v\n", err)
+		}
+	}
+}
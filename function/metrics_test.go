@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Counters(t *testing.T) {
+	m := NewMetrics()
+	m.RecordNotification(http.StatusOK)
+	m.RecordNotification(http.StatusOK)
+	m.RecordNotification(http.StatusForbidden)
+	m.RecordDispatch("github", nil)
+	m.RecordDispatch("sink", fmt.Errorf("boom"))
+	m.RecordRenewal("renew", nil)
+	m.RecordRenewal("sweep", fmt.Errorf("boom"))
+
+	assert.Equal(t, 2.0, m.CounterValue(metricNotificationsReceived, "status", "200"))
+	assert.Equal(t, 1.0, m.CounterValue(metricNotificationsReceived, "status", "403"))
+	assert.Equal(t, 1.0, m.CounterValue(metricVideosDispatched, "target", "github", "result", "success"))
+	assert.Equal(t, 1.0, m.CounterValue(metricVideosDispatched, "target", "sink", "result", "failure"))
+	assert.Equal(t, 1.0, m.CounterValue(metricRenewals, "source", "renew", "result", "success"))
+	assert.Equal(t, 1.0, m.CounterValue(metricRenewals, "source", "sweep", "result", "failure"))
+	assert.Equal(t, 0.0, m.CounterValue(metricRenewals, "source", "sweep", "result", "success"))
+}
+
+func TestMetrics_NilIsNoOp(t *testing.T) {
+	var m *Metrics
+	m.RecordNotification(http.StatusOK)
+	m.RecordDispatch("github", nil)
+	m.ObserveStorage("load_state", time.Millisecond, nil)
+	assert.Equal(t, 0.0, m.CounterValue(metricNotificationsReceived, "status", "200"))
+	assert.Equal(t, uint64(0), m.HistogramCount(metricStorageDuration, "operation", "load_state", "result", "success"))
+}
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	m := NewMetrics()
+	m.RecordNotification(http.StatusOK)
+	m.ObserveHubRequest("subscribe", 30*time.Millisecond, nil)
+	m.ObserveHubRequest("subscribe", 3*time.Second, nil)
+
+	var buf bytes.Buffer
+	require.NoError(t, m.WritePrometheus(&buf))
+	output := buf.String()
+
+	assert.Contains(t, output, "# TYPE youtube_webhook_notifications_received_total counter\n")
+	assert.Contains(t, output, `youtube_webhook_notifications_received_total{status="200"} 1`+"\n")
+	assert.Contains(t, output, "# TYPE youtube_webhook_hub_request_duration_seconds histogram\n")
+	assert.Contains(t, output, `youtube_webhook_hub_request_duration_seconds_bucket{mode="subscribe",result="success",le="0.025"} 0`+"\n")
+	assert.Contains(t, output, `youtube_webhook_hub_request_duration_seconds_bucket{mode="subscribe",result="success",le="0.05"} 1`+"\n")
+	assert.Contains(t, output, `youtube_webhook_hub_request_duration_seconds_bucket{mode="subscribe",result="success",le="5"} 2`+"\n")
+	assert.Contains(t, output, `youtube_webhook_hub_request_duration_seconds_bucket{mode="subscribe",result="success",le="+Inf"} 2`+"\n")
+	assert.Contains(t, output, `youtube_webhook_hub_request_duration_seconds_count{mode="subscribe",result="success"} 2`+"\n")
+	assert.Contains(t, output, `youtube_webhook_hub_request_duration_seconds_sum{mode="subscribe",result="success"} 3.03`+"\n")
+}
+
+func TestHandleGetMetrics(t *testing.T) {
+	t.Run("exposes_metrics", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.Metrics.RecordRenewal("renew", nil)
+
+		rec := httptest.NewRecorder()
+		handleGetMetrics(deps)(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+		assert.Contains(t, rec.Body.String(), `youtube_webhook_renewals_total{source="renew",result="success"} 1`)
+	})
+
+	t.Run("disabled_without_metrics", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.Metrics = nil
+
+		rec := httptest.NewRecorder()
+		handleGetMetrics(deps)(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("routed", func(t *testing.T) {
+		SetDependencies(CreateTestDependencies())
+		defer SetDependencies(nil)
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestHandlers_RecordMetrics(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	t.Run("notification_and_dispatch", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		now := time.Now()
+		notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>metrics123</yt:videoId>
+    <yt:channelId>UCXuqSBlHAE6Xw-yeJA0Tunw</yt:channelId>
+    <title>Metrics Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader("not xml")))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricNotificationsReceived, "status", "200"))
+		assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricNotificationsReceived, "status", "400"))
+		assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricVideosDispatched, "target", "github", "result", "success"))
+	})
+
+	t.Run("renewals", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}))
+
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricRenewals, "source", "renew", "result", "success"))
+	})
+}
+
+func TestInstrumentStorage(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics()
+	mock := NewMockStorageClient()
+	storage := instrumentStorage(mock, metrics)
+
+	_, isObjectStorage := storage.(ObjectStorage)
+	assert.True(t, isObjectStorage, "wrapper should keep ObjectStorage support")
+
+	_, err := storage.LoadSubscriptionState(ctx)
+	require.NoError(t, err)
+	require.NoError(t, storage.SaveSubscription(ctx, &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}))
+	_, err = storage.GetSubscription(ctx, "UC0000000000000000000000")
+	assert.Equal(t, ErrSubscriptionNotFound, err)
+
+	mock.SaveError = ErrMockSaveFailure
+	assert.Error(t, storage.DeleteSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw"))
+
+	assert.Equal(t, uint64(1), metrics.HistogramCount(metricStorageDuration, "operation", "load_state", "result", "success"))
+	assert.Equal(t, uint64(1), metrics.HistogramCount(metricStorageDuration, "operation", "save_subscription", "result", "success"))
+	assert.Equal(t, uint64(1), metrics.HistogramCount(metricStorageDuration, "operation", "get_subscription", "result", "success"))
+	assert.Equal(t, uint64(1), metrics.HistogramCount(metricStorageDuration, "operation", "delete_subscription", "result", "failure"))
+}
+
+func TestInstrumentPubSub(t *testing.T) {
+	metrics := NewMetrics()
+	mock := NewMockPubSubClient()
+	client := instrumentPubSub(mock, metrics)
+
+	require.NoError(t, client.Subscribe(context.Background(), "UCXuqSBlHAE6Xw-yeJA0Tunw", "secret", 0))
+	assert.Equal(t, "secret", mock.GetLastSecret())
+
+	mock.SetUnsubscribeError(fmt.Errorf("hub down"))
+	assert.Error(t, client.Unsubscribe(context.Background(), "UCXuqSBlHAE6Xw-yeJA0Tunw"))
+
+	assert.Equal(t, uint64(1), metrics.HistogramCount(metricHubRequestDuration, "mode", "subscribe", "result", "success"))
+	assert.Equal(t, uint64(1), metrics.HistogramCount(metricHubRequestDuration, "mode", "unsubscribe", "result", "failure"))
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Media types API responses are written in. JSON is the default; YAML is used when the
+// client's Accept header prefers it.
+const (
+	JSONContentType = "application/json"
+	YAMLContentType = "application/yaml"
+)
+
+// responseFormats maps the media types in Accept to the response format they ask for
+var responseFormats = map[string]string{
+	JSONContentType:      JSONContentType,
+	"text/json":          JSONContentType,
+	YAMLContentType:      YAMLContentType,
+	"application/x-yaml": YAMLContentType,
+	"text/yaml":          YAMLContentType,
+	"text/x-yaml":        YAMLContentType,
+}
+
+// negotiateFormat returns the response format an Accept header asks for: the supported
+// media type with the highest quality, JSON on a tie. Wildcards, a missing header and
+// headers naming no supported type get JSON.
+func negotiateFormat(accept string) string {
+	format, best := JSONContentType, 0.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		candidate, ok := responseFormats[mediaType]
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > best || (quality == best && candidate == JSONContentType) {
+			format, best = candidate, quality
+		}
+	}
+	if best == 0 {
+		return JSONContentType
+	}
+	return format
+}
+
+// formatWriter carries the response format negotiated for a request to
+// writeJSONResponse, which finds it by unwrapping the response writer it is given
+type formatWriter struct {
+	http.ResponseWriter
+	format string
+}
+
+// Unwrap lets http.ResponseController flush streamed responses
+func (f *formatWriter) Unwrap() http.ResponseWriter {
+	return f.ResponseWriter
+}
+
+// withResponseFormat returns w set to answer in the format r's Accept header asks for
+func withResponseFormat(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	return &formatWriter{ResponseWriter: w, format: negotiateFormat(r.Header.Get("Accept"))}
+}
+
+// responseFormat returns the format negotiated for the response w writes, JSON when
+// none was
+func responseFormat(w http.ResponseWriter) string {
+	for w != nil {
+		if f, ok := w.(*formatWriter); ok {
+			return f.format
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = unwrapper.Unwrap()
+	}
+	return JSONContentType
+}
+
+// encodeYAML renders a response as YAML. It is converted from the JSON encoding so both
+// formats use the same field names.
+func encodeYAML(response interface{}) ([]byte, error) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(yamlNumbers(doc))
+}
+
+// yamlNumbers replaces the json.Numbers in a decoded JSON document with ints or floats,
+// which YAML would otherwise write as quoted strings
+func yamlNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = yamlNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// writeYAMLResponse writes response as YAML, falling back to JSON when it cannot be
+// converted
+func writeYAMLResponse(w http.ResponseWriter, statusCode int, response interface{}) bool {
+	data, err := encodeYAML(response)
+	if err != nil {
+		fmt.Printf("Error encoding YAML response: %v\n", err)
+		return false
+	}
+	w.Header().Set("Content-Type", YAMLContentType)
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(data); err != nil {
+		fmt.Printf("Error writing response: %v\n", err)
+	}
+	return true
+}
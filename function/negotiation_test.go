@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	testCases := map[string]string{
+		"":                                   JSONContentType,
+		"*/*":                                JSONContentType,
+		"application/json":                   JSONContentType,
+		"application/yaml":                   YAMLContentType,
+		"text/yaml; charset=utf-8":           YAMLContentType,
+		"application/x-yaml, */*;q=0.1":      YAMLContentType,
+		"application/json, application/yaml": JSONContentType,
+		"application/json;q=0.5, application/yaml;q=0.9": YAMLContentType,
+		"application/yaml;q=0":                           JSONContentType,
+		"text/html":                                      JSONContentType,
+	}
+
+	for accept, expected := range testCases {
+		assert.Equal(t, expected, negotiateFormat(accept), "Accept: %s", accept)
+	}
+}
+
+func TestYouTubeWebhook_ResponseFormat(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive}))
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	serve := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, req)
+		return rec
+	}
+
+	t.Run("json_by_default", func(t *testing.T) {
+		rec := serve("/subscriptions", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, JSONContentType, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	})
+
+	t.Run("yaml_when_asked_for", func(t *testing.T) {
+		rec := serve("/subscriptions", "application/yaml")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, YAMLContentType, rec.Header().Get("Content-Type"))
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+		var doc map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &doc))
+		assert.Equal(t, 1, doc["total"], "numbers stay numbers")
+		subscriptions := doc["subscriptions"].([]interface{})
+		require.Len(t, subscriptions, 1)
+		assert.Equal(t, "UCXuqSBlHAE6Xw-yeJA0Tunw", subscriptions[0].(map[string]interface{})["channel_id"], "fields keep their JSON names")
+	})
+
+	t.Run("errors_follow_accept", func(t *testing.T) {
+		rec := serve("/subscriptions/UC_x5XG1OV2P6uZZ5FSM9Ttw", "application/yaml")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, YAMLContentType, rec.Header().Get("Content-Type"))
+
+		var envelope map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &envelope))
+		assert.Equal(t, ErrCodeNotFound, envelope["code"])
+	})
+
+	t.Run("other_responses_keep_their_type", func(t *testing.T) {
+		rec := serve("/openapi.yaml", "application/json")
+		assert.Equal(t, OpenAPIContentType, rec.Header().Get("Content-Type"))
+		assert.Empty(t, rec.Header().Get("Cache-Control"), "the spec does not change between deploys")
+	})
+}
+
+func TestWriteJSONResponse_SetsContentType(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeJSONResponse(rec, http.StatusOK, APIResponse{Status: "success"})
+
+	assert.Equal(t, JSONContentType, rec.Header().Get("Content-Type"), "set without going through the router")
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+}
@@ -16,6 +16,7 @@ import (
 func TestNotification_EdgeCases(t *testing.T) {
 	t.Run("InvalidXMLStructure", func(t *testing.T) {
 		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 
 		// Test with XML that has correct structure but invalid content
 		xmlPayload := `<?xml version="1.0" encoding="UTF-8"?>
@@ -35,13 +36,15 @@ func TestNotification_EdgeCases(t *testing.T) {
 		handler := handleNotification(deps)
 		handler(w, req)
 
-		// Should handle invalid dates gracefully and skip processing
-		assert.Equal(t, http.StatusOK, w.Code)
-		assert.Contains(t, w.Body.String(), "Skipped: Not a new video")
+		// Entries without a video ID are rejected before anything is dispatched
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid entry: missing video ID")
+		assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
 	})
 
 	t.Run("GitHubNotConfigured", func(t *testing.T) {
 		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 		
 		// Configure GitHub client to be not configured (empty token)
 		mockGitHub := deps.GitHubClient.(*MockGitHubClient)
@@ -75,6 +78,7 @@ func TestNotification_EdgeCases(t *testing.T) {
 
 	t.Run("GitHubTriggerError", func(t *testing.T) {
 		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 		
 		// Configure GitHub client to fail
 		mockGitHub := deps.GitHubClient.(*MockGitHubClient)
@@ -102,8 +106,8 @@ func TestNotification_EdgeCases(t *testing.T) {
 		handler := handleNotification(deps)
 		handler(w, req)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
-		assert.Contains(t, w.Body.String(), "Failed to trigger GitHub workflow")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "GitHub dispatch failed and was queued for retry")
 	})
 
 	t.Run("InvalidXML", func(t *testing.T) {
@@ -141,6 +145,7 @@ func TestNotification_EdgeCases(t *testing.T) {
 
 	t.Run("OldVideo", func(t *testing.T) {
 		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 
 		// Test with old video (published over 1 hour ago)
 		now := time.Now()
@@ -170,6 +175,7 @@ func TestNotification_EdgeCases(t *testing.T) {
 
 	t.Run("SuccessfulNotification", func(t *testing.T) {
 		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 
 		// Test with valid XML for a new video
 		now := time.Now()
@@ -254,15 +260,16 @@ func TestNotification_XMLParsingEdgeCases(t *testing.T) {
 		handler := handleNotification(deps)
 		handler(w, req)
 
-		// Should handle gracefully and skip due to empty fields
-		assert.Equal(t, http.StatusOK, w.Code)
-		assert.Contains(t, w.Body.String(), "Skipped: Not a new video")
+		// Should reject the entry because of its empty channel ID
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid entry: missing channel ID")
 	})
 
-	t.Run("XMLWithUnsupportedEncoding", func(t *testing.T) {
+	t.Run("XMLWithISO88591Encoding", func(t *testing.T) {
 		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 
-		// XML with different encoding - Go's XML parser doesn't handle ISO-8859-1 properly
+		// XML in ISO-8859-1 is decoded into UTF-8 before parsing
 		xmlPayload := `<?xml version="1.0" encoding="ISO-8859-1"?>
 		<feed xmlns="http://www.w3.org/2005/Atom">
 			<entry>
@@ -280,9 +287,9 @@ func TestNotification_XMLParsingEdgeCases(t *testing.T) {
 		handler := handleNotification(deps)
 		handler(w, req)
 
-		// Go's XML parser may reject unsupported encoding
-		assert.Equal(t, http.StatusBadRequest, w.Code)
-		assert.Contains(t, w.Body.String(), "Invalid XML")
+		// The entry is parsed rather than rejected as invalid XML
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "Invalid XML")
 	})
 
 	t.Run("XMLWithMissingNamespaces", func(t *testing.T) {
@@ -306,10 +313,11 @@ func TestNotification_XMLParsingEdgeCases(t *testing.T) {
 		handler := handleNotification(deps)
 		handler(w, req)
 
-		// Without proper yt namespace, entry exists but video processing succeeds
-		// because the timestamp logic still works even with empty video/channel IDs
-		assert.Equal(t, http.StatusOK, w.Code)
-		assert.Contains(t, w.Body.String(), "Successfully triggered workflow for new video")
+		// Without proper yt namespace the video and channel IDs are not parsed,
+		// so the entry is rejected instead of triggering a workflow
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid entry: missing video ID")
+		assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
 	})
 }
 
@@ -319,30 +327,35 @@ func TestNotification_TimestampEdgeCases(t *testing.T) {
 		name      string
 		published string
 		updated   string
+		status    int
 		expected  string
 	}{
 		{
 			name:      "malformed_published_date",
 			published: "not-a-date",
 			updated:   time.Now().Format(time.RFC3339),
-			expected:  "Skipped: Not a new video", // Skip processing on parse error for safety
+			status:    http.StatusBadRequest,
+			expected:  `Invalid entry: invalid timestamp: published \"not-a-date\"`,
 		},
 		{
 			name:      "malformed_updated_date",
 			published: time.Now().Format(time.RFC3339),
 			updated:   "not-a-date",
-			expected:  "Skipped: Not a new video", // Skip processing on parse error for safety
+			status:    http.StatusBadRequest,
+			expected:  `Invalid entry: invalid timestamp: updated \"not-a-date\"`,
 		},
 		{
 			name:      "both_dates_malformed",
 			published: "not-a-date-1",
 			updated:   "not-a-date-2",
-			expected:  "Skipped: Not a new video", // Skip processing on parse error for safety
+			status:    http.StatusBadRequest,
+			expected:  `Invalid entry: invalid timestamp: published \"not-a-date-1\"`, // First invalid field is reported
 		},
 		{
 			name:      "future_dates",
 			published: time.Now().Add(1 * time.Hour).Format(time.RFC3339),
 			updated:   time.Now().Add(2 * time.Hour).Format(time.RFC3339),
+			status:    http.StatusOK,
 			expected:  "Skipped: Not a new video", // Future dates with large gaps should be skipped
 		},
 	}
@@ -350,6 +363,7 @@ func TestNotification_TimestampEdgeCases(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			deps := CreateTestDependencies()
+			subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 
 			xmlPayload := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 			<feed xmlns="http://www.w3.org/2005/Atom">
@@ -368,7 +382,7 @@ func TestNotification_TimestampEdgeCases(t *testing.T) {
 			handler := handleNotification(deps)
 			handler(w, req)
 
-			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.status, w.Code)
 			assert.Contains(t, w.Body.String(), tc.expected)
 		})
 	}
@@ -377,6 +391,7 @@ func TestNotification_TimestampEdgeCases(t *testing.T) {
 // TestNotification_ConcurrentRequests tests thread safety
 func TestNotification_ConcurrentRequests(t *testing.T) {
 	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UCXuqSBlHAE6Xw-yeJA0Tunw")
 	const numRequests = 10
 
 	done := make(chan bool, numRequests)
@@ -431,7 +446,7 @@ func (f *failingReader) Read(p []byte) (n int, err error) {
 	return 0, fmt.Errorf("simulated read error")
 }
 
-// TestIsNewVideo_EdgeCases tests the isNewVideo function with edge cases
+// TestIsNewVideo_EdgeCases tests VideoProcessor.IsNewVideo with edge cases
 func TestIsNewVideo_EdgeCases(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -515,7 +530,7 @@ func TestIsNewVideo_EdgeCases(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := isNewVideo(tc.entry)
+			result := NewVideoProcessor().IsNewVideo(tc.entry)
 			assert.Equal(t, tc.expected, result, tc.description)
 		})
 	}
@@ -551,6 +566,13 @@ func TestAtomFeedParsing_EdgeCases(t *testing.T) {
 			expectEntry: false,
 			description: "Invalid XML should cause parse error",
 		},
+		{
+			name:        "multiple_entries",
+			xmlContent:  `<?xml version="1.0" encoding="UTF-8"?><feed xmlns="http://www.w3.org/2005/Atom"><entry><yt:videoId xmlns:yt="http://www.youtube.com/xml/schemas/2015">test123</yt:videoId></entry><entry><yt:videoId xmlns:yt="http://www.youtube.com/xml/schemas/2015">test456</yt:videoId></entry></feed>`,
+			expectError: false,
+			expectEntry: true,
+			description: "Every entry of a batched feed should be parsed",
+		},
 		{
 			name:        "feed_with_attributes",
 			xmlContent:  `<?xml version="1.0" encoding="UTF-8"?><feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en"><entry><yt:videoId xmlns:yt="http://www.youtube.com/xml/schemas/2015">test123</yt:videoId></entry></feed>`,
@@ -570,9 +592,9 @@ func TestAtomFeedParsing_EdgeCases(t *testing.T) {
 			} else {
 				assert.NoError(t, err, tc.description)
 				if tc.expectEntry {
-					assert.NotNil(t, feed.Entry, tc.description)
+					assert.NotEmpty(t, feed.Entries, tc.description)
 				} else {
-					assert.Nil(t, feed.Entry, tc.description)
+					assert.Empty(t, feed.Entries, tc.description)
 				}
 			}
 		})
@@ -0,0 +1,8 @@
+package webhook
+
+// getNotificationMaxBodyBytes returns the largest notification body accepted, from
+// NOTIFICATION_MAX_BODY_BYTES (default 1 MiB). Hub notifications are a few kilobytes,
+// even with several entries.
+func getNotificationMaxBodyBytes() int64 {
+	return envConfig().NotificationMaxBodyBytes
+}
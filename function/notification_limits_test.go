@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNotificationMaxBodyBytes(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int64
+	}{
+		{"", config.DefaultNotificationMaxBodyBytes},
+		{"4096", 4096},
+		{"0", config.DefaultNotificationMaxBodyBytes},
+		{"-1", config.DefaultNotificationMaxBodyBytes},
+		{"abc", config.DefaultNotificationMaxBodyBytes},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("NOTIFICATION_MAX_BODY_BYTES", tc.value)
+			assert.Equal(t, tc.expected, getNotificationMaxBodyBytes())
+		})
+	}
+}
+
+func TestHandleNotification_OversizedBody(t *testing.T) {
+	t.Setenv("NOTIFICATION_MAX_BODY_BYTES", "64")
+	deps := CreateTestDependencies()
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("<feed>"+strings.Repeat("x", 100)+"</feed>"))
+	rec := httptest.NewRecorder()
+	handleNotification(deps)(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	var envelope APIResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+	assert.Equal(t, ErrCodeBodyTooLarge, envelope.Code)
+	assert.Equal(t, "Notification body too large", envelope.Message)
+	assert.Equal(t, float64(64), envelope.Details["max_bytes"])
+
+	deadLetters, err := NewDeadLetterStore(deps.StorageClient, envConfig()).List(req.Context())
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters, "oversized bodies are not dead-lettered")
+}
+
+func TestHandleNotification_EntityDeclarationsRejected(t *testing.T) {
+	deps := CreateTestDependencies()
+
+	body := `<?xml version="1.0"?><!DOCTYPE feed [<!ENTITY lol "lol">]><feed>&lol;</feed>`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleNotification(deps)(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var envelope APIResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+	assert.Equal(t, ErrCodeInvalidXML, envelope.Code)
+	assert.Equal(t, "DOCTYPE declarations are not allowed", envelope.Details["reason"])
+}
@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// notificationSources restricts the addresses notifications are accepted from, from
+// NOTIFICATION_ALLOWED_IPS and NOTIFICATION_DENIED_IPS
+type notificationSources struct {
+	allowed    []netip.Prefix
+	restricted bool // NOTIFICATION_ALLOWED_IPS is set, so addresses outside allowed are refused
+	denied     []netip.Prefix
+}
+
+// newNotificationSources returns the source allow and deny lists of cfg. Entries that
+// are not addresses or ranges were skipped by config.Parse; an allow list with none
+// left refuses every address.
+func newNotificationSources(cfg *config.Config) notificationSources {
+	return notificationSources{
+		allowed:    cfg.NotificationAllowedIPs,
+		restricted: cfg.RestrictNotificationSources,
+		denied:     cfg.NotificationDeniedIPs,
+	}
+}
+
+// enabled reports whether notifications are checked against either list
+func (s notificationSources) enabled() bool {
+	return s.restricted || len(s.denied) > 0
+}
+
+// allows reports whether a notification from addr is accepted: it is on no deny list
+// entry and, when there is an allow list, on one of its entries
+func (s notificationSources) allows(addr netip.Addr) bool {
+	for _, prefix := range s.denied {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if !s.restricted {
+		return true
+	}
+	for _, prefix := range s.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestSource returns the address a request came from: the peer address or, when
+// trustForwardedFor (TRUST_FORWARDED_FOR, for platforms whose load balancer is the
+// direct peer), the last X-Forwarded-For address. The last one is added by the load
+// balancer; earlier ones are whatever the client sent.
+func requestSource(r *http.Request, trustForwardedFor bool) (netip.Addr, bool) {
+	source := r.RemoteAddr
+	if trustForwardedFor {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			source = strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+
+	if addrPort, err := netip.ParseAddrPort(source); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(source)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// checkNotificationSource refuses notifications from addresses the source lists do not
+// accept, and those whose address is unknown while either list is set
+func checkNotificationSource(r *http.Request, cfg *config.Config) error {
+	sources := newNotificationSources(cfg)
+	if !sources.enabled() {
+		return nil
+	}
+	addr, ok := requestSource(r, cfg.TrustForwardedFor)
+	if !ok {
+		return fmt.Errorf("%w: unknown address %q", ErrSourceNotAllowed, r.RemoteAddr)
+	}
+	if !sources.allows(addr) {
+		return fmt.Errorf("%w: %s", ErrSourceNotAllowed, addr)
+	}
+	return nil
+}
+
+// notificationTopics returns the topic URLs a notification names: the self links of its
+// Link header and of its feed
+func notificationTopics(r *http.Request, feed *AtomFeed) []string {
+	var topics []string
+	for _, header := range r.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, found := strings.Cut(strings.TrimSpace(link), ";")
+			if !found || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			if linkRel(params) == "self" {
+				topics = append(topics, strings.Trim(target, "<>"))
+			}
+		}
+	}
+	for _, link := range feed.Links {
+		if link.Rel == "self" {
+			topics = append(topics, link.Href)
+		}
+	}
+	return topics
+}
+
+// linkRel returns the rel parameter of a Link header value's parameters
+func linkRel(params string) string {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(strings.TrimSpace(name), "rel") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// checkNotificationTopic refuses notifications, when required (REQUIRE_SUBSCRIBED_TOPIC),
+// that name no topic, name one other than their entries' channel or playlist, or whose
+// topic has no subscription
+func checkNotificationTopic(ctx context.Context, storage StorageService, r *http.Request, feed *AtomFeed, required bool) error {
+	if !required || storage == nil {
+		return nil
+	}
+
+	topics := notificationTopics(r, feed)
+	if len(topics) == 0 {
+		return fmt.Errorf("%w: no topic named", ErrUnsubscribedTopic)
+	}
+	// Every entry is for the same channel or playlist
+	subscriptionID := feed.Entries[0].SubscriptionID()
+	for _, topic := range topics {
+		if topicID, ok := topicIDFromURL(topic); !ok || topicID != subscriptionID {
+			return fmt.Errorf("%w: topic %s is not %s", ErrUnsubscribedTopic, topic, subscriptionID)
+		}
+	}
+
+	if _, err := storage.GetSubscription(ctx, subscriptionID); err != nil {
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			return fmt.Errorf("%w: %s", ErrUnsubscribedTopic, subscriptionID)
+		}
+		return fmt.Errorf("failed to load subscription state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationSources(t *testing.T) {
+	addr := netip.MustParseAddr
+	assert.False(t, newNotificationSources(envConfig()).enabled(), "unset accepts every address")
+
+	t.Setenv("NOTIFICATION_ALLOWED_IPS", "203.0.113.0/24, 2001:db8::1")
+	t.Setenv("NOTIFICATION_DENIED_IPS", "203.0.113.66")
+	sources := newNotificationSources(envConfig())
+	assert.True(t, sources.allows(addr("203.0.113.5")))
+	assert.True(t, sources.allows(addr("2001:db8::1")))
+	assert.False(t, sources.allows(addr("203.0.113.66")), "the deny list wins")
+	assert.False(t, sources.allows(addr("198.51.100.1")))
+
+	t.Setenv("NOTIFICATION_ALLOWED_IPS", "not-an-address")
+	assert.False(t, newNotificationSources(envConfig()).allows(addr("203.0.113.5")), "an allow list with no valid entry refuses everything")
+
+	t.Setenv("NOTIFICATION_ALLOWED_IPS", "")
+	assert.True(t, newNotificationSources(envConfig()).allows(addr("198.51.100.1")), "only the deny list applies")
+}
+
+func TestRequestSource(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "[::ffff:198.51.100.1]:4711"
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 203.0.113.5")
+
+	source, ok := requestSource(req, false)
+	require.True(t, ok)
+	assert.Equal(t, "198.51.100.1", source.String(), "X-Forwarded-For is not trusted by default")
+
+	source, ok = requestSource(req, true)
+	require.True(t, ok)
+	assert.Equal(t, "203.0.113.5", source.String(), "the load balancer's address is the last one")
+
+	req.Header.Del("X-Forwarded-For")
+	req.RemoteAddr = "198.51.100.2"
+	source, ok = requestSource(req, true)
+	require.True(t, ok)
+	assert.Equal(t, "198.51.100.2", source.String(), "addresses without a port are accepted")
+}
+
+func TestNotificationTopics(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Link", `<https://pubsubhubbub.appspot.com>; rel=hub, <https://www.youtube.com/feeds/videos.xml?channel_id=UCa>; rel="self"`)
+	feed := &AtomFeed{Links: []Link{
+		{Rel: "hub", Href: "https://pubsubhubbub.appspot.com"},
+		{Rel: "self", Href: "https://www.youtube.com/feeds/videos.xml?channel_id=UCb"},
+	}}
+
+	assert.Equal(t, []string{
+		"https://www.youtube.com/feeds/videos.xml?channel_id=UCa",
+		"https://www.youtube.com/feeds/videos.xml?channel_id=UCb",
+	}, notificationTopics(req, feed))
+}
+
+func TestHandleNotification_Sources(t *testing.T) {
+	const channelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	const otherChannelID = "UC_x5XG1OV2P6uZZ5FSM9Ttw"
+	now := time.Now()
+	notification := func(channelID string, selfLink bool) string {
+		link := ""
+		if selfLink {
+			link = fmt.Sprintf(`<link rel="self" href="%s"/>`, topicURLFor(channelID))
+		}
+		return fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  %s
+  <entry>
+    <yt:videoId>source123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Source Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, link, channelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+	}
+
+	newDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		deps.Metrics = NewMetrics()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: channelID}))
+		return deps
+	}
+	serve := func(deps *Dependencies, req *http.Request) (*httptest.ResponseRecorder, APIResponse) {
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, req)
+		var envelope APIResponse
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+		}
+		return rec, envelope
+	}
+
+	t.Run("source_outside_allow_list_is_rejected", func(t *testing.T) {
+		t.Setenv("NOTIFICATION_ALLOWED_IPS", "203.0.113.0/24")
+		deps := newDeps()
+
+		req := httptest.NewRequest("POST", "/", strings.NewReader(notification(channelID, true)))
+		req.RemoteAddr = "198.51.100.1:4711"
+		rec, envelope := serve(deps, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, ErrCodeSourceNotAllowed, envelope.Code)
+		assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricNotificationsRejected, "reason", "source"))
+		assert.Zero(t, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+
+		req = httptest.NewRequest("POST", "/", strings.NewReader(notification(channelID, true)))
+		req.RemoteAddr = "203.0.113.5:4711"
+		rec, _ = serve(deps, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("topics_are_not_checked_by_default", func(t *testing.T) {
+		deps := newDeps()
+		mismatched := httptest.NewRequest("POST", "/", strings.NewReader(notification(channelID, false)))
+		mismatched.Header.Set("Link", fmt.Sprintf("<%s>; rel=self", topicURLFor(otherChannelID)))
+		rec, _ := serve(deps, mismatched)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("unsubscribed_channel_is_rejected", func(t *testing.T) {
+		deps := newDeps()
+		rec, envelope := serve(deps, httptest.NewRequest("POST", "/", strings.NewReader(notification(otherChannelID, false))))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, ErrCodeInvalidSignature, envelope.Code)
+		assert.Zero(t, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("subscribed_topic_required", func(t *testing.T) {
+		t.Setenv("REQUIRE_SUBSCRIBED_TOPIC", "true")
+		deps := newDeps()
+
+		// A Link header naming another channel than the entries
+		mismatched := httptest.NewRequest("POST", "/", strings.NewReader(notification(channelID, true)))
+		mismatched.Header.Set("Link", fmt.Sprintf("<%s>; rel=self", topicURLFor(otherChannelID)))
+
+		tests := []struct {
+			name string
+			req  *http.Request
+		}{
+			{"unsubscribed_topic", httptest.NewRequest("POST", "/", strings.NewReader(notification(otherChannelID, true)))},
+			{"no_topic", httptest.NewRequest("POST", "/", strings.NewReader(notification(channelID, false)))},
+			{"link_header_mismatch", mismatched},
+		}
+
+		for i, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				rec, envelope := serve(deps, tc.req)
+				assert.Equal(t, http.StatusForbidden, rec.Code)
+				assert.Equal(t, ErrCodeUnknownTopic, envelope.Code)
+				assert.Equal(t, float64(i+1), deps.Metrics.CounterValue(metricNotificationsRejected, "reason", "topic"))
+			})
+		}
+		assert.Zero(t, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+
+		req := httptest.NewRequest("POST", "/", strings.NewReader(notification(channelID, true)))
+		req.Header.Set("Link", fmt.Sprintf("<%s>; rel=self", topicURLFor(channelID)))
+		rec, _ := serve(deps, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+}
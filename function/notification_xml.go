@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxNotificationXMLDepth bounds how deeply a notification's elements may nest. An Atom
+// entry nests four or five deep; anything far beyond that is not a hub notification.
+const maxNotificationXMLDepth = 32
+
+// newNotificationDecoder returns the hardened decoder notifications are parsed with. It
+// is strict, knows only the five predefined entities, so nothing a document declares and
+// no external entity is ever resolved, and decodes the encodings declared in the XML
+// declaration, such as ISO-8859-1 or windows-1252, into UTF-8.
+func newNotificationDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = true
+	decoder.Entity = nil
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder
+}
+
+// decodeNotificationXML checks a notification's shape and decodes it into feed
+func decodeNotificationXML(body []byte, feed *AtomFeed) error {
+	if err := checkNotificationXML(body); err != nil {
+		return err
+	}
+	return newNotificationDecoder(body).Decode(feed)
+}
+
+// checkNotificationXML rejects documents that are well-formed but not shaped like a
+// notification in ways that make parsing them expensive: DTDs, which could declare
+// entities, and elements nested deeper than maxNotificationXMLDepth.
+func checkNotificationXML(body []byte) error {
+	decoder := newNotificationDecoder(body)
+	depth := 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.Directive:
+			if bytes.HasPrefix(bytes.TrimSpace(token), []byte("DOCTYPE")) {
+				return errors.New("DOCTYPE declarations are not allowed")
+			}
+		case xml.StartElement:
+			depth++
+			if depth > maxNotificationXMLDepth {
+				return fmt.Errorf("elements nest deeper than %d levels", maxNotificationXMLDepth)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
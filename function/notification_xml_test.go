@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNotificationXML(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		problem string
+	}{
+		{
+			name: "notification",
+			body: `<?xml version="1.0"?><feed xmlns:yt="http://www.youtube.com/xml/schemas/2015">` +
+				`<entry><yt:videoId>abc</yt:videoId><author><name>A</name></author></entry></feed>`,
+		},
+		{
+			name:    "entity_declarations",
+			body:    `<?xml version="1.0"?><!DOCTYPE feed [<!ENTITY a "aaaa"><!ENTITY b "&a;&a;&a;&a;">]><feed>&b;</feed>`,
+			problem: "DOCTYPE",
+		},
+		{
+			name:    "deep_nesting",
+			body:    strings.Repeat("<a>", maxNotificationXMLDepth+1) + strings.Repeat("</a>", maxNotificationXMLDepth+1),
+			problem: "nest deeper",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkNotificationXML([]byte(tc.body))
+			if tc.problem == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.problem)
+		})
+	}
+}
+
+func TestDecodeNotificationXML_Charsets(t *testing.T) {
+	testCases := []struct {
+		name     string
+		encoding string
+		title    []byte
+		expected string
+	}{
+		{"utf8", "UTF-8", []byte("Caf\xc3\xa9"), "Café"},
+		{"iso_8859_1", "ISO-8859-1", []byte("Caf\xe9"), "Café"},
+		{"windows_1252", "windows-1252", []byte("Caf\xe9 \x96 live"), "Café – live"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := []byte(`<?xml version="1.0" encoding="` + tc.encoding + `"?><feed xmlns="http://www.w3.org/2005/Atom"><entry><title>`)
+			body = append(body, tc.title...)
+			body = append(body, "</title></entry></feed>"...)
+
+			var feed AtomFeed
+			require.NoError(t, decodeNotificationXML(body, &feed))
+			require.Len(t, feed.Entries, 1)
+			assert.Equal(t, tc.expected, feed.Entries[0].Title)
+		})
+	}
+
+	t.Run("unknown_encoding", func(t *testing.T) {
+		var feed AtomFeed
+		err := decodeNotificationXML([]byte(`<?xml version="1.0" encoding="x-unknown"?><feed/>`), &feed)
+		assert.Error(t, err)
+	})
+}
+
+func TestDecodeNotificationXML_EntitiesNotResolved(t *testing.T) {
+	var feed AtomFeed
+	body := `<?xml version="1.0"?><!DOCTYPE feed [<!ENTITY xxe SYSTEM "file:///etc/passwd">]>` +
+		`<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>&xxe;</title></entry></feed>`
+	assert.Error(t, decodeNotificationXML([]byte(body), &feed))
+
+	err := newNotificationDecoder([]byte(`<feed><title>&xxe;</title></feed>`)).Decode(&feed)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid character entity")
+}
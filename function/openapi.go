@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPIContentType is the media type GET /openapi.yaml is served with
+const OpenAPIContentType = "application/yaml"
+
+// openAPISpec is the OpenAPI 3 document describing every endpoint in routes
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// OpenAPISpec returns the service's OpenAPI 3 document
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+// handleGetOpenAPISpec handles GET /openapi.yaml requests. The document is public, like
+// /healthz, so API clients can be generated without credentials.
+func handleGetOpenAPISpec(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", OpenAPIContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(openAPISpec)
+	}
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	openAPIPathLine   = regexp.MustCompile(`^  (/\S*):$`)
+	openAPIMethodLine = regexp.MustCompile(`^    (get|put|patch|post|delete):$`)
+)
+
+// documentedOperations returns the "METHOD /path" operations in the OpenAPI document
+func documentedOperations(t *testing.T) map[string]bool {
+	operations := make(map[string]bool)
+	var path string
+	scanner := bufio.NewScanner(bytes.NewReader(OpenAPISpec()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := openAPIPathLine.FindStringSubmatch(line); match != nil {
+			path = match[1]
+		} else if match := openAPIMethodLine.FindStringSubmatch(line); match != nil && path != "" {
+			operations[strings.ToUpper(match[1])+" "+path] = true
+		} else if line == "components:" {
+			break
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return operations
+}
+
+func TestOpenAPISpec_DocumentsEveryRoute(t *testing.T) {
+	operations := documentedOperations(t)
+
+	routed := make(map[string]bool)
+	for _, rt := range routes {
+		operation := rt.method + " /" + rt.pattern
+		routed[operation] = true
+		assert.True(t, operations[operation], "%s is not in openapi.yaml", operation)
+	}
+	for operation := range operations {
+		assert.True(t, routed[operation], "openapi.yaml documents %s, which is not routed", operation)
+	}
+}
+
+func TestHandleGetOpenAPISpec(t *testing.T) {
+	// The document stays public when management credentials are required
+	t.Setenv("API_KEYS", "secret-key")
+	SetDependencies(CreateTestDependencies())
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/openapi.yaml", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, OpenAPIContentType, rec.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "openapi: 3."))
+}
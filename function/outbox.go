@@ -0,0 +1,388 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// outboxObject is where dispatches in progress are recorded
+const outboxObject = "outbox/pending.json"
+
+// outboxUpdateAttempts bounds the attempts of an outbox update that keeps racing
+// updates from other instances
+const outboxUpdateAttempts = 5
+
+// OutboxEntry is a new video whose delivery to the sinks and GitHub has started but
+// not finished. Entries left behind by an instance that died mid-dispatch are
+// delivered again by reconciliation.
+type OutboxEntry struct {
+	DispatchID           string    `json:"dispatch_id"`
+	VideoID              string    `json:"video_id"`
+	ChannelID            string    `json:"channel_id"`
+	PlaylistID           string    `json:"playlist_id,omitempty"` // Set when the video came from a playlist's feed
+	Title                string    `json:"title"`
+	Published            string    `json:"published"`
+	Updated              string    `json:"updated"`
+	LiveBroadcastContent string    `json:"live_broadcast_content,omitempty"`
+	WrittenAt            time.Time `json:"written_at"`
+	LastAttemptAt        time.Time `json:"last_attempt_at"`
+	Attempts             int       `json:"attempts"` // Deliveries started for the entry, the first included
+}
+
+// entry rebuilds the notification entry being delivered
+func (o *OutboxEntry) entry() *Entry {
+	return &Entry{
+		VideoID:              o.VideoID,
+		ChannelID:            o.ChannelID,
+		Title:                o.Title,
+		Published:            o.Published,
+		Updated:              o.Updated,
+		PlaylistID:           o.PlaylistID,
+		LiveBroadcastContent: o.LiveBroadcastContent,
+	}
+}
+
+// outbox is the stored list of deliveries in progress
+type outbox struct {
+	Pending []*OutboxEntry `json:"pending"`
+}
+
+// OutboxResponse lists the deliveries in the outbox
+type OutboxResponse struct {
+	Status  string         `json:"status"`
+	Pending []*OutboxEntry `json:"pending"`
+}
+
+// OutboxResult describes one reconciled outbox entry
+type OutboxResult struct {
+	DispatchID string `json:"dispatch_id"`
+	VideoID    string `json:"video_id"`
+	ChannelID  string `json:"channel_id"`
+	Attempts   int    `json:"attempts"`
+	Message    string `json:"message"`
+}
+
+// OutboxReconcileResponse summarises a pass over the outbox
+type OutboxReconcileResponse struct {
+	Status     string         `json:"status"`
+	Reconciled int            `json:"reconciled"`
+	Abandoned  int            `json:"abandoned"` // Dropped after OUTBOX_MAX_ATTEMPTS deliveries
+	Pending    int            `json:"pending"`   // Still in the outbox after this pass
+	Results    []OutboxResult `json:"results"`
+}
+
+// DispatchOutbox records a new video before it is delivered and removes it once the
+// delivery finished, so a delivery cut short by the instance dying is made again by
+// reconciliation. Delivery is at least once: a video delivered just before the
+// instance died is delivered twice, with the same dispatch_id. Every instance shares
+// the outbox, so it is only written back while no other instance wrote it meanwhile.
+type DispatchOutbox struct {
+	store       ObjectStorage
+	staleAfter  time.Duration
+	maxAttempts int
+	mu          sync.Mutex // Spares this instance's own updates from racing each other
+}
+
+// getOutboxStaleAfter returns how long a delivery may stay in the outbox before it is
+// taken to have been abandoned. It must exceed the function timeout, or deliveries
+// still in progress are made twice.
+func getOutboxStaleAfter() time.Duration {
+	return envConfig().OutboxStaleAfter
+}
+
+// getOutboxMaxAttempts returns how many deliveries an outbox entry gets before it is
+// abandoned
+func getOutboxMaxAttempts() int {
+	return envConfig().OutboxMaxAttempts
+}
+
+// NewDispatchOutbox creates an outbox in the given storage.
+// Returns nil (deliveries are not recorded) when the storage cannot hold objects.
+func NewDispatchOutbox(storage StorageService, cfg *config.Config) *DispatchOutbox {
+	store, ok := storage.(ObjectStorage)
+	if !ok {
+		return nil
+	}
+	return &DispatchOutbox{store: store, staleAfter: cfg.OutboxStaleAfter, maxAttempts: cfg.OutboxMaxAttempts}
+}
+
+// load reads the outbox and its generation, treating a missing object as empty
+func (o *DispatchOutbox) load(ctx context.Context) (*outbox, int64, error) {
+	box := &outbox{}
+
+	data, generation, err := o.store.ReadObjectGeneration(ctx, outboxObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return box, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read dispatch outbox: %v", err)
+	}
+	if err := json.Unmarshal(data, box); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode dispatch outbox: %v", err)
+	}
+	return box, generation, nil
+}
+
+// update applies a change to the stored outbox and writes it back, as long as no
+// other instance wrote it meanwhile; otherwise the change is applied again to the
+// newer outbox. change reports whether it changed anything to write.
+func (o *DispatchOutbox) update(ctx context.Context, change func(box *outbox) bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		box, generation, err := o.load(ctx)
+		if err != nil {
+			return err
+		}
+		if !change(box) {
+			return nil
+		}
+
+		data, err := json.Marshal(box)
+		if err != nil {
+			return fmt.Errorf("failed to encode dispatch outbox: %v", err)
+		}
+		err = o.store.WriteObjectIfGeneration(ctx, outboxObject, data, generation)
+		if errors.Is(err, ErrObjectChanged) && attempt < outboxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write dispatch outbox: %w", err)
+		}
+		return nil
+	}
+}
+
+// Write records that delivery of entry is starting. An entry already in the outbox,
+// from a redelivery or reconciliation, has its attempt time refreshed so it is not
+// reconciled while this delivery is in progress.
+func (o *DispatchOutbox) Write(ctx context.Context, entry *Entry) error {
+	now := currentTime(ctx)
+	id := dispatchID(entry)
+	return o.update(ctx, func(box *outbox) bool {
+		for _, pending := range box.Pending {
+			if pending.DispatchID == id {
+				pending.LastAttemptAt = now
+				return true
+			}
+		}
+		box.Pending = append(box.Pending, &OutboxEntry{
+			DispatchID:           id,
+			VideoID:              entry.VideoID,
+			ChannelID:            entry.ChannelID,
+			PlaylistID:           entry.PlaylistID,
+			Title:                entry.Title,
+			Published:            entry.Published,
+			Updated:              entry.Updated,
+			LiveBroadcastContent: entry.LiveBroadcastContent,
+			WrittenAt:            now,
+			LastAttemptAt:        now,
+			Attempts:             1,
+		})
+		return true
+	})
+}
+
+// MarkDelivered removes a finished delivery from the outbox
+func (o *DispatchOutbox) MarkDelivered(ctx context.Context, entry *Entry) error {
+	id := dispatchID(entry)
+	return o.update(ctx, func(box *outbox) bool {
+		var pending []*OutboxEntry
+		for _, queued := range box.Pending {
+			if queued.DispatchID != id {
+				pending = append(pending, queued)
+			}
+		}
+		if len(pending) == len(box.Pending) {
+			return false
+		}
+		box.Pending = pending
+		return true
+	})
+}
+
+// List returns the deliveries in the outbox
+func (o *DispatchOutbox) List(ctx context.Context) (*OutboxResponse, error) {
+	box, _, err := o.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := &OutboxResponse{Status: "success", Pending: box.Pending}
+	if response.Pending == nil {
+		response.Pending = []*OutboxEntry{}
+	}
+	return response, nil
+}
+
+// claimStale takes the deliveries not attempted within the stale period, or every one
+// with all set, for reconciliation: each claimed entry's attempt is counted and its
+// attempt time refreshed so no other pass claims it meanwhile. Entries out of attempts
+// are removed and returned separately.
+func (o *DispatchOutbox) claimStale(ctx context.Context, all bool) (claimed, abandoned []*OutboxEntry, err error) {
+	now := currentTime(ctx)
+	err = o.update(ctx, func(box *outbox) bool {
+		claimed, abandoned = nil, nil
+		var pending []*OutboxEntry
+		for _, entry := range box.Pending {
+			switch {
+			case !all && now.Sub(entry.LastAttemptAt) < o.staleAfter:
+				pending = append(pending, entry)
+			case entry.Attempts >= o.maxAttempts:
+				abandoned = append(abandoned, entry)
+			default:
+				entry.Attempts++
+				entry.LastAttemptAt = now
+				claimed = append(claimed, entry)
+				pending = append(pending, entry)
+			}
+		}
+		box.Pending = pending
+		return len(claimed) > 0 || len(abandoned) > 0
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return claimed, abandoned, nil
+}
+
+// writeOutbox records that delivery of entry is starting. Failures are logged and
+// returned; the delivery goes ahead without reconciliation should the instance die.
+func (ns *NotificationService) writeOutbox(ctx context.Context, entry *Entry) error {
+	if ns.Outbox == nil {
+		return nil
+	}
+	if err := ns.Outbox.Write(ctx, entry); err != nil {
+		fmt.Printf("Unable to record delivery of %s in the outbox, delivering without it: %v\n", entry.VideoID, err)
+		return err
+	}
+	return nil
+}
+
+// markDelivered removes a finished delivery from the outbox; failures are only logged,
+// as reconciliation then finds the video already processed
+func (ns *NotificationService) markDelivered(ctx context.Context, entry *Entry) {
+	if ns.Outbox == nil {
+		return
+	}
+	if err := ns.Outbox.MarkDelivered(ctx, entry); err != nil {
+		fmt.Printf("Unable to remove delivered video %s from the outbox: %v\n", entry.VideoID, err)
+	}
+}
+
+// reconcileOutbox delivers again the outbox entries whose delivery was abandoned, or
+// every entry with all set. Entries whose video was recorded as processed were
+// delivered before the instance died and are only removed.
+func (ns *NotificationService) reconcileOutbox(ctx context.Context, all bool) (*OutboxReconcileResponse, error) {
+	claimed, abandoned, err := ns.Outbox.claimStale(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &OutboxReconcileResponse{Status: "success", Results: []OutboxResult{}}
+	for _, pending := range abandoned {
+		fmt.Printf("Abandoned delivery of %s after %d attempts\n", pending.VideoID, pending.Attempts)
+		summary.Abandoned++
+		summary.Results = append(summary.Results, OutboxResult{
+			DispatchID: pending.DispatchID,
+			VideoID:    pending.VideoID,
+			ChannelID:  pending.ChannelID,
+			Attempts:   pending.Attempts,
+			Message:    fmt.Sprintf("Abandoned after %d attempts", pending.Attempts),
+		})
+	}
+
+	for _, pending := range claimed {
+		entry := pending.entry()
+		summary.Reconciled++
+		result := OutboxResult{
+			DispatchID: pending.DispatchID,
+			VideoID:    pending.VideoID,
+			ChannelID:  pending.ChannelID,
+			Attempts:   pending.Attempts,
+		}
+
+		if ns.alreadyProcessed(ctx, entry) {
+			ns.markDelivered(ctx, entry)
+			result.Message = "Already delivered"
+			summary.Results = append(summary.Results, result)
+			continue
+		}
+
+		delivery, err := ns.notificationPipeline().deliver(ctx, entry)
+		result.Message = delivery.Message
+		if err != nil {
+			fmt.Printf("Unable to deliver %s from the outbox: %v\n", entry.VideoID, err)
+		}
+		summary.Results = append(summary.Results, result)
+	}
+
+	box, err := ns.Outbox.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary.Pending = len(box.Pending)
+	return summary, nil
+}
+
+// reconcileStaleOutbox delivers again the outbox entries whose delivery was
+// abandoned; failures are only logged
+func (ns *NotificationService) reconcileStaleOutbox(ctx context.Context) {
+	if ns.Outbox == nil {
+		return
+	}
+	summary, err := ns.reconcileOutbox(ctx, false)
+	if err != nil {
+		fmt.Printf("Unable to reconcile the dispatch outbox: %v\n", err)
+		return
+	}
+	if summary.Reconciled > 0 || summary.Abandoned > 0 {
+		fmt.Printf("Reconciled %d outbox deliveries, abandoned %d, %d still pending\n",
+			summary.Reconciled, summary.Abandoned, summary.Pending)
+	}
+}
+
+// handleReconcileOutbox handles POST /outbox requests using dependency injection.
+// Deliveries left in the outbox longer than OUTBOX_STALE_MINUTES are made again;
+// ?all=true reconciles every delivery in the outbox immediately.
+func handleReconcileOutbox(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		service := newNotificationService(deps)
+		if service.Outbox == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "", "Storage backend does not support the dispatch outbox")
+			return
+		}
+
+		summary, err := service.reconcileOutbox(r.Context(), r.URL.Query().Get("all") == "true")
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, summary)
+	}
+}
+
+// handleGetOutbox handles GET /outbox requests using dependency injection
+func handleGetOutbox(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		box := NewDispatchOutbox(deps.StorageClient, deps.config())
+		if box == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "", "Storage backend does not support the dispatch outbox")
+			return
+		}
+
+		response, err := box.List(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
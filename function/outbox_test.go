@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readOutbox returns the stored outbox
+func readOutbox(t *testing.T, storage *MockStorageClient) *outbox {
+	box := &outbox{}
+	data, err := storage.ReadObject(context.Background(), outboxObject)
+	if err == ErrObjectNotFound {
+		return box
+	}
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, box))
+	return box
+}
+
+// abandonOutbox moves every outbox entry's last attempt past the stale period, as if
+// the instance delivering it had died
+func abandonOutbox(t *testing.T, storage *MockStorageClient) {
+	box := readOutbox(t, storage)
+	for _, entry := range box.Pending {
+		entry.LastAttemptAt = time.Now().Add(-time.Hour)
+	}
+	data, err := json.Marshal(box)
+	require.NoError(t, err)
+	require.NoError(t, storage.WriteObject(context.Background(), outboxObject, data))
+}
+
+// racingOutboxStorage has another instance update the outbox before each of the
+// next races conditional writes of it
+type racingOutboxStorage struct {
+	*MockStorageClient
+	races int
+	other func()
+}
+
+func (r *racingOutboxStorage) WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error {
+	if r.races > 0 {
+		r.races--
+		r.other()
+	}
+	return r.MockStorageClient.WriteObjectIfGeneration(ctx, name, data, generation)
+}
+
+func TestDispatchOutbox_ConcurrentInstances(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMockStorageClient()
+	other := NewDispatchOutbox(storage, envConfig())
+	racing := &racingOutboxStorage{MockStorageClient: storage}
+	outbox := NewDispatchOutbox(racing, envConfig())
+
+	racing.races = 1
+	racing.other = func() { require.NoError(t, other.Write(ctx, newRetryTestEntry("theirs01"))) }
+	require.NoError(t, outbox.Write(ctx, newRetryTestEntry("mine0001")))
+	require.Len(t, readOutbox(t, storage).Pending, 2, "neither instance's write is lost")
+
+	racing.races = 1
+	racing.other = func() { require.NoError(t, other.MarkDelivered(ctx, newRetryTestEntry("theirs01"))) }
+	require.NoError(t, outbox.MarkDelivered(ctx, newRetryTestEntry("mine0001")))
+	assert.Empty(t, readOutbox(t, storage).Pending, "neither delivery is left behind")
+
+	racing.races = outboxUpdateAttempts
+	racing.other = func() { require.NoError(t, other.Write(ctx, newRetryTestEntry("theirs02"))) }
+	assert.ErrorIs(t, outbox.Write(ctx, newRetryTestEntry("mine0002")), ErrObjectChanged)
+}
+
+func TestGetOutboxSettings(t *testing.T) {
+	testCases := []struct {
+		value      string
+		staleAfter time.Duration
+		attempts   int
+	}{
+		{"", 10 * time.Minute, 5},
+		{"3", 3 * time.Minute, 3},
+		{"0", 10 * time.Minute, 5},
+		{"invalid", 10 * time.Minute, 5},
+	}
+
+	for _, tc := range testCases {
+		t.Setenv("OUTBOX_STALE_MINUTES", tc.value)
+		t.Setenv("OUTBOX_MAX_ATTEMPTS", tc.value)
+		assert.Equal(t, tc.staleAfter, getOutboxStaleAfter(), "OUTBOX_STALE_MINUTES=%q", tc.value)
+		assert.Equal(t, tc.attempts, getOutboxMaxAttempts(), "OUTBOX_MAX_ATTEMPTS=%q", tc.value)
+	}
+}
+
+func TestDispatchOutbox_Delivery(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+
+	t.Run("delivered_entry_is_removed", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+
+		rec := postPremiereNotification(deps, "outbox001")
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Empty(t, readOutbox(t, storage).Pending)
+	})
+
+	t.Run("entry_is_written_before_dispatch", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		var pending []*OutboxEntry
+		deps.NotificationStages = &NotificationStages{
+			Dispatcher: EntryDispatcherFunc(func(ctx context.Context, entry *Entry) (*NotificationResult, error) {
+				pending = readOutbox(t, storage).Pending
+				return &NotificationResult{Status: "success", Message: "Dispatched"}, nil
+			}),
+		}
+
+		postPremiereNotification(deps, "outbox002")
+
+		require.Len(t, pending, 1)
+		assert.Equal(t, "outbox002", pending[0].VideoID)
+		assert.Equal(t, 1, pending[0].Attempts)
+		assert.Empty(t, readOutbox(t, storage).Pending)
+	})
+
+	t.Run("outbox_write_failure_still_dispatches", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.ObjectErrors = map[string]error{outboxObject: ErrMockSaveFailure}
+
+		rec := postPremiereNotification(deps, "outbox003")
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("failed_dispatch_stays_in_outbox", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.ObjectErrors = map[string]error{dispatchQueueObject: ErrMockSaveFailure}
+		deps.GitHubClient.(*MockGitHubClient).SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+
+		rec := postPremiereNotification(deps, "outbox004")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		require.Len(t, readOutbox(t, storage).Pending, 1)
+		assert.Equal(t, "outbox004", readOutbox(t, storage).Pending[0].VideoID)
+	})
+}
+
+func TestDispatchOutbox_Reconcile(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+	ctx := context.Background()
+
+	t.Run("abandoned_delivery_is_made_again", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		pending := NewDispatchOutbox(storage, envConfig())
+		require.NoError(t, pending.Write(ctx, newRetryTestEntry("died0001")))
+		require.NoError(t, pending.Write(ctx, newRetryTestEntry("inflight")))
+		box := readOutbox(t, storage)
+		box.Pending[0].LastAttemptAt = time.Now().Add(-time.Hour)
+		data, err := json.Marshal(box)
+		require.NoError(t, err)
+		require.NoError(t, storage.WriteObject(ctx, outboxObject, data))
+
+		summary, err := newNotificationService(deps).reconcileOutbox(ctx, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, summary.Reconciled)
+		assert.Equal(t, 1, summary.Pending, "the delivery still in progress is left alone")
+		require.Len(t, summary.Results, 1)
+		assert.Equal(t, "died0001", summary.Results[0].VideoID)
+		assert.Equal(t, 2, summary.Results[0].Attempts)
+		assert.Contains(t, summary.Results[0].Message, "Successfully triggered workflow")
+		assert.Equal(t, "died0001", gh.GetLastEntry().VideoID)
+		require.Len(t, readOutbox(t, storage).Pending, 1)
+		assert.Equal(t, "inflight", readOutbox(t, storage).Pending[0].VideoID)
+	})
+
+	t.Run("processed_video_is_only_removed", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		entry := newRetryTestEntry("died0002")
+		require.NoError(t, NewDispatchOutbox(storage, envConfig()).Write(ctx, entry))
+		require.NoError(t, NewProcessedVideoRegistry(storage, envConfig()).MarkProcessed(ctx, entry.VideoID))
+
+		summary, err := newNotificationService(deps).reconcileOutbox(ctx, true)
+		require.NoError(t, err)
+
+		require.Len(t, summary.Results, 1)
+		assert.Equal(t, "Already delivered", summary.Results[0].Message)
+		assert.Equal(t, 0, summary.Pending)
+		assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("failing_delivery_is_abandoned", func(t *testing.T) {
+		t.Setenv("OUTBOX_MAX_ATTEMPTS", "2")
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.ObjectErrors = map[string]error{dispatchQueueObject: ErrMockSaveFailure}
+		deps.GitHubClient.(*MockGitHubClient).SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+		require.NoError(t, NewDispatchOutbox(storage, envConfig()).Write(ctx, newRetryTestEntry("died0003")))
+
+		abandonOutbox(t, storage)
+		summary, err := newNotificationService(deps).reconcileOutbox(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Reconciled)
+		assert.Equal(t, 1, summary.Pending)
+
+		abandonOutbox(t, storage)
+		summary, err = newNotificationService(deps).reconcileOutbox(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Abandoned)
+		assert.Equal(t, 0, summary.Pending)
+		assert.Empty(t, readOutbox(t, storage).Pending)
+	})
+
+	t.Run("notification_reconciles_stale_deliveries", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		require.NoError(t, NewDispatchOutbox(storage, envConfig()).Write(ctx, newRetryTestEntry("died0004")))
+		abandonOutbox(t, storage)
+
+		rec := postPremiereNotification(deps, "outbox005")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 2, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+		assert.Empty(t, readOutbox(t, storage).Pending)
+	})
+}
+
+func TestOutboxEndpoints(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	deps := CreateTestDependencies()
+	storage := deps.StorageClient.(*MockStorageClient)
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	require.NoError(t, NewDispatchOutbox(storage, envConfig()).Write(context.Background(), newRetryTestEntry("outbox1")))
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/outbox", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listing OutboxResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing))
+	require.Len(t, listing.Pending, 1)
+	assert.Equal(t, "outbox1", listing.Pending[0].VideoID)
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/outbox", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var summary OutboxReconcileResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, 0, summary.Reconciled, "the delivery is not stale yet")
+	assert.Equal(t, 1, summary.Pending)
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/outbox?all=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Reconciled)
+	assert.Equal(t, 0, summary.Pending)
+	assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+}
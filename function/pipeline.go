@@ -0,0 +1,397 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Topics of the notification event bus, in the order a notification moves through them.
+// Each stage subscribes to one topic and publishes its result on the next; every entry
+// ends on TopicEntryDone, whether a filter stopped it or it was dispatched.
+const (
+	TopicNotificationReceived  = "notification.received"  // Request
+	TopicNotificationParsed    = "notification.parsed"    // Request, Body and Feed
+	TopicNotificationValidated = "notification.validated" // Request, Body and Feed
+	TopicEntryReceived         = "entry.received"         // Entry; one event per entry of the feed
+	TopicEntryEnriched         = "entry.enriched"         // Entry
+	TopicEntryAccepted         = "entry.accepted"         // Entry that passed every filter
+	TopicEntryDone             = "entry.done"             // Entry with its Result and Err
+	TopicNotificationDone      = "notification.done"      // Request with the notification's Result and Err
+)
+
+// PipelineEvent is a message on the notification event bus; which fields are set
+// depends on its topic
+type PipelineEvent struct {
+	Topic   string
+	Request *http.Request
+	Body    []byte
+	Feed    *AtomFeed
+	Entry   *Entry
+	Result  *NotificationResult
+	Err     error
+
+	run *pipelineRun // The notification the event belongs to
+}
+
+// EventHandler handles an event published on the bus
+type EventHandler func(ctx context.Context, event *PipelineEvent)
+
+// EventBus delivers each published event to the handlers subscribed to its topic,
+// synchronously and in the order they subscribed. Handlers may publish further events,
+// which are delivered before Publish returns.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[string][]EventHandler)}
+}
+
+// Subscribe adds a handler for the events published on topic
+func (b *EventBus) Subscribe(topic string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+// Publish delivers an event to the handlers of its topic
+func (b *EventBus) Publish(ctx context.Context, event *PipelineEvent) {
+	b.mu.RLock()
+	handlers := b.handlers[event.Topic]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
+
+// NotificationParser reads and decodes a notification request. The raw body is returned
+// with the feed for signature verification, and with decoding errors for dead-lettering.
+type NotificationParser interface {
+	Parse(r *http.Request) (*AtomFeed, []byte, error)
+}
+
+// NotificationValidator rejects a parsed notification whose entries are invalid or that
+// is not signed as its subscription requires
+type NotificationValidator interface {
+	Validate(r *http.Request, body []byte, feed *AtomFeed) error
+}
+
+// EntryEnricher adds to an entry what later stages need; failures are its own to log
+type EntryEnricher interface {
+	Enrich(ctx context.Context, entry *Entry)
+}
+
+// EntryFilter decides whether an entry is dispatched. Returning a result stops the
+// entry there with that result; nil passes it on.
+type EntryFilter interface {
+	Filter(ctx context.Context, entry *Entry) *NotificationResult
+}
+
+// EntryDispatcher delivers an entry that passed every filter
+type EntryDispatcher interface {
+	Dispatch(ctx context.Context, entry *Entry) (*NotificationResult, error)
+}
+
+// NotificationParserFunc adapts a function to a NotificationParser
+type NotificationParserFunc func(r *http.Request) (*AtomFeed, []byte, error)
+
+// Parse calls f(r)
+func (f NotificationParserFunc) Parse(r *http.Request) (*AtomFeed, []byte, error) {
+	return f(r)
+}
+
+// NotificationValidatorFunc adapts a function to a NotificationValidator
+type NotificationValidatorFunc func(r *http.Request, body []byte, feed *AtomFeed) error
+
+// Validate calls f(r, body, feed)
+func (f NotificationValidatorFunc) Validate(r *http.Request, body []byte, feed *AtomFeed) error {
+	return f(r, body, feed)
+}
+
+// EntryEnricherFunc adapts a function to an EntryEnricher
+type EntryEnricherFunc func(ctx context.Context, entry *Entry)
+
+// Enrich calls f(ctx, entry)
+func (f EntryEnricherFunc) Enrich(ctx context.Context, entry *Entry) {
+	f(ctx, entry)
+}
+
+// EntryFilterFunc adapts a function to an EntryFilter
+type EntryFilterFunc func(ctx context.Context, entry *Entry) *NotificationResult
+
+// Filter calls f(ctx, entry)
+func (f EntryFilterFunc) Filter(ctx context.Context, entry *Entry) *NotificationResult {
+	return f(ctx, entry)
+}
+
+// EntryDispatcherFunc adapts a function to an EntryDispatcher
+type EntryDispatcherFunc func(ctx context.Context, entry *Entry) (*NotificationResult, error)
+
+// Dispatch calls f(ctx, entry)
+func (f EntryDispatcherFunc) Dispatch(ctx context.Context, entry *Entry) (*NotificationResult, error) {
+	return f(ctx, entry)
+}
+
+// NotificationStages customises the notification pipeline. Parser, Validator and
+// Dispatcher replace the built-in stage when set. Enrichers and Filters are added to the
+// built-in ones, so extra dedupe or enrichment runs without giving them up, and
+// Subscribers receive the bus's events after the stages, e.g. to deliver accepted
+// entries to further sinks.
+type NotificationStages struct {
+	Parser      NotificationParser
+	Validator   NotificationValidator
+	Enrichers   []EntryEnricher // Run after the subscription's activity is recorded
+	Filters     []EntryFilter   // Run after the new-video, duplicate and channel filters, before premieres are held
+	Dispatcher  EntryDispatcher
+	Subscribers map[string][]EventHandler // By topic
+}
+
+// notificationPipeline connects the stages a notification passes through (parse,
+// validate, enrich, filter and dispatch) by subscribing each to the bus
+type notificationPipeline struct {
+	bus        *EventBus
+	parser     NotificationParser
+	validator  NotificationValidator
+	enrichers  []EntryEnricher
+	filters    []EntryFilter
+	dispatcher EntryDispatcher
+	ns         *NotificationService
+}
+
+// pipelineRun collects the outcome of one notification as its events finish
+type pipelineRun struct {
+	finished bool // The notification ended before its entries were processed
+	result   *NotificationResult
+	err      error
+	entries  []*PipelineEvent
+}
+
+// newNotificationPipeline builds the pipeline of a notification service from the
+// built-in stages and the service's Stages
+func newNotificationPipeline(ns *NotificationService) *notificationPipeline {
+	stages := ns.Stages
+	if stages == nil {
+		stages = &NotificationStages{}
+	}
+
+	p := &notificationPipeline{
+		bus:        NewEventBus(),
+		parser:     NotificationParserFunc(ns.parseNotification),
+		validator:  NotificationValidatorFunc(ns.validateNotification),
+		dispatcher: EntryDispatcherFunc(ns.deliverEntry),
+		ns:         ns,
+	}
+	if stages.Parser != nil {
+		p.parser = stages.Parser
+	}
+	if stages.Validator != nil {
+		p.validator = stages.Validator
+	}
+	if stages.Dispatcher != nil {
+		p.dispatcher = stages.Dispatcher
+	}
+
+	// Activity is recorded for subscription health before deciding what to do with the entry
+	p.enrichers = append([]EntryEnricher{EntryEnricherFunc(ns.recordActivity)}, stages.Enrichers...)
+
+	// Upcoming premieres are held last, as only videos about to be dispatched are looked up
+	p.filters = []EntryFilter{
+		EntryFilterFunc(ns.filterNotNew),
+		EntryFilterFunc(ns.filterDuplicate),
+		EntryFilterFunc(ns.filterChannel),
+	}
+	p.filters = append(p.filters, stages.Filters...)
+	p.filters = append(p.filters, EntryFilterFunc(ns.holdPremiere))
+
+	p.bus.Subscribe(TopicNotificationReceived, p.parse)
+	p.bus.Subscribe(TopicNotificationParsed, p.validate)
+	p.bus.Subscribe(TopicNotificationValidated, p.split)
+	p.bus.Subscribe(TopicEntryReceived, p.enrich)
+	p.bus.Subscribe(TopicEntryEnriched, p.filter)
+	p.bus.Subscribe(TopicEntryAccepted, p.dispatch)
+	for topic, handlers := range stages.Subscribers {
+		for _, handler := range handlers {
+			p.bus.Subscribe(topic, handler)
+		}
+	}
+	return p
+}
+
+// process publishes a notification request on the bus and returns its outcome
+func (p *notificationPipeline) process(r *http.Request) (*NotificationResult, error) {
+	ctx := r.Context()
+	run := &pipelineRun{}
+	p.bus.Publish(ctx, &PipelineEvent{Topic: TopicNotificationReceived, Request: r, run: run})
+
+	result, err := run.outcome()
+	p.bus.Publish(ctx, &PipelineEvent{Topic: TopicNotificationDone, Request: r, Result: result, Err: err, run: run})
+	return result, err
+}
+
+func (p *notificationPipeline) parse(ctx context.Context, event *PipelineEvent) {
+	feed, body, err := p.parser.Parse(event.Request)
+	if err != nil {
+		p.reject(event, body, err)
+		return
+	}
+	if len(feed.Entries) == 0 {
+		event.run.finish(&NotificationResult{
+			Status:  "success",
+			Message: "Empty notification (no entry found)",
+		}, nil)
+		return
+	}
+	p.bus.Publish(ctx, &PipelineEvent{Topic: TopicNotificationParsed, Request: event.Request, Body: body, Feed: feed, run: event.run})
+}
+
+func (p *notificationPipeline) validate(ctx context.Context, event *PipelineEvent) {
+	if err := p.validator.Validate(event.Request, event.Body, event.Feed); err != nil {
+		p.reject(event, event.Body, err)
+		return
+	}
+	p.bus.Publish(ctx, &PipelineEvent{Topic: TopicNotificationValidated, Request: event.Request, Body: event.Body, Feed: event.Feed, run: event.run})
+}
+
+// split publishes each entry of a validated notification in turn. Every entry is
+// processed even when an earlier one failed; entries already dispatched are skipped as
+// duplicates when the hub redelivers the notification.
+func (p *notificationPipeline) split(ctx context.Context, event *PipelineEvent) {
+	for _, entry := range event.Feed.Entries {
+		p.bus.Publish(ctx, &PipelineEvent{Topic: TopicEntryReceived, Request: event.Request, Entry: entry, run: event.run})
+	}
+}
+
+func (p *notificationPipeline) enrich(ctx context.Context, event *PipelineEvent) {
+	for _, enricher := range p.enrichers {
+		enricher.Enrich(ctx, event.Entry)
+	}
+	p.bus.Publish(ctx, &PipelineEvent{Topic: TopicEntryEnriched, Request: event.Request, Entry: event.Entry, run: event.run})
+}
+
+func (p *notificationPipeline) filter(ctx context.Context, event *PipelineEvent) {
+	for _, filter := range p.filters {
+		if result := filter.Filter(ctx, event.Entry); result != nil {
+			p.finishEntry(ctx, event, result, nil)
+			return
+		}
+	}
+	p.bus.Publish(ctx, &PipelineEvent{Topic: TopicEntryAccepted, Request: event.Request, Entry: event.Entry, run: event.run})
+}
+
+func (p *notificationPipeline) dispatch(ctx context.Context, event *PipelineEvent) {
+	result, err := p.deliver(ctx, event.Entry)
+	p.finishEntry(ctx, event, result, err)
+}
+
+// deliver dispatches an entry, recording it in the outbox first so a dispatch cut short
+// by the instance dying is made again, and removing it once dispatched. When storage
+// cannot take the outbox entry the entry is dispatched without it.
+func (p *notificationPipeline) deliver(ctx context.Context, entry *Entry) (*NotificationResult, error) {
+	recorded := p.ns.writeOutbox(ctx, entry) == nil
+
+	result, err := p.dispatcher.Dispatch(ctx, entry)
+	if err == nil && recorded {
+		p.ns.markDelivered(ctx, entry)
+	}
+	return result, err
+}
+
+// finishEntry records an entry's result and publishes it on TopicEntryDone. A
+// dispatcher that returned no result gets one made from its error.
+func (p *notificationPipeline) finishEntry(ctx context.Context, event *PipelineEvent, result *NotificationResult, err error) {
+	if result == nil {
+		result = missingResult(event.Entry, err)
+	}
+	done := &PipelineEvent{Topic: TopicEntryDone, Request: event.Request, Entry: event.Entry, Result: result, Err: err, run: event.run}
+	event.run.entries = append(event.run.entries, done)
+	p.bus.Publish(ctx, done)
+}
+
+// missingResult stands in for the result a dispatcher did not return
+func missingResult(entry *Entry, err error) *NotificationResult {
+	if err != nil {
+		return &NotificationResult{Status: "error", Message: err.Error()}
+	}
+	return &NotificationResult{Status: "success", Message: fmt.Sprintf("Processed video: %s", entry.VideoID)}
+}
+
+// reject ends a notification that could not be parsed or validated, dead-lettering
+// those that are malformed so they can be replayed
+func (p *notificationPipeline) reject(event *PipelineEvent, body []byte, err error) {
+	var message string
+	switch {
+	case errors.Is(err, ErrBodyRead):
+		message = "Failed to read request body"
+	case errors.Is(err, ErrBodyTooLarge):
+		message = "Notification body too large"
+	case errors.Is(err, ErrInvalidXML):
+		message = "Invalid XML"
+		p.ns.deadLetter(event.Request, body, err)
+	case errors.Is(err, ErrInvalidEntry):
+		message = "Invalid entry: " + strings.TrimPrefix(err.Error(), ErrInvalidEntry.Error()+": ")
+		p.ns.deadLetter(event.Request, body, err)
+	case errors.Is(err, ErrMissingSignature), errors.Is(err, ErrInvalidSignature):
+		message = "Invalid signature"
+	case errors.Is(err, ErrUnsubscribedTopic):
+		message = "Topic not subscribed"
+	case errors.Is(err, ErrUnverifiedSignature):
+		message = "Unable to verify signature: " + strings.TrimPrefix(err.Error(), ErrUnverifiedSignature.Error()+": ")
+	default:
+		message = err.Error()
+	}
+	event.run.finish(&NotificationResult{
+		Status:  "error",
+		Message: message,
+	}, err)
+}
+
+// finish ends the notification with a result of its own
+func (run *pipelineRun) finish(result *NotificationResult, err error) {
+	run.finished = true
+	run.result = result
+	run.err = err
+}
+
+// outcome returns a notification's result: its own when it ended early, its entry's
+// when it had one, and otherwise each entry's result. A notification with several
+// entries fails, so the hub redelivers it, when any entry failed.
+func (run *pipelineRun) outcome() (*NotificationResult, error) {
+	if run.finished {
+		return run.result, run.err
+	}
+	if len(run.entries) == 1 {
+		return run.entries[0].Result, run.entries[0].Err
+	}
+
+	result := &NotificationResult{Status: "success"}
+	var firstErr error
+	failed := 0
+	for _, entry := range run.entries {
+		if entry.Err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = entry.Err
+			}
+		}
+		result.Entries = append(result.Entries, EntryResult{
+			VideoID:    entry.Entry.VideoID,
+			Status:     entry.Result.Status,
+			Message:    entry.Result.Message,
+			Deliveries: entry.Result.Deliveries,
+		})
+	}
+
+	result.Message = fmt.Sprintf("Processed %d entries", len(run.entries))
+	if failed > 0 {
+		result.Status = "error"
+		result.Message = fmt.Sprintf("%d of %d entries failed", failed, len(run.entries))
+	}
+	return result, firstErr
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus_Publish(t *testing.T) {
+	bus := NewEventBus()
+	var delivered []string
+	bus.Subscribe("first", func(ctx context.Context, event *PipelineEvent) {
+		delivered = append(delivered, "first:a")
+		bus.Publish(ctx, &PipelineEvent{Topic: "second"})
+	})
+	bus.Subscribe("first", func(ctx context.Context, event *PipelineEvent) {
+		delivered = append(delivered, "first:b")
+	})
+	bus.Subscribe("second", func(ctx context.Context, event *PipelineEvent) {
+		delivered = append(delivered, "second")
+	})
+
+	bus.Publish(context.Background(), &PipelineEvent{Topic: "first"})
+	bus.Publish(context.Background(), &PipelineEvent{Topic: "unsubscribed"})
+
+	assert.Equal(t, []string{"first:a", "second", "first:b"}, delivered,
+		"handlers run in subscription order and nested events are delivered before Publish returns")
+}
+
+func TestNotificationPipeline_Stages(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+
+	t.Run("filter_added", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		var done []*PipelineEvent
+		deps.NotificationStages = &NotificationStages{
+			Filters: []EntryFilter{EntryFilterFunc(func(ctx context.Context, entry *Entry) *NotificationResult {
+				return &NotificationResult{Status: "success", Message: "Skipped: blocked " + entry.VideoID}
+			})},
+			Subscribers: map[string][]EventHandler{TopicEntryDone: {func(ctx context.Context, event *PipelineEvent) {
+				done = append(done, event)
+			}}},
+		}
+
+		rec := postPremiereNotification(deps, "pipeline001")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Skipped: blocked pipeline001", rec.Body.String())
+		assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+		require.Len(t, done, 1)
+		assert.Equal(t, "pipeline001", done[0].Entry.VideoID)
+	})
+
+	t.Run("built_in_filters_run_first", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		filtered := 0
+		deps.NotificationStages = &NotificationStages{
+			Filters: []EntryFilter{EntryFilterFunc(func(ctx context.Context, entry *Entry) *NotificationResult {
+				filtered++
+				return nil
+			})},
+		}
+
+		require.Equal(t, http.StatusOK, postPremiereNotification(deps, "pipeline002").Code)
+		rec := postPremiereNotification(deps, "pipeline002")
+
+		assert.Contains(t, rec.Body.String(), "Duplicate skipped")
+		assert.Equal(t, 1, filtered, "duplicates are skipped before added filters")
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("enricher_and_subscriber_added", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		var accepted []string
+		deps.NotificationStages = &NotificationStages{
+			Enrichers: []EntryEnricher{EntryEnricherFunc(func(ctx context.Context, entry *Entry) {
+				entry.Title = "[enriched] " + entry.Title
+			})},
+			Subscribers: map[string][]EventHandler{TopicEntryAccepted: {func(ctx context.Context, event *PipelineEvent) {
+				accepted = append(accepted, event.Entry.Title)
+			}}},
+		}
+
+		rec := postPremiereNotification(deps, "pipeline003")
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, []string{"[enriched] Premiere"}, accepted)
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount(), "the built-in dispatcher still runs")
+	})
+
+	t.Run("dispatcher_replaced", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.NotificationStages = &NotificationStages{
+			Dispatcher: EntryDispatcherFunc(func(ctx context.Context, entry *Entry) (*NotificationResult, error) {
+				return &NotificationResult{Status: "success", Message: "Queued " + entry.VideoID}, nil
+			}),
+		}
+
+		rec := postPremiereNotification(deps, "pipeline004")
+
+		assert.Equal(t, "Queued pipeline004", rec.Body.String())
+		assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("dispatcher_returns_no_result", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		var dispatchErr error
+		var done []*PipelineEvent
+		deps.NotificationStages = &NotificationStages{
+			Dispatcher: EntryDispatcherFunc(func(ctx context.Context, entry *Entry) (*NotificationResult, error) {
+				return nil, dispatchErr
+			}),
+			Subscribers: map[string][]EventHandler{TopicEntryDone: {func(ctx context.Context, event *PipelineEvent) {
+				done = append(done, event)
+			}}},
+		}
+
+		dispatchErr = errors.New("queue unavailable")
+		rec := postPremiereNotification(deps, "pipeline005")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "queue unavailable")
+		require.Len(t, done, 1)
+		assert.Equal(t, "error", done[0].Result.Status, "subscribers see a result made from the error")
+
+		dispatchErr = nil
+		rec = postPremiereNotification(deps, "pipeline006")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Processed video: pipeline006", rec.Body.String())
+
+		// Each entry of a batch gets a result
+		now := time.Now()
+		entry := func(videoID string) string {
+			return fmt.Sprintf(`<entry><yt:videoId>%s</yt:videoId><yt:channelId>UCXuqSBlHAE6Xw-yeJA0Tunw</yt:channelId>`+
+				`<title>Batched</title><published>%s</published><updated>%s</updated></entry>`,
+				videoID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+		}
+		dispatchErr = errors.New("queue unavailable")
+		batch := `<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">` +
+			entry("pipeline007") + entry("pipeline008") + `</feed>`
+		rec = httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(batch)))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		var result NotificationResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		require.Len(t, result.Entries, 2)
+		assert.Equal(t, "queue unavailable", result.Entries[1].Message)
+	})
+
+	t.Run("rejected_notification", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		var topics []string
+		record := func(ctx context.Context, event *PipelineEvent) {
+			topics = append(topics, event.Topic)
+		}
+		deps.NotificationStages = &NotificationStages{
+			Subscribers: map[string][]EventHandler{
+				TopicNotificationParsed: {record},
+				TopicNotificationDone:   {record},
+			},
+		}
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader("<feed")))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, []string{TopicNotificationDone}, topics, "malformed notifications stop at the parse stage")
+	})
+}
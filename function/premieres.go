@@ -0,0 +1,432 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Live broadcast states reported by the YouTube Data API's snippet.liveBroadcastContent
+const (
+	BroadcastUpcoming = "upcoming" // A premiere or live stream that has not started
+	BroadcastLive     = "live"     // A premiere or live stream that is on now
+	BroadcastNone     = "none"     // A regular video, or a premiere or stream that has ended
+)
+
+// Premiere handling modes, selected with PREMIERE_HANDLING
+const (
+	PremiereHandlingOff   = "off"   // Premiere state is not looked up (default)
+	PremiereHandlingTrack = "track" // Premiere state is looked up and recorded; videos are dispatched at once
+	PremiereHandlingDelay = "delay" // Upcoming premieres are held until they go live
+)
+
+// Steps reported for held premieres by POST /premieres
+const (
+	PremiereActionReleased = "released" // Went live; dispatched
+	PremiereActionWaiting  = "waiting"  // Still upcoming; checked again later
+	PremiereActionDropped  = "dropped"  // Deleted, or never went live; not dispatched
+	PremiereActionFailed   = "failed"   // The lookup failed; checked again later
+)
+
+// pendingPremieresObject is where held premieres are stored
+const pendingPremieresObject = "premieres/pending.json"
+
+// premiereCheckInterval is how often a held premiere past its scheduled start is looked up
+const premiereCheckInterval = 5 * time.Minute
+
+// premiereAbandonAfter is how long after its scheduled start a premiere that is still
+// upcoming is dropped, as YouTube leaves cancelled premieres upcoming
+const premiereAbandonAfter = 7 * 24 * time.Hour
+
+// PendingPremiere is a new video held until its premiere or live stream starts
+type PendingPremiere struct {
+	VideoID            string    `json:"video_id"`
+	ChannelID          string    `json:"channel_id"`
+	PlaylistID         string    `json:"playlist_id,omitempty"` // Set when the video came from a playlist's feed
+	Title              string    `json:"title"`
+	Published          string    `json:"published"`
+	Updated            string    `json:"updated"`
+	ScheduledStartTime time.Time `json:"scheduled_start_time,omitempty"` // Zero when YouTube gave no schedule
+	HeldAt             time.Time `json:"held_at"`
+	LastCheckedAt      time.Time `json:"last_checked_at"`
+	Checks             int       `json:"checks"` // Lookups since it was held
+	LastError          string    `json:"last_error,omitempty"`
+}
+
+// entry rebuilds the notification entry the premiere was held for
+func (p *PendingPremiere) entry() *Entry {
+	return &Entry{
+		VideoID:    p.VideoID,
+		ChannelID:  p.ChannelID,
+		Title:      p.Title,
+		Published:  p.Published,
+		Updated:    p.Updated,
+		Link:       Link{Href: "https://www.youtube.com/watch?v=" + p.VideoID},
+		HeldAt:     p.HeldAt,
+		PlaylistID: p.PlaylistID,
+	}
+}
+
+// due reports whether the premiere should be looked up again at now: once it is past
+// its scheduled start and premiereCheckInterval after the last lookup
+func (p *PendingPremiere) due(now time.Time) bool {
+	return !now.Before(p.ScheduledStartTime) && now.Sub(p.LastCheckedAt) >= premiereCheckInterval
+}
+
+// waitingSince returns when the premiere was due to start, or when it was held when
+// YouTube gave no schedule
+func (p *PendingPremiere) waitingSince() time.Time {
+	if p.ScheduledStartTime.IsZero() {
+		return p.HeldAt
+	}
+	return p.ScheduledStartTime
+}
+
+// pendingPremieres is the stored list of held premieres
+type pendingPremieres struct {
+	Pending []*PendingPremiere `json:"pending"`
+}
+
+// PendingPremieresResponse lists the held premieres
+type PendingPremieresResponse struct {
+	Status  string             `json:"status"`
+	Pending []*PendingPremiere `json:"pending"`
+}
+
+// PremiereCheckResult describes one held premiere looked up by a check
+type PremiereCheckResult struct {
+	VideoID              string `json:"video_id"`
+	ChannelID            string `json:"channel_id"`
+	Action               string `json:"action"`
+	LiveBroadcastContent string `json:"live_broadcast_content,omitempty"`
+	Message              string `json:"message"`
+}
+
+// PremiereCheckResponse summarises a pass over the held premieres
+type PremiereCheckResponse struct {
+	Status   string                `json:"status"`
+	Checked  int                   `json:"checked"`
+	Released int                   `json:"released"`
+	Dropped  int                   `json:"dropped"`
+	Pending  int                   `json:"pending"` // Still held after this pass
+	Results  []PremiereCheckResult `json:"results"`
+}
+
+// PremiereTracker persists the premieres held until they go live when
+// PREMIERE_HANDLING is delay
+type PremiereTracker struct {
+	store ObjectStorage
+	mu    sync.Mutex
+}
+
+// getPremiereHandling returns the configured premiere handling, falling back to off for
+// unknown values
+func getPremiereHandling() string {
+	return envConfig().PremiereHandling
+}
+
+// NewPremiereTracker creates a premiere tracker in the given storage.
+// Returns nil (premieres are not held) when the storage cannot hold objects.
+func NewPremiereTracker(storage StorageService) *PremiereTracker {
+	store, ok := storage.(ObjectStorage)
+	if !ok {
+		return nil
+	}
+	return &PremiereTracker{store: store}
+}
+
+// newPremiereHandling returns the YouTube Data API client premiere state is looked up
+// with and the tracker upcoming premieres are held in, as handling (PREMIERE_HANDLING)
+// selects. The client is nil when premieres are not handled or YOUTUBE_API_KEY is not
+// set, and the tracker is nil unless premieres are delayed.
+func newPremiereHandling(storage StorageService, handling string) (*YouTubeAPIClient, *PremiereTracker) {
+	if handling == PremiereHandlingOff {
+		return nil, nil
+	}
+	youtube := NewYouTubeAPIClient()
+	if !youtube.IsConfigured() {
+		return nil, nil
+	}
+	if handling != PremiereHandlingDelay {
+		return youtube, nil
+	}
+	return youtube, NewPremiereTracker(storage)
+}
+
+// load reads the held premieres, treating a missing object as empty
+func (t *PremiereTracker) load(ctx context.Context) (*pendingPremieres, error) {
+	premieres := &pendingPremieres{}
+
+	data, err := t.store.ReadObject(ctx, pendingPremieresObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return premieres, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending premieres: %v", err)
+	}
+	if err := json.Unmarshal(data, premieres); err != nil {
+		return nil, fmt.Errorf("failed to decode pending premieres: %v", err)
+	}
+	return premieres, nil
+}
+
+// save writes the held premieres
+func (t *PremiereTracker) save(ctx context.Context, premieres *pendingPremieres) error {
+	data, err := json.Marshal(premieres)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending premieres: %v", err)
+	}
+	if err := t.store.WriteObject(ctx, pendingPremieresObject, data); err != nil {
+		return fmt.Errorf("failed to write pending premieres: %v", err)
+	}
+	return nil
+}
+
+// Hold records entry as an upcoming premiere. A premiere already held keeps when it was
+// held and takes the entry's title and the new schedule.
+func (t *PremiereTracker) Hold(ctx context.Context, entry *Entry, broadcast *VideoBroadcast) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	premieres, err := t.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := currentTime(ctx)
+	for _, held := range premieres.Pending {
+		if held.VideoID == entry.VideoID {
+			held.Title = entry.Title
+			held.Updated = entry.Updated
+			held.ScheduledStartTime = broadcast.ScheduledStartTime
+			held.LastCheckedAt = now
+			return t.save(ctx, premieres)
+		}
+	}
+	premieres.Pending = append(premieres.Pending, &PendingPremiere{
+		VideoID:            entry.VideoID,
+		ChannelID:          entry.ChannelID,
+		PlaylistID:         entry.PlaylistID,
+		Title:              entry.Title,
+		Published:          entry.Published,
+		Updated:            entry.Updated,
+		ScheduledStartTime: broadcast.ScheduledStartTime,
+		HeldAt:             now,
+		LastCheckedAt:      now,
+	})
+	return t.save(ctx, premieres)
+}
+
+// List returns the held premieres
+func (t *PremiereTracker) List(ctx context.Context) ([]*PendingPremiere, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	premieres, err := t.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if premieres.Pending == nil {
+		return []*PendingPremiere{}, nil
+	}
+	return premieres.Pending, nil
+}
+
+// CheckDue looks up every held premiere that is due, or every one with all set, and
+// stops holding those that went live, were deleted or never went live. The entries of
+// the premieres that went live are returned for dispatch.
+func (t *PremiereTracker) CheckDue(ctx context.Context, lookup func(context.Context, string) (*VideoBroadcast, error),
+	all bool) (*PremiereCheckResponse, []*Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	premieres, err := t.load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := currentTime(ctx)
+	summary := &PremiereCheckResponse{Status: "success", Results: []PremiereCheckResult{}}
+	var released []*Entry
+	var pending []*PendingPremiere
+	for _, held := range premieres.Pending {
+		if !all && !held.due(now) {
+			pending = append(pending, held)
+			continue
+		}
+
+		summary.Checked++
+		held.Checks++
+		held.LastCheckedAt = now
+		broadcast, err := lookup(ctx, held.VideoID)
+
+		result := PremiereCheckResult{VideoID: held.VideoID, ChannelID: held.ChannelID}
+		switch {
+		case errors.Is(err, ErrVideoNotFound):
+			summary.Dropped++
+			result.Action = PremiereActionDropped
+			result.Message = "Video no longer exists"
+		case err != nil:
+			held.LastError = err.Error()
+			pending = append(pending, held)
+			result.Action = PremiereActionFailed
+			result.Message = fmt.Sprintf("Unable to look up premiere state: %v", err)
+		case broadcast.LiveBroadcastContent != BroadcastUpcoming:
+			summary.Released++
+			entry := held.entry()
+			entry.LiveBroadcastContent = broadcast.LiveBroadcastContent
+			released = append(released, entry)
+			result.Action = PremiereActionReleased
+			result.LiveBroadcastContent = broadcast.LiveBroadcastContent
+			result.Message = "Premiere went live"
+		default:
+			if !broadcast.ScheduledStartTime.IsZero() {
+				held.ScheduledStartTime = broadcast.ScheduledStartTime
+			}
+			held.LastError = ""
+			result.LiveBroadcastContent = broadcast.LiveBroadcastContent
+			if now.Sub(held.waitingSince()) > premiereAbandonAfter {
+				summary.Dropped++
+				result.Action = PremiereActionDropped
+				result.Message = fmt.Sprintf("Still upcoming %s after it was due to start", premiereAbandonAfter)
+				break
+			}
+			pending = append(pending, held)
+			result.Action = PremiereActionWaiting
+			result.Message = "Premiere not live yet"
+			if !held.ScheduledStartTime.IsZero() {
+				result.Message = fmt.Sprintf("Premiere scheduled for %s", held.ScheduledStartTime.Format(timeFormat()))
+			}
+		}
+		summary.Results = append(summary.Results, result)
+	}
+	premieres.Pending = pending
+
+	summary.Pending = len(premieres.Pending)
+	if summary.Checked == 0 {
+		return summary, nil, nil
+	}
+	if err := t.save(ctx, premieres); err != nil {
+		return nil, nil, err
+	}
+	return summary, released, nil
+}
+
+// holdPremiere looks up whether a new video is an upcoming premiere or live stream when
+// PREMIERE_HANDLING is enabled, recording the state on entry. When premieres are delayed
+// an upcoming one is held and the result recorded for it is returned; otherwise nil is
+// returned and the video is dispatched. Failures are logged and the video is dispatched.
+func (ns *NotificationService) holdPremiere(ctx context.Context, entry *Entry) *NotificationResult {
+	if ns.YouTubeAPI == nil {
+		return nil
+	}
+	broadcast, err := ns.YouTubeAPI.GetBroadcast(ctx, entry.VideoID)
+	if err != nil {
+		fmt.Printf("Unable to look up premiere state of %s: %v\n", entry.VideoID, err)
+		return nil
+	}
+	entry.LiveBroadcastContent = broadcast.LiveBroadcastContent
+	if broadcast.LiveBroadcastContent != BroadcastUpcoming || ns.Premieres == nil {
+		return nil
+	}
+
+	if err := ns.Premieres.Hold(ctx, entry, broadcast); err != nil {
+		fmt.Printf("Unable to hold premiere %s, dispatching it now: %v\n", entry.VideoID, err)
+		return nil
+	}
+	message := fmt.Sprintf("Held: premiere not live yet (VideoID: %s)", entry.VideoID)
+	if !broadcast.ScheduledStartTime.IsZero() {
+		message = fmt.Sprintf("Held: premiere scheduled for %s (VideoID: %s)",
+			broadcast.ScheduledStartTime.Format(timeFormat()), entry.VideoID)
+	}
+	return ns.recordHistory(ctx, entry, HistoryDecisionPremiereHeld, &NotificationResult{
+		Status:  "success",
+		Message: message,
+	})
+}
+
+// releasePremieres checks the held premieres that are due, or every one with all set,
+// and dispatches those that went live. Each released premiere's result carries the
+// dispatch result.
+func (ns *NotificationService) releasePremieres(ctx context.Context, all bool) (*PremiereCheckResponse, error) {
+	summary, released, err := ns.Premieres.CheckDue(ctx, ns.YouTubeAPI.GetBroadcast, all)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range released {
+		message := fmt.Sprintf("Duplicate skipped: video already processed (VideoID: %s)", entry.VideoID)
+		if !ns.alreadyProcessed(ctx, entry) {
+			result, err := ns.notificationPipeline().deliver(ctx, entry)
+			message = result.Message
+			if err != nil {
+				fmt.Printf("Unable to dispatch premiere %s: %v\n", entry.VideoID, err)
+			}
+		}
+		for i := range summary.Results {
+			if summary.Results[i].VideoID == entry.VideoID {
+				summary.Results[i].Message += ": " + message
+			}
+		}
+	}
+	return summary, nil
+}
+
+// releaseDuePremieres dispatches the held premieres that went live; failures are only logged
+func (ns *NotificationService) releaseDuePremieres(ctx context.Context) {
+	if ns.Premieres == nil {
+		return
+	}
+	summary, err := ns.releasePremieres(ctx, false)
+	if err != nil {
+		fmt.Printf("Unable to check pending premieres: %v\n", err)
+		return
+	}
+	if summary.Checked > 0 {
+		fmt.Printf("Checked %d pending premieres: %d released, %d dropped, %d still pending\n",
+			summary.Checked, summary.Released, summary.Dropped, summary.Pending)
+	}
+}
+
+// handleGetPremieres handles GET /premieres requests using dependency injection
+func handleGetPremieres(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tracker := NewPremiereTracker(deps.StorageClient)
+		if tracker == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "", "Storage backend does not support pending premieres")
+			return
+		}
+
+		pending, err := tracker.List(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, PendingPremieresResponse{Status: "success", Pending: pending})
+	}
+}
+
+// handleCheckPremieres handles POST /premieres requests using dependency injection.
+// Held premieres past their scheduled start are looked up and dispatched once they go
+// live; ?all=true looks up every held premiere immediately.
+func handleCheckPremieres(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		service := newNotificationService(deps)
+		if service.Premieres == nil {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "",
+				"Premieres are not held; set PREMIERE_HANDLING=delay and YOUTUBE_API_KEY")
+			return
+		}
+
+		summary, err := service.releasePremieres(r.Context(), r.URL.Query().Get("all") == "true")
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, summary)
+	}
+}
@@ -0,0 +1,313 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const premiereTestVideo = "premiere123"
+
+// premiereAPI serves videos.list with each video's liveBroadcastContent and scheduled
+// start; videos it does not know are not found
+type premiereAPI struct {
+	mu        sync.Mutex
+	states    map[string]string
+	scheduled time.Time
+	lookups   int
+}
+
+func (p *premiereAPI) set(videoID, state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.states[videoID] = state
+}
+
+func (p *premiereAPI) lookupCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lookups
+}
+
+// newPremiereAPIServer starts a premiereAPI and points PREMIERE_HANDLING at it in mode
+func newPremiereAPIServer(t *testing.T, mode string) *premiereAPI {
+	api := &premiereAPI{states: map[string]string{}, scheduled: time.Now().Add(time.Hour).Truncate(time.Second)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snippet,liveStreamingDetails", r.URL.Query().Get("part"))
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		api.lookups++
+		videoID := r.URL.Query().Get("id")
+		state, ok := api.states[videoID]
+		if !ok {
+			fmt.Fprint(w, `{"items":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"items":[{"id":%q,"snippet":{"channelId":%q,"liveBroadcastContent":%q},"liveStreamingDetails":{"scheduledStartTime":%q}}]}`,
+			videoID, routingChannelID, state, api.scheduled.Format(time.RFC3339))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("PREMIERE_HANDLING", mode)
+	t.Setenv("YOUTUBE_API_KEY", "test-key")
+	t.Setenv("YOUTUBE_API_BASE_URL", server.URL)
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+	return api
+}
+
+// postPremiereNotification delivers a notification for a video published just now
+func postPremiereNotification(deps *Dependencies, videoID string) *httptest.ResponseRecorder {
+	subscribeTestChannels(deps, routingChannelID)
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>%s</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Premiere</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, videoID, routingChannelID, now.Format(time.RFC3339), now.Format(time.RFC3339))
+
+	rec := httptest.NewRecorder()
+	handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+	return rec
+}
+
+// readPendingPremieres returns the stored held premieres
+func readPendingPremieres(t *testing.T, storage *MockStorageClient) []*PendingPremiere {
+	pending, err := NewPremiereTracker(storage).List(context.Background())
+	require.NoError(t, err)
+	return pending
+}
+
+// makePremieresDue moves every held premiere's schedule and last lookup into the past
+func makePremieresDue(t *testing.T, storage *MockStorageClient) {
+	premieres := &pendingPremieres{Pending: readPendingPremieres(t, storage)}
+	for _, held := range premieres.Pending {
+		held.ScheduledStartTime = time.Now().Add(-time.Minute)
+		held.LastCheckedAt = time.Now().Add(-premiereCheckInterval)
+	}
+	data, err := json.Marshal(premieres)
+	require.NoError(t, err)
+	require.NoError(t, storage.WriteObject(context.Background(), pendingPremieresObject, data))
+}
+
+func TestGetPremiereHandling(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"", PremiereHandlingOff},
+		{"track", PremiereHandlingTrack},
+		{"delay", PremiereHandlingDelay},
+		{"wait", PremiereHandlingOff},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("PREMIERE_HANDLING", tc.value)
+			assert.Equal(t, tc.expected, getPremiereHandling())
+		})
+	}
+}
+
+func TestYouTubeAPIClient_GetBroadcast(t *testing.T) {
+	api := newPremiereAPIServer(t, PremiereHandlingTrack)
+	api.set(premiereTestVideo, BroadcastUpcoming)
+	api.set("regular1", "")
+	client := NewYouTubeAPIClient()
+
+	broadcast, err := client.GetBroadcast(context.Background(), premiereTestVideo)
+	require.NoError(t, err)
+	assert.Equal(t, BroadcastUpcoming, broadcast.LiveBroadcastContent)
+	assert.True(t, api.scheduled.Equal(broadcast.ScheduledStartTime))
+	assert.True(t, broadcast.ActualStartTime.IsZero())
+
+	broadcast, err = client.GetBroadcast(context.Background(), "regular1")
+	require.NoError(t, err)
+	assert.Equal(t, BroadcastNone, broadcast.LiveBroadcastContent)
+
+	_, err = client.GetBroadcast(context.Background(), "missingVid1")
+	assert.ErrorIs(t, err, ErrVideoNotFound)
+}
+
+func TestHandleNotification_PremiereTracked(t *testing.T) {
+	api := newPremiereAPIServer(t, PremiereHandlingTrack)
+	api.set(premiereTestVideo, BroadcastUpcoming)
+	deps := CreateTestDependencies()
+	storage := deps.StorageClient.(*MockStorageClient)
+
+	rec := postPremiereNotification(deps, premiereTestVideo)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount(), "dispatched at once when only tracked")
+	assert.Empty(t, readPendingPremieres(t, storage))
+
+	history, err := NewNotificationHistory(storage, envConfig()).List(context.Background(), routingChannelID, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, HistoryDecisionDispatched, history[0].Decision)
+	assert.Equal(t, BroadcastUpcoming, history[0].LiveBroadcastContent)
+}
+
+func TestHandleNotification_PremiereDelayed(t *testing.T) {
+	api := newPremiereAPIServer(t, PremiereHandlingDelay)
+	api.set(premiereTestVideo, BroadcastUpcoming)
+	api.set("regular1", BroadcastNone)
+	deps := CreateTestDependencies()
+	storage := deps.StorageClient.(*MockStorageClient)
+	gh := deps.GitHubClient.(*MockGitHubClient)
+
+	rec := postPremiereNotification(deps, premiereTestVideo)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Held: premiere scheduled for")
+	assert.Zero(t, gh.GetTriggerCallCount())
+
+	pending := readPendingPremieres(t, storage)
+	require.Len(t, pending, 1)
+	assert.Equal(t, premiereTestVideo, pending[0].VideoID)
+	assert.True(t, api.scheduled.Equal(pending[0].ScheduledStartTime))
+
+	// Regular videos are dispatched as usual, and the premiere is not due yet
+	rec = postPremiereNotification(deps, "regular1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, gh.GetTriggerCallCount())
+	assert.Equal(t, 2, api.lookupCount(), "the held premiere is not looked up before its scheduled start")
+
+	// Once it goes live the next notification dispatches it
+	api.set(premiereTestVideo, BroadcastLive)
+	makePremieresDue(t, storage)
+	rec = postPremiereNotification(deps, "regular2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 3, gh.GetTriggerCallCount(), "the premiere was released; regular2 is unknown to the API and dispatched too")
+	assert.Empty(t, readPendingPremieres(t, storage))
+
+	history, err := NewNotificationHistory(storage, envConfig()).List(context.Background(), routingChannelID, 0)
+	require.NoError(t, err)
+	var decisions []string
+	for _, entry := range history {
+		decisions = append(decisions, entry.VideoID+":"+entry.Decision)
+		if entry.VideoID == premiereTestVideo && entry.Decision == HistoryDecisionDispatched {
+			assert.Equal(t, BroadcastLive, entry.LiveBroadcastContent)
+			assert.NotEmpty(t, entry.HeldAt)
+		}
+	}
+	assert.Contains(t, decisions, premiereTestVideo+":"+HistoryDecisionPremiereHeld)
+	assert.Contains(t, decisions, premiereTestVideo+":"+HistoryDecisionDispatched)
+}
+
+func TestPremiereTracker_CheckDue(t *testing.T) {
+	ctx := context.Background()
+	lookup := func(states map[string]string) func(context.Context, string) (*VideoBroadcast, error) {
+		return func(_ context.Context, videoID string) (*VideoBroadcast, error) {
+			state, ok := states[videoID]
+			switch {
+			case !ok:
+				return nil, ErrVideoNotFound
+			case state == "error":
+				return nil, fmt.Errorf("YouTube API returned status 500")
+			}
+			return &VideoBroadcast{LiveBroadcastContent: state}, nil
+		}
+	}
+	hold := func(t *testing.T, tracker *PremiereTracker, videoIDs ...string) {
+		for _, videoID := range videoIDs {
+			require.NoError(t, tracker.Hold(ctx, newRetryTestEntry(videoID), &VideoBroadcast{LiveBroadcastContent: BroadcastUpcoming}))
+		}
+	}
+
+	t.Run("outcomes", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		tracker := NewPremiereTracker(storage)
+		hold(t, tracker, "live1", "ended1", "waiting1", "deleted1", "failing1")
+		hold(t, tracker, "waiting1")
+		makePremieresDue(t, storage)
+
+		summary, released, err := tracker.CheckDue(ctx, lookup(map[string]string{
+			"live1": BroadcastLive, "ended1": BroadcastNone, "waiting1": BroadcastUpcoming, "failing1": "error",
+		}), false)
+		require.NoError(t, err)
+		assert.Equal(t, 5, summary.Checked)
+		assert.Equal(t, 2, summary.Released)
+		assert.Equal(t, 1, summary.Dropped)
+		assert.Equal(t, 2, summary.Pending)
+		require.Len(t, released, 2)
+		assert.Equal(t, "live1", released[0].VideoID)
+		assert.Equal(t, BroadcastLive, released[0].LiveBroadcastContent)
+		assert.False(t, released[0].HeldAt.IsZero())
+
+		pending := readPendingPremieres(t, storage)
+		require.Len(t, pending, 2)
+		assert.Equal(t, "waiting1", pending[0].VideoID)
+		assert.Equal(t, "YouTube API returned status 500", pending[1].LastError)
+
+		summary, _, err = tracker.CheckDue(ctx, lookup(nil), false)
+		require.NoError(t, err)
+		assert.Zero(t, summary.Checked, "just checked")
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		tracker := NewPremiereTracker(storage)
+		require.NoError(t, tracker.Hold(ctx, newRetryTestEntry("stale1"),
+			&VideoBroadcast{LiveBroadcastContent: BroadcastUpcoming, ScheduledStartTime: time.Now().Add(-8 * 24 * time.Hour)}))
+
+		summary, released, err := tracker.CheckDue(ctx, lookup(map[string]string{"stale1": BroadcastUpcoming}), true)
+		require.NoError(t, err)
+		assert.Empty(t, released)
+		require.Len(t, summary.Results, 1)
+		assert.Equal(t, PremiereActionDropped, summary.Results[0].Action)
+		assert.Empty(t, readPendingPremieres(t, storage))
+	})
+}
+
+func TestPremiereEndpoints(t *testing.T) {
+	api := newPremiereAPIServer(t, PremiereHandlingDelay)
+	api.set(premiereTestVideo, BroadcastUpcoming)
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	require.Equal(t, http.StatusOK, postPremiereNotification(deps, premiereTestVideo).Code)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/premieres", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listing PendingPremieresResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing))
+	require.Len(t, listing.Pending, 1)
+	assert.Equal(t, premiereTestVideo, listing.Pending[0].VideoID)
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/premieres", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var summary PremiereCheckResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Zero(t, summary.Checked, "not due before its scheduled start")
+
+	api.set(premiereTestVideo, BroadcastLive)
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/premieres?all=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Released)
+	require.Len(t, summary.Results, 1)
+	assert.Contains(t, summary.Results[0].Message, "Successfully triggered workflow")
+	assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+
+	t.Setenv("PREMIERE_HANDLING", PremiereHandlingTrack)
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/premieres", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds the storage check made by /readyz
+const readinessTimeout = 5 * time.Second
+
+// Component and probe statuses
+const (
+	ProbeStatusOK       = "ok"
+	ProbeStatusError    = "error"
+	ProbeStatusReady    = "ready"
+	ProbeStatusNotReady = "not_ready"
+)
+
+// processStartTime is when the function instance started
+var processStartTime = time.Now()
+
+// StoragePinger is implemented by storage backends that can check they are reachable
+// without going through caches
+type StoragePinger interface {
+	Ping(ctx context.Context) error
+}
+
+// LivenessResponse is returned by GET /healthz
+type LivenessResponse struct {
+	Status        string  `json:"status"`
+	Timestamp     string  `json:"timestamp"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// ComponentCheck is the status of one dependency checked by /readyz
+type ComponentCheck struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ReadinessResponse is returned by GET /readyz
+type ReadinessResponse struct {
+	Status     string                    `json:"status"`
+	Timestamp  string                    `json:"timestamp"`
+	Components map[string]ComponentCheck `json:"components"`
+}
+
+// handleHealthz handles GET /healthz requests: the process is alive and serving
+func handleHealthz(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := deps.now()
+		writeJSONResponse(w, http.StatusOK, LivenessResponse{
+			Status:        ProbeStatusOK,
+			Timestamp:     now.Format(timeFormat()),
+			UptimeSeconds: now.Sub(processStartTime).Seconds(),
+		})
+	}
+}
+
+// handleReadyz handles GET /readyz requests using dependency injection. The function
+// is ready when storage is reachable and GITHUB_TOKEN and FUNCTION_URL are configured;
+// otherwise it responds 503 with the failing components.
+func handleReadyz(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		components := map[string]ComponentCheck{
+			"storage":      checkStorage(ctx, deps.StorageClient),
+			"github_token": checkConfigured(deps.GitHubClient != nil && deps.GitHubClient.IsConfigured(), "GITHUB_TOKEN not set"),
+			"function_url": checkConfigured(deps.config().FunctionURL != "", "FUNCTION_URL not set"),
+		}
+
+		response := ReadinessResponse{
+			Status:     ProbeStatusReady,
+			Timestamp:  deps.now().Format(timeFormat()),
+			Components: components,
+		}
+		statusCode := http.StatusOK
+		for _, check := range components {
+			if check.Status != ProbeStatusOK {
+				response.Status = ProbeStatusNotReady
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+		writeJSONResponse(w, statusCode, response)
+	}
+}
+
+// checkStorage checks the storage backend can be reached. Backends that cannot be
+// pinged are checked by loading the subscription state.
+func checkStorage(ctx context.Context, storage StorageService) ComponentCheck {
+	if storage == nil {
+		return ComponentCheck{Status: ProbeStatusError, Message: "storage not configured"}
+	}
+
+	var err error
+	if pinger, ok := storage.(StoragePinger); ok {
+		err = pinger.Ping(ctx)
+	} else {
+		_, err = storage.LoadSubscriptionState(ctx)
+	}
+	if err != nil {
+		return ComponentCheck{Status: ProbeStatusError, Message: err.Error()}
+	}
+	return ComponentCheck{Status: ProbeStatusOK}
+}
+
+// checkConfigured reports a setting that must be present
+func checkConfigured(configured bool, message string) ComponentCheck {
+	if !configured {
+		return ComponentCheck{Status: ProbeStatusError, Message: message}
+	}
+	return ComponentCheck{Status: ProbeStatusOK}
+}
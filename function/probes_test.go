@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthz(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).LoadError = errors.New("bucket unreachable")
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	// Liveness does not depend on storage
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response LivenessResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, ProbeStatusOK, response.Status)
+	assert.NotEmpty(t, response.Timestamp)
+}
+
+func TestReadyz(t *testing.T) {
+	readyz := func(deps *Dependencies) (*httptest.ResponseRecorder, ReadinessResponse) {
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+		var response ReadinessResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return rec, response
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		t.Setenv("FUNCTION_URL", "https://example.run.app")
+
+		rec, response := readyz(CreateTestDependencies())
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, ProbeStatusReady, response.Status)
+		for _, name := range []string{"storage", "github_token", "function_url"} {
+			assert.Equal(t, ProbeStatusOK, response.Components[name].Status, name)
+		}
+	})
+
+	t.Run("storage_unreachable", func(t *testing.T) {
+		t.Setenv("FUNCTION_URL", "https://example.run.app")
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).LoadError = errors.New("bucket unreachable")
+
+		rec, response := readyz(deps)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, ProbeStatusNotReady, response.Status)
+		assert.Equal(t, ProbeStatusError, response.Components["storage"].Status)
+		assert.Contains(t, response.Components["storage"].Message, "bucket unreachable")
+		assert.Equal(t, ProbeStatusOK, response.Components["github_token"].Status)
+	})
+
+	t.Run("missing_configuration", func(t *testing.T) {
+		t.Setenv("FUNCTION_URL", "")
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetConfigured(false)
+
+		rec, response := readyz(deps)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, ProbeStatusOK, response.Components["storage"].Status)
+		assert.Equal(t, "GITHUB_TOKEN not set", response.Components["github_token"].Message)
+		assert.Equal(t, "FUNCTION_URL not set", response.Components["function_url"].Message)
+	})
+}
+
+func TestCloudStorageService_Ping(t *testing.T) {
+	ops := NewMockCloudStorageOperations()
+	service := NewCloudStorageServiceWithOperations(ops, "test-bucket")
+
+	// A missing state object still means the bucket is reachable
+	assert.NoError(t, service.Ping(t.Context()))
+
+	ops.SetGetError(errors.New("permission denied"))
+	err := service.Ping(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test-bucket")
+}
@@ -1,17 +1,141 @@
 package webhook
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// defaultHubURL is the hub YouTube publishes to, used unless HUB_URL names another
+const defaultHubURL = "https://pubsubhubbub.appspot.com/subscribe"
+
+// defaultHubRequestTimeout bounds each hub request unless HUB_REQUEST_TIMEOUT_SECONDS is set
+const defaultHubRequestTimeout = 30 * time.Second
+
+// Transient hub errors are retried with exponential backoff unless HUB_MAX_ATTEMPTS,
+// HUB_RETRY_DELAY_MS or HUB_MAX_RETRY_DELAY_SECONDS say otherwise
+const (
+	defaultHubMaxAttempts   = 3
+	defaultHubRetryDelay    = 500 * time.Millisecond
+	defaultHubMaxRetryDelay = 30 * time.Second
+)
+
+// retryableHubStatus reports whether a hub response is worth retrying
+func retryableHubStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// maxHubResponseText bounds how much of a hub error response is kept
+const maxHubResponseText = 512
+
+// HubError is a non-2xx response from the hub
+type HubError struct {
+	StatusCode int
+	Body       string // The start of the hub's response text
+	Retries    int    // Transient errors retried before this response
+}
+
+func (e *HubError) Error() string {
+	message := fmt.Sprintf("PubSubHubbub hub returned status: %d", e.StatusCode)
+	if e.Retries > 0 {
+		message += fmt.Sprintf(" (after %d retries)", e.Retries)
+	}
+	if e.Body != "" {
+		message += ": " + e.Body
+	}
+	return message
+}
+
+// hubFailureStatus returns the status to answer a failed hub request with: the hub's
+// own 5xx, 503 while the hub's circuit breaker is open, 504 when the hub timed out, and
+// 502 when it could not be reached or rejected the request
+func hubFailureStatus(err error) int {
+	if errors.Is(err, ErrHubUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	var hubErr *HubError
+	if errors.As(err, &hubErr) {
+		if hubErr.StatusCode >= 500 {
+			return hubErr.StatusCode
+		}
+		return http.StatusBadGateway
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrDeadlineBudget) || errors.As(err, &netErr) && netErr.Timeout() {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// getHubURL returns the hub new subscriptions are made with, from HUB_URL
+func getHubURL() string {
+	if hubURL := os.Getenv("HUB_URL"); hubURL != "" {
+		return hubURL
+	}
+	return defaultHubURL
+}
+
+// validateHubURL checks that a hub URL is an absolute http or https URL
+func validateHubURL(hubURL string) error {
+	u, err := url.Parse(hubURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("hub_url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// hubURLKey is the context key of the hub withHubURL sends requests to
+type hubURLKey struct{}
+
+// withHubURL returns a context whose hub requests go to hubURL instead of the client's
+// hub. An empty hubURL leaves the client's hub in use.
+func withHubURL(ctx context.Context, hubURL string) context.Context {
+	if hubURL == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, hubURLKey{}, hubURL)
+}
+
+// callbackURLKey is the context key of the callback URL withCallbackURL subscribes with
+type callbackURLKey struct{}
+
+// withCallbackURL returns a context whose hub requests give callbackURL as hub.callback
+// instead of the client's callback. An empty callbackURL leaves the client's in use.
+func withCallbackURL(ctx context.Context, callbackURL string) context.Context {
+	if callbackURL == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, callbackURLKey{}, callbackURL)
+}
+
+// hubRetriesKey is the context key of the counter withHubRetries adds
+type hubRetriesKey struct{}
+
+// withHubRetries returns a context that counts the hub retries of the requests made
+// with it, and the counter
+func withHubRetries(ctx context.Context) (context.Context, *int) {
+	retries := new(int)
+	return context.WithValue(ctx, hubRetriesKey{}, retries), retries
+}
+
 // PubSubClient defines the interface for PubSubHubbub operations.
+// Requests are cancelled when their context is.
 type PubSubClient interface {
-	Subscribe(channelID string) error
-	Unsubscribe(channelID string) error
+	Subscribe(ctx context.Context, channelID, secret string, leaseSeconds int) error
+	Unsubscribe(ctx context.Context, channelID string) error
 }
 
 // HTTPPubSubClient implements PubSubClient using HTTP requests.
@@ -19,6 +143,11 @@ type HTTPPubSubClient struct {
 	hubURL      string
 	callbackURL string
 	client      *http.Client
+
+	// Retries of transient hub errors; a zero maxAttempts makes a single attempt
+	maxAttempts   int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
 }
 
 // NewHTTPPubSubClient creates a new HTTP-based PubSub client.
@@ -29,42 +158,143 @@ func NewHTTPPubSubClient() *HTTPPubSubClient {
 	}
 
 	return &HTTPPubSubClient{
-		hubURL:      "https://pubsubhubbub.appspot.com/subscribe",
+		hubURL:      getHubURL(),
 		callbackURL: callbackURL,
-		client:      &http.Client{Timeout: 30 * time.Second},
+		client:      &http.Client{Timeout: getSecondsEnv("HUB_REQUEST_TIMEOUT_SECONDS", defaultHubRequestTimeout)},
+
+		maxAttempts:   getIntEnv("HUB_MAX_ATTEMPTS", defaultHubMaxAttempts),
+		retryDelay:    time.Duration(getIntEnv("HUB_RETRY_DELAY_MS", int(defaultHubRetryDelay/time.Millisecond))) * time.Millisecond,
+		maxRetryDelay: getSecondsEnv("HUB_MAX_RETRY_DELAY_SECONDS", defaultHubMaxRetryDelay),
 	}
 }
 
-// Subscribe subscribes to a YouTube channel via PubSubHubbub.
-func (c *HTTPPubSubClient) Subscribe(channelID string) error {
-	return c.makePubSubHubbubRequest(channelID, "subscribe")
+// Subscribe subscribes to a YouTube channel's or playlist's feed via PubSubHubbub.
+// A non-empty secret is sent as hub.secret so the hub signs its notifications.
+// The hub is asked for a lease of leaseSeconds, or SUBSCRIPTION_LEASE_SECONDS when it is 0.
+func (c *HTTPPubSubClient) Subscribe(ctx context.Context, channelID, secret string, leaseSeconds int) error {
+	return c.makePubSubHubbubRequest(ctx, channelID, "subscribe", secret, leaseSeconds)
 }
 
-// Unsubscribe unsubscribes from a YouTube channel via PubSubHubbub.
-func (c *HTTPPubSubClient) Unsubscribe(channelID string) error {
-	return c.makePubSubHubbubRequest(channelID, "unsubscribe")
+// Unsubscribe unsubscribes from a YouTube channel's or playlist's feed via PubSubHubbub.
+func (c *HTTPPubSubClient) Unsubscribe(ctx context.Context, channelID string) error {
+	return c.makePubSubHubbubRequest(ctx, channelID, "unsubscribe", "", 0)
 }
 
-// makePubSubHubbubRequest makes a subscription/unsubscription request to the hub.
-func (c *HTTPPubSubClient) makePubSubHubbubRequest(channelID, mode string) error {
-	topicURL := fmt.Sprintf("https://www.youtube.com/feeds/videos.xml?channel_id=%s", channelID)
+// makePubSubHubbubRequest makes a subscription/unsubscription request to the hub, or
+// to the hub set on ctx with withHubURL.
+// 429, 502, 503 and 504 responses are retried with exponential backoff and jitter,
+// waiting at least as long as the hub's Retry-After asks. A Retry-After longer than
+// maxRetryDelay ends the retries. Requests and retries that would not finish within the
+// request's deadline budget fail with a BudgetError instead.
+func (c *HTTPPubSubClient) makePubSubHubbubRequest(ctx context.Context, channelID, mode, secret string, leaseSeconds int) error {
+	topicURL := topicURLFor(channelID)
+
+	callbackURL := c.callbackURL
+	if override, ok := ctx.Value(callbackURLKey{}).(string); ok {
+		callbackURL = override
+	}
 
 	data := url.Values{}
-	data.Set("hub.callback", c.callbackURL)
+	data.Set("hub.callback", callbackURL)
 	data.Set("hub.topic", topicURL)
 	data.Set("hub.mode", mode)
 	data.Set("hub.verify", "async")
-	data.Set("hub.lease_seconds", "86400")
+	if leaseSeconds <= 0 {
+		leaseSeconds = getLeaseSeconds()
+	}
+	data.Set("hub.lease_seconds", strconv.Itoa(leaseSeconds))
+	if secret != "" {
+		data.Set("hub.secret", secret)
+	}
+	body := data.Encode()
 
-	resp, err := c.client.PostForm(c.hubURL, data)
+	hubURL := c.hubURL
+	if override, ok := ctx.Value(hubURLKey{}).(string); ok {
+		hubURL = override
+	}
+
+	retries, _ := ctx.Value(hubRetriesKey{}).(*int)
+	delay := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		if _, err := budgetShare(ctx, callHub, 1); err != nil {
+			return err
+		}
+		statusCode, retryAfter, text, err := c.postToHub(ctx, hubURL, body)
+		if err != nil {
+			return err
+		}
+		if statusCode >= 200 && statusCode < 300 {
+			return nil
+		}
+
+		failure := &HubError{StatusCode: statusCode, Body: text, Retries: attempt - 1}
+		if !retryableHubStatus(statusCode) || attempt >= c.maxAttempts {
+			return failure
+		}
+		if retryAfter > c.maxRetryDelay {
+			return fmt.Errorf("%w; hub asked to retry after %s", failure, retryAfter)
+		}
+
+		// Full delay halved plus up to half again, so clients backing off together spread out
+		wait := delay/2 + rand.N(delay/2+1)
+		if wait < retryAfter {
+			wait = retryAfter
+		}
+		if err := budgetRetry(ctx, callHub, wait); err != nil {
+			return fmt.Errorf("%w; %w", failure, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w; retry cancelled: %v", failure, ctx.Err())
+		case <-time.After(wait):
+		}
+		if retries != nil {
+			*retries++
+		}
+		delay *= 2
+		if delay > c.maxRetryDelay {
+			delay = c.maxRetryDelay
+		}
+	}
+}
+
+// postToHub sends one request to the hub, returning its status, any Retry-After and,
+// for errors, the start of its response text
+func (c *HTTPPubSubClient) postToHub(ctx context.Context, hubURL, body string) (int, time.Duration, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", hubURL, strings.NewReader(body))
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to create PubSubHubbub request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to make PubSubHubbub request: %w", err)
+		return 0, 0, "", fmt.Errorf("failed to make PubSubHubbub request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	var text string
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("PubSubHubbub hub returned status: %d", resp.StatusCode)
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxHubResponseText))
+		text = strings.Join(strings.Fields(string(raw)), " ")
 	}
+	return resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After"), currentTime(ctx)), text, nil
+}
 
-	return nil
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+// Missing, invalid and past values are 0.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
 }
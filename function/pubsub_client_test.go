@@ -1,9 +1,13 @@
 package webhook
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -39,6 +43,20 @@ func TestNewHTTPPubSubClient(t *testing.T) {
 	}
 }
 
+func TestNewHTTPPubSubClient_Timeout(t *testing.T) {
+	defer os.Unsetenv("HUB_REQUEST_TIMEOUT_SECONDS")
+
+	os.Setenv("HUB_REQUEST_TIMEOUT_SECONDS", "10")
+	if timeout := NewHTTPPubSubClient().client.Timeout; timeout != 10*time.Second {
+		t.Errorf("Expected timeout 10s, got %v", timeout)
+	}
+
+	os.Setenv("HUB_REQUEST_TIMEOUT_SECONDS", "-1")
+	if timeout := NewHTTPPubSubClient().client.Timeout; timeout != defaultHubRequestTimeout {
+		t.Errorf("Expected default timeout for invalid value, got %v", timeout)
+	}
+}
+
 func TestNewHTTPPubSubClient_DefaultURL(t *testing.T) {
 	// Test without FUNCTION_URL set
 	os.Unsetenv("FUNCTION_URL")
@@ -97,7 +115,7 @@ func TestHTTPPubSubClient_Subscribe_Success(t *testing.T) {
 		client:      &http.Client{Timeout: 30 * time.Second},
 	}
 
-	err := client.Subscribe("UC123")
+	err := client.Subscribe(context.Background(), "UC123", "", 0)
 	if err != nil {
 		t.Errorf("Subscribe failed: %v", err)
 	}
@@ -128,7 +146,7 @@ func TestHTTPPubSubClient_Unsubscribe_Success(t *testing.T) {
 		client:      &http.Client{Timeout: 30 * time.Second},
 	}
 
-	err := client.Unsubscribe("UC456")
+	err := client.Unsubscribe(context.Background(), "UC456")
 	if err != nil {
 		t.Errorf("Unsubscribe failed: %v", err)
 	}
@@ -147,7 +165,7 @@ func TestHTTPPubSubClient_Subscribe_HTTPError(t *testing.T) {
 		client:      &http.Client{Timeout: 30 * time.Second},
 	}
 
-	err := client.Subscribe("UC123")
+	err := client.Subscribe(context.Background(), "UC123", "", 0)
 	if err == nil {
 		t.Error("Expected error for HTTP 400 response")
 	}
@@ -165,7 +183,7 @@ func TestHTTPPubSubClient_Subscribe_NetworkError(t *testing.T) {
 		client:      &http.Client{Timeout: 1 * time.Second},
 	}
 
-	err := client.Subscribe("UC123")
+	err := client.Subscribe(context.Background(), "UC123", "", 0)
 	if err == nil {
 		t.Error("Expected network error")
 	}
@@ -175,6 +193,33 @@ func TestHTTPPubSubClient_Subscribe_NetworkError(t *testing.T) {
 	}
 }
 
+func TestHTTPPubSubClient_Subscribe_ContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // Don't answer until the test ends; the caller's deadline must end the request
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &HTTPPubSubClient{
+		hubURL:      server.URL,
+		callbackURL: "https://test-callback.com",
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.Subscribe(ctx, "UC123", "", 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Request was not cancelled with its context, took %v", elapsed)
+	}
+}
+
 func TestHTTPPubSubClient_Unsubscribe_HTTPError(t *testing.T) {
 	// Create a test server that returns an error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -188,7 +233,7 @@ func TestHTTPPubSubClient_Unsubscribe_HTTPError(t *testing.T) {
 		client:      &http.Client{Timeout: 30 * time.Second},
 	}
 
-	err := client.Unsubscribe("UC456")
+	err := client.Unsubscribe(context.Background(), "UC456")
 	if err == nil {
 		t.Error("Expected error for HTTP 500 response")
 	}
@@ -225,7 +270,7 @@ func TestHTTPPubSubClient_makePubSubHubbubRequest_StatusCodes(t *testing.T) {
 				client:      &http.Client{Timeout: 30 * time.Second},
 			}
 
-			err := client.makePubSubHubbubRequest("UC123", "subscribe")
+			err := client.makePubSubHubbubRequest(context.Background(), "UC123", "subscribe", "", 0)
 
 			if tc.expectError && err == nil {
 				t.Errorf("Expected error for status code %d", tc.statusCode)
@@ -274,7 +319,7 @@ func TestHTTPPubSubClient_RequestFormat(t *testing.T) {
 	}
 
 	channelID := "UCaBcd123"
-	err := client.Subscribe(channelID)
+	err := client.Subscribe(context.Background(), channelID, "", 0)
 	if err != nil {
 		t.Fatalf("Subscribe failed: %v", err)
 	}
@@ -299,4 +344,372 @@ func TestHTTPPubSubClient_RequestFormat(t *testing.T) {
 			t.Errorf("Field %s: expected %s, got %s", field, expectedValue, actualValue)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestHTTPPubSubClient_Subscribe_SendsSecret(t *testing.T) {
+	var secrets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		secrets = append(secrets, r.FormValue("hub.secret"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := &HTTPPubSubClient{
+		hubURL:      server.URL,
+		callbackURL: "https://test-callback.com",
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if err := client.Subscribe(context.Background(), "UC123", "s3cret", 0); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := client.Unsubscribe(context.Background(), "UC123"); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+
+	if len(secrets) != 2 || secrets[0] != "s3cret" || secrets[1] != "" {
+		t.Errorf("Expected hub.secret only on subscribe, got %q", secrets)
+	}
+}
+
+func TestHTTPPubSubClient_Subscribe_LeaseSeconds(t *testing.T) {
+	t.Setenv("SUBSCRIPTION_LEASE_SECONDS", "43200")
+
+	var leases []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		leases = append(leases, r.FormValue("hub.lease_seconds"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := &HTTPPubSubClient{
+		hubURL:      server.URL,
+		callbackURL: "https://test-callback.com",
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, lease := range []int{432000, 0} {
+		if err := client.Subscribe(context.Background(), "UC123", "", lease); err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+	}
+
+	if len(leases) != 2 || leases[0] != "432000" || leases[1] != "43200" {
+		t.Errorf("Expected the requested lease, then SUBSCRIPTION_LEASE_SECONDS, got %q", leases)
+	}
+}
+
+// newFlakyHub serves the given statuses in turn, then 202 Accepted, counting requests
+func newFlakyHub(statuses []int, retryAfter string, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if *requests <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[*requests-1])
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+}
+
+func TestHTTPPubSubClient_RetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		requests   int
+		retries    int
+		wantErr    string
+	}{
+		{"recovers_after_503", []int{503, 502}, "", 3, 2, ""},
+		{"honors_retry_after", []int{429}, "0", 2, 1, ""},
+		{"gives_up_after_max_attempts", []int{503, 503, 503}, "", 3, 2, "status: 503 (after 2 retries)"},
+		{"does_not_retry_400", []int{400}, "", 1, 0, "status: 400"},
+		{"retry_after_too_long", []int{429}, "120", 1, 0, "hub asked to retry after 2m0s"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := newFlakyHub(tc.statuses, tc.retryAfter, &requests)
+			defer server.Close()
+
+			client := &HTTPPubSubClient{
+				hubURL:        server.URL,
+				callbackURL:   "https://test-callback.com",
+				client:        server.Client(),
+				maxAttempts:   3,
+				retryDelay:    time.Millisecond,
+				maxRetryDelay: 30 * time.Second,
+			}
+
+			ctx, retries := withHubRetries(context.Background())
+			err := client.Subscribe(ctx, "UC123", "", 0)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("Subscribe failed: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if requests != tc.requests {
+				t.Errorf("Expected %d hub requests, got %d", tc.requests, requests)
+			}
+			if *retries != tc.retries {
+				t.Errorf("Expected %d retries counted, got %d", tc.retries, *retries)
+			}
+		})
+	}
+}
+
+func TestHTTPPubSubClient_RetryCancelled(t *testing.T) {
+	requests := 0
+	server := newFlakyHub([]int{503, 503}, "", &requests)
+	defer server.Close()
+
+	client := &HTTPPubSubClient{
+		hubURL:        server.URL,
+		callbackURL:   "https://test-callback.com",
+		client:        server.Client(),
+		maxAttempts:   3,
+		retryDelay:    time.Minute,
+		maxRetryDelay: time.Minute,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.Subscribe(ctx, "UC123", "", 0)
+	if err == nil || !strings.Contains(err.Error(), "retry cancelled") {
+		t.Errorf("Expected a cancelled retry, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 hub request, got %d", requests)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+
+	for _, tc := range tests {
+		if got := parseRetryAfter(tc.value, now); got != tc.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestSubscribe_ReportsHubRetries(t *testing.T) {
+	requests := 0
+	server := newFlakyHub([]int{503}, "", &requests)
+	defer server.Close()
+
+	deps := CreateTestDependencies()
+	deps.PubSubClient = &HTTPPubSubClient{
+		hubURL:        server.URL,
+		callbackURL:   "https://test-callback.com",
+		client:        server.Client(),
+		maxAttempts:   3,
+		retryDelay:    time.Millisecond,
+		maxRetryDelay: time.Second,
+	}
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscribe?channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"hub_retries":1`) {
+		t.Errorf("Expected hub_retries in the response, got %s", rec.Body.String())
+	}
+}
+
+func TestHubFailureStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"hub_5xx_passed_through", &HubError{StatusCode: 503}, http.StatusServiceUnavailable},
+		{"hub_4xx", &HubError{StatusCode: 400}, http.StatusBadGateway},
+		{"wrapped_hub_error", fmt.Errorf("%w; retry cancelled", &HubError{StatusCode: 500}), http.StatusInternalServerError},
+		{"deadline", fmt.Errorf("failed to make PubSubHubbub request: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"connection_refused", errors.New("failed to make PubSubHubbub request: connection refused"), http.StatusBadGateway},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hubFailureStatus(tc.err); got != tc.want {
+				t.Errorf("hubFailureStatus(%v) = %d, want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubscribe_HubFailureResponses(t *testing.T) {
+	subscribe := func(t *testing.T, hub *HTTPPubSubClient) (*httptest.ResponseRecorder, APIResponse) {
+		deps := CreateTestDependencies()
+		deps.PubSubClient = hub
+		SetDependencies(deps)
+		t.Cleanup(func() { SetDependencies(nil) })
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscribe?channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw", nil))
+		var response APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return rec, response
+	}
+
+	t.Run("hub_5xx", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "Internal\n  error ")
+		}))
+		defer server.Close()
+
+		rec, response := subscribe(t, &HTTPPubSubClient{hubURL: server.URL, client: server.Client()})
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500, got %d", rec.Code)
+		}
+		if response.Code != ErrCodeHubError || response.HubStatus != 500 || response.HubResponse != "Internal error" {
+			t.Errorf("Expected the hub's status and text in the error, got %+v", response)
+		}
+	})
+
+	t.Run("hub_rejects_request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Invalid value for hub.callback", http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		rec, response := subscribe(t, &HTTPPubSubClient{hubURL: server.URL, client: server.Client()})
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("Expected 502, got %d", rec.Code)
+		}
+		if response.HubStatus != 400 || response.HubResponse != "Invalid value for hub.callback" {
+			t.Errorf("Expected the hub's status and text in the error, got %+v", response)
+		}
+	})
+
+	t.Run("hub_timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		rec, response := subscribe(t, &HTTPPubSubClient{hubURL: server.URL, client: &http.Client{Timeout: 20 * time.Millisecond}})
+		if rec.Code != http.StatusGatewayTimeout || response.Code != ErrCodeGatewayTimeout {
+			t.Errorf("Expected 504 gateway_timeout, got %d %q", rec.Code, response.Code)
+		}
+	})
+
+	t.Run("hub_unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		rec, response := subscribe(t, &HTTPPubSubClient{hubURL: server.URL, client: &http.Client{Timeout: time.Second}})
+		if rec.Code != http.StatusBadGateway || response.Code != ErrCodeBadGateway || response.HubStatus != 0 {
+			t.Errorf("Expected 502 bad_gateway without a hub status, got %d %+v", rec.Code, response)
+		}
+	})
+}
+
+func TestSubscribe_HubURL(t *testing.T) {
+	// newHub counts the requests a hub receives, by hub.mode
+	newHub := func() (*httptest.Server, map[string]int) {
+		modes := map[string]int{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			modes[r.FormValue("hub.mode")]++
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		return server, modes
+	}
+	defaultHub, defaultModes := newHub()
+	defer defaultHub.Close()
+	selfHosted, selfHostedModes := newHub()
+	defer selfHosted.Close()
+
+	t.Setenv("HUB_URL", defaultHub.URL)
+	deps := CreateTestDependencies()
+	deps.PubSubClient = NewHTTPPubSubClient()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+	storage := deps.StorageClient.(*MockStorageClient)
+
+	const channelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	const otherChannelID = "UCBJycsmduvYEL83R_U4JriQ"
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscribe?channel_id="+channelID+"&hub_url="+url.QueryEscape(selfHosted.URL), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscribe?channel_id="+otherChannelID, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	state := storage.GetState()
+	if got := state.Subscriptions[channelID].HubURL; got != selfHosted.URL {
+		t.Errorf("Expected the self-hosted hub to be recorded, got %q", got)
+	}
+	if got := state.Subscriptions[otherChannelID].HubURL; got != defaultHub.URL {
+		t.Errorf("Expected HUB_URL to be recorded, got %q", got)
+	}
+	if selfHostedModes["subscribe"] != 1 || defaultModes["subscribe"] != 1 {
+		t.Fatalf("Expected one subscribe on each hub, got %v and %v", selfHostedModes, defaultModes)
+	}
+
+	// Renewing and unsubscribing go to the hub the subscription was made with
+	for _, sub := range state.Subscriptions {
+		sub.Status = StatusActive
+		sub.ExpiresAt = time.Now().Add(time.Hour)
+	}
+	storage.SetState(state)
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/renew", strings.NewReader(`{"force":true}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("DELETE", "/unsubscribe?channel_id="+channelID, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if selfHostedModes["subscribe"] != 2 || selfHostedModes["unsubscribe"] != 1 {
+		t.Errorf("Expected a renewal and an unsubscribe on the self-hosted hub, got %v", selfHostedModes)
+	}
+	if defaultModes["subscribe"] != 2 || defaultModes["unsubscribe"] != 0 {
+		t.Errorf("Expected only a renewal on the default hub, got %v", defaultModes)
+	}
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscribe?channel_id="+channelID+"&hub_url=ftp%3A%2F%2Fhub.example.com", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-http hub, got %d", rec.Code)
+	}
+}
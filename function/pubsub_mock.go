@@ -1,6 +1,9 @@
 package webhook
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // MockPubSubClient implements PubSubClient for testing.
 type MockPubSubClient struct {
@@ -11,6 +14,8 @@ type MockPubSubClient struct {
 	unsubscribeCount int
 	lastChannelID    string
 	lastMode         string
+	lastSecret       string
+	lastLeaseSeconds int
 	subscriptions    map[string]bool
 }
 
@@ -22,13 +27,15 @@ func NewMockPubSubClient() *MockPubSubClient {
 }
 
 // Subscribe simulates subscribing to a channel.
-func (m *MockPubSubClient) Subscribe(channelID string) error {
+func (m *MockPubSubClient) Subscribe(ctx context.Context, channelID, secret string, leaseSeconds int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.subscribeCount++
 	m.lastChannelID = channelID
 	m.lastMode = "subscribe"
+	m.lastSecret = secret
+	m.lastLeaseSeconds = leaseSeconds
 
 	if m.subscribeError != nil {
 		return m.subscribeError
@@ -39,7 +46,7 @@ func (m *MockPubSubClient) Subscribe(channelID string) error {
 }
 
 // Unsubscribe simulates unsubscribing from a channel.
-func (m *MockPubSubClient) Unsubscribe(channelID string) error {
+func (m *MockPubSubClient) Unsubscribe(ctx context.Context, channelID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -97,6 +104,20 @@ func (m *MockPubSubClient) GetLastMode() string {
 	return m.lastMode
 }
 
+// GetLastSecret returns the hub secret passed to the last subscribe call.
+func (m *MockPubSubClient) GetLastSecret() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastSecret
+}
+
+// GetLastLeaseSeconds returns the lease requested by the last subscribe call.
+func (m *MockPubSubClient) GetLastLeaseSeconds() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastLeaseSeconds
+}
+
 // IsSubscribed returns whether a channel is currently subscribed.
 func (m *MockPubSubClient) IsSubscribed(channelID string) bool {
 	m.mu.RLock()
@@ -115,5 +136,7 @@ func (m *MockPubSubClient) Reset() {
 	m.unsubscribeCount = 0
 	m.lastChannelID = ""
 	m.lastMode = ""
+	m.lastSecret = ""
+	m.lastLeaseSeconds = 0
 	m.subscriptions = make(map[string]bool)
 }
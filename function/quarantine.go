@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Backoff between renewal attempts of a subscription: the delay doubles after every
+// failed attempt, up to the maximum
+const (
+	renewalRetryBaseDelay = 30 * time.Minute
+	renewalRetryMaxDelay  = 12 * time.Hour
+)
+
+// renewalBackoff returns the delay before retrying a subscription whose renewal has
+// failed the given number of times
+func renewalBackoff(attempts int) time.Duration {
+	delay := renewalRetryBaseDelay
+	for i := 1; i < attempts && delay < renewalRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > renewalRetryMaxDelay {
+		return renewalRetryMaxDelay
+	}
+	return delay
+}
+
+// recordRenewalFailure counts a failed renewal and schedules the next attempt, or
+// quarantines the subscription once it has failed maxAttempts times
+func recordRenewalFailure(sub *Subscription, err error, now time.Time, maxAttempts int) {
+	sub.RenewalAttempts++
+	recordRenewalOutcome(sub, err)
+
+	if sub.RenewalAttempts >= maxAttempts {
+		sub.Status = StatusQuarantined
+		sub.QuarantinedAt = now
+		sub.NextRenewalAt = time.Time{}
+		return
+	}
+	sub.NextRenewalAt = now.Add(renewalBackoff(sub.RenewalAttempts))
+}
+
+// backingOff reports whether a failed renewal of sub is still waiting out its backoff
+func backingOff(sub *Subscription, now time.Time) bool {
+	return !sub.NextRenewalAt.IsZero() && now.Before(sub.NextRenewalAt)
+}
+
+// quarantinedChannels returns the quarantined channels in the state, sorted
+func quarantinedChannels(state *SubscriptionState) []string {
+	channels := []string{}
+	for channelID, sub := range state.Subscriptions {
+		if sub.Status == StatusQuarantined {
+			channels = append(channels, channelID)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// handleReactivateSubscription handles POST /subscriptions/{channel_id}/reactivate requests
+// using dependency injection. The renewal backoff is reset and a new subscribe request is
+// sent to the hub; the subscription stays quarantined if the hub rejects it.
+func handleReactivateSubscription(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := loadChannelSubscription(w, r, deps, channelID)
+		if !ok {
+			return
+		}
+		if sub.Status != StatusQuarantined {
+			writeErrorResponse(w, http.StatusConflict, channelID,
+				fmt.Sprintf("Subscription is %s, not quarantined", sub.Status))
+			return
+		}
+
+		err := resubscribe(r.Context(), deps, channelID, sub)
+		deps.Metrics.RecordRenewal("reactivate", err)
+		if err != nil {
+			writeHubError(w, channelID, "PubSubHubbub subscription failed", err)
+			return
+		}
+
+		// The new lease is recorded when the hub verifies the subscription
+		markPending(sub, deps.now())
+		sub.RenewalAttempts = 0
+		sub.NextRenewalAt = time.Time{}
+		sub.QuarantinedAt = time.Time{}
+		recordRenewalOutcome(sub, nil)
+
+		if err := deps.StorageClient.SaveSubscription(r.Context(), sub); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to save subscription state: %v", err))
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, APIResponse{
+			Status:    "success",
+			ChannelID: channelID,
+			Message:   "Subscription reactivated; pending hub verification",
+		})
+	}
+}
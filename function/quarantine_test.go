@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewalBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Minute, renewalBackoff(1))
+	assert.Equal(t, time.Hour, renewalBackoff(2))
+	assert.Equal(t, 2*time.Hour, renewalBackoff(3))
+	assert.Equal(t, renewalRetryMaxDelay, renewalBackoff(20))
+}
+
+func TestRecordRenewalFailure(t *testing.T) {
+	now := time.Now()
+	sub := &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive}
+
+	recordRenewalFailure(sub, errors.New("hub unavailable"), now, 2)
+	assert.Equal(t, StatusActive, sub.Status)
+	assert.Equal(t, 1, sub.RenewalAttempts)
+	assert.Equal(t, now.Add(30*time.Minute), sub.NextRenewalAt)
+	assert.Equal(t, RenewalStatusFailed, sub.LastRenewalStatus)
+
+	recordRenewalFailure(sub, errors.New("hub unavailable"), now, 2)
+	assert.Equal(t, StatusQuarantined, sub.Status)
+	assert.Equal(t, now, sub.QuarantinedAt)
+	assert.True(t, sub.NextRenewalAt.IsZero())
+}
+
+func TestHandleRenewSubscriptions_BackoffAndQuarantine(t *testing.T) {
+	t.Setenv("RENEWAL_THRESHOLD_HOURS", "12")
+	t.Setenv("MAX_RENEWAL_ATTEMPTS", "2")
+
+	deps := CreateTestDependencies()
+	channelIDs := seedExpiringSubscriptions(t, deps, 1)
+	channelID := channelIDs[0]
+	pubsub := deps.PubSubClient.(*MockPubSubClient)
+	pubsub.SetSubscribeError(errors.New("hub unavailable"))
+
+	renew := func() RenewalSummaryResponse {
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response
+	}
+	subscription := func() *Subscription {
+		sub, err := deps.StorageClient.GetSubscription(t.Context(), channelID)
+		require.NoError(t, err)
+		return sub
+	}
+
+	// First failure schedules a retry
+	response := renew()
+	require.Len(t, response.Results, 1)
+	assert.NotEmpty(t, response.Results[0].NextAttemptAt)
+	assert.False(t, response.Results[0].Quarantined)
+	assert.Empty(t, response.Quarantined)
+
+	// The next run waits out the backoff instead of hitting the hub again
+	response = renew()
+	assert.Empty(t, response.Results)
+	assert.Equal(t, 1, response.RenewalsBackingOff)
+	assert.Equal(t, 1, pubsub.GetSubscribeCount())
+
+	// Once the backoff has passed the retry fails again and the subscription is quarantined
+	sub := subscription()
+	sub.NextRenewalAt = time.Now().Add(-time.Minute)
+	require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), sub))
+
+	response = renew()
+	require.Len(t, response.Results, 1)
+	assert.True(t, response.Results[0].Quarantined)
+	assert.Equal(t, []string{channelID}, response.Quarantined)
+	assert.Equal(t, StatusQuarantined, subscription().Status)
+
+	// Quarantined subscriptions are reported but never retried
+	response = renew()
+	assert.Empty(t, response.Results)
+	assert.Equal(t, 0, response.RenewalsCandidates)
+	assert.Equal(t, []string{channelID}, response.Quarantined)
+	assert.Equal(t, 2, pubsub.GetSubscribeCount())
+}
+
+func TestHandleReactivateSubscription(t *testing.T) {
+	const channelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	quarantined := func() *Subscription {
+		return &Subscription{
+			ChannelID:       channelID,
+			Status:          StatusQuarantined,
+			ExpiresAt:       time.Now().Add(time.Hour),
+			RenewalAttempts: 3,
+			QuarantinedAt:   time.Now().Add(-time.Hour),
+			Secret:          "secret",
+		}
+	}
+	reactivate := func(deps *Dependencies, channelID string) *httptest.ResponseRecorder {
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, httptest.NewRequest("POST", "/subscriptions/"+channelID+"/reactivate", nil))
+		return rec
+	}
+
+	t.Run("reactivates_quarantined_subscription", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), quarantined()))
+
+		rec := reactivate(deps, channelID)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		sub, err := deps.StorageClient.GetSubscription(t.Context(), channelID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusPending, sub.Status)
+		assert.Equal(t, 0, sub.RenewalAttempts)
+		assert.True(t, sub.QuarantinedAt.IsZero())
+		assert.Equal(t, 1, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("hub_failure_keeps_quarantine", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), quarantined()))
+		deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(errors.New("hub unavailable"))
+
+		rec := reactivate(deps, channelID)
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+
+		sub, err := deps.StorageClient.GetSubscription(t.Context(), channelID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusQuarantined, sub.Status)
+	})
+
+	t.Run("not_quarantined", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		sub := quarantined()
+		sub.Status = StatusActive
+		require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), sub))
+
+		assert.Equal(t, http.StatusConflict, reactivate(deps, channelID).Code)
+	})
+
+	t.Run("not_found_and_invalid", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		assert.Equal(t, http.StatusNotFound, reactivate(deps, channelID).Code)
+		assert.Equal(t, http.StatusBadRequest, reactivate(deps, "bogus").Code)
+	})
+}
+
+func TestSweep_KeepsQuarantine(t *testing.T) {
+	deps := CreateTestDependencies()
+	sub := &Subscription{
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Status:    StatusQuarantined,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+	require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), sub))
+
+	rec := httptest.NewRecorder()
+	handleSweepSubscriptions(deps)(rec, httptest.NewRequest("POST", "/sweep", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	stored, err := deps.StorageClient.GetSubscription(t.Context(), sub.ChannelID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusQuarantined, stored.Status)
+	assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+}
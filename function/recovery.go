@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Why the hub stopped delivering a subscription that is being recovered
+const (
+	RecoveryReasonDenied     = "denied"     // The hub denied or cancelled the subscription
+	RecoveryReasonUnverified = "unverified" // The hub did not verify a subscribe request in time
+	RecoveryReasonLapsed     = "lapsed"     // The lease ended without a verified renewal
+)
+
+// Recovery steps reported by /renew and /sweep
+const (
+	RecoveryActionResubscribed = "resubscribed" // Subscribe request sent; pending hub verification
+	RecoveryActionFailed       = "failed"       // The hub refused the request; retried after the backoff
+	RecoveryActionGaveUp       = "gave_up"      // RECOVERY_MAX_ATTEMPTS made; quarantined until reactivated
+)
+
+// RecoveryRecord tracks the automatic re-subscription of a subscription the hub stopped
+// delivering. It is kept after the recovery ends, successfully or not, until another begins.
+type RecoveryRecord struct {
+	Reason        string    `json:"reason"`
+	StartedAt     time.Time `json:"started_at"`
+	Attempts      int       `json:"attempts"`
+	LastAttemptAt time.Time `json:"last_attempt_at,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"` // Zero while an attempt awaits the hub's verification
+	RecoveredAt   time.Time `json:"recovered_at,omitempty"`    // When the hub verified a re-subscribe
+	GaveUpAt      time.Time `json:"gave_up_at,omitempty"`      // When the subscription was quarantined instead
+}
+
+// RecoveryResult describes one recovery step taken for a subscription
+type RecoveryResult struct {
+	ChannelID     string `json:"channel_id"`
+	Reason        string `json:"reason"`
+	Action        string `json:"action"`
+	Attempt       int    `json:"attempt"`
+	Message       string `json:"message"`
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+}
+
+// getRecoveryMaxAttempts returns how many times a subscription the hub stopped delivering
+// is re-subscribed before it is quarantined, from RECOVERY_MAX_ATTEMPTS (default 5; 0
+// disables automatic recovery)
+func getRecoveryMaxAttempts() int {
+	return envConfig().RecoveryMaxAttempts
+}
+
+// recovering reports whether sub is being re-subscribed automatically
+func recovering(sub *Subscription) bool {
+	return sub.Recovery != nil && sub.Recovery.RecoveredAt.IsZero() && sub.Recovery.GaveUpAt.IsZero()
+}
+
+// startRecovery records that the hub stopped delivering sub and schedules a re-subscribe.
+// A recovery already under way keeps its attempts and backs off from its last one. When
+// maxAttempts have been made sub is quarantined instead and false is returned.
+func startRecovery(sub *Subscription, reason, detail string, now time.Time, maxAttempts int) bool {
+	if !recovering(sub) {
+		sub.Recovery = &RecoveryRecord{StartedAt: now}
+	}
+	rec := sub.Recovery
+	rec.Reason = reason
+	rec.LastError = detail
+	sub.Status = StatusExpired
+	sub.QuarantinedAt = time.Time{}
+	if sub.ExpiresAt.IsZero() {
+		sub.ExpiresAt = now
+	}
+
+	if rec.Attempts >= maxAttempts {
+		giveUpRecovery(sub, now)
+		return false
+	}
+	rec.NextAttemptAt = now
+	if rec.Attempts > 0 {
+		rec.NextAttemptAt = rec.LastAttemptAt.Add(renewalBackoff(rec.Attempts))
+	}
+	return true
+}
+
+// giveUpRecovery quarantines sub after its recovery ran out of attempts
+func giveUpRecovery(sub *Subscription, now time.Time) {
+	rec := sub.Recovery
+	rec.GaveUpAt = now
+	rec.NextAttemptAt = time.Time{}
+	sub.Status = StatusQuarantined
+	sub.QuarantinedAt = now
+	sub.NextRenewalAt = time.Time{}
+	recordRenewalOutcome(sub, fmt.Errorf("recovery gave up after %d attempts: %s", rec.Attempts, rec.LastError))
+}
+
+// completeRecovery records that the hub verified a re-subscribe of sub
+func completeRecovery(sub *Subscription, now time.Time) {
+	if !recovering(sub) {
+		return
+	}
+	sub.Recovery.RecoveredAt = now
+	sub.Recovery.NextAttemptAt = time.Time{}
+	sub.Recovery.LastError = ""
+	fmt.Printf("Subscription to channel %s recovered after %d attempt(s)\n", sub.ChannelID, sub.Recovery.Attempts)
+}
+
+// recoverSubscriptions finds the subscriptions the hub stopped delivering, because it
+// never verified a subscribe request or let the lease lapse within SWEEP_GRACE_HOURS
+// without a verified renewal, and re-subscribes those whose backoff has passed. Denied
+// subscriptions are put into recovery when the hub's denial arrives. The steps taken
+// are returned sorted by channel.
+func recoverSubscriptions(ctx context.Context, deps *Dependencies, state *SubscriptionState, now time.Time) []RecoveryResult {
+	maxAttempts := deps.config().RecoveryMaxAttempts
+	if maxAttempts == 0 {
+		return nil
+	}
+	grace := deps.config().SweepGracePeriod
+
+	channelIDs := make([]string, 0, len(state.Subscriptions))
+	for channelID := range state.Subscriptions {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	var results []RecoveryResult
+	for _, channelID := range channelIDs {
+		sub := state.Subscriptions[channelID]
+		switch {
+		case verificationOverdue(sub, now):
+			detail := fmt.Sprintf("hub did not verify the subscription within %s", verificationTimeout)
+			if !startRecovery(sub, RecoveryReasonUnverified, detail, now, maxAttempts) {
+				results = append(results, RecoveryResult{
+					ChannelID: channelID,
+					Reason:    sub.Recovery.Reason,
+					Action:    RecoveryActionGaveUp,
+					Attempt:   sub.Recovery.Attempts,
+					Message:   fmt.Sprintf("The %s; gave up after %d attempts and quarantined", detail, sub.Recovery.Attempts),
+				})
+				continue
+			}
+		case (sub.Status == StatusActive || sub.Status == StatusVerified) && !sub.ExpiresAt.IsZero() &&
+			sub.ExpiresAt.Before(now) && now.Sub(sub.ExpiresAt) <= grace:
+			startRecovery(sub, RecoveryReasonLapsed, "lease lapsed without a verified renewal", now, maxAttempts)
+		}
+
+		if recovering(sub) && sub.Status != StatusPending && !now.Before(sub.Recovery.NextAttemptAt) {
+			results = append(results, attemptRecovery(ctx, deps, channelID, sub, now, maxAttempts))
+		}
+	}
+	return results
+}
+
+// attemptRecovery sends a new subscribe request for sub with its hub, secret and lease.
+// A refused request is retried after the renewal backoff, until maxAttempts.
+func attemptRecovery(ctx context.Context, deps *Dependencies, channelID string, sub *Subscription,
+	now time.Time, maxAttempts int) RecoveryResult {
+	rec := sub.Recovery
+	rec.Attempts++
+	rec.LastAttemptAt = now
+	rec.NextAttemptAt = time.Time{}
+
+	err := resubscribe(ctx, deps, channelID, sub)
+	deps.Metrics.RecordRenewal("recovery", err)
+	recordRenewalOutcome(sub, err)
+
+	result := RecoveryResult{ChannelID: channelID, Reason: rec.Reason, Attempt: rec.Attempts}
+	if err != nil {
+		rec.LastError = err.Error()
+		if rec.Attempts >= maxAttempts {
+			giveUpRecovery(sub, now)
+			result.Action = RecoveryActionGaveUp
+			result.Message = fmt.Sprintf("Re-subscribe failed: %v; gave up after %d attempts and quarantined", err, rec.Attempts)
+			return result
+		}
+		rec.NextAttemptAt = now.Add(renewalBackoff(rec.Attempts))
+		result.Action = RecoveryActionFailed
+		result.Message = fmt.Sprintf("Re-subscribe failed: %v", err)
+		result.NextAttemptAt = rec.NextAttemptAt.Format(timeFormat())
+		return result
+	}
+
+	// The recovery completes when the hub verifies the re-subscribe
+	rec.LastError = ""
+	markPending(sub, now)
+	result.Action = RecoveryActionResubscribed
+	result.Message = "Re-subscribe requested; pending hub verification"
+	return result
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const recoveryTestChannel = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+// newRecoveryTestDeps returns test dependencies holding sub
+func newRecoveryTestDeps(sub *Subscription) *Dependencies {
+	deps := CreateTestDependencies()
+	deps.Metrics = NewMetrics()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(sub))
+	return deps
+}
+
+// runRecovery runs a recovery pass over the stored state at now and saves it
+func runRecovery(t *testing.T, deps *Dependencies, now time.Time) ([]RecoveryResult, *Subscription) {
+	t.Helper()
+	state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+	require.NoError(t, err)
+	results := recoverSubscriptions(t.Context(), deps, state, now)
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(t.Context(), state))
+	return results, state.Subscriptions[recoveryTestChannel]
+}
+
+func TestGetRecoveryMaxAttempts(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int
+	}{
+		{"", 5},
+		{"3", 3},
+		{"0", 0},
+		{"-1", 5},
+		{"abc", 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("RECOVERY_MAX_ATTEMPTS", tc.value)
+			assert.Equal(t, tc.expected, getRecoveryMaxAttempts())
+		})
+	}
+}
+
+func TestRecovery_DeniedSubscription(t *testing.T) {
+	deps := newRecoveryTestDeps(&Subscription{
+		ChannelID: recoveryTestChannel,
+		TopicURL:  "https://www.youtube.com/feeds/videos.xml?channel_id=" + recoveryTestChannel,
+		Status:    StatusActive,
+		ExpiresAt: time.Now().Add(48 * time.Hour),
+		Secret:    "secret",
+	})
+
+	query := url.Values{}
+	query.Set("hub.mode", "denied")
+	query.Set("hub.topic", "https://www.youtube.com/feeds/videos.xml?channel_id="+recoveryTestChannel)
+	query.Set("hub.reason", "lease cancelled")
+	rec := httptest.NewRecorder()
+	handleVerificationChallenge(deps)(rec, httptest.NewRequest("GET", "/?"+query.Encode(), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[recoveryTestChannel]
+	assert.Equal(t, StatusExpired, sub.Status, "not quarantined while it is recovered")
+	assert.True(t, sub.QuarantinedAt.IsZero())
+	require.NotNil(t, sub.Recovery)
+	assert.Equal(t, RecoveryReasonDenied, sub.Recovery.Reason)
+	assert.Equal(t, "hub denied the subscription: lease cancelled", sub.Recovery.LastError)
+	assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricHubCallbacks, "mode", "denied", "result", "recovering"))
+
+	// The next scheduled run re-subscribes it
+	renew := httptest.NewRecorder()
+	handleRenewSubscriptions(deps)(renew, httptest.NewRequest("POST", "/renew", nil))
+	require.Equal(t, http.StatusOK, renew.Code)
+	var response RenewalSummaryResponse
+	require.NoError(t, json.Unmarshal(renew.Body.Bytes(), &response))
+	require.Len(t, response.Recoveries, 1)
+	assert.Equal(t, RecoveryActionResubscribed, response.Recoveries[0].Action)
+	assert.Empty(t, response.Results, "the renewal leaves recovering subscriptions alone")
+	assert.Equal(t, 1, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+
+	sub = deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[recoveryTestChannel]
+	assert.Equal(t, StatusPending, sub.Status)
+	assert.Equal(t, 1, sub.Recovery.Attempts)
+
+	// The hub's verification completes the recovery
+	verify := httptest.NewRecorder()
+	handleVerificationChallenge(deps)(verify, verificationRequest("abc", "subscribe", recoveryTestChannel, "3600"))
+	require.Equal(t, http.StatusOK, verify.Code)
+
+	sub = deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[recoveryTestChannel]
+	assert.Equal(t, StatusVerified, sub.Status)
+	assert.False(t, sub.Recovery.RecoveredAt.IsZero())
+	assert.False(t, recovering(sub))
+}
+
+func TestRecovery_BackoffAndGiveUp(t *testing.T) {
+	t.Setenv("RECOVERY_MAX_ATTEMPTS", "3")
+	now := time.Now()
+	deps := newRecoveryTestDeps(&Subscription{
+		ChannelID: recoveryTestChannel,
+		Status:    StatusExpired,
+		ExpiresAt: now,
+		Recovery:  &RecoveryRecord{Reason: RecoveryReasonDenied, StartedAt: now, NextAttemptAt: now},
+	})
+	deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(assert.AnError)
+
+	results, sub := runRecovery(t, deps, now)
+	require.Len(t, results, 1)
+	assert.Equal(t, RecoveryActionFailed, results[0].Action)
+	assert.Equal(t, now.Add(30*time.Minute), sub.Recovery.NextAttemptAt)
+
+	results, _ = runRecovery(t, deps, now.Add(10*time.Minute))
+	assert.Empty(t, results, "waiting out the backoff")
+
+	results, sub = runRecovery(t, deps, now.Add(31*time.Minute))
+	require.Len(t, results, 1)
+	assert.Equal(t, 2, results[0].Attempt)
+	assert.Equal(t, now.Add(91*time.Minute), sub.Recovery.NextAttemptAt, "the backoff doubles")
+
+	results, sub = runRecovery(t, deps, now.Add(2*time.Hour))
+	require.Len(t, results, 1)
+	assert.Equal(t, RecoveryActionGaveUp, results[0].Action)
+	assert.Equal(t, StatusQuarantined, sub.Status)
+	assert.False(t, sub.Recovery.GaveUpAt.IsZero())
+	assert.Contains(t, sub.LastRenewalError, "recovery gave up after 3 attempts")
+	assert.Equal(t, 3, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+}
+
+func TestRecovery_UnverifiedSubscription(t *testing.T) {
+	now := time.Now()
+	deps := newRecoveryTestDeps(&Subscription{
+		ChannelID:   recoveryTestChannel,
+		Status:      StatusPending,
+		LastRenewal: now.Add(-verificationTimeout - time.Minute),
+	})
+
+	results, sub := runRecovery(t, deps, now)
+	require.Len(t, results, 1)
+	assert.Equal(t, RecoveryReasonUnverified, results[0].Reason)
+	assert.Equal(t, RecoveryActionResubscribed, results[0].Action)
+	assert.Equal(t, StatusPending, sub.Status)
+
+	// The hub ignores the re-subscribe too; the next attempt follows the backoff
+	results, sub = runRecovery(t, deps, now.Add(verificationTimeout+time.Minute))
+	require.Len(t, results, 1)
+	assert.Equal(t, 2, sub.Recovery.Attempts)
+}
+
+func TestRecovery_LapsedSubscription(t *testing.T) {
+	now := time.Now()
+
+	t.Run("within_grace", func(t *testing.T) {
+		deps := newRecoveryTestDeps(&Subscription{ChannelID: recoveryTestChannel, Status: StatusActive, ExpiresAt: now.Add(-2 * time.Hour)})
+
+		results, sub := runRecovery(t, deps, now)
+		require.Len(t, results, 1)
+		assert.Equal(t, RecoveryReasonLapsed, results[0].Reason)
+		assert.Equal(t, StatusPending, sub.Status)
+	})
+
+	t.Run("past_grace", func(t *testing.T) {
+		deps := newRecoveryTestDeps(&Subscription{ChannelID: recoveryTestChannel, Status: StatusActive, ExpiresAt: now.Add(-48 * time.Hour)})
+
+		results, sub := runRecovery(t, deps, now)
+		assert.Empty(t, results, "left to the sweeper to expire")
+		assert.Nil(t, sub.Recovery)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("RECOVERY_MAX_ATTEMPTS", "0")
+		deps := newRecoveryTestDeps(&Subscription{ChannelID: recoveryTestChannel, Status: StatusActive, ExpiresAt: now.Add(-2 * time.Hour)})
+
+		results, _ := runRecovery(t, deps, now)
+		assert.Empty(t, results)
+	})
+}
+
+func TestHandleSweepSubscriptions_Recovery(t *testing.T) {
+	deps := newRecoveryTestDeps(&Subscription{ChannelID: recoveryTestChannel, Status: StatusActive, ExpiresAt: time.Now().Add(-2 * time.Hour)})
+	deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(assert.AnError)
+
+	response := runSweep(t, deps)
+	require.Len(t, response.Recoveries, 1)
+	assert.Equal(t, RecoveryActionFailed, response.Recoveries[0].Action)
+	assert.Empty(t, response.Results, "the sweep leaves recovering subscriptions to the recovery")
+
+	sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[recoveryTestChannel]
+	assert.True(t, recovering(sub))
+	assert.Equal(t, 1, sub.Recovery.Attempts)
+}
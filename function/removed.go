@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RemovedSubscription is an unsubscribed channel kept in the state so it can be restored
+// with its configuration intact
+type RemovedSubscription struct {
+	Subscription *Subscription `json:"subscription"`
+	RemovedAt    time.Time     `json:"removed_at"`
+	RemovedBy    string        `json:"removed_by"` // Caller of the unsubscribe, as in the audit log
+}
+
+// getRemovedRetention returns how long unsubscribed channels are kept for restoring, from
+// REMOVED_RETENTION_DAYS (default 30; 0 deletes them on unsubscribe)
+func getRemovedRetention() time.Duration {
+	return envConfig().RemovedRetention
+}
+
+// markRemoved moves sub from the subscriptions to the removed section of state
+func markRemoved(state *SubscriptionState, sub *Subscription, removedBy string, now time.Time) {
+	delete(state.Subscriptions, sub.ChannelID)
+	if state.Removed == nil {
+		state.Removed = make(map[string]*RemovedSubscription)
+	}
+	state.Removed[sub.ChannelID] = &RemovedSubscription{Subscription: sub, RemovedAt: now, RemovedBy: removedBy}
+}
+
+// removeSubscription deletes an unsubscribed channel's subscription. Unless
+// REMOVED_RETENTION_DAYS is 0 it is moved to the removed section of the state instead,
+// purging the removed subscriptions kept past retention on the way.
+func removeSubscription(ctx context.Context, deps *Dependencies, sub *Subscription, removedBy string) error {
+	retention := deps.config().RemovedRetention
+	if retention == 0 {
+		return deps.StorageClient.DeleteSubscription(ctx, sub.ChannelID)
+	}
+
+	state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+	if err != nil {
+		return err
+	}
+	if stored := state.Subscriptions[sub.ChannelID]; stored != nil {
+		sub = stored
+	}
+	now := deps.now()
+	purgeRemoved(state, now, retention)
+	markRemoved(state, sub, removedBy, now)
+	return deps.StorageClient.SaveSubscriptionState(ctx, state)
+}
+
+// purgeRemoved deletes the removed subscriptions kept longer than retention and returns
+// their channel IDs
+func purgeRemoved(state *SubscriptionState, now time.Time, retention time.Duration) []string {
+	var purged []string
+	for channelID, removed := range state.Removed {
+		if now.Sub(removed.RemovedAt) > retention {
+			delete(state.Removed, channelID)
+			purged = append(purged, channelID)
+		}
+	}
+	return purged
+}
+
+// handleRestoreSubscription handles POST /subscriptions/{channel_id}/restore requests
+// using dependency injection. An unsubscribed channel still within REMOVED_RETENTION_DAYS
+// is subscribed again with its previous repositories, sinks, filter, hub and lease.
+func handleRestoreSubscription(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to load subscription state: %v", err))
+			return
+		}
+		if _, exists := state.Subscriptions[channelID]; exists {
+			writeErrorResponse(w, http.StatusConflict, channelID, "Already subscribed to this channel")
+			return
+		}
+
+		now := deps.now()
+		removed := state.Removed[channelID]
+		if removed == nil || removed.Subscription == nil || now.Sub(removed.RemovedAt) > deps.config().RemovedRetention {
+			writeErrorResponse(w, http.StatusNotFound, channelID,
+				"No removed subscription to restore for this channel")
+			return
+		}
+
+		sub := removed.Subscription
+		sub.ChannelID = channelID
+		err = resubscribe(ctx, deps, channelID, sub)
+		if err != nil {
+			writeHubError(w, channelID, "PubSubHubbub subscription failed", err)
+			return
+		}
+
+		// The new lease is recorded when the hub verifies the subscription
+		markPending(sub, now)
+		sub.RenewalAttempts = 0
+		sub.NextRenewalAt = time.Time{}
+		sub.QuarantinedAt = time.Time{}
+		sub.AlertedExpiry = time.Time{}
+		sub.Recovery = nil
+		recordRenewalOutcome(sub, nil)
+
+		delete(state.Removed, channelID)
+		state.Subscriptions[channelID] = sub
+		if err := deps.StorageClient.SaveSubscriptionState(ctx, state); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to save subscription state: %v", err))
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, APIResponse{
+			Status:      "success",
+			ChannelID:   channelID,
+			ChannelName: sub.ChannelName,
+			Message:     "Subscription restored; pending hub verification",
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const removedTestChannel = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+// newConfiguredSubscription returns an active subscription with every per-channel setting
+// a restore has to bring back
+func newConfiguredSubscription() *Subscription {
+	return &Subscription{
+		ChannelID:             removedTestChannel,
+		ChannelName:           "Configured Channel",
+		Status:                StatusActive,
+		ExpiresAt:             time.Now().Add(12 * time.Hour),
+		Secret:                "hub-secret",
+		HubURL:                "https://hub.example.com/",
+		RequestedLeaseSeconds: 432000,
+		Repositories:          []RepositoryTarget{{RepoOwner: "owner", RepoName: "site"}},
+		Sinks:                 []WebhookSink{{URL: "https://sink.example.com/hook"}},
+		Filter:                &NotificationFilter{ExcludeShorts: true},
+	}
+}
+
+// serve sends a request through YouTubeWebhook with deps
+func serve(deps *Dependencies, method, target string) *httptest.ResponseRecorder {
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestGetRemovedRetention(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 30 * 24 * time.Hour},
+		{"7", 7 * 24 * time.Hour},
+		{"0", 0},
+		{"-1", 30 * 24 * time.Hour},
+		{"abc", 30 * 24 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("REMOVED_RETENTION_DAYS", tc.value)
+			assert.Equal(t, tc.expected, getRemovedRetention())
+		})
+	}
+}
+
+func TestHandleUnsubscribe_KeepsRemovedSubscription(t *testing.T) {
+	t.Setenv("API_KEYS", "ops-key")
+	deps := CreateTestDependencies()
+	require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), newConfiguredSubscription()))
+
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/unsubscribe?channel_id="+removedTestChannel, nil)
+	req.Header.Set(APIKeyHeader, "ops-key")
+	YouTubeWebhook(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+	require.NoError(t, err)
+	assert.NotContains(t, state.Subscriptions, removedTestChannel)
+	require.Contains(t, state.Removed, removedTestChannel)
+
+	removed := state.Removed[removedTestChannel]
+	assert.Equal(t, apiKeyCaller("ops-key"), removed.RemovedBy)
+	assert.WithinDuration(t, time.Now(), removed.RemovedAt, time.Minute)
+	assert.Equal(t, "Configured Channel", removed.Subscription.ChannelName)
+	assert.Equal(t, "hub-secret", removed.Subscription.Secret)
+}
+
+func TestHandleUnsubscribe_RetentionDisabled(t *testing.T) {
+	t.Setenv("REMOVED_RETENTION_DAYS", "0")
+	deps := CreateTestDependencies()
+	require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), newConfiguredSubscription()))
+
+	rec := serve(deps, "DELETE", "/unsubscribe?channel_id="+removedTestChannel)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, state.Subscriptions)
+	assert.Empty(t, state.Removed)
+}
+
+func TestHandleRestoreSubscription(t *testing.T) {
+	unsubscribed := func(t *testing.T) *Dependencies {
+		deps := CreateTestDependencies()
+		require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), newConfiguredSubscription()))
+		require.Equal(t, http.StatusNoContent, serve(deps, "DELETE", "/unsubscribe?channel_id="+removedTestChannel).Code)
+		return deps
+	}
+	restore := func(deps *Dependencies) *httptest.ResponseRecorder {
+		return serve(deps, "POST", "/subscriptions/"+removedTestChannel+"/restore")
+	}
+
+	t.Run("restores_previous_configuration", func(t *testing.T) {
+		deps := unsubscribed(t)
+
+		rec := restore(deps)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var response APIResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "Configured Channel", response.ChannelName)
+
+		state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+		require.NoError(t, err)
+		assert.Empty(t, state.Removed)
+
+		sub := state.Subscriptions[removedTestChannel]
+		require.NotNil(t, sub)
+		assert.Equal(t, StatusPending, sub.Status)
+		assert.Equal(t, "hub-secret", sub.Secret)
+		assert.Equal(t, "https://hub.example.com/", sub.HubURL)
+		assert.Equal(t, 432000, sub.RequestedLeaseSeconds)
+		assert.Equal(t, []RepositoryTarget{{RepoOwner: "owner", RepoName: "site"}}, sub.Repositories)
+		assert.Len(t, sub.Sinks, 1)
+		assert.Equal(t, &NotificationFilter{ExcludeShorts: true}, sub.Filter)
+		assert.Equal(t, 1, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("hub_failure_keeps_removed_record", func(t *testing.T) {
+		deps := unsubscribed(t)
+		deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(errors.New("hub unavailable"))
+
+		assert.Equal(t, http.StatusBadGateway, restore(deps).Code)
+
+		state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+		require.NoError(t, err)
+		assert.Contains(t, state.Removed, removedTestChannel)
+		assert.Empty(t, state.Subscriptions)
+	})
+
+	t.Run("already_subscribed", func(t *testing.T) {
+		deps := unsubscribed(t)
+		require.NoError(t, deps.StorageClient.SaveSubscription(t.Context(), &Subscription{ChannelID: removedTestChannel, Status: StatusActive}))
+
+		assert.Equal(t, http.StatusConflict, restore(deps).Code)
+	})
+
+	t.Run("never_removed", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, restore(CreateTestDependencies()).Code)
+	})
+
+	t.Run("past_retention", func(t *testing.T) {
+		deps := unsubscribed(t)
+		t.Setenv("REMOVED_RETENTION_DAYS", "0")
+
+		assert.Equal(t, http.StatusNotFound, restore(deps).Code)
+	})
+}
+
+func TestHandleSweepSubscriptions_PurgesRemoved(t *testing.T) {
+	deps := CreateTestDependencies()
+	now := time.Now()
+	state := &SubscriptionState{
+		Subscriptions: map[string]*Subscription{},
+		Removed: map[string]*RemovedSubscription{
+			"UCrecent": {Subscription: &Subscription{ChannelID: "UCrecent"}, RemovedAt: now.Add(-24 * time.Hour)},
+			"UCold":    {Subscription: &Subscription{ChannelID: "UCold"}, RemovedAt: now.Add(-31 * 24 * time.Hour)},
+		},
+	}
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(t.Context(), state))
+
+	rec := httptest.NewRecorder()
+	handleSweepSubscriptions(deps)(rec, httptest.NewRequest("POST", "/sweep", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response SweepSummaryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Purged)
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, "UCold", response.Results[0].ChannelID)
+
+	saved, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+	require.NoError(t, err)
+	assert.Contains(t, saved.Removed, "UCrecent")
+	assert.NotContains(t, saved.Removed, "UCold")
+}
@@ -0,0 +1,491 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// renewalReportPrefix is where a report of every renewal run is stored
+const renewalReportPrefix = "renewal/reports/"
+
+// latestRenewalReport holds a copy of the most recent run's report, served by GET /renew/report
+const latestRenewalReport = "renewal/latest.json"
+
+// defaultRenewalConcurrency is how many hub renewal requests run at once by default
+const defaultRenewalConcurrency = config.DefaultRenewalConcurrency
+
+// RenewalStreamContentType is the Accept value that makes POST /renew stream its progress
+const RenewalStreamContentType = "application/x-ndjson"
+
+// Renewal progress event types
+const (
+	RenewalEventStart   = "start"
+	RenewalEventResult  = "result"
+	RenewalEventSummary = "summary"
+	RenewalEventError   = "error"
+)
+
+// RenewalEvent is one line of a streamed POST /renew response: a start giving the
+// number of renewals the run will attempt, a result as each renewal finishes, then the
+// run's summary, or an error if the run could not be saved
+type RenewalEvent struct {
+	Type     string                  `json:"type"`
+	Renewals int                     `json:"renewals,omitempty"`
+	Result   *RenewalResult          `json:"result,omitempty"`
+	Summary  *RenewalSummaryResponse `json:"summary,omitempty"`
+	Message  string                  `json:"message,omitempty"`
+}
+
+// Renewal run triggers recorded in reports
+const (
+	RenewalTriggerScheduler = "scheduler"
+	RenewalTriggerManual    = "manual"
+)
+
+// RenewalReport records one renewal run so operators can audit what each run did
+type RenewalReport struct {
+	RunID        string          `json:"run_id"`
+	Trigger      string          `json:"trigger"` // scheduler or manual
+	SchedulerJob string          `json:"scheduler_job,omitempty"`
+	StartedAt    time.Time       `json:"started_at"`
+	FinishedAt   time.Time       `json:"finished_at"`
+	BatchSize    int             `json:"batch_size"` // 0 when every candidate is renewed in one run
+	Concurrency  int             `json:"concurrency"`
+	Forced       bool            `json:"forced,omitempty"`
+	Channels     []string        `json:"channels,omitempty"` // The channels the run was limited to
+	TotalChecked int             `json:"total_checked"`
+	Candidates   int             `json:"candidates"`
+	Succeeded    int             `json:"succeeded"`
+	Failed       int             `json:"failed"`
+	Deferred     int             `json:"deferred"` // Candidates left for the next run
+	BackingOff   int             `json:"backing_off"`
+	Quarantined  []string        `json:"quarantined"`
+	Results      []RenewalResult `json:"results"`
+
+	NextRenewalAt string           `json:"next_renewal_at,omitempty"` // When the next subscription falls due
+	ExpiryAlerts  []string         `json:"expiry_alerts,omitempty"`   // Channels alerted about as expiring without renewal
+	Recoveries    []RecoveryResult `json:"recoveries,omitempty"`      // Re-subscribes of subscriptions the hub stopped delivering
+}
+
+// RenewRequest is the optional JSON body of POST /renew. Without one, every subscription
+// within the renewal threshold is renewed.
+type RenewRequest struct {
+	ChannelIDs []string `json:"channel_ids,omitempty"` // Only renew these channels
+	Force      bool     `json:"force,omitempty"`       // Renew regardless of the threshold and failure backoff
+}
+
+// parseRenewRequest reads the optional body of a POST /renew request. Unknown fields,
+// such as those Cloud Scheduler sends, are ignored.
+func parseRenewRequest(r *http.Request) (RenewRequest, error) {
+	var req RenewRequest
+	if r.Body == nil {
+		return req, nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return req, fmt.Errorf("invalid request body: %v", err)
+	}
+	for _, channelID := range req.ChannelIDs {
+		if !validateTopicID(channelID) {
+			return req, fmt.Errorf("invalid channel ID format: %s", channelID)
+		}
+	}
+	return req, nil
+}
+
+// getRenewalBatchSize returns how many subscriptions one run renews; 0 renews every candidate
+func getRenewalBatchSize() int {
+	return envConfig().RenewalBatchSize
+}
+
+// getRenewalConcurrency returns how many subscriptions are renewed at once
+func getRenewalConcurrency() int {
+	return envConfig().RenewalConcurrency
+}
+
+// getRenewalJitter returns the maximum random delay before each hub request after the first
+func getRenewalJitter() time.Duration {
+	return envConfig().RenewalJitter
+}
+
+// renewalTrigger reports whether the run was started by Cloud Scheduler, and the job name
+func renewalTrigger(r *http.Request) (string, string) {
+	if r.Header.Get("X-CloudScheduler") == "true" {
+		return RenewalTriggerScheduler, r.Header.Get("X-CloudScheduler-JobName")
+	}
+	return RenewalTriggerManual, ""
+}
+
+// renewalCandidates returns the channels due for renewal, soonest expiry first, so a
+// limited batch renews the subscriptions closest to lapsing. Quarantined subscriptions
+// are skipped; those still backing off after a failed renewal are skipped and counted.
+// The request can limit the run to some channels, and force renews them whatever their
+// expiry or backoff.
+func renewalCandidates(state *SubscriptionState, now time.Time, threshold time.Duration, req RenewRequest) ([]string, int) {
+	var selected map[string]bool
+	if len(req.ChannelIDs) > 0 {
+		selected = make(map[string]bool, len(req.ChannelIDs))
+		for _, channelID := range req.ChannelIDs {
+			selected[channelID] = true
+		}
+	}
+
+	var candidates []string
+	var waiting int
+	for channelID, subscription := range state.Subscriptions {
+		if selected != nil && !selected[channelID] {
+			continue
+		}
+		// Subscriptions the hub has not verified yet have no lease to renew
+		// Recovering subscriptions are re-subscribed on the recovery's own backoff
+		if subscription.Status == StatusPending || subscription.Status == StatusQuarantined || recovering(subscription) {
+			continue
+		}
+		if !req.Force && subscription.ExpiresAt.Sub(now) > threshold {
+			continue
+		}
+		if !req.Force && backingOff(subscription, now) {
+			waiting++
+			continue
+		}
+		candidates = append(candidates, channelID)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := state.Subscriptions[candidates[i]], state.Subscriptions[candidates[j]]
+		if !a.ExpiresAt.Equal(b.ExpiresAt) {
+			return a.ExpiresAt.Before(b.ExpiresAt)
+		}
+		return candidates[i] < candidates[j]
+	})
+	return candidates, waiting
+}
+
+// nextRenewalAt returns when a scheduled run will next renew sub: threshold before the
+// end of the lease the hub granted, or after that once a failed renewal has backed off.
+// A time in the past means the next run renews it. Returns the zero time for
+// subscriptions that are not renewed: pending, quarantined, recovering, or without a lease.
+func nextRenewalAt(sub *Subscription, threshold time.Duration) time.Time {
+	if sub.Status == StatusPending || sub.Status == StatusQuarantined || recovering(sub) || sub.ExpiresAt.IsZero() {
+		return time.Time{}
+	}
+	due := sub.ExpiresAt.Add(-threshold)
+	if sub.NextRenewalAt.After(due) {
+		return sub.NextRenewalAt
+	}
+	return due
+}
+
+// upcomingRenewal returns the earliest renewal that falls due after now, or the zero
+// time when no subscription is waiting for one
+func upcomingRenewal(state *SubscriptionState, now time.Time, threshold time.Duration) time.Time {
+	var next time.Time
+	for _, sub := range state.Subscriptions {
+		due := nextRenewalAt(sub, threshold)
+		if due.After(now) && (next.IsZero() || due.Before(next)) {
+			next = due
+		}
+	}
+	return next
+}
+
+// renewalWorkingSet holds the subscriptions a renewal run can act on, streamed from
+// storage so the rest are never held: those due for renewal or an expiry alert,
+// pending, quarantined or recovering ones, and those the request names. The others
+// are only counted, along with the earliest of their renewals.
+type renewalWorkingSet struct {
+	state  *SubscriptionState
+	total  int
+	next   time.Time         // Earliest renewal due among the subscriptions left out
+	stored map[string][]byte // Each kept subscription as loaded, to find what the run changed
+}
+
+// loadRenewalWorkingSet streams the stored subscriptions into a working set for req
+func loadRenewalWorkingSet(ctx context.Context, storage StorageService, req RenewRequest, now time.Time,
+	threshold, alertWindow time.Duration) (*renewalWorkingSet, error) {
+	selected := make(map[string]bool, len(req.ChannelIDs))
+	for _, channelID := range req.ChannelIDs {
+		selected[channelID] = true
+	}
+	horizon := threshold
+	if alertWindow > horizon {
+		horizon = alertWindow
+	}
+
+	set := &renewalWorkingSet{
+		state:  &SubscriptionState{Subscriptions: make(map[string]*Subscription)},
+		stored: make(map[string][]byte),
+	}
+	err := storage.StreamSubscriptions(ctx, func(sub *Subscription) error {
+		set.total++
+		keep := selected[sub.ChannelID] || (req.Force && len(selected) == 0) ||
+			sub.Status == StatusPending || sub.Status == StatusQuarantined || recovering(sub) ||
+			sub.ExpiresAt.Sub(now) <= horizon
+		if !keep {
+			due := nextRenewalAt(sub, threshold)
+			if due.After(now) && (set.next.IsZero() || due.Before(set.next)) {
+				set.next = due
+			}
+			return nil
+		}
+
+		data, err := json.Marshal(sub)
+		if err != nil {
+			return fmt.Errorf("failed to marshal subscription: %v", err)
+		}
+		set.state.Subscriptions[sub.ChannelID] = sub
+		set.stored[sub.ChannelID] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// nextRenewal returns the earliest renewal that falls due after now, across the
+// working set and the subscriptions left out of it
+func (set *renewalWorkingSet) nextRenewal(now time.Time, threshold time.Duration) time.Time {
+	next := upcomingRenewal(set.state, now, threshold)
+	if next.IsZero() || (!set.next.IsZero() && set.next.Before(next)) {
+		return set.next
+	}
+	return next
+}
+
+// save writes back the subscriptions the run changed, one at a time, so subscriptions
+// it did not touch are never rewritten
+func (set *renewalWorkingSet) save(ctx context.Context, storage StorageService) error {
+	channelIDs := make([]string, 0, len(set.state.Subscriptions))
+	for channelID := range set.state.Subscriptions {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for _, channelID := range channelIDs {
+		sub := set.state.Subscriptions[channelID]
+		data, err := json.Marshal(sub)
+		if err != nil {
+			return fmt.Errorf("failed to marshal subscription: %v", err)
+		}
+		if string(data) == string(set.stored[channelID]) {
+			continue
+		}
+		if err := storage.SaveSubscription(ctx, sub); err != nil {
+			return err
+		}
+		set.stored[channelID] = data
+	}
+	return nil
+}
+
+// renewBatch renews the batch's subscriptions with up to concurrency hub requests in
+// flight, starting them in order with up to jitter between starts. Each finished result
+// is passed to progress, one at a time. Each renewal is bounded to its share of the
+// request's deadline budget. When ctx is cancelled, too little of the budget is left or
+// the hub's circuit breaker opens, no further renewals are started; the results of
+// those that were are returned in batch order.
+func renewBatch(ctx context.Context, deps *Dependencies, state *SubscriptionState, batch []string,
+	concurrency int, jitter time.Duration, progress func(RenewalResult)) []RenewalResult {
+	now := deps.now()
+	maxAttempts := deps.config().MaxRenewalAttempts
+	results := make([]RenewalResult, len(batch))
+
+	var progressMu sync.Mutex
+	var wg sync.WaitGroup
+	type renewalJob struct {
+		index int
+		share time.Duration
+	}
+	jobs := make(chan renewalJob)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				// Each worker only touches its own subscription, so state needs no lock
+				i := job.index
+				channelID := batch[i]
+				subscription := state.Subscriptions[channelID]
+				previousExpiry := subscription.ExpiresAt
+				startedAt, started := deps.now(), time.Now()
+				renewCtx, cancel := withBudgetShare(ctx, job.share)
+				result := renewSubscription(renewCtx, channelID, subscription, state, deps)
+				cancel()
+				result.PreviousExpiresAt = formatOptionalTime(previousExpiry)
+				result.StartedAt = startedAt
+				result.DurationMs = time.Since(started).Milliseconds()
+
+				if !result.Success {
+					// Back off before the next attempt, or quarantine after too many failures
+					recordRenewalFailure(subscription, fmt.Errorf("%s", result.Message), now, maxAttempts)
+					result.Quarantined = subscription.Status == StatusQuarantined
+					result.NextAttemptAt = formatOptionalTime(subscription.NextRenewalAt)
+				}
+				results[i] = result
+
+				if progress != nil {
+					progressMu.Lock()
+					progress(result)
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	started := 0
+dispatch:
+	for i := range batch {
+		// Spread hub requests out; stop early if the invocation is being cancelled
+		if ctx.Err() != nil || (i > 0 && waitJitter(ctx, jitter) != nil) {
+			break
+		}
+		// The renewals left run in waves of concurrency, each wave with an equal share.
+		// When the budget cannot cover every wave the renewal gets what is left, and
+		// those it leaves too little for are deferred to the next run.
+		waves := (len(batch) - i + concurrency - 1) / concurrency
+		share, err := budgetShare(ctx, callRenewal, waves)
+		if err != nil {
+			share, err = budgetShare(ctx, callRenewal, 1)
+		}
+		if err == nil {
+			// The hub's circuit breaker opened during the batch
+			err = deps.HubBreaker.Check()
+		}
+		if err != nil {
+			fmt.Printf("Deferring %d renewals: %v\n", len(batch)-i, err)
+			break
+		}
+		select {
+		case jobs <- renewalJob{index: i, share: share}:
+			started++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results[:started]
+}
+
+// wantsRenewalStream reports whether the client asked for streamed renewal progress
+func wantsRenewalStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), RenewalStreamContentType)
+}
+
+// renewalStream writes RenewalEvents as newline-delimited JSON, flushing each one so
+// the client sees progress before the run finishes
+type renewalStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	encoder    *json.Encoder
+}
+
+// startRenewalStream commits a 200 response and returns a stream for its events
+func startRenewalStream(w http.ResponseWriter) *renewalStream {
+	w.Header().Set("Content-Type", RenewalStreamContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	return &renewalStream{w: w, controller: http.NewResponseController(w), encoder: json.NewEncoder(w)}
+}
+
+// send writes one event; the client may have gone away, so failures are only logged
+func (s *renewalStream) send(event RenewalEvent) {
+	if err := s.encoder.Encode(event); err != nil {
+		fmt.Printf("Error streaming renewal progress: %v\n", err)
+		return
+	}
+	if err := s.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		fmt.Printf("Error flushing renewal progress: %v\n", err)
+	}
+}
+
+// waitJitter sleeps for a random delay up to max, returning early with the context's
+// error when it is cancelled
+func waitJitter(ctx context.Context, max time.Duration) error {
+	if max <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(rand.N(max)):
+		return nil
+	}
+}
+
+// saveRenewalReport stores the report of a renewal run, returning the object name.
+// Returns "" when the storage cannot hold objects.
+func saveRenewalReport(ctx context.Context, storage StorageService, report *RenewalReport) (string, error) {
+	store, ok := storage.(ObjectStorage)
+	if !ok {
+		return "", nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode renewal report: %v", err)
+	}
+
+	name := renewalReportPrefix + report.StartedAt.UTC().Format("20060102T150405Z")
+	if report.RunID != "" {
+		name += "-" + report.RunID
+	}
+	name += ".json"
+	if err := store.WriteObject(ctx, name, data); err != nil {
+		return "", fmt.Errorf("failed to write renewal report: %v", err)
+	}
+	if err := store.WriteObject(ctx, latestRenewalReport, data); err != nil {
+		return name, fmt.Errorf("failed to write latest renewal report: %v", err)
+	}
+	return name, nil
+}
+
+// RenewalReportResponse is returned by GET /renew/report
+type RenewalReportResponse struct {
+	Status string         `json:"status"`
+	Report *RenewalReport `json:"report"`
+}
+
+// handleGetRenewalReport handles GET /renew/report requests using dependency injection:
+// the report of the most recent renewal run
+func handleGetRenewalReport(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := deps.StorageClient.(ObjectStorage)
+		if !ok {
+			writeErrorResponse(w, http.StatusNotImplemented, "",
+				"Renewal reports are not supported by the storage backend")
+			return
+		}
+
+		data, err := store.ReadObject(r.Context(), latestRenewalReport)
+		if errors.Is(err, ErrObjectNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "", "No renewal run has been reported yet")
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "",
+				fmt.Sprintf("Failed to read renewal report: %v", err))
+			return
+		}
+
+		var report RenewalReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "",
+				fmt.Sprintf("Failed to decode renewal report: %v", err))
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, RenewalReportResponse{Status: "success", Report: &report})
+	}
+}
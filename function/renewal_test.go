@@ -1,14 +1,19 @@
 package webhook
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleRenewSubscriptions(t *testing.T) {
@@ -95,7 +100,6 @@ func TestHandleRenewSubscriptions(t *testing.T) {
 		result := response.Results[0]
 		assert.Equal(t, expiringSubscription.ChannelID, result.ChannelID)
 		assert.True(t, result.Success)
-		assert.NotEmpty(t, result.NewExpiryTime)
 		assert.Equal(t, 0, result.AttemptCount)
 	})
 
@@ -341,3 +345,532 @@ func TestHandleRenewSubscriptions(t *testing.T) {
 		assert.Contains(t, body, "Failed to save subscription state")
 	})
 }
+
+// seedExpiringSubscriptions stores count active subscriptions expiring an hour apart,
+// the first one hour from now
+func seedExpiringSubscriptions(t *testing.T, deps *Dependencies, count int) []string {
+	t.Helper()
+	now := time.Now()
+	state := &SubscriptionState{Subscriptions: make(map[string]*Subscription)}
+	var channelIDs []string
+	for i := 0; i < count; i++ {
+		channelID := fmt.Sprintf("UC%022d", i)
+		state.Subscriptions[channelID] = &Subscription{
+			ChannelID: channelID,
+			Status:    StatusActive,
+			ExpiresAt: now.Add(time.Duration(i+1) * time.Hour),
+		}
+		channelIDs = append(channelIDs, channelID)
+	}
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(t.Context(), state))
+	return channelIDs
+}
+
+func TestHandleRenewSubscriptions_Batching(t *testing.T) {
+	t.Setenv("RENEWAL_THRESHOLD_HOURS", "12")
+	t.Setenv("RENEWAL_BATCH_SIZE", "2")
+	t.Setenv("RENEWAL_JITTER_MS", "1")
+
+	deps := CreateTestDependencies()
+	channelIDs := seedExpiringSubscriptions(t, deps, 5)
+
+	rec := httptest.NewRecorder()
+	handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response RenewalSummaryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 5, response.RenewalsCandidates)
+	assert.Equal(t, 2, response.RenewalsSucceeded)
+	assert.Equal(t, 3, response.RenewalsDeferred)
+
+	// The subscriptions closest to expiry are renewed first
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, channelIDs[0], response.Results[0].ChannelID)
+	assert.Equal(t, channelIDs[1], response.Results[1].ChannelID)
+	assert.Equal(t, 2, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+}
+
+func TestHandleRenewSubscriptions_Selection(t *testing.T) {
+	t.Setenv("RENEWAL_THRESHOLD_HOURS", "2")
+
+	renew := func(deps *Dependencies, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("selected_channels_within_threshold", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		channelIDs := seedExpiringSubscriptions(t, deps, 4)
+
+		rec := renew(deps, fmt.Sprintf(`{"channel_ids": [%q, %q]}`, channelIDs[1], channelIDs[3]))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.RenewalsCandidates, "the 4h subscription is outside the threshold")
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, channelIDs[1], response.Results[0].ChannelID)
+		assert.False(t, response.Forced)
+	})
+
+	t.Run("force_ignores_threshold_and_backoff", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		channelIDs := seedExpiringSubscriptions(t, deps, 3)
+		storage := deps.StorageClient.(*MockStorageClient)
+		sub, err := storage.GetSubscription(t.Context(), channelIDs[0])
+		require.NoError(t, err)
+		sub.NextRenewalAt = time.Now().Add(time.Hour)
+		require.NoError(t, storage.SaveSubscription(t.Context(), sub))
+
+		rec := renew(deps, `{"force": true}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.True(t, response.Forced)
+		assert.Equal(t, 3, response.RenewalsSucceeded)
+		assert.Equal(t, 0, response.RenewalsBackingOff)
+		for _, result := range response.Results {
+			assert.False(t, result.StartedAt.IsZero())
+			assert.GreaterOrEqual(t, result.DurationMs, int64(0))
+		}
+
+		data, err := storage.ReadObject(t.Context(), response.Report)
+		require.NoError(t, err)
+		var report RenewalReport
+		require.NoError(t, json.Unmarshal(data, &report))
+		assert.True(t, report.Forced)
+	})
+
+	t.Run("force_selected_channel", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		channelIDs := seedExpiringSubscriptions(t, deps, 4)
+
+		rec := renew(deps, fmt.Sprintf(`{"channel_ids": [%q], "force": true}`, channelIDs[3]))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, channelIDs[3], response.Results[0].ChannelID)
+		assert.Equal(t, 1, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("scheduler_body_is_ignored", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedExpiringSubscriptions(t, deps, 3)
+
+		rec := renew(deps, `{"source": "cloud-scheduler", "timestamp": "2025-01-21T10:30:00Z"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.RenewalsCandidates)
+	})
+
+	t.Run("invalid_requests", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedExpiringSubscriptions(t, deps, 1)
+
+		assert.Equal(t, http.StatusBadRequest, renew(deps, `{"channel_ids": ["bad"]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, renew(deps, `{"force": "yes"}`).Code)
+
+		rec := renew(deps, `{"channel_ids": ["UCBJycsmduvYEL83R_U4JriQ"]}`)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "UCBJycsmduvYEL83R_U4JriQ")
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+}
+
+func TestHandleRenewSubscriptions_Report(t *testing.T) {
+	t.Setenv("RENEWAL_THRESHOLD_HOURS", "12")
+
+	t.Run("scheduler_run", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedExpiringSubscriptions(t, deps, 2)
+		deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(fmt.Errorf("hub unavailable"))
+
+		req := httptest.NewRequest("POST", "/renew", nil)
+		req.Header.Set("X-CloudScheduler", "true")
+		req.Header.Set("X-CloudScheduler-JobName", "youtube-subscription-renewal-prod")
+		rec := httptest.NewRecorder()
+		rec.Header().Set(RequestIDHeader, "run-1")
+		handleRenewSubscriptions(deps)(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.NotEmpty(t, response.Report)
+		assert.Regexp(t, `^renewal/reports/\d{8}T\d{6}Z-run-1\.json$`, response.Report)
+
+		data, err := deps.StorageClient.(*MockStorageClient).ReadObject(t.Context(), response.Report)
+		require.NoError(t, err)
+		var report RenewalReport
+		require.NoError(t, json.Unmarshal(data, &report))
+		assert.Equal(t, "run-1", report.RunID)
+		assert.Equal(t, RenewalTriggerScheduler, report.Trigger)
+		assert.Equal(t, "youtube-subscription-renewal-prod", report.SchedulerJob)
+		assert.Equal(t, 2, report.Candidates)
+		assert.Equal(t, 2, report.Failed)
+		assert.Len(t, report.Results, 2)
+		assert.False(t, report.FinishedAt.Before(report.StartedAt))
+	})
+
+	t.Run("manual_run_without_candidates", func(t *testing.T) {
+		deps := CreateTestDependencies()
+
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		data, err := deps.StorageClient.(*MockStorageClient).ReadObject(t.Context(), response.Report)
+		require.NoError(t, err)
+
+		var report RenewalReport
+		require.NoError(t, json.Unmarshal(data, &report))
+		assert.Equal(t, RenewalTriggerManual, report.Trigger)
+		assert.Equal(t, 0, report.Candidates)
+		assert.NotNil(t, report.Results)
+	})
+
+	t.Run("report_failure_does_not_fail_run", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SaveError = ErrMockSaveFailure
+
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Empty(t, response.Report)
+	})
+}
+
+func TestHandleGetRenewalReport(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/renew/report", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "no run has been reported yet")
+
+	for _, runID := range []string{"run-1", "run-2"} {
+		rec = httptest.NewRecorder()
+		rec.Header().Set(RequestIDHeader, runID)
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/renew/report", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response RenewalReportResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.NotNil(t, response.Report)
+	assert.Equal(t, "run-2", response.Report.RunID, "the most recent run is reported")
+	assert.Equal(t, RenewalTriggerManual, response.Report.Trigger)
+}
+
+func TestWaitJitter(t *testing.T) {
+	assert.NoError(t, waitJitter(t.Context(), 0))
+	assert.NoError(t, waitJitter(t.Context(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	assert.ErrorIs(t, waitJitter(ctx, time.Hour), context.Canceled)
+}
+
+// concurrencyPubSub records how many Subscribe calls are in flight at once
+type concurrencyPubSub struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       int
+	delay       time.Duration
+}
+
+func (p *concurrencyPubSub) Subscribe(ctx context.Context, channelID, secret string, leaseSeconds int) error {
+	p.mu.Lock()
+	p.calls++
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	p.mu.Unlock()
+
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *concurrencyPubSub) Unsubscribe(ctx context.Context, channelID string) error {
+	return nil
+}
+
+func TestGetRenewalConcurrency(t *testing.T) {
+	assert.Equal(t, defaultRenewalConcurrency, getRenewalConcurrency())
+
+	t.Setenv("RENEWAL_CONCURRENCY", "16")
+	assert.Equal(t, 16, getRenewalConcurrency())
+
+	t.Setenv("RENEWAL_CONCURRENCY", "0")
+	assert.Equal(t, defaultRenewalConcurrency, getRenewalConcurrency())
+
+	t.Setenv("RENEWAL_CONCURRENCY", "many")
+	assert.Equal(t, defaultRenewalConcurrency, getRenewalConcurrency())
+}
+
+func TestRenewBatch(t *testing.T) {
+	t.Run("bounded_concurrency_keeps_batch_order", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		pubsub := &concurrencyPubSub{delay: 20 * time.Millisecond}
+		deps.PubSubClient = pubsub
+		channelIDs := seedExpiringSubscriptions(t, deps, 9)
+		state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+		require.NoError(t, err)
+
+		var progressed []string
+		results := renewBatch(t.Context(), deps, state, channelIDs, 3, 0, func(result RenewalResult) {
+			progressed = append(progressed, result.ChannelID)
+		})
+
+		require.Len(t, results, 9)
+		for i, result := range results {
+			assert.Equal(t, channelIDs[i], result.ChannelID)
+			assert.True(t, result.Success)
+			assert.GreaterOrEqual(t, result.DurationMs, int64(20))
+		}
+		assert.ElementsMatch(t, channelIDs, progressed)
+		assert.Equal(t, 3, pubsub.maxInFlight)
+	})
+
+	t.Run("failures_back_off", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(fmt.Errorf("hub unavailable"))
+		channelIDs := seedExpiringSubscriptions(t, deps, 4)
+		state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+		require.NoError(t, err)
+
+		results := renewBatch(t.Context(), deps, state, channelIDs, 2, 0, nil)
+		require.Len(t, results, 4)
+		for _, result := range results {
+			assert.False(t, result.Success)
+			assert.NotEmpty(t, result.NextAttemptAt)
+			assert.Equal(t, 1, state.Subscriptions[result.ChannelID].RenewalAttempts)
+		}
+	})
+
+	t.Run("cancelled_context_starts_nothing", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		channelIDs := seedExpiringSubscriptions(t, deps, 3)
+		state, err := deps.StorageClient.LoadSubscriptionState(t.Context())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		assert.Empty(t, renewBatch(ctx, deps, state, channelIDs, 2, 0, nil))
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+}
+
+func TestHandleRenewSubscriptions_Stream(t *testing.T) {
+	t.Setenv("RENEWAL_THRESHOLD_HOURS", "12")
+
+	readEvents := func(t *testing.T, rec *httptest.ResponseRecorder) []RenewalEvent {
+		var events []RenewalEvent
+		for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+			var event RenewalEvent
+			require.NoError(t, json.Unmarshal([]byte(line), &event))
+			events = append(events, event)
+		}
+		return events
+	}
+
+	t.Run("results_then_summary", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		channelIDs := seedExpiringSubscriptions(t, deps, 3)
+
+		req := httptest.NewRequest("POST", "/renew", nil)
+		req.Header.Set("Accept", RenewalStreamContentType)
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, RenewalStreamContentType, rec.Header().Get("Content-Type"))
+		assert.True(t, rec.Flushed)
+
+		events := readEvents(t, rec)
+		require.Len(t, events, 5)
+		assert.Equal(t, RenewalEventStart, events[0].Type)
+		assert.Equal(t, 3, events[0].Renewals)
+
+		var streamed []string
+		for _, event := range events[1:4] {
+			assert.Equal(t, RenewalEventResult, event.Type)
+			require.NotNil(t, event.Result)
+			assert.NotEmpty(t, event.Result.PreviousExpiresAt)
+			assert.NotEmpty(t, event.Result.RequestedExpiresAt)
+			streamed = append(streamed, event.Result.ChannelID)
+		}
+		assert.ElementsMatch(t, channelIDs, streamed)
+
+		assert.Equal(t, RenewalEventSummary, events[4].Type)
+		require.NotNil(t, events[4].Summary)
+		assert.Equal(t, 3, events[4].Summary.RenewalsSucceeded)
+		assert.Len(t, events[4].Summary.Results, 3)
+	})
+
+	t.Run("save_failure_ends_with_error", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedExpiringSubscriptions(t, deps, 2)
+		deps.StorageClient.(*MockStorageClient).SaveError = ErrMockSaveFailure
+
+		req := httptest.NewRequest("POST", "/renew", nil)
+		req.Header.Set("Accept", RenewalStreamContentType)
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, req)
+
+		events := readEvents(t, rec)
+		require.Len(t, events, 4)
+		assert.Equal(t, RenewalEventError, events[3].Type)
+		assert.Contains(t, events[3].Message, "Failed to save subscription state")
+	})
+
+	t.Run("errors_before_renewing_stay_json", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).LoadError = ErrMockLoadFailure
+
+		req := httptest.NewRequest("POST", "/renew", nil)
+		req.Header.Set("Accept", RenewalStreamContentType)
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+	})
+}
+
+func TestNextRenewalAt(t *testing.T) {
+	now := time.Date(2025, 1, 21, 10, 0, 0, 0, time.UTC)
+	threshold := 12 * time.Hour
+
+	tests := []struct {
+		name string
+		sub  Subscription
+		want time.Time
+	}{
+		{"threshold_before_expiry", Subscription{Status: StatusActive, ExpiresAt: now.Add(36 * time.Hour)}, now.Add(24 * time.Hour)},
+		{"already_due", Subscription{Status: StatusVerified, ExpiresAt: now.Add(time.Hour)}, now.Add(-11 * time.Hour)},
+		{"backing_off", Subscription{Status: StatusActive, ExpiresAt: now.Add(time.Hour), NextRenewalAt: now.Add(30 * time.Minute)}, now.Add(30 * time.Minute)},
+		{"backoff_before_threshold", Subscription{Status: StatusActive, ExpiresAt: now.Add(36 * time.Hour), NextRenewalAt: now.Add(time.Hour)}, now.Add(24 * time.Hour)},
+		{"pending", Subscription{Status: StatusPending, ExpiresAt: now.Add(36 * time.Hour)}, time.Time{}},
+		{"quarantined", Subscription{Status: StatusQuarantined, ExpiresAt: now.Add(36 * time.Hour)}, time.Time{}},
+		{"no_lease", Subscription{Status: StatusActive}, time.Time{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, nextRenewalAt(&tc.sub, threshold))
+		})
+	}
+}
+
+func TestHandleRenewSubscriptions_NextRenewal(t *testing.T) {
+	t.Setenv("RENEWAL_THRESHOLD_HOURS", "12")
+
+	deps := CreateTestDependencies()
+	now := time.Now().UTC().Truncate(time.Second)
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{
+		"UCXuqSBlHAE6Xw-yeJA0Tunw": {ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive, ExpiresAt: now.Add(time.Hour)},
+		"UCBJycsmduvYEL83R_U4JriQ": {ChannelID: "UCBJycsmduvYEL83R_U4JriQ", Status: StatusActive, ExpiresAt: now.Add(30 * time.Hour)},
+		"UC_x5XG1OV2P6uZZ5FSM9Ttw": {ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: StatusActive, ExpiresAt: now.Add(48 * time.Hour)},
+	}}
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(t.Context(), state))
+	want := now.Add(18 * time.Hour).Format(time.RFC3339)
+
+	t.Run("renewal_summary", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.RenewalsSucceeded)
+		assert.Equal(t, want, response.NextRenewalAt)
+
+		data, err := deps.StorageClient.(*MockStorageClient).ReadObject(t.Context(), response.Report)
+		require.NoError(t, err)
+		var report RenewalReport
+		require.NoError(t, json.Unmarshal(data, &report))
+		assert.Equal(t, want, report.NextRenewalAt)
+	})
+
+	t.Run("subscription_list", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handleGetSubscriptions(deps)(rec, httptest.NewRequest("GET", "/subscriptions", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response SubscriptionsListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		nextRenewals := map[string]string{}
+		for _, info := range response.Subscriptions {
+			nextRenewals[info.ChannelID] = info.NextRenewalAt
+		}
+		assert.Equal(t, want, nextRenewals["UCBJycsmduvYEL83R_U4JriQ"])
+		assert.Equal(t, now.Add(36*time.Hour).Format(time.RFC3339), nextRenewals["UC_x5XG1OV2P6uZZ5FSM9Ttw"])
+	})
+}
+
+func TestHandleRenewSubscriptions_WorkingSet(t *testing.T) {
+	t.Setenv("RENEWAL_THRESHOLD_HOURS", "12")
+	t.Setenv("EXPIRY_ALERT_HOURS", "24")
+
+	deps := CreateTestDependencies()
+	mockStorage := deps.StorageClient.(*MockStorageClient)
+	now := time.Now().UTC().Truncate(time.Second)
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{
+		"UCXuqSBlHAE6Xw-yeJA0Tunw": {ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive, ExpiresAt: now.Add(time.Hour)},
+		"UCBJycsmduvYEL83R_U4JriQ": {ChannelID: "UCBJycsmduvYEL83R_U4JriQ", Status: StatusActive, ExpiresAt: now.Add(20 * time.Hour)},
+		"UC_x5XG1OV2P6uZZ5FSM9Ttw": {ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: StatusActive, ExpiresAt: now.Add(48 * time.Hour)},
+	}}
+	require.NoError(t, mockStorage.SaveSubscriptionState(t.Context(), state))
+
+	t.Run("keeps_subscriptions_the_run_can_act_on", func(t *testing.T) {
+		set, err := loadRenewalWorkingSet(t.Context(), mockStorage, RenewRequest{}, now, 12*time.Hour, getExpiryAlertWindow())
+		require.NoError(t, err)
+		assert.Equal(t, 3, set.total)
+		assert.Len(t, set.state.Subscriptions, 2, "due for renewal or an expiry alert")
+		assert.NotContains(t, set.state.Subscriptions, "UC_x5XG1OV2P6uZZ5FSM9Ttw")
+		assert.Equal(t, now.Add(36*time.Hour), set.next)
+
+		forced, err := loadRenewalWorkingSet(t.Context(), mockStorage, RenewRequest{Force: true}, now, 12*time.Hour, getExpiryAlertWindow())
+		require.NoError(t, err)
+		assert.Len(t, forced.state.Subscriptions, 3)
+	})
+
+	t.Run("saves_only_changed_subscriptions", func(t *testing.T) {
+		mockStorage.SaveCallCount = 0
+		rec := httptest.NewRecorder()
+		handleRenewSubscriptions(deps)(rec, httptest.NewRequest("POST", "/renew", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RenewalSummaryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 3, response.TotalChecked)
+		assert.Equal(t, 1, response.RenewalsSucceeded)
+
+		// Only the renewed subscription is written; the others are not rewritten
+		assert.Equal(t, 1, mockStorage.SaveCallCount)
+		stored, err := mockStorage.GetSubscription(t.Context(), "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		require.NoError(t, err)
+		assert.False(t, stored.LastRenewal.IsZero())
+	})
+}
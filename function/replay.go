@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Where a replayed video's payload was rebuilt from
+const (
+	ReplaySourceHistory    = "history"
+	ReplaySourceYouTubeAPI = "youtube_api"
+)
+
+// replayTargetPubSub identifies the Cloud Pub/Sub event in replay results
+const replayTargetPubSub = "pubsub"
+
+// videoIDPattern matches YouTube video IDs
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// ReplayTargetResult describes the delivery of a replayed video to one target
+type ReplayTargetResult struct {
+	Type    string `json:"type"` // github, webhook or pubsub
+	Target  string `json:"target"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// ReplayResponse reports a replayed video's deliveries
+type ReplayResponse struct {
+	Status    string               `json:"status"` // success, partial or failed
+	VideoID   string               `json:"video_id"`
+	ChannelID string               `json:"channel_id"`
+	Title     string               `json:"title"`
+	Source    string               `json:"source"`
+	Results   []ReplayTargetResult `json:"results"`
+}
+
+// handleReplayVideo handles POST /replay?video_id=...&channel_id=... requests using dependency
+// injection. The dispatch payload is rebuilt from the channel's notification history, or from
+// the YouTube Data API when the video is not in it, and sent again to every GitHub repository,
+// webhook sink and event topic configured for the channel. Filters and duplicate suppression
+// are bypassed, and failed deliveries are reported rather than queued for retry.
+func handleReplayVideo(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		videoID := r.URL.Query().Get("video_id")
+		channelID := r.URL.Query().Get("channel_id")
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+		if !videoIDPattern.MatchString(videoID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid video ID format")
+			return
+		}
+
+		entry, source, status, message := findReplayEntry(r, deps, channelID, videoID)
+		if entry == nil {
+			writeErrorResponse(w, status, channelID, message)
+			return
+		}
+
+		service := newNotificationService(deps)
+		response := ReplayResponse{
+			VideoID:   entry.VideoID,
+			ChannelID: entry.ChannelID,
+			Title:     entry.Title,
+			Source:    source,
+			Results:   service.replay(r, entry),
+		}
+		if len(response.Results) == 0 {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, channelID,
+				"No GitHub token, webhook sinks or event topic configured to replay to")
+			return
+		}
+
+		delivered := 0
+		var summary []string
+		for _, result := range response.Results {
+			if result.Success {
+				delivered++
+			}
+			summary = append(summary, fmt.Sprintf("%s %s: %s", result.Type, result.Target, result.Message))
+		}
+		statusCode := http.StatusOK
+		switch delivered {
+		case len(response.Results):
+			response.Status = "success"
+		case 0:
+			response.Status = "failed"
+			statusCode = http.StatusBadGateway
+		default:
+			response.Status = "partial"
+		}
+
+		service.recordHistory(r.Context(), entry, HistoryDecisionReplayed, &NotificationResult{
+			Status:  response.Status,
+			Message: fmt.Sprintf("Replayed to %d of %d targets: %s", delivered, len(response.Results), strings.Join(summary, "; ")),
+		})
+		writeJSONResponse(w, statusCode, response)
+	}
+}
+
+// findReplayEntry rebuilds the notification entry for a video. When it cannot, it
+// returns nil with the status code and message to respond with.
+func findReplayEntry(r *http.Request, deps *Dependencies, channelID, videoID string) (*Entry, string, int, string) {
+	if history := NewNotificationHistory(deps.StorageClient, deps.config()); history != nil {
+		found, err := history.Find(r.Context(), channelID, videoID)
+		if err != nil {
+			fmt.Printf("Unable to search notification history for %s: %v\n", videoID, err)
+		}
+		if found != nil {
+			return found.entry(channelID), ReplaySourceHistory, 0, ""
+		}
+	}
+
+	// The videos API cannot tell whether a video is in a playlist
+	if topicTypeOf(channelID) == TopicTypePlaylist {
+		return nil, "", http.StatusNotFound, "Video not found in the playlist's notification history"
+	}
+
+	youtube := NewYouTubeAPIClient()
+	youtube.Client = deps.HTTPClients.Client(httpClientYouTube, youtube.Client.Timeout)
+	if !youtube.IsConfigured() {
+		return nil, "", http.StatusNotFound,
+			"Video not found in notification history; set YOUTUBE_API_KEY to replay older videos"
+	}
+
+	entry, err := youtube.GetVideo(r.Context(), videoID)
+	if errors.Is(err, ErrVideoNotFound) {
+		return nil, "", http.StatusNotFound, "Video not found"
+	}
+	if err != nil {
+		return nil, "", http.StatusBadGateway, fmt.Sprintf("Failed to look up video: %v", err)
+	}
+	if entry.ChannelID != channelID {
+		return nil, "", http.StatusNotFound, fmt.Sprintf("Video %s does not belong to channel %s", videoID, channelID)
+	}
+	return entry, ReplaySourceYouTubeAPI, 0, ""
+}
+
+// replay delivers an entry to every target configured for its channel
+func (ns *NotificationService) replay(r *http.Request, entry *Entry) []ReplayTargetResult {
+	var results []ReplayTargetResult
+
+	if ns.GitHubClient.IsConfigured() {
+		targets := resolveRepositories(r.Context(), ns.StorageClient, entry.SubscriptionID(),
+			RepositoryTarget{RepoOwner: ns.RepoOwner, RepoName: ns.RepoName})
+		for _, target := range targets {
+			err := dispatchToRepository(r.Context(), ns.GitHubClient, target, entry)
+			ns.Metrics.RecordDispatch("github", err)
+			results = append(results, replayResult(TargetTypeGitHub, target.String(), err))
+		}
+	}
+
+	if ns.SinkClient != nil {
+		if sinks := resolveSinks(r.Context(), ns.StorageClient, entry.SubscriptionID()); len(sinks) > 0 {
+			for _, sinkResult := range ns.SinkClient.DeliverAll(r.Context(), sinks, entry) {
+				ns.Metrics.RecordDispatch("sink", sinkResult.Err)
+				results = append(results, replayResult(TargetTypeWebhook, sinkResult.URL, sinkResult.Err))
+			}
+		}
+	}
+
+	if ns.EventPublisher != nil {
+		err := ns.EventPublisher.PublishVideoEvent(r.Context(), entry)
+		ns.Metrics.RecordDispatch("pubsub", err)
+		results = append(results, replayResult(replayTargetPubSub, "video events", err))
+	}
+
+	return results
+}
+
+// replayResult describes the outcome of one replayed delivery
+func replayResult(targetType, target string, err error) ReplayTargetResult {
+	if err != nil {
+		return ReplayTargetResult{Type: targetType, Target: target, Message: err.Error()}
+	}
+	return ReplayTargetResult{Type: targetType, Target: target, Success: true, Message: "Delivered"}
+}
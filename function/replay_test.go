@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	replayTestChannel = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	replayTestVideo   = "dQw4w9WgXcQ"
+)
+
+// newYouTubeAPIServer serves videos.list for replayTestVideo in replayTestChannel
+func newYouTubeAPIServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/videos", r.URL.Path)
+		assert.Equal(t, "test-key", r.URL.Query().Get("key"))
+		if r.URL.Query().Get("id") != replayTestVideo {
+			fmt.Fprint(w, `{"items":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"items":[{"id":%q,"snippet":{"publishedAt":"2026-10-01T12:00:00Z","channelId":%q,"title":"From the API"}}]}`,
+			replayTestVideo, replayTestChannel)
+	}))
+}
+
+func postReplay(query string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/replay?"+query, nil))
+	return rec
+}
+
+func TestYouTubeAPIClient_GetVideo(t *testing.T) {
+	server := newYouTubeAPIServer(t)
+	defer server.Close()
+
+	client := &YouTubeAPIClient{APIKey: "test-key", BaseURL: server.URL, Client: server.Client()}
+
+	entry, err := client.GetVideo(context.Background(), replayTestVideo)
+	require.NoError(t, err)
+	assert.Equal(t, replayTestChannel, entry.ChannelID)
+	assert.Equal(t, "From the API", entry.Title)
+	assert.Equal(t, "2026-10-01T12:00:00Z", entry.Published)
+
+	_, err = client.GetVideo(context.Background(), "missingVid1")
+	assert.ErrorIs(t, err, ErrVideoNotFound)
+}
+
+func TestReplayEndpoint(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	ctx := context.Background()
+
+	t.Run("replays_from_history", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		publisher := deps.EventPublisher.(*MockEventPublisher)
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		history := NewNotificationHistory(deps.StorageClient, envConfig())
+		require.NoError(t, history.Append(ctx, replayTestChannel, HistoryEntry{
+			ReceivedAt: time.Now(),
+			VideoID:    replayTestVideo,
+			Title:      "Original Title",
+			Published:  "2026-10-01T12:00:00Z",
+			Updated:    "2026-10-01T12:05:00Z",
+			Decision:   HistoryDecisionDispatched,
+		}))
+
+		rec := postReplay("video_id=" + replayTestVideo + "&channel_id=" + replayTestChannel)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response ReplayResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, ReplaySourceHistory, response.Source)
+		assert.Equal(t, "Original Title", response.Title)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, TargetTypeGitHub, response.Results[0].Type)
+		assert.Equal(t, "test-owner/test-repo", response.Results[0].Target)
+
+		require.NotNil(t, gh.GetLastEntry())
+		assert.Equal(t, "2026-10-01T12:05:00Z", gh.GetLastEntry().Updated)
+		assert.Len(t, publisher.GetPublishedEntries(), 1)
+
+		entries, err := history.List(ctx, replayTestChannel, 1)
+		require.NoError(t, err)
+		assert.Equal(t, HistoryDecisionReplayed, entries[0].Decision)
+	})
+
+	t.Run("partial_failure", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.EventPublisher.(*MockEventPublisher).SetPublishError(fmt.Errorf("topic not found"))
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		require.NoError(t, NewNotificationHistory(deps.StorageClient, envConfig()).Append(ctx, replayTestChannel,
+			HistoryEntry{VideoID: replayTestVideo, Title: "Original Title"}))
+
+		rec := postReplay("video_id=" + replayTestVideo + "&channel_id=" + replayTestChannel)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response ReplayResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "partial", response.Status)
+		assert.False(t, response.Results[1].Success)
+		assert.Equal(t, "topic not found", response.Results[1].Message)
+	})
+
+	t.Run("every_delivery_failed", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+		deps.EventPublisher = nil
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		require.NoError(t, NewNotificationHistory(deps.StorageClient, envConfig()).Append(ctx, replayTestChannel,
+			HistoryEntry{VideoID: replayTestVideo}))
+
+		rec := postReplay("video_id=" + replayTestVideo + "&channel_id=" + replayTestChannel)
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+	})
+
+	t.Run("falls_back_to_youtube_api", func(t *testing.T) {
+		server := newYouTubeAPIServer(t)
+		defer server.Close()
+		os.Setenv("YOUTUBE_API_KEY", "test-key")
+		os.Setenv("YOUTUBE_API_BASE_URL", server.URL)
+		defer func() {
+			os.Unsetenv("YOUTUBE_API_KEY")
+			os.Unsetenv("YOUTUBE_API_BASE_URL")
+		}()
+
+		deps := CreateTestDependencies()
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		rec := postReplay("video_id=" + replayTestVideo + "&channel_id=" + replayTestChannel)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response ReplayResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, ReplaySourceYouTubeAPI, response.Source)
+		assert.Equal(t, "From the API", response.Title)
+
+		rec = postReplay("video_id=" + replayTestVideo + "&channel_id=UCaaaaaaaaaaaaaaaaaaaaaa")
+		assert.Equal(t, http.StatusNotFound, rec.Code, "video from another channel")
+	})
+
+	t.Run("not_in_history_without_api_key", func(t *testing.T) {
+		SetDependencies(CreateTestDependencies())
+		defer SetDependencies(nil)
+
+		rec := postReplay("video_id=" + replayTestVideo + "&channel_id=" + replayTestChannel)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "YOUTUBE_API_KEY")
+	})
+
+	t.Run("invalid_parameters", func(t *testing.T) {
+		SetDependencies(CreateTestDependencies())
+		defer SetDependencies(nil)
+
+		assert.Equal(t, http.StatusBadRequest, postReplay("video_id="+replayTestVideo+"&channel_id=bad").Code)
+		assert.Equal(t, http.StatusBadRequest, postReplay("video_id=short&channel_id="+replayTestChannel).Code)
+	})
+
+	t.Run("no_targets", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetConfigured(false)
+		deps.EventPublisher = nil
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		require.NoError(t, NewNotificationHistory(deps.StorageClient, envConfig()).Append(ctx, replayTestChannel,
+			HistoryEntry{VideoID: replayTestVideo}))
+
+		rec := postReplay("video_id=" + replayTestVideo + "&channel_id=" + replayTestChannel)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+}
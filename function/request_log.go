@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redactedQueryValue replaces query values in logged requests when REQUEST_LOG_REDACT is set
+const redactedQueryValue = "REDACTED"
+
+// RequestLogger logs one line per request: method, path, status, latency, body sizes and
+// user agent. Successful hub notifications, the bulk of the traffic, can be sampled.
+type RequestLogger struct {
+	NotificationSampleRatio float64   // Share of successful notifications logged; other requests are always logged
+	Redact                  bool      // Log route patterns instead of paths, and hide query values
+	Output                  io.Writer // Optional; nil writes to stdout
+
+	sample func() float64 // Optional; nil uses math/rand
+}
+
+// NewRequestLogger creates a request logger configured from REQUEST_LOG,
+// NOTIFICATION_LOG_SAMPLE_RATIO and REQUEST_LOG_REDACT. Returns nil (nothing is logged)
+// when REQUEST_LOG is false.
+func NewRequestLogger() *RequestLogger {
+	if os.Getenv("REQUEST_LOG") == "false" {
+		return nil
+	}
+	return &RequestLogger{
+		NotificationSampleRatio: getNotificationLogSampleRatio(),
+		Redact:                  os.Getenv("REQUEST_LOG_REDACT") == "true",
+	}
+}
+
+// getNotificationLogSampleRatio returns the share of successful notifications logged,
+// from NOTIFICATION_LOG_SAMPLE_RATIO (default 1, all of them)
+func getNotificationLogSampleRatio() float64 {
+	ratioStr := os.Getenv("NOTIFICATION_LOG_SAMPLE_RATIO")
+	if ratioStr == "" {
+		return 1
+	}
+
+	var ratio float64
+	if _, err := fmt.Sscanf(ratioStr, "%g", &ratio); err == nil && ratio >= 0 && ratio <= 1 {
+		return ratio
+	}
+	return 1
+}
+
+// start begins logging r, returning the response writer and request to serve it with
+// and a function that writes the log line once it has been served. A nil logger logs
+// nothing.
+func (l *RequestLogger) start(w http.ResponseWriter, r *http.Request, matched *route) (http.ResponseWriter, *http.Request, func()) {
+	if l == nil {
+		return w, r, func() {}
+	}
+
+	started := time.Now()
+	rec := &sizeRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+	body := &countingReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	path := l.path(r, matched)
+	finish := func() {
+		status := rec.status()
+		if !l.sampled(r, status) {
+			return
+		}
+		output := l.Output
+		if output == nil {
+			output = os.Stdout
+		}
+		fmt.Fprintf(output, "Request %s %s status=%d latency_ms=%d request_bytes=%d response_bytes=%d user_agent=%q request_id=%s\n",
+			r.Method, path, status, time.Since(started).Milliseconds(), body.n, rec.bytes,
+			r.UserAgent(), w.Header().Get(RequestIDHeader))
+	}
+	return rec, r, finish
+}
+
+// path returns the path to log for r: the path and query as requested, or with
+// redaction the route pattern and the query's parameter names only. The callback path
+// is always logged as its pattern, keeping the token out of the log.
+func (l *RequestLogger) path(r *http.Request, matched *route) string {
+	path := r.URL.Path
+	if l.Redact || secretRoute(matched) {
+		path = spanRoute(matched)
+	}
+	if !l.Redact {
+		if r.URL.RawQuery != "" {
+			return path + "?" + r.URL.RawQuery
+		}
+		return path
+	}
+
+	query := r.URL.Query()
+	if len(query) == 0 {
+		return path
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = url.QueryEscape(name) + "=" + redactedQueryValue
+	}
+	return path + "?" + strings.Join(pairs, "&")
+}
+
+// sampled reports whether a request that got status is logged. Only successful hub
+// notifications are sampled.
+func (l *RequestLogger) sampled(r *http.Request, status int) bool {
+	notification := r.Method == http.MethodPost && isHubPath(r.URL.Path)
+	if !notification || status >= http.StatusBadRequest || l.NotificationSampleRatio >= 1 {
+		return true
+	}
+	sample := l.sample
+	if sample == nil {
+		sample = rand.Float64
+	}
+	return sample() < l.NotificationSampleRatio
+}
+
+// sizeRecorder passes a response through while noting its status and size
+type sizeRecorder struct {
+	statusRecorder
+	bytes int
+}
+
+func (s *sizeRecorder) Write(p []byte) (int, error) {
+	n, err := s.statusRecorder.Write(p)
+	s.bytes += n
+	return n, err
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
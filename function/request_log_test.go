@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestLogNotification is a notification for an old video, skipped with 200 OK
+const requestLogNotification = `<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>old123</yt:videoId>
+    <yt:channelId>UCXuqSBlHAE6Xw-yeJA0Tunw</yt:channelId>
+    <title>Old Video</title>
+    <published>2020-01-01T00:00:00Z</published>
+    <updated>2020-01-01T00:00:00Z</updated>
+  </entry>
+</feed>`
+
+func TestNewRequestLogger(t *testing.T) {
+	logger := NewRequestLogger()
+	require.NotNil(t, logger)
+	assert.Equal(t, 1.0, logger.NotificationSampleRatio)
+	assert.False(t, logger.Redact)
+
+	t.Setenv("NOTIFICATION_LOG_SAMPLE_RATIO", "0.25")
+	t.Setenv("REQUEST_LOG_REDACT", "true")
+	logger = NewRequestLogger()
+	assert.Equal(t, 0.25, logger.NotificationSampleRatio)
+	assert.True(t, logger.Redact)
+
+	t.Setenv("NOTIFICATION_LOG_SAMPLE_RATIO", "2")
+	assert.Equal(t, 1.0, NewRequestLogger().NotificationSampleRatio, "out of range ratios log everything")
+
+	t.Setenv("REQUEST_LOG", "false")
+	assert.Nil(t, NewRequestLogger())
+}
+
+func TestYouTubeWebhook_RequestLog(t *testing.T) {
+	var output bytes.Buffer
+	deps := CreateTestDependencies()
+	deps.RequestLog = &RequestLogger{NotificationSampleRatio: 1, Output: &output}
+	deps.StorageClient.(*MockStorageClient).SetState(&SubscriptionState{Subscriptions: map[string]*Subscription{
+		"UCXuqSBlHAE6Xw-yeJA0Tunw": {ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive},
+	}})
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	serve := func(req *http.Request) string {
+		output.Reset()
+		YouTubeWebhook(httptest.NewRecorder(), req)
+		return output.String()
+	}
+
+	t.Run("logs_request", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/subscribe?channel_id=UC_x5XG1OV2P6uZZ5FSM9Ttw", nil)
+		req.Header.Set("User-Agent", "test-agent/1.0")
+		req.Header.Set(RequestIDHeader, "req-1")
+		line := serve(req)
+
+		assert.True(t, strings.HasPrefix(line, "Request POST /subscribe?channel_id=UC_x5XG1OV2P6uZZ5FSM9Ttw status=200 latency_ms="), line)
+		assert.Regexp(t, `response_bytes=[1-9]\d*`, line)
+		assert.Contains(t, line, `user_agent="test-agent/1.0" request_id=req-1`)
+	})
+
+	t.Run("counts_request_body", func(t *testing.T) {
+		line := serve(httptest.NewRequest("POST", "/", strings.NewReader("<feed>")))
+		assert.Contains(t, line, "status=400")
+		assert.Contains(t, line, "request_bytes=6")
+	})
+
+	t.Run("redacts", func(t *testing.T) {
+		deps.RequestLog.Redact = true
+		defer func() { deps.RequestLog.Redact = false }()
+
+		line := serve(httptest.NewRequest("GET", "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/history?limit=5&channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw", nil))
+		assert.Contains(t, line, "Request GET /subscriptions/{channel_id}/history?channel_id=REDACTED&limit=REDACTED status=")
+		assert.NotContains(t, line, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	})
+
+	t.Run("samples_successful_notifications", func(t *testing.T) {
+		deps.RequestLog.NotificationSampleRatio = 0.5
+		defer func() { deps.RequestLog.NotificationSampleRatio = 1 }()
+		next := 0.9
+		deps.RequestLog.sample = func() float64 { return next }
+		defer func() { deps.RequestLog.sample = nil }()
+
+		notification := func() *http.Request {
+			return httptest.NewRequest("POST", "/", strings.NewReader(requestLogNotification))
+		}
+		assert.Empty(t, serve(notification()), "a notification outside the sample is not logged")
+		next = 0.1
+		assert.Contains(t, serve(notification()), "Request POST / status=200")
+
+		next = 0.9
+		assert.Contains(t, serve(httptest.NewRequest("POST", "/", strings.NewReader("<feed>"))), "status=400",
+			"failed notifications are always logged")
+		assert.Contains(t, serve(httptest.NewRequest("GET", "/healthz", nil)), "status=200",
+			"other requests are always logged")
+	})
+}
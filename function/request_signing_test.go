@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const signingTestSecret = "shared-secret"
+
+// signedRequest builds a request signed with secret at signedAt
+func signedRequest(secret string, signedAt time.Time, method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	req.Header.Set(RequestTimestampHeader, timestamp)
+	req.Header.Set(RequestSignatureHeader, RequestSignature(secret, timestamp, method, signedPath(req), []byte(body)))
+	return req
+}
+
+func TestVerifyRequestSignature(t *testing.T) {
+	now := time.Now()
+	body := `{"channel_ids":["UCXuqSBlHAE6Xw-yeJA0Tunw"]}`
+
+	testCases := []struct {
+		name    string
+		request func() *http.Request
+		valid   bool
+	}{
+		{"valid", func() *http.Request {
+			return signedRequest(signingTestSecret, now, "POST", "/renew?dry_run=true", body)
+		}, true},
+		{"wrong_secret", func() *http.Request {
+			return signedRequest("other-secret", now, "POST", "/renew", body)
+		}, false},
+		{"tampered_body", func() *http.Request {
+			req := signedRequest(signingTestSecret, now, "POST", "/renew", body)
+			req.Body = io.NopCloser(strings.NewReader(`{"force":true}`))
+			return req
+		}, false},
+		{"tampered_query", func() *http.Request {
+			req := signedRequest(signingTestSecret, now, "DELETE", "/unsubscribe?channel_id=UCa", "")
+			req.URL.RawQuery = "channel_id=UCb"
+			return req
+		}, false},
+		{"other_method", func() *http.Request {
+			req := signedRequest(signingTestSecret, now, "GET", "/subscriptions", "")
+			req.Method = "DELETE"
+			return req
+		}, false},
+		{"expired", func() *http.Request {
+			return signedRequest(signingTestSecret, now.Add(-10*time.Minute), "GET", "/subscriptions", "")
+		}, false},
+		{"missing_timestamp", func() *http.Request {
+			req := signedRequest(signingTestSecret, now, "GET", "/subscriptions", "")
+			req.Header.Del(RequestTimestampHeader)
+			return req
+		}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyRequestSignature(tc.request(), signingTestSecret, now)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestSignature_RestoresBody(t *testing.T) {
+	req := signedRequest(signingTestSecret, time.Now(), "PUT", "/subscriptions/UC123/filters", `{"exclude_shorts":true}`)
+	require.NoError(t, verifyRequestSignature(req, signingTestSecret, time.Now()))
+
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"exclude_shorts":true}`, string(body))
+}
+
+func TestYouTubeWebhook_SignedRequests(t *testing.T) {
+	t.Setenv("REQUEST_SIGNING_SECRET", signingTestSecret)
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, req)
+		return rec
+	}
+
+	t.Run("signed_request_allowed", func(t *testing.T) {
+		rec := serve(signedRequest(signingTestSecret, time.Now(), "POST", "/subscribe?channel_id="+routingChannelID, ""))
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, 1, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("unsigned_request_rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(httptest.NewRequest("GET", "/subscriptions", nil)).Code)
+	})
+
+	t.Run("bad_signature_rejected", func(t *testing.T) {
+		rec := serve(signedRequest("other-secret", time.Now(), "GET", "/subscriptions", ""))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid request signature")
+	})
+
+	t.Run("api_keys_still_accepted", func(t *testing.T) {
+		t.Setenv("API_KEYS", "key-1")
+		req := httptest.NewRequest("GET", "/subscriptions", nil)
+		req.Header.Set(APIKeyHeader, "key-1")
+		assert.Equal(t, http.StatusOK, serve(req).Code)
+	})
+}
+
+func TestAuthorizeAPI_SignedCaller(t *testing.T) {
+	t.Setenv("REQUEST_SIGNING_SECRET", signingTestSecret)
+
+	caller, ok := authorizeAPI(httptest.NewRecorder(), signedRequest(signingTestSecret, time.Now(), "GET", "/audit", ""))
+	require.True(t, ok)
+	assert.Equal(t, signingCaller(signingTestSecret), caller)
+	assert.True(t, strings.HasPrefix(caller, "signed:"))
+	assert.NotContains(t, caller, signingTestSecret)
+}
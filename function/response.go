@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RequestIDHeader carries the request ID on both requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// Error codes included in the error envelope
+const (
+	ErrCodeBadRequest       = "bad_request"
+	ErrCodeInvalidXML       = "invalid_xml"
+	ErrCodeInvalidEntry     = "invalid_entry"
+	ErrCodeBodyReadFailed   = "body_read_failed"
+	ErrCodeBodyTooLarge     = "payload_too_large"
+	ErrCodeMissingChallenge = "missing_challenge"
+	ErrCodeInvalidSignature = "invalid_signature"
+	ErrCodeSourceNotAllowed = "source_not_allowed"
+	ErrCodeUnknownTopic     = "unknown_topic"
+	ErrCodeUnauthorized     = "unauthorized"
+	ErrCodeForbidden        = "forbidden"
+	ErrCodeNotFound         = "not_found"
+	ErrCodeMethodNotAllowed = "method_not_allowed"
+	ErrCodeConflict         = "conflict"
+	ErrCodeInternal         = "internal_error"
+	ErrCodeBadGateway       = "bad_gateway"
+	ErrCodeUnavailable      = "service_unavailable"
+	ErrCodeGatewayTimeout   = "gateway_timeout"
+	ErrCodeHubError         = "hub_error"
+
+	ErrCodeIdempotencyKeyReused  = "idempotency_key_reused"
+	ErrCodeIdempotencyInProgress = "idempotency_in_progress"
+)
+
+// errorCodeForStatus returns the default error code for an HTTP status
+func errorCodeForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodeBodyTooLarge
+	case http.StatusBadGateway:
+		return ErrCodeBadGateway
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrCodeGatewayTimeout
+	}
+	if statusCode >= 500 {
+		return ErrCodeInternal
+	}
+	return ErrCodeBadRequest
+}
+
+// assignRequestID picks the request ID for r and echoes it on the response.
+// Callers' X-Request-ID is honored, then the Cloud Trace ID, otherwise ids generates one.
+func assignRequestID(w http.ResponseWriter, r *http.Request, ids IDGenerator) string {
+	id := strings.TrimSpace(r.Header.Get(RequestIDHeader))
+	if id == "" || len(id) > 128 {
+		id = ""
+		if trace := r.Header.Get("X-Cloud-Trace-Context"); trace != "" {
+			id = strings.SplitN(trace, "/", 2)[0]
+		}
+	}
+	if id == "" {
+		id = ids.NewID()
+	}
+
+	w.Header().Set(RequestIDHeader, id)
+	return id
+}
+
+// wantsPlainText reports whether the client prefers a text/plain error body. Only the
+// hub paths (GET and POST / and the callback path) negotiate; every other endpoint always answers with JSON.
+// JSON is the default; text is only used when explicitly asked for and JSON is not.
+func wantsPlainText(r *http.Request) bool {
+	if r == nil || !isHubPath(r.URL.Path) {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") && !strings.Contains(accept, "application/json")
+}
+
+// writeError writes the error envelope, negotiating between JSON and plain text
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, code, channelID, message string) {
+	writeErrorDetails(w, r, statusCode, code, channelID, message, nil)
+}
+
+// writeErrorDetails writes the error envelope with machine-readable details, such as
+// the methods a path allows. Plain-text errors leave the details out.
+func writeErrorDetails(w http.ResponseWriter, r *http.Request, statusCode int, code, channelID, message string, details map[string]interface{}) {
+	if code == "" {
+		code = errorCodeForStatus(statusCode)
+	}
+	requestID := w.Header().Get(RequestIDHeader)
+
+	if wantsPlainText(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(statusCode)
+		body := fmt.Sprintf("%s: %s", code, message)
+		if requestID != "" {
+			body += fmt.Sprintf(" (request_id: %s)", requestID)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			fmt.Printf("Error writing response: %v\n", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSONResponse(w, statusCode, APIResponse{
+		Status:    "error",
+		Code:      code,
+		ChannelID: channelID,
+		Message:   message,
+		RequestID: requestID,
+		Details:   details,
+	})
+}
+
+// writeHubError writes the error envelope for a failed hub request, with the status
+// hubFailureStatus picks. When the hub answered, its status and response text are
+// included and the code is hub_error. While the hub's circuit breaker is open,
+// Retry-After says when to try again.
+func writeHubError(w http.ResponseWriter, channelID, message string, err error) {
+	statusCode := hubFailureStatus(err)
+	setRetryAfter(w, err)
+	response := APIResponse{
+		Status:    "error",
+		Code:      errorCodeForStatus(statusCode),
+		ChannelID: channelID,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+	var hubErr *HubError
+	if errors.As(err, &hubErr) {
+		response.Code = ErrCodeHubError
+		response.HubStatus = hubErr.StatusCode
+		response.HubResponse = hubErr.Body
+		response.HubRetries = hubErr.Retries
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSONResponse(w, statusCode, response)
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignRequestID(t *testing.T) {
+	t.Run("honors_incoming_header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		rec := httptest.NewRecorder()
+
+		assert.Equal(t, "abc-123", assignRequestID(rec, req, randomIDs{}))
+		assert.Equal(t, "abc-123", rec.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("falls_back_to_trace_context", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+		rec := httptest.NewRecorder()
+
+		assert.Equal(t, "105445aa7843bc8bf206b12000100000", assignRequestID(rec, req, randomIDs{}))
+	})
+
+	t.Run("generates_when_missing_or_oversized", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, strings.Repeat("x", 200))
+		rec := httptest.NewRecorder()
+
+		id := assignRequestID(rec, req, randomIDs{})
+		assert.Len(t, id, 16)
+		assert.Equal(t, id, rec.Header().Get(RequestIDHeader))
+	})
+}
+
+func TestErrorCodeForStatus(t *testing.T) {
+	testCases := map[int]string{
+		http.StatusBadRequest:          ErrCodeBadRequest,
+		http.StatusNotFound:            ErrCodeNotFound,
+		http.StatusMethodNotAllowed:    ErrCodeMethodNotAllowed,
+		http.StatusConflict:            ErrCodeConflict,
+		http.StatusBadGateway:          ErrCodeBadGateway,
+		http.StatusServiceUnavailable:  ErrCodeUnavailable,
+		http.StatusGatewayTimeout:      ErrCodeGatewayTimeout,
+		http.StatusInternalServerError: ErrCodeInternal,
+		http.StatusTeapot:              ErrCodeBadRequest,
+	}
+
+	for status, expected := range testCases {
+		assert.Equal(t, expected, errorCodeForStatus(status), "status %d", status)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	t.Run("json_by_default", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		rec.Header().Set(RequestIDHeader, "req-1")
+
+		writeError(rec, req, http.StatusNotFound, "", "UC123", "Subscription not found")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var envelope APIResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+		assert.Equal(t, "error", envelope.Status)
+		assert.Equal(t, ErrCodeNotFound, envelope.Code)
+		assert.Equal(t, "UC123", envelope.ChannelID)
+		assert.Equal(t, "Subscription not found", envelope.Message)
+		assert.Equal(t, "req-1", envelope.RequestID)
+	})
+
+	t.Run("plain_text_when_requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Accept", "text/plain")
+		rec := httptest.NewRecorder()
+		rec.Header().Set(RequestIDHeader, "req-2")
+
+		writeError(rec, req, http.StatusBadRequest, ErrCodeInvalidXML, "", "Invalid XML")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		assert.Equal(t, "invalid_xml: Invalid XML (request_id: req-2)", rec.Body.String())
+	})
+
+	t.Run("json_wins_when_both_accepted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Accept", "text/plain, application/json")
+
+		assert.False(t, wantsPlainText(req))
+		assert.False(t, wantsPlainText(nil))
+	})
+
+	t.Run("json_for_management_paths", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+		req.Header.Set("Accept", "text/plain")
+
+		assert.False(t, wantsPlainText(req))
+	})
+
+	t.Run("details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+
+		writeErrorDetails(rec, req, http.StatusBadRequest, ErrCodeInvalidXML, "", "Invalid XML",
+			map[string]interface{}{"reason": "unexpected EOF"})
+
+		var envelope APIResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+		assert.Equal(t, "unexpected EOF", envelope.Details["reason"])
+
+		req.Header.Set("Accept", "text/plain")
+		rec = httptest.NewRecorder()
+		writeErrorDetails(rec, req, http.StatusBadRequest, ErrCodeInvalidXML, "", "Invalid XML",
+			map[string]interface{}{"reason": "unexpected EOF"})
+		assert.Equal(t, "invalid_xml: Invalid XML", rec.Body.String(), "plain text leaves details out")
+	})
+}
+
+func TestYouTubeWebhook_RequestIDEcho(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "caller-id")
+	rec := httptest.NewRecorder()
+
+	YouTubeWebhook(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "caller-id", rec.Header().Get(RequestIDHeader))
+
+	var envelope APIResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+	assert.Equal(t, ErrCodeMissingChallenge, envelope.Code)
+	assert.Equal(t, "caller-id", envelope.RequestID)
+}
@@ -0,0 +1,364 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// dispatchQueueObject is where failed GitHub dispatches are stored
+const dispatchQueueObject = "retry/dispatch_queue.json"
+
+// Backoff between retries of a failed dispatch: the delay doubles after every
+// failed attempt, up to the maximum
+const (
+	dispatchRetryBaseDelay = time.Minute
+	dispatchRetryMaxDelay  = 6 * time.Hour
+)
+
+// FailedDispatch is a GitHub dispatch that failed and is waiting to be retried
+type FailedDispatch struct {
+	DispatchID    string            `json:"dispatch_id"`
+	VideoID       string            `json:"video_id"`
+	ChannelID     string            `json:"channel_id"`
+	PlaylistID    string            `json:"playlist_id,omitempty"` // Set when the video came from a playlist's feed
+	Title         string            `json:"title"`
+	Published     string            `json:"published"`
+	Updated       string            `json:"updated"`
+	RepoOwner     string            `json:"repo_owner"`
+	RepoName      string            `json:"repo_name"`
+	Mode          string            `json:"mode,omitempty"` // Route dispatch mode and workflow_dispatch settings
+	Workflow      string            `json:"workflow,omitempty"`
+	Ref           string            `json:"ref,omitempty"`
+	Inputs        map[string]string `json:"inputs,omitempty"`
+	Payload       map[string]string `json:"payload,omitempty"`
+	Attempts      int               `json:"attempts"`
+	LastError     string            `json:"last_error"`
+	FirstFailedAt time.Time         `json:"first_failed_at"`
+	LastAttemptAt time.Time         `json:"last_attempt_at"`
+	NextAttemptAt time.Time         `json:"next_attempt_at"` // Zero once dead-lettered
+}
+
+// entry rebuilds the notification entry the dispatch was made for
+func (d *FailedDispatch) entry() *Entry {
+	return &Entry{
+		VideoID:    d.VideoID,
+		ChannelID:  d.ChannelID,
+		Title:      d.Title,
+		Published:  d.Published,
+		Updated:    d.Updated,
+		PlaylistID: d.PlaylistID,
+	}
+}
+
+// target returns the repository the dispatch is sent to
+func (d *FailedDispatch) target() RepositoryTarget {
+	return RepositoryTarget{
+		RepoOwner: d.RepoOwner,
+		RepoName:  d.RepoName,
+		Mode:      d.Mode,
+		Workflow:  d.Workflow,
+		Ref:       d.Ref,
+		Inputs:    d.Inputs,
+		Payload:   d.Payload,
+	}
+}
+
+// key identifies a dispatch to one repository
+func (d *FailedDispatch) key() string {
+	return d.DispatchID + "|" + d.target().String()
+}
+
+// dispatchQueue is the stored queue: dispatches awaiting retry, and dispatches
+// that ran out of attempts
+type dispatchQueue struct {
+	Pending    []*FailedDispatch `json:"pending"`
+	DeadLetter []*FailedDispatch `json:"dead_letter"`
+}
+
+// RetryResult describes one retried dispatch
+type RetryResult struct {
+	DispatchID string `json:"dispatch_id"`
+	VideoID    string `json:"video_id"`
+	ChannelID  string `json:"channel_id"`
+	Repository string `json:"repository"`
+	Success    bool   `json:"success"`
+	Attempts   int    `json:"attempts"`
+	Message    string `json:"message"`
+}
+
+// RetrySummaryResponse summarises a pass over the retry queue
+type RetrySummaryResponse struct {
+	Status       string        `json:"status"`
+	Attempted    int           `json:"attempted"`
+	Succeeded    int           `json:"succeeded"`
+	Failed       int           `json:"failed"`
+	DeadLettered int           `json:"dead_lettered"`
+	Pending      int           `json:"pending"`     // Still queued after this pass
+	DeadLetter   int           `json:"dead_letter"` // Dispatches that ran out of attempts
+	Results      []RetryResult `json:"results"`
+}
+
+// RetryQueueResponse lists the dispatches in the retry queue
+type RetryQueueResponse struct {
+	Status     string            `json:"status"`
+	Pending    []*FailedDispatch `json:"pending"`
+	DeadLetter []*FailedDispatch `json:"dead_letter"`
+}
+
+// DispatchRetryQueue persists failed GitHub dispatches so they are retried with
+// exponential backoff instead of relying on the hub to redeliver the notification.
+// Dispatches that fail DISPATCH_RETRY_MAX_ATTEMPTS times move to a dead-letter list.
+type DispatchRetryQueue struct {
+	store       ObjectStorage
+	maxAttempts int
+	mu          sync.Mutex
+}
+
+// getDispatchRetryMaxAttempts returns how many retries a failed dispatch gets
+// before it is dead-lettered
+func getDispatchRetryMaxAttempts() int {
+	return envConfig().DispatchRetryMaxAttempts
+}
+
+// NewDispatchRetryQueue creates a retry queue in the given storage.
+// Returns nil (failed dispatches are not retried) when the storage cannot hold objects.
+func NewDispatchRetryQueue(storage StorageService, cfg *config.Config) *DispatchRetryQueue {
+	store, ok := storage.(ObjectStorage)
+	if !ok {
+		return nil
+	}
+	return &DispatchRetryQueue{store: store, maxAttempts: cfg.DispatchRetryMaxAttempts}
+}
+
+// retryBackoff returns the delay before the next retry of a dispatch that has
+// failed the given number of retries
+func retryBackoff(attempts int) time.Duration {
+	delay := dispatchRetryBaseDelay
+	for i := 0; i < attempts && delay < dispatchRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > dispatchRetryMaxDelay {
+		return dispatchRetryMaxDelay
+	}
+	return delay
+}
+
+// load reads the queue, treating a missing object as empty
+func (q *DispatchRetryQueue) load(ctx context.Context) (*dispatchQueue, error) {
+	queue := &dispatchQueue{}
+
+	data, err := q.store.ReadObject(ctx, dispatchQueueObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return queue, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dispatch retry queue: %v", err)
+	}
+	if err := json.Unmarshal(data, queue); err != nil {
+		return nil, fmt.Errorf("failed to decode dispatch retry queue: %v", err)
+	}
+	return queue, nil
+}
+
+// save writes the queue
+func (q *DispatchRetryQueue) save(ctx context.Context, queue *dispatchQueue) error {
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return fmt.Errorf("failed to encode dispatch retry queue: %v", err)
+	}
+	if err := q.store.WriteObject(ctx, dispatchQueueObject, data); err != nil {
+		return fmt.Errorf("failed to write dispatch retry queue: %v", err)
+	}
+	return nil
+}
+
+// Enqueue records a failed dispatch of entry to target for retry. A dispatch that
+// is already queued is left as it is.
+func (q *DispatchRetryQueue) Enqueue(ctx context.Context, entry *Entry, target RepositoryTarget, dispatchErr error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue, err := q.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := currentTime(ctx)
+	failed := &FailedDispatch{
+		DispatchID:    dispatchID(entry),
+		VideoID:       entry.VideoID,
+		ChannelID:     entry.ChannelID,
+		PlaylistID:    entry.PlaylistID,
+		Title:         entry.Title,
+		Published:     entry.Published,
+		Updated:       entry.Updated,
+		RepoOwner:     target.RepoOwner,
+		RepoName:      target.RepoName,
+		Mode:          target.Mode,
+		Workflow:      target.Workflow,
+		Ref:           target.Ref,
+		Inputs:        target.Inputs,
+		Payload:       target.Payload,
+		LastError:     dispatchErr.Error(),
+		FirstFailedAt: now,
+		LastAttemptAt: now,
+		NextAttemptAt: now.Add(retryBackoff(0)),
+	}
+	for _, queued := range queue.Pending {
+		if queued.key() == failed.key() {
+			return nil
+		}
+	}
+	queue.Pending = append(queue.Pending, failed)
+	return q.save(ctx, queue)
+}
+
+// List returns the queued and dead-lettered dispatches
+func (q *DispatchRetryQueue) List(ctx context.Context) (*RetryQueueResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue, err := q.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := &RetryQueueResponse{
+		Status:     "success",
+		Pending:    queue.Pending,
+		DeadLetter: queue.DeadLetter,
+	}
+	if response.Pending == nil {
+		response.Pending = []*FailedDispatch{}
+	}
+	if response.DeadLetter == nil {
+		response.DeadLetter = []*FailedDispatch{}
+	}
+	return response, nil
+}
+
+// RetryDue retries every queued dispatch whose backoff has elapsed. With all set,
+// every queued dispatch is retried immediately and dead-lettered dispatches are
+// returned to the queue with a fresh attempt budget first.
+func (q *DispatchRetryQueue) RetryDue(ctx context.Context, github GitHubClientInterface, metrics *Metrics, all bool) (*RetrySummaryResponse, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue, err := q.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if all {
+		for _, dead := range queue.DeadLetter {
+			dead.Attempts = 0
+			queue.Pending = append(queue.Pending, dead)
+		}
+		queue.DeadLetter = nil
+	}
+
+	now := currentTime(ctx)
+	summary := &RetrySummaryResponse{Status: "success", Results: []RetryResult{}}
+	var pending []*FailedDispatch
+	for _, dispatch := range queue.Pending {
+		if !all && now.Before(dispatch.NextAttemptAt) {
+			pending = append(pending, dispatch)
+			continue
+		}
+
+		summary.Attempted++
+		dispatch.Attempts++
+		dispatch.LastAttemptAt = now
+		target := dispatch.target()
+		err := dispatchToRepository(ctx, github, target, dispatch.entry())
+		metrics.RecordDispatch("github", err)
+
+		result := RetryResult{
+			DispatchID: dispatch.DispatchID,
+			VideoID:    dispatch.VideoID,
+			ChannelID:  dispatch.ChannelID,
+			Repository: target.String(),
+			Attempts:   dispatch.Attempts,
+		}
+		switch {
+		case err == nil:
+			summary.Succeeded++
+			result.Success = true
+			result.Message = "Dispatch retried successfully"
+		case dispatch.Attempts >= q.maxAttempts:
+			summary.Failed++
+			summary.DeadLettered++
+			dispatch.LastError = err.Error()
+			dispatch.NextAttemptAt = time.Time{}
+			queue.DeadLetter = append(queue.DeadLetter, dispatch)
+			result.Message = fmt.Sprintf("Moved to dead letter after %d attempts: %v", dispatch.Attempts, err)
+		default:
+			summary.Failed++
+			dispatch.LastError = err.Error()
+			dispatch.NextAttemptAt = now.Add(retryBackoff(dispatch.Attempts))
+			pending = append(pending, dispatch)
+			result.Message = fmt.Sprintf("Retry failed, next attempt at %s: %v",
+				dispatch.NextAttemptAt.Format(time.RFC3339), err)
+		}
+		summary.Results = append(summary.Results, result)
+	}
+	queue.Pending = pending
+
+	summary.Pending = len(queue.Pending)
+	summary.DeadLetter = len(queue.DeadLetter)
+	if summary.Attempted == 0 && !all {
+		return summary, nil
+	}
+	if err := q.save(ctx, queue); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// handleRetryDispatches handles POST /retry requests using dependency injection.
+// Queued dispatches whose backoff has elapsed are retried; ?all=true retries every
+// queued and dead-lettered dispatch immediately.
+func handleRetryDispatches(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queue := NewDispatchRetryQueue(deps.StorageClient, deps.config())
+		if queue == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "", "Storage backend does not support the dispatch retry queue")
+			return
+		}
+		if !deps.GitHubClient.IsConfigured() {
+			writeErrorResponse(w, http.StatusServiceUnavailable, "", "GitHub token not configured")
+			return
+		}
+
+		summary, err := queue.RetryDue(r.Context(), deps.GitHubClient, deps.Metrics, r.URL.Query().Get("all") == "true")
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, summary)
+	}
+}
+
+// handleGetRetryQueue handles GET /retry requests using dependency injection
+func handleGetRetryQueue(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queue := NewDispatchRetryQueue(deps.StorageClient, deps.config())
+		if queue == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "", "Storage backend does not support the dispatch retry queue")
+			return
+		}
+
+		response, err := queue.List(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRetryTestEntry(videoID string) *Entry {
+	return &Entry{
+		VideoID:   videoID,
+		ChannelID: routingChannelID,
+		Title:     "Retry Video",
+		Published: "2025-01-21T12:00:00Z",
+		Updated:   "2025-01-21T12:05:00Z",
+	}
+}
+
+// readRetryQueue returns the stored queue
+func readRetryQueue(t *testing.T, storage *MockStorageClient) *dispatchQueue {
+	data, err := storage.ReadObject(context.Background(), dispatchQueueObject)
+	require.NoError(t, err)
+	var queue dispatchQueue
+	require.NoError(t, json.Unmarshal(data, &queue))
+	return &queue
+}
+
+// makeRetriesDue moves every queued dispatch's next attempt into the past
+func makeRetriesDue(t *testing.T, storage *MockStorageClient) {
+	queue := readRetryQueue(t, storage)
+	for _, dispatch := range queue.Pending {
+		dispatch.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+	data, err := json.Marshal(queue)
+	require.NoError(t, err)
+	require.NoError(t, storage.WriteObject(context.Background(), dispatchQueueObject, data))
+}
+
+func TestGetDispatchRetryMaxAttempts(t *testing.T) {
+	defer os.Unsetenv("DISPATCH_RETRY_MAX_ATTEMPTS")
+
+	testCases := []struct {
+		value    string
+		expected int
+	}{
+		{"", 5},
+		{"3", 3},
+		{"0", 5},
+		{"invalid", 5},
+	}
+
+	for _, tc := range testCases {
+		os.Setenv("DISPATCH_RETRY_MAX_ATTEMPTS", tc.value)
+		assert.Equal(t, tc.expected, getDispatchRetryMaxAttempts(), "DISPATCH_RETRY_MAX_ATTEMPTS=%q", tc.value)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, retryBackoff(0))
+	assert.Equal(t, 2*time.Minute, retryBackoff(1))
+	assert.Equal(t, 8*time.Minute, retryBackoff(3))
+	assert.Equal(t, dispatchRetryMaxDelay, retryBackoff(20))
+}
+
+func TestDispatchRetryQueue(t *testing.T) {
+	ctx := context.Background()
+	target := RepositoryTarget{RepoOwner: "test-owner", RepoName: "test-repo"}
+
+	t.Run("enqueue_skips_duplicates", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		queue := NewDispatchRetryQueue(storage, envConfig())
+		entry := newRetryTestEntry("retry1")
+
+		require.NoError(t, queue.Enqueue(ctx, entry, target, fmt.Errorf("status 502")))
+		require.NoError(t, queue.Enqueue(ctx, entry, target, fmt.Errorf("status 503")))
+		require.NoError(t, queue.Enqueue(ctx, entry, RepositoryTarget{RepoOwner: "other", RepoName: "repo"}, fmt.Errorf("status 502")))
+
+		stored := readRetryQueue(t, storage)
+		require.Len(t, stored.Pending, 2)
+		assert.Equal(t, "status 502", stored.Pending[0].LastError)
+		assert.Equal(t, dispatchID(entry), stored.Pending[0].DispatchID)
+		assert.True(t, stored.Pending[0].NextAttemptAt.After(time.Now()))
+	})
+
+	t.Run("only_due_dispatches_are_retried", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		queue := NewDispatchRetryQueue(storage, envConfig())
+		gh := NewMockGitHubClient()
+		require.NoError(t, queue.Enqueue(ctx, newRetryTestEntry("retry1"), target, fmt.Errorf("status 502")))
+
+		summary, err := queue.RetryDue(ctx, gh, NewMetrics(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 0, summary.Attempted)
+		assert.Equal(t, 1, summary.Pending)
+
+		makeRetriesDue(t, storage)
+		summary, err = queue.RetryDue(ctx, gh, NewMetrics(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Succeeded)
+		assert.Equal(t, 0, summary.Pending)
+		assert.Equal(t, "retry1", gh.GetLastEntry().VideoID)
+		assert.Equal(t, []string{"test-owner/test-repo"}, gh.GetTriggeredRepositories())
+	})
+
+	t.Run("workflow_dispatch_routes_are_retried_as_configured", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		queue := NewDispatchRetryQueue(storage, envConfig())
+		gh := NewMockGitHubClient()
+		workflowTarget := RepositoryTarget{RepoOwner: "test-owner", RepoName: "test-repo", Mode: DispatchModeWorkflow,
+			Workflow: "publish.yml", Ref: "main", Inputs: map[string]string{"video": "video_id"}}
+		require.NoError(t, queue.Enqueue(ctx, newRetryTestEntry("retry1"), workflowTarget, fmt.Errorf("status 502")))
+
+		makeRetriesDue(t, storage)
+		summary, err := queue.RetryDue(ctx, gh, NewMetrics(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Succeeded)
+		assert.Equal(t, "test-owner/test-repo:publish.yml", summary.Results[0].Repository)
+		assert.Equal(t, 1, gh.GetWorkflowCallCount())
+		_, _, inputs := gh.GetLastWorkflowDispatch()
+		assert.Equal(t, map[string]string{"video": "retry1"}, inputs)
+	})
+
+	t.Run("repository_dispatch_ref_and_payload_are_kept", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		queue := NewDispatchRetryQueue(storage, envConfig())
+		gh := NewMockGitHubClient()
+		stagingTarget := RepositoryTarget{RepoOwner: "test-owner", RepoName: "test-repo", Ref: "staging",
+			Payload: map[string]string{"stage": "staging"}}
+		require.NoError(t, queue.Enqueue(ctx, newRetryTestEntry("retry1"), stagingTarget, fmt.Errorf("status 502")))
+
+		makeRetriesDue(t, storage)
+		summary, err := queue.RetryDue(ctx, gh, NewMetrics(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Succeeded)
+		assert.Equal(t, stagingTarget.clientPayloadExtra(), gh.GetLastExtra())
+	})
+
+	t.Run("failures_back_off_then_dead_letter", func(t *testing.T) {
+		os.Setenv("DISPATCH_RETRY_MAX_ATTEMPTS", "2")
+		defer os.Unsetenv("DISPATCH_RETRY_MAX_ATTEMPTS")
+
+		storage := NewMockStorageClient()
+		queue := NewDispatchRetryQueue(storage, envConfig())
+		gh := NewMockGitHubClient()
+		gh.SetTriggerError(fmt.Errorf("GitHub API returned status 502"))
+		require.NoError(t, queue.Enqueue(ctx, newRetryTestEntry("retry1"), target, fmt.Errorf("status 502")))
+
+		makeRetriesDue(t, storage)
+		summary, err := queue.RetryDue(ctx, gh, NewMetrics(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Failed)
+		assert.Equal(t, 1, summary.Pending)
+		stored := readRetryQueue(t, storage)
+		assert.Equal(t, 1, stored.Pending[0].Attempts)
+		assert.True(t, stored.Pending[0].NextAttemptAt.After(time.Now().Add(time.Minute)), "backoff doubles")
+
+		makeRetriesDue(t, storage)
+		summary, err = queue.RetryDue(ctx, gh, NewMetrics(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.DeadLettered)
+		assert.Equal(t, 0, summary.Pending)
+		assert.Equal(t, 1, summary.DeadLetter)
+		assert.Contains(t, summary.Results[0].Message, "Moved to dead letter after 2 attempts")
+	})
+
+	t.Run("all_retries_dead_letters", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		queue := NewDispatchRetryQueue(storage, envConfig())
+		gh := NewMockGitHubClient()
+		data, err := json.Marshal(dispatchQueue{DeadLetter: []*FailedDispatch{{
+			DispatchID: "abc", VideoID: "dead1", ChannelID: routingChannelID, RepoOwner: "o", RepoName: "r", Attempts: 5,
+		}}})
+		require.NoError(t, err)
+		require.NoError(t, storage.WriteObject(ctx, dispatchQueueObject, data))
+
+		summary, err := queue.RetryDue(ctx, gh, NewMetrics(), true)
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Succeeded)
+		assert.Equal(t, 0, summary.DeadLetter)
+		assert.Equal(t, 1, summary.Results[0].Attempts, "dead letters get a fresh attempt budget")
+	})
+}
+
+func TestRetryEndpoints(t *testing.T) {
+	deps := CreateTestDependencies()
+	storage := deps.StorageClient.(*MockStorageClient)
+	gh := deps.GitHubClient.(*MockGitHubClient)
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	require.NoError(t, NewDispatchRetryQueue(storage, envConfig()).Enqueue(context.Background(), newRetryTestEntry("retry1"),
+		RepositoryTarget{RepoOwner: "test-owner", RepoName: "test-repo"}, fmt.Errorf("status 502")))
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/retry", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listing RetryQueueResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing))
+	require.Len(t, listing.Pending, 1)
+	assert.Equal(t, "retry1", listing.Pending[0].VideoID)
+	assert.Empty(t, listing.DeadLetter)
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/retry", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var summary RetrySummaryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, 0, summary.Attempted, "backoff has not elapsed")
+
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/retry?all=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Succeeded)
+	assert.Equal(t, 1, gh.GetTriggerCallCount())
+
+	gh.SetConfigured(false)
+	rec = httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/retry", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestHandleNotification_RetriesDueDispatches(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, routingChannelID)
+	storage := deps.StorageClient.(*MockStorageClient)
+	gh := deps.GitHubClient.(*MockGitHubClient)
+	require.NoError(t, NewDispatchRetryQueue(storage, envConfig()).Enqueue(context.Background(), newRetryTestEntry("earlier1"),
+		RepositoryTarget{RepoOwner: "test-owner", RepoName: "test-repo"}, fmt.Errorf("status 502")))
+	makeRetriesDue(t, storage)
+
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>next1</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Next Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, routingChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	rec := httptest.NewRecorder()
+	handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, gh.GetTriggerCallCount(), "queued dispatch retried alongside the new video")
+	assert.Empty(t, readRetryQueue(t, storage).Pending)
+}
@@ -1,56 +1,212 @@
 package webhook
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 )
 
+// pathParams holds the values of a route's "{name}" path segments
+type pathParams map[string]string
+
+// routeHandler builds the handler for a matched route
+type routeHandler func(deps *Dependencies, params pathParams) http.HandlerFunc
+
+// route maps a method and path pattern to a handler. Patterns omit the leading slash;
+// a "{name}" segment matches any single path segment.
+type route struct {
+	method  string
+	pattern string
+	handler routeHandler
+}
+
+// withDeps adapts a handler that takes no path parameters
+func withDeps(handler func(*Dependencies) http.HandlerFunc) routeHandler {
+	return func(deps *Dependencies, _ pathParams) http.HandlerFunc {
+		return handler(deps)
+	}
+}
+
+// withChannel adapts a handler for a route with a "{channel_id}" segment
+func withChannel(handler func(*Dependencies, string) http.HandlerFunc) routeHandler {
+	return func(deps *Dependencies, params pathParams) http.HandlerFunc {
+		return handler(deps, params["channel_id"])
+	}
+}
+
+// withDeadLetter adapts a handler for a route with an "{id}" dead-letter segment
+func withDeadLetter(handler func(*Dependencies, string) http.HandlerFunc) routeHandler {
+	return func(deps *Dependencies, params pathParams) http.HandlerFunc {
+		return handler(deps, params["id"])
+	}
+}
+
+// withBackup adapts a handler for a route with a "{name}" state backup segment
+func withBackup(handler func(*Dependencies, string) http.HandlerFunc) routeHandler {
+	return func(deps *Dependencies, params pathParams) http.HandlerFunc {
+		return handler(deps, params["name"])
+	}
+}
+
+// routes lists every endpoint served by YouTubeWebhook. Hub verification challenges
+// and notifications are delivered to the root path.
+var routes = []route{
+	{http.MethodGet, "", hubRoot(handleVerificationChallenge)},
+	{http.MethodPost, "", hubRoot(handleNotification)},
+	{http.MethodGet, "notify/{token}", withCallbackToken(handleVerificationChallenge)},
+	{http.MethodPost, "notify/{token}", withCallbackToken(handleNotification)},
+	{http.MethodPost, "subscribe", audited(withDeps(idempotent(handleSubscribe)))},
+	{http.MethodDelete, "unsubscribe", audited(withDeps(idempotent(handleUnsubscribe)))},
+	{http.MethodGet, "subscriptions", withDeps(handleGetSubscriptions)},
+	{http.MethodGet, "subscriptions/expiring", withDeps(handleGetExpiringSubscriptions)},
+	{http.MethodGet, "subscriptions/{channel_id}", withChannel(handleGetSubscription)},
+	{http.MethodPut, "subscriptions/{channel_id}/repositories", audited(withChannel(handleSetRepositories))},
+	{http.MethodPut, "subscriptions/{channel_id}/sinks", audited(withChannel(handleSetSinks))},
+	{http.MethodGet, "subscriptions/{channel_id}/filters", withChannel(handleGetFilter)},
+	{http.MethodPut, "subscriptions/{channel_id}/filters", audited(withChannel(handleSetFilter))},
+	{http.MethodDelete, "subscriptions/{channel_id}/filters", audited(withChannel(handleDeleteFilter))},
+	{http.MethodGet, "subscriptions/{channel_id}/config", withChannel(handleGetChannelConfig)},
+	{http.MethodPatch, "subscriptions/{channel_id}/config", audited(withChannel(handlePatchChannelConfig))},
+	{http.MethodPost, "subscriptions/{channel_id}/reactivate", audited(withChannel(handleReactivateSubscription))},
+	{http.MethodPost, "subscriptions/{channel_id}/restore", audited(withChannel(handleRestoreSubscription))},
+	{http.MethodGet, "subscriptions/{channel_id}/history", withChannel(handleGetHistory)},
+	{http.MethodGet, "events", withDeps(handleGetEvents)},
+	{http.MethodPost, "renew", audited(withDeps(handleRenewSubscriptions))},
+	{http.MethodGet, "renew/report", withDeps(handleGetRenewalReport)},
+	{http.MethodPost, "sweep", audited(withDeps(handleSweepSubscriptions))},
+	{http.MethodPost, "retry", withDeps(handleRetryDispatches)},
+	{http.MethodGet, "retry", withDeps(handleGetRetryQueue)},
+	{http.MethodPost, "premieres", withDeps(handleCheckPremieres)},
+	{http.MethodGet, "premieres", withDeps(handleGetPremieres)},
+	{http.MethodPost, "outbox", withDeps(handleReconcileOutbox)},
+	{http.MethodGet, "outbox", withDeps(handleGetOutbox)},
+	{http.MethodPost, "replay", withDeps(handleReplayVideo)},
+	{http.MethodPost, "targets/test", withDeps(handleTestTarget)},
+	{http.MethodGet, "deadletters", withDeps(handleListDeadLetters)},
+	{http.MethodGet, "deadletters/{id}", withDeadLetter(handleGetDeadLetter)},
+	{http.MethodPost, "deadletters/{id}/replay", withDeadLetter(handleReplayDeadLetter)},
+	{http.MethodGet, "stats", withDeps(handleGetStats)},
+	{http.MethodGet, "metrics", withDeps(handleGetMetrics)},
+	{http.MethodGet, "healthz", withDeps(handleHealthz)},
+	{http.MethodGet, "readyz", withDeps(handleReadyz)},
+	{http.MethodGet, "config", withDeps(handleGetConfig)},
+	{http.MethodGet, "diagnostics", withDeps(handleGetDiagnostics)},
+	{http.MethodGet, "audit", withDeps(handleGetAudit)},
+	{http.MethodGet, "openapi.yaml", withDeps(handleGetOpenAPISpec)},
+	{http.MethodGet, "debug/notifications", func(deps *Dependencies, _ pathParams) http.HandlerFunc {
+		return handleGetDebugNotifications(deps, getDebugBuffer())
+	}},
+	{http.MethodGet, "admin/state", withDeps(handleExportState)},
+	{http.MethodPut, "admin/state", withDeps(handleImportState)},
+	{http.MethodGet, "admin/backups", withDeps(handleListStateBackups)},
+	{http.MethodPost, "admin/backups/{name}/restore", withBackup(handleRestoreStateBackup)},
+}
+
+// matchPattern reports whether path matches pattern, returning the path parameters
+func matchPattern(pattern, path string) (pathParams, bool) {
+	patternSegments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(path, "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := pathParams{}
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// matchRoute finds the route for method and path. When the path is known but the
+// method is not, it returns the methods the path allows instead.
+func matchRoute(method, path string) (*route, pathParams, []string) {
+	var allowed []string
+	for i := range routes {
+		params, ok := matchPattern(routes[i].pattern, path)
+		if !ok {
+			continue
+		}
+		if routes[i].method == method {
+			return &routes[i], params, nil
+		}
+		allowed = append(allowed, routes[i].method)
+	}
+	return nil, nil, allowed
+}
+
 // YouTubeWebhook handles YouTube PubSubHubbub notifications and subscription management
 // using dependency injection instead of global state
 func YouTubeWebhook(w http.ResponseWriter, r *http.Request) {
+	instanceStart.Do(startInstance)
+
 	// Set CORS headers for all requests
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+APIKeyHeader+", "+
+		RequestSignatureHeader+", "+RequestTimestampHeader+", "+RequestIDHeader)
+	w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
 
-	// Get dependencies for this request
-	deps := GetDependencies()
+	// Get dependencies for this request, with any overrides its context carries
+	deps := GetDependencies().WithOverrides(requestOverrides(r.Context())...)
+	assignRequestID(w, r, deps.ids())
+
+	// Bound the request by the function timeout, so downstream calls fail fast rather
+	// than the platform killing the invocation mid-call. The context also carries the
+	// clock and ID generator, for the stores the request reaches.
+	ctx, cancel := withRequestBudget(deps.withClock(r.Context()))
+	defer cancel()
+	r = r.WithContext(ctx)
 
 	// Route based on path and method
 	path := strings.TrimPrefix(r.URL.Path, "/")
+	matched, params, allowed := matchRoute(r.Method, path)
+
+	// Log the request once it has been served
+	w, r, logged := deps.RequestLog.start(w, r, matched)
+	defer logged()
+
+	// Trace the request, and its storage, hub and GitHub calls, when a tracer is configured
+	deps, w, r, finish := traceRequest(deps, w, r, spanRoute(matched))
+	defer finish()
+
+	// Answer in JSON, or YAML when the Accept header prefers it
+	w = withResponseFormat(w, r)
+
+	if matched == nil {
+		if len(allowed) == 0 {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "", "Endpoint not found")
+			return
+		}
+		w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+		if r.Method == http.MethodOptions {
+			// CORS preflight request
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		writeErrorDetails(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "", "Method not allowed",
+			map[string]interface{}{"allowed_methods": append(allowed, http.MethodOptions)})
+		return
+	}
 
-	switch {
-	case path == "subscribe" && r.Method == http.MethodPost:
-		handler := handleSubscribe(deps)
-		handler(w, r)
-	case path == "unsubscribe" && r.Method == http.MethodDelete:
-		handler := handleUnsubscribe(deps)
-		handler(w, r)
-	case path == "subscriptions" && r.Method == http.MethodGet:
-		handler := handleGetSubscriptions(deps)
-		handler(w, r)
-	case path == "renew" && r.Method == http.MethodPost:
-		handler := handleRenewSubscriptions(deps)
-		handler(w, r)
-	case r.Method == http.MethodGet:
-		// Default GET behavior - YouTube verification challenge
-		handleVerificationChallenge(w, r)
-	case r.Method == http.MethodPost:
-		// Default POST behavior - YouTube notifications
-		handler := handleNotification(deps)
-		handler(w, r)
-	case r.Method == http.MethodOptions:
-		// CORS preflight request
-		w.WriteHeader(http.StatusOK)
-	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		if _, err := w.Write([]byte("Method not allowed")); err != nil {
-			fmt.Printf("Error writing response: %v\n", err)
+	// Management endpoints require API credentials when API_KEYS, OIDC_AUDIENCE or
+	// REQUEST_SIGNING_SECRET is set; hub verification and notifications stay open
+	if isManagementPath(path) {
+		caller, ok := authorizeAPI(w, r)
+		if !ok {
+			return
 		}
+		r = r.WithContext(withCaller(r.Context(), caller))
 	}
+
+	matched.handler(deps, params)(w, r)
 }
 
 // handleGetSubscriptions handles GET /subscriptions requests using dependency injection
@@ -58,6 +214,12 @@ func handleGetSubscriptions(deps *Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		query, err := parseSubscriptionsQuery(r.URL.Query())
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "", err.Error())
+			return
+		}
+
 		// Load subscription state from injected storage client
 		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
 		if err != nil {
@@ -67,49 +229,115 @@ func handleGetSubscriptions(deps *Dependencies) http.HandlerFunc {
 		}
 
 		// Calculate expiry status and statistics (same logic as original)
-		now := getCurrentTime()
-		subscriptions := make([]SubscriptionInfo, 0)
-		total := 0
-		active := 0
-		expired := 0
+		now := deps.now()
+		thresholds := healthThresholds(deps.config())
+		response := SubscriptionsListResponse{
+			Subscriptions: make([]SubscriptionInfo, 0),
+		}
 
 		for _, sub := range state.Subscriptions {
-			total++
+			info := buildSubscriptionInfo(sub, now, thresholds, deps.config().RenewalThreshold)
+			response.Total++
 
-			status := "active"
-			daysUntilExpiry := sub.ExpiresAt.Sub(now).Hours() / 24
+			switch info.Status {
+			case StatusExpired:
+				response.Expired++
+			case StatusPending:
+				response.Pending++
+			case StatusQuarantined:
+				response.Quarantined++
+			default:
+				response.Active++
+			}
 
-			if sub.ExpiresAt.Before(now) {
-				status = "expired"
-				expired++
-			} else {
-				active++
+			switch info.Indicator {
+			case HealthHealthy:
+				response.Healthy++
+			case HealthStale:
+				response.Stale++
+			case HealthBroken:
+				response.Broken++
 			}
 
-			subscriptions = append(subscriptions, SubscriptionInfo{
-				ChannelID:       sub.ChannelID,
-				Status:          status,
-				ExpiresAt:       sub.ExpiresAt.Format(timeFormat()),
-				DaysUntilExpiry: daysUntilExpiry,
-			})
+			if query.Matches(info.Status) {
+				response.Subscriptions = append(response.Subscriptions, info)
+			}
 		}
 
-		response := SubscriptionsListResponse{
-			Subscriptions: subscriptions,
-			Total:         total,
-			Active:        active,
-			Expired:       expired,
+		// Summary counts cover every subscription; the list is filtered and paginated
+		query.Sort(response.Subscriptions)
+		response.Subscriptions, response.NextPageToken = query.Page(response.Subscriptions)
+
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+// handleGetSubscription handles GET /subscriptions/{channel_id} requests using dependency injection
+func handleGetSubscription(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		sub, err := deps.StorageClient.GetSubscription(r.Context(), channelID)
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, channelID, "Subscription not found for this channel")
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Unable to load subscription state from storage: %v", err))
+			return
+		}
+
+		// Never expose the hub secret
+		redacted := *sub
+		redacted.Secret = ""
+		redacted.Sinks = redactSinks(sub.Sinks)
+
+		response := SubscriptionDetailResponse{
+			SubscriptionInfo: buildSubscriptionInfo(sub, deps.now(), healthThresholds(deps.config()), deps.config().RenewalThreshold),
+			Subscription:     &redacted,
 		}
 		writeJSONResponse(w, http.StatusOK, response)
 	}
 }
 
+// buildSubscriptionInfo summarises a subscription for API responses, treating it as
+// expiring within the renewal threshold
+func buildSubscriptionInfo(sub *Subscription, now time.Time, thresholds HealthThresholds, threshold time.Duration) SubscriptionInfo {
+	var daysUntilExpiry float64
+	if !sub.ExpiresAt.IsZero() {
+		daysUntilExpiry = sub.ExpiresAt.Sub(now).Hours() / 24
+	}
 
-// Helper functions to make the code more testable by abstracting time and formats
+	return SubscriptionInfo{
+		ChannelID:          sub.ChannelID,
+		ChannelName:        sub.ChannelName,
+		TopicType:          sub.Type(),
+		Status:             subscriptionStatus(sub, now, threshold),
+		ExpiresAt:          formatOptionalTime(sub.ExpiresAt),
+		DaysUntilExpiry:    daysUntilExpiry,
+		LeaseSeconds:       sub.LeaseSeconds,
+		RenewalAttempts:    sub.RenewalAttempts,
+		SubscribedAt:       formatOptionalTime(sub.SubscribedAt),
+		LastRenewal:        formatOptionalTime(sub.LastRenewal),
+		LastVideoID:        sub.LastVideoID,
+		LastRunURL:         sub.LastRunURL,
+		NextRenewalAt:      formatOptionalTime(nextRenewalAt(sub, threshold)),
+		HubURL:             sub.HubURL,
+		SubscriptionHealth: computeSubscriptionHealth(sub, now, thresholds),
+	}
+}
 
-// getCurrentTime returns the current time (can be mocked in tests)
-func getCurrentTime() time.Time {
-	return time.Now()
+
+// formatOptionalTime formats t, or returns "" for the zero time
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(timeFormat())
 }
 
 // timeFormat returns the time format to use (can be customized if needed)
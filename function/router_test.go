@@ -2,6 +2,7 @@ package webhook
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -105,8 +106,11 @@ func TestYouTubeWebhook_RenewSubscriptions(t *testing.T) {
 }
 
 func TestYouTubeWebhook_VerificationChallenge(t *testing.T) {
+	SetDependencies(newVerificationTestDeps())
+	defer SetDependencies(nil)
+
 	// Create test request with challenge parameter
-	req := httptest.NewRequest("GET", "/?hub.challenge=test-challenge-123", nil)
+	req := verificationRequest("test-challenge-123", "subscribe", verificationTestChannel, "")
 	rec := httptest.NewRecorder()
 
 	// Call refactored router
@@ -125,6 +129,11 @@ func TestYouTubeWebhook_VerificationChallenge(t *testing.T) {
 }
 
 func TestYouTubeWebhook_Notification(t *testing.T) {
+	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, "UC1234567890123456789012")
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
 	// Set environment variables for GitHub integration
 	os.Setenv("REPO_OWNER", "test-owner")
 	os.Setenv("REPO_NAME", "test-repo")
@@ -144,7 +153,7 @@ func TestYouTubeWebhook_Notification(t *testing.T) {
   <entry>
     <id>yt:video:test123</id>
     <yt:videoId>test123</yt:videoId>
-    <yt:channelId>UC123456789012345678901</yt:channelId>
+    <yt:channelId>UC1234567890123456789012</yt:channelId>
     <title>Test Video</title>
     <published>%s</published>
     <updated>%s</updated>
@@ -192,9 +201,72 @@ func TestYouTubeWebhook_MethodNotAllowed(t *testing.T) {
 	}
 
 	// Check response body
-	body := rec.Body.String()
-	if body != "Method not allowed" {
-		t.Errorf("Expected 'Method not allowed', got: %s", body)
+	var envelope APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("Expected JSON error envelope, got: %s", rec.Body.String())
+	}
+	if envelope.Code != ErrCodeMethodNotAllowed || envelope.Message != "Method not allowed" {
+		t.Errorf("Expected method_not_allowed envelope, got: %s", rec.Body.String())
+	}
+	if methods, _ := envelope.Details["allowed_methods"].([]interface{}); len(methods) != 3 {
+		t.Errorf("Expected GET, POST and OPTIONS in details, got: %s", rec.Body.String())
+	}
+	if envelope.RequestID == "" || envelope.RequestID != rec.Header().Get(RequestIDHeader) {
+		t.Errorf("Expected request ID in body to match header, got: %s", rec.Body.String())
+	}
+}
+
+func TestYouTubeWebhook_RouteMatching(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{"post_unsubscribe", "POST", "/unsubscribe?channel_id=UCabcdefghijklmnopqrstuv", http.StatusMethodNotAllowed, "DELETE, OPTIONS"},
+		{"get_subscribe", "GET", "/subscribe", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{"delete_retry", "DELETE", "/retry", http.StatusMethodNotAllowed, "POST, GET, OPTIONS"},
+		{"post_filters", "POST", "/subscriptions/UCabcdefghijklmnopqrstuv/filters", http.StatusMethodNotAllowed, "GET, PUT, DELETE, OPTIONS"},
+		{"get_unknown_path", "GET", "/unknown", http.StatusNotFound, ""},
+		{"post_unknown_path", "POST", "/unknown", http.StatusNotFound, ""},
+		{"unknown_subpath", "GET", "/subscriptions/UCabcdefghijklmnopqrstuv/unknown", http.StatusNotFound, ""},
+		{"options_known_path", "OPTIONS", "/renew", http.StatusOK, "POST, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			YouTubeWebhook(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if allow := rec.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("Expected Allow %q, got %q", tt.expectedAllow, allow)
+			}
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	params, ok := matchPattern("subscriptions/{channel_id}/filters", "subscriptions/UC123/filters")
+	if !ok || params["channel_id"] != "UC123" {
+		t.Errorf("Expected match with channel_id UC123, got %v %v", ok, params)
+	}
+
+	for _, path := range []string{"subscriptions/UC123", "subscriptions/UC123/sinks", "subscriptions/UC123/filters/extra"} {
+		if _, ok := matchPattern("subscriptions/{channel_id}/filters", path); ok {
+			t.Errorf("Expected %q not to match", path)
+		}
+	}
+
+	if _, ok := matchPattern("", ""); !ok {
+		t.Error("Expected root pattern to match the root path")
 	}
 }
 
@@ -211,7 +283,7 @@ func TestYouTubeWebhook_CORSHeaders(t *testing.T) {
 		t.Errorf("Expected CORS origin header to be '*', got: %s", rec.Header().Get("Access-Control-Allow-Origin"))
 	}
 
-	if rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST, DELETE, OPTIONS" {
+	if rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, PATCH, DELETE, OPTIONS" {
 		t.Errorf("Expected CORS methods header, got: %s", rec.Header().Get("Access-Control-Allow-Methods"))
 	}
 
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxRepositoriesPerChannel caps how many workflows one notification can fan out to
+const maxRepositoriesPerChannel = 10
+
+// repoNamePattern matches GitHub owner and repository names
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// RepositoryTarget is a GitHub repository that receives a dispatch for each new video.
+// By default a repository_dispatch event is sent; in workflow_dispatch mode the named
+// workflow is run on Ref with its inputs mapped from video fields.
+type RepositoryTarget struct {
+	RepoOwner string            `json:"repo_owner"`
+	RepoName  string            `json:"repo_name"`
+	Mode      string            `json:"mode,omitempty"`     // repository_dispatch (default) or workflow_dispatch
+	Workflow  string            `json:"workflow,omitempty"` // Workflow file name or ID, workflow_dispatch only
+	Ref       string            `json:"ref,omitempty"`      // Branch or tag to run on; client_payload.ref with repository_dispatch
+	Inputs    map[string]string `json:"inputs,omitempty"`   // Workflow input name -> video field, workflow_dispatch only
+	Payload   map[string]string `json:"payload,omitempty"`  // Static client_payload.payload fields, repository_dispatch only
+}
+
+// String returns the target as owner/name, followed by the workflow in workflow_dispatch mode
+func (t RepositoryTarget) String() string {
+	if t.Mode == DispatchModeWorkflow {
+		return fmt.Sprintf("%s/%s:%s", t.RepoOwner, t.RepoName, t.Workflow)
+	}
+	return fmt.Sprintf("%s/%s", t.RepoOwner, t.RepoName)
+}
+
+// RepositoryRoutesRequest is the body of PUT /subscriptions/{channel_id}/repositories
+type RepositoryRoutesRequest struct {
+	Repositories []RepositoryTarget `json:"repositories"`
+}
+
+// RepositoryRoutesResponse reports the repositories a channel dispatches to
+type RepositoryRoutesResponse struct {
+	Status       string             `json:"status"`
+	ChannelID    string             `json:"channel_id"`
+	Repositories []RepositoryTarget `json:"repositories"`
+	Default      bool               `json:"default"` // true when falling back to REPO_OWNER/REPO_NAME
+}
+
+// validateRepositoryTargets checks a routing table entry before it is stored
+func validateRepositoryTargets(targets []RepositoryTarget) error {
+	if len(targets) > maxRepositoriesPerChannel {
+		return fmt.Errorf("at most %d repositories can be routed per channel", maxRepositoriesPerChannel)
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if !repoNamePattern.MatchString(target.RepoOwner) || !repoNamePattern.MatchString(target.RepoName) {
+			return fmt.Errorf("invalid repository %q: repo_owner and repo_name are required", target.String())
+		}
+		if err := validateDispatchMode(target); err != nil {
+			return fmt.Errorf("invalid repository %s: %v", target.String(), err)
+		}
+		key := strings.ToLower(target.String())
+		if seen[key] {
+			return fmt.Errorf("repository %s is listed more than once", target.String())
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// defaultRepository returns the REPO_OWNER/REPO_NAME target used by channels without routes
+func defaultRepository() RepositoryTarget {
+	return RepositoryTarget{RepoOwner: os.Getenv("REPO_OWNER"), RepoName: os.Getenv("REPO_NAME")}
+}
+
+// resolveRepositories returns the repositories a channel's notifications dispatch to.
+// Channels without routes (or without a subscription) use the default repository.
+func resolveRepositories(ctx context.Context, storage StorageService, channelID string, fallback RepositoryTarget) []RepositoryTarget {
+	if storage != nil {
+		sub, err := storage.GetSubscription(ctx, channelID)
+		if err != nil && !errors.Is(err, ErrSubscriptionNotFound) {
+			fmt.Printf("Unable to load repository routes for %s, using default: %v\n", channelID, err)
+		}
+		if sub != nil && len(sub.Repositories) > 0 {
+			return sub.Repositories
+		}
+	}
+	return []RepositoryTarget{fallback}
+}
+
+// handleSetRepositories handles PUT /subscriptions/{channel_id}/repositories requests using dependency injection.
+// An empty list removes the channel's routes so it falls back to the default repository.
+func handleSetRepositories(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		var req RepositoryRoutesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Request body must be a JSON list of repositories")
+			return
+		}
+		if err := validateRepositoryTargets(req.Repositories); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, err.Error())
+			return
+		}
+
+		sub, err := deps.StorageClient.GetSubscription(ctx, channelID)
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, channelID, "Subscription not found for this channel")
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to load subscription state: %v", err))
+			return
+		}
+
+		sub.Repositories = req.Repositories
+		if len(sub.Repositories) == 0 {
+			sub.Repositories = nil
+		}
+		if err := deps.StorageClient.SaveSubscription(ctx, sub); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to save subscription state: %v", err))
+			return
+		}
+
+		response := RepositoryRoutesResponse{
+			Status:       "success",
+			ChannelID:    channelID,
+			Repositories: sub.Repositories,
+			Default:      len(sub.Repositories) == 0,
+		}
+		if response.Default {
+			response.Repositories = []RepositoryTarget{defaultRepository()}
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
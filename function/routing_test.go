@@ -0,0 +1,309 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const routingChannelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+func TestValidateRepositoryTargets(t *testing.T) {
+	tooMany := make([]RepositoryTarget, maxRepositoriesPerChannel+1)
+	for i := range tooMany {
+		tooMany[i] = RepositoryTarget{RepoOwner: "owner", RepoName: fmt.Sprintf("repo-%d", i)}
+	}
+
+	testCases := []struct {
+		name        string
+		targets     []RepositoryTarget
+		expectError bool
+	}{
+		{"empty_list", nil, false},
+		{"valid_targets", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo"}, {RepoOwner: "other.org", RepoName: "site_v2"}}, false},
+		{"missing_name", []RepositoryTarget{{RepoOwner: "owner", RepoName: ""}}, true},
+		{"invalid_characters", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo/../x"}}, true},
+		{"duplicate_case_insensitive", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo"}, {RepoOwner: "Owner", RepoName: "Repo"}}, true},
+		{"too_many", tooMany, true},
+		{"workflow_dispatch", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Mode: DispatchModeWorkflow,
+			Workflow: "publish.yml", Ref: "main", Inputs: map[string]string{"video": "video_id", "video_title": "title"}}}, false},
+		{"two_workflows_in_one_repository", []RepositoryTarget{
+			{RepoOwner: "owner", RepoName: "repo", Mode: DispatchModeWorkflow, Workflow: "publish.yml", Ref: "main"},
+			{RepoOwner: "owner", RepoName: "repo", Mode: DispatchModeWorkflow, Workflow: "1234", Ref: "v1.2"},
+		}, false},
+		{"workflow_dispatch_missing_ref", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Mode: DispatchModeWorkflow, Workflow: "publish.yml"}}, true},
+		{"workflow_dispatch_invalid_workflow", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Mode: DispatchModeWorkflow, Workflow: "../publish", Ref: "main"}}, true},
+		{"workflow_dispatch_unknown_field", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Mode: DispatchModeWorkflow,
+			Workflow: "publish.yml", Ref: "main", Inputs: map[string]string{"video": "thumbnail"}}}, true},
+		{"workflow_settings_without_mode", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Workflow: "publish.yml"}}, true},
+		{"repository_dispatch_ref_and_payload", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Ref: "staging",
+			Payload: map[string]string{"stage": "staging", "notify": "false"}}}, false},
+		{"repository_dispatch_invalid_ref", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Ref: "main..evil"}}, true},
+		{"repository_dispatch_invalid_payload_name", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Payload: map[string]string{"bad name": "x"}}}, true},
+		{"repository_dispatch_payload_value_too_long", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo",
+			Payload: map[string]string{"notes": strings.Repeat("x", maxPayloadValueLength+1)}}}, true},
+		{"workflow_dispatch_payload", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Mode: DispatchModeWorkflow,
+			Workflow: "publish.yml", Ref: "main", Payload: map[string]string{"stage": "staging"}}}, true},
+		{"unknown_mode", []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Mode: "push"}}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRepositoryTargets(tc.targets)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResolveRepositories(t *testing.T) {
+	ctx := context.Background()
+	fallback := RepositoryTarget{RepoOwner: "default-owner", RepoName: "default-repo"}
+
+	t.Run("routed_channel", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{
+			ChannelID:    routingChannelID,
+			Repositories: []RepositoryTarget{{RepoOwner: "a", RepoName: "one"}, {RepoOwner: "b", RepoName: "two"}},
+		}))
+
+		targets := resolveRepositories(ctx, storage, routingChannelID, fallback)
+		assert.Equal(t, []RepositoryTarget{{RepoOwner: "a", RepoName: "one"}, {RepoOwner: "b", RepoName: "two"}}, targets)
+	})
+
+	t.Run("unrouted_channel_uses_default", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: routingChannelID}))
+
+		assert.Equal(t, []RepositoryTarget{fallback}, resolveRepositories(ctx, storage, routingChannelID, fallback))
+	})
+
+	t.Run("unknown_channel_uses_default", func(t *testing.T) {
+		assert.Equal(t, []RepositoryTarget{fallback}, resolveRepositories(ctx, NewMockStorageClient(), routingChannelID, fallback))
+	})
+
+	t.Run("storage_error_uses_default", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.LoadError = ErrMockLoadFailure
+
+		assert.Equal(t, []RepositoryTarget{fallback}, resolveRepositories(ctx, storage, routingChannelID, fallback))
+	})
+
+	t.Run("nil_storage_uses_default", func(t *testing.T) {
+		assert.Equal(t, []RepositoryTarget{fallback}, resolveRepositories(ctx, nil, routingChannelID, fallback))
+	})
+}
+
+func TestHandleSetRepositories(t *testing.T) {
+	os.Setenv("REPO_OWNER", "default-owner")
+	os.Setenv("REPO_NAME", "default-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	put := func(deps *Dependencies, channelID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/subscriptions/"+channelID+"/repositories", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handleSetRepositories(deps, channelID)(rec, req)
+		return rec
+	}
+
+	t.Run("sets_routes", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: routingChannelID}))
+
+		rec := put(deps, routingChannelID, `{"repositories":[{"repo_owner":"a","repo_name":"one"},{"repo_owner":"b","repo_name":"two"}]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RepositoryRoutesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.False(t, response.Default)
+		assert.Len(t, response.Repositories, 2)
+
+		saved := storage.GetState().Subscriptions[routingChannelID]
+		assert.Equal(t, []RepositoryTarget{{RepoOwner: "a", RepoName: "one"}, {RepoOwner: "b", RepoName: "two"}}, saved.Repositories)
+	})
+
+	t.Run("empty_list_resets_to_default", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.SetState(newSweepTestState(&Subscription{
+			ChannelID:    routingChannelID,
+			Repositories: []RepositoryTarget{{RepoOwner: "a", RepoName: "one"}},
+		}))
+
+		rec := put(deps, routingChannelID, `{"repositories":[]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response RepositoryRoutesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.True(t, response.Default)
+		assert.Equal(t, []RepositoryTarget{{RepoOwner: "default-owner", RepoName: "default-repo"}}, response.Repositories)
+		assert.Nil(t, storage.GetState().Subscriptions[routingChannelID].Repositories)
+	})
+
+	t.Run("error_responses", func(t *testing.T) {
+		testCases := []struct {
+			name           string
+			channelID      string
+			body           string
+			loadError      error
+			saveError      error
+			expectedStatus int
+		}{
+			{"invalid_channel", "bad", `{"repositories":[]}`, nil, nil, http.StatusBadRequest},
+			{"invalid_json", routingChannelID, `not json`, nil, nil, http.StatusBadRequest},
+			{"invalid_repository", routingChannelID, `{"repositories":[{"repo_owner":"a"}]}`, nil, nil, http.StatusBadRequest},
+			{"not_subscribed", "UC0000000000000000000000", `{"repositories":[]}`, nil, nil, http.StatusNotFound},
+			{"load_failure", routingChannelID, `{"repositories":[]}`, ErrMockLoadFailure, nil, http.StatusInternalServerError},
+			{"save_failure", routingChannelID, `{"repositories":[]}`, nil, ErrMockSaveFailure, http.StatusInternalServerError},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				deps := CreateTestDependencies()
+				storage := deps.StorageClient.(*MockStorageClient)
+				storage.SetState(newSweepTestState(&Subscription{ChannelID: routingChannelID}))
+				storage.LoadError = tc.loadError
+				storage.SaveError = tc.saveError
+
+				assert.Equal(t, tc.expectedStatus, put(deps, tc.channelID, tc.body).Code)
+			})
+		}
+	})
+}
+
+func TestHandleNotification_MultipleRepositories(t *testing.T) {
+	os.Setenv("REPO_OWNER", "default-owner")
+	os.Setenv("REPO_NAME", "default-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>route123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Routed Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, routingChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	routedDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID:    routingChannelID,
+			Repositories: []RepositoryTarget{{RepoOwner: "a", RepoName: "one"}, {RepoOwner: "b", RepoName: "two"}},
+		}))
+		return deps
+	}
+
+	t.Run("dispatches_to_every_route", func(t *testing.T) {
+		deps := routedDeps()
+		gh := deps.GitHubClient.(*MockGitHubClient)
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "in 2 repositories")
+		assert.Equal(t, []string{"a/one", "b/two"}, gh.GetTriggeredRepositories())
+	})
+
+	t.Run("unrouted_channel_uses_default_repository", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, routingChannelID)
+		gh := deps.GitHubClient.(*MockGitHubClient)
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Successfully triggered workflow for new video: route123", rec.Body.String())
+		assert.Equal(t, []string{"default-owner/default-repo"}, gh.GetTriggeredRepositories())
+	})
+
+	t.Run("workflow_dispatch_route_maps_inputs", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: routingChannelID,
+			Repositories: []RepositoryTarget{{RepoOwner: "a", RepoName: "one", Mode: DispatchModeWorkflow,
+				Workflow: "publish.yml", Ref: "main", Inputs: map[string]string{"video": "video_id", "url": "video_url"}}},
+		}))
+		gh := deps.GitHubClient.(*MockGitHubClient)
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 0, gh.GetTriggerCallCount(), "no repository_dispatch is sent")
+		assert.Equal(t, 1, gh.GetWorkflowCallCount())
+		workflow, ref, inputs := gh.GetLastWorkflowDispatch()
+		assert.Equal(t, "publish.yml", workflow)
+		assert.Equal(t, "main", ref)
+		assert.Equal(t, map[string]string{"video": "route123", "url": "https://www.youtube.com/watch?v=route123"}, inputs)
+	})
+
+	t.Run("repository_dispatch_route_adds_ref_and_payload", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: routingChannelID,
+			Repositories: []RepositoryTarget{{RepoOwner: "a", RepoName: "one", Ref: "staging",
+				Payload: map[string]string{"stage": "staging"}}},
+		}))
+		gh := deps.GitHubClient.(*MockGitHubClient)
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, gh.GetTriggerCallCount())
+		assert.Equal(t, map[string]interface{}{"ref": "staging", "payload": map[string]string{"stage": "staging"}}, gh.GetLastExtra())
+	})
+
+	t.Run("partial_failure_still_dispatches_remaining", func(t *testing.T) {
+		deps := routedDeps()
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		gh.SetRepositoryTriggerError("a/one", fmt.Errorf("GitHub API returned status 404"))
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "1 of 2 repositories queued for retry")
+		assert.Contains(t, rec.Body.String(), "a/one: GitHub API returned status 404")
+		assert.Equal(t, []string{"a/one", "b/two"}, gh.GetTriggeredRepositories())
+	})
+}
+
+func TestYouTubeWebhook_SetRepositoriesRoute(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: routingChannelID}))
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	req := httptest.NewRequest("PUT", "/subscriptions/"+routingChannelID+"/repositories",
+		strings.NewReader(`{"repositories":[{"repo_owner":"a","repo_name":"one"}]}`))
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// runPollInterval is how often GitHub is polled for a dispatched workflow run
+var runPollInterval = 2 * time.Second
+
+// getRunCorrelationTimeout returns how long to poll for the workflow run started by a dispatch.
+// Correlation is disabled (zero) unless GITHUB_RUN_CORRELATION_SECONDS is set.
+func getRunCorrelationTimeout() time.Duration {
+	return envConfig().RunCorrelationTimeout
+}
+
+// correlateWorkflowRun polls GitHub until the run for the given dispatch appears or the timeout elapses.
+// Returns nil without error when no run was found in time.
+func correlateWorkflowRun(ctx context.Context, gh GitHubClientInterface, repoOwner, repoName, id string, since time.Time, timeout time.Duration) (*WorkflowRun, error) {
+	// Runs are listed with second precision, so allow for clock skew with GitHub
+	since = since.Add(-time.Minute)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		run, err := gh.FindDispatchRun(ctx, repoOwner, repoName, id, since)
+		if err != nil || run != nil {
+			return run, err
+		}
+
+		if time.Now().Add(runPollInterval).After(deadline) {
+			return nil, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(runPollInterval):
+		}
+	}
+}
+
+// recordWorkflowRun stores the run triggered for a channel's latest video on its subscription.
+// Failures are logged rather than returned so they never fail the notification.
+func recordWorkflowRun(ctx context.Context, storage StorageService, entry *Entry, run *WorkflowRun) {
+	if storage == nil || entry == nil || run == nil {
+		return
+	}
+
+	sub, err := storage.GetSubscription(ctx, entry.SubscriptionID())
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Unable to record workflow run for %s: %v\n", entry.SubscriptionID(), err)
+		return
+	}
+	sub.LastRunID = run.ID
+	sub.LastRunURL = run.HTMLURL
+
+	if err := storage.SaveSubscription(ctx, sub); err != nil {
+		fmt.Printf("Unable to record workflow run for %s: %v\n", entry.SubscriptionID(), err)
+	}
+}
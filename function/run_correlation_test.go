@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const correlationChannelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+func TestGetRunCorrelationTimeout(t *testing.T) {
+	defer os.Unsetenv("GITHUB_RUN_CORRELATION_SECONDS")
+
+	os.Unsetenv("GITHUB_RUN_CORRELATION_SECONDS")
+	assert.Equal(t, time.Duration(0), getRunCorrelationTimeout())
+
+	os.Setenv("GITHUB_RUN_CORRELATION_SECONDS", "15")
+	assert.Equal(t, 15*time.Second, getRunCorrelationTimeout())
+
+	os.Setenv("GITHUB_RUN_CORRELATION_SECONDS", "invalid")
+	assert.Equal(t, time.Duration(0), getRunCorrelationTimeout())
+}
+
+func TestCorrelateWorkflowRun(t *testing.T) {
+	originalInterval := runPollInterval
+	runPollInterval = time.Millisecond
+	defer func() { runPollInterval = originalInterval }()
+
+	ctx := context.Background()
+
+	t.Run("returns_matching_run", func(t *testing.T) {
+		gh := NewMockGitHubClient()
+		gh.SetDispatchRun(&WorkflowRun{ID: 42, HTMLURL: "https://github.com/o/r/actions/runs/42"}, nil)
+
+		run, err := correlateWorkflowRun(ctx, gh, "o", "r", "abc", time.Now(), time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, run)
+		assert.Equal(t, int64(42), run.ID)
+		assert.Equal(t, 1, gh.GetFindRunCallCount())
+	})
+
+	t.Run("gives_up_after_timeout", func(t *testing.T) {
+		gh := NewMockGitHubClient()
+
+		run, err := correlateWorkflowRun(ctx, gh, "o", "r", "abc", time.Now(), 20*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Nil(t, run)
+		assert.Greater(t, gh.GetFindRunCallCount(), 1)
+	})
+
+	t.Run("stops_on_lookup_error", func(t *testing.T) {
+		gh := NewMockGitHubClient()
+		gh.SetDispatchRun(nil, fmt.Errorf("rate limited"))
+
+		run, err := correlateWorkflowRun(ctx, gh, "o", "r", "abc", time.Now(), time.Second)
+		assert.Error(t, err)
+		assert.Nil(t, run)
+		assert.Equal(t, 1, gh.GetFindRunCallCount())
+	})
+
+	t.Run("stops_when_context_cancelled", func(t *testing.T) {
+		gh := NewMockGitHubClient()
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := correlateWorkflowRun(cancelled, gh, "o", "r", "abc", time.Now(), time.Second)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRecordWorkflowRun(t *testing.T) {
+	ctx := context.Background()
+	run := &WorkflowRun{ID: 7, HTMLURL: "https://github.com/o/r/actions/runs/7"}
+	entry := &Entry{VideoID: "v1", ChannelID: correlationChannelID}
+
+	t.Run("stores_run_on_subscription", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: correlationChannelID}))
+
+		recordWorkflowRun(ctx, storage, entry, run)
+
+		saved := storage.GetState().Subscriptions[correlationChannelID]
+		assert.Equal(t, int64(7), saved.LastRunID)
+		assert.Equal(t, run.HTMLURL, saved.LastRunURL)
+	})
+
+	t.Run("ignores_unknown_channel_and_nil_inputs", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		recordWorkflowRun(ctx, storage, entry, run)
+		recordWorkflowRun(ctx, storage, entry, nil)
+		recordWorkflowRun(ctx, nil, entry, run)
+		assert.Equal(t, 0, storage.SaveCallCount)
+	})
+
+	t.Run("tolerates_storage_errors", func(t *testing.T) {
+		storage := NewMockStorageClient()
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: correlationChannelID}))
+		storage.SaveError = ErrMockSaveFailure
+		recordWorkflowRun(ctx, storage, entry, run)
+
+		storage.LoadError = ErrMockLoadFailure
+		recordWorkflowRun(ctx, storage, entry, run)
+	})
+}
+
+func TestHandleNotification_RunCorrelation(t *testing.T) {
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	os.Setenv("GITHUB_RUN_CORRELATION_SECONDS", "1")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+		os.Unsetenv("GITHUB_RUN_CORRELATION_SECONDS")
+	}()
+
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>run123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Correlated Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, correlationChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	t.Run("records_run_and_reports_url", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: correlationChannelID}))
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		gh.SetDispatchRun(&WorkflowRun{ID: 99, HTMLURL: "https://github.com/test-owner/test-repo/actions/runs/99"}, nil)
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "(run: https://github.com/test-owner/test-repo/actions/runs/99)")
+
+		saved := storage.GetState().Subscriptions[correlationChannelID]
+		assert.Equal(t, "run123", saved.LastVideoID)
+		assert.Equal(t, int64(99), saved.LastRunID)
+	})
+
+	t.Run("lookup_failure_does_not_fail_notification", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, correlationChannelID)
+		gh := deps.GitHubClient.(*MockGitHubClient)
+		gh.SetDispatchRun(nil, fmt.Errorf("GitHub API returned status 403"))
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "(run:")
+	})
+}
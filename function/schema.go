@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CurrentSchemaVersion is the subscription state schema this build reads and writes.
+// States stored with an older version are migrated when loaded; saving one is refused.
+const CurrentSchemaVersion = "2.0"
+
+// legacySchemaVersion is assumed for states stored without a version
+const legacySchemaVersion = "1.0"
+
+// schemaMigration upgrades a subscription state from one schema version to the next
+type schemaMigration struct {
+	From        string
+	To          string
+	Description string
+	Migrate     func(state *SubscriptionState)
+}
+
+// schemaMigrations lists every migration in order; each one's To is the next one's From,
+// and the last one's To is CurrentSchemaVersion
+var schemaMigrations = []schemaMigration{
+	{
+		From:        "1.0",
+		To:          "2.0",
+		Description: "key every subscription and removed channel by its channel ID and topic URL",
+		Migrate:     migrateChannelKeys,
+	},
+}
+
+// migrateChannelKeys drops empty entries and fills in the channel ID and topic URL of
+// subscriptions stored before every entry carried them
+func migrateChannelKeys(state *SubscriptionState) {
+	fill := func(channelID string, sub *Subscription) {
+		if sub.ChannelID == "" {
+			sub.ChannelID = channelID
+		}
+		if sub.TopicURL == "" {
+			sub.TopicURL = fmt.Sprintf("https://www.youtube.com/feeds/videos.xml?channel_id=%s", sub.ChannelID)
+		}
+	}
+
+	for channelID, sub := range state.Subscriptions {
+		if sub == nil {
+			delete(state.Subscriptions, channelID)
+			continue
+		}
+		fill(channelID, sub)
+	}
+	for channelID, removed := range state.Removed {
+		if removed == nil || removed.Subscription == nil {
+			delete(state.Removed, channelID)
+			continue
+		}
+		fill(channelID, removed.Subscription)
+	}
+}
+
+// migrateSubscriptionState brings a loaded state up to CurrentSchemaVersion, returning
+// the descriptions of the migrations applied. A state without a version is treated as
+// legacySchemaVersion. States written by a newer schema cannot be read, since saving
+// them again would drop what this build does not know about.
+func migrateSubscriptionState(state *SubscriptionState) ([]string, error) {
+	if state.Metadata.Version == "" {
+		state.Metadata.Version = legacySchemaVersion
+	}
+	newer, err := schemaVersionNewer(state.Metadata.Version, CurrentSchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	if newer {
+		return nil, fmt.Errorf("%w: state is version %s, this build reads up to %s",
+			ErrSchemaTooNew, state.Metadata.Version, CurrentSchemaVersion)
+	}
+
+	var applied []string
+	for state.Metadata.Version != CurrentSchemaVersion {
+		migration := findSchemaMigration(state.Metadata.Version)
+		if migration == nil {
+			return applied, fmt.Errorf("%w: no migration from version %s", ErrSchemaUnknown, state.Metadata.Version)
+		}
+		migration.Migrate(state)
+		state.Metadata.Version = migration.To
+		applied = append(applied, fmt.Sprintf("%s -> %s: %s", migration.From, migration.To, migration.Description))
+	}
+	if len(applied) > 0 {
+		fmt.Printf("Migrated subscription state to schema %s: %s\n", CurrentSchemaVersion, strings.Join(applied, "; "))
+	}
+	return applied, nil
+}
+
+// migrateSubscription brings one subscription stored with the given schema version up
+// to CurrentSchemaVersion, as migrateSubscriptionState would within a whole state
+func migrateSubscription(channelID string, sub *Subscription, version string) (*Subscription, error) {
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{channelID: sub}}
+	state.Metadata.Version = version
+	if _, err := migrateSubscriptionState(state); err != nil {
+		return nil, err
+	}
+	return state.Subscriptions[channelID], nil
+}
+
+// findSchemaMigration returns the migration from the given version, or nil
+func findSchemaMigration(version string) *schemaMigration {
+	for i := range schemaMigrations {
+		if schemaMigrations[i].From == version {
+			return &schemaMigrations[i]
+		}
+	}
+	return nil
+}
+
+// stampSchemaVersion sets the version of a state about to be saved, refusing states
+// loaded with an older or newer schema that were not migrated
+func stampSchemaVersion(state *SubscriptionState) error {
+	if state.Metadata.Version == "" {
+		state.Metadata.Version = CurrentSchemaVersion
+	}
+	if state.Metadata.Version != CurrentSchemaVersion {
+		return fmt.Errorf("%w: state is version %s, this build writes %s",
+			ErrSchemaOutdated, state.Metadata.Version, CurrentSchemaVersion)
+	}
+	return nil
+}
+
+// schemaVersionNewer reports whether version a is newer than version b, both as
+// "major.minor"
+func schemaVersionNewer(a, b string) (bool, error) {
+	parse := func(version string) (int, int, error) {
+		major, minor, _ := strings.Cut(version, ".")
+		majorN, err := strconv.Atoi(major)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: %q", ErrSchemaUnknown, version)
+		}
+		minorN := 0
+		if minor != "" {
+			if minorN, err = strconv.Atoi(minor); err != nil {
+				return 0, 0, fmt.Errorf("%w: %q", ErrSchemaUnknown, version)
+			}
+		}
+		return majorN, minorN, nil
+	}
+
+	aMajor, aMinor, err := parse(a)
+	if err != nil {
+		return false, err
+	}
+	bMajor, bMinor, err := parse(b)
+	if err != nil {
+		return false, err
+	}
+	if aMajor != bMajor {
+		return aMajor > bMajor, nil
+	}
+	return aMinor > bMinor, nil
+}
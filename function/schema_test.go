@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyStateJSON is a state as stored before schema versions were consulted
+const legacyStateJSON = `{
+  "subscriptions": {
+    "UCXuqSBlHAE6Xw-yeJA0Tunw": {"status": "active"},
+    "UC_x5XG1OV2P6uZZ5FSM9Ttw": null
+  },
+  "removed": {
+    "UCBJycsmduvYEL83R_U4JriQ": {"subscription": {"status": "active"}, "removed_by": "admin"}
+  },
+  "metadata": {"last_updated": "2025-01-21T10:29:12Z", "version": "1.0"}
+}`
+
+func TestSchemaMigrations_Chain(t *testing.T) {
+	version := legacySchemaVersion
+	for _, migration := range schemaMigrations {
+		assert.Equal(t, version, migration.From, "migrations must follow each other")
+		newer, err := schemaVersionNewer(migration.To, migration.From)
+		require.NoError(t, err)
+		assert.True(t, newer, "migration from %s must go to a newer version", migration.From)
+		version = migration.To
+	}
+	assert.Equal(t, CurrentSchemaVersion, version, "the last migration must reach the current version")
+}
+
+func TestMigrateSubscriptionState(t *testing.T) {
+	t.Run("legacy_state", func(t *testing.T) {
+		state := &SubscriptionState{
+			Subscriptions: map[string]*Subscription{
+				"UCXuqSBlHAE6Xw-yeJA0Tunw": {Status: StatusActive},
+				"UC_x5XG1OV2P6uZZ5FSM9Ttw": nil,
+			},
+			Removed: map[string]*RemovedSubscription{
+				"UCBJycsmduvYEL83R_U4JriQ": {Subscription: &Subscription{Status: StatusActive}},
+				"UCempty":                  {},
+			},
+		}
+		state.Metadata.Version = "1.0"
+
+		applied, err := migrateSubscriptionState(state)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(schemaMigrations))
+		assert.Equal(t, CurrentSchemaVersion, state.Metadata.Version)
+
+		require.Len(t, state.Subscriptions, 1, "empty subscriptions are dropped")
+		sub := state.Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"]
+		assert.Equal(t, "UCXuqSBlHAE6Xw-yeJA0Tunw", sub.ChannelID)
+		assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw", sub.TopicURL)
+
+		require.Len(t, state.Removed, 1)
+		assert.Equal(t, "UCBJycsmduvYEL83R_U4JriQ", state.Removed["UCBJycsmduvYEL83R_U4JriQ"].Subscription.ChannelID)
+	})
+
+	t.Run("missing_version_is_legacy", func(t *testing.T) {
+		state := &SubscriptionState{Subscriptions: map[string]*Subscription{}}
+		applied, err := migrateSubscriptionState(state)
+		require.NoError(t, err)
+		assert.NotEmpty(t, applied)
+		assert.Equal(t, CurrentSchemaVersion, state.Metadata.Version)
+	})
+
+	t.Run("current_version_unchanged", func(t *testing.T) {
+		state := &SubscriptionState{Subscriptions: map[string]*Subscription{}}
+		state.Metadata.Version = CurrentSchemaVersion
+		applied, err := migrateSubscriptionState(state)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("newer_version_refused", func(t *testing.T) {
+		state := &SubscriptionState{Subscriptions: map[string]*Subscription{}}
+		state.Metadata.Version = "99.0"
+		_, err := migrateSubscriptionState(state)
+		assert.ErrorIs(t, err, ErrSchemaTooNew)
+	})
+
+	t.Run("unknown_version_refused", func(t *testing.T) {
+		for _, version := range []string{"0.9", "beta"} {
+			state := &SubscriptionState{Subscriptions: map[string]*Subscription{}}
+			state.Metadata.Version = version
+			_, err := migrateSubscriptionState(state)
+			assert.ErrorIs(t, err, ErrSchemaUnknown, version)
+		}
+	})
+}
+
+func TestStampSchemaVersion(t *testing.T) {
+	state := &SubscriptionState{}
+	require.NoError(t, stampSchemaVersion(state))
+	assert.Equal(t, CurrentSchemaVersion, state.Metadata.Version)
+
+	state.Metadata.Version = legacySchemaVersion
+	assert.ErrorIs(t, stampSchemaVersion(state), ErrSchemaOutdated)
+}
+
+func TestCloudStorageService_MigratesOnLoad(t *testing.T) {
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	require.NoError(t, mockOps.PutObject(ctx, "test-bucket", legacyStateObject, []byte(legacyStateJSON)))
+	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+
+	state, err := service.LoadSubscriptionState(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, state.Metadata.Version)
+	assert.Len(t, state.Subscriptions, 1)
+	assert.Equal(t, "UCXuqSBlHAE6Xw-yeJA0Tunw", state.Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"].ChannelID)
+
+	// Saving writes the current schema
+	require.NoError(t, service.SaveSubscriptionState(ctx, state))
+	stored, err := decodeStateObject(mockOps.objects["test-bucket/"+stateIndexObject])
+	require.NoError(t, err)
+	assert.Contains(t, string(stored), `"version": "`+CurrentSchemaVersion+`"`)
+	stored, err = decodeStateObject(mockOps.objects["test-bucket/"+stateChannelObject("UCXuqSBlHAE6Xw-yeJA0Tunw")])
+	require.NoError(t, err)
+	assert.Contains(t, string(stored), `"version": "`+CurrentSchemaVersion+`"`)
+
+	t.Run("refuses_outdated_state", func(t *testing.T) {
+		outdated := &SubscriptionState{Subscriptions: map[string]*Subscription{}}
+		outdated.Metadata.Version = legacySchemaVersion
+		assert.ErrorIs(t, service.SaveSubscriptionState(ctx, outdated), ErrSchemaOutdated)
+	})
+
+	t.Run("refuses_newer_state", func(t *testing.T) {
+		newer := strings.Replace(legacyStateJSON, `"version": "1.0"`, `"version": "3.0"`, 1)
+		legacyOps := NewMockCloudStorageOperations()
+		require.NoError(t, legacyOps.PutObject(ctx, "test-bucket", legacyStateObject, []byte(newer)))
+		_, err := NewCloudStorageServiceWithOperations(legacyOps, "test-bucket").LoadSubscriptionState(ctx)
+		assert.ErrorIs(t, err, ErrSchemaTooNew)
+	})
+}
+
+func TestHandleImportState_MigratesLegacyExport(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "admin-secret")
+	deps := CreateTestDependencies()
+
+	importState := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/admin/state", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		handleImportState(deps)(rec, req)
+		return rec
+	}
+
+	// Imports are validated before they are migrated, so empty subscriptions are still refused
+	legacyExport := strings.Replace(legacyStateJSON, `,
+    "UC_x5XG1OV2P6uZZ5FSM9Ttw": null`, "", 1)
+	rec := importState(legacyExport)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	state, err := deps.StorageClient.LoadSubscriptionState(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, state.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	assert.NotEmpty(t, state.Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"].TopicURL)
+
+	rec = importState(strings.Replace(legacyExport, `"version": "1.0"`, `"version": "3.0"`, 1))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "newer than this build")
+}
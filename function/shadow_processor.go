@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"fmt"
+	"os"
+)
+
+// Outcomes of comparing a shadow candidate's decision with the active one
+const (
+	ShadowResultAgree    = "agree"
+	ShadowResultDisagree = "disagree"
+	ShadowResultError    = "error" // The candidate panicked
+)
+
+// ShadowVideoProcessor evaluates a candidate video processor alongside the active one.
+// Every decision is the active processor's; the candidate's is only compared with it,
+// and disagreements are logged and counted, so a new detection strategy can be tried
+// on production traffic without changing what is dispatched.
+type ShadowVideoProcessor struct {
+	Active    VideoProcessorInterface
+	Candidate VideoProcessorInterface
+	Label     string   // Names the candidate in metrics
+	Metrics   *Metrics // Optional; counts agreements and disagreements when set
+}
+
+// newNotificationVideoProcessor creates the video processor notifications are handled
+// with: the one configured by NEW_VIDEO_STRATEGY, shadowed by the candidate configured
+// with NEW_VIDEO_SHADOW_STRATEGY when that is set. The candidate's thresholds default
+// to the active ones. Both judge age by clock.
+func newNotificationVideoProcessor(metrics *Metrics, clock Clock) VideoProcessorInterface {
+	active := NewVideoProcessor()
+	active.Clock = clock
+	if os.Getenv("NEW_VIDEO_SHADOW_STRATEGY") == "" {
+		return active
+	}
+
+	candidate := &VideoProcessor{
+		Strategy:       getNewVideoStrategy("NEW_VIDEO_SHADOW_STRATEGY"),
+		MaxAge:         getMinutesEnv("NEW_VIDEO_SHADOW_MAX_AGE_MINUTES", active.maxAge()),
+		MaxUpdateDelta: getMinutesEnv("NEW_VIDEO_SHADOW_UPDATE_GAP_MINUTES", active.maxUpdateDelta()),
+		Clock:          clock,
+	}
+	return &ShadowVideoProcessor{
+		Active:    active,
+		Candidate: candidate,
+		Label:     candidate.Strategy,
+		Metrics:   metrics,
+	}
+}
+
+// IsNewVideo returns the active processor's decision, after comparing it with the
+// candidate's. A candidate that panics is logged and counted as disagreeing.
+func (sp *ShadowVideoProcessor) IsNewVideo(entry *Entry) bool {
+	active := sp.Active.IsNewVideo(entry)
+
+	candidate, err := sp.candidateIsNewVideo(entry)
+	switch {
+	case err != nil:
+		fmt.Printf("Shadow video processor %s failed on video %s: %v\n", sp.Candidate, entry.VideoID, err)
+		sp.Metrics.RecordShadowDecision(sp.Label, ShadowResultError)
+	case candidate != active:
+		fmt.Printf("Shadow video processor disagrees on video %s (channel %s, published %s, updated %s): active %s says new=%t, candidate %s says new=%t\n",
+			entry.VideoID, entry.ChannelID, entry.Published, entry.Updated, sp.Active, active, sp.Candidate, candidate)
+		sp.Metrics.RecordShadowDecision(sp.Label, ShadowResultDisagree)
+	default:
+		sp.Metrics.RecordShadowDecision(sp.Label, ShadowResultAgree)
+	}
+	return active
+}
+
+// candidateIsNewVideo asks the candidate, recovering from a panic so it cannot fail the
+// notification
+func (sp *ShadowVideoProcessor) candidateIsNewVideo(entry *Entry) (isNew bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return sp.Candidate.IsNewVideo(entry), nil
+}
+
+// ValidateEntry validates with the active processor
+func (sp *ShadowVideoProcessor) ValidateEntry(entry *Entry) error {
+	return sp.Active.ValidateEntry(entry)
+}
+
+// ValidateFeed validates with the active processor
+func (sp *ShadowVideoProcessor) ValidateFeed(feed *AtomFeed) error {
+	return sp.Active.ValidateFeed(feed)
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panickingProcessor is a candidate whose newness check panics
+type panickingProcessor struct {
+	VideoProcessor
+}
+
+func (p *panickingProcessor) IsNewVideo(entry *Entry) bool {
+	panic("candidate bug")
+}
+
+func TestNewNotificationVideoProcessor(t *testing.T) {
+	t.Run("shadow_disabled", func(t *testing.T) {
+		processor := newNotificationVideoProcessor(NewMetrics(), nil)
+		assert.IsType(t, &VideoProcessor{}, processor)
+	})
+
+	t.Run("shadow_enabled", func(t *testing.T) {
+		t.Setenv("NEW_VIDEO_MAX_AGE_MINUTES", "90")
+		t.Setenv("NEW_VIDEO_SHADOW_STRATEGY", "updated")
+		t.Setenv("NEW_VIDEO_SHADOW_UPDATE_GAP_MINUTES", "30")
+
+		shadow, ok := newNotificationVideoProcessor(NewMetrics(), nil).(*ShadowVideoProcessor)
+		require.True(t, ok)
+		assert.Equal(t, NewVideoStrategyWindow, shadow.Active.(*VideoProcessor).Strategy)
+		assert.Equal(t, NewVideoStrategyUpdated, shadow.Label)
+
+		candidate := shadow.Candidate.(*VideoProcessor)
+		assert.Equal(t, NewVideoStrategyUpdated, candidate.Strategy)
+		assert.Equal(t, 90*time.Minute, candidate.MaxAge, "thresholds default to the active ones")
+		assert.Equal(t, 30*time.Minute, candidate.MaxUpdateDelta)
+	})
+}
+
+func TestShadowVideoProcessor_IsNewVideo(t *testing.T) {
+	now := time.Now()
+	fresh := &Entry{
+		VideoID:   "fresh",
+		Published: now.Add(-10 * time.Minute).Format(time.RFC3339),
+		Updated:   now.Add(-9 * time.Minute).Format(time.RFC3339),
+	}
+	republished := &Entry{ // Old video edited a few minutes ago
+		VideoID:   "republished",
+		Published: now.Add(-72 * time.Hour).Format(time.RFC3339),
+		Updated:   now.Add(-5 * time.Minute).Format(time.RFC3339),
+	}
+
+	metrics := NewMetrics()
+	shadow := &ShadowVideoProcessor{
+		Active:    &VideoProcessor{Strategy: NewVideoStrategyWindow},
+		Candidate: &VideoProcessor{Strategy: NewVideoStrategyUpdated},
+		Label:     NewVideoStrategyUpdated,
+		Metrics:   metrics,
+	}
+
+	assert.True(t, shadow.IsNewVideo(fresh))
+	assert.False(t, shadow.IsNewVideo(republished), "the active decision is returned when the candidate disagrees")
+
+	assert.Equal(t, 1.0, metrics.CounterValue(metricShadowDecisions, "candidate", "updated", "result", ShadowResultAgree))
+	assert.Equal(t, 1.0, metrics.CounterValue(metricShadowDecisions, "candidate", "updated", "result", ShadowResultDisagree))
+
+	t.Run("candidate_panic", func(t *testing.T) {
+		shadow := &ShadowVideoProcessor{
+			Active:    &VideoProcessor{},
+			Candidate: &panickingProcessor{},
+			Label:     "broken",
+			Metrics:   metrics,
+		}
+		assert.True(t, shadow.IsNewVideo(fresh))
+		assert.Equal(t, 1.0, metrics.CounterValue(metricShadowDecisions, "candidate", "broken", "result", ShadowResultError))
+	})
+
+	t.Run("validation_uses_active", func(t *testing.T) {
+		shadow := &ShadowVideoProcessor{Active: &VideoProcessor{}, Candidate: &panickingProcessor{}}
+		assert.ErrorIs(t, shadow.ValidateEntry(&Entry{}), ErrMissingVideoID)
+		assert.ErrorIs(t, shadow.ValidateFeed(&AtomFeed{}), ErrInvalidEntry)
+	})
+}
+
+func TestHandleNotification_ShadowStrategy(t *testing.T) {
+	t.Setenv("NEW_VIDEO_SHADOW_STRATEGY", "accept_all")
+	deps := CreateTestDependencies()
+	subscribeTestChannels(deps, routingChannelID)
+
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>oldVideo123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Edited description</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, routingChannelID, now.Add(-72*time.Hour).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	rec := httptest.NewRecorder()
+	handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Not a new video")
+	assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount(), "the candidate never dispatches")
+	assert.Equal(t, 1.0, deps.Metrics.CounterValue(metricShadowDecisions, "candidate", "accept_all", "result", ShadowResultDisagree))
+}
@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const signedChannelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+// signHubBody returns the X-Hub-Signature header a hub would send for body
+func signHubBody(secret, body string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signNotification signs req with the secret stored for channelID, as the hub would
+func signNotification(req *http.Request, deps *Dependencies, channelID, body string) {
+	state := deps.StorageClient.(*MockStorageClient).GetState()
+	if sub, exists := state.Subscriptions[channelID]; exists && sub.Secret != "" {
+		req.Header.Set(HubSignatureHeader, signHubBody(sub.Secret, body))
+	}
+}
+
+// subscribeTestChannels stores an active subscription without a secret for each
+// channel not yet subscribed, so unsigned notifications for it are accepted
+func subscribeTestChannels(deps *Dependencies, channelIDs ...string) {
+	storage := deps.StorageClient.(*MockStorageClient)
+	state := storage.GetState()
+	if state == nil {
+		state = newSweepTestState()
+	}
+	for _, channelID := range channelIDs {
+		if state.Subscriptions[channelID] == nil {
+			state.Subscriptions[channelID] = &Subscription{
+				ChannelID: channelID,
+				Status:    StatusActive,
+				ExpiresAt: time.Now().Add(24 * time.Hour),
+			}
+		}
+	}
+	storage.SetState(state)
+}
+
+func TestGenerateHubSecret(t *testing.T) {
+	first, err := generateHubSecret()
+	require.NoError(t, err)
+	second, err := generateHubSecret()
+	require.NoError(t, err)
+
+	assert.Len(t, first, 64)
+	assert.NotEqual(t, first, second)
+}
+
+func TestEnsureHubSecret(t *testing.T) {
+	sub := &Subscription{}
+	secret, err := ensureHubSecret(sub)
+	require.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	assert.Equal(t, secret, sub.Secret)
+
+	again, err := ensureHubSecret(sub)
+	require.NoError(t, err)
+	assert.Equal(t, secret, again, "existing secrets must be reused")
+}
+
+func TestVerifyHubSignature(t *testing.T) {
+	body := []byte("<feed/>")
+	sha256Mac := hmac.New(sha256.New, []byte("secret"))
+	sha256Mac.Write(body)
+
+	testCases := []struct {
+		name     string
+		header   string
+		expected error
+	}{
+		{"valid_sha1", signHubBody("secret", "<feed/>"), nil},
+		{"valid_sha256", "sha256=" + hex.EncodeToString(sha256Mac.Sum(nil)), nil},
+		{"missing", "", ErrMissingSignature},
+		{"wrong_secret", signHubBody("other", "<feed/>"), ErrInvalidSignature},
+		{"tampered_body", signHubBody("secret", "<feed>changed</feed>"), ErrInvalidSignature},
+		{"unsupported_algorithm", "md5=abcd", ErrInvalidSignature},
+		{"malformed", "sha1", ErrInvalidSignature},
+		{"non_hex", "sha1=zz", ErrInvalidSignature},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, verifyHubSignature(tc.header, body, "secret"))
+		})
+	}
+}
+
+func TestCheckNotificationSignature(t *testing.T) {
+	ctx := context.Background()
+	body := []byte("<feed/>")
+
+	storage := NewMockStorageClient()
+	storage.SetState(newSweepTestState(
+		&Subscription{ChannelID: signedChannelID, Secret: "secret"},
+		&Subscription{ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw"},
+	))
+
+	t.Run("signed_channel", func(t *testing.T) {
+		assert.NoError(t, checkNotificationSignature(ctx, storage, signedChannelID, signHubBody("secret", "<feed/>"), body, false))
+		assert.Equal(t, ErrMissingSignature, checkNotificationSignature(ctx, storage, signedChannelID, "", body, false))
+	})
+
+	t.Run("legacy_channel_without_secret", func(t *testing.T) {
+		assert.NoError(t, checkNotificationSignature(ctx, storage, "UC_x5XG1OV2P6uZZ5FSM9Ttw", "", body, false))
+
+		assert.Equal(t, ErrInvalidSignature, checkNotificationSignature(ctx, storage, "UC_x5XG1OV2P6uZZ5FSM9Ttw", "", body, true))
+	})
+
+	t.Run("unknown_channel_is_rejected", func(t *testing.T) {
+		err := checkNotificationSignature(ctx, storage, "UCunsubscribed000000000000", "", body, false)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+		err = checkNotificationSignature(ctx, storage, "UCunsubscribed000000000000", signHubBody("secret", "<feed/>"), body, false)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("storage_error", func(t *testing.T) {
+		failing := NewMockStorageClient()
+		failing.LoadError = ErrMockLoadFailure
+		err := checkNotificationSignature(ctx, failing, signedChannelID, "", body, false)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to load subscription state")
+	})
+}
+
+func TestHandleNotification_Signature(t *testing.T) {
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>signed123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Signed Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, signedChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	newDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: signedChannelID, Secret: "secret"}))
+		return deps
+	}
+
+	t.Run("valid_signature_is_processed", func(t *testing.T) {
+		deps := newDeps()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(notification))
+		req.Header.Set(HubSignatureHeader, signHubBody("secret", notification))
+		rec := httptest.NewRecorder()
+
+		handleNotification(deps)(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("invalid_signature_is_rejected", func(t *testing.T) {
+		deps := newDeps()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(notification))
+		req.Header.Set(HubSignatureHeader, signHubBody("forged", notification))
+		rec := httptest.NewRecorder()
+
+		handleNotification(deps)(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		var envelope APIResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+		assert.Equal(t, ErrCodeInvalidSignature, envelope.Code)
+		assert.Equal(t, 0, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+		assert.True(t, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[signedChannelID].LastNotificationAt.IsZero())
+	})
+
+	t.Run("storage_failure_is_internal_error", func(t *testing.T) {
+		deps := newDeps()
+		deps.StorageClient.(*MockStorageClient).LoadError = ErrMockLoadFailure
+		rec := httptest.NewRecorder()
+
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestSubscriptionSecrets(t *testing.T) {
+	t.Run("renewal_backfills_missing_secret", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		sub := &Subscription{ChannelID: signedChannelID, ExpiresAt: time.Now().Add(time.Hour)}
+		state := newSweepTestState(sub)
+
+		result := renewSubscription(context.Background(), signedChannelID, sub, state, deps)
+
+		assert.True(t, result.Success)
+		assert.NotEmpty(t, sub.Secret)
+		assert.Equal(t, sub.Secret, deps.PubSubClient.(*MockPubSubClient).GetLastSecret())
+	})
+
+	t.Run("detail_response_hides_secret", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: signedChannelID, Secret: "secret"}))
+
+		rec := httptest.NewRecorder()
+		handleGetSubscription(deps, signedChannelID)(rec, httptest.NewRequest("GET", "/subscriptions/"+signedChannelID, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "secret")
+	})
+}
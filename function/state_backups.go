@@ -0,0 +1,322 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/samsoir/youtube-webhook/function/config"
+)
+
+// Subscription state backups are stored one object per save, with an index listing
+// them so they can be found without listing the bucket
+const (
+	stateBackupPrefix      = "subscriptions/backups/"
+	stateBackupIndexObject = "subscriptions/backups/index.json"
+	stateBackupTimeFormat  = "20060102150405"
+)
+
+// stateBackupNamePattern matches the names generated by stateBackupName
+var stateBackupNamePattern = regexp.MustCompile(`^state-[0-9]{14}\.json$`)
+
+// StateBackup describes one stored copy of the subscription state
+type StateBackup struct {
+	Name          string    `json:"name"` // state-YYYYMMDDHHMMSS.json, in UTC
+	CreatedAt     time.Time `json:"created_at"`
+	Bytes         int       `json:"bytes"`
+	Subscriptions int       `json:"subscriptions"`
+	Version       string    `json:"version"` // Schema version of the backed up state
+}
+
+// stateBackupIndex is the stored index, oldest backup first
+type stateBackupIndex struct {
+	Entries []StateBackup `json:"entries"`
+}
+
+// StateBackupListResponse lists the stored backups, newest first
+type StateBackupListResponse struct {
+	Status        string        `json:"status"`
+	KeepCount     int           `json:"keep_count"`
+	RetentionDays int           `json:"retention_days"`
+	Count         int           `json:"count"`
+	Backups       []StateBackup `json:"backups"`
+}
+
+// StateBackupRestoreResponse reports what restoring a backup changed, or would change
+// on a dry run
+type StateBackupRestoreResponse struct {
+	StateImportResponse
+	Backup StateBackup `json:"backup"`
+}
+
+// StateBackupStore keeps a copy of the subscription state from every successful save.
+// Backups beyond the newest STATE_BACKUP_COUNT, or older than
+// STATE_BACKUP_RETENTION_DAYS, are deleted when new ones are stored.
+type StateBackupStore struct {
+	store     ObjectStorage
+	keep      int
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+// getStateBackupCount returns how many backups are kept.
+// STATE_BACKUP_COUNT=0 disables backups.
+func getStateBackupCount() int {
+	return envConfig().StateBackupCount
+}
+
+// getStateBackupRetention returns how long backups are kept
+func getStateBackupRetention() time.Duration {
+	return envConfig().StateBackupRetention
+}
+
+// NewStateBackupStore creates a backup store in the given storage.
+// Returns nil (the state is not backed up) when the storage cannot hold objects or
+// STATE_BACKUP_COUNT is zero.
+func NewStateBackupStore(storage StorageService, cfg *config.Config) *StateBackupStore {
+	store, ok := storage.(ObjectStorage)
+	keep := cfg.StateBackupCount
+	if !ok || keep == 0 {
+		return nil
+	}
+	return &StateBackupStore{store: store, keep: keep, retention: cfg.StateBackupRetention}
+}
+
+// stateBackupName returns the name of the backup taken at the given time
+func stateBackupName(createdAt time.Time) string {
+	return "state-" + createdAt.UTC().Format(stateBackupTimeFormat) + ".json"
+}
+
+// stateBackupObject returns the object a backup is stored in
+func stateBackupObject(name string) string {
+	return stateBackupPrefix + name
+}
+
+// loadIndex reads the index, treating a missing object as empty
+func (s *StateBackupStore) loadIndex(ctx context.Context) (*stateBackupIndex, error) {
+	index := &stateBackupIndex{}
+
+	data, err := s.store.ReadObject(ctx, stateBackupIndexObject)
+	if errors.Is(err, ErrObjectNotFound) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state backup index: %v", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to decode state backup index: %v", err)
+	}
+	return index, nil
+}
+
+// saveIndex writes the index
+func (s *StateBackupStore) saveIndex(ctx context.Context, index *stateBackupIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to encode state backup index: %v", err)
+	}
+	if err := s.store.WriteObject(ctx, stateBackupIndexObject, data); err != nil {
+		return fmt.Errorf("failed to write state backup index: %v", err)
+	}
+	return nil
+}
+
+// expired reports whether a backup is past the retention period
+func (s *StateBackupStore) expired(entry StateBackup, now time.Time) bool {
+	return now.Sub(entry.CreatedAt) > s.retention
+}
+
+// Add stores a copy of the state and deletes the backups that have expired or exceed
+// the count. Saves within the same second share one backup, the last one winning.
+// Failures to delete old backups are only logged.
+func (s *StateBackupStore) Add(ctx context.Context, state *SubscriptionState) (*StateBackup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state backup: %v", err)
+	}
+
+	now := currentTime(ctx)
+	backup := StateBackup{
+		Name:          stateBackupName(now),
+		CreatedAt:     now.UTC(),
+		Bytes:         len(data),
+		Subscriptions: len(state.Subscriptions),
+		Version:       state.Metadata.Version,
+	}
+	if err := s.store.WriteObject(ctx, stateBackupObject(backup.Name), data); err != nil {
+		return nil, fmt.Errorf("failed to write state backup: %v", err)
+	}
+
+	index, err := s.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entries []StateBackup
+	for _, entry := range index.Entries {
+		if entry.Name != backup.Name {
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, backup)
+
+	excess := len(entries) - s.keep
+	var kept []StateBackup
+	for i, entry := range entries {
+		if i < excess || s.expired(entry, now) {
+			if err := s.store.DeleteObject(ctx, stateBackupObject(entry.Name)); err != nil {
+				fmt.Printf("Unable to delete old state backup %s: %v\n", entry.Name, err)
+			}
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	index.Entries = kept
+	if err := s.saveIndex(ctx, index); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// List returns the backups within the retention period, newest first
+func (s *StateBackupStore) List(ctx context.Context) ([]StateBackup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := currentTime(ctx)
+	entries := make([]StateBackup, 0, len(index.Entries))
+	for i := len(index.Entries) - 1; i >= 0; i-- {
+		if !s.expired(index.Entries[i], now) {
+			entries = append(entries, index.Entries[i])
+		}
+	}
+	return entries, nil
+}
+
+// Get returns a backup's index entry and the state it holds
+func (s *StateBackupStore) Get(ctx context.Context, name string) (*StateBackup, *SubscriptionState, error) {
+	if !stateBackupNamePattern.MatchString(name) {
+		return nil, nil, ErrStateBackupNotFound
+	}
+
+	backups, err := s.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var backup *StateBackup
+	for i := range backups {
+		if backups[i].Name == name {
+			backup = &backups[i]
+		}
+	}
+	if backup == nil {
+		return nil, nil, ErrStateBackupNotFound
+	}
+
+	data, err := s.store.ReadObject(ctx, stateBackupObject(name))
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil, nil, ErrStateBackupNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read state backup: %v", err)
+	}
+
+	var state SubscriptionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode state backup: %v", err)
+	}
+	return backup, &state, nil
+}
+
+// backupSavedState stores a backup of a state that was just saved; failures are only
+// logged, since the save itself succeeded
+func backupSavedState(ctx context.Context, storage StorageService, state *SubscriptionState) {
+	backups := NewStateBackupStore(storage, envConfig())
+	if backups == nil {
+		return
+	}
+	if _, err := backups.Add(ctx, state); err != nil {
+		fmt.Printf("Unable to back up subscription state: %v\n", err)
+	}
+}
+
+// handleListStateBackups handles admin-only GET /admin/backups requests
+func handleListStateBackups(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r) {
+			return
+		}
+
+		backups := NewStateBackupStore(deps.StorageClient, deps.config())
+		if backups == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "",
+				"State backups are disabled (STATE_BACKUP_COUNT=0) or the storage backend cannot hold them")
+			return
+		}
+
+		entries, err := backups.List(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, StateBackupListResponse{
+			Status:        "success",
+			KeepCount:     backups.keep,
+			RetentionDays: int(backups.retention / (24 * time.Hour)),
+			Count:         len(entries),
+			Backups:       entries,
+		})
+	}
+}
+
+// handleRestoreStateBackup handles admin-only POST /admin/backups/{name}/restore
+// requests: the backed up state replaces the stored one, as a replace import would.
+// ?dry_run=true only reports what would change. Hub subscriptions are not touched.
+func handleRestoreStateBackup(deps *Dependencies, name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r) {
+			return
+		}
+
+		backups := NewStateBackupStore(deps.StorageClient, deps.config())
+		if backups == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "",
+				"State backups are disabled (STATE_BACKUP_COUNT=0) or the storage backend cannot hold them")
+			return
+		}
+
+		ctx := r.Context()
+		backup, state, err := backups.Get(ctx, name)
+		if errors.Is(err, ErrStateBackupNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "", fmt.Sprintf("State backup %s not found", name))
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+
+		dryRun := r.URL.Query().Get("dry_run") == "true"
+		response, status, err := applyImportedState(ctx, deps.StorageClient, state, ImportModeReplace, dryRun)
+		if err != nil {
+			writeErrorResponse(w, status, "", err.Error())
+			return
+		}
+		if !dryRun {
+			fmt.Printf("Restored state backup %s: %d subscriptions\n", backup.Name, response.Total)
+		}
+		writeJSONResponse(w, http.StatusOK, StateBackupRestoreResponse{StateImportResponse: *response, Backup: *backup})
+	}
+}
@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedStateBackup stores a backup of the state as if it had been taken at createdAt
+func seedStateBackup(t *testing.T, store *StateBackupStore, createdAt time.Time, state *SubscriptionState) StateBackup {
+	ctx := context.Background()
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	backup := StateBackup{
+		Name:          stateBackupName(createdAt),
+		CreatedAt:     createdAt.UTC(),
+		Bytes:         len(data),
+		Subscriptions: len(state.Subscriptions),
+		Version:       state.Metadata.Version,
+	}
+	require.NoError(t, store.store.WriteObject(ctx, stateBackupObject(backup.Name), data))
+
+	index, err := store.loadIndex(ctx)
+	require.NoError(t, err)
+	index.Entries = append(index.Entries, backup)
+	require.NoError(t, store.saveIndex(ctx, index))
+	return backup
+}
+
+// newBackupTestState returns a current-schema state holding the given channels
+func newBackupTestState(channelIDs ...string) *SubscriptionState {
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{}}
+	for _, channelID := range channelIDs {
+		state.Subscriptions[channelID] = &Subscription{ChannelID: channelID, Status: StatusActive}
+	}
+	state.Metadata.Version = CurrentSchemaVersion
+	return state
+}
+
+func TestGetStateBackupSettings(t *testing.T) {
+	defer os.Unsetenv("STATE_BACKUP_COUNT")
+	defer os.Unsetenv("STATE_BACKUP_RETENTION_DAYS")
+
+	countCases := map[string]int{"": 48, "10": 10, "0": 0, "-1": 48, "invalid": 48}
+	for value, expected := range countCases {
+		os.Setenv("STATE_BACKUP_COUNT", value)
+		assert.Equal(t, expected, getStateBackupCount(), "STATE_BACKUP_COUNT=%q", value)
+	}
+
+	retentionCases := map[string]time.Duration{"": 30 * 24 * time.Hour, "7": 7 * 24 * time.Hour, "0": 30 * 24 * time.Hour}
+	for value, expected := range retentionCases {
+		os.Setenv("STATE_BACKUP_RETENTION_DAYS", value)
+		assert.Equal(t, expected, getStateBackupRetention(), "STATE_BACKUP_RETENTION_DAYS=%q", value)
+	}
+}
+
+func TestNewStateBackupStore(t *testing.T) {
+	assert.NotNil(t, NewStateBackupStore(NewMockStorageClient(), envConfig()))
+
+	t.Setenv("STATE_BACKUP_COUNT", "0")
+	assert.Nil(t, NewStateBackupStore(NewMockStorageClient(), envConfig()), "a zero count disables backups")
+}
+
+func TestStateBackupStore(t *testing.T) {
+	ctx := context.Background()
+	channelID := "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+	t.Run("add_list_get", func(t *testing.T) {
+		store := NewStateBackupStore(NewMockStorageClient(), envConfig())
+		older := seedStateBackup(t, store, time.Now().Add(-time.Hour), newBackupTestState())
+
+		backup, err := store.Add(ctx, newBackupTestState(channelID))
+		require.NoError(t, err)
+		assert.Regexp(t, stateBackupNamePattern, backup.Name)
+		assert.Equal(t, 1, backup.Subscriptions)
+		assert.Equal(t, CurrentSchemaVersion, backup.Version)
+
+		backups, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+		assert.Equal(t, backup.Name, backups[0].Name, "newest first")
+		assert.Equal(t, older.Name, backups[1].Name)
+
+		entry, state, err := store.Get(ctx, backup.Name)
+		require.NoError(t, err)
+		assert.Equal(t, backup.Bytes, entry.Bytes)
+		assert.Contains(t, state.Subscriptions, channelID)
+	})
+
+	t.Run("same_second_replaces", func(t *testing.T) {
+		store := NewStateBackupStore(NewMockStorageClient(), envConfig())
+		_, err := store.Add(ctx, newBackupTestState())
+		require.NoError(t, err)
+		backup, err := store.Add(ctx, newBackupTestState(channelID))
+		require.NoError(t, err)
+
+		backups, err := store.List(ctx)
+		require.NoError(t, err)
+		if len(backups) == 1 { // Both saves fell in the same second
+			assert.Equal(t, 1, backups[0].Subscriptions, "the last save wins")
+		}
+		assert.Equal(t, backup.Name, backups[0].Name)
+	})
+
+	t.Run("prunes_beyond_count", func(t *testing.T) {
+		t.Setenv("STATE_BACKUP_COUNT", "2")
+		storage := NewMockStorageClient()
+		store := NewStateBackupStore(storage, envConfig())
+		oldest := seedStateBackup(t, store, time.Now().Add(-3*time.Hour), newBackupTestState())
+		kept := seedStateBackup(t, store, time.Now().Add(-2*time.Hour), newBackupTestState())
+
+		backup, err := store.Add(ctx, newBackupTestState(channelID))
+		require.NoError(t, err)
+
+		backups, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+		assert.Equal(t, backup.Name, backups[0].Name)
+		assert.Equal(t, kept.Name, backups[1].Name)
+
+		_, err = storage.ReadObject(ctx, stateBackupObject(oldest.Name))
+		assert.ErrorIs(t, err, ErrObjectNotFound, "pruned backups are deleted")
+	})
+
+	t.Run("prunes_expired", func(t *testing.T) {
+		t.Setenv("STATE_BACKUP_RETENTION_DAYS", "7")
+		storage := NewMockStorageClient()
+		store := NewStateBackupStore(storage, envConfig())
+		expired := seedStateBackup(t, store, time.Now().Add(-8*24*time.Hour), newBackupTestState())
+		recent := seedStateBackup(t, store, time.Now().Add(-6*24*time.Hour), newBackupTestState())
+
+		backups, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, backups, 1, "expired backups are not listed")
+		assert.Equal(t, recent.Name, backups[0].Name)
+
+		_, _, err = store.Get(ctx, expired.Name)
+		assert.ErrorIs(t, err, ErrStateBackupNotFound)
+
+		_, err = store.Add(ctx, newBackupTestState())
+		require.NoError(t, err)
+		_, err = storage.ReadObject(ctx, stateBackupObject(expired.Name))
+		assert.ErrorIs(t, err, ErrObjectNotFound)
+	})
+
+	t.Run("get_unknown", func(t *testing.T) {
+		store := NewStateBackupStore(NewMockStorageClient(), envConfig())
+		for _, name := range []string{"state-20200101000000.json", "../state.json", "index.json"} {
+			_, _, err := store.Get(ctx, name)
+			assert.ErrorIs(t, err, ErrStateBackupNotFound, name)
+		}
+	})
+}
+
+func TestCloudStorageService_BacksUpOnSave(t *testing.T) {
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+
+	require.NoError(t, service.SaveSubscriptionState(ctx, newBackupTestState("UCXuqSBlHAE6Xw-yeJA0Tunw")))
+
+	backups, err := NewStateBackupStore(service, envConfig()).List(ctx)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, 1, backups[0].Subscriptions)
+	assert.Contains(t, mockOps.objects, "test-bucket/"+stateBackupObject(backups[0].Name))
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("STATE_BACKUP_COUNT", "0")
+		mockOps := NewMockCloudStorageOperations()
+		service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+		require.NoError(t, service.SaveSubscriptionState(ctx, newBackupTestState()))
+		assert.NotContains(t, mockOps.objects, "test-bucket/"+stateBackupIndexObject)
+	})
+}
+
+func TestHandleStateBackups(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "admin-secret")
+	ctx := context.Background()
+	deps := CreateTestDependencies()
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(ctx,
+		newBackupTestState("UCXuqSBlHAE6Xw-yeJA0Tunw", "UC_x5XG1OV2P6uZZ5FSM9Ttw")))
+	backup := seedStateBackup(t, NewStateBackupStore(deps.StorageClient, envConfig()), time.Now().Add(-time.Hour),
+		newBackupTestState("UCXuqSBlHAE6Xw-yeJA0Tunw"))
+
+	serve := func(handler http.HandlerFunc, method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	restorePath := "/admin/backups/" + backup.Name + "/restore"
+
+	t.Run("requires_admin", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(handleListStateBackups(deps), "GET", "/admin/backups", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(handleRestoreStateBackup(deps, backup.Name), "POST", restorePath, "").Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		rec := serve(handleListStateBackups(deps), "GET", "/admin/backups", "admin-secret")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp StateBackupListResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, 48, resp.KeepCount)
+		assert.Equal(t, 30, resp.RetentionDays)
+		require.Equal(t, 1, resp.Count)
+		assert.Equal(t, backup.Name, resp.Backups[0].Name)
+	})
+
+	t.Run("restore_dry_run", func(t *testing.T) {
+		rec := serve(handleRestoreStateBackup(deps, backup.Name), "POST", restorePath+"?dry_run=true", "admin-secret")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp StateBackupRestoreResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.True(t, resp.DryRun)
+		assert.Equal(t, ImportModeReplace, resp.Mode)
+		assert.Equal(t, 1, resp.Removed)
+		assert.Equal(t, backup.Name, resp.Backup.Name)
+
+		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Len(t, state.Subscriptions, 2, "a dry run saves nothing")
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		rec := serve(handleRestoreStateBackup(deps, backup.Name), "POST", restorePath, "admin-secret")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Len(t, state.Subscriptions, 1)
+		assert.Contains(t, state.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		rec := serve(handleRestoreStateBackup(deps, "state-20200101000000.json"), "POST", "/admin/backups/state-20200101000000.json/restore", "admin-secret")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("STATE_BACKUP_COUNT", "0")
+		assert.Equal(t, http.StatusNotImplemented, serve(handleListStateBackups(deps), "GET", "/admin/backups", "admin-secret").Code)
+		assert.Equal(t, http.StatusNotImplemented, serve(handleRestoreStateBackup(deps, backup.Name), "POST", restorePath, "admin-secret").Code)
+	})
+}
+
+func TestRouter_StateBackupRoutes(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "admin-secret")
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	backup := seedStateBackup(t, NewStateBackupStore(deps.StorageClient, envConfig()), time.Now().Add(-time.Minute), newBackupTestState())
+
+	req := httptest.NewRequest("POST", "/admin/backups/"+backup.Name+"/restore?dry_run=true", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
@@ -0,0 +1,429 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// The subscription state is stored as one object per channel plus an index listing
+// them with the generation of each object, so single-channel updates rewrite one small
+// object and the index, and a changed state is read one changed channel at a time
+const (
+	stateIndexObject   = "subscriptions/index.json"
+	stateChannelPrefix = "subscriptions/channels/"
+	legacyStateObject  = "subscriptions/state.json" // Single object written by older builds
+)
+
+// stateChannelConcurrency bounds the channel objects read or written at once
+const stateChannelConcurrency = 16
+
+// stateIndexUpdateAttempts bounds the attempts of an index update that keeps racing
+// updates from other instances
+const stateIndexUpdateAttempts = 5
+
+// stateIndex is the stored index: every channel with the generation of its object, the
+// removed channels kept for restoring, and the state metadata
+type stateIndex struct {
+	Channels    map[string]int64                `json:"channels"`
+	Removed     map[string]*RemovedSubscription `json:"removed,omitempty"`
+	LastUpdated time.Time                       `json:"last_updated"`
+	Version     string                          `json:"version"`
+}
+
+// stateChannel is a stored channel object; each carries the schema version it was
+// written with, since channels are written on their own
+type stateChannel struct {
+	Version      string        `json:"version"`
+	Subscription *Subscription `json:"subscription"`
+}
+
+// stateSnapshot is a state as stored: the generation of the index it was read or
+// written with, and the generation of every channel object
+type stateSnapshot struct {
+	state      *SubscriptionState
+	generation int64
+	channels   map[string]int64
+}
+
+// index returns the index the snapshot was read with and its generation, nil when
+// none was stored
+func (snapshot *stateSnapshot) index() (*stateIndex, int64) {
+	if snapshot.generation == 0 {
+		return nil, 0
+	}
+	return &stateIndex{
+		Channels:    copyGenerations(snapshot.channels),
+		Removed:     snapshot.state.Removed,
+		LastUpdated: snapshot.state.Metadata.LastUpdated,
+		Version:     snapshot.state.Metadata.Version,
+	}, snapshot.generation
+}
+
+// stateChannelObject returns the object a channel is stored in
+func stateChannelObject(channelID string) string {
+	return stateChannelPrefix + channelID + ".json"
+}
+
+// copyGenerations returns a copy of a channel generation map
+func copyGenerations(generations map[string]int64) map[string]int64 {
+	copied := make(map[string]int64, len(generations))
+	for channelID, generation := range generations {
+		copied[channelID] = generation
+	}
+	return copied
+}
+
+// sameSubscription reports whether two subscriptions would be stored identically
+func sameSubscription(a, b *Subscription) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
+
+// decodeStateIndex decodes a stored index
+func decodeStateIndex(data []byte) (*stateIndex, error) {
+	data, err := decodeStateObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var index stateIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state index: %v", err)
+	}
+	if index.Channels == nil {
+		index.Channels = make(map[string]int64)
+	}
+	return &index, nil
+}
+
+// decodeChannelObject decodes a stored channel, migrating it to the current schema
+func decodeChannelObject(channelID string, data []byte) (*Subscription, error) {
+	data, err := decodeStateObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var channel stateChannel
+	if err := json.Unmarshal(data, &channel); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal channel %s: %v", channelID, err)
+	}
+	if channel.Subscription == nil {
+		return nil, fmt.Errorf("failed to unmarshal channel %s: no subscription", channelID)
+	}
+	return migrateSubscription(channelID, channel.Subscription, channel.Version)
+}
+
+// subscriptionState returns the state the index describes, without its channels,
+// bringing the removed channels it holds up to the current schema
+func (index *stateIndex) subscriptionState() (*SubscriptionState, error) {
+	state := &SubscriptionState{Subscriptions: make(map[string]*Subscription), Removed: index.Removed}
+	state.Metadata.LastUpdated = index.LastUpdated
+	state.Metadata.Version = index.Version
+	if _, err := migrateSubscriptionState(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// readIndex reads the index and its generation, or nil when none is stored. While the
+// cache is fresh the read is conditional, returning ErrObjectNotModified while the
+// index is still at the cached generation, or still missing for generation 0.
+func (s *CloudStorageService) readIndex(ctx context.Context, cached *stateSnapshot, fresh bool) (*stateIndex, int64, error) {
+	var data []byte
+	var generation int64
+	var err error
+	if fresh {
+		data, generation, err = s.storageOps.GetObjectIfChanged(ctx, s.bucketName, s.objectPath, cached.generation)
+		if err == storage.ErrObjectNotExist && cached.generation == 0 {
+			err = ErrObjectNotModified
+		}
+	} else {
+		data, generation, err = s.storageOps.GetObjectWithGeneration(ctx, s.bucketName, s.objectPath)
+	}
+	if errors.Is(err, ErrObjectNotModified) {
+		return nil, 0, err
+	}
+	if err == storage.ErrObjectNotExist {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get storage object: %v", err)
+	}
+
+	index, err := decodeStateIndex(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return index, generation, nil
+}
+
+// readChannels returns the listed channels and the generations read, reusing the
+// cached channels still at the listed generation and reading the others concurrently.
+// A channel whose object is gone was deleted since the index was read, and is left out.
+func (s *CloudStorageService) readChannels(ctx context.Context, channels map[string]int64, cached *stateSnapshot) (map[string]*Subscription, map[string]int64, error) {
+	subscriptions := make(map[string]*Subscription, len(channels))
+	generations := make(map[string]int64, len(channels))
+
+	var stale []string
+	for channelID, generation := range channels {
+		if cached != nil && cached.channels[channelID] == generation && cached.state.Subscriptions[channelID] != nil {
+			subscriptions[channelID] = cached.state.Subscriptions[channelID]
+			generations[channelID] = generation
+			continue
+		}
+		stale = append(stale, channelID)
+	}
+
+	var mu sync.Mutex
+	err := forEachChannel(stale, func(channelID string) error {
+		data, generation, err := s.storageOps.GetObjectWithGeneration(ctx, s.bucketName, stateChannelObject(channelID))
+		if err == storage.ErrObjectNotExist {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get storage object: %v", err)
+		}
+		subscription, err := decodeChannelObject(channelID, data)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		subscriptions[channelID] = subscription
+		generations[channelID] = generation
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return subscriptions, generations, nil
+}
+
+// writeChannels writes the given channels of subscriptions concurrently, returning the
+// generation each was written as
+func (s *CloudStorageService) writeChannels(ctx context.Context, subscriptions map[string]*Subscription, channelIDs []string) (map[string]int64, error) {
+	generations := make(map[string]int64, len(channelIDs))
+
+	var mu sync.Mutex
+	err := forEachChannel(channelIDs, func(channelID string) error {
+		data, err := s.encodeObject(stateChannel{Version: CurrentSchemaVersion, Subscription: subscriptions[channelID]})
+		if err != nil {
+			return err
+		}
+		generation, err := s.storageOps.PutObjectWithGeneration(ctx, s.bucketName, stateChannelObject(channelID), data)
+		if err != nil {
+			return fmt.Errorf("failed to put storage object: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		generations[channelID] = generation
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return generations, nil
+}
+
+// forEachChannel calls fn for every channel with up to stateChannelConcurrency calls
+// running at once, returning the first error
+func forEachChannel(channelIDs []string, fn func(channelID string) error) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	slots := make(chan struct{}, stateChannelConcurrency)
+	for _, channelID := range channelIDs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(channelID string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fn(channelID); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(channelID)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// updateIndex applies a change to the stored index and writes it back, as long as no
+// other instance wrote it meanwhile; otherwise the change is applied again to the
+// newer index. When from is given, the first attempt starts from the index it was
+// read with instead of reading it again. Returns the generations the index was read
+// and written at.
+func (s *CloudStorageService) updateIndex(ctx context.Context, from *stateSnapshot, change func(index *stateIndex)) (int64, int64, error) {
+	for attempt := 1; ; attempt++ {
+		var index *stateIndex
+		var generation int64
+		if attempt == 1 && from != nil {
+			index, generation = from.index()
+		} else {
+			var err error
+			if index, generation, err = s.readIndex(ctx, nil, false); err != nil {
+				return 0, 0, err
+			}
+		}
+		if index == nil {
+			index = &stateIndex{Channels: make(map[string]int64), Version: CurrentSchemaVersion}
+		}
+		if index.Version != CurrentSchemaVersion {
+			return 0, 0, fmt.Errorf("%w: index is version %s, this build writes %s",
+				ErrSchemaOutdated, index.Version, CurrentSchemaVersion)
+		}
+
+		change(index)
+		index.LastUpdated = currentTime(ctx)
+		data, err := s.encodeObject(index)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		written, err := s.storageOps.PutObjectIfGeneration(ctx, s.bucketName, s.objectPath, data, generation)
+		if errors.Is(err, ErrObjectChanged) && attempt < stateIndexUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to put storage object: %w", err)
+		}
+		return generation, written, nil
+	}
+}
+
+// migrateLegacyState moves a state stored by older builds as the single state.json
+// object into the per-channel layout. The channels and index are written as a save
+// would write them, then state.json is deleted. Nothing happens once an index exists.
+func (s *CloudStorageService) migrateLegacyState(ctx context.Context) error {
+	// Checked first: once moved, the common case costs one read of a missing object
+	data, _, err := s.storageOps.GetObjectWithGeneration(ctx, s.bucketName, legacyStateObject)
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get storage object: %v", err)
+	}
+	_, _, err = s.storageOps.GetObjectWithGeneration(ctx, s.bucketName, s.objectPath)
+	if err == nil {
+		fmt.Printf("Ignoring %s: subscription state is already stored per channel\n", legacyStateObject)
+		return nil
+	}
+	if err != storage.ErrObjectNotExist {
+		return fmt.Errorf("failed to get storage object: %v", err)
+	}
+
+	data, err = decodeStateObject(data)
+	if err != nil {
+		return err
+	}
+
+	var state SubscriptionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to unmarshal state: %v", err)
+	}
+	if state.Subscriptions == nil {
+		state.Subscriptions = make(map[string]*Subscription)
+	}
+	// Bring states written by older builds up to the current schema
+	if _, err := migrateSubscriptionState(&state); err != nil {
+		return err
+	}
+
+	if err := s.writeState(ctx, &state); err != nil {
+		return fmt.Errorf("failed to move state to per-channel objects: %w", err)
+	}
+	// The state is in the index now; a leftover state.json is no longer read
+	if err := s.storageOps.DeleteObject(ctx, s.bucketName, legacyStateObject); err != nil && err != storage.ErrObjectNotExist {
+		fmt.Printf("Unable to delete %s: %v\n", legacyStateObject, err)
+	}
+	fmt.Printf("Moved subscription state to per-channel objects: %d channels\n", len(state.Subscriptions))
+	return nil
+}
+
+// StreamSubscriptions reads the index, then its channels a few at a time, reusing the
+// cached channels still at their generation. Channels read are not cached, so a stream
+// holds only the channels being passed to fn.
+func (s *CloudStorageService) StreamSubscriptions(ctx context.Context, fn func(*Subscription) error) error {
+	if err := s.initialize(ctx); err != nil {
+		return err
+	}
+
+	// Saves not yet written are newer than anything stored
+	if unsaved := s.getUnsavedState(); unsaved != nil {
+		return streamState(unsaved, fn)
+	}
+
+	cached, fresh := s.getCachedState()
+	index, _, err := s.readIndex(ctx, cached, fresh)
+	if errors.Is(err, ErrObjectNotModified) {
+		return streamState(s.deepCopyState(cached.state), fn)
+	}
+	if err != nil {
+		return err
+	}
+	if index == nil {
+		return nil
+	}
+
+	channelIDs := make([]string, 0, len(index.Channels))
+	for channelID := range index.Channels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for start := 0; start < len(channelIDs); start += stateChannelConcurrency {
+		end := start + stateChannelConcurrency
+		if end > len(channelIDs) {
+			end = len(channelIDs)
+		}
+		chunk := make(map[string]int64, end-start)
+		for _, channelID := range channelIDs[start:end] {
+			chunk[channelID] = index.Channels[channelID]
+		}
+
+		subscriptions, _, err := s.readChannels(ctx, chunk, cached)
+		if err != nil {
+			return err
+		}
+		for _, channelID := range channelIDs[start:end] {
+			if subscription := subscriptions[channelID]; subscription != nil {
+				if err := fn(copySubscription(subscription)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// streamState calls fn with each subscription of a loaded state, by channel ID
+func streamState(state *SubscriptionState, fn func(*Subscription) error) error {
+	channelIDs := make([]string, 0, len(state.Subscriptions))
+	for channelID := range state.Subscriptions {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for _, channelID := range channelIDs {
+		if err := fn(state.Subscriptions[channelID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// How an imported state is applied to the stored one
+const (
+	ImportModeReplace = "replace" // The imported state becomes the whole state
+	ImportModeMerge   = "merge"   // Imported subscriptions are added or overwrite stored ones
+)
+
+// maxStateImportBytes caps the size of an imported state document
+const maxStateImportBytes = 10 << 20
+
+// StateExportResponse carries the full subscription state, including hub and sink
+// secrets, so it can be imported into another deployment
+type StateExportResponse struct {
+	Status     string             `json:"status"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Count      int                `json:"count"`
+	State      *SubscriptionState `json:"state"`
+}
+
+// StateImportResponse reports what an import changed, or would change on a dry run
+type StateImportResponse struct {
+	Status   string `json:"status"`
+	Mode     string `json:"mode"`
+	DryRun   bool   `json:"dry_run"`
+	Imported int    `json:"imported"` // Subscriptions in the imported state
+	Added    int    `json:"added"`    // Imported channels that were not stored
+	Updated  int    `json:"updated"`  // Imported channels that overwrite stored ones
+	Removed  int    `json:"removed"`  // Stored channels dropped by a replace
+	Total    int    `json:"total"`    // Subscriptions stored after the import
+}
+
+// importableStatuses are the lifecycle states a subscription can be stored in
+var importableStatuses = map[string]bool{
+	StatusPending:     true,
+	StatusVerified:    true,
+	StatusActive:      true,
+	StatusExpired:     true,
+	StatusQuarantined: true,
+}
+
+// validateImportedState checks every subscription in an imported state, filling in a
+// missing channel ID from its key. All problems are reported together.
+func validateImportedState(state *SubscriptionState) error {
+	if state == nil || state.Subscriptions == nil {
+		return fmt.Errorf("state must contain a subscriptions object")
+	}
+
+	var problems []string
+	for channelID, sub := range state.Subscriptions {
+		if err := validateImportedSubscription(channelID, sub); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", channelID, err))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid subscriptions: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateImportedSubscription checks one imported subscription stored under channelID
+func validateImportedSubscription(channelID string, sub *Subscription) error {
+	if !validateTopicID(channelID) {
+		return fmt.Errorf("invalid channel ID format")
+	}
+	if sub == nil {
+		return fmt.Errorf("subscription is empty")
+	}
+	if sub.ChannelID == "" {
+		sub.ChannelID = channelID
+	}
+	if sub.ChannelID != channelID {
+		return fmt.Errorf("stored under a different channel ID (%s)", sub.ChannelID)
+	}
+	if !importableStatuses[sub.Status] {
+		return fmt.Errorf("unknown status %q", sub.Status)
+	}
+	if err := validateRepositoryTargets(sub.Repositories); err != nil {
+		return err
+	}
+	if err := validateWebhookSinks(sub.Sinks); err != nil {
+		return err
+	}
+	if sub.Filter != nil {
+		if err := sub.Filter.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleExportState handles admin-only GET /admin/state requests
+func handleExportState(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r) {
+			return
+		}
+
+		state, err := deps.StorageClient.LoadSubscriptionState(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "",
+				fmt.Sprintf("Unable to load subscription state from storage: %v", err))
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, StateExportResponse{
+			Status:     "success",
+			ExportedAt: deps.now(),
+			Count:      len(state.Subscriptions),
+			State:      state,
+		})
+	}
+}
+
+// handleImportState handles admin-only PUT /admin/state requests. The body is a
+// subscription state as returned by GET /admin/state (the "state" object). ?mode=merge
+// keeps stored channels the import does not mention; ?dry_run=true only validates and
+// reports what would change. Hub subscriptions are not touched.
+func handleImportState(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r) {
+			return
+		}
+
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			mode = ImportModeReplace
+		}
+		if mode != ImportModeReplace && mode != ImportModeMerge {
+			writeErrorResponse(w, http.StatusBadRequest, "",
+				fmt.Sprintf("mode must be %q or %q", ImportModeReplace, ImportModeMerge))
+			return
+		}
+		dryRun := r.URL.Query().Get("dry_run") == "true"
+
+		var imported SubscriptionState
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStateImportBytes)).Decode(&imported); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "", fmt.Sprintf("Request body must be a subscription state: %v", err))
+			return
+		}
+		response, status, err := applyImportedState(r.Context(), deps.StorageClient, &imported, mode, dryRun)
+		if err != nil {
+			writeErrorResponse(w, status, "", err.Error())
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+// applyImportedState validates and migrates an imported state and applies it to the
+// stored one in the given mode, saving it unless dryRun is set. On failure it returns
+// the HTTP status to answer with.
+func applyImportedState(ctx context.Context, storage StorageService, imported *SubscriptionState, mode string, dryRun bool) (*StateImportResponse, int, error) {
+	if err := validateImportedState(imported); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	// Exports from older builds are migrated; newer ones cannot be imported
+	if _, err := migrateSubscriptionState(imported); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	state, err := storage.LoadSubscriptionState(ctx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("Unable to load subscription state from storage: %v", err)
+	}
+
+	response := &StateImportResponse{
+		Status:   "success",
+		Mode:     mode,
+		DryRun:   dryRun,
+		Imported: len(imported.Subscriptions),
+	}
+	for channelID := range imported.Subscriptions {
+		if _, exists := state.Subscriptions[channelID]; exists {
+			response.Updated++
+		} else {
+			response.Added++
+		}
+	}
+
+	if mode == ImportModeReplace {
+		response.Removed = len(state.Subscriptions) - response.Updated
+		state.Subscriptions = imported.Subscriptions
+	} else {
+		for channelID, sub := range imported.Subscriptions {
+			state.Subscriptions[channelID] = sub
+		}
+	}
+	response.Total = len(state.Subscriptions)
+
+	if !dryRun {
+		if err := storage.SaveSubscriptionState(ctx, state); err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("Failed to save subscription state: %v", err)
+		}
+		fmt.Printf("Imported %d subscriptions (%s): %d added, %d updated, %d removed\n",
+			response.Imported, mode, response.Added, response.Updated, response.Removed)
+	}
+	return response, http.StatusOK, nil
+}
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	transferChannelA = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	transferChannelB = "UC_x5XG1OV2P6uZZ5FSM9Ttw"
+	transferChannelC = "UCBJycsmduvYEL83R_U4JriQ"
+)
+
+// seedTransferState stores active subscriptions for the given channels
+func seedTransferState(t *testing.T, deps *Dependencies, channelIDs ...string) {
+	state := &SubscriptionState{Subscriptions: make(map[string]*Subscription)}
+	for _, channelID := range channelIDs {
+		state.Subscriptions[channelID] = &Subscription{
+			ChannelID: channelID,
+			Status:    StatusActive,
+			Secret:    "secret-" + channelID,
+			ExpiresAt: time.Now().Add(24 * time.Hour),
+		}
+	}
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(context.Background(), state))
+}
+
+func TestHandleExportState(t *testing.T) {
+	os.Setenv("ADMIN_TOKEN", "admin-secret")
+	defer os.Unsetenv("ADMIN_TOKEN")
+
+	t.Run("exports_full_state", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedTransferState(t, deps, transferChannelA, transferChannelB)
+
+		req := httptest.NewRequest("GET", "/admin/state", nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		handleExportState(deps)(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response StateExportResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, 2, response.Count)
+		require.Contains(t, response.State.Subscriptions, transferChannelA)
+		assert.Equal(t, "secret-"+transferChannelA, response.State.Subscriptions[transferChannelA].Secret)
+	})
+
+	t.Run("storage_error", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).LoadError = ErrMockLoadFailure
+
+		req := httptest.NewRequest("GET", "/admin/state", nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		handleExportState(deps)(rec, req)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("requires_admin_token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handleExportState(CreateTestDependencies())(rec, httptest.NewRequest("GET", "/admin/state", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestHandleImportState(t *testing.T) {
+	os.Setenv("ADMIN_TOKEN", "admin-secret")
+	defer os.Unsetenv("ADMIN_TOKEN")
+
+	importState := func(deps *Dependencies, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		handleImportState(deps)(rec, req)
+		return rec
+	}
+
+	imported := `{"subscriptions": {
+		"` + transferChannelB + `": {"status": "active", "secret": "imported"},
+		"` + transferChannelC + `": {"channel_id": "` + transferChannelC + `", "status": "expired"}
+	}}`
+
+	t.Run("replace", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedTransferState(t, deps, transferChannelA, transferChannelB)
+
+		rec := importState(deps, "/admin/state", imported)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var response StateImportResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, StateImportResponse{
+			Status: "success", Mode: ImportModeReplace, Imported: 2, Added: 1, Updated: 1, Removed: 1, Total: 2,
+		}, response)
+
+		state, err := deps.StorageClient.LoadSubscriptionState(context.Background())
+		require.NoError(t, err)
+		assert.NotContains(t, state.Subscriptions, transferChannelA)
+		require.Contains(t, state.Subscriptions, transferChannelB)
+		assert.Equal(t, transferChannelB, state.Subscriptions[transferChannelB].ChannelID, "channel ID filled in from key")
+		assert.Equal(t, "imported", state.Subscriptions[transferChannelB].Secret)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedTransferState(t, deps, transferChannelA, transferChannelB)
+
+		rec := importState(deps, "/admin/state?mode=merge", imported)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var response StateImportResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 0, response.Removed)
+		assert.Equal(t, 3, response.Total)
+
+		state, err := deps.StorageClient.LoadSubscriptionState(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, state.Subscriptions, 3)
+		assert.Equal(t, "secret-"+transferChannelA, state.Subscriptions[transferChannelA].Secret)
+	})
+
+	t.Run("dry_run_does_not_save", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		seedTransferState(t, deps, transferChannelA)
+		storage := deps.StorageClient.(*MockStorageClient)
+		saves := storage.SaveCallCount
+
+		rec := importState(deps, "/admin/state?dry_run=true", imported)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response StateImportResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.True(t, response.DryRun)
+		assert.Equal(t, 1, response.Removed)
+		assert.Equal(t, saves, storage.SaveCallCount)
+	})
+
+	t.Run("validation_errors", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"not_json", `not json`},
+			{"missing_subscriptions", `{}`},
+			{"invalid_channel_id", `{"subscriptions": {"bad": {"status": "active"}}}`},
+			{"mismatched_channel_id", `{"subscriptions": {"` + transferChannelA + `": {"channel_id": "` + transferChannelB + `", "status": "active"}}}`},
+			{"unknown_status", `{"subscriptions": {"` + transferChannelA + `": {"status": "paused"}}}`},
+			{"invalid_repository", `{"subscriptions": {"` + transferChannelA + `": {"status": "active", "repositories": [{"repo_owner": "", "repo_name": "x"}]}}}`},
+			{"null_subscription", `{"subscriptions": {"` + transferChannelA + `": null}}`},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				deps := CreateTestDependencies()
+				rec := importState(deps, "/admin/state", tc.body)
+				assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+				assert.Equal(t, 0, deps.StorageClient.(*MockStorageClient).SaveCallCount)
+			})
+		}
+	})
+
+	t.Run("invalid_mode", func(t *testing.T) {
+		rec := importState(CreateTestDependencies(), "/admin/state?mode=append", imported)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("save_error", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SaveError = ErrMockSaveFailure
+
+		rec := importState(deps, "/admin/state", imported)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("routed", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		SetDependencies(deps)
+		defer SetDependencies(nil)
+
+		req := httptest.NewRequest("PUT", "/admin/state", strings.NewReader(imported))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		YouTubeWebhook(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		req = httptest.NewRequest("GET", "/admin/state", nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec = httptest.NewRecorder()
+		YouTubeWebhook(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var response StateExportResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.Count)
+	})
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Windows GET /stats aggregates the notification history over
+const (
+	statsShortWindow = 24 * time.Hour
+	statsLongWindow  = 7 * 24 * time.Hour
+)
+
+// StatsResponse is returned by GET /stats: notification activity aggregated from the
+// persisted notification history
+type StatsResponse struct {
+	Status      string         `json:"status"`
+	GeneratedAt string         `json:"generated_at"`
+	Last24h     StatsWindow    `json:"last_24h"`
+	Last7d      StatsWindow    `json:"last_7d"`
+	Channels    []ChannelStats `json:"channels"`          // Most active first
+	Partial     bool           `json:"partial,omitempty"` // A channel's history is full and ends within 7 days, so counts are lower bounds
+}
+
+// StatsWindow counts the notifications processed within a window
+type StatsWindow struct {
+	Notifications  int            `json:"notifications"`
+	Decisions      map[string]int `json:"decisions"`        // Notifications per history decision
+	Dispatched     int            `json:"dispatched"`       // Dispatched to GitHub, including replays
+	DispatchFailed int            `json:"dispatch_failed"`  // Dispatch failed and was dead-lettered or dropped
+	QueuedForRetry int            `json:"queued_for_retry"` // Dispatch failed and will be retried
+
+	// Average time from a new video's publication to the hub's notification arriving;
+	// omitted when no new video was notified
+	AverageHubLatencySeconds float64 `json:"average_hub_latency_seconds,omitempty"`
+
+	latencyTotal time.Duration
+	latencyCount int
+}
+
+// ChannelStats is one channel's activity over the last 7 days
+type ChannelStats struct {
+	ChannelID          string `json:"channel_id"`
+	ChannelName        string `json:"channel_name,omitempty"`
+	Notifications24h   int    `json:"notifications_24h"`
+	Notifications7d    int    `json:"notifications_7d"`
+	Dispatched7d       int    `json:"dispatched_7d"`
+	DispatchFailed7d   int    `json:"dispatch_failed_7d"`
+	LastNotificationAt string `json:"last_notification_at,omitempty"`
+}
+
+// add counts one history entry in the window
+func (s *StatsWindow) add(entry HistoryEntry) {
+	s.Notifications++
+	s.Decisions[entry.Decision]++
+	switch entry.Decision {
+	case HistoryDecisionDispatched, HistoryDecisionReplayed:
+		s.Dispatched++
+	case HistoryDecisionFailed:
+		s.DispatchFailed++
+	case HistoryDecisionQueuedForRetry:
+		s.QueuedForRetry++
+	}
+
+	if latency, ok := hubLatency(entry); ok {
+		s.latencyTotal += latency
+		s.latencyCount++
+		s.AverageHubLatencySeconds = (s.latencyTotal / time.Duration(s.latencyCount)).Seconds()
+	}
+}
+
+// hubLatency returns how long after publication the hub notified a new video. Updates to
+// old videos, redeliveries, replays, premieres and live streams say nothing about the
+// hub's delay and are skipped.
+func hubLatency(entry HistoryEntry) (time.Duration, bool) {
+	switch entry.Decision {
+	case HistoryDecisionNotNew, HistoryDecisionDuplicate, HistoryDecisionReplayed, HistoryDecisionPremiereHeld:
+		return 0, false
+	}
+	if entry.HeldAt != "" || entry.LiveBroadcastContent == BroadcastUpcoming || entry.LiveBroadcastContent == BroadcastLive {
+		return 0, false
+	}
+	published, err := time.Parse(time.RFC3339, entry.Published)
+	if err != nil || entry.ReceivedAt.Before(published) {
+		return 0, false
+	}
+	return entry.ReceivedAt.Sub(published), true
+}
+
+// handleGetStats handles GET /stats requests using dependency injection: notification
+// counts over the last 24 hours and 7 days, dispatch outcomes, the average hub latency
+// and each subscribed channel's activity, computed from the notification history
+func handleGetStats(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		history := NewNotificationHistory(deps.StorageClient, deps.config())
+		if history == nil {
+			writeErrorResponse(w, http.StatusNotImplemented, "",
+				"Notification history is disabled or not supported by the storage backend")
+			return
+		}
+
+		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "",
+				fmt.Sprintf("Unable to load subscription state from storage: %v", err))
+			return
+		}
+
+		now := deps.now()
+		response := StatsResponse{
+			Status:      "success",
+			GeneratedAt: now.Format(timeFormat()),
+			Last24h:     StatsWindow{Decisions: map[string]int{}},
+			Last7d:      StatsWindow{Decisions: map[string]int{}},
+			Channels:    make([]ChannelStats, 0, len(state.Subscriptions)),
+		}
+
+		for channelID, sub := range state.Subscriptions {
+			entries, err := history.List(ctx, channelID, 0)
+			if err != nil {
+				writeErrorResponse(w, http.StatusInternalServerError, channelID, err.Error())
+				return
+			}
+
+			channel := ChannelStats{ChannelID: channelID, ChannelName: sub.ChannelName}
+			if len(entries) > 0 {
+				channel.LastNotificationAt = entries[0].ReceivedAt.Format(timeFormat())
+				oldest := entries[len(entries)-1].ReceivedAt
+				if len(entries) >= history.size && now.Sub(oldest) < statsLongWindow {
+					response.Partial = true
+				}
+			}
+
+			for _, entry := range entries {
+				age := now.Sub(entry.ReceivedAt)
+				if age >= statsLongWindow {
+					break // Entries are newest first
+				}
+				response.Last7d.add(entry)
+				channel.Notifications7d++
+				switch entry.Decision {
+				case HistoryDecisionDispatched, HistoryDecisionReplayed:
+					channel.Dispatched7d++
+				case HistoryDecisionFailed:
+					channel.DispatchFailed7d++
+				}
+				if age < statsShortWindow {
+					response.Last24h.add(entry)
+					channel.Notifications24h++
+				}
+			}
+			response.Channels = append(response.Channels, channel)
+		}
+
+		sort.Slice(response.Channels, func(i, j int) bool {
+			a, b := response.Channels[i], response.Channels[j]
+			if a.Notifications7d != b.Notifications7d {
+				return a.Notifications7d > b.Notifications7d
+			}
+			return a.ChannelID < b.ChannelID
+		})
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
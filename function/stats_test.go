@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getStats calls GET /stats and decodes the response
+func getStats(t *testing.T, deps *Dependencies) StatsResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleGetStats(deps)(rec, httptest.NewRequest("GET", "/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response StatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response
+}
+
+func TestHubLatency(t *testing.T) {
+	received := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		entry    HistoryEntry
+		expected time.Duration
+		ok       bool
+	}{
+		{"new_video", HistoryEntry{ReceivedAt: received, Published: "2026-10-16T08:59:30Z", Decision: HistoryDecisionDispatched}, 30 * time.Second, true},
+		{"filtered", HistoryEntry{ReceivedAt: received, Published: "2026-10-16T08:58:00Z", Decision: HistoryDecisionFiltered}, 2 * time.Minute, true},
+		{"old_video_update", HistoryEntry{ReceivedAt: received, Published: "2026-10-01T08:00:00Z", Decision: HistoryDecisionNotNew}, 0, false},
+		{"redelivery", HistoryEntry{ReceivedAt: received, Published: "2026-10-16T08:00:00Z", Decision: HistoryDecisionDuplicate}, 0, false},
+		{"unparseable", HistoryEntry{ReceivedAt: received, Published: "yesterday", Decision: HistoryDecisionDispatched}, 0, false},
+		{"clock_skew", HistoryEntry{ReceivedAt: received, Published: "2026-10-16T09:00:10Z", Decision: HistoryDecisionDispatched}, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			latency, ok := hubLatency(tc.entry)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, latency)
+		})
+	}
+}
+
+func TestHandleGetStats(t *testing.T) {
+	deps := CreateTestDependencies()
+	ctx := t.Context()
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(ctx, &SubscriptionState{Subscriptions: map[string]*Subscription{
+		historyTestChannel: {ChannelID: historyTestChannel, ChannelName: "Busy Channel", Status: StatusActive},
+		eventsOtherChannel: {ChannelID: eventsOtherChannel, Status: StatusActive},
+	}}))
+
+	now := time.Now()
+	published := func(ago time.Duration) string { return now.Add(-ago).UTC().Format(time.RFC3339) }
+	history := NewNotificationHistory(deps.StorageClient, envConfig())
+	for _, entry := range []HistoryEntry{
+		{ReceivedAt: now.Add(-10 * 24 * time.Hour), VideoID: "ancient", Decision: HistoryDecisionDispatched},
+		{ReceivedAt: now.Add(-3 * 24 * time.Hour), VideoID: "older", Published: published(3*24*time.Hour + time.Minute), Decision: HistoryDecisionFailed},
+		{ReceivedAt: now.Add(-2 * time.Hour), VideoID: "recent", Published: published(2*time.Hour + 20*time.Second), Decision: HistoryDecisionDispatched},
+		{ReceivedAt: now.Add(-time.Hour), VideoID: "update", Published: published(30 * 24 * time.Hour), Decision: HistoryDecisionNotNew},
+	} {
+		require.NoError(t, history.Append(ctx, historyTestChannel, entry))
+	}
+	require.NoError(t, history.Append(ctx, eventsOtherChannel, HistoryEntry{
+		ReceivedAt: now.Add(-30 * time.Minute), VideoID: "queued", Published: published(30*time.Minute + 40*time.Second),
+		Decision: HistoryDecisionQueuedForRetry,
+	}))
+
+	response := getStats(t, deps)
+
+	assert.Equal(t, 3, response.Last24h.Notifications)
+	assert.Equal(t, 1, response.Last24h.Dispatched)
+	assert.Equal(t, 1, response.Last24h.QueuedForRetry)
+	assert.Equal(t, 0, response.Last24h.DispatchFailed)
+	assert.Equal(t, map[string]int{HistoryDecisionDispatched: 1, HistoryDecisionNotNew: 1, HistoryDecisionQueuedForRetry: 1}, response.Last24h.Decisions)
+	assert.InDelta(t, 30, response.Last24h.AverageHubLatencySeconds, 1, "the update to an old video is left out")
+
+	assert.Equal(t, 4, response.Last7d.Notifications, "entries older than 7 days are not counted")
+	assert.Equal(t, 1, response.Last7d.DispatchFailed)
+	assert.InDelta(t, 40, response.Last7d.AverageHubLatencySeconds, 1)
+	assert.False(t, response.Partial)
+
+	require.Len(t, response.Channels, 2)
+	busy := response.Channels[0]
+	assert.Equal(t, historyTestChannel, busy.ChannelID, "the most active channel comes first")
+	assert.Equal(t, "Busy Channel", busy.ChannelName)
+	assert.Equal(t, 2, busy.Notifications24h)
+	assert.Equal(t, 3, busy.Notifications7d)
+	assert.Equal(t, 1, busy.Dispatched7d)
+	assert.Equal(t, 1, busy.DispatchFailed7d)
+	assert.NotEmpty(t, busy.LastNotificationAt)
+	assert.Equal(t, 1, response.Channels[1].Notifications24h)
+}
+
+func TestHandleGetStats_Partial(t *testing.T) {
+	t.Setenv("NOTIFICATION_HISTORY_SIZE", "2")
+	deps := CreateTestDependencies()
+	ctx := t.Context()
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(ctx, &SubscriptionState{Subscriptions: map[string]*Subscription{
+		historyTestChannel: {ChannelID: historyTestChannel, Status: StatusActive},
+	}}))
+
+	history := NewNotificationHistory(deps.StorageClient, envConfig())
+	for i := 3; i > 0; i-- {
+		require.NoError(t, history.Append(ctx, historyTestChannel, HistoryEntry{
+			ReceivedAt: time.Now().Add(-time.Duration(i) * time.Hour), Decision: HistoryDecisionDispatched,
+		}))
+	}
+
+	response := getStats(t, deps)
+	assert.True(t, response.Partial, "the capped history no longer holds the whole week")
+	assert.Equal(t, 2, response.Last7d.Notifications)
+}
+
+func TestHandleGetStats_Empty(t *testing.T) {
+	response := getStats(t, CreateTestDependencies())
+	assert.Empty(t, response.Channels)
+	assert.NotNil(t, response.Channels)
+	assert.Equal(t, 0, response.Last7d.Notifications)
+	assert.Zero(t, response.Last7d.AverageHubLatencySeconds)
+}
+
+func TestHandleGetStats_HistoryDisabled(t *testing.T) {
+	t.Setenv("NOTIFICATION_HISTORY_SIZE", "0")
+
+	rec := httptest.NewRecorder()
+	handleGetStats(CreateTestDependencies())(rec, httptest.NewRequest("GET", "/stats", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// defaultStorageBackfillSize caps the writes kept for backfill unless
+// STORAGE_BACKFILL_SIZE says otherwise
+const defaultStorageBackfillSize = 1000
+
+// StorageBackfill keeps the notification history entries and processed videos that
+// could not be written while storage was unavailable, and writes them once it is back.
+// Notifications are still dispatched in the meantime. The writes are kept in memory on
+// the instance that took them, so they are lost if it shuts down first; when more than
+// STORAGE_BACKFILL_SIZE are waiting the oldest are dropped.
+type StorageBackfill struct {
+	mu        sync.Mutex
+	history   []backfillHistory
+	processed []string
+	size      int
+}
+
+// backfillHistory is a history entry waiting to be appended to a subscription's history
+type backfillHistory struct {
+	subscriptionID string
+	entry          HistoryEntry
+}
+
+// BackfillSummary reports the outcome of a backfill
+type BackfillSummary struct {
+	History   int `json:"history"`   // History entries written
+	Processed int `json:"processed"` // Processed videos recorded
+	Pending   int `json:"pending"`   // Writes still waiting for storage
+}
+
+// NewStorageBackfill creates an empty backfill holding up to STORAGE_BACKFILL_SIZE
+// writes (default 1000). STORAGE_BACKFILL_SIZE=0 disables it, returning nil.
+func NewStorageBackfill() *StorageBackfill {
+	size := getIntEnv("STORAGE_BACKFILL_SIZE", defaultStorageBackfillSize)
+	if size == 0 {
+		return nil
+	}
+	return &StorageBackfill{size: size}
+}
+
+// deferHistory keeps a history entry that could not be appended
+func (b *StorageBackfill) deferHistory(subscriptionID string, entry HistoryEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.history = append(b.history, backfillHistory{subscriptionID: subscriptionID, entry: entry})
+	b.trim()
+}
+
+// deferProcessed keeps a processed video that could not be recorded
+func (b *StorageBackfill) deferProcessed(videoID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.hasProcessed(videoID) {
+		b.processed = append(b.processed, videoID)
+		b.trim()
+	}
+}
+
+// WasProcessed reports whether the video was processed but not yet recorded, so
+// redeliveries are still skipped while storage is unavailable
+func (b *StorageBackfill) WasProcessed(videoID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hasProcessed(videoID)
+}
+
+func (b *StorageBackfill) hasProcessed(videoID string) bool {
+	for _, processed := range b.processed {
+		if processed == videoID {
+			return true
+		}
+	}
+	return false
+}
+
+// Pending returns how many writes are waiting for storage
+func (b *StorageBackfill) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.history) + len(b.processed)
+}
+
+// trim drops the oldest writes beyond the size, history first
+func (b *StorageBackfill) trim() {
+	excess := len(b.history) + len(b.processed) - b.size
+	if excess <= 0 {
+		return
+	}
+	dropped := min(excess, len(b.history))
+	b.history = b.history[dropped:]
+	b.processed = b.processed[excess-dropped:]
+	fmt.Printf("Storage backfill full; dropped the %d oldest writes\n", excess)
+}
+
+// Flush writes the waiting processed videos to registry and history entries to history,
+// in the order they were taken. It stops at the first write that fails, keeping it and
+// those after it for the next flush. A nil registry or history drops its writes.
+func (b *StorageBackfill) Flush(ctx context.Context, registry *ProcessedVideoRegistry, history *NotificationHistory) (*BackfillSummary, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	summary := &BackfillSummary{}
+	defer func() { summary.Pending = len(b.history) + len(b.processed) }()
+
+	for len(b.processed) > 0 {
+		if registry != nil {
+			if err := registry.MarkProcessed(ctx, b.processed[0]); err != nil {
+				return summary, err
+			}
+			summary.Processed++
+		}
+		b.processed = b.processed[1:]
+	}
+	for len(b.history) > 0 {
+		if history != nil {
+			pending := b.history[0]
+			if err := history.Append(ctx, pending.subscriptionID, pending.entry); err != nil {
+				return summary, err
+			}
+			summary.History++
+		}
+		b.history = b.history[1:]
+	}
+	return summary, nil
+}
+
+// flushBackfill writes what was deferred while storage was unavailable; failures are
+// only logged, leaving the writes for the next notification
+func (ns *NotificationService) flushBackfill(ctx context.Context) {
+	if ns.Backfill == nil || ns.Backfill.Pending() == 0 {
+		return
+	}
+	summary, err := ns.Backfill.Flush(ctx, ns.Registry, ns.History)
+	if err != nil {
+		fmt.Printf("Unable to backfill storage writes, %d still pending: %v\n", summary.Pending, err)
+		return
+	}
+	fmt.Printf("Backfilled %d history entries and %d processed videos\n", summary.History, summary.Processed)
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageBackfill_Notifications(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+	ctx := context.Background()
+
+	deps := CreateTestDependencies()
+	deps.StorageBackfill = NewStorageBackfill()
+	storage := deps.StorageClient.(*MockStorageClient)
+	gh := deps.GitHubClient.(*MockGitHubClient)
+	storage.ObjectErrors = map[string]error{
+		processedVideosObject:           ErrMockSaveFailure,
+		historyObject(routingChannelID): ErrMockSaveFailure,
+		outboxObject:                    ErrMockSaveFailure,
+	}
+
+	rec := postPremiereNotification(deps, "backfill01")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 1, gh.GetTriggerCallCount(), "the video is dispatched while storage is failing")
+	assert.Equal(t, 2, deps.StorageBackfill.Pending())
+
+	rec = postPremiereNotification(deps, "backfill01")
+	assert.Contains(t, rec.Body.String(), "Duplicate skipped", "redeliveries are skipped before the backfill")
+	assert.Equal(t, 1, gh.GetTriggerCallCount())
+
+	storage.ObjectErrors = nil
+	require.Equal(t, http.StatusOK, postPremiereNotification(deps, "backfill02").Code)
+
+	assert.Zero(t, deps.StorageBackfill.Pending())
+	processed, err := NewProcessedVideoRegistry(storage, envConfig()).WasProcessed(ctx, "backfill01")
+	require.NoError(t, err)
+	assert.True(t, processed)
+	history, err := NewNotificationHistory(storage, envConfig()).List(ctx, routingChannelID, 0)
+	require.NoError(t, err)
+	var decisions []string
+	for _, entry := range history {
+		decisions = append(decisions, entry.VideoID+":"+entry.Decision)
+	}
+	assert.Equal(t, []string{
+		"backfill02:" + HistoryDecisionDispatched,
+		"backfill01:" + HistoryDecisionDuplicate,
+		"backfill01:" + HistoryDecisionDispatched,
+	}, decisions)
+}
+
+func TestStorageBackfill_Flush(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMockStorageClient()
+	registry := NewProcessedVideoRegistry(storage, envConfig())
+	history := NewNotificationHistory(storage, envConfig())
+
+	t.Run("failed_write_is_kept", func(t *testing.T) {
+		backfill := NewStorageBackfill()
+		backfill.deferProcessed("video1")
+		backfill.deferHistory("UCXuqSBlHAE6Xw-yeJA0Tunw", HistoryEntry{VideoID: "video1"})
+		storage.ObjectErrors = map[string]error{historyObject("UCXuqSBlHAE6Xw-yeJA0Tunw"): ErrMockSaveFailure}
+
+		summary, err := backfill.Flush(ctx, registry, history)
+		assert.Error(t, err)
+		assert.Equal(t, &BackfillSummary{Processed: 1, Pending: 1}, summary)
+
+		storage.ObjectErrors = nil
+		summary, err = backfill.Flush(ctx, registry, history)
+		require.NoError(t, err)
+		assert.Equal(t, &BackfillSummary{History: 1}, summary)
+	})
+
+	t.Run("oldest_writes_are_dropped", func(t *testing.T) {
+		t.Setenv("STORAGE_BACKFILL_SIZE", "2")
+		backfill := NewStorageBackfill()
+		backfill.deferHistory("UCXuqSBlHAE6Xw-yeJA0Tunw", HistoryEntry{VideoID: "video1"})
+		backfill.deferProcessed("video1")
+		backfill.deferProcessed("video2")
+
+		assert.Equal(t, 2, backfill.Pending())
+		assert.True(t, backfill.WasProcessed("video1"), "history is dropped before processed videos")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("STORAGE_BACKFILL_SIZE", "0")
+		assert.Nil(t, NewStorageBackfill())
+	})
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Storage calls fail fast for STORAGE_BREAKER_COOLDOWN_SECONDS once
+// STORAGE_BREAKER_FAILURES calls in a row have failed
+const (
+	defaultStorageBreakerFailures = 5
+	defaultStorageBreakerCooldown = 30 * time.Second
+)
+
+// NewStorageBreaker creates the circuit breaker around the storage backend. It opens
+// after STORAGE_BREAKER_FAILURES consecutive failures (default 5), failing storage calls
+// with ErrStorageUnavailable for STORAGE_BREAKER_COOLDOWN_SECONDS (default 30).
+// STORAGE_BREAKER_FAILURES=0 disables it, returning nil.
+func NewStorageBreaker(metrics *Metrics) *CircuitBreaker {
+	return newCircuitBreaker("storage", ErrStorageUnavailable,
+		getIntEnv("STORAGE_BREAKER_FAILURES", defaultStorageBreakerFailures),
+		getSecondsEnv("STORAGE_BREAKER_COOLDOWN_SECONDS", defaultStorageBreakerCooldown),
+		storageFailure, metrics)
+}
+
+// storageFailure reports whether a storage error counts towards opening the breaker.
+// Answers such as a missing object, and calls the caller cancelled, do not.
+func storageFailure(err error) bool {
+	return !errors.Is(err, ErrObjectNotFound) && !errors.Is(err, ErrObjectNotModified) &&
+		!errors.Is(err, ErrObjectChanged) && !errors.Is(err, ErrSubscriptionNotFound) && !errors.Is(err, context.Canceled)
+}
+
+// breakerStorage sends every storage operation through a StorageBreaker
+type breakerStorage struct {
+	StorageService
+	breaker *CircuitBreaker
+}
+
+// breakerObjectStorage also forwards ObjectStorage for backends that support it
+type breakerObjectStorage struct {
+	*breakerStorage
+	objects ObjectStorage
+}
+
+// guardStorage wraps storage so its operations go through breaker; a nil breaker
+// returns storage unchanged. The wrapper keeps the ObjectStorage capability of the
+// underlying backend.
+func guardStorage(storage StorageService, breaker *CircuitBreaker) StorageService {
+	if breaker == nil {
+		return storage
+	}
+	wrapped := &breakerStorage{StorageService: storage, breaker: breaker}
+	if objects, ok := storage.(ObjectStorage); ok {
+		return &breakerObjectStorage{breakerStorage: wrapped, objects: objects}
+	}
+	return wrapped
+}
+
+func (s *breakerStorage) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
+	var state *SubscriptionState
+	err := s.breaker.call(func() (err error) {
+		state, err = s.StorageService.LoadSubscriptionState(ctx)
+		return err
+	})
+	return state, err
+}
+
+func (s *breakerStorage) SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error {
+	return s.breaker.call(func() error {
+		return s.StorageService.SaveSubscriptionState(ctx, state)
+	})
+}
+
+func (s *breakerStorage) GetSubscription(ctx context.Context, channelID string) (*Subscription, error) {
+	var sub *Subscription
+	err := s.breaker.call(func() (err error) {
+		sub, err = s.StorageService.GetSubscription(ctx, channelID)
+		return err
+	})
+	return sub, err
+}
+
+func (s *breakerStorage) SaveSubscription(ctx context.Context, subscription *Subscription) error {
+	return s.breaker.call(func() error {
+		return s.StorageService.SaveSubscription(ctx, subscription)
+	})
+}
+
+func (s *breakerStorage) DeleteSubscription(ctx context.Context, channelID string) error {
+	return s.breaker.call(func() error {
+		return s.StorageService.DeleteSubscription(ctx, channelID)
+	})
+}
+
+func (s *breakerStorage) StreamSubscriptions(ctx context.Context, fn func(*Subscription) error) error {
+	// An error from fn stops the stream without being a storage failure
+	var fnErr error
+	err := s.breaker.call(func() error {
+		err := s.StorageService.StreamSubscriptions(ctx, func(sub *Subscription) error {
+			fnErr = fn(sub)
+			return fnErr
+		})
+		if err != nil && err == fnErr {
+			return nil
+		}
+		return err
+	})
+	if err == nil && fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+func (s *breakerObjectStorage) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	var data []byte
+	err := s.breaker.call(func() (err error) {
+		data, err = s.objects.ReadObject(ctx, name)
+		return err
+	})
+	return data, err
+}
+
+func (s *breakerObjectStorage) WriteObject(ctx context.Context, name string, data []byte) error {
+	return s.breaker.call(func() error {
+		return s.objects.WriteObject(ctx, name, data)
+	})
+}
+
+func (s *breakerObjectStorage) CreateObject(ctx context.Context, name string, data []byte) error {
+	return s.breaker.call(func() error {
+		return s.objects.CreateObject(ctx, name, data)
+	})
+}
+
+func (s *breakerObjectStorage) ReadObjectGeneration(ctx context.Context, name string) ([]byte, int64, error) {
+	var data []byte
+	var generation int64
+	err := s.breaker.call(func() (err error) {
+		data, generation, err = s.objects.ReadObjectGeneration(ctx, name)
+		return err
+	})
+	return data, generation, err
+}
+
+func (s *breakerObjectStorage) WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error {
+	return s.breaker.call(func() error {
+		return s.objects.WriteObjectIfGeneration(ctx, name, data, generation)
+	})
+}
+
+func (s *breakerObjectStorage) DeleteObject(ctx context.Context, name string) error {
+	return s.breaker.call(func() error {
+		return s.objects.DeleteObject(ctx, name)
+	})
+}
@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStorageBreaker(t *testing.T) {
+	breaker := NewStorageBreaker(nil)
+	require.NotNil(t, breaker)
+	assert.Equal(t, defaultStorageBreakerFailures, breaker.threshold)
+	assert.Equal(t, defaultStorageBreakerCooldown, breaker.cooldown)
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	t.Setenv("STORAGE_BREAKER_FAILURES", "0")
+	assert.Nil(t, NewStorageBreaker(nil))
+	storage := NewMockStorageClient()
+	assert.Same(t, storage, guardStorage(storage, nil), "without a breaker storage is not wrapped")
+}
+
+func TestStorageBreaker(t *testing.T) {
+	t.Setenv("STORAGE_BREAKER_FAILURES", "2")
+	ctx := context.Background()
+	storageDown := errors.New("storage down")
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	newGuarded := func() (*MockStorageClient, *CircuitBreaker, StorageService) {
+		storage := NewMockStorageClient()
+		breaker := NewStorageBreaker(nil)
+		breaker.clock = clock
+		return storage, breaker, guardStorage(storage, breaker)
+	}
+
+	t.Run("opens_after_consecutive_failures", func(t *testing.T) {
+		storage, breaker, guarded := newGuarded()
+		storage.LoadError = storageDown
+
+		for i := 0; i < 2; i++ {
+			_, err := guarded.LoadSubscriptionState(ctx)
+			assert.ErrorIs(t, err, storageDown)
+		}
+		assert.Equal(t, BreakerOpen, breaker.State())
+
+		_, err := guarded.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		assert.ErrorIs(t, err, ErrStorageUnavailable)
+		assert.Contains(t, err.Error(), "2 calls in a row failed")
+		assert.Equal(t, 2, storage.LoadCallCount, "storage is not called while the breaker is open")
+	})
+
+	t.Run("answers_are_not_failures", func(t *testing.T) {
+		_, breaker, guarded := newGuarded()
+		objects := guarded.(ObjectStorage)
+
+		for i := 0; i < 3; i++ {
+			_, err := objects.ReadObject(ctx, "missing.json")
+			assert.ErrorIs(t, err, ErrObjectNotFound)
+		}
+		assert.Equal(t, BreakerClosed, breaker.State())
+	})
+
+	t.Run("trial_call_closes_or_reopens", func(t *testing.T) {
+		storage, breaker, guarded := newGuarded()
+		storage.SaveError = storageDown
+		for i := 0; i < 2; i++ {
+			assert.Error(t, guarded.SaveSubscriptionState(ctx, &SubscriptionState{}))
+		}
+
+		clock.Advance(breaker.cooldown)
+		assert.Equal(t, BreakerHalfOpen, breaker.State())
+		assert.ErrorIs(t, guarded.SaveSubscriptionState(ctx, &SubscriptionState{}), storageDown)
+		assert.Equal(t, BreakerOpen, breaker.State(), "a failed trial opens the breaker again")
+
+		clock.Advance(breaker.cooldown)
+		storage.SaveError = nil
+		assert.NoError(t, guarded.SaveSubscriptionState(ctx, &SubscriptionState{Subscriptions: map[string]*Subscription{}}))
+		assert.Equal(t, BreakerClosed, breaker.State())
+		assert.NoError(t, guarded.SaveSubscriptionState(ctx, &SubscriptionState{Subscriptions: map[string]*Subscription{}}))
+	})
+}
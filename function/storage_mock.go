@@ -9,12 +9,16 @@ import (
 
 // MockStorageClient implements StorageClient for testing.
 type MockStorageClient struct {
-	mu    sync.RWMutex
-	state *SubscriptionState
+	mu          sync.RWMutex
+	state       *SubscriptionState
+	objects     map[string][]byte
+	generations map[string]int64 // Of each object, from a counter of every write
+	writes      int64
 
 	// Control test behavior
 	LoadError      error
 	SaveError      error
+	ObjectErrors   map[string]error // Fails writes of the named objects
 	LoadCallCount  int
 	SaveCallCount  int
 	LastSavedState *SubscriptionState
@@ -28,7 +32,7 @@ func NewMockStorageClient() *MockStorageClient {
 				Subscriptions: make(map[string]*Subscription),
 			}
 			state.Metadata.LastUpdated = time.Now()
-			state.Metadata.Version = "1.0"
+			state.Metadata.Version = CurrentSchemaVersion
 			return state
 		}(),
 	}
@@ -50,12 +54,16 @@ func (m *MockStorageClient) LoadSubscriptionState(ctx context.Context) (*Subscri
 			Subscriptions: make(map[string]*Subscription),
 		}
 		state.Metadata.LastUpdated = time.Now()
-		state.Metadata.Version = "1.0"
+		state.Metadata.Version = CurrentSchemaVersion
 		return state, nil
 	}
 
-	// Deep copy the state to prevent modifications
-	return m.deepCopyState(m.state), nil
+	// Deep copy the state to prevent modifications, migrating it like the real backends
+	state := m.deepCopyState(m.state)
+	if _, err := migrateSubscriptionState(state); err != nil {
+		return nil, err
+	}
+	return state, nil
 }
 
 // SaveSubscriptionState saves the subscription state to memory.
@@ -69,9 +77,11 @@ func (m *MockStorageClient) SaveSubscriptionState(ctx context.Context, state *Su
 		return m.SaveError
 	}
 
-	// Update metadata
+	// Update metadata, refusing states in an older schema
 	state.Metadata.LastUpdated = time.Now()
-	state.Metadata.Version = "1.0"
+	if err := stampSchemaVersion(state); err != nil {
+		return err
+	}
 
 	// Deep copy for storage
 	m.state = m.deepCopyState(state)
@@ -80,6 +90,199 @@ func (m *MockStorageClient) SaveSubscriptionState(ctx context.Context, state *Su
 	return nil
 }
 
+// GetSubscription returns a single subscription from memory.
+func (m *MockStorageClient) GetSubscription(ctx context.Context, channelID string) (*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.LoadCallCount++
+
+	if m.LoadError != nil {
+		return nil, m.LoadError
+	}
+
+	if m.state == nil || m.state.Subscriptions[channelID] == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return copySubscription(m.state.Subscriptions[channelID]), nil
+}
+
+// SaveSubscription stores a single subscription in memory.
+func (m *MockStorageClient) SaveSubscription(ctx context.Context, subscription *Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.SaveCallCount++
+
+	if m.SaveError != nil {
+		return m.SaveError
+	}
+
+	state := m.deepCopyState(m.state)
+	if state == nil {
+		state = &SubscriptionState{Subscriptions: make(map[string]*Subscription)}
+	}
+	state.Subscriptions[subscription.ChannelID] = copySubscription(subscription)
+	state.Metadata.LastUpdated = time.Now()
+	state.Metadata.Version = CurrentSchemaVersion
+
+	m.state = state
+	m.LastSavedState = m.deepCopyState(state)
+	return nil
+}
+
+// DeleteSubscription removes a single subscription from memory.
+func (m *MockStorageClient) DeleteSubscription(ctx context.Context, channelID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.SaveCallCount++
+
+	if m.SaveError != nil {
+		return m.SaveError
+	}
+
+	if m.state != nil {
+		state := m.deepCopyState(m.state)
+		delete(state.Subscriptions, channelID)
+		state.Metadata.LastUpdated = time.Now()
+		m.state = state
+		m.LastSavedState = m.deepCopyState(state)
+	}
+	return nil
+}
+
+// StreamSubscriptions calls fn with a copy of each subscription in memory.
+func (m *MockStorageClient) StreamSubscriptions(ctx context.Context, fn func(*Subscription) error) error {
+	state, err := m.LoadSubscriptionState(ctx)
+	if err != nil {
+		return err
+	}
+	return streamState(state, fn)
+}
+
+// ReadObject returns an auxiliary object from memory.
+func (m *MockStorageClient) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.LoadError != nil {
+		return nil, m.LoadError
+	}
+
+	data, exists := m.objects[name]
+	if !exists {
+		return nil, ErrObjectNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// WriteObject stores an auxiliary object in memory.
+func (m *MockStorageClient) WriteObject(ctx context.Context, name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.SaveError != nil {
+		return m.SaveError
+	}
+	if err := m.ObjectErrors[name]; err != nil {
+		return err
+	}
+
+	m.putObject(name, data)
+	return nil
+}
+
+// CreateObject stores an auxiliary object in memory unless it exists.
+func (m *MockStorageClient) CreateObject(ctx context.Context, name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.SaveError != nil {
+		return m.SaveError
+	}
+	if err := m.ObjectErrors[name]; err != nil {
+		return err
+	}
+	if _, exists := m.objects[name]; exists {
+		return ErrObjectChanged
+	}
+
+	m.putObject(name, data)
+	return nil
+}
+
+// ReadObjectGeneration returns an auxiliary object from memory with its generation.
+func (m *MockStorageClient) ReadObjectGeneration(ctx context.Context, name string) ([]byte, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.LoadError != nil {
+		return nil, 0, m.LoadError
+	}
+
+	data, exists := m.objects[name]
+	if !exists {
+		return nil, 0, ErrObjectNotFound
+	}
+	return append([]byte(nil), data...), m.generations[name], nil
+}
+
+// WriteObjectIfGeneration stores an auxiliary object in memory while it is still at
+// generation.
+func (m *MockStorageClient) WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.SaveError != nil {
+		return m.SaveError
+	}
+	if err := m.ObjectErrors[name]; err != nil {
+		return err
+	}
+	if m.generations[name] != generation {
+		return ErrObjectChanged
+	}
+
+	m.putObject(name, data)
+	return nil
+}
+
+// putObject stores an object at a new generation; the lock must be held.
+func (m *MockStorageClient) putObject(name string, data []byte) {
+	if m.objects == nil {
+		m.objects = make(map[string][]byte)
+	}
+	if m.generations == nil {
+		m.generations = make(map[string]int64)
+	}
+	m.writes++
+	m.objects[name] = append([]byte(nil), data...)
+	m.generations[name] = m.writes
+}
+
+// DeleteObject removes an auxiliary object from memory.
+func (m *MockStorageClient) DeleteObject(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.SaveError != nil {
+		return m.SaveError
+	}
+
+	delete(m.objects, name)
+	delete(m.generations, name)
+	return nil
+}
+
+// Ping reports LoadError, simulating unreachable storage.
+func (m *MockStorageClient) Ping(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.LoadError
+}
+
 // Close is a no-op for the mock client.
 func (m *MockStorageClient) Close() error {
 	return nil
@@ -109,7 +312,7 @@ func (m *MockStorageClient) Reset() {
 			Subscriptions: make(map[string]*Subscription),
 		}
 		state.Metadata.LastUpdated = time.Now()
-		state.Metadata.Version = "1.0"
+		state.Metadata.Version = CurrentSchemaVersion
 		return state
 	}()
 	m.LoadError = nil
@@ -117,6 +320,8 @@ func (m *MockStorageClient) Reset() {
 	m.LoadCallCount = 0
 	m.SaveCallCount = 0
 	m.LastSavedState = nil
+	m.objects = nil
+	m.generations = nil
 }
 
 // deepCopyState creates a deep copy of the subscription state.
@@ -1,48 +1,143 @@
 package webhook
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net/http"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/api/googleapi"
 )
 
-// StorageService defines the interface for subscription state storage operations
+// State object encodings selected by STATE_COMPRESSION
+const (
+	StateCompressionGzip = "gzip"
+	StateCompressionNone = "none"
+)
+
+// StorageService defines the interface for subscription state storage operations.
+// Whole-state operations are used by bulk jobs (renew, sweep, list); handlers that
+// touch a single channel use the per-subscription operations so backends that store
+// each subscription separately avoid rewriting everything.
 type StorageService interface {
 	LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error)
 	SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error
+	GetSubscription(ctx context.Context, channelID string) (*Subscription, error) // ErrSubscriptionNotFound when missing
+	SaveSubscription(ctx context.Context, subscription *Subscription) error
+	DeleteSubscription(ctx context.Context, channelID string) error
+	// StreamSubscriptions calls fn with every subscription in turn, by channel ID,
+	// without holding the whole state; an error from fn stops the stream and is returned
+	StreamSubscriptions(ctx context.Context, fn func(*Subscription) error) error
 	Close() error
 }
 
+// NewStorageService creates the storage backend selected by STORAGE_BACKEND
+// ("gcs", the default, or "firestore")
+func NewStorageService() StorageService {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "gcs":
+		return NewCloudStorageService()
+	case "firestore":
+		return NewFirestoreStorageService()
+	default:
+		fmt.Printf("Unknown STORAGE_BACKEND %q, using gcs\n", backend)
+		return NewCloudStorageService()
+	}
+}
+
+// ObjectStorage is implemented by storage backends that can also hold
+// auxiliary documents (debug buffers, logs) alongside the subscription state.
+// ReadObject returns ErrObjectNotFound when the object does not exist; deleting a
+// missing object is not an error.
+type ObjectStorage interface {
+	ReadObject(ctx context.Context, name string) ([]byte, error)
+	WriteObject(ctx context.Context, name string, data []byte) error
+	DeleteObject(ctx context.Context, name string) error
+
+	// CreateObject writes an object only if it does not exist yet, returning
+	// ErrObjectChanged otherwise, so of several writers racing to create it one wins
+	CreateObject(ctx context.Context, name string, data []byte) error
+
+	// ReadObjectGeneration reads an object with its generation, which changes on every
+	// write. WriteObjectIfGeneration writes it only while it is still at that
+	// generation (0: while it does not exist), returning ErrObjectChanged otherwise, so
+	// an object shared by several instances can be read, changed and written back.
+	ReadObjectGeneration(ctx context.Context, name string) ([]byte, int64, error)
+	WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error
+}
+
 // CloudStorageOperations defines the interface for cloud storage operations
 // This abstracts away the Google Cloud Storage implementation details
 type CloudStorageOperations interface {
 	GetObject(ctx context.Context, bucket, objectPath string) ([]byte, error)
 	PutObject(ctx context.Context, bucket, objectPath string, data []byte) error
+	DeleteObject(ctx context.Context, bucket, objectPath string) error
 	Close() error
+
+	// Generation-aware variants used to validate the cached subscription state.
+	// A generation identifies one version of an object and changes on every write.
+	// GetObjectIfChanged reads the object only if it is no longer at the given
+	// generation, returning ErrObjectNotModified otherwise. PutObjectIfGeneration
+	// writes the object only while it is still at the given generation (0: while it does
+	// not exist), returning ErrObjectChanged otherwise. Data that starts with the gzip
+	// magic number is written with Content-Encoding: gzip.
+	GetObjectWithGeneration(ctx context.Context, bucket, objectPath string) ([]byte, int64, error)
+	GetObjectIfChanged(ctx context.Context, bucket, objectPath string, generation int64) ([]byte, int64, error)
+	PutObjectWithGeneration(ctx context.Context, bucket, objectPath string, data []byte) (int64, error)
+	PutObjectIfGeneration(ctx context.Context, bucket, objectPath string, data []byte, generation int64) (int64, error)
 }
 
 // CloudStorageService provides an optimized Cloud Storage implementation
-// with connection pooling and caching
+// with connection pooling and caching. The state is stored as one object per channel
+// plus an index (see state_index.go), so single-channel operations touch one small
+// object and the index. Within one instance, concurrent loads share a single read of
+// the state, and saves are written behind: a save made while another is being
+// written, or within STATE_SAVE_COALESCE_MS of the first, is coalesced with it into
+// one write of the latest state.
 type CloudStorageService struct {
 	storageOps CloudStorageOperations
 	bucketName string
-	objectPath string
+	objectPath string // The index object
 
-	// Cache layer
-	cache      *SubscriptionState
+	// Cache layer; the cached state is used only while the index generation is still
+	// current, and its channels are reused while their generations are
+	cache      *stateSnapshot
 	cacheTime  time.Time
 	cacheTTL   time.Duration
 	cacheMutex sync.RWMutex
 
-	// Initialization
-	initOnce sync.Once
-	initErr  error
+	// Concurrent loads that miss the cache share one read of the state
+	loads singleflight.Group
+
+	// Writes of the state, whole or one channel, are made one at a time
+	writeMutex sync.Mutex
+
+	// Write-behind layer; the first queued save writes for every save queued behind it
+	saveMutex     sync.Mutex
+	pendingSave   *SubscriptionState // Latest state not yet being written
+	pendingDone   []chan error       // Saves waiting for pendingSave to be written
+	writingSave   *SubscriptionState // State being written
+	saveInFlight  bool
+	coalesceDelay time.Duration
+
+	compression string // Encoding the state object is written in; reads accept either
+
+	// Initialization; a failed attempt is retried on the next call
+	initMutex   sync.Mutex
+	initialized bool
+	layoutMutex sync.Mutex
+	layoutReady bool // The state is in the per-channel layout
 }
 
 // RealCloudStorageOperations implements CloudStorageOperations using Google Cloud Storage
@@ -61,32 +156,97 @@ func NewRealCloudStorageOperations(ctx context.Context) (*RealCloudStorageOperat
 
 // GetObject retrieves an object from Cloud Storage
 func (r *RealCloudStorageOperations) GetObject(ctx context.Context, bucket, objectPath string) ([]byte, error) {
-	bucketHandle := r.client.Bucket(bucket)
-	obj := bucketHandle.Object(objectPath)
+	data, _, err := r.GetObjectWithGeneration(ctx, bucket, objectPath)
+	return data, err
+}
+
+// GetObjectWithGeneration retrieves an object and the generation that was read
+func (r *RealCloudStorageOperations) GetObjectWithGeneration(ctx context.Context, bucket, objectPath string) ([]byte, int64, error) {
+	return r.readObject(ctx, r.client.Bucket(bucket).Object(objectPath))
+}
+
+// GetObjectIfChanged retrieves an object unless it is still at the given generation.
+// Generation 0 (a missing object) reads the object unconditionally.
+func (r *RealCloudStorageOperations) GetObjectIfChanged(ctx context.Context, bucket, objectPath string, generation int64) ([]byte, int64, error) {
+	obj := r.client.Bucket(bucket).Object(objectPath)
+	if generation == 0 {
+		return r.readObject(ctx, obj)
+	}
+
+	obj = obj.If(storage.Conditions{GenerationNotMatch: generation})
+	data, current, err := r.readObject(ctx, obj)
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotModified {
+		return nil, generation, ErrObjectNotModified
+	}
+	return data, current, err
+}
 
+// readObject reads an object and the generation that was read. Objects stored with
+// Content-Encoding: gzip may be returned decompressed or not, depending on how they
+// were served.
+func (r *RealCloudStorageOperations) readObject(ctx context.Context, obj *storage.ObjectHandle) ([]byte, int64, error) {
 	reader, err := obj.NewReader(ctx)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer reader.Close()
 
-	return io.ReadAll(reader)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, reader.Attrs.Generation, nil
 }
 
 // PutObject stores an object in Cloud Storage
 func (r *RealCloudStorageOperations) PutObject(ctx context.Context, bucket, objectPath string, data []byte) error {
-	bucketHandle := r.client.Bucket(bucket)
-	obj := bucketHandle.Object(objectPath)
+	_, err := r.PutObjectWithGeneration(ctx, bucket, objectPath, data)
+	return err
+}
+
+// PutObjectWithGeneration stores an object and returns the generation it was written as
+func (r *RealCloudStorageOperations) PutObjectWithGeneration(ctx context.Context, bucket, objectPath string, data []byte) (int64, error) {
+	return r.writeObject(ctx, r.client.Bucket(bucket).Object(objectPath), data)
+}
+
+// PutObjectIfGeneration stores an object only if it is still at the given generation,
+// or does not exist for generation 0
+func (r *RealCloudStorageOperations) PutObjectIfGeneration(ctx context.Context, bucket, objectPath string, data []byte, generation int64) (int64, error) {
+	conditions := storage.Conditions{GenerationMatch: generation}
+	if generation == 0 {
+		conditions = storage.Conditions{DoesNotExist: true}
+	}
 
+	written, err := r.writeObject(ctx, r.client.Bucket(bucket).Object(objectPath).If(conditions), data)
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return 0, ErrObjectChanged
+	}
+	return written, err
+}
+
+// writeObject writes an object and returns the generation it was written as
+func (r *RealCloudStorageOperations) writeObject(ctx context.Context, obj *storage.ObjectHandle, data []byte) (int64, error) {
 	writer := obj.NewWriter(ctx)
 	writer.ContentType = "application/json"
+	if isGzipData(data) {
+		writer.ContentEncoding = "gzip"
+	}
 
 	if _, err := writer.Write(data); err != nil {
 		writer.Close()
-		return err
+		return 0, err
 	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+	return writer.Attrs().Generation, nil
+}
 
-	return writer.Close()
+// DeleteObject removes an object from Cloud Storage
+func (r *RealCloudStorageOperations) DeleteObject(ctx context.Context, bucket, objectPath string) error {
+	return r.client.Bucket(bucket).Object(objectPath).Delete(ctx)
 }
 
 // Close closes the storage client
@@ -94,72 +254,156 @@ func (r *RealCloudStorageOperations) Close() error {
 	return r.client.Close()
 }
 
+// getStateCacheTTL returns how long the subscription state may be served from cache.
+// STATE_CACHE_TTL_SECONDS=0 disables the cache.
+func getStateCacheTTL() time.Duration {
+	ttlStr := os.Getenv("STATE_CACHE_TTL_SECONDS")
+	if ttlStr == "" {
+		return 5 * time.Minute // Default: 5 minutes
+	}
+
+	var seconds int
+	if _, err := fmt.Sscanf(ttlStr, "%d", &seconds); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// getStateSaveCoalesceDelay returns how long the first of several saves waits for the
+// others before the state is written. With 0, only saves made while a write is in
+// flight are coalesced.
+func getStateSaveCoalesceDelay() time.Duration {
+	return time.Duration(getIntEnv("STATE_SAVE_COALESCE_MS", 0)) * time.Millisecond
+}
+
+// getStateCompression returns the encoding the state object is written in:
+// STATE_COMPRESSION=none writes plain JSON, anything else gzip
+func getStateCompression() string {
+	if os.Getenv("STATE_COMPRESSION") == StateCompressionNone {
+		return StateCompressionNone
+	}
+	return StateCompressionGzip
+}
+
 // NewCloudStorageService creates a new Cloud Storage service with real operations
 func NewCloudStorageService() *CloudStorageService {
 	// storageOps will be created during initialization
 	return &CloudStorageService{
-		objectPath: "subscriptions/state.json",
-		cacheTTL:   5 * time.Minute,
+		objectPath:    stateIndexObject,
+		cacheTTL:      getStateCacheTTL(),
+		coalesceDelay: getStateSaveCoalesceDelay(),
+		compression:   getStateCompression(),
 	}
 }
 
 // NewCloudStorageServiceWithOperations creates a service with custom storage operations (for testing)
 func NewCloudStorageServiceWithOperations(ops CloudStorageOperations, bucketName string) *CloudStorageService {
 	return &CloudStorageService{
-		storageOps: ops,
-		bucketName: bucketName,
-		objectPath: "subscriptions/state.json",
-		cacheTTL:   5 * time.Minute,
+		storageOps:    ops,
+		bucketName:    bucketName,
+		objectPath:    stateIndexObject,
+		cacheTTL:      getStateCacheTTL(),
+		coalesceDelay: getStateSaveCoalesceDelay(),
+		compression:   getStateCompression(),
 	}
 }
 
-// initialize sets up the storage operations with proper error handling
+// initialize sets up the storage operations on first use and moves a state written by
+// older builds into the per-channel layout. A failed move is retried on the next call.
 func (s *CloudStorageService) initialize(ctx context.Context) error {
-	s.initOnce.Do(func() {
-		if s.bucketName == "" {
-			s.bucketName = os.Getenv("SUBSCRIPTION_BUCKET")
-		}
-		if s.bucketName == "" {
-			s.initErr = fmt.Errorf("SUBSCRIPTION_BUCKET environment variable not set")
-			return
-		}
+	if err := s.initializeOperations(ctx); err != nil {
+		return err
+	}
 
-		// Only create storage operations if not already provided (e.g., in tests)
-		if s.storageOps == nil {
-			ops, err := NewRealCloudStorageOperations(ctx)
-			if err != nil {
-				s.initErr = fmt.Errorf("failed to create storage operations: %v", err)
-				return
-			}
-			s.storageOps = ops
-		}
-	})
-	return s.initErr
+	s.layoutMutex.Lock()
+	defer s.layoutMutex.Unlock()
+
+	if s.layoutReady {
+		return nil
+	}
+	if err := s.migrateLegacyState(ctx); err != nil {
+		return err
+	}
+	s.layoutReady = true
+	return nil
 }
 
-// LoadSubscriptionState loads subscription state with caching
-func (s *CloudStorageService) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
+// initializeOperations sets up the storage operations on first use. The storage
+// client is then reused for every later call; a failed attempt is not cached, so a
+// transient error does not leave the instance without storage.
+func (s *CloudStorageService) initializeOperations(ctx context.Context) error {
+	s.initMutex.Lock()
+	defer s.initMutex.Unlock()
+
+	if s.initialized {
+		return nil
+	}
 
-	// Check cache first
-	if cachedState := s.getCachedState(); cachedState != nil {
-		return s.deepCopyState(cachedState), nil
+	if s.bucketName == "" {
+		s.bucketName = os.Getenv("SUBSCRIPTION_BUCKET")
 	}
+	if s.bucketName == "" {
+		return fmt.Errorf("SUBSCRIPTION_BUCKET environment variable not set")
+	}
+
+	// Only create storage operations if not already provided (e.g., in tests)
+	if s.storageOps == nil {
+		ops, err := NewRealCloudStorageOperations(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create storage operations: %v", err)
+		}
+		s.storageOps = ops
+	}
+
+	s.initialized = true
+	return nil
+}
+
+// LoadSubscriptionState loads subscription state with caching. Within the cache TTL the
+// index is only read if its generation has changed, so writes made by other instances
+// are seen immediately while an unchanged state costs one request that transfers
+// nothing. Of a changed index, only the channels whose generation changed are read. A
+// state saved by this instance but not yet written is returned as is.
+func (s *CloudStorageService) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
 
 	// Initialize client if needed
 	if err := s.initialize(ctx); err != nil {
 		return nil, err
 	}
 
-	// Load from Cloud Storage
-	state, err := s.loadFromStorage(ctx)
+	// Saves not yet written are newer than anything stored
+	if unsaved := s.getUnsavedState(); unsaved != nil {
+		return markLoaded(unsaved), nil
+	}
+
+	// Load from Cloud Storage, sharing the read with concurrent loads. The read is not
+	// cancelled with the first caller's request, since the others are waiting on it.
+	loaded, err, _ := s.loads.Do(s.objectPath, func() (interface{}, error) {
+		snapshot, err := s.loadSnapshot(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return snapshot.state, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Update cache
-	s.setCachedState(state)
+	return markLoaded(s.deepCopyState(loaded.(*SubscriptionState))), nil
+}
 
-	return s.deepCopyState(state), nil
+// markLoaded records the channels a state holds as loaded, keeping those it was loaded
+// with itself: an unsaved state still removes the channels its own caller removed
+func markLoaded(state *SubscriptionState) *SubscriptionState {
+	loaded := make(map[string]bool, len(state.loaded)+len(state.Subscriptions))
+	for channelID := range state.loaded {
+		loaded[channelID] = true
+	}
+	for channelID := range state.Subscriptions {
+		loaded[channelID] = true
+	}
+	state.loaded = loaded
+	return state
 }
 
 // SaveSubscriptionState saves subscription state and updates cache
@@ -170,27 +414,335 @@ func (s *CloudStorageService) SaveSubscriptionState(ctx context.Context, state *
 		return err
 	}
 
-	// Update metadata
-	s.updateMetadata(state)
+	// Update metadata, refusing states in an older schema
+	if err := s.updateMetadata(ctx, state); err != nil {
+		return err
+	}
+
+	// Save to Cloud Storage, together with any saves made meanwhile
+	return s.queueSave(ctx, state)
+}
+
+// queueSave hands a state to the write-behind layer and waits until it, or a state
+// saved after it, has been written. The first caller becomes the writer: it waits for
+// the coalescing delay, then writes the latest queued state until none is left, so
+// every caller's save is stored before it returns.
+func (s *CloudStorageService) queueSave(ctx context.Context, state *SubscriptionState) error {
+	done := make(chan error, 1)
+
+	s.saveMutex.Lock()
+	s.pendingSave = s.deepCopyState(state)
+	s.pendingDone = append(s.pendingDone, done)
+	writer := !s.saveInFlight
+	s.saveInFlight = true
+	s.saveMutex.Unlock()
+
+	if writer {
+		// Later callers are waiting on these writes, so they outlive this request
+		s.flushSaves(context.WithoutCancel(ctx))
+	}
+	return <-done
+}
+
+// flushSaves writes the latest queued state, repeating while saves keep arriving, and
+// reports each write's result to the saves it covered
+func (s *CloudStorageService) flushSaves(ctx context.Context) {
+	if s.coalesceDelay > 0 {
+		time.Sleep(s.coalesceDelay)
+	}
+
+	for {
+		s.saveMutex.Lock()
+		state, waiting := s.pendingSave, s.pendingDone
+		s.pendingSave, s.pendingDone = nil, nil
+		s.writingSave = state
+		if state == nil {
+			s.saveInFlight = false
+			s.saveMutex.Unlock()
+			return
+		}
+		s.saveMutex.Unlock()
+
+		err := s.writeState(ctx, state)
+		if len(waiting) > 1 {
+			fmt.Printf("Coalesced %d subscription state saves into one write\n", len(waiting))
+		}
+		for _, done := range waiting {
+			done <- err
+		}
+	}
+}
+
+// writeState writes a state to Cloud Storage and caches it. Only the channels that
+// differ from the stored state are written, then the index, and the objects of
+// channels no longer in the state are deleted. After a failed write the cache is
+// dropped, since it may hold states that were never stored.
+func (s *CloudStorageService) writeState(ctx context.Context, state *SubscriptionState) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	snapshot, err := s.saveToStorage(ctx, state)
+	if err != nil {
+		s.clearCachedState()
+		return err
+	}
+
+	// Update cache after successful save, unless other instances saved meanwhile
+	if snapshot != nil {
+		s.setCachedState(snapshot)
+	} else {
+		s.clearCachedState()
+	}
+
+	// Keep a copy of every saved state for restoring
+	backupSavedState(ctx, s, state)
+
+	return nil
+}
+
+// getUnsavedState returns a copy of the latest state queued or being written, or nil
+// when every save has been written
+func (s *CloudStorageService) getUnsavedState() *SubscriptionState {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	if s.pendingSave != nil {
+		return s.deepCopyState(s.pendingSave)
+	}
+	return s.deepCopyState(s.writingSave)
+}
+
+// GetSubscription reads a single channel object. While the cache is fresh the object
+// is only read if its generation changed since it was cached.
+func (s *CloudStorageService) GetSubscription(ctx context.Context, channelID string) (*Subscription, error) {
+	if err := s.initialize(ctx); err != nil {
+		return nil, err
+	}
+
+	// Saves not yet written are newer than anything stored
+	if unsaved := s.getUnsavedState(); unsaved != nil {
+		subscription, exists := unsaved.Subscriptions[channelID]
+		if !exists {
+			return nil, ErrSubscriptionNotFound
+		}
+		return subscription, nil
+	}
+
+	var data []byte
+	var generation int64
+	var err error
+	cached, fresh := s.getCachedState()
+	if fresh && cached.state.Subscriptions[channelID] != nil {
+		data, generation, err = s.storageOps.GetObjectIfChanged(ctx, s.bucketName, stateChannelObject(channelID), cached.channels[channelID])
+		if errors.Is(err, ErrObjectNotModified) {
+			return copySubscription(cached.state.Subscriptions[channelID]), nil
+		}
+	} else {
+		data, generation, err = s.storageOps.GetObjectWithGeneration(ctx, s.bucketName, stateChannelObject(channelID))
+	}
+	if err == storage.ErrObjectNotExist {
+		s.cacheChannel(channelID, nil, 0, 0, 0)
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage object: %v", err)
+	}
+
+	subscription, err := decodeChannelObject(channelID, data)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheChannel(channelID, subscription, generation, 0, 0)
+	return subscription, nil
+}
 
-	// Save to Cloud Storage
-	if err := s.saveToStorage(ctx, state); err != nil {
+// SaveSubscription writes a single channel object and records its generation in the
+// index. While whole-state saves are waiting to be written, the subscription is saved
+// through the state instead, so those saves cannot undo it.
+func (s *CloudStorageService) SaveSubscription(ctx context.Context, subscription *Subscription) error {
+	if err := s.initialize(ctx); err != nil {
 		return err
 	}
+	if s.getUnsavedState() != nil {
+		return s.updateState(ctx, func(state *SubscriptionState) {
+			state.Subscriptions[subscription.ChannelID] = subscription
+		})
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
 
-	// Update cache after successful save
-	s.setCachedState(state)
+	data, err := s.encodeObject(stateChannel{Version: CurrentSchemaVersion, Subscription: subscription})
+	if err != nil {
+		return err
+	}
+	generation, err := s.storageOps.PutObjectWithGeneration(ctx, s.bucketName, stateChannelObject(subscription.ChannelID), data)
+	if err != nil {
+		s.clearCachedState()
+		return fmt.Errorf("failed to put storage object: %v", err)
+	}
 
+	before, after, err := s.updateIndex(ctx, nil, func(index *stateIndex) {
+		index.Channels[subscription.ChannelID] = generation
+	})
+	if err != nil {
+		s.clearCachedState()
+		return err
+	}
+	s.cacheChannel(subscription.ChannelID, subscription, generation, before, after)
+	return nil
+}
+
+// DeleteSubscription removes a channel from the index, then deletes its object. While
+// whole-state saves are waiting to be written, the channel is removed through the
+// state instead.
+func (s *CloudStorageService) DeleteSubscription(ctx context.Context, channelID string) error {
+	if err := s.initialize(ctx); err != nil {
+		return err
+	}
+	if s.getUnsavedState() != nil {
+		return s.updateState(ctx, func(state *SubscriptionState) {
+			delete(state.Subscriptions, channelID)
+		})
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	before, after, err := s.updateIndex(ctx, nil, func(index *stateIndex) {
+		delete(index.Channels, channelID)
+	})
+	if err != nil {
+		s.clearCachedState()
+		return err
+	}
+	s.cacheChannel(channelID, nil, 0, before, after)
+
+	// An object left behind is no longer listed, so a failed delete is only logged
+	err = s.storageOps.DeleteObject(ctx, s.bucketName, stateChannelObject(channelID))
+	if err != nil && err != storage.ErrObjectNotExist {
+		fmt.Printf("Unable to delete channel object %s: %v\n", channelID, err)
+	}
+	return nil
+}
+
+// updateState applies a change to the whole state and saves it
+func (s *CloudStorageService) updateState(ctx context.Context, change func(state *SubscriptionState)) error {
+	state, err := s.LoadSubscriptionState(ctx)
+	if err != nil {
+		return err
+	}
+
+	change(state)
+	return s.SaveSubscriptionState(ctx, state)
+}
+
+// ReadObject reads an auxiliary object from the state bucket
+func (s *CloudStorageService) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	if err := s.initializeOperations(ctx); err != nil {
+		return nil, err
+	}
+
+	data, err := s.storageOps.GetObject(ctx, s.bucketName, name)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage object: %v", err)
+	}
+	return data, nil
+}
+
+// WriteObject writes an auxiliary object to the state bucket
+func (s *CloudStorageService) WriteObject(ctx context.Context, name string, data []byte) error {
+	if err := s.initializeOperations(ctx); err != nil {
+		return err
+	}
+
+	if err := s.storageOps.PutObject(ctx, s.bucketName, name, data); err != nil {
+		return fmt.Errorf("failed to put storage object: %v", err)
+	}
+	return nil
+}
+
+// CreateObject writes an auxiliary object to the state bucket unless it exists
+func (s *CloudStorageService) CreateObject(ctx context.Context, name string, data []byte) error {
+	if err := s.initializeOperations(ctx); err != nil {
+		return err
+	}
+
+	_, err := s.storageOps.PutObjectIfGeneration(ctx, s.bucketName, name, data, 0)
+	if errors.Is(err, ErrObjectChanged) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create storage object: %v", err)
+	}
+	return nil
+}
+
+// ReadObjectGeneration reads an auxiliary object from the state bucket with its
+// generation
+func (s *CloudStorageService) ReadObjectGeneration(ctx context.Context, name string) ([]byte, int64, error) {
+	if err := s.initializeOperations(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	data, generation, err := s.storageOps.GetObjectWithGeneration(ctx, s.bucketName, name)
+	if err == storage.ErrObjectNotExist {
+		return nil, 0, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get storage object: %v", err)
+	}
+	return data, generation, nil
+}
+
+// WriteObjectIfGeneration writes an auxiliary object to the state bucket while it is
+// still at generation
+func (s *CloudStorageService) WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error {
+	if err := s.initializeOperations(ctx); err != nil {
+		return err
+	}
+
+	_, err := s.storageOps.PutObjectIfGeneration(ctx, s.bucketName, name, data, generation)
+	if errors.Is(err, ErrObjectChanged) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put storage object: %v", err)
+	}
+	return nil
+}
+
+// DeleteObject removes an auxiliary object from the state bucket
+func (s *CloudStorageService) DeleteObject(ctx context.Context, name string) error {
+	if err := s.initializeOperations(ctx); err != nil {
+		return err
+	}
+
+	err := s.storageOps.DeleteObject(ctx, s.bucketName, name)
+	if err != nil && err != storage.ErrObjectNotExist {
+		return fmt.Errorf("failed to delete storage object: %v", err)
+	}
+	return nil
+}
+
+// Ping checks the state bucket can be read, bypassing the cache
+func (s *CloudStorageService) Ping(ctx context.Context) error {
+	if err := s.initializeOperations(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.storageOps.GetObject(ctx, s.bucketName, s.objectPath); err != nil && err != storage.ErrObjectNotExist {
+		return fmt.Errorf("failed to reach bucket %s: %v", s.bucketName, err)
+	}
 	return nil
 }
 
 // Close closes the storage operations and clears cache
 func (s *CloudStorageService) Close() error {
-	s.cacheMutex.Lock()
-	defer s.cacheMutex.Unlock()
-
-	s.cache = nil
-	s.cacheTime = time.Time{}
+	s.clearCachedState()
 
 	if s.storageOps != nil {
 		return s.storageOps.Close()
@@ -200,80 +752,239 @@ func (s *CloudStorageService) Close() error {
 
 // Private helper methods
 
-func (s *CloudStorageService) getCachedState() *SubscriptionState {
+// getCachedState returns the cached snapshot and whether it is still within the TTL.
+// An expired snapshot is still returned so its channels can be reused while their
+// generations match; nil when nothing is cached or the cache is disabled.
+func (s *CloudStorageService) getCachedState() (*stateSnapshot, bool) {
 	s.cacheMutex.RLock()
 	defer s.cacheMutex.RUnlock()
 
-	if s.cache != nil && time.Since(s.cacheTime) < s.cacheTTL {
-		return s.cache
+	if s.cache == nil || s.cacheTTL <= 0 {
+		return nil, false
 	}
-	return nil
+	return s.cache, time.Since(s.cacheTime) < s.cacheTTL
 }
 
-func (s *CloudStorageService) setCachedState(state *SubscriptionState) {
+func (s *CloudStorageService) setCachedState(snapshot *stateSnapshot) {
 	s.cacheMutex.Lock()
 	defer s.cacheMutex.Unlock()
 
-	s.cache = s.deepCopyState(state)
+	s.cache = &stateSnapshot{
+		state:      s.deepCopyState(snapshot.state),
+		generation: snapshot.generation,
+		channels:   copyGenerations(snapshot.channels),
+	}
 	s.cacheTime = time.Now()
 }
 
-func (s *CloudStorageService) loadFromStorage(ctx context.Context) (*SubscriptionState, error) {
-	data, err := s.storageOps.GetObject(ctx, s.bucketName, s.objectPath)
+// cacheChannel records a channel read or written on its own, sub nil when it no longer
+// exists. When the index was updated from the cached generation (indexBefore), the
+// cache moves to the new one (indexAfter), so the next load still skips reading it.
+// The cached snapshot is replaced rather than changed, since loads copy it unlocked.
+func (s *CloudStorageService) cacheChannel(channelID string, sub *Subscription, generation int64, indexBefore, indexAfter int64) {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	if s.cache == nil {
+		return
+	}
+
+	state := *s.cache.state
+	state.Subscriptions = make(map[string]*Subscription, len(s.cache.state.Subscriptions)+1)
+	for id, cached := range s.cache.state.Subscriptions {
+		state.Subscriptions[id] = cached
+	}
+	updated := &stateSnapshot{state: &state, generation: s.cache.generation, channels: copyGenerations(s.cache.channels)}
+	if sub == nil {
+		delete(updated.state.Subscriptions, channelID)
+		delete(updated.channels, channelID)
+	} else {
+		updated.state.Subscriptions[channelID] = copySubscription(sub)
+		updated.channels[channelID] = generation
+	}
+	if indexAfter != 0 && indexBefore == s.cache.generation {
+		updated.generation = indexAfter
+	}
+	s.cache = updated
+}
+
+// clearCachedState drops the cached state, so the next load reads the index and every
+// channel
+func (s *CloudStorageService) clearCachedState() {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	s.cache = nil
+	s.cacheTime = time.Time{}
+}
+
+// loadSnapshot returns the stored state, from cache while the index is unchanged, and
+// caches what was read
+func (s *CloudStorageService) loadSnapshot(ctx context.Context) (*stateSnapshot, error) {
+	cached, fresh := s.getCachedState()
+	snapshot, err := s.loadFromStorage(ctx, cached, fresh)
+	if errors.Is(err, ErrObjectNotModified) {
+		return cached, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.setCachedState(snapshot)
+	return snapshot, nil
+}
+
+// loadFromStorage reads the index and the channels it lists, reusing the cached
+// channels still at their generation. While the cache is fresh the index read is
+// conditional, returning ErrObjectNotModified while the index is still at the cached
+// generation, or still missing for generation 0.
+func (s *CloudStorageService) loadFromStorage(ctx context.Context, cached *stateSnapshot, fresh bool) (*stateSnapshot, error) {
+	index, generation, err := s.readIndex(ctx, cached, fresh)
+	if err != nil {
+		return nil, err
+	}
+	// If the index doesn't exist, nothing has been stored yet
+	if index == nil {
+		return &stateSnapshot{state: s.createEmptyState(ctx), channels: make(map[string]int64)}, nil
+	}
+
+	state, err := index.subscriptionState()
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, generations, err := s.readChannels(ctx, index.Channels, cached)
+	if err != nil {
+		return nil, err
+	}
+	state.Subscriptions = subscriptions
+
+	return &stateSnapshot{state: state, generation: generation, channels: generations}, nil
+}
+
+// saveToStorage writes the channels that differ from the stored state, then updates
+// the index it read through updateIndex, so channels other instances saved meanwhile are kept,
+// then deletes the objects of the channels the caller removed. Returns nil when the
+// index changed meanwhile, since the state saved is then not all that is stored.
+func (s *CloudStorageService) saveToStorage(ctx context.Context, state *SubscriptionState) (*stateSnapshot, error) {
+	stored, err := s.loadSnapshot(ctx)
 	if err != nil {
-		// If file doesn't exist, return empty state
-		if err == storage.ErrObjectNotExist {
-			return s.createEmptyState(), nil
+		return nil, err
+	}
+
+	var changed []string
+	for channelID, sub := range state.Subscriptions {
+		if _, exists := stored.channels[channelID]; exists && sameSubscription(stored.state.Subscriptions[channelID], sub) {
+			continue
 		}
-		return nil, fmt.Errorf("failed to get storage object: %v", err)
+		changed = append(changed, channelID)
+	}
+	written, err := s.writeChannels(ctx, state.Subscriptions, changed)
+	if err != nil {
+		return nil, err
 	}
 
-	var state SubscriptionState
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal state: %v", err)
+	// A state not loaded from storage replaces it whole
+	var removed []string
+	for channelID := range stored.channels {
+		if _, kept := state.Subscriptions[channelID]; !kept && (state.loaded == nil || state.loaded[channelID]) {
+			removed = append(removed, channelID)
+		}
 	}
 
-	// Ensure subscriptions map is initialized
-	if state.Subscriptions == nil {
-		state.Subscriptions = make(map[string]*Subscription)
+	var channels map[string]int64
+	before, generation, err := s.updateIndex(ctx, stored, func(index *stateIndex) {
+		for channelID, generation := range written {
+			index.Channels[channelID] = generation
+		}
+		for _, channelID := range removed {
+			delete(index.Channels, channelID)
+		}
+		index.Removed = state.Removed
+		channels = copyGenerations(index.Channels)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &state, nil
+	// An object left behind is no longer listed, so a failed delete is only logged
+	for _, channelID := range removed {
+		err := s.storageOps.DeleteObject(ctx, s.bucketName, stateChannelObject(channelID))
+		if err != nil && err != storage.ErrObjectNotExist {
+			fmt.Printf("Unable to delete channel object %s: %v\n", channelID, err)
+		}
+	}
+
+	if before != stored.generation {
+		return nil, nil
+	}
+	return &stateSnapshot{state: state, generation: generation, channels: channels}, nil
 }
 
-func (s *CloudStorageService) saveToStorage(ctx context.Context, state *SubscriptionState) error {
-	data, err := json.MarshalIndent(state, "", "  ")
+// encodeObject renders an index or channel object: indented JSON, gzipped unless
+// STATE_COMPRESSION=none. Compressed, the indentation costs next to nothing and keeps
+// the decompressed object readable.
+func (s *CloudStorageService) encodeObject(v interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %v", err)
+		return nil, fmt.Errorf("failed to marshal state: %v", err)
+	}
+	if s.compression == StateCompressionNone {
+		return data, nil
 	}
 
-	if err := s.storageOps.PutObject(ctx, s.bucketName, s.objectPath, data); err != nil {
-		return fmt.Errorf("failed to put storage object: %v", err)
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress state: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress state: %v", err)
 	}
+	return compressed.Bytes(), nil
+}
 
-	return nil
+// decodeStateObject returns the JSON of a stored state object, decompressing gzipped
+// objects. Plain JSON objects, written before compression or with
+// STATE_COMPRESSION=none, are returned as is.
+func decodeStateObject(data []byte) ([]byte, error) {
+	if !isGzipData(data) {
+		return data, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress state: %v", err)
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress state: %v", err)
+	}
+	return decompressed, nil
 }
 
-// Legacy testMode methods removed - use dependency injection instead
+// isGzipData reports whether data starts with the gzip magic number, which JSON never does
+func isGzipData(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
 
-func (s *CloudStorageService) createEmptyState() *SubscriptionState {
+func (s *CloudStorageService) createEmptyState(ctx context.Context) *SubscriptionState {
 	return &SubscriptionState{
 		Subscriptions: make(map[string]*Subscription),
 		Metadata: struct {
 			LastUpdated time.Time `json:"last_updated"`
 			Version     string    `json:"version"`
 		}{
-			LastUpdated: time.Now(),
-			Version:     "1.0",
+			LastUpdated: currentTime(ctx),
+			Version:     CurrentSchemaVersion,
 		},
 	}
 }
 
-func (s *CloudStorageService) updateMetadata(state *SubscriptionState) {
-	state.Metadata.LastUpdated = time.Now()
-	if state.Metadata.Version == "" {
-		state.Metadata.Version = "1.0"
-	}
+func (s *CloudStorageService) updateMetadata(ctx context.Context, state *SubscriptionState) error {
+	state.Metadata.LastUpdated = currentTime(ctx)
+	return stampSchemaVersion(state)
 }
 
 func (s *CloudStorageService) deepCopyState(original *SubscriptionState) *SubscriptionState {
@@ -285,35 +996,52 @@ func (s *CloudStorageService) deepCopyState(original *SubscriptionState) *Subscr
 		Subscriptions: make(map[string]*Subscription),
 		Metadata:      original.Metadata,
 	}
+	if original.loaded != nil {
+		copy.loaded = make(map[string]bool, len(original.loaded))
+		for channelID := range original.loaded {
+			copy.loaded[channelID] = true
+		}
+	}
 
 	for k, v := range original.Subscriptions {
 		if v != nil {
-			subCopy := *v
-			copy.Subscriptions[k] = &subCopy
+			copy.Subscriptions[k] = copySubscription(v)
+		}
+	}
+	for k, v := range original.Removed {
+		if v != nil && v.Subscription != nil {
+			if copy.Removed == nil {
+				copy.Removed = make(map[string]*RemovedSubscription)
+			}
+			copy.Removed[k] = &RemovedSubscription{Subscription: copySubscription(v.Subscription), RemovedAt: v.RemovedAt, RemovedBy: v.RemovedBy}
 		}
 	}
 
 	return copy
 }
 
-// LegacyStorageService provides backward compatibility with the old CloudStorageClient
-type LegacyStorageService struct {
-	optimized *CloudStorageService
-}
-
-// NewLegacyStorageService creates a service that maintains compatibility
-func NewLegacyStorageService() *LegacyStorageService {
-	return &LegacyStorageService{
-		optimized: NewCloudStorageService(),
+// copySubscription returns a copy of sub sharing nothing with it, so changes made in
+// place to a loaded subscription, such as a recovery attempt, cannot reach the cache
+// the next save is compared with
+func copySubscription(sub *Subscription) *Subscription {
+	copied := *sub
+	if sub.Recovery != nil {
+		recovery := *sub.Recovery
+		copied.Recovery = &recovery
 	}
-}
-
-// LoadSubscriptionState provides backward compatibility
-func (b *LegacyStorageService) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
-	return b.optimized.LoadSubscriptionState(ctx)
-}
-
-// SaveSubscriptionState provides backward compatibility
-func (b *LegacyStorageService) SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error {
-	return b.optimized.SaveSubscriptionState(ctx, state)
+	if sub.Filter != nil {
+		filter := *sub.Filter
+		filter.IncludeKeywords = slices.Clone(sub.Filter.IncludeKeywords)
+		filter.ExcludeKeywords = slices.Clone(sub.Filter.ExcludeKeywords)
+		copied.Filter = &filter
+	}
+	copied.Sinks = slices.Clone(sub.Sinks)
+	if sub.Repositories != nil {
+		copied.Repositories = make([]RepositoryTarget, len(sub.Repositories))
+		for i, target := range sub.Repositories {
+			target.Inputs = maps.Clone(target.Inputs)
+			copied.Repositories[i] = target
+		}
+	}
+	return &copied
 }
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,40 +18,117 @@ import (
 
 // MockCloudStorageOperations implements CloudStorageOperations for testing
 type MockCloudStorageOperations struct {
-	objects map[string][]byte
-	getErr  error
-	putErr  error
-	closed  bool
+	mu          sync.Mutex
+	objects     map[string][]byte
+	generations map[string]int64
+	nextGen     int64
+	getCalls    int
+	condCalls   int
+	getErr      error
+	putErr      error
+	closed      bool
 }
 
 func NewMockCloudStorageOperations() *MockCloudStorageOperations {
 	return &MockCloudStorageOperations{
-		objects: make(map[string][]byte),
+		objects:     make(map[string][]byte),
+		generations: make(map[string]int64),
 	}
 }
 
 func (m *MockCloudStorageOperations) GetObject(ctx context.Context, bucket, objectPath string) ([]byte, error) {
+	data, _, err := m.GetObjectWithGeneration(ctx, bucket, objectPath)
+	return data, err
+}
+
+func (m *MockCloudStorageOperations) GetObjectWithGeneration(ctx context.Context, bucket, objectPath string) ([]byte, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.getCalls++
 	if m.getErr != nil {
-		return nil, m.getErr
+		return nil, 0, m.getErr
 	}
-	
+
 	key := bucket + "/" + objectPath
 	data, exists := m.objects[key]
 	if !exists {
-		return nil, storage.ErrObjectNotExist
+		return nil, 0, storage.ErrObjectNotExist
 	}
-	
-	return data, nil
+
+	return data, m.generations[key], nil
+}
+
+func (m *MockCloudStorageOperations) GetObjectIfChanged(ctx context.Context, bucket, objectPath string, generation int64) ([]byte, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.condCalls++
+	if m.getErr != nil {
+		return nil, 0, m.getErr
+	}
+
+	key := bucket + "/" + objectPath
+	data, exists := m.objects[key]
+	if !exists {
+		return nil, 0, storage.ErrObjectNotExist
+	}
+	if m.generations[key] == generation {
+		return nil, generation, ErrObjectNotModified
+	}
+
+	m.getCalls++
+	return data, m.generations[key], nil
 }
 
 func (m *MockCloudStorageOperations) PutObject(ctx context.Context, bucket, objectPath string, data []byte) error {
+	_, err := m.PutObjectWithGeneration(ctx, bucket, objectPath, data)
+	return err
+}
+
+func (m *MockCloudStorageOperations) PutObjectWithGeneration(ctx context.Context, bucket, objectPath string, data []byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.put(bucket+"/"+objectPath, data)
+}
+
+func (m *MockCloudStorageOperations) PutObjectIfGeneration(ctx context.Context, bucket, objectPath string, data []byte, generation int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := bucket + "/" + objectPath
+	if m.generations[key] != generation {
+		return 0, ErrObjectChanged
+	}
+	return m.put(key, data)
+}
+
+func (m *MockCloudStorageOperations) put(key string, data []byte) (int64, error) {
 	if m.putErr != nil {
-		return m.putErr
+		return 0, m.putErr
 	}
-	
-	key := bucket + "/" + objectPath
+
 	m.objects[key] = make([]byte, len(data))
 	copy(m.objects[key], data)
+	m.nextGen++
+	m.generations[key] = m.nextGen
+	return m.nextGen, nil
+}
+
+func (m *MockCloudStorageOperations) DeleteObject(ctx context.Context, bucket, objectPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.putErr != nil {
+		return m.putErr
+	}
+
+	key := bucket + "/" + objectPath
+	if _, exists := m.objects[key]; !exists {
+		return storage.ErrObjectNotExist
+	}
+	delete(m.objects, key)
+	delete(m.generations, key)
 	return nil
 }
 
@@ -58,25 +138,56 @@ func (m *MockCloudStorageOperations) Close() error {
 }
 
 func (m *MockCloudStorageOperations) SetGetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.getErr = err
 }
 
 func (m *MockCloudStorageOperations) SetPutError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.putErr = err
 }
 
 func (m *MockCloudStorageOperations) Reset() {
 	m.objects = make(map[string][]byte)
+	m.generations = make(map[string]int64)
 	m.getErr = nil
 	m.putErr = nil
 	m.closed = false
 }
 
+// storedState decodes the index and channel objects the service wrote
+func (m *MockCloudStorageOperations) storedState(t testing.TB, bucket string) *SubscriptionState {
+	t.Helper()
+	index, err := decodeStateIndex(m.objects[bucket+"/"+stateIndexObject])
+	require.NoError(t, err)
+	state, err := index.subscriptionState()
+	require.NoError(t, err)
+	for channelID := range index.Channels {
+		sub, err := decodeChannelObject(channelID, m.objects[bucket+"/"+stateChannelObject(channelID)])
+		require.NoError(t, err)
+		state.Subscriptions[channelID] = sub
+	}
+	return state
+}
+
+// storedBytes returns the total size of the index and channel objects
+func (m *MockCloudStorageOperations) storedBytes(bucket string) int {
+	total := len(m.objects[bucket+"/"+stateIndexObject])
+	for key, data := range m.objects {
+		if strings.HasPrefix(key, bucket+"/"+stateChannelPrefix) {
+			total += len(data)
+		}
+	}
+	return total
+}
+
 func TestNewCloudStorageService(t *testing.T) {
 	service := NewCloudStorageService()
 	
 	assert.NotNil(t, service)
-	assert.Equal(t, "subscriptions/state.json", service.objectPath)
+	assert.Equal(t, stateIndexObject, service.objectPath)
 	assert.Equal(t, 5*time.Minute, service.cacheTTL)
 	assert.Nil(t, service.storageOps) // Not initialized yet
 	assert.Nil(t, service.cache)
@@ -89,7 +200,7 @@ func TestNewCloudStorageServiceWithOperations(t *testing.T) {
 	assert.NotNil(t, service)
 	assert.Equal(t, mockOps, service.storageOps)
 	assert.Equal(t, "test-bucket", service.bucketName)
-	assert.Equal(t, "subscriptions/state.json", service.objectPath)
+	assert.Equal(t, stateIndexObject, service.objectPath)
 	assert.Equal(t, 5*time.Minute, service.cacheTTL)
 }
 
@@ -112,6 +223,20 @@ func TestCloudStorageService_InitializeErrors(t *testing.T) {
 		assert.Contains(t, err.Error(), "SUBSCRIPTION_BUCKET environment variable not set")
 	})
 
+	t.Run("RetriesAfterFailure", func(t *testing.T) {
+		os.Unsetenv("SUBSCRIPTION_BUCKET")
+		defer os.Unsetenv("SUBSCRIPTION_BUCKET")
+
+		service := &CloudStorageService{storageOps: NewMockCloudStorageOperations(), objectPath: stateIndexObject}
+		ctx := context.Background()
+
+		assert.Error(t, service.initialize(ctx))
+
+		os.Setenv("SUBSCRIPTION_BUCKET", "test-bucket")
+		assert.NoError(t, service.initialize(ctx), "a failed initialization should not be cached")
+		assert.Equal(t, "test-bucket", service.bucketName)
+	})
+
 	t.Run("InitializeOnlyOnce", func(t *testing.T) {
 		// Set up environment
 		os.Setenv("SUBSCRIPTION_BUCKET", "test-bucket")
@@ -137,7 +262,7 @@ func TestCloudStorageService_CacheOperations(t *testing.T) {
 	service.cacheTTL = 100 * time.Millisecond // Short TTL for testing
 
 	t.Run("EmptyCache", func(t *testing.T) {
-		cached := service.getCachedState()
+		cached, _ := service.getCachedState()
 		assert.Nil(t, cached)
 	})
 
@@ -151,13 +276,16 @@ func TestCloudStorageService_CacheOperations(t *testing.T) {
 		testState.Metadata.LastUpdated = time.Now()
 
 		// Set cache
-		service.setCachedState(testState)
+		service.setCachedState(&stateSnapshot{state: testState, generation: 3, channels: map[string]int64{"test": 2}})
 		
 		// Get from cache
-		cached := service.getCachedState()
+		cached, fresh := service.getCachedState()
 		require.NotNil(t, cached)
-		assert.Equal(t, "UCtest", cached.Subscriptions["test"].ChannelID)
-		assert.Equal(t, "1.0", cached.Metadata.Version)
+		assert.True(t, fresh)
+		assert.Equal(t, "UCtest", cached.state.Subscriptions["test"].ChannelID)
+		assert.Equal(t, "1.0", cached.state.Metadata.Version)
+		assert.Equal(t, int64(3), cached.generation)
+		assert.Equal(t, int64(2), cached.channels["test"])
 	})
 
 	t.Run("CacheExpiration", func(t *testing.T) {
@@ -168,19 +296,145 @@ func TestCloudStorageService_CacheOperations(t *testing.T) {
 		}
 
 		// Set cache
-		service.setCachedState(testState)
+		service.setCachedState(&stateSnapshot{state: testState})
 		
 		// Should be cached
-		cached := service.getCachedState()
-		assert.NotNil(t, cached)
+		_, fresh := service.getCachedState()
+		assert.True(t, fresh)
 
 		// Wait for expiration
 		time.Sleep(150 * time.Millisecond)
 		
-		// Should be expired
-		expired := service.getCachedState()
-		assert.Nil(t, expired)
+		// Should be expired, but kept for reusing unchanged channels
+		expired, fresh := service.getCachedState()
+		assert.False(t, fresh)
+		assert.NotNil(t, expired)
+	})
+
+	t.Run("CacheChannel", func(t *testing.T) {
+		service.setCachedState(&stateSnapshot{
+			state:      &SubscriptionState{Subscriptions: map[string]*Subscription{"UCa": {ChannelID: "UCa"}}},
+			generation: 5,
+			channels:   map[string]int64{"UCa": 4},
+		})
+		before, _ := service.getCachedState()
+
+		service.cacheChannel("UCb", &Subscription{ChannelID: "UCb"}, 6, 5, 7)
+		cached, _ := service.getCachedState()
+		assert.Len(t, cached.state.Subscriptions, 2)
+		assert.Equal(t, int64(6), cached.channels["UCb"])
+		assert.Equal(t, int64(7), cached.generation, "the index was updated from the cached one")
+		assert.Len(t, before.state.Subscriptions, 1, "the earlier snapshot is not changed")
+
+		service.cacheChannel("UCa", nil, 0, 1, 8)
+		cached, _ = service.getCachedState()
+		assert.NotContains(t, cached.state.Subscriptions, "UCa")
+		assert.Equal(t, int64(7), cached.generation, "an index updated from another generation is re-read")
+	})
+}
+
+func TestCloudStorageService_CacheValidation(t *testing.T) {
+	t.Setenv("STATE_BACKUP_COUNT", "0") // Count reads of the state objects only
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	// Two instances sharing one bucket
+	first := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+	second := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+	require.NoError(t, first.initialize(ctx))
+	require.NoError(t, second.initialize(ctx))
+	mockOps.getCalls = 0
+
+	_, err := first.LoadSubscriptionState(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, mockOps.getCalls)
+
+	t.Run("unchanged_object_served_from_cache", func(t *testing.T) {
+		_, err := first.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, mockOps.getCalls, "state should not be re-read")
+		assert.Equal(t, 1, mockOps.condCalls)
+	})
+
+	t.Run("write_from_another_instance_invalidates_cache", func(t *testing.T) {
+		state := &SubscriptionState{Subscriptions: map[string]*Subscription{
+			"UCXuqSBlHAE6Xw-yeJA0Tunw": {ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive},
+			"UC_x5XG1OV2P6uZZ5FSM9Ttw": {ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: StatusActive},
+		}}
+		require.NoError(t, second.SaveSubscriptionState(ctx, state))
+
+		getCalls := mockOps.getCalls
+		loaded, err := first.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, loaded.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		assert.Equal(t, getCalls+3, mockOps.getCalls, "the index and both channels are read")
+	})
+
+	t.Run("only_changed_channels_are_read", func(t *testing.T) {
+		state, err := second.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		state.Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"].ChannelName = "Renamed"
+		require.NoError(t, second.SaveSubscriptionState(ctx, state))
+
+		getCalls := mockOps.getCalls
+		loaded, err := first.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", loaded.Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"].ChannelName)
+		assert.Len(t, loaded.Subscriptions, 2)
+		assert.Equal(t, getCalls+2, mockOps.getCalls, "the index and the renamed channel are read")
+	})
+
+	t.Run("own_write_keeps_cache_current", func(t *testing.T) {
+		state, err := first.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		delete(state.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		require.NoError(t, first.SaveSubscriptionState(ctx, state))
+		assert.NotContains(t, mockOps.objects, "test-bucket/"+stateChannelObject("UCXuqSBlHAE6Xw-yeJA0Tunw"))
+
+		getCalls := mockOps.getCalls
+		loaded, err := first.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Len(t, loaded.Subscriptions, 1)
+		assert.Equal(t, getCalls, mockOps.getCalls)
 	})
+
+	t.Run("expired_cache_reuses_unchanged_channels", func(t *testing.T) {
+		first.cacheMutex.Lock()
+		first.cacheTime = time.Now().Add(-time.Hour)
+		first.cacheMutex.Unlock()
+
+		getCalls := mockOps.getCalls
+		_, err := first.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, getCalls+1, mockOps.getCalls, "only the index is read")
+	})
+
+	t.Run("metadata_error_reloads_state", func(t *testing.T) {
+		mockOps.SetGetError(errors.New("storage error"))
+		defer mockOps.SetGetError(nil)
+
+		_, err := first.LoadSubscriptionState(ctx)
+		assert.ErrorContains(t, err, "failed to get storage object")
+	})
+}
+
+func TestGetStateCacheTTL(t *testing.T) {
+	defer os.Unsetenv("STATE_CACHE_TTL_SECONDS")
+
+	os.Unsetenv("STATE_CACHE_TTL_SECONDS")
+	assert.Equal(t, 5*time.Minute, getStateCacheTTL())
+
+	os.Setenv("STATE_CACHE_TTL_SECONDS", "30")
+	assert.Equal(t, 30*time.Second, getStateCacheTTL())
+
+	os.Setenv("STATE_CACHE_TTL_SECONDS", "0")
+	assert.Equal(t, time.Duration(0), getStateCacheTTL())
+	service := NewCloudStorageServiceWithOperations(NewMockCloudStorageOperations(), "test-bucket")
+	service.setCachedState(&stateSnapshot{state: &SubscriptionState{}})
+	cached, _ := service.getCachedState()
+	assert.Nil(t, cached, "a zero TTL disables the cache")
+
+	os.Setenv("STATE_CACHE_TTL_SECONDS", "-5")
+	assert.Equal(t, 5*time.Minute, getStateCacheTTL())
 }
 
 func TestCloudStorageService_DeepCopyState(t *testing.T) {
@@ -208,6 +462,16 @@ func TestCloudStorageService_DeepCopyState(t *testing.T) {
 	assert.Equal(t, "active", originalState.Subscriptions["test1"].Status)
 	assert.Equal(t, "modified", copied.Subscriptions["test1"].Status)
 
+	// Removed subscriptions are copied too
+	originalState.Removed = map[string]*RemovedSubscription{
+		"UCgone": {Subscription: &Subscription{ChannelID: "UCgone", Status: "active"}, RemovedBy: "anonymous"},
+	}
+	copied = service.deepCopyState(originalState)
+	require.Contains(t, copied.Removed, "UCgone")
+	copied.Removed["UCgone"].Subscription.Status = "modified"
+	assert.Equal(t, "active", originalState.Removed["UCgone"].Subscription.Status)
+	assert.Equal(t, "anonymous", copied.Removed["UCgone"].RemovedBy)
+
 	// Test nil input
 	nilCopy := service.deepCopyState(nil)
 	assert.Nil(t, nilCopy)
@@ -226,11 +490,11 @@ func TestCloudStorageService_UpdateMetadata(t *testing.T) {
 	
 	// Update metadata
 	beforeUpdate := time.Now()
-	service.updateMetadata(state)
+	require.NoError(t, service.updateMetadata(context.Background(), state))
 	afterUpdate := time.Now()
 	
 	// Verify metadata was updated
-	assert.Equal(t, "1.0", state.Metadata.Version)
+	assert.Equal(t, CurrentSchemaVersion, state.Metadata.Version)
 	assert.True(t, state.Metadata.LastUpdated.After(beforeUpdate))
 	assert.True(t, state.Metadata.LastUpdated.Before(afterUpdate))
 }
@@ -261,7 +525,7 @@ func TestCloudStorageService_LoadSubscriptionState(t *testing.T) {
 		
 		// Verify loaded data
 		assert.Equal(t, "UCtest", loaded.Subscriptions["test"].ChannelID)
-		assert.Equal(t, "1.0", loaded.Metadata.Version)
+		assert.Equal(t, CurrentSchemaVersion, loaded.Metadata.Version)
 	})
 
 	t.Run("LoadFromStorage_ObjectNotExist", func(t *testing.T) {
@@ -276,7 +540,7 @@ func TestCloudStorageService_LoadSubscriptionState(t *testing.T) {
 		// Should return empty state
 		assert.NotNil(t, loaded.Subscriptions)
 		assert.Len(t, loaded.Subscriptions, 0)
-		assert.Equal(t, "1.0", loaded.Metadata.Version)
+		assert.Equal(t, CurrentSchemaVersion, loaded.Metadata.Version)
 	})
 
 	t.Run("LoadFromStorage_Error", func(t *testing.T) {
@@ -301,7 +565,7 @@ func TestCloudStorageService_LoadSubscriptionState(t *testing.T) {
 			},
 		}
 		cachedState.Metadata.Version = "1.0"
-		service.setCachedState(cachedState)
+		service.setCachedState(&stateSnapshot{state: cachedState, channels: map[string]int64{}})
 
 		// Load should return cached version
 		ctx := context.Background()
@@ -314,8 +578,8 @@ func TestCloudStorageService_LoadSubscriptionState(t *testing.T) {
 		
 		// Verify it's a deep copy (modifying loaded shouldn't affect cache)
 		loaded.Subscriptions["cached"].Status = "modified"
-		cachedAgain := service.getCachedState()
-		assert.Equal(t, "active", cachedAgain.Subscriptions["cached"].Status)
+		cachedAgain, _ := service.getCachedState()
+		assert.Equal(t, "active", cachedAgain.state.Subscriptions["cached"].Status)
 	})
 }
 
@@ -335,21 +599,17 @@ func TestCloudStorageService_SaveSubscriptionState(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify metadata was updated
-		assert.Equal(t, "1.0", testState.Metadata.Version)
+		assert.Equal(t, CurrentSchemaVersion, testState.Metadata.Version)
 		assert.False(t, testState.Metadata.LastUpdated.IsZero())
 
 		// Verify cache was updated
-		cached := service.getCachedState()
+		cached, _ := service.getCachedState()
 		require.NotNil(t, cached)
-		assert.Equal(t, "UCsave", cached.Subscriptions["save-test"].ChannelID)
+		assert.Equal(t, "UCsave", cached.state.Subscriptions["save-test"].ChannelID)
 
 		// Verify data was saved to storage
-		data, err := mockOps.GetObject(ctx, "test-bucket", "subscriptions/state.json")
-		require.NoError(t, err)
-		
-		var savedState SubscriptionState
-		err = json.Unmarshal(data, &savedState)
-		require.NoError(t, err)
+		assert.Contains(t, mockOps.objects, "test-bucket/"+stateChannelObject("save-test"))
+		savedState := mockOps.storedState(t, "test-bucket")
 		assert.Equal(t, "UCsave", savedState.Subscriptions["save-test"].ChannelID)
 	})
 
@@ -369,6 +629,290 @@ func TestCloudStorageService_SaveSubscriptionState(t *testing.T) {
 	})
 }
 
+func TestCloudStorageService_PerSubscriptionOperations(t *testing.T) {
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+
+	_, err := service.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	assert.Equal(t, ErrSubscriptionNotFound, err)
+
+	require.NoError(t, service.SaveSubscription(ctx, &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "active"}))
+	require.NoError(t, service.SaveSubscription(ctx, &Subscription{ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw", Status: "active"}))
+
+	sub, err := service.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	require.NoError(t, err)
+	assert.Equal(t, "active", sub.Status)
+
+	t.Run("writes_one_channel", func(t *testing.T) {
+		_, err := service.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		channelObject := "test-bucket/" + stateChannelObject("UC_x5XG1OV2P6uZZ5FSM9Ttw")
+		otherGeneration := mockOps.generations[channelObject]
+		require.NoError(t, service.SaveSubscription(ctx, &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "verified"}))
+		assert.Equal(t, otherGeneration, mockOps.generations[channelObject], "other channels are not rewritten")
+
+		// Served from cache while neither object changed
+		getCalls := mockOps.getCalls
+		sub, err := service.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		require.NoError(t, err)
+		assert.Equal(t, "verified", sub.Status)
+		_, err = service.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, getCalls, mockOps.getCalls)
+	})
+
+	require.NoError(t, service.DeleteSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw"))
+	assert.NotContains(t, mockOps.objects, "test-bucket/"+stateChannelObject("UCXuqSBlHAE6Xw-yeJA0Tunw"))
+
+	stored := mockOps.storedState(t, "test-bucket")
+	assert.Len(t, stored.Subscriptions, 1)
+	assert.Contains(t, stored.Subscriptions, "UC_x5XG1OV2P6uZZ5FSM9Ttw")
+
+	t.Run("seen_by_other_instances", func(t *testing.T) {
+		other := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+		_, err := other.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, service.SaveSubscription(ctx, &Subscription{ChannelID: "UCBJycsmduvYEL83R_U4JriQ", Status: "active"}))
+		sub, err := other.GetSubscription(ctx, "UCBJycsmduvYEL83R_U4JriQ")
+		require.NoError(t, err)
+		assert.Equal(t, "active", sub.Status)
+
+		require.NoError(t, service.DeleteSubscription(ctx, "UCBJycsmduvYEL83R_U4JriQ"))
+		_, err = other.GetSubscription(ctx, "UCBJycsmduvYEL83R_U4JriQ")
+		assert.Equal(t, ErrSubscriptionNotFound, err)
+		loaded, err := other.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.NotContains(t, loaded.Subscriptions, "UCBJycsmduvYEL83R_U4JriQ")
+	})
+
+	t.Run("index_conflict_is_retried", func(t *testing.T) {
+		racing := &racingIndexOperations{MockCloudStorageOperations: mockOps, races: 2}
+		raced := NewCloudStorageServiceWithOperations(racing, "test-bucket")
+		require.NoError(t, raced.SaveSubscription(ctx, &Subscription{ChannelID: "UCBJycsmduvYEL83R_U4JriQ", Status: "active"}))
+
+		stored := mockOps.storedState(t, "test-bucket")
+		assert.Contains(t, stored.Subscriptions, "UCBJycsmduvYEL83R_U4JriQ")
+		assert.Contains(t, stored.Subscriptions, "UC_x5XG1OV2P6uZZ5FSM9Ttw", "the other instance's write is kept")
+
+		racing.races = stateIndexUpdateAttempts
+		assert.ErrorIs(t, raced.DeleteSubscription(ctx, "UCBJycsmduvYEL83R_U4JriQ"), ErrObjectChanged)
+	})
+
+	t.Run("whole_state_saves_keep_other_instances_channels", func(t *testing.T) {
+		other := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+		state, err := service.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		require.Contains(t, state.Subscriptions, "UCBJycsmduvYEL83R_U4JriQ")
+
+		// Another instance subscribes a channel after the state was loaded
+		require.NoError(t, other.SaveSubscription(ctx, &Subscription{ChannelID: "UCabcdefghijklmnopqrstuv", Status: "active"}))
+
+		delete(state.Subscriptions, "UCBJycsmduvYEL83R_U4JriQ")
+		require.NoError(t, service.SaveSubscriptionState(ctx, state))
+
+		stored := mockOps.storedState(t, "test-bucket")
+		assert.Contains(t, stored.Subscriptions, "UCabcdefghijklmnopqrstuv", "a channel saved after the load is kept")
+		assert.Contains(t, mockOps.objects, "test-bucket/"+stateChannelObject("UCabcdefghijklmnopqrstuv"))
+		assert.NotContains(t, stored.Subscriptions, "UCBJycsmduvYEL83R_U4JriQ", "the channel the caller removed is deleted")
+		assert.NotContains(t, mockOps.objects, "test-bucket/"+stateChannelObject("UCBJycsmduvYEL83R_U4JriQ"))
+	})
+
+	t.Run("storage_errors", func(t *testing.T) {
+		failing := NewCloudStorageServiceWithOperations(NewMockCloudStorageOperations(), "test-bucket")
+		failing.storageOps.(*MockCloudStorageOperations).SetGetError(errors.New("get failed"))
+
+		_, err := failing.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		assert.Error(t, err)
+		assert.Error(t, failing.SaveSubscription(ctx, &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}))
+		assert.Error(t, failing.DeleteSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw"))
+	})
+}
+
+func TestCloudStorageService_LegacyStateMigration(t *testing.T) {
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	legacy := `{"subscriptions": {"UCXuqSBlHAE6Xw-yeJA0Tunw": {"channel_id": "UCXuqSBlHAE6Xw-yeJA0Tunw", "status": "active"}},
+		"metadata": {"version": "` + CurrentSchemaVersion + `"}}`
+	require.NoError(t, mockOps.PutObject(ctx, "test-bucket", legacyStateObject, []byte(legacy)))
+
+	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+	state, err := service.LoadSubscriptionState(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, state.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+
+	assert.NotContains(t, mockOps.objects, "test-bucket/"+legacyStateObject)
+	assert.Contains(t, mockOps.objects, "test-bucket/"+stateIndexObject)
+	assert.Contains(t, mockOps.objects, "test-bucket/"+stateChannelObject("UCXuqSBlHAE6Xw-yeJA0Tunw"))
+
+	t.Run("ignored_once_an_index_exists", func(t *testing.T) {
+		stale := `{"subscriptions": {}, "metadata": {"version": "` + CurrentSchemaVersion + `"}}`
+		require.NoError(t, mockOps.PutObject(ctx, "test-bucket", legacyStateObject, []byte(stale)))
+
+		state, err := NewCloudStorageServiceWithOperations(mockOps, "test-bucket").LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, state.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	})
+}
+
+func TestCloudStorageService_StreamSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+
+	channelIDs := make([]string, 0, 40)
+	for i := 0; i < 40; i++ {
+		channelID := fmt.Sprintf("UCstream%02d", i)
+		channelIDs = append(channelIDs, channelID)
+		require.NoError(t, service.SaveSubscription(ctx, &Subscription{ChannelID: channelID, Status: StatusActive}))
+	}
+
+	collect := func(service *CloudStorageService) []string {
+		var streamed []string
+		require.NoError(t, service.StreamSubscriptions(ctx, func(sub *Subscription) error {
+			streamed = append(streamed, sub.ChannelID)
+			sub.Status = "changed" // Callers get their own copy
+			return nil
+		}))
+		return streamed
+	}
+
+	// In channel order, from a cold instance and from a warm one
+	assert.Equal(t, channelIDs, collect(NewCloudStorageServiceWithOperations(mockOps, "test-bucket")))
+	assert.Equal(t, channelIDs, collect(service))
+	sub, err := service.GetSubscription(ctx, "UCstream00")
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, sub.Status)
+
+	t.Run("stops_on_error", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := service.StreamSubscriptions(ctx, func(*Subscription) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+// racingIndexOperations rewrites the index before each conditional write of it, as
+// another instance would, until its races run out
+type racingIndexOperations struct {
+	*MockCloudStorageOperations
+	races int
+}
+
+func (r *racingIndexOperations) PutObjectIfGeneration(ctx context.Context, bucket, objectPath string, data []byte, generation int64) (int64, error) {
+	if r.races > 0 {
+		r.races--
+		current, _, err := r.GetObjectWithGeneration(ctx, bucket, objectPath)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := r.PutObjectWithGeneration(ctx, bucket, objectPath, current); err != nil {
+			return 0, err
+		}
+	}
+	return r.MockCloudStorageOperations.PutObjectIfGeneration(ctx, bucket, objectPath, data, generation)
+}
+
+func TestNewStorageService(t *testing.T) {
+	defer os.Unsetenv("STORAGE_BACKEND")
+
+	testCases := []struct {
+		backend  string
+		expected interface{}
+	}{
+		{"", &CloudStorageService{}},
+		{"gcs", &CloudStorageService{}},
+		{"firestore", &FirestoreStorageService{}},
+		{"unknown", &CloudStorageService{}},
+	}
+
+	for _, tc := range testCases {
+		os.Setenv("STORAGE_BACKEND", tc.backend)
+		assert.IsType(t, tc.expected, NewStorageService(), "STORAGE_BACKEND=%q", tc.backend)
+	}
+}
+
+func TestCloudStorageService_Objects(t *testing.T) {
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+
+	t.Run("missing_object", func(t *testing.T) {
+		_, err := service.ReadObject(ctx, "debug/missing.json")
+		assert.ErrorIs(t, err, ErrObjectNotFound)
+	})
+
+	t.Run("round_trip", func(t *testing.T) {
+		require.NoError(t, service.WriteObject(ctx, "debug/notifications.json", []byte(`[]`)))
+		assert.Contains(t, mockOps.objects, "test-bucket/debug/notifications.json")
+
+		data, err := service.ReadObject(ctx, "debug/notifications.json")
+		require.NoError(t, err)
+		assert.Equal(t, `[]`, string(data))
+	})
+
+	t.Run("create", func(t *testing.T) {
+		require.NoError(t, service.CreateObject(ctx, "debug/created.json", []byte(`"first"`)))
+		assert.ErrorIs(t, service.CreateObject(ctx, "debug/created.json", []byte(`"second"`)), ErrObjectChanged)
+
+		data, err := service.ReadObject(ctx, "debug/created.json")
+		require.NoError(t, err)
+		assert.Equal(t, `"first"`, string(data), "an existing object is kept")
+	})
+
+	t.Run("conditional_write", func(t *testing.T) {
+		_, _, err := service.ReadObjectGeneration(ctx, "debug/shared.json")
+		assert.ErrorIs(t, err, ErrObjectNotFound)
+		require.NoError(t, service.WriteObjectIfGeneration(ctx, "debug/shared.json", []byte(`"first"`), 0))
+
+		data, generation, err := service.ReadObjectGeneration(ctx, "debug/shared.json")
+		require.NoError(t, err)
+		assert.Equal(t, `"first"`, string(data))
+		require.NoError(t, service.WriteObject(ctx, "debug/shared.json", []byte(`"other"`)))
+		assert.ErrorIs(t, service.WriteObjectIfGeneration(ctx, "debug/shared.json", []byte(`"stale"`), generation), ErrObjectChanged)
+
+		data, generation, err = service.ReadObjectGeneration(ctx, "debug/shared.json")
+		require.NoError(t, err)
+		require.NoError(t, service.WriteObjectIfGeneration(ctx, "debug/shared.json", []byte(`"second"`), generation))
+		data, err = service.ReadObject(ctx, "debug/shared.json")
+		require.NoError(t, err)
+		assert.Equal(t, `"second"`, string(data))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, service.WriteObject(ctx, "debug/deleted.json", []byte(`[]`)))
+		require.NoError(t, service.DeleteObject(ctx, "debug/deleted.json"))
+		assert.NotContains(t, mockOps.objects, "test-bucket/debug/deleted.json")
+
+		assert.NoError(t, service.DeleteObject(ctx, "debug/deleted.json"), "deleting a missing object is not an error")
+	})
+
+	t.Run("storage_errors", func(t *testing.T) {
+		mockOps.SetGetError(errors.New("get failed"))
+		mockOps.SetPutError(errors.New("put failed"))
+		defer mockOps.Reset()
+
+		_, err := service.ReadObject(ctx, "debug/notifications.json")
+		assert.Contains(t, err.Error(), "failed to get storage object")
+		err = service.WriteObject(ctx, "debug/notifications.json", []byte(`[]`))
+		assert.Contains(t, err.Error(), "failed to put storage object")
+	})
+
+	t.Run("initialization_error", func(t *testing.T) {
+		uninitialized := NewCloudStorageServiceWithOperations(mockOps, "")
+		os.Unsetenv("SUBSCRIPTION_BUCKET")
+
+		_, err := uninitialized.ReadObject(ctx, "debug/notifications.json")
+		assert.Error(t, err)
+		assert.Error(t, uninitialized.WriteObject(ctx, "debug/notifications.json", nil))
+	})
+}
+
 func TestCloudStorageService_Close(t *testing.T) {
 	mockOps := NewMockCloudStorageOperations()
 	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
@@ -377,10 +921,11 @@ func TestCloudStorageService_Close(t *testing.T) {
 	testState := &SubscriptionState{
 		Subscriptions: map[string]*Subscription{},
 	}
-	service.setCachedState(testState)
+	service.setCachedState(&stateSnapshot{state: testState})
 	
 	// Verify cache exists
-	assert.NotNil(t, service.getCachedState())
+	cached, _ := service.getCachedState()
+	assert.NotNil(t, cached)
 	
 	// Close service
 	err := service.Close()
@@ -414,11 +959,11 @@ func TestCloudStorageService_ConcurrentAccess(t *testing.T) {
 			testState.Metadata.Version = "1.0"
 			
 			// Set and get cache concurrently
-			service.setCachedState(testState)
-			cached := service.getCachedState()
+			service.setCachedState(&stateSnapshot{state: testState})
+			cached, _ := service.getCachedState()
 			
 			if cached != nil {
-				assert.Equal(t, "1.0", cached.Metadata.Version)
+				assert.Equal(t, "1.0", cached.state.Metadata.Version)
 			}
 		}(i)
 	}
@@ -514,40 +1059,433 @@ func TestCloudStorageService_Integration(t *testing.T) {
 	assert.Equal(t, "UCtest", state2.Subscriptions["test"].ChannelID)
 	
 	// 4. Verify cache was populated
-	cached := service.getCachedState()
+	cached, _ := service.getCachedState()
 	assert.NotNil(t, cached)
-	assert.Equal(t, "UCtest", cached.Subscriptions["test"].ChannelID)
+	assert.Equal(t, "UCtest", cached.state.Subscriptions["test"].ChannelID)
 }
 
-func TestLegacyStorageService(t *testing.T) {
-	t.Run("CreatesWithCloudStorageService", func(t *testing.T) {
-		legacy := NewLegacyStorageService()
-		
-		assert.NotNil(t, legacy)
-		assert.NotNil(t, legacy.optimized)
-		assert.IsType(t, &CloudStorageService{}, legacy.optimized)
-	})
-
-	t.Run("DelegatesToCloudStorageService", func(t *testing.T) {
-		// Create legacy service with mocked underlying service
-		legacy := &LegacyStorageService{
-			optimized: NewCloudStorageServiceWithOperations(
-				NewMockCloudStorageOperations(), 
-				"test-bucket",
-			),
-		}
-		
-		ctx := context.Background()
+// gatedStorageOperations makes the mock storage safe for concurrent use and can hold
+// reads or writes of the state object until released
+type gatedStorageOperations struct {
+	*MockCloudStorageOperations
+	mu        sync.Mutex
+	gate      chan struct{} // Reads and writes of the state object wait on it when set
+	started   chan struct{} // Receives once per gated call
+	stateGets int
+	statePuts int
+}
+
+func newGatedStorageOperations() *gatedStorageOperations {
+	return &gatedStorageOperations{MockCloudStorageOperations: NewMockCloudStorageOperations(), started: make(chan struct{}, 100)}
+}
+
+// hold makes later reads and writes of the state object wait until release is called
+func (g *gatedStorageOperations) hold() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.gate = make(chan struct{})
+}
+
+func (g *gatedStorageOperations) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	close(g.gate)
+	g.gate = nil
+}
+
+func (g *gatedStorageOperations) wait(objectPath string) {
+	if objectPath != stateIndexObject {
+		return
+	}
+	g.mu.Lock()
+	gate := g.gate
+	g.mu.Unlock()
+	if gate != nil {
+		g.started <- struct{}{}
+		<-gate
+	}
+}
+
+func (g *gatedStorageOperations) GetObjectWithGeneration(ctx context.Context, bucket, objectPath string) ([]byte, int64, error) {
+	g.wait(objectPath)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if objectPath == stateIndexObject {
+		g.stateGets++
+	}
+	return g.MockCloudStorageOperations.GetObjectWithGeneration(ctx, bucket, objectPath)
+}
+
+func (g *gatedStorageOperations) GetObject(ctx context.Context, bucket, objectPath string) ([]byte, error) {
+	data, _, err := g.GetObjectWithGeneration(ctx, bucket, objectPath)
+	return data, err
+}
+
+func (g *gatedStorageOperations) PutObjectWithGeneration(ctx context.Context, bucket, objectPath string, data []byte) (int64, error) {
+	g.wait(objectPath)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if objectPath == stateIndexObject {
+		g.statePuts++
+	}
+	return g.MockCloudStorageOperations.PutObjectWithGeneration(ctx, bucket, objectPath, data)
+}
+
+func (g *gatedStorageOperations) PutObjectIfGeneration(ctx context.Context, bucket, objectPath string, data []byte, generation int64) (int64, error) {
+	g.wait(objectPath)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if objectPath == stateIndexObject {
+		g.statePuts++
+	}
+	return g.MockCloudStorageOperations.PutObjectIfGeneration(ctx, bucket, objectPath, data, generation)
+}
+
+func (g *gatedStorageOperations) PutObject(ctx context.Context, bucket, objectPath string, data []byte) error {
+	_, err := g.PutObjectWithGeneration(ctx, bucket, objectPath, data)
+	return err
+}
+
+func (g *gatedStorageOperations) GetObjectIfChanged(ctx context.Context, bucket, objectPath string, generation int64) ([]byte, int64, error) {
+	g.wait(objectPath)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.MockCloudStorageOperations.GetObjectIfChanged(ctx, bucket, objectPath, generation)
+}
+
+func (g *gatedStorageOperations) DeleteObject(ctx context.Context, bucket, objectPath string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.MockCloudStorageOperations.DeleteObject(ctx, bucket, objectPath)
+}
 
-		// Test LoadSubscriptionState delegation
-		_, err := legacy.LoadSubscriptionState(ctx)
+func (g *gatedStorageOperations) SetPutError(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.MockCloudStorageOperations.SetPutError(err)
+}
+
+func (g *gatedStorageOperations) counts() (int, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stateGets, g.statePuts
+}
+
+// pendingSaves returns how many saves are queued behind the one being written
+func (s *CloudStorageService) pendingSaves() int {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+	return len(s.pendingDone)
+}
+
+func TestCloudStorageService_SingleFlightLoads(t *testing.T) {
+	ctx := context.Background()
+	ops := newGatedStorageOperations()
+	service := NewCloudStorageServiceWithOperations(ops, "test-bucket")
+
+	ops.hold()
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			state, err := service.LoadSubscriptionState(ctx)
+			if err == nil {
+				state.Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"] = &Subscription{} // Callers get their own copy
+			}
+			errs <- err
+		}()
+	}
+
+	<-ops.started
+	time.Sleep(20 * time.Millisecond) // Let the other loads join the first
+	ops.release()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
 		assert.NoError(t, err)
+	}
+	gets, _ := ops.counts()
+	assert.Equal(t, 1, gets, "concurrent loads should share one read")
 
-		// Test SaveSubscriptionState delegation
-		testState := &SubscriptionState{
-			Subscriptions: map[string]*Subscription{},
+	state, err := service.LoadSubscriptionState(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, state.Subscriptions)
+}
+
+func TestCloudStorageService_InPlaceChangesPersist(t *testing.T) {
+	ctx := context.Background()
+	mockOps := NewMockCloudStorageOperations()
+	service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+	channelID := "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{channelID: {
+		ChannelID:    channelID,
+		Status:       StatusActive,
+		Recovery:     &RecoveryRecord{Reason: RecoveryReasonUnverified, Attempts: 1},
+		Filter:       &NotificationFilter{IncludeKeywords: []string{"live"}},
+		Sinks:        []WebhookSink{{URL: "https://a.example.com"}},
+		Repositories: []RepositoryTarget{{RepoOwner: "owner", RepoName: "repo", Inputs: map[string]string{"id": "video_id"}}},
+	}}}
+	require.NoError(t, service.SaveSubscriptionState(ctx, state))
+
+	// Change the loaded subscription in place, as a recovery attempt does
+	loaded, err := service.LoadSubscriptionState(ctx)
+	require.NoError(t, err)
+	sub := loaded.Subscriptions[channelID]
+	sub.Recovery.Attempts++
+	sub.Filter.IncludeKeywords[0] = "premiere"
+	sub.Sinks[0].URL = "https://b.example.com"
+	sub.Repositories[0].Inputs["id"] = "title"
+	require.NoError(t, service.SaveSubscriptionState(ctx, loaded))
+
+	fresh, err := NewCloudStorageServiceWithOperations(mockOps, "test-bucket").GetSubscription(ctx, channelID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fresh.Recovery.Attempts)
+	assert.Equal(t, []string{"premiere"}, fresh.Filter.IncludeKeywords)
+	assert.Equal(t, "https://b.example.com", fresh.Sinks[0].URL)
+	assert.Equal(t, "title", fresh.Repositories[0].Inputs["id"])
+}
+
+func TestCloudStorageService_WriteBehind(t *testing.T) {
+	t.Setenv("STATE_BACKUP_COUNT", "0")
+	ctx := context.Background()
+	newState := func(channelIDs ...string) *SubscriptionState {
+		state := &SubscriptionState{Subscriptions: map[string]*Subscription{}}
+		for _, channelID := range channelIDs {
+			state.Subscriptions[channelID] = &Subscription{ChannelID: channelID, Status: StatusActive}
 		}
-		err = legacy.SaveSubscriptionState(ctx, testState)
-		assert.NoError(t, err)
+		return state
+	}
+
+	t.Run("saves_during_a_write_are_coalesced", func(t *testing.T) {
+		ops := newGatedStorageOperations()
+		service := NewCloudStorageServiceWithOperations(ops, "test-bucket")
+
+		ops.hold()
+		first := make(chan error, 1)
+		go func() { first <- service.SaveSubscriptionState(ctx, newState("UCfirst")) }()
+		<-ops.started
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 5)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- service.SaveSubscriptionState(ctx, newState("UClatest"))
+			}()
+		}
+		require.Eventually(t, func() bool { return service.pendingSaves() == 5 }, time.Second, time.Millisecond)
+
+		// Loads see the queued state before it is written
+		state, err := service.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, state.Subscriptions, "UClatest")
+
+		ops.release()
+		require.NoError(t, <-first)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+
+		gets, puts := ops.counts()
+		assert.Equal(t, 1, gets, "only the first save reads the index to find changed channels")
+		assert.Equal(t, 2, puts, "the queued saves should be written once")
+		assert.Contains(t, ops.storedState(t, "test-bucket").Subscriptions, "UClatest")
+	})
+
+	t.Run("coalescing_delay", func(t *testing.T) {
+		t.Setenv("STATE_SAVE_COALESCE_MS", "200")
+		ops := newGatedStorageOperations()
+		service := NewCloudStorageServiceWithOperations(ops, "test-bucket")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, service.SaveSubscriptionState(ctx, newState("UCdelayed")))
+			}()
+		}
+		wg.Wait()
+
+		_, puts := ops.counts()
+		assert.Equal(t, 1, puts)
 	})
-}
\ No newline at end of file
+
+	t.Run("failed_write_fails_every_coalesced_save", func(t *testing.T) {
+		ops := newGatedStorageOperations()
+		service := NewCloudStorageServiceWithOperations(ops, "test-bucket")
+		require.NoError(t, service.SaveSubscriptionState(ctx, newState("UCstored")))
+
+		ops.hold()
+		first := make(chan error, 1)
+		go func() { first <- service.SaveSubscriptionState(ctx, newState("UCfirst")) }()
+		<-ops.started
+		second := make(chan error, 1)
+		go func() { second <- service.SaveSubscriptionState(ctx, newState("UClost")) }()
+		require.Eventually(t, func() bool { return service.pendingSaves() == 1 }, time.Second, time.Millisecond)
+
+		ops.SetPutError(errors.New("storage error"))
+		ops.release()
+		assert.ErrorContains(t, <-first, "failed to put storage object")
+		assert.ErrorContains(t, <-second, "failed to put storage object")
+
+		ops.SetPutError(nil)
+		state, err := service.LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, state.Subscriptions, "UCstored", "the failed states should not be served")
+		assert.NotContains(t, state.Subscriptions, "UClost")
+	})
+}
+
+func TestGetStateSaveCoalesceDelay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), getStateSaveCoalesceDelay())
+
+	t.Setenv("STATE_SAVE_COALESCE_MS", "250")
+	assert.Equal(t, 250*time.Millisecond, getStateSaveCoalesceDelay())
+
+	t.Setenv("STATE_SAVE_COALESCE_MS", "invalid")
+	assert.Equal(t, time.Duration(0), getStateSaveCoalesceDelay())
+}
+
+func TestCloudStorageService_Compression(t *testing.T) {
+	ctx := context.Background()
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{
+		"UCXuqSBlHAE6Xw-yeJA0Tunw": {ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: StatusActive},
+	}}
+	stored := func(mockOps *MockCloudStorageOperations) []byte {
+		return mockOps.objects["test-bucket/"+stateChannelObject("UCXuqSBlHAE6Xw-yeJA0Tunw")]
+	}
+
+	t.Run("gzip_by_default", func(t *testing.T) {
+		mockOps := NewMockCloudStorageOperations()
+		service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+		require.NoError(t, service.SaveSubscriptionState(ctx, state))
+		assert.True(t, isGzipData(stored(mockOps)))
+		assert.True(t, isGzipData(mockOps.objects["test-bucket/"+stateIndexObject]))
+
+		// A fresh instance reads the compressed object
+		loaded, err := NewCloudStorageServiceWithOperations(mockOps, "test-bucket").LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, loaded.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	})
+
+	t.Run("uncompressed", func(t *testing.T) {
+		t.Setenv("STATE_COMPRESSION", StateCompressionNone)
+		mockOps := NewMockCloudStorageOperations()
+		service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+		require.NoError(t, service.SaveSubscriptionState(ctx, state))
+		assert.True(t, json.Valid(stored(mockOps)))
+	})
+
+	t.Run("reads_either_encoding", func(t *testing.T) {
+		mockOps := NewMockCloudStorageOperations()
+		plain := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+		plain.compression = StateCompressionNone
+		require.NoError(t, plain.SaveSubscriptionState(ctx, state))
+
+		loaded, err := NewCloudStorageServiceWithOperations(mockOps, "test-bucket").LoadSubscriptionState(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, loaded.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+	})
+
+	t.Run("corrupt_object", func(t *testing.T) {
+		mockOps := NewMockCloudStorageOperations()
+		require.NoError(t, mockOps.PutObject(ctx, "test-bucket", stateIndexObject, []byte{0x1f, 0x8b, 0x00}))
+		_, err := NewCloudStorageServiceWithOperations(mockOps, "test-bucket").LoadSubscriptionState(ctx)
+		assert.ErrorContains(t, err, "failed to decompress state")
+	})
+}
+
+func TestGetStateCompression(t *testing.T) {
+	assert.Equal(t, StateCompressionGzip, getStateCompression())
+
+	t.Setenv("STATE_COMPRESSION", StateCompressionNone)
+	assert.Equal(t, StateCompressionNone, getStateCompression())
+
+	t.Setenv("STATE_COMPRESSION", "zstd")
+	assert.Equal(t, StateCompressionGzip, getStateCompression())
+}
+
+// benchmarkState returns a state holding the given number of subscriptions, each with
+// a repository route and a secret, roughly as large as production entries
+func benchmarkState(subscriptions int) *SubscriptionState {
+	state := &SubscriptionState{Subscriptions: make(map[string]*Subscription, subscriptions)}
+	state.Metadata.Version = CurrentSchemaVersion
+	now := time.Now()
+	for i := 0; i < subscriptions; i++ {
+		channelID := fmt.Sprintf("UC%022d", i)
+		state.Subscriptions[channelID] = &Subscription{
+			ChannelID:    channelID,
+			ChannelName:  fmt.Sprintf("Channel %d", i),
+			TopicURL:     "https://www.youtube.com/feeds/videos.xml?channel_id=" + channelID,
+			CallbackURL:  "https://example.cloudfunctions.net/YouTubeWebhook",
+			Status:       StatusActive,
+			LeaseSeconds: 432000,
+			ExpiresAt:    now.Add(time.Duration(i) * time.Minute),
+			Secret:       fmt.Sprintf("%064x", i),
+			Repositories: []RepositoryTarget{{RepoOwner: "octo", RepoName: fmt.Sprintf("site-%d", i%10)}},
+		}
+	}
+	return state
+}
+
+// BenchmarkCloudStorageService_Load measures reading and decoding the index and every
+// channel, bypassing the cache. Run with -benchmem; object-bytes is what would be
+// transferred.
+func BenchmarkCloudStorageService_Load(b *testing.B) {
+	for _, subscriptions := range []int{100, 5000} {
+		for _, compression := range []string{StateCompressionNone, StateCompressionGzip} {
+			b.Run(fmt.Sprintf("%d/%s", subscriptions, compression), func(b *testing.B) {
+				ctx := context.Background()
+				mockOps := NewMockCloudStorageOperations()
+				service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+				service.compression = compression
+				require.NoError(b, service.SaveSubscriptionState(ctx, benchmarkState(subscriptions)))
+				service.cacheTTL = 0
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := service.LoadSubscriptionState(ctx); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(mockOps.storedBytes("test-bucket")), "object-bytes")
+			})
+		}
+	}
+}
+
+// BenchmarkCloudStorageService_Save measures saving a state in which one subscription
+// changed, writing that channel and the index
+func BenchmarkCloudStorageService_Save(b *testing.B) {
+	b.Setenv("STATE_BACKUP_COUNT", "0")
+	for _, subscriptions := range []int{100, 5000} {
+		for _, compression := range []string{StateCompressionNone, StateCompressionGzip} {
+			b.Run(fmt.Sprintf("%d/%s", subscriptions, compression), func(b *testing.B) {
+				ctx := context.Background()
+				mockOps := NewMockCloudStorageOperations()
+				service := NewCloudStorageServiceWithOperations(mockOps, "test-bucket")
+				service.compression = compression
+				state := benchmarkState(subscriptions)
+				require.NoError(b, service.SaveSubscriptionState(ctx, state))
+				changed := state.Subscriptions[fmt.Sprintf("UC%022d", 0)]
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					changed.LastRenewal = time.Unix(int64(i), 0)
+					if err := service.SaveSubscriptionState(ctx, state); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(mockOps.storedBytes("test-bucket")), "object-bytes")
+			})
+		}
+	}
+}
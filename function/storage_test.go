@@ -22,8 +22,8 @@ func TestLoadSubscriptionState_MissingBucket(t *testing.T) {
 		}
 	}()
 
-	// Test CloudStorageClient directly (not our mock)
-	client := &CloudStorageClient{}
+	// Test CloudStorageService directly (not our mock)
+	client := NewCloudStorageService()
 	ctx := context.Background()
 	_, err := client.LoadSubscriptionState(ctx)
 
@@ -48,8 +48,8 @@ func TestSaveSubscriptionState_MissingBucket(t *testing.T) {
 		Subscriptions: make(map[string]*Subscription),
 	}
 
-	// Test CloudStorageClient directly
-	client := &CloudStorageClient{}
+	// Test CloudStorageService directly
+	client := NewCloudStorageService()
 	ctx := context.Background()
 	err := client.SaveSubscriptionState(ctx, state)
 
@@ -67,7 +67,7 @@ func TestCloudStorage_ComprehensiveErrorCoverage(t *testing.T) {
 		os.Setenv("SUBSCRIPTION_BUCKET", "invalid-bucket-name-with-special-chars@#$")
 		defer os.Unsetenv("SUBSCRIPTION_BUCKET")
 
-		client := &CloudStorageClient{}
+		client := NewCloudStorageService()
 		_, err := client.LoadSubscriptionState(ctx)
 		// This might succeed or fail depending on GCP configuration, but we're exercising the code path
 		if err != nil {
@@ -92,7 +92,7 @@ func TestCloudStorage_ComprehensiveErrorCoverage(t *testing.T) {
 			Subscriptions: make(map[string]*Subscription),
 		}
 
-		client := &CloudStorageClient{}
+		client := NewCloudStorageService()
 		err := client.SaveSubscriptionState(ctx, state)
 		// This might succeed or fail depending on GCP configuration, but we're exercising the code path
 		if err != nil {
@@ -114,7 +114,7 @@ func TestSaveSubscriptionState_CloudStorageEdgeCases(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("test_metadata_version_setting", func(t *testing.T) {
-		// Test that metadata version gets set to "1.0" when empty
+		// Test that metadata version gets set to the current schema version when empty
 		state := &SubscriptionState{
 			Subscriptions: make(map[string]*Subscription),
 		}
@@ -132,7 +132,7 @@ func TestSaveSubscriptionState_CloudStorageEdgeCases(t *testing.T) {
 		}()
 
 		// This will likely fail due to no GCS credentials, but we're testing the version setting logic
-		client := &CloudStorageClient{}
+		client := NewCloudStorageService()
 		err := client.SaveSubscriptionState(ctx, state)
 		// The important thing is that the version was set during the call
 		// Error is expected due to no real GCS setup or CI environment limitations
@@ -163,7 +163,7 @@ func TestLoadSubscriptionState_EdgeCases(t *testing.T) {
 		}()
 
 		// This will likely fail due to no GCS credentials
-		client := &CloudStorageClient{}
+		client := NewCloudStorageService()
 		_, err := client.LoadSubscriptionState(ctx)
 		// Error is expected due to no real GCS setup or CI environment limitations
 		if err != nil {
@@ -188,7 +188,7 @@ func TestMockStorageClient(t *testing.T) {
 		require.NoError(t, err)
 		assert.NotNil(t, state)
 		assert.NotNil(t, state.Subscriptions)
-		assert.Equal(t, "1.0", state.Metadata.Version)
+		assert.Equal(t, CurrentSchemaVersion, state.Metadata.Version)
 		assert.False(t, state.Metadata.LastUpdated.IsZero())
 	})
 
@@ -241,7 +241,7 @@ func TestMockStorageClient(t *testing.T) {
 		// Verify state was saved
 		savedState := mockClient.GetState()
 		assert.Contains(t, savedState.Subscriptions, channelID)
-		assert.Equal(t, "1.0", savedState.Metadata.Version)
+		assert.Equal(t, CurrentSchemaVersion, savedState.Metadata.Version)
 		assert.False(t, savedState.Metadata.LastUpdated.IsZero())
 	})
 
@@ -283,6 +283,34 @@ func TestMockStorageClient(t *testing.T) {
 		assert.Equal(t, 1, mockClient.SaveCallCount)
 	})
 
+	t.Run("PerSubscriptionOperations", func(t *testing.T) {
+		mockClient := NewMockStorageClient()
+
+		_, err := mockClient.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		assert.Equal(t, ErrSubscriptionNotFound, err)
+
+		require.NoError(t, mockClient.SaveSubscription(ctx, &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "active"}))
+		sub, err := mockClient.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		require.NoError(t, err)
+		assert.Equal(t, "active", sub.Status)
+
+		// Returned subscriptions are copies
+		sub.Status = "modified"
+		assert.Equal(t, "active", mockClient.GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"].Status)
+
+		require.NoError(t, mockClient.DeleteSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw"))
+		assert.Empty(t, mockClient.GetState().Subscriptions)
+		assert.Equal(t, 2, mockClient.LoadCallCount)
+		assert.Equal(t, 2, mockClient.SaveCallCount)
+
+		mockClient.LoadError = ErrMockLoadFailure
+		mockClient.SaveError = ErrMockSaveFailure
+		_, err = mockClient.GetSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		assert.Equal(t, ErrMockLoadFailure, err)
+		assert.Equal(t, ErrMockSaveFailure, mockClient.SaveSubscription(ctx, &Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}))
+		assert.Equal(t, ErrMockSaveFailure, mockClient.DeleteSubscription(ctx, "UCXuqSBlHAE6Xw-yeJA0Tunw"))
+	})
+
 	t.Run("Reset", func(t *testing.T) {
 		mockClient := NewMockStorageClient()
 
@@ -324,7 +352,7 @@ func TestSaveSubscriptionStateValidation(t *testing.T) {
 	savedState := mockClient.GetState()
 	assert.NotNil(t, savedState)
 	assert.NotNil(t, savedState.Subscriptions) // Should be initialized
-	assert.Equal(t, "1.0", savedState.Metadata.Version)
+	assert.Equal(t, CurrentSchemaVersion, savedState.Metadata.Version)
 }
 
 // TestMockStorageClient_Close tests the Close method that was not covered
@@ -29,7 +29,7 @@ func TestGetSubscriptions_Success(t *testing.T) {
 			"UCXuqSBlHAE6Xw-yeJA0Tunw": {
 				ChannelID:    "UCXuqSBlHAE6Xw-yeJA0Tunw",
 				Status:       "active",
-				ExpiresAt:    now.Add(12 * time.Hour), // Active, expires in 12 hours
+				ExpiresAt:    now.Add(12 * time.Hour), // Expiring, within the 12 hour renewal threshold
 				SubscribedAt: now.Add(-12 * time.Hour),
 			},
 			"UCBJycsmduvYEL83R_U4JriQ": {
@@ -89,6 +89,9 @@ func TestGetSubscriptions_Success(t *testing.T) {
 	assert.Contains(t, sub1, "status", "Should include status")
 	assert.Contains(t, sub1, "expires_at", "Should include expires_at")
 	assert.Contains(t, sub1, "days_until_expiry", "Should include days_until_expiry")
+	assert.Contains(t, sub1, "lease_seconds", "Should include lease_seconds")
+	assert.Contains(t, sub1, "renewal_attempts", "Should include renewal_attempts")
+	assert.Contains(t, sub1, "subscribed_at", "Should include subscribed_at")
 
 	// Verify status values
 	statusValues := make(map[string]int)
@@ -97,7 +100,8 @@ func TestGetSubscriptions_Success(t *testing.T) {
 		status := subMap["status"].(string)
 		statusValues[status]++
 	}
-	assert.Equal(t, 2, statusValues["active"], "Should have 2 active subscriptions")
+	assert.Equal(t, 1, statusValues["active"], "Should have 1 active subscription")
+	assert.Equal(t, 1, statusValues["expiring"], "Should have 1 expiring subscription")
 	assert.Equal(t, 1, statusValues["expired"], "Should have 1 expired subscription")
 
 	// Verify channel IDs are present
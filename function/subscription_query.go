@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Page size limits for GET /subscriptions
+const maxSubscriptionsPageSize = 500
+
+// Sort orders accepted by GET /subscriptions; prefix with "-" for descending
+const (
+	SortByChannel = "channel"
+	SortByExpiry  = "expiry"
+)
+
+// subscriptionsQuery holds the filter, sort and pagination parameters of GET /subscriptions
+type subscriptionsQuery struct {
+	Status     string // Lifecycle status to keep; "active" also keeps verified and expiring
+	SortBy     string
+	Descending bool
+	Limit      int // 0 returns every remaining subscription
+	Offset     int
+}
+
+// parseSubscriptionsQuery reads and validates the GET /subscriptions query parameters
+func parseSubscriptionsQuery(values url.Values) (subscriptionsQuery, error) {
+	query := subscriptionsQuery{SortBy: SortByChannel}
+
+	switch status := values.Get("status"); status {
+	case "", StatusPending, StatusVerified, StatusActive, StatusExpiring, StatusExpired, StatusQuarantined:
+		query.Status = status
+	default:
+		return query, fmt.Errorf("invalid status %q (available: pending, verified, active, expiring, expired, quarantined)", status)
+	}
+
+	if sortBy := values.Get("sort"); sortBy != "" {
+		query.Descending = strings.HasPrefix(sortBy, "-")
+		query.SortBy = strings.TrimPrefix(sortBy, "-")
+		if query.SortBy != SortByChannel && query.SortBy != SortByExpiry {
+			return query, fmt.Errorf("invalid sort %q (available: channel, expiry)", sortBy)
+		}
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxSubscriptionsPageSize {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxSubscriptionsPageSize)
+		}
+		query.Limit = n
+	}
+
+	if token := values.Get("page_token"); token != "" {
+		offset, err := decodePageToken(token)
+		if err != nil {
+			return query, err
+		}
+		query.Offset = offset
+	}
+	return query, nil
+}
+
+// Matches reports whether a subscription with the given reported status passes the status filter
+func (q subscriptionsQuery) Matches(status string) bool {
+	switch q.Status {
+	case "":
+		return true
+	case StatusActive:
+		return status == StatusActive || status == StatusVerified || status == StatusExpiring
+	}
+	return status == q.Status
+}
+
+// Sort orders subscriptions by the requested key. Subscriptions without an expiry
+// sort after those with one; ties are broken by channel ID so pages are stable.
+func (q subscriptionsQuery) Sort(subscriptions []SubscriptionInfo) {
+	sort.SliceStable(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if q.SortBy == SortByExpiry && a.ExpiresAt != b.ExpiresAt {
+			if a.ExpiresAt == "" || b.ExpiresAt == "" {
+				return b.ExpiresAt == ""
+			}
+			if q.Descending {
+				return a.DaysUntilExpiry > b.DaysUntilExpiry
+			}
+			return a.DaysUntilExpiry < b.DaysUntilExpiry
+		}
+		if q.Descending && q.SortBy == SortByChannel {
+			return a.ChannelID > b.ChannelID
+		}
+		return a.ChannelID < b.ChannelID
+	})
+}
+
+// Page returns the requested page of sorted subscriptions and the token for the next
+// page, or "" when this is the last page
+func (q subscriptionsQuery) Page(subscriptions []SubscriptionInfo) ([]SubscriptionInfo, string) {
+	if q.Offset >= len(subscriptions) {
+		return []SubscriptionInfo{}, ""
+	}
+	page := subscriptions[q.Offset:]
+	if q.Limit == 0 || len(page) <= q.Limit {
+		return page, ""
+	}
+	return page[:q.Limit], encodePageToken(q.Offset + q.Limit)
+}
+
+// encodePageToken returns the opaque token for the page starting at offset
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// decodePageToken returns the offset encoded in a page token
+func decodePageToken(token string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	value, ok := strings.CutPrefix(string(data), "offset:")
+	if err != nil || !ok {
+		return 0, fmt.Errorf("invalid page_token")
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid page_token")
+	}
+	return offset, nil
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedListSubscriptions stores five subscriptions: two active, one expiring soon,
+// one pending and one expired
+func seedListSubscriptions(t *testing.T, deps *Dependencies) {
+	t.Helper()
+	now := time.Now()
+	state := &SubscriptionState{Subscriptions: map[string]*Subscription{
+		"UCaaaaaaaaaaaaaaaaaaaaaa": {ChannelID: "UCaaaaaaaaaaaaaaaaaaaaaa", Status: StatusActive, ExpiresAt: now.Add(96 * time.Hour)},
+		"UCbbbbbbbbbbbbbbbbbbbbbb": {ChannelID: "UCbbbbbbbbbbbbbbbbbbbbbb", Status: StatusActive, ExpiresAt: now.Add(48 * time.Hour)},
+		"UCcccccccccccccccccccccc": {ChannelID: "UCcccccccccccccccccccccc", Status: StatusActive, ExpiresAt: now.Add(time.Hour)},
+		"UCdddddddddddddddddddddd": {ChannelID: "UCdddddddddddddddddddddd", Status: StatusPending},
+		"UCeeeeeeeeeeeeeeeeeeeeee": {ChannelID: "UCeeeeeeeeeeeeeeeeeeeeee", Status: StatusActive, ExpiresAt: now.Add(-time.Hour)},
+	}}
+	require.NoError(t, deps.StorageClient.SaveSubscriptionState(t.Context(), state))
+}
+
+func listChannelIDs(response SubscriptionsListResponse) []string {
+	ids := make([]string, len(response.Subscriptions))
+	for i, sub := range response.Subscriptions {
+		ids[i] = sub.ChannelID[:3]
+	}
+	return ids
+}
+
+func TestGetSubscriptions_Query(t *testing.T) {
+	deps := CreateTestDependencies()
+	seedListSubscriptions(t, deps)
+
+	list := func(query string) (*httptest.ResponseRecorder, SubscriptionsListResponse) {
+		rec := httptest.NewRecorder()
+		handleGetSubscriptions(deps)(rec, httptest.NewRequest("GET", "/subscriptions?"+query, nil))
+
+		var response SubscriptionsListResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		}
+		return rec, response
+	}
+
+	t.Run("default_sorted_by_channel", func(t *testing.T) {
+		_, response := list("")
+		assert.Equal(t, []string{"UCa", "UCb", "UCc", "UCd", "UCe"}, listChannelIDs(response))
+		assert.Empty(t, response.NextPageToken)
+	})
+
+	t.Run("status_filter", func(t *testing.T) {
+		_, response := list("status=active")
+		// Active includes subscriptions that are expiring soon
+		assert.Equal(t, []string{"UCa", "UCb", "UCc"}, listChannelIDs(response))
+		assert.Equal(t, 5, response.Total, "summary counts cover every subscription")
+
+		_, response = list("status=expiring")
+		assert.Equal(t, []string{"UCc"}, listChannelIDs(response))
+
+		_, response = list("status=expired")
+		assert.Equal(t, []string{"UCe"}, listChannelIDs(response))
+
+		_, response = list("status=pending")
+		assert.Equal(t, []string{"UCd"}, listChannelIDs(response))
+	})
+
+	t.Run("sort_by_expiry", func(t *testing.T) {
+		_, response := list("sort=expiry")
+		assert.Equal(t, []string{"UCe", "UCc", "UCb", "UCa", "UCd"}, listChannelIDs(response))
+
+		_, response = list("sort=-expiry")
+		assert.Equal(t, []string{"UCa", "UCb", "UCc", "UCe", "UCd"}, listChannelIDs(response))
+
+		_, response = list("sort=-channel")
+		assert.Equal(t, []string{"UCe", "UCd", "UCc", "UCb", "UCa"}, listChannelIDs(response))
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		var pages [][]string
+		token := ""
+		for {
+			_, response := list("limit=2&page_token=" + url.QueryEscape(token))
+			pages = append(pages, listChannelIDs(response))
+			if response.NextPageToken == "" {
+				break
+			}
+			token = response.NextPageToken
+		}
+		assert.Equal(t, [][]string{{"UCa", "UCb"}, {"UCc", "UCd"}, {"UCe"}}, pages)
+	})
+
+	t.Run("filtered_pagination", func(t *testing.T) {
+		_, response := list("status=active&sort=expiry&limit=2")
+		assert.Equal(t, []string{"UCc", "UCb"}, listChannelIDs(response))
+		require.NotEmpty(t, response.NextPageToken)
+
+		_, response = list("status=active&sort=expiry&limit=2&page_token=" + response.NextPageToken)
+		assert.Equal(t, []string{"UCa"}, listChannelIDs(response))
+		assert.Empty(t, response.NextPageToken)
+	})
+
+	t.Run("invalid_parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"status=bogus",
+			"sort=name",
+			"limit=0",
+			fmt.Sprintf("limit=%d", maxSubscriptionsPageSize+1),
+			"limit=ten",
+			"page_token=not-a-token",
+		} {
+			rec, _ := list(query)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+	})
+}
+
+func TestPageToken_RoundTrip(t *testing.T) {
+	offset, err := decodePageToken(encodePageToken(42))
+	require.NoError(t, err)
+	assert.Equal(t, 42, offset)
+
+	_, err = decodePageToken(encodePageToken(-1))
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -273,21 +274,21 @@ func TestMockPubSubClient_UncoveredMethods(t *testing.T) {
 	assert.Equal(t, "", mock.GetLastMode())
 	
 	// Test Subscribe tracking
-	err := mock.Subscribe("UCTestChannel1")
+	err := mock.Subscribe(context.Background(), "UCTestChannel1", "", 0)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, mock.GetSubscribeCount())
 	assert.Equal(t, "UCTestChannel1", mock.GetLastChannelID())
 	assert.Equal(t, "subscribe", mock.GetLastMode())
 	
 	// Test another Subscribe
-	err = mock.Subscribe("UCTestChannel2")
+	err = mock.Subscribe(context.Background(), "UCTestChannel2", "", 0)
 	assert.NoError(t, err)
 	assert.Equal(t, 2, mock.GetSubscribeCount())
 	assert.Equal(t, "UCTestChannel2", mock.GetLastChannelID())
 	assert.Equal(t, "subscribe", mock.GetLastMode())
 	
 	// Test Unsubscribe tracking
-	err = mock.Unsubscribe("UCTestChannel1")
+	err = mock.Unsubscribe(context.Background(), "UCTestChannel1")
 	assert.NoError(t, err)
 	assert.Equal(t, 1, mock.GetUnsubscribeCount())
 	assert.Equal(t, "UCTestChannel1", mock.GetLastChannelID())
@@ -295,7 +296,7 @@ func TestMockPubSubClient_UncoveredMethods(t *testing.T) {
 	
 	// Test Unsubscribe with error
 	mock.SetUnsubscribeError(fmt.Errorf("unsubscribe failed"))
-	err = mock.Unsubscribe("UCTestChannel3")
+	err = mock.Unsubscribe(context.Background(), "UCTestChannel3")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unsubscribe failed")
 	assert.Equal(t, 2, mock.GetUnsubscribeCount()) // Should still increment even on error
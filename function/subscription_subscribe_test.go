@@ -45,13 +45,8 @@ func TestSubscribeToChannel_Success(t *testing.T) {
 
 	assert.Equal(t, "success", response.Status, "Status should be 'success'")
 	assert.Equal(t, channelID, response.ChannelID, "Should return the channel ID")
-	assert.Equal(t, "Subscription initiated", response.Message, "Should have correct message")
-	assert.NotEmpty(t, response.ExpiresAt, "Should include expiration time")
-
-	// Verify expiration time is in the future
-	expiresAt, err := time.Parse(time.RFC3339, response.ExpiresAt)
-	require.NoError(t, err, "ExpiresAt should be valid RFC3339")
-	assert.True(t, expiresAt.After(time.Now()), "Expiration should be in the future")
+	assert.Equal(t, "Subscription initiated; pending hub verification", response.Message, "Should have correct message")
+	assert.Empty(t, response.ExpiresAt, "Expiration is unknown until the hub verifies")
 
 	// Verify subscription was stored using dependency injection
 	assert.Equal(t, 1, deps.StorageClient.(*MockStorageClient).LoadCallCount, "Should load state once")
@@ -63,8 +58,9 @@ func TestSubscribeToChannel_Success(t *testing.T) {
 	assert.Contains(t, savedState.Subscriptions, channelID, "Should store subscription")
 
 	sub := savedState.Subscriptions[channelID]
-	assert.Equal(t, "active", sub.Status, "Subscription should be active")
-	assert.Equal(t, 86400, sub.LeaseSeconds, "Lease should be 24 hours")
+	assert.Equal(t, StatusPending, sub.Status, "Subscription should wait for hub verification")
+	assert.Zero(t, sub.LeaseSeconds, "Lease is set by hub verification")
+	assert.True(t, sub.ExpiresAt.IsZero(), "Expiry is set by hub verification")
 	assert.NotZero(t, sub.SubscribedAt, "Should set subscription time")
 }
 
@@ -103,7 +99,8 @@ func TestSubscribeToChannel_AlreadySubscribed(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Response should be valid JSON")
 
-	assert.Equal(t, "conflict", response.Status, "Status should be 'conflict'")
+	assert.Equal(t, "error", response.Status, "Conflicts use the error envelope")
+	assert.Equal(t, ErrCodeConflict, response.Code, "Code should be 'conflict'")
 	assert.Equal(t, channelID, response.ChannelID, "Should return the channel ID")
 	assert.Equal(t, "Already subscribed to this channel", response.Message)
 	assert.NotEmpty(t, response.ExpiresAt, "Should include existing expiration time")
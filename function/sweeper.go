@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sweep actions recorded for each lapsed subscription
+const (
+	SweepActionResubscribed = "resubscribed"
+	SweepActionExpired      = "expired"
+	SweepActionPurged       = "purged"
+)
+
+// SweepSummaryResponse summarises a single sweep of lapsed subscriptions
+type SweepSummaryResponse struct {
+	Status       string        `json:"status"`
+	TotalChecked int           `json:"total_checked"`
+	Lapsed       int           `json:"lapsed"`
+	Resubscribed int           `json:"resubscribed"`
+	Expired      int           `json:"expired"`
+	Purged       int           `json:"purged"`
+	Results      []SweepResult `json:"results"`
+
+	// Re-subscribes of subscriptions the hub stopped delivering, made before the sweep
+	Recoveries []RecoveryResult `json:"recoveries,omitempty"`
+}
+
+// SweepResult describes what the sweeper did with one lapsed subscription
+type SweepResult struct {
+	ChannelID string `json:"channel_id"`
+	Action    string `json:"action"`
+	Message   string `json:"message"`
+}
+
+// handleSweepSubscriptions handles POST /sweep requests using dependency injection.
+// Unlike /renew, which only extends leases that have not yet expired, the sweeper
+// deals with subscriptions whose lease has already lapsed. Subscriptions the hub stopped
+// delivering are first re-subscribed with backoff (see recoverSubscriptions) and are left
+// to the recovery until it ends.
+func handleSweepSubscriptions(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		state, err := deps.StorageClient.LoadSubscriptionState(ctx)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "",
+				fmt.Sprintf("Failed to load subscription state: %v", err))
+			return
+		}
+
+		now := deps.now()
+		gracePeriod := deps.config().SweepGracePeriod
+		retention := deps.config().SweepRetention
+
+		response := SweepSummaryResponse{
+			Status:       "success",
+			TotalChecked: len(state.Subscriptions),
+			Results:      []SweepResult{},
+			Recoveries:   recoverSubscriptions(ctx, deps, state, now),
+		}
+
+		for channelID, subscription := range state.Subscriptions {
+			if recovering(subscription) {
+				continue
+			}
+			if subscription.Status == StatusPending {
+				if !verificationOverdue(subscription, now) {
+					continue
+				}
+			} else if !subscription.ExpiresAt.Before(now) {
+				continue
+			}
+			response.Lapsed++
+
+			result := sweepSubscription(ctx, channelID, subscription, state, deps, now, gracePeriod, retention)
+			switch result.Action {
+			case "":
+				continue
+			case SweepActionResubscribed:
+				response.Resubscribed++
+			case SweepActionExpired:
+				response.Expired++
+			case SweepActionPurged:
+				response.Purged++
+			}
+			response.Results = append(response.Results, result)
+		}
+
+		// Removed subscriptions past REMOVED_RETENTION_DAYS can no longer be restored
+		for _, channelID := range purgeRemoved(state, now, deps.config().RemovedRetention) {
+			response.Purged++
+			response.Results = append(response.Results, SweepResult{
+				ChannelID: channelID,
+				Action:    SweepActionPurged,
+				Message:   "Unsubscribed channel past its restore retention",
+			})
+		}
+
+		// Save updated state if anything was touched
+		if len(response.Results) > 0 || len(response.Recoveries) > 0 {
+			if err := deps.StorageClient.SaveSubscriptionState(ctx, state); err != nil {
+				writeErrorResponse(w, http.StatusInternalServerError, "",
+					fmt.Sprintf("Failed to save subscription state: %v", err))
+				return
+			}
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+// sweepSubscription decides the fate of a single lapsed subscription.
+//
+// Pending subscriptions the hub never verified are marked expired. Subscriptions lapsed
+// for longer than the retention period are purged. Subscriptions seen lapsed for the
+// first time within the grace period get one final re-subscribe attempt; everything else
+// is marked expired so it is not retried on the next sweep. Subscriptions already expired
+// or quarantined are left as they are, returning a result without an action.
+func sweepSubscription(ctx context.Context, channelID string, subscription *Subscription, state *SubscriptionState,
+	deps *Dependencies, now time.Time, gracePeriod, retention time.Duration) SweepResult {
+	if subscription.Status == StatusPending {
+		subscription.Status = StatusExpired
+		if subscription.ExpiresAt.IsZero() {
+			// The lease never started; count retention from now
+			subscription.ExpiresAt = now
+		}
+		return SweepResult{
+			ChannelID: channelID,
+			Action:    SweepActionExpired,
+			Message:   fmt.Sprintf("Hub did not verify the subscription within %s", verificationTimeout),
+		}
+	}
+
+	lapsedFor := now.Sub(subscription.ExpiresAt)
+
+	if lapsedFor > retention {
+		delete(state.Subscriptions, channelID)
+		return SweepResult{
+			ChannelID: channelID,
+			Action:    SweepActionPurged,
+			Message:   fmt.Sprintf("Lapsed for %s, past retention of %s", lapsedFor.Round(time.Minute), retention),
+		}
+	}
+
+	if subscription.Status != StatusExpired && subscription.Status != StatusQuarantined && lapsedFor <= gracePeriod {
+		err := resubscribe(ctx, deps, channelID, subscription)
+		deps.Metrics.RecordRenewal("sweep", err)
+		recordRenewalOutcome(subscription, err)
+		if err != nil {
+			subscription.Status = StatusExpired
+			return SweepResult{
+				ChannelID: channelID,
+				Action:    SweepActionExpired,
+				Message:   fmt.Sprintf("Final re-subscribe failed: %v", err),
+			}
+		}
+
+		// The new lease is recorded when the hub verifies the re-subscribe
+		markPending(subscription, now)
+		subscription.RenewalAttempts = 0
+		return SweepResult{
+			ChannelID: channelID,
+			Action:    SweepActionResubscribed,
+			Message:   "Final re-subscribe requested; pending hub verification",
+		}
+	}
+
+	// Expired subscriptions wait to be purged; quarantined ones stay quarantined until reactivated
+	if subscription.Status == StatusExpired || subscription.Status == StatusQuarantined {
+		return SweepResult{ChannelID: channelID}
+	}
+
+	subscription.Status = StatusExpired
+	return SweepResult{
+		ChannelID: channelID,
+		Action:    SweepActionExpired,
+		Message:   "Lease lapsed",
+	}
+}
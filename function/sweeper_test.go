@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSweepTestState(subs ...*Subscription) *SubscriptionState {
+	state := &SubscriptionState{
+		Subscriptions: make(map[string]*Subscription),
+	}
+	for _, sub := range subs {
+		state.Subscriptions[sub.ChannelID] = sub
+	}
+	state.Metadata.LastUpdated = time.Now()
+	state.Metadata.Version = "1.0"
+	return state
+}
+
+func runSweep(t *testing.T, deps *Dependencies) SweepSummaryResponse {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/sweep", nil)
+	w := httptest.NewRecorder()
+
+	handler := handleSweepSubscriptions(deps)
+	handler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response SweepSummaryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestHandleSweepSubscriptions(t *testing.T) {
+	os.Setenv("SWEEP_GRACE_HOURS", "24")
+	os.Setenv("SWEEP_RETENTION_DAYS", "7")
+	// Lapsed subscriptions are covered by recovery_test.go when recovery is enabled
+	t.Setenv("RECOVERY_MAX_ATTEMPTS", "0")
+	defer func() {
+		os.Unsetenv("SWEEP_GRACE_HOURS")
+		os.Unsetenv("SWEEP_RETENTION_DAYS")
+	}()
+
+	t.Run("ignores_unexpired_subscriptions", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:    "active",
+			ExpiresAt: time.Now().Add(2 * time.Hour),
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 1, response.TotalChecked)
+		assert.Equal(t, 0, response.Lapsed)
+		assert.Empty(t, response.Results)
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+		assert.Equal(t, 0, deps.StorageClient.(*MockStorageClient).SaveCallCount)
+	})
+
+	t.Run("resubscribes_within_grace_window", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID:       "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:          "active",
+			ExpiresAt:       time.Now().Add(-2 * time.Hour),
+			RenewalAttempts: 3,
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 1, response.Lapsed)
+		assert.Equal(t, 1, response.Resubscribed)
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, SweepActionResubscribed, response.Results[0].Action)
+
+		saved := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"]
+		assert.Equal(t, StatusPending, saved.Status)
+		assert.Equal(t, 0, saved.RenewalAttempts)
+		assert.True(t, saved.ExpiresAt.Before(time.Now()), "New lease is recorded when the hub verifies")
+	})
+
+	t.Run("marks_expired_when_final_resubscribe_fails", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.PubSubClient.(*MockPubSubClient).SetSubscribeError(assert.AnError)
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:    "active",
+			ExpiresAt: time.Now().Add(-2 * time.Hour),
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 1, response.Expired)
+		require.Len(t, response.Results, 1)
+		assert.Contains(t, response.Results[0].Message, "Final re-subscribe failed")
+
+		saved := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"]
+		assert.Equal(t, "expired", saved.Status)
+	})
+
+	t.Run("does_not_retry_already_expired_subscriptions", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:    "expired",
+			ExpiresAt: time.Now().Add(-2 * time.Hour),
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 1, response.Lapsed)
+		assert.Equal(t, 0, response.Expired, "only status changes are counted")
+		assert.Empty(t, response.Results)
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+		assert.Equal(t, 0, deps.StorageClient.(*MockStorageClient).SaveCallCount)
+	})
+
+	t.Run("leaves_lapsed_quarantined_subscriptions", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:    StatusQuarantined,
+			ExpiresAt: time.Now().Add(-48 * time.Hour),
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 1, response.Lapsed)
+		assert.Equal(t, 0, response.Expired)
+		assert.Empty(t, response.Results)
+		assert.Equal(t, StatusQuarantined, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"].Status)
+	})
+
+	t.Run("marks_expired_past_grace_window", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:    "active",
+			ExpiresAt: time.Now().Add(-48 * time.Hour),
+		}))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 1, response.Expired)
+		assert.Equal(t, 0, deps.PubSubClient.(*MockPubSubClient).GetSubscribeCount())
+	})
+
+	t.Run("purges_past_retention", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(
+			&Subscription{
+				ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+				Status:    "expired",
+				ExpiresAt: time.Now().Add(-8 * 24 * time.Hour),
+			},
+			&Subscription{
+				ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw",
+				Status:    "active",
+				ExpiresAt: time.Now().Add(10 * time.Hour),
+			},
+		))
+
+		response := runSweep(t, deps)
+
+		assert.Equal(t, 2, response.TotalChecked)
+		assert.Equal(t, 1, response.Purged)
+
+		saved := deps.StorageClient.(*MockStorageClient).GetState()
+		assert.NotContains(t, saved.Subscriptions, "UCXuqSBlHAE6Xw-yeJA0Tunw")
+		assert.Contains(t, saved.Subscriptions, "UC_x5XG1OV2P6uZZ5FSM9Ttw")
+	})
+
+	t.Run("storage_load_error", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).LoadError = ErrMockLoadFailure
+
+		req := httptest.NewRequest("POST", "/sweep", nil)
+		w := httptest.NewRecorder()
+		handleSweepSubscriptions(deps)(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("storage_save_error", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		mockStorage := deps.StorageClient.(*MockStorageClient)
+		mockStorage.SetState(newSweepTestState(&Subscription{
+			ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			Status:    "active",
+			ExpiresAt: time.Now().Add(-48 * time.Hour),
+		}))
+		mockStorage.SaveError = ErrMockSaveFailure
+
+		req := httptest.NewRequest("POST", "/sweep", nil)
+		w := httptest.NewRecorder()
+		handleSweepSubscriptions(deps)(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestYouTubeWebhook_Sweep(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	req := httptest.NewRequest("POST", "/sweep", nil)
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "total_checked")
+}
+
+func TestSweepConfiguration(t *testing.T) {
+	os.Unsetenv("SWEEP_GRACE_HOURS")
+	os.Unsetenv("SWEEP_RETENTION_DAYS")
+	assert.Equal(t, 24*time.Hour, getSweepGracePeriod())
+	assert.Equal(t, 7*24*time.Hour, getSweepRetention())
+
+	os.Setenv("SWEEP_GRACE_HOURS", "6")
+	os.Setenv("SWEEP_RETENTION_DAYS", "30")
+	assert.Equal(t, 6*time.Hour, getSweepGracePeriod())
+	assert.Equal(t, 30*24*time.Hour, getSweepRetention())
+
+	os.Setenv("SWEEP_GRACE_HOURS", "invalid")
+	os.Setenv("SWEEP_RETENTION_DAYS", "-1")
+	assert.Equal(t, 24*time.Hour, getSweepGracePeriod())
+	assert.Equal(t, 7*24*time.Hour, getSweepRetention())
+
+	os.Unsetenv("SWEEP_GRACE_HOURS")
+	os.Unsetenv("SWEEP_RETENTION_DAYS")
+}
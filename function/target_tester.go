@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TestEventType is the event type used for test-fire deliveries so receivers
+// can tell them apart from real video notifications
+const TestEventType = "youtube-webhook-test"
+
+// Supported notification target types
+const (
+	TargetTypeGitHub  = "github"
+	TargetTypeSlack   = "slack"
+	TargetTypeWebhook = "webhook"
+)
+
+// TargetTestRequest describes the target to send a test payload to
+type TargetTestRequest struct {
+	Type      string `json:"type"`
+	RepoOwner string `json:"repo_owner,omitempty"`
+	RepoName  string `json:"repo_name,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// TargetTestResponse reports the outcome of a test delivery
+type TargetTestResponse struct {
+	Status     string `json:"status"`
+	Type       string `json:"type"`
+	Target     string `json:"target"`
+	Delivered  bool   `json:"delivered"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Message    string `json:"message"`
+}
+
+// TargetTester delivers sample payloads to notification targets
+type TargetTester struct {
+	GitHubClient GitHubClientInterface
+	Client       *http.Client
+}
+
+// NewTargetTester creates a target tester that uses the given GitHub client
+func NewTargetTester(githubClient GitHubClientInterface) *TargetTester {
+	return &TargetTester{
+		GitHubClient: githubClient,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// sampleTestPayload returns the labeled payload sent to targets during a test
+func sampleTestPayload(ctx context.Context) map[string]interface{} {
+	return map[string]interface{}{
+		"test":        true,
+		"message":     "This is a test delivery from youtube-webhook. No video was published.",
+		"video_id":    "test-video-id",
+		"channel_id":  "UCtest-channel-id",
+		"title":       "[TEST] youtube-webhook target test",
+		"published":   currentTime(ctx).Format(time.RFC3339),
+		"video_url":   "https://www.youtube.com/watch?v=test-video-id",
+		"environment": os.Getenv("ENVIRONMENT"),
+	}
+}
+
+// Test sends a sample payload to the target described by req
+func (tt *TargetTester) Test(ctx context.Context, req TargetTestRequest) TargetTestResponse {
+	response := TargetTestResponse{Type: req.Type}
+	start := time.Now()
+
+	switch req.Type {
+	case TargetTypeGitHub:
+		response.Target = fmt.Sprintf("%s/%s", req.RepoOwner, req.RepoName)
+		if !tt.GitHubClient.IsConfigured() {
+			response.Message = "GitHub token not configured"
+			break
+		}
+		if err := tt.GitHubClient.SendTestDispatch(ctx, req.RepoOwner, req.RepoName); err != nil {
+			response.Message = fmt.Sprintf("Test dispatch failed: %v", err)
+			break
+		}
+		response.Delivered = true
+		response.Message = fmt.Sprintf("Test %s event dispatched", TestEventType)
+	case TargetTypeSlack:
+		response.Target = req.URL
+		payload := map[string]interface{}{
+			"text": ":test_tube: *[TEST]* youtube-webhook target test - no video was published.",
+		}
+		response.StatusCode, response.Message = tt.postJSON(ctx, req.URL, payload)
+		response.Delivered = response.StatusCode >= 200 && response.StatusCode < 300
+	case TargetTypeWebhook:
+		response.Target = req.URL
+		payload := map[string]interface{}{
+			"event_type": TestEventType,
+			"payload":    sampleTestPayload(ctx),
+		}
+		response.StatusCode, response.Message = tt.postJSON(ctx, req.URL, payload)
+		response.Delivered = response.StatusCode >= 200 && response.StatusCode < 300
+	}
+
+	response.LatencyMs = time.Since(start).Milliseconds()
+	response.Status = "success"
+	if !response.Delivered {
+		response.Status = "failed"
+	}
+	return response
+}
+
+// postJSON posts payload to targetURL and returns the status code and a description
+func (tt *TargetTester) postJSON(ctx context.Context, targetURL string, payload interface{}) (int, string) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Sprintf("failed to marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", targetURL, bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Sprintf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := tt.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Sprintf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Sprintf("target returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return resp.StatusCode, fmt.Sprintf("target accepted test payload with status %d", resp.StatusCode)
+}
+
+// validateTargetTestRequest checks that the request names a supported, complete target
+func validateTargetTestRequest(req *TargetTestRequest) error {
+	switch req.Type {
+	case TargetTypeGitHub:
+		if req.RepoOwner == "" {
+			req.RepoOwner = os.Getenv("REPO_OWNER")
+		}
+		if req.RepoName == "" {
+			req.RepoName = os.Getenv("REPO_NAME")
+		}
+		if req.RepoOwner == "" || req.RepoName == "" {
+			return fmt.Errorf("repo_owner and repo_name are required for github targets")
+		}
+	case TargetTypeSlack, TargetTypeWebhook:
+		parsed, err := url.Parse(req.URL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("a valid http(s) url is required for %s targets", req.Type)
+		}
+	case "":
+		return fmt.Errorf("type is required (github, slack or webhook)")
+	default:
+		return fmt.Errorf("unsupported target type %q (expected github, slack or webhook)", req.Type)
+	}
+	return nil
+}
+
+// handleTestTarget handles POST /targets/test requests using dependency injection.
+func handleTestTarget(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TargetTestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "", "Request body must be a JSON target description")
+			return
+		}
+
+		if err := validateTargetTestRequest(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "", err.Error())
+			return
+		}
+
+		tester := NewTargetTester(deps.GitHubClient)
+		tester.Client = deps.HTTPClients.Client(httpClientTargets, tester.Client.Timeout)
+		writeJSONResponse(w, http.StatusOK, tester.Test(r.Context(), req))
+	}
+}
@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postTargetTest(t *testing.T, deps *Dependencies, body string) (*httptest.ResponseRecorder, TargetTestResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/targets/test", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleTestTarget(deps)(w, req)
+
+	var response TargetTestResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	}
+	return w, response
+}
+
+func TestHandleTestTarget_GitHub(t *testing.T) {
+	deps := CreateTestDependencies()
+	mockGitHub := deps.GitHubClient.(*MockGitHubClient)
+
+	w, response := postTargetTest(t, deps, `{"type":"github","repo_owner":"owner","repo_name":"repo"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, response.Delivered)
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "owner/repo", response.Target)
+	assert.Equal(t, 1, mockGitHub.GetTestCallCount())
+	assert.Equal(t, 0, mockGitHub.GetTriggerCallCount(), "test must not trigger the real workflow")
+}
+
+func TestHandleTestTarget_GitHubDefaultsFromEnv(t *testing.T) {
+	os.Setenv("REPO_OWNER", "env-owner")
+	os.Setenv("REPO_NAME", "env-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	deps := CreateTestDependencies()
+	_, response := postTargetTest(t, deps, `{"type":"github"}`)
+
+	assert.Equal(t, "env-owner/env-repo", response.Target)
+	assert.True(t, response.Delivered)
+}
+
+func TestHandleTestTarget_GitHubFailures(t *testing.T) {
+	t.Run("not_configured", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetConfigured(false)
+
+		_, response := postTargetTest(t, deps, `{"type":"github","repo_owner":"o","repo_name":"r"}`)
+
+		assert.False(t, response.Delivered)
+		assert.Equal(t, "failed", response.Status)
+		assert.Contains(t, response.Message, "not configured")
+	})
+
+	t.Run("dispatch_error", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.GitHubClient.(*MockGitHubClient).SetTriggerError(assert.AnError)
+
+		_, response := postTargetTest(t, deps, `{"type":"github","repo_owner":"o","repo_name":"r"}`)
+
+		assert.False(t, response.Delivered)
+		assert.Contains(t, response.Message, "Test dispatch failed")
+	})
+}
+
+func TestHandleTestTarget_Slack(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	deps := CreateTestDependencies()
+	_, response := postTargetTest(t, deps, `{"type":"slack","url":"`+server.URL+`"}`)
+
+	assert.True(t, response.Delivered)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, received["text"], "[TEST]")
+}
+
+func TestHandleTestTarget_Webhook(t *testing.T) {
+	t.Run("delivered", func(t *testing.T) {
+		var received map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &received)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		deps := CreateTestDependencies()
+		_, response := postTargetTest(t, deps, `{"type":"webhook","url":"`+server.URL+`"}`)
+
+		assert.True(t, response.Delivered)
+		assert.Equal(t, http.StatusAccepted, response.StatusCode)
+		assert.Equal(t, TestEventType, received["event_type"])
+		payload := received["payload"].(map[string]interface{})
+		assert.Equal(t, true, payload["test"])
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("invalid signature"))
+		}))
+		defer server.Close()
+
+		deps := CreateTestDependencies()
+		_, response := postTargetTest(t, deps, `{"type":"webhook","url":"`+server.URL+`"}`)
+
+		assert.False(t, response.Delivered)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		assert.Contains(t, response.Message, "invalid signature")
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		_, response := postTargetTest(t, deps, `{"type":"webhook","url":"http://127.0.0.1:1"}`)
+
+		assert.False(t, response.Delivered)
+		assert.Contains(t, response.Message, "failed to send request")
+	})
+}
+
+func TestHandleTestTarget_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{"invalid_json", `not json`},
+		{"missing_type", `{}`},
+		{"unknown_type", `{"type":"carrier-pigeon"}`},
+		{"github_missing_repo", `{"type":"github"}`},
+		{"slack_missing_url", `{"type":"slack"}`},
+		{"webhook_bad_scheme", `{"type":"webhook","url":"ftp://example.com"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deps := CreateTestDependencies()
+			w, _ := postTargetTest(t, deps, tc.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestGitHubClient_SendTestDispatch(t *testing.T) {
+	var received GitHubDispatch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/owner/repo/dispatches", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &GitHubClient{Token: "token", BaseURL: server.URL, Client: server.Client()}
+
+	require.NoError(t, client.SendTestDispatch(context.Background(), "owner", "repo"))
+	assert.Equal(t, TestEventType, received.EventType)
+	assert.Equal(t, true, received.ClientPayload["test"])
+
+	assert.Error(t, client.SendTestDispatch(context.Background(), "", "repo"))
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// Subscription topic types. A subscription follows either a channel's uploads or a
+// playlist, and is stored under the channel or playlist ID; the two never collide, as
+// channel IDs start with UC and playlist IDs do not.
+const (
+	TopicTypeChannel  = "channel"
+	TopicTypePlaylist = "playlist"
+)
+
+// youtubeFeedURL is the feed the hub publishes for every channel and playlist
+const youtubeFeedURL = "https://www.youtube.com/feeds/videos.xml"
+
+// Playlist ID validation regex: user playlists (PL), channel uploads (UU), favourites
+// (FL), liked videos (LL) and album playlists (OLAK5uy_)
+var playlistIDRegex = regexp.MustCompile(`^(PL|UU|FL|LL|OL)[a-zA-Z0-9_-]{16,48}$`)
+
+// validatePlaylistID validates YouTube playlist ID format
+func validatePlaylistID(playlistID string) bool {
+	return playlistIDRegex.MatchString(playlistID)
+}
+
+// validateTopicID validates the ID a subscription is stored under: a channel or playlist ID
+func validateTopicID(topicID string) bool {
+	return validateChannelID(topicID) || validatePlaylistID(topicID)
+}
+
+// topicTypeOf returns the type of topic a subscription ID names
+func topicTypeOf(topicID string) string {
+	if validatePlaylistID(topicID) {
+		return TopicTypePlaylist
+	}
+	return TopicTypeChannel
+}
+
+// topicParam returns the feed query parameter that names the topic: channel_id or playlist_id
+func topicParam(topicID string) string {
+	if topicTypeOf(topicID) == TopicTypePlaylist {
+		return "playlist_id"
+	}
+	return "channel_id"
+}
+
+// topicURLFor returns the feed URL the hub knows a channel or playlist by
+func topicURLFor(topicID string) string {
+	return youtubeFeedURL + "?" + topicParam(topicID) + "=" + topicID
+}
+
+// topicIDFromURL extracts the channel or playlist ID from a YouTube feed topic URL
+func topicIDFromURL(topic string) (string, bool) {
+	parsed, err := url.Parse(topic)
+	if err != nil {
+		return "", false
+	}
+	query := parsed.Query()
+	if playlistID := query.Get("playlist_id"); playlistID != "" {
+		return playlistID, validatePlaylistID(playlistID) && query.Get("channel_id") == ""
+	}
+	channelID := query.Get("channel_id")
+	return channelID, validateChannelID(channelID)
+}
+
+// feedPlaylistID returns the playlist a notification was published for, from the
+// feed's self (topic) link, or "" for channel feeds
+func feedPlaylistID(feed *AtomFeed) string {
+	for _, link := range feed.Links {
+		if link.Rel != "self" {
+			continue
+		}
+		if topicID, ok := topicIDFromURL(link.Href); ok && topicTypeOf(topicID) == TopicTypePlaylist {
+			return topicID
+		}
+	}
+	return ""
+}
+
+// Type returns the subscription's topic type; subscriptions stored before playlists
+// were supported have none and are channels
+func (s *Subscription) Type() string {
+	if s.TopicType == "" {
+		return topicTypeOf(s.ChannelID)
+	}
+	return s.TopicType
+}
@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const topicsTestPlaylist = "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"
+
+func TestTopicIDFromURL(t *testing.T) {
+	testCases := []struct {
+		topic    string
+		expected string
+		ok       bool
+	}{
+		{"https://www.youtube.com/feeds/videos.xml?channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw", "UCXuqSBlHAE6Xw-yeJA0Tunw", true},
+		{"https://www.youtube.com/feeds/videos.xml?playlist_id=" + topicsTestPlaylist, topicsTestPlaylist, true},
+		{"https://www.youtube.com/xml/feeds/videos.xml?playlist_id=UUXuqSBlHAE6Xw-yeJA0Tunw", "UUXuqSBlHAE6Xw-yeJA0Tunw", true},
+		{"https://www.youtube.com/feeds/videos.xml?playlist_id=PL1", "PL1", false},
+		{"https://www.youtube.com/feeds/videos.xml?playlist_id=UCXuqSBlHAE6Xw-yeJA0Tunw", "UCXuqSBlHAE6Xw-yeJA0Tunw", false},
+		{"https://www.youtube.com/feeds/videos.xml?channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw&playlist_id=" + topicsTestPlaylist, topicsTestPlaylist, false},
+		{"https://www.youtube.com/feeds/videos.xml", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.topic, func(t *testing.T) {
+			topicID, ok := topicIDFromURL(tc.topic)
+			assert.Equal(t, tc.expected, topicID)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestTopicURLFor(t *testing.T) {
+	assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw", topicURLFor("UCXuqSBlHAE6Xw-yeJA0Tunw"))
+	assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?playlist_id="+topicsTestPlaylist, topicURLFor(topicsTestPlaylist))
+
+	assert.Equal(t, TopicTypeChannel, (&Subscription{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}).Type())
+	assert.Equal(t, TopicTypePlaylist, (&Subscription{ChannelID: topicsTestPlaylist}).Type(), "state without a topic type")
+	assert.Equal(t, TopicTypePlaylist, (&Subscription{ChannelID: topicsTestPlaylist, TopicType: TopicTypePlaylist}).Type())
+}
+
+func TestSubscribeToPlaylist(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		deps := CreateTestDependencies()
+
+		w := httptest.NewRecorder()
+		handleSubscribe(deps)(w, httptest.NewRequest("POST", "/subscribe?playlist_id="+topicsTestPlaylist, nil))
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, topicsTestPlaylist, response.ChannelID)
+
+		sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[topicsTestPlaylist]
+		require.NotNil(t, sub)
+		assert.Equal(t, TopicTypePlaylist, sub.TopicType)
+		assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?playlist_id="+topicsTestPlaylist, sub.TopicURL)
+		assert.Equal(t, StatusPending, sub.Status)
+	})
+
+	t.Run("already_subscribed", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: topicsTestPlaylist, TopicType: TopicTypePlaylist}))
+
+		w := httptest.NewRecorder()
+		handleSubscribe(deps)(w, httptest.NewRequest("POST", "/subscribe?playlist_id="+topicsTestPlaylist, nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "Already subscribed to this playlist")
+	})
+
+	for name, query := range map[string]string{
+		"invalid_playlist_id": "playlist_id=PL1",
+		"channel_id_given":    "playlist_id=" + topicsTestPlaylist + "&channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw",
+		"handle_given":        "playlist_id=" + topicsTestPlaylist + "&handle=@example",
+	} {
+		t.Run(name, func(t *testing.T) {
+			deps := CreateTestDependencies()
+
+			w := httptest.NewRecorder()
+			handleSubscribe(deps)(w, httptest.NewRequest("POST", "/subscribe?"+query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, 0, deps.StorageClient.(*MockStorageClient).SaveCallCount)
+		})
+	}
+}
+
+func TestUnsubscribeFromPlaylist(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{ChannelID: topicsTestPlaylist, TopicType: TopicTypePlaylist}))
+
+	w := httptest.NewRecorder()
+	handleUnsubscribe(deps)(w, httptest.NewRequest("DELETE", "/unsubscribe?playlist_id="+topicsTestPlaylist, nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.NotContains(t, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions, topicsTestPlaylist)
+}
+
+func TestHandleVerificationChallenge_Playlist(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID: topicsTestPlaylist,
+		TopicType: TopicTypePlaylist,
+		TopicURL:  topicURLFor(topicsTestPlaylist),
+		Status:    StatusPending,
+	}))
+
+	req := httptest.NewRequest("GET", "/?hub.mode=subscribe&hub.challenge=playlist-challenge&hub.lease_seconds=86400&hub.topic="+
+		"https%3A%2F%2Fwww.youtube.com%2Ffeeds%2Fvideos.xml%3Fplaylist_id%3D"+topicsTestPlaylist, nil)
+	w := httptest.NewRecorder()
+	handleVerificationChallenge(deps)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "playlist-challenge", w.Body.String())
+	assert.Equal(t, StatusVerified, deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[topicsTestPlaylist].Status)
+}
+
+func TestHandleNotification_PlaylistFeed(t *testing.T) {
+	now := time.Now()
+	entry := func(videoID, channelID string) string {
+		return fmt.Sprintf(`
+  <entry>
+    <yt:videoId>%s</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Playlist Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>`, videoID, channelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+	}
+	notification := `<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <link rel="hub" href="https://pubsubhubbub.appspot.com"/>
+  <link rel="self" href="https://www.youtube.com/xml/feeds/videos.xml?playlist_id=` + topicsTestPlaylist + `"/>` +
+		entry("playlist001", "UCXuqSBlHAE6Xw-yeJA0Tunw") +
+		entry("playlist002", "UC_x5XG1OV2P6uZZ5FSM9Ttw") + `
+</feed>`
+
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID: topicsTestPlaylist,
+		TopicType: TopicTypePlaylist,
+		Repositories: []RepositoryTarget{{RepoOwner: "octo", RepoName: "playlists", Mode: DispatchModeWorkflow,
+			Workflow: "publish.yml", Ref: "main", Inputs: map[string]string{"playlist": "playlist_id", "channel": "channel_id"}}},
+	}))
+	gh := deps.GitHubClient.(*MockGitHubClient)
+
+	rec := httptest.NewRecorder()
+	handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 2, gh.GetWorkflowCallCount(), "entries from different channels are dispatched by the playlist's routes")
+	_, _, inputs := gh.GetLastWorkflowDispatch()
+	assert.Equal(t, map[string]string{"playlist": topicsTestPlaylist, "channel": "UC_x5XG1OV2P6uZZ5FSM9Ttw"}, inputs)
+
+	sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[topicsTestPlaylist]
+	assert.False(t, sub.LastNotificationAt.IsZero(), "the playlist's subscription records the notification")
+}
@@ -0,0 +1,381 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	texporter "github.com/GoogleCloudPlatform/opentelemetry-operations-go/exporter/trace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans this package creates
+const tracerName = "github.com/samsoir/youtube-webhook/function"
+
+// getTraceSampleRatio returns the share of requests traced, from TRACE_SAMPLE_RATIO
+// (default 0, tracing off). Requests whose caller sampled them are always traced.
+func getTraceSampleRatio() float64 {
+	ratioStr := os.Getenv("TRACE_SAMPLE_RATIO")
+	if ratioStr == "" {
+		return 0
+	}
+
+	var ratio float64
+	if _, err := fmt.Sscanf(ratioStr, "%g", &ratio); err == nil && ratio >= 0 && ratio <= 1 {
+		return ratio
+	}
+	return 0
+}
+
+// NewTracer creates a tracer exporting to Cloud Trace in GOOGLE_CLOUD_PROJECT.
+// Returns nil (nothing is traced) when TRACE_SAMPLE_RATIO is 0, the project is unset
+// or the exporter cannot be created.
+func NewTracer() trace.Tracer {
+	ratio := getTraceSampleRatio()
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if ratio == 0 {
+		return nil
+	}
+	if projectID == "" {
+		fmt.Println("TRACE_SAMPLE_RATIO needs GOOGLE_CLOUD_PROJECT; tracing disabled")
+		return nil
+	}
+
+	exporter, err := texporter.New(texporter.WithProjectID(projectID))
+	if err != nil {
+		fmt.Printf("Unable to create Cloud Trace exporter, tracing disabled: %v\n", err)
+		return nil
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	return &providerTracer{Tracer: provider.Tracer(tracerName), provider: provider}
+}
+
+// providerTracer is a tracer that can flush its provider's batched spans on shutdown
+type providerTracer struct {
+	trace.Tracer
+	provider *sdktrace.TracerProvider
+}
+
+// Shutdown exports the spans still batched and stops the provider
+func (t *providerTracer) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+// traceRequest starts the span of a request, continuing the caller's trace from its
+// traceparent header, and returns dependencies whose storage, hub and GitHub calls are
+// child spans of it. finish ends the span with the response status. Without a tracer
+// everything is returned unchanged.
+func traceRequest(deps *Dependencies, w http.ResponseWriter, r *http.Request, route string) (*Dependencies, http.ResponseWriter, *http.Request, func()) {
+	if deps.Tracer == nil {
+		return deps, w, r, func() {}
+	}
+
+	// The callback path is recorded as its pattern, keeping the token out of traces
+	path := r.URL.Path
+	if strings.Contains(route, "{token}") {
+		path = route
+	}
+
+	ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := deps.Tracer.Start(ctx, r.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", path),
+			attribute.String("http.route", route),
+			attribute.String("request_id", w.Header().Get(RequestIDHeader)),
+		))
+
+	traced := *deps
+	if deps.StorageClient != nil {
+		traced.StorageClient = traceStorage(deps.StorageClient, deps.Tracer)
+	}
+	if deps.PubSubClient != nil {
+		traced.PubSubClient = tracePubSub(deps.PubSubClient, deps.Tracer)
+	}
+	if deps.GitHubClient != nil {
+		traced.GitHubClient = traceGitHub(deps.GitHubClient, deps.Tracer)
+	}
+
+	rec := &statusRecorder{ResponseWriter: w}
+	finish := func() {
+		status := rec.status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+	}
+	return &traced, rec, r.WithContext(ctx), finish
+}
+
+// spanRoute names a request's span by its route pattern, keeping IDs out of span names
+func spanRoute(matched *route) string {
+	if matched == nil {
+		return "unmatched"
+	}
+	return "/" + matched.pattern
+}
+
+// startSpan starts a child span of the request in ctx
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan ends a span, marking it failed when err is set
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// statusRecorder passes a response through while noting its status
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	if s.statusCode == 0 {
+		s.statusCode = statusCode
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.statusCode == 0 {
+		s.statusCode = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController flush streamed responses
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// status returns the response's status code
+func (s *statusRecorder) status() int {
+	if s.statusCode == 0 {
+		return http.StatusOK
+	}
+	return s.statusCode
+}
+
+// tracedStorage records a span for every storage operation
+type tracedStorage struct {
+	StorageService
+	tracer trace.Tracer
+}
+
+// tracedObjectStorage also forwards ObjectStorage for backends that support it
+type tracedObjectStorage struct {
+	*tracedStorage
+	objects ObjectStorage
+}
+
+// traceStorage wraps storage so its operations are traced. The wrapper keeps the
+// ObjectStorage capability of the underlying backend.
+func traceStorage(storage StorageService, tracer trace.Tracer) StorageService {
+	wrapped := &tracedStorage{StorageService: storage, tracer: tracer}
+	if objects, ok := storage.(ObjectStorage); ok {
+		return &tracedObjectStorage{tracedStorage: wrapped, objects: objects}
+	}
+	return wrapped
+}
+
+func (s *tracedStorage) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
+	ctx, span := startSpan(ctx, s.tracer, "storage.load_state")
+	state, err := s.StorageService.LoadSubscriptionState(ctx)
+	endSpan(span, err)
+	return state, err
+}
+
+func (s *tracedStorage) SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.save_state")
+	err := s.StorageService.SaveSubscriptionState(ctx, state)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStorage) GetSubscription(ctx context.Context, channelID string) (*Subscription, error) {
+	ctx, span := startSpan(ctx, s.tracer, "storage.get_subscription", attribute.String("channel_id", channelID))
+	sub, err := s.StorageService.GetSubscription(ctx, channelID)
+	// A missing subscription is an answer, not a storage failure
+	traced := err
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		traced = nil
+	}
+	endSpan(span, traced)
+	return sub, err
+}
+
+func (s *tracedStorage) SaveSubscription(ctx context.Context, subscription *Subscription) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.save_subscription", attribute.String("channel_id", subscription.ChannelID))
+	err := s.StorageService.SaveSubscription(ctx, subscription)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStorage) DeleteSubscription(ctx context.Context, channelID string) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.delete_subscription", attribute.String("channel_id", channelID))
+	err := s.StorageService.DeleteSubscription(ctx, channelID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStorage) StreamSubscriptions(ctx context.Context, fn func(*Subscription) error) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.stream_subscriptions")
+	err := s.StorageService.StreamSubscriptions(ctx, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedObjectStorage) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	ctx, span := startSpan(ctx, s.tracer, "storage.read_object", attribute.String("object", name))
+	data, err := s.objects.ReadObject(ctx, name)
+	traced := err
+	if errors.Is(err, ErrObjectNotFound) {
+		traced = nil
+	}
+	endSpan(span, traced)
+	return data, err
+}
+
+func (s *tracedObjectStorage) WriteObject(ctx context.Context, name string, data []byte) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.write_object", attribute.String("object", name))
+	err := s.objects.WriteObject(ctx, name, data)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedObjectStorage) CreateObject(ctx context.Context, name string, data []byte) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.create_object", attribute.String("object", name))
+	err := s.objects.CreateObject(ctx, name, data)
+	traced := err
+	if errors.Is(err, ErrObjectChanged) {
+		traced = nil
+	}
+	endSpan(span, traced)
+	return err
+}
+
+func (s *tracedObjectStorage) ReadObjectGeneration(ctx context.Context, name string) ([]byte, int64, error) {
+	ctx, span := startSpan(ctx, s.tracer, "storage.read_object", attribute.String("object", name))
+	data, generation, err := s.objects.ReadObjectGeneration(ctx, name)
+	traced := err
+	if errors.Is(err, ErrObjectNotFound) {
+		traced = nil
+	}
+	endSpan(span, traced)
+	return data, generation, err
+}
+
+func (s *tracedObjectStorage) WriteObjectIfGeneration(ctx context.Context, name string, data []byte, generation int64) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.write_object", attribute.String("object", name))
+	err := s.objects.WriteObjectIfGeneration(ctx, name, data, generation)
+	traced := err
+	if errors.Is(err, ErrObjectChanged) {
+		traced = nil
+	}
+	endSpan(span, traced)
+	return err
+}
+
+func (s *tracedObjectStorage) DeleteObject(ctx context.Context, name string) error {
+	ctx, span := startSpan(ctx, s.tracer, "storage.delete_object", attribute.String("object", name))
+	err := s.objects.DeleteObject(ctx, name)
+	endSpan(span, err)
+	return err
+}
+
+// tracedPubSub records a span for every hub request
+type tracedPubSub struct {
+	PubSubClient
+	tracer trace.Tracer
+}
+
+// tracePubSub wraps client so hub requests are traced
+func tracePubSub(client PubSubClient, tracer trace.Tracer) PubSubClient {
+	return &tracedPubSub{PubSubClient: client, tracer: tracer}
+}
+
+func (p *tracedPubSub) Subscribe(ctx context.Context, channelID, secret string, leaseSeconds int) error {
+	ctx, span := startSpan(ctx, p.tracer, "hub.subscribe", attribute.String("channel_id", channelID))
+	err := p.PubSubClient.Subscribe(ctx, channelID, secret, leaseSeconds)
+	endSpan(span, err)
+	return err
+}
+
+func (p *tracedPubSub) Unsubscribe(ctx context.Context, channelID string) error {
+	ctx, span := startSpan(ctx, p.tracer, "hub.unsubscribe", attribute.String("channel_id", channelID))
+	err := p.PubSubClient.Unsubscribe(ctx, channelID)
+	endSpan(span, err)
+	return err
+}
+
+// tracedGitHub records a span for every GitHub API call
+type tracedGitHub struct {
+	GitHubClientInterface
+	tracer trace.Tracer
+}
+
+// traceGitHub wraps client so GitHub API calls are traced
+func traceGitHub(client GitHubClientInterface, tracer trace.Tracer) GitHubClientInterface {
+	return &tracedGitHub{GitHubClientInterface: client, tracer: tracer}
+}
+
+// repositoryAttribute names the repository a GitHub call is for
+func repositoryAttribute(repoOwner, repoName string) attribute.KeyValue {
+	return attribute.String("repository", repoOwner+"/"+repoName)
+}
+
+func (g *tracedGitHub) TriggerWorkflow(ctx context.Context, repoOwner, repoName string, entry *Entry, extra map[string]interface{}) error {
+	ctx, span := startSpan(ctx, g.tracer, "github.repository_dispatch",
+		repositoryAttribute(repoOwner, repoName), attribute.String("video_id", entry.VideoID))
+	err := g.GitHubClientInterface.TriggerWorkflow(ctx, repoOwner, repoName, entry, extra)
+	endSpan(span, err)
+	return err
+}
+
+func (g *tracedGitHub) DispatchWorkflow(ctx context.Context, repoOwner, repoName, workflow, ref string, inputs map[string]string) error {
+	ctx, span := startSpan(ctx, g.tracer, "github.workflow_dispatch",
+		repositoryAttribute(repoOwner, repoName), attribute.String("workflow", workflow))
+	err := g.GitHubClientInterface.DispatchWorkflow(ctx, repoOwner, repoName, workflow, ref, inputs)
+	endSpan(span, err)
+	return err
+}
+
+func (g *tracedGitHub) SendTestDispatch(ctx context.Context, repoOwner, repoName string) error {
+	ctx, span := startSpan(ctx, g.tracer, "github.test_dispatch", repositoryAttribute(repoOwner, repoName))
+	err := g.GitHubClientInterface.SendTestDispatch(ctx, repoOwner, repoName)
+	endSpan(span, err)
+	return err
+}
+
+func (g *tracedGitHub) FindDispatchRun(ctx context.Context, repoOwner, repoName, dispatchID string, since time.Time) (*WorkflowRun, error) {
+	ctx, span := startSpan(ctx, g.tracer, "github.find_dispatch_run", repositoryAttribute(repoOwner, repoName))
+	run, err := g.GitHubClientInterface.FindDispatchRun(ctx, repoOwner, repoName, dispatchID, since)
+	endSpan(span, err)
+	return run, err
+}
+
+func (g *tracedGitHub) CheckAccess(ctx context.Context, repoOwner, repoName string) error {
+	ctx, span := startSpan(ctx, g.tracer, "github.check_access", repositoryAttribute(repoOwner, repoName))
+	err := g.GitHubClientInterface.CheckAccess(ctx, repoOwner, repoName)
+	endSpan(span, err)
+	return err
+}
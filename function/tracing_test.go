@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestTracer returns a tracer recording every span in memory
+func newTestTracer(t *testing.T) (*Dependencies, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { provider.Shutdown(t.Context()) })
+
+	deps := CreateTestDependencies()
+	deps.Tracer = provider.Tracer(tracerName)
+	return deps, recorder
+}
+
+// spanNames returns the names of the ended spans, in the order they ended
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func TestGetTraceSampleRatio(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected float64
+	}{
+		{"", 0},
+		{"0.25", 0.25},
+		{"1", 1},
+		{"1.5", 0},
+		{"-0.1", 0},
+		{"abc", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Setenv("TRACE_SAMPLE_RATIO", tc.value)
+			assert.Equal(t, tc.expected, getTraceSampleRatio())
+		})
+	}
+}
+
+func TestNewTracer_Disabled(t *testing.T) {
+	t.Setenv("TRACE_SAMPLE_RATIO", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+	assert.Nil(t, NewTracer())
+
+	t.Setenv("TRACE_SAMPLE_RATIO", "1")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	assert.Nil(t, NewTracer(), "Cloud Trace needs a project")
+}
+
+func TestYouTubeWebhook_TracesSubscribe(t *testing.T) {
+	t.Setenv("FUNCTION_URL", "https://example.com/webhook")
+	deps, recorder := newTestTracer(t)
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("POST", "/subscribe?channel_id="+routingChannelID, nil)
+	req.Header.Set("traceparent", parent)
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	names := spanNames(recorder)
+	assert.Contains(t, names, "storage.get_subscription")
+	assert.Contains(t, names, "hub.subscribe")
+	assert.Contains(t, names, "storage.save_subscription")
+	require.Equal(t, "POST /subscribe", names[len(names)-1], "the request span ends last")
+
+	spans := recorder.Ended()
+	root := spans[len(spans)-1]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.SpanContext().TraceID().String(), "the caller's trace continues")
+	assert.Equal(t, "00f067aa0ba902b7", root.Parent().SpanID().String())
+	for _, span := range spans[:len(spans)-1] {
+		assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID(), "%s is a child of the request", span.Name())
+	}
+}
+
+func TestYouTubeWebhook_TracesGitHubDispatch(t *testing.T) {
+	t.Setenv("REPO_OWNER", "test-owner")
+	t.Setenv("REPO_NAME", "test-repo")
+	deps, recorder := newTestTracer(t)
+	deps.GitHubClient.(*MockGitHubClient).SetTriggerError(errors.New("dispatch refused"))
+	subscribeTestChannels(deps, routingChannelID)
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	now := time.Now()
+	body := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>trace123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Traced Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, routingChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+	var dispatch sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "github.repository_dispatch" {
+			dispatch = span
+		}
+	}
+	require.NotNil(t, dispatch, "spans: %v", spanNames(recorder))
+	assert.Equal(t, codes.Error, dispatch.Status().Code)
+	assert.Equal(t, "dispatch refused", dispatch.Status().Description)
+}
+
+func TestYouTubeWebhook_NoTracer(t *testing.T) {
+	deps := CreateTestDependencies()
+	SetDependencies(deps)
+	defer SetDependencies(nil)
+
+	rec := httptest.NewRecorder()
+	YouTubeWebhook(rec, httptest.NewRequest("GET", "/subscriptions", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.IsType(t, &MockStorageClient{}, deps.StorageClient, "untraced dependencies are used as they are")
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Hub verification modes, and the mode of a denial callback
+const (
+	hubModeSubscribe   = "subscribe"
+	hubModeUnsubscribe = "unsubscribe"
+	hubModeDenied      = "denied"
+)
+
+// Results of hub callbacks, as counted by Metrics.RecordHubCallback
+const (
+	hubCallbackConfirmed   = "confirmed"   // Verification echoed
+	hubCallbackRefused     = "refused"     // Verification of an intent we do not have
+	hubCallbackQuarantined = "quarantined" // Denial quarantined the subscription
+	hubCallbackRecovering  = "recovering"  // Denial put the subscription into automatic recovery
+	hubCallbackIgnored     = "ignored"     // Denial of a subscription we do not track
+)
+
+// handleVerificationChallenge handles the hub's intent verification (GET /?hub.challenge=...).
+// The challenge is only echoed for intents this service actually has: a subscribe for a
+// channel we track, or an unsubscribe for a channel we no longer track. Confirmed
+// subscriptions move to the verified state with the lease the hub granted. Denials
+// (GET /?hub.mode=denied) are handled by handleSubscriptionDenied.
+func handleVerificationChallenge(deps *Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("hub.mode") == hubModeDenied {
+			handleSubscriptionDenied(deps, w, r)
+			return
+		}
+
+		challenge := query.Get("hub.challenge")
+		if challenge == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeMissingChallenge, "", "hub.challenge parameter is required")
+			return
+		}
+
+		mode := query.Get("hub.mode")
+		if mode != hubModeSubscribe && mode != hubModeUnsubscribe {
+			writeError(w, r, http.StatusBadRequest, "", "", "hub.mode must be subscribe or unsubscribe")
+			return
+		}
+
+		topic := query.Get("hub.topic")
+		channelID, ok := topicIDFromURL(topic)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, "", "", "hub.topic must be a YouTube channel or playlist feed URL")
+			return
+		}
+
+		ctx := r.Context()
+		subscription, err := deps.StorageClient.GetSubscription(ctx, channelID)
+		if err != nil && !errors.Is(err, ErrSubscriptionNotFound) {
+			writeError(w, r, http.StatusInternalServerError, "", channelID,
+				fmt.Sprintf("Failed to load subscription state: %v", err))
+			return
+		}
+
+		switch mode {
+		case hubModeSubscribe:
+			if subscription == nil || (subscription.TopicURL != "" && subscription.TopicURL != topic) {
+				deps.Metrics.RecordHubCallback(mode, hubCallbackRefused)
+				writeError(w, r, http.StatusNotFound, "", channelID, "No pending subscription for this topic")
+				return
+			}
+			markVerified(subscription, query.Get("hub.lease_seconds"), deps.now())
+			if err := deps.StorageClient.SaveSubscription(ctx, subscription); err != nil {
+				// The hub's subscription is still valid; confirm it and let renewal correct the record
+				fmt.Printf("Failed to record verification for channel %s: %v\n", channelID, err)
+			}
+		case hubModeUnsubscribe:
+			// Unsubscribing removes the record before the hub verifies asynchronously, so a
+			// record that still exists means we never asked to unsubscribe
+			if subscription != nil {
+				fmt.Printf("Refused unsubscribe verification for channel %s, which is still subscribed\n", channelID)
+				deps.Metrics.RecordHubCallback(mode, hubCallbackRefused)
+				writeError(w, r, http.StatusNotFound, "", channelID, "Unsubscribe was not requested for this topic")
+				return
+			}
+			fmt.Printf("Hub confirmed unsubscribe of channel %s\n", channelID)
+		}
+		deps.Metrics.RecordHubCallback(mode, hubCallbackConfirmed)
+
+		// The hub expects the challenge echoed back verbatim as plain text
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(challenge)); err != nil {
+			fmt.Printf("Error writing response: %v\n", err)
+		}
+	}
+}
+
+// handleSubscriptionDenied handles the hub's denial of a subscription (GET /?hub.mode=denied),
+// sent when the hub refuses a subscribe request or cancels a subscription it had granted.
+// The subscription's lease ends and it is re-subscribed automatically with backoff (see
+// recoverSubscriptions); once RECOVERY_MAX_ATTEMPTS have been made, or with recovery
+// disabled, it is quarantined with the hub's reason until reactivated. Denials are always
+// acknowledged, even for topics we do not track.
+func handleSubscriptionDenied(deps *Dependencies, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	topic := query.Get("hub.topic")
+	channelID, ok := topicIDFromURL(topic)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, "", "", "hub.topic must be a YouTube channel or playlist feed URL")
+		return
+	}
+	reason := query.Get("hub.reason")
+	if reason == "" {
+		reason = "no reason given"
+	}
+
+	ctx := r.Context()
+	subscription, err := deps.StorageClient.GetSubscription(ctx, channelID)
+	if err != nil && !errors.Is(err, ErrSubscriptionNotFound) {
+		writeError(w, r, http.StatusInternalServerError, "", channelID,
+			fmt.Sprintf("Failed to load subscription state: %v", err))
+		return
+	}
+
+	if subscription == nil || (subscription.TopicURL != "" && subscription.TopicURL != topic) {
+		fmt.Printf("Hub denied subscription to untracked channel %s: %s\n", channelID, reason)
+		deps.Metrics.RecordHubCallback(hubModeDenied, hubCallbackIgnored)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	now := deps.now()
+	outcome := hubCallbackQuarantined
+	markDenied(subscription, reason, now)
+	if maxAttempts := deps.config().RecoveryMaxAttempts; maxAttempts > 0 &&
+		startRecovery(subscription, RecoveryReasonDenied, subscription.LastRenewalError, now, maxAttempts) {
+		outcome = hubCallbackRecovering
+	}
+	if outcome == hubCallbackRecovering {
+		fmt.Printf("Hub denied subscription to channel %s, re-subscribing it: %s\n", channelID, reason)
+	} else {
+		fmt.Printf("Hub denied subscription to channel %s, quarantining it: %s\n", channelID, reason)
+	}
+	if err := deps.StorageClient.SaveSubscription(ctx, subscription); err != nil {
+		writeError(w, r, http.StatusInternalServerError, "", channelID,
+			fmt.Sprintf("Failed to save subscription state: %v", err))
+		return
+	}
+	deps.Metrics.RecordHubCallback(hubModeDenied, outcome)
+	w.WriteHeader(http.StatusOK)
+}
@@ -1,20 +1,138 @@
 package webhook
 
 import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 )
 
-// VideoProcessor handles video-related business logic
-type VideoProcessor struct{}
+// New video detection strategies, selected with NEW_VIDEO_STRATEGY
+const (
+	// NewVideoStrategyWindow treats a video as new when it was published recently and
+	// barely updated since (default)
+	NewVideoStrategyWindow = "window"
+	// NewVideoStrategyFirstSeen treats every video as new the first time it is seen and
+	// relies on the processed-video registry to skip later notifications for it
+	NewVideoStrategyFirstSeen = "first_seen"
+	// NewVideoStrategyUpdated treats a video as new when it was updated recently,
+	// whenever it was published
+	NewVideoStrategyUpdated = "updated"
+	// NewVideoStrategyAcceptAll treats every notification as a new video
+	NewVideoStrategyAcceptAll = "accept_all"
+)
+
+// Default new video detection thresholds
+const (
+	defaultNewVideoMaxAge         = time.Hour
+	defaultNewVideoMaxUpdateDelta = 15 * time.Minute
+)
+
+// VideoProcessorInterface decides which notification entries are new videos and
+// validates them. *VideoProcessor implements it, and *ShadowVideoProcessor wraps one
+// to evaluate a candidate alongside it.
+type VideoProcessorInterface interface {
+	IsNewVideo(entry *Entry) bool
+	ValidateEntry(entry *Entry) error
+	ValidateFeed(feed *AtomFeed) error
+}
+
+// VideoProcessor handles video-related business logic. Zero values use the
+// window strategy with the default thresholds.
+type VideoProcessor struct {
+	Strategy       string
+	MaxAge         time.Duration // How long after publishing (or updating) a video still counts as new
+	MaxUpdateDelta time.Duration // Largest gap between publish and update for a new video (window strategy)
+	Clock          Clock         // Optional; nil judges age by the system clock
+}
 
-// NewVideoProcessor creates a new video processor instance
+// NewVideoProcessor creates a video processor configured from NEW_VIDEO_STRATEGY,
+// NEW_VIDEO_MAX_AGE_MINUTES and NEW_VIDEO_UPDATE_GAP_MINUTES
 func NewVideoProcessor() *VideoProcessor {
-	return &VideoProcessor{}
+	return &VideoProcessor{
+		Strategy:       getNewVideoStrategy("NEW_VIDEO_STRATEGY"),
+		MaxAge:         getMinutesEnv("NEW_VIDEO_MAX_AGE_MINUTES", defaultNewVideoMaxAge),
+		MaxUpdateDelta: getMinutesEnv("NEW_VIDEO_UPDATE_GAP_MINUTES", defaultNewVideoMaxUpdateDelta),
+	}
+}
+
+// getNewVideoStrategy returns the detection strategy in the named environment variable,
+// falling back to window for unknown values
+func getNewVideoStrategy(name string) string {
+	switch strategy := os.Getenv(name); strategy {
+	case NewVideoStrategyWindow, NewVideoStrategyFirstSeen, NewVideoStrategyUpdated, NewVideoStrategyAcceptAll:
+		return strategy
+	case "":
+		return NewVideoStrategyWindow
+	default:
+		fmt.Printf("Unknown %s %q, using %s\n", name, strategy, NewVideoStrategyWindow)
+		return NewVideoStrategyWindow
+	}
+}
+
+// getMinutesEnv parses a minute count from the named environment variable
+func getMinutesEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	if minutes, err := time.ParseDuration(value + "m"); err == nil && minutes > 0 {
+		return minutes
+	}
+	return defaultValue
+}
+
+// String describes the strategy and its thresholds, as used in shadow mode logs
+func (vp *VideoProcessor) String() string {
+	strategy := vp.Strategy
+	if strategy == "" {
+		strategy = NewVideoStrategyWindow
+	}
+	return fmt.Sprintf("%s (max age %s, update gap %s)", strategy, vp.maxAge(), vp.maxUpdateDelta())
+}
+
+// maxAge returns the configured maximum age, or the default
+func (vp *VideoProcessor) maxAge() time.Duration {
+	if vp.MaxAge > 0 {
+		return vp.MaxAge
+	}
+	return defaultNewVideoMaxAge
+}
+
+// now returns the time on the processor's clock
+func (vp *VideoProcessor) now() time.Time {
+	if vp.Clock != nil {
+		return vp.Clock.Now()
+	}
+	return time.Now()
+}
+
+// maxUpdateDelta returns the configured maximum publish/update gap, or the default
+func (vp *VideoProcessor) maxUpdateDelta() time.Duration {
+	if vp.MaxUpdateDelta > 0 {
+		return vp.MaxUpdateDelta
+	}
+	return defaultNewVideoMaxUpdateDelta
 }
 
 // IsNewVideo determines if a video entry represents a new video publication
-// rather than an update to an existing video
+// rather than an update to an existing video, according to the strategy
 func (vp *VideoProcessor) IsNewVideo(entry *Entry) bool {
+	switch vp.Strategy {
+	case NewVideoStrategyFirstSeen, NewVideoStrategyAcceptAll:
+		// first_seen leaves repeat notifications to the processed-video registry
+		return true
+	case NewVideoStrategyUpdated:
+		updated, err := time.Parse(time.RFC3339, entry.Updated)
+		if err != nil {
+			// If we can't parse the timestamp, skip for safety (don't assume it's new)
+			return false
+		}
+		return vp.now().Sub(updated) <= vp.maxAge()
+	}
+
 	// Parse timestamps
 	published, err := time.Parse(time.RFC3339, entry.Published)
 	if err != nil {
@@ -28,28 +146,30 @@ func (vp *VideoProcessor) IsNewVideo(entry *Entry) bool {
 		return false
 	}
 
-	now := time.Now()
+	now := vp.now()
 
 	// Consider a video "new" if:
-	// 1. It was published within the last hour
-	// 2. The difference between published and updated time is small (less than 15 minutes)
+	// 1. It was published within the maximum age (default 1 hour)
+	// 2. The difference between published and updated time is small (default less than 15 minutes)
 	timeSincePublished := now.Sub(published)
 	updatePublishDiff := updated.Sub(published)
 
-	// If published more than 1 hour ago, it's likely an old video update
-	if timeSincePublished > time.Hour {
+	// If published longer ago than the maximum age, it's likely an old video update
+	if timeSincePublished > vp.maxAge() {
 		return false
 	}
 
 	// If there's a large gap between publish and update, it's likely an update to an old video
-	if updatePublishDiff > 15*time.Minute {
+	if updatePublishDiff > vp.maxUpdateDelta() {
 		return false
 	}
 
 	return true
 }
 
-// ValidateEntry performs basic validation on video entry data
+// ValidateEntry checks that an entry has the IDs and timestamps needed to process it.
+// Errors wrap ErrInvalidEntry, ErrMissingVideoID, ErrMissingChannelID, ErrInvalidChannelID,
+// ErrInvalidTimestamp or ErrChannelMismatch; the first problem found is returned.
 func (vp *VideoProcessor) ValidateEntry(entry *Entry) error {
 	if entry == nil {
 		return ErrInvalidEntry
@@ -63,5 +183,73 @@ func (vp *VideoProcessor) ValidateEntry(entry *Entry) error {
 		return ErrMissingChannelID
 	}
 
+	if !validateChannelID(entry.ChannelID) {
+		return fmt.Errorf("%w: %q", ErrInvalidChannelID, entry.ChannelID)
+	}
+
+	for _, timestamp := range []struct{ field, value string }{
+		{"published", entry.Published},
+		{"updated", entry.Updated},
+	} {
+		if _, err := time.Parse(time.RFC3339, timestamp.value); err != nil {
+			return fmt.Errorf("%w: %s %q", ErrInvalidTimestamp, timestamp.field, timestamp.value)
+		}
+	}
+
+	// The author link names the publishing channel when present
+	if authorChannel := channelFromURL(entry.Author.URI); authorChannel != "" && authorChannel != entry.ChannelID {
+		return fmt.Errorf("%w: entry is for %s but its author is %s", ErrChannelMismatch, entry.ChannelID, authorChannel)
+	}
+
+	return nil
+}
+
+// ValidateFeed validates each of a notification's entries and checks that they all
+// belong to one channel, the channel of the feed's topic (self link) when it has one.
+// A playlist's feed may mix entries from any channels.
+func (vp *VideoProcessor) ValidateFeed(feed *AtomFeed) error {
+	if len(feed.Entries) == 0 {
+		return ErrInvalidEntry
+	}
+	for _, entry := range feed.Entries {
+		if err := vp.ValidateEntry(entry); err != nil {
+			return err
+		}
+	}
+	if feedPlaylistID(feed) != "" {
+		return nil
+	}
+
+	channelID := feed.Entries[0].ChannelID
+	for _, entry := range feed.Entries[1:] {
+		if entry.ChannelID != channelID {
+			return fmt.Errorf("%w: feed mixes entries for %s and %s", ErrChannelMismatch, channelID, entry.ChannelID)
+		}
+	}
+
+	for _, link := range feed.Links {
+		if link.Rel != "self" {
+			continue
+		}
+		if topicChannel := channelFromURL(link.Href); topicChannel != "" && topicChannel != channelID {
+			return fmt.Errorf("%w: entry is for %s but the feed topic is %s", ErrChannelMismatch, channelID, topicChannel)
+		}
+	}
 	return nil
 }
+
+// channelFromURL returns the channel ID in a topic URL (?channel_id=...) or channel
+// page URL (/channel/...), or "" when the URL names no channel
+func channelFromURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if channelID := parsed.Query().Get("channel_id"); channelID != "" {
+		return channelID
+	}
+	if rest, ok := strings.CutPrefix(parsed.Path, "/channel/"); ok {
+		return strings.Trim(rest, "/")
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -129,6 +130,97 @@ func TestVideoProcessor_IsNewVideo(t *testing.T) {
 	}
 }
 
+func TestNewVideoProcessor_Configuration(t *testing.T) {
+	defer func() {
+		os.Unsetenv("NEW_VIDEO_STRATEGY")
+		os.Unsetenv("NEW_VIDEO_MAX_AGE_MINUTES")
+		os.Unsetenv("NEW_VIDEO_UPDATE_GAP_MINUTES")
+	}()
+
+	processor := NewVideoProcessor()
+	assert.Equal(t, NewVideoStrategyWindow, processor.Strategy)
+	assert.Equal(t, time.Hour, processor.MaxAge)
+	assert.Equal(t, 15*time.Minute, processor.MaxUpdateDelta)
+
+	os.Setenv("NEW_VIDEO_STRATEGY", "first_seen")
+	os.Setenv("NEW_VIDEO_MAX_AGE_MINUTES", "180")
+	os.Setenv("NEW_VIDEO_UPDATE_GAP_MINUTES", "invalid")
+	processor = NewVideoProcessor()
+	assert.Equal(t, NewVideoStrategyFirstSeen, processor.Strategy)
+	assert.Equal(t, 3*time.Hour, processor.MaxAge)
+	assert.Equal(t, 15*time.Minute, processor.MaxUpdateDelta)
+
+	os.Setenv("NEW_VIDEO_STRATEGY", "newest")
+	assert.Equal(t, NewVideoStrategyWindow, NewVideoProcessor().Strategy, "unknown strategies fall back to window")
+}
+
+func TestVideoProcessor_IsNewVideo_Strategies(t *testing.T) {
+	now := time.Now()
+	republished := &Entry{ // Old video edited a few minutes ago
+		Published: now.Add(-72 * time.Hour).Format(time.RFC3339),
+		Updated:   now.Add(-5 * time.Minute).Format(time.RFC3339),
+	}
+	slowUpload := &Entry{ // Published 2 hours ago, processing finished 30 minutes later
+		Published: now.Add(-2 * time.Hour).Format(time.RFC3339),
+		Updated:   now.Add(-90 * time.Minute).Format(time.RFC3339),
+	}
+
+	testCases := []struct {
+		name      string
+		processor *VideoProcessor
+		entry     *Entry
+		expected  bool
+	}{
+		{"window_default_rejects_slow_upload", &VideoProcessor{}, slowUpload, false},
+		{"window_with_wider_thresholds", &VideoProcessor{MaxAge: 3 * time.Hour, MaxUpdateDelta: time.Hour}, slowUpload, true},
+		{"window_rejects_edit_of_old_video", &VideoProcessor{Strategy: NewVideoStrategyWindow}, republished, false},
+		{"updated_accepts_recent_edit", &VideoProcessor{Strategy: NewVideoStrategyUpdated}, republished, true},
+		{"updated_rejects_old_update", &VideoProcessor{Strategy: NewVideoStrategyUpdated}, slowUpload, false},
+		{"updated_rejects_invalid_timestamp", &VideoProcessor{Strategy: NewVideoStrategyUpdated}, &Entry{Updated: "invalid"}, false},
+		{"first_seen_accepts_old_video", &VideoProcessor{Strategy: NewVideoStrategyFirstSeen}, republished, true},
+		{"accept_all", &VideoProcessor{Strategy: NewVideoStrategyAcceptAll}, slowUpload, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.processor.IsNewVideo(tc.entry))
+		})
+	}
+}
+
+func TestVideoProcessor_ValidateFeed(t *testing.T) {
+	processor := NewVideoProcessor()
+	entry := &Entry{
+		VideoID:   "test_video_id",
+		ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+		Published: time.Now().Format(time.RFC3339),
+		Updated:   time.Now().Format(time.RFC3339),
+	}
+	topic := func(channelID string) []Link {
+		return []Link{
+			{Rel: "hub", Href: "https://pubsubhubbub.appspot.com"},
+			{Rel: "self", Href: "https://www.youtube.com/xml/feeds/videos.xml?channel_id=" + channelID},
+		}
+	}
+
+	assert.NoError(t, processor.ValidateFeed(&AtomFeed{Links: topic(entry.ChannelID), Entries: []*Entry{entry}}))
+	assert.NoError(t, processor.ValidateFeed(&AtomFeed{Entries: []*Entry{entry}}), "feeds without a self link are not checked")
+	assert.ErrorIs(t, processor.ValidateFeed(&AtomFeed{Links: topic("UC1234567890123456789012"), Entries: []*Entry{entry}}), ErrChannelMismatch)
+	assert.ErrorIs(t, processor.ValidateFeed(&AtomFeed{}), ErrInvalidEntry)
+
+	second := *entry
+	second.VideoID = "second_video_id"
+	assert.NoError(t, processor.ValidateFeed(&AtomFeed{Links: topic(entry.ChannelID), Entries: []*Entry{entry, &second}}))
+
+	other := second
+	other.ChannelID = "UC1234567890123456789012"
+	assert.ErrorIs(t, processor.ValidateFeed(&AtomFeed{Entries: []*Entry{entry, &other}}), ErrChannelMismatch)
+
+	invalid := second
+	invalid.VideoID = ""
+	assert.ErrorIs(t, processor.ValidateFeed(&AtomFeed{Entries: []*Entry{entry, &invalid}}), ErrMissingVideoID)
+}
+
 func TestVideoProcessor_ValidateEntry(t *testing.T) {
 	processor := NewVideoProcessor()
 
@@ -193,16 +285,61 @@ func TestVideoProcessor_ValidateEntry(t *testing.T) {
 			description: "Entry with missing both IDs should return ErrMissingVideoID (first validation error)",
 		},
 		{
-			name: "valid_entry_with_empty_optional_fields",
+			name: "valid_entry_with_empty_title",
 			entry: &Entry{
 				VideoID:   "test_video_id",
 				ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
 				Title:     "", // Empty title should be OK
-				Published: "", // Empty timestamps should be OK for validation
-				Updated:   "",
+				Published: time.Now().Format(time.RFC3339),
+				Updated:   time.Now().Format(time.RFC3339),
 			},
 			expectedErr: nil,
-			description: "Entry with empty optional fields should pass validation",
+			description: "Entry with an empty title should pass validation",
+		},
+		{
+			name: "empty_timestamps",
+			entry: &Entry{
+				VideoID:   "test_video_id",
+				ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+				Published: "",
+				Updated:   "",
+			},
+			expectedErr: ErrInvalidTimestamp,
+			description: "Entry without timestamps should return ErrInvalidTimestamp",
+		},
+		{
+			name: "malformed_updated_timestamp",
+			entry: &Entry{
+				VideoID:   "test_video_id",
+				ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+				Published: time.Now().Format(time.RFC3339),
+				Updated:   "yesterday",
+			},
+			expectedErr: ErrInvalidTimestamp,
+			description: "Entry with a malformed timestamp should return ErrInvalidTimestamp",
+		},
+		{
+			name: "invalid_channel_id",
+			entry: &Entry{
+				VideoID:   "test_video_id",
+				ChannelID: "../UCXuqSBlHAE6Xw",
+				Published: time.Now().Format(time.RFC3339),
+				Updated:   time.Now().Format(time.RFC3339),
+			},
+			expectedErr: ErrInvalidChannelID,
+			description: "Entry with a malformed channel ID should return ErrInvalidChannelID",
+		},
+		{
+			name: "author_channel_mismatch",
+			entry: &Entry{
+				VideoID:   "test_video_id",
+				ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw",
+				Published: time.Now().Format(time.RFC3339),
+				Updated:   time.Now().Format(time.RFC3339),
+				Author:    Author{Name: "Other", URI: "https://www.youtube.com/channel/UC1234567890123456789012"},
+			},
+			expectedErr: ErrChannelMismatch,
+			description: "Entry whose author is another channel should return ErrChannelMismatch",
 		},
 	}
 
@@ -210,7 +347,7 @@ func TestVideoProcessor_ValidateEntry(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			err := processor.ValidateEntry(tc.entry)
 			if tc.expectedErr != nil {
-				assert.Equal(t, tc.expectedErr, err, tc.description)
+				assert.ErrorIs(t, err, tc.expectedErr, tc.description)
 			} else {
 				assert.NoError(t, err, tc.description)
 			}
@@ -1,24 +1,23 @@
 package webhook
 
 import (
-	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
 	"regexp"
+	"sync"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+	"github.com/samsoir/youtube-webhook/function/config"
 )
 
 // AtomFeed represents the structure of a YouTube Atom feed notification
 type AtomFeed struct {
 	XMLName xml.Name `xml:"feed"`
-	Entry   *Entry   `xml:"entry"`
+	Links   []Link   `xml:"link"`  // Hub and self (topic) links
+	Entries []*Entry `xml:"entry"` // Usually one; the hub may batch several into one notification
 }
 
 // Entry represents a single video entry in the YouTube Atom feed
@@ -28,6 +27,36 @@ type Entry struct {
 	Title     string `xml:"title"`
 	Published string `xml:"published"`
 	Updated   string `xml:"updated"`
+	Link      Link   `xml:"link"`
+	Author    Author `xml:"author"`
+
+	// Premiere state looked up with the YouTube Data API when PREMIERE_HANDLING is enabled
+	LiveBroadcastContent string    `xml:"-"`
+	HeldAt               time.Time `xml:"-"` // When dispatch was held for the premiere to go live
+
+	// Playlist whose feed delivered the entry, from the feed's self link; empty for channel feeds
+	PlaylistID string `xml:"-"`
+}
+
+// SubscriptionID returns the ID of the subscription the entry was delivered for: its
+// playlist's, or else its channel's
+func (e *Entry) SubscriptionID() string {
+	if e.PlaylistID != "" {
+		return e.PlaylistID
+	}
+	return e.ChannelID
+}
+
+// Link is an entry's alternate link, e.g. https://www.youtube.com/watch?v=VIDEO_ID
+type Link struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// Author is the channel that published an entry
+type Author struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
 }
 
 // GitHubDispatch represents the payload structure for GitHub repository dispatch events
@@ -36,10 +65,19 @@ type GitHubDispatch struct {
 	ClientPayload map[string]interface{} `json:"client_payload"`
 }
 
-// Subscription represents a YouTube channel subscription
+// WorkflowDispatch represents the payload structure for GitHub workflow_dispatch events
+type WorkflowDispatch struct {
+	Ref    string            `json:"ref"`
+	Inputs map[string]string `json:"inputs,omitempty"`
+}
+
+// Subscription represents a subscription to a YouTube channel's or playlist's feed.
+// ChannelID holds the playlist ID for playlist subscriptions, so both are stored,
+// routed and managed alike.
 type Subscription struct {
 	ChannelID       string    `json:"channel_id"`
-	ChannelName     string    `json:"channel_name,omitempty"`
+	ChannelName     string    `json:"channel_name,omitempty"` // The playlist's title for playlists
+	TopicType       string    `json:"topic_type,omitempty"`   // channel or playlist; see Type
 	TopicURL        string    `json:"topic_url"`
 	CallbackURL     string    `json:"callback_url"`
 	Status          string    `json:"status"`
@@ -49,106 +87,172 @@ type Subscription struct {
 	LastRenewal     time.Time `json:"last_renewal"`
 	RenewalAttempts int       `json:"renewal_attempts"`
 	HubResponse     string    `json:"hub_response"`
+	VerifiedAt      time.Time `json:"verified_at,omitempty"` // When the hub last confirmed the subscription
+	Secret          string    `json:"secret,omitempty"`      // hub.secret used to verify X-Hub-Signature; never returned by the API
+
+	// When ChannelName was last looked up; renewals look it up again after CHANNEL_NAME_REFRESH_HOURS
+	ChannelNameUpdatedAt time.Time `json:"channel_name_updated_at,omitempty"`
+
+	// Health tracking
+	LastNotificationAt time.Time `json:"last_notification_at"`
+	LastVideoID        string    `json:"last_video_id,omitempty"`
+	LastRenewalStatus  string    `json:"last_renewal_status,omitempty"`
+	LastRenewalError   string    `json:"last_renewal_error,omitempty"`
+
+	// Renewal backoff: the earliest retry after a failed renewal, and when renewal was given up
+	NextRenewalAt time.Time `json:"next_renewal_at,omitempty"`
+	QuarantinedAt time.Time `json:"quarantined_at,omitempty"`
+
+	// The expiry an alert was last sent for; one alert is sent per expiry
+	AlertedExpiry time.Time `json:"alerted_expiry,omitempty"`
+
+	// Automatic re-subscription after the hub stopped delivering, the last one kept when it ends
+	Recovery *RecoveryRecord `json:"recovery,omitempty"`
+
+	// Workflow run triggered for LastVideoID, when run correlation is enabled
+	LastRunID  int64  `json:"last_run_id,omitempty"`
+	LastRunURL string `json:"last_run_url,omitempty"`
+
+	// Repositories notifications are dispatched to; empty uses REPO_OWNER/REPO_NAME
+	Repositories []RepositoryTarget `json:"repositories,omitempty"`
+
+	// Webhook sinks that also receive each new video
+	Sinks []WebhookSink `json:"sinks,omitempty"`
+
+	// Rules new videos must pass before anything is dispatched; nil dispatches every video
+	Filter *NotificationFilter `json:"filter,omitempty"`
+
+	// Lease asked of the hub on every subscribe and renewal; 0 uses SUBSCRIPTION_LEASE_SECONDS
+	RequestedLeaseSeconds int `json:"requested_lease_seconds,omitempty"`
+
+	// Hub the subscription was made with, which renewals and unsubscribing use; empty uses HUB_URL
+	HubURL string `json:"hub_url,omitempty"`
 }
 
 // SubscriptionState represents the complete subscription state stored in Cloud Storage
 type SubscriptionState struct {
 	Subscriptions map[string]*Subscription `json:"subscriptions"`
-	Metadata      struct {
+	// Unsubscribed channels that can still be restored, by channel ID
+	Removed  map[string]*RemovedSubscription `json:"removed,omitempty"`
+	Metadata struct {
 		LastUpdated time.Time `json:"last_updated"`
 		Version     string    `json:"version"`
 	} `json:"metadata"`
+
+	// Channels the state was loaded with, so a save only deletes those the caller
+	// removed; nil for a state not loaded from storage, which replaces it whole
+	loaded map[string]bool
 }
 
 // API Response types
+//
+// APIResponse doubles as the error envelope: errors carry status "error", a
+// machine-readable code, the request ID and, for some errors, details.
 type APIResponse struct {
-	Status    string `json:"status"`
-	ChannelID string `json:"channel_id,omitempty"`
-	Message   string `json:"message,omitempty"`
-	ExpiresAt string `json:"expires_at,omitempty"`
+	Status      string `json:"status"`
+	Code        string `json:"code,omitempty"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	ChannelName string `json:"channel_name,omitempty"`
+	Message     string `json:"message,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	RequestID   string `json:"request_id,omitempty"`
+	HubRetries  int    `json:"hub_retries,omitempty"`  // Transient hub errors retried before the hub answered
+	HubStatus   int    `json:"hub_status,omitempty"`   // The hub's status when it rejected the request
+	HubResponse string `json:"hub_response,omitempty"` // The start of the hub's response text
+
+	Details map[string]interface{} `json:"details,omitempty"` // Machine-readable specifics of an error
 }
 
 type SubscriptionsListResponse struct {
 	Subscriptions []SubscriptionInfo `json:"subscriptions"`
 	Total         int                `json:"total"`
-	Active        int                `json:"active"`
+	Active        int                `json:"active"` // Verified, active or expiring
+	Pending       int                `json:"pending"`
 	Expired       int                `json:"expired"`
+	Quarantined   int                `json:"quarantined"`
+	Healthy       int                `json:"healthy"`
+	Stale         int                `json:"stale"`
+	Broken        int                `json:"broken"`
+	NextPageToken string             `json:"next_page_token,omitempty"` // Set when more subscriptions match
 }
 
 type SubscriptionInfo struct {
 	ChannelID       string  `json:"channel_id"`
+	ChannelName     string  `json:"channel_name,omitempty"`
+	TopicType       string  `json:"topic_type"`
 	Status          string  `json:"status"`
 	ExpiresAt       string  `json:"expires_at"`
 	DaysUntilExpiry float64 `json:"days_until_expiry"`
+	LeaseSeconds    int     `json:"lease_seconds"`
+	RenewalAttempts int     `json:"renewal_attempts"`
+	SubscribedAt    string  `json:"subscribed_at,omitempty"`
+	LastRenewal     string  `json:"last_renewal,omitempty"`
+	LastVideoID     string  `json:"last_video_id,omitempty"`
+	LastRunURL      string  `json:"last_run_url,omitempty"`
+	NextRenewalAt   string  `json:"next_renewal_at,omitempty"` // When a scheduled run will renew it, after any failure backoff
+	HubURL          string  `json:"hub_url,omitempty"`
+	SubscriptionHealth
+}
+
+// SubscriptionDetailResponse is returned by GET /subscriptions/{channel_id}
+type SubscriptionDetailResponse struct {
+	SubscriptionInfo
+	Subscription *Subscription `json:"subscription"`
 }
 
 // Renewal Response types
 type RenewalSummaryResponse struct {
-	Status             string          `json:"status"`
-	TotalChecked       int             `json:"total_checked"`
-	RenewalsCandidates int             `json:"renewals_candidates"`
-	RenewalsSucceeded  int             `json:"renewals_succeeded"`
-	RenewalsFailed     int             `json:"renewals_failed"`
-	Results            []RenewalResult `json:"results"`
+	Status             string           `json:"status"`
+	TotalChecked       int              `json:"total_checked"`
+	RenewalsCandidates int              `json:"renewals_candidates"`
+	RenewalsSucceeded  int              `json:"renewals_succeeded"`
+	RenewalsFailed     int              `json:"renewals_failed"`
+	RenewalsDeferred   int              `json:"renewals_deferred"`    // Candidates left for the next run by RENEWAL_BATCH_SIZE
+	RenewalsBackingOff int              `json:"renewals_backing_off"` // Due, but waiting out the backoff after a failure
+	Quarantined        []string         `json:"quarantined"`          // Channels not renewed until reactivated
+	Results            []RenewalResult  `json:"results"`
+	Report             string           `json:"report,omitempty"`          // Storage object holding this run's report
+	Forced             bool             `json:"forced,omitempty"`          // Threshold and backoff were ignored
+	NextRenewalAt      string           `json:"next_renewal_at,omitempty"` // When the next subscription not yet due falls due
+	ExpiryAlerts       []string         `json:"expiry_alerts,omitempty"`   // Channels alerted about as expiring without renewal
+	Recoveries         []RecoveryResult `json:"recoveries,omitempty"`      // Re-subscribes of subscriptions the hub stopped delivering
 }
 
 type RenewalResult struct {
 	ChannelID     string `json:"channel_id"`
 	Success       bool   `json:"success"`
 	Message       string `json:"message"`
-	NewExpiryTime string `json:"new_expiry_time,omitempty"`
 	AttemptCount  int    `json:"attempt_count"`
+	NextAttemptAt string `json:"next_attempt_at,omitempty"` // When a failed renewal is retried
+	Quarantined   bool   `json:"quarantined,omitempty"`     // This failure quarantined the subscription
+	HubRetries    int    `json:"hub_retries,omitempty"`     // Transient hub errors retried during the attempt
+
+	// The lease's end before the attempt, and the end a successful renewal asked the hub
+	// for, which takes effect once the hub verifies it
+	PreviousExpiresAt  string `json:"previous_expires_at,omitempty"`
+	RequestedExpiresAt string `json:"requested_expires_at,omitempty"`
+
+	// Timing of the renewal attempt, excluding the jitter delay before it
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
 }
 
 // Channel ID validation regex
 var channelIDRegex = regexp.MustCompile(`^UC[a-zA-Z0-9_-]{22}$`)
 
-// StorageInterface defines the contract for subscription state storage operations
-type StorageInterface interface {
-	LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error)
-	SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error
-}
-
-// CloudStorageClient implements StorageInterface using Google Cloud Storage
-type CloudStorageClient struct{}
-
-// CloudStorageClient is the production storage implementation
-// For testing, use dependency injection with MockStorageClient
-
 func init() {
 	functions.HTTP("YouTubeWebhook", YouTubeWebhook)
 }
 
+// instanceStart runs startInstance on an instance's first request. It is not run from
+// init, which every program importing this package runs, the CLI included.
+var instanceStart sync.Once
 
-// handleVerificationChallenge handles YouTube's verification challenge
-func handleVerificationChallenge(w http.ResponseWriter, r *http.Request) {
-	challenge := r.URL.Query().Get("hub.challenge")
-	if challenge == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(challenge)); err != nil {
-		fmt.Printf("Error writing response: %v\n", err)
-	}
-}
-
-
-// Backward compatibility functions for existing tests
-
-// triggerGitHubWorkflow is a backward compatibility function that uses the new GitHubClient
-func triggerGitHubWorkflow(entry *Entry) error {
-	client := NewGitHubClient()
-	repoOwner := os.Getenv("REPO_OWNER")
-	repoName := os.Getenv("REPO_NAME")
-	return client.TriggerWorkflow(repoOwner, repoName, entry)
-}
-
-// isNewVideo is a backward compatibility function that uses the new VideoProcessor
-func isNewVideo(entry *Entry) bool {
-	processor := NewVideoProcessor()
-	return processor.IsNewVideo(entry)
+// startInstance does an instance's cold start work: logging configuration problems and
+// closing the dependencies on shutdown
+func startInstance() {
+	logConfigReport()
+	closeOnShutdown()
 }
 
 // validateChannelID validates YouTube channel ID format
@@ -156,172 +260,55 @@ func validateChannelID(channelID string) bool {
 	return channelIDRegex.MatchString(channelID)
 }
 
-
-// LoadSubscriptionState loads subscription state from Cloud Storage
-func (c *CloudStorageClient) LoadSubscriptionState(ctx context.Context) (*SubscriptionState, error) {
-
-	bucketName := os.Getenv("SUBSCRIPTION_BUCKET")
-	if bucketName == "" {
-		return nil, fmt.Errorf("SUBSCRIPTION_BUCKET environment variable not set")
-	}
-
-	client, err := storage.NewClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create storage client: %v", err)
-	}
-	defer client.Close()
-
-	bucket := client.Bucket(bucketName)
-	obj := bucket.Object("subscriptions/state.json")
-
-	reader, err := obj.NewReader(ctx)
-	if err != nil {
-		// If file doesn't exist, return empty state
-		if err == storage.ErrObjectNotExist {
-			return &SubscriptionState{
-				Subscriptions: make(map[string]*Subscription),
-				Metadata: struct {
-					LastUpdated time.Time `json:"last_updated"`
-					Version     string    `json:"version"`
-				}{
-					LastUpdated: time.Now(),
-					Version:     "1.0",
-				},
-			}, nil
-		}
-		return nil, fmt.Errorf("failed to read state file: %v", err)
-	}
-	defer reader.Close()
-
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read state data: %v", err)
-	}
-
-	var state SubscriptionState
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to parse state JSON: %v", err)
-	}
-
-	// Initialize subscriptions map if nil
-	if state.Subscriptions == nil {
-		state.Subscriptions = make(map[string]*Subscription)
-	}
-
-	return &state, nil
-}
-
-// SaveSubscriptionState saves subscription state to Cloud Storage
-func (c *CloudStorageClient) SaveSubscriptionState(ctx context.Context, state *SubscriptionState) error {
-
-	bucketName := os.Getenv("SUBSCRIPTION_BUCKET")
-	if bucketName == "" {
-		return fmt.Errorf("SUBSCRIPTION_BUCKET environment variable not set")
-	}
-
-	client, err := storage.NewClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create storage client: %v", err)
-	}
-	defer client.Close()
-
-	// Update metadata
-	state.Metadata.LastUpdated = time.Now()
-	if state.Metadata.Version == "" {
-		state.Metadata.Version = "1.0"
-	}
-
-	// Marshal to JSON
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %v", err)
-	}
-
-	bucket := client.Bucket(bucketName)
-	obj := bucket.Object("subscriptions/state.json")
-
-	writer := obj.NewWriter(ctx)
-	writer.ContentType = "application/json"
-
-	if _, err := writer.Write(data); err != nil {
-		writer.Close()
-		return fmt.Errorf("failed to write state data: %v", err)
-	}
-
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to close writer: %v", err)
-	}
-
-	return nil
-}
-
-// writeJSONResponse writes a JSON response with the given status code
+// writeJSONResponse writes a JSON response with the given status code, or YAML when the
+// client's Accept header asked for it. Responses reflect the service's current state, so
+// they are never cached.
 func writeJSONResponse(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Add("Vary", "Accept")
+	if responseFormat(w) == YAMLContentType && writeYAMLResponse(w, statusCode, response) {
+		return
+	}
+	w.Header().Set("Content-Type", JSONContentType)
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		fmt.Printf("Error encoding JSON response: %v\n", err)
 	}
 }
 
-// writeErrorResponse writes a standardized error response
+// writeErrorResponse writes a standardized JSON error envelope for management API handlers
 func writeErrorResponse(w http.ResponseWriter, statusCode int, channelID, message string) {
-	response := APIResponse{
-		Status:  "error",
-		Message: message,
-	}
-	if channelID != "" {
-		response.ChannelID = channelID
-	}
-	writeJSONResponse(w, statusCode, response)
+	writeError(w, nil, statusCode, "", channelID, message)
 }
 
-
-
-
-
-
-// Configuration helper functions
-
+// envConfig parses the configuration from the environment, for code that runs without
+// dependencies. Invalid values take their defaults; logConfigReport reports them.
+func envConfig() *config.Config {
+	cfg, _ := config.FromEnv()
+	return cfg
+}
 
 // getRenewalThreshold returns the time threshold for renewal
 func getRenewalThreshold() time.Duration {
-	thresholdHours := os.Getenv("RENEWAL_THRESHOLD_HOURS")
-	if thresholdHours == "" {
-		return 12 * time.Hour // Default: 12 hours
-	}
-
-	if hours, err := time.ParseDuration(thresholdHours + "h"); err == nil {
-		return hours
-	}
-	return 12 * time.Hour
+	return envConfig().RenewalThreshold
 }
 
 // getMaxRenewalAttempts returns the maximum number of renewal attempts
 func getMaxRenewalAttempts() int {
-	maxAttemptsStr := os.Getenv("MAX_RENEWAL_ATTEMPTS")
-	if maxAttemptsStr == "" {
-		return 3 // Default: 3 attempts
-	}
-
-	var attempts int
-	if _, err := fmt.Sscanf(maxAttemptsStr, "%d", &attempts); err == nil && attempts > 0 {
-		return attempts
-	}
-	return 3
+	return envConfig().MaxRenewalAttempts
 }
 
 // getLeaseSeconds returns the lease duration in seconds
 func getLeaseSeconds() int {
-	leaseSecondsStr := os.Getenv("SUBSCRIPTION_LEASE_SECONDS")
-	if leaseSecondsStr == "" {
-		return 86400 // Default: 24 hours
-	}
+	return envConfig().LeaseSeconds
+}
 
-	var seconds int
-	if _, err := fmt.Sscanf(leaseSecondsStr, "%d", &seconds); err == nil && seconds > 0 {
-		return seconds
-	}
-	return 86400
+// getSweepGracePeriod returns how long after expiry the sweeper still attempts a final re-subscribe
+func getSweepGracePeriod() time.Duration {
+	return envConfig().SweepGracePeriod
 }
 
-// Legacy functions removed - use dependency injection instead
+// getSweepRetention returns how long lapsed subscriptions are kept before being purged
+func getSweepRetention() time.Duration {
+	return envConfig().SweepRetention
+}
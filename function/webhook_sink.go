@@ -0,0 +1,365 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SinkSignatureHeader carries the HMAC-SHA256 of the sink payload when the sink has a secret
+const SinkSignatureHeader = "X-Webhook-Signature"
+
+// SinkEventType identifies video notifications delivered to webhook sinks
+const SinkEventType = "youtube-video-published"
+
+// maxSinksPerChannel caps how many webhook sinks one notification can fan out to
+const maxSinksPerChannel = 10
+
+// sinkRetryDelay is the delay before the first retry; it doubles on each attempt
+var sinkRetryDelay = 500 * time.Millisecond
+
+// WebhookSink is a URL that receives a JSON payload for each new video: SinkPayload for
+// plain webhooks, or a formatted message for Discord and Slack webhooks
+type WebhookSink struct {
+	Type   string `json:"type,omitempty"` // webhook (default), discord or slack
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"` // Signs payloads with HMAC-SHA256 when set; never returned by the API
+}
+
+// SinkPayload is the JSON body POSTed to webhook sinks
+type SinkPayload struct {
+	EventType   string `json:"event_type"`
+	VideoID     string `json:"video_id"`
+	ChannelID   string `json:"channel_id"`
+	PlaylistID  string `json:"playlist_id,omitempty"` // Set when the video came from a playlist's feed
+	Title       string `json:"title"`
+	Published   string `json:"published"`
+	Updated     string `json:"updated"`
+	VideoURL    string `json:"video_url"`
+	DispatchID  string `json:"dispatch_id"`
+	Environment string `json:"environment,omitempty"`
+	DeliveredAt string `json:"delivered_at"`
+}
+
+// SinkResult reports the outcome of delivering to one sink
+type SinkResult struct {
+	URL        string
+	Delivered  bool
+	Attempts   int
+	StatusCode int
+	Err        error
+}
+
+// WebhookSinkClient delivers video payloads to webhook sinks with retries
+type WebhookSinkClient struct {
+	Client      *http.Client
+	MaxAttempts int
+}
+
+// NewWebhookSinkClient creates a sink client; SINK_MAX_ATTEMPTS sets the attempts per sink (default 3)
+func NewWebhookSinkClient() *WebhookSinkClient {
+	return &WebhookSinkClient{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: getSinkMaxAttempts(),
+	}
+}
+
+// getSinkMaxAttempts returns how many times delivery to a sink is attempted
+func getSinkMaxAttempts() int {
+	return envConfig().SinkMaxAttempts
+}
+
+// newSinkPayload builds the payload delivered for a video entry
+func newSinkPayload(ctx context.Context, entry *Entry) SinkPayload {
+	return SinkPayload{
+		EventType:   SinkEventType,
+		VideoID:     entry.VideoID,
+		ChannelID:   entry.ChannelID,
+		PlaylistID:  entry.PlaylistID,
+		Title:       entry.Title,
+		Published:   entry.Published,
+		Updated:     entry.Updated,
+		VideoURL:    fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.VideoID),
+		DispatchID:  dispatchID(entry),
+		Environment: os.Getenv("ENVIRONMENT"),
+		DeliveredAt: currentTime(ctx).UTC().Format(time.RFC3339),
+	}
+}
+
+// signSinkPayload returns the X-Webhook-Signature value ("sha256=<hex>") for body
+func signSinkPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverAll delivers the entry to every sink concurrently and returns results in sink order
+func (sc *WebhookSinkClient) DeliverAll(ctx context.Context, sinks []WebhookSink, entry *Entry) []SinkResult {
+	payload := newSinkPayload(ctx, entry)
+	results := make([]SinkResult, len(sinks))
+
+	var wg sync.WaitGroup
+	for i, sink := range sinks {
+		var body []byte
+		var err error
+		if sink.sinkType() == SinkTypeWebhook {
+			body, err = json.Marshal(payload)
+		} else {
+			body, err = chatSinkBody(sink.sinkType(), entry)
+		}
+		if err != nil {
+			results[i] = SinkResult{URL: sink.label(), Err: fmt.Errorf("failed to marshal payload: %v", err)}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, sink WebhookSink, body []byte) {
+			defer wg.Done()
+			results[i] = sc.deliver(ctx, sink, body)
+		}(i, sink, body)
+	}
+	wg.Wait()
+	return results
+}
+
+// deliver posts body to one sink, retrying network errors, 429 and 5xx responses with backoff
+func (sc *WebhookSinkClient) deliver(ctx context.Context, sink WebhookSink, body []byte) SinkResult {
+	result := SinkResult{URL: sink.label()}
+	delay := sinkRetryDelay
+
+	for result.Attempts < sc.MaxAttempts {
+		if result.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				return result
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		result.Attempts++
+
+		retryable, err := sc.post(ctx, sink, body, &result)
+		if err == nil {
+			result.Delivered = true
+			result.Err = nil
+			return result
+		}
+		result.Err = err
+		if !retryable {
+			return result
+		}
+	}
+	return result
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying
+func (sc *WebhookSinkClient) post(ctx context.Context, sink WebhookSink, body []byte, result *SinkResult) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "youtube-webhook")
+	if sink.Secret != "" {
+		req.Header.Set(SinkSignatureHeader, signSinkPayload(sink.Secret, body))
+	}
+
+	resp, err := sc.Client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("sink returned status %d: %s", resp.StatusCode, string(respBody))
+}
+
+// validateWebhookSinks checks a channel's sinks before they are stored
+func validateWebhookSinks(sinks []WebhookSink) error {
+	if len(sinks) > maxSinksPerChannel {
+		return fmt.Errorf("at most %d webhook sinks can be configured per channel", maxSinksPerChannel)
+	}
+
+	seen := make(map[string]bool)
+	for _, sink := range sinks {
+		switch sink.sinkType() {
+		case SinkTypeWebhook:
+		case SinkTypeDiscord, SinkTypeSlack:
+			if sink.Secret != "" {
+				return fmt.Errorf("sink %s: secret is only used with webhook sinks", sink.label())
+			}
+		default:
+			return fmt.Errorf("invalid sink type %q: must be %s, %s or %s", sink.Type, SinkTypeWebhook, SinkTypeDiscord, SinkTypeSlack)
+		}
+		parsed, err := url.Parse(sink.URL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("invalid sink url %q: a valid http(s) url is required", sink.label())
+		}
+		if seen[sink.URL] {
+			return fmt.Errorf("sink %s is listed more than once", sink.label())
+		}
+		seen[sink.URL] = true
+	}
+	return nil
+}
+
+// resolveSinks returns the webhook sinks configured for a channel, followed by the
+// global Discord and Slack sinks it does not already list
+func resolveSinks(ctx context.Context, storage StorageService, channelID string) []WebhookSink {
+	var sinks []WebhookSink
+	if storage != nil {
+		sub, err := storage.GetSubscription(ctx, channelID)
+		if err != nil && !errors.Is(err, ErrSubscriptionNotFound) {
+			fmt.Printf("Unable to load webhook sinks for %s: %v\n", channelID, err)
+		}
+		if sub != nil {
+			sinks = append(sinks, sub.Sinks...)
+		}
+	}
+
+	for _, global := range globalSinks() {
+		listed := false
+		for _, sink := range sinks {
+			listed = listed || sink.URL == global.URL
+		}
+		if !listed {
+			sinks = append(sinks, global)
+		}
+	}
+	return sinks
+}
+
+// summarizeSinkResults logs failed deliveries and returns a short summary for the notification response
+func summarizeSinkResults(entry *Entry, results []SinkResult) string {
+	delivered := 0
+	var failed []string
+	for _, result := range results {
+		if result.Delivered {
+			delivered++
+			continue
+		}
+		fmt.Printf("Webhook sink %s failed for video %s after %d attempt(s): %v\n",
+			result.URL, entry.VideoID, result.Attempts, result.Err)
+		failed = append(failed, result.URL)
+	}
+
+	summary := fmt.Sprintf("webhook sinks: %d/%d delivered", delivered, len(results))
+	if len(failed) > 0 {
+		summary += fmt.Sprintf(", failed: %s", strings.Join(failed, ", "))
+	}
+	return summary
+}
+
+// redactSinks returns sinks with their secrets and chat webhook tokens removed for API responses
+func redactSinks(sinks []WebhookSink) []WebhookSink {
+	if sinks == nil {
+		return nil
+	}
+	redacted := make([]WebhookSink, len(sinks))
+	for i, sink := range sinks {
+		redacted[i] = WebhookSink{Type: sink.Type, URL: sink.maskedURL()}
+	}
+	return redacted
+}
+
+// WebhookSinksRequest is the body of PUT /subscriptions/{channel_id}/sinks
+type WebhookSinksRequest struct {
+	Sinks []WebhookSink `json:"sinks"`
+}
+
+// WebhookSinkInfo describes a configured sink without its secret. Discord and Slack URLs
+// have their token masked.
+type WebhookSinkInfo struct {
+	Type   string `json:"type,omitempty"`
+	URL    string `json:"url"`
+	Signed bool   `json:"signed"`
+}
+
+// WebhookSinksResponse reports the sinks configured for a channel
+type WebhookSinksResponse struct {
+	Status    string            `json:"status"`
+	ChannelID string            `json:"channel_id"`
+	Sinks     []WebhookSinkInfo `json:"sinks"`
+}
+
+// handleSetSinks handles PUT /subscriptions/{channel_id}/sinks requests using dependency injection.
+// The list replaces the channel's sinks; an empty list removes them.
+func handleSetSinks(deps *Dependencies, channelID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if !validateTopicID(channelID) {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Invalid channel ID format")
+			return
+		}
+
+		var req WebhookSinksRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, "Request body must be a JSON list of sinks")
+			return
+		}
+		if err := validateWebhookSinks(req.Sinks); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, channelID, err.Error())
+			return
+		}
+
+		sub, err := deps.StorageClient.GetSubscription(ctx, channelID)
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, channelID, "Subscription not found for this channel")
+			return
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to load subscription state: %v", err))
+			return
+		}
+
+		sub.Sinks = req.Sinks
+		if len(sub.Sinks) == 0 {
+			sub.Sinks = nil
+		}
+		if err := deps.StorageClient.SaveSubscription(ctx, sub); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, channelID,
+				fmt.Sprintf("Failed to save subscription state: %v", err))
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, WebhookSinksResponse{
+			Status:    "success",
+			ChannelID: channelID,
+			Sinks:     sinkInfos(sub.Sinks),
+		})
+	}
+}
+
+// sinkInfos describes configured sinks for API responses, without their secrets
+func sinkInfos(sinks []WebhookSink) []WebhookSinkInfo {
+	infos := make([]WebhookSinkInfo, 0, len(sinks))
+	for i, redacted := range redactSinks(sinks) {
+		infos = append(infos, WebhookSinkInfo{
+			Type:   redacted.Type,
+			URL:    redacted.URL,
+			Signed: sinks[i].Secret != "",
+		})
+	}
+	return infos
+}
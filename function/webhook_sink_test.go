@@ -0,0 +1,420 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sinkChannelID = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+// useFastSinkRetries shortens the sink retry delay for the duration of a test
+func useFastSinkRetries(t *testing.T) {
+	original := sinkRetryDelay
+	sinkRetryDelay = time.Millisecond
+	t.Cleanup(func() { sinkRetryDelay = original })
+}
+
+func newSinkTestEntry() *Entry {
+	return &Entry{
+		VideoID:   "sink123",
+		ChannelID: sinkChannelID,
+		Title:     "Sink Video",
+		Published: "2025-01-21T12:00:00Z",
+		Updated:   "2025-01-21T12:05:00Z",
+	}
+}
+
+func TestGetSinkMaxAttempts(t *testing.T) {
+	defer os.Unsetenv("SINK_MAX_ATTEMPTS")
+
+	testCases := []struct {
+		value    string
+		expected int
+	}{
+		{"", 3},
+		{"5", 5},
+		{"0", 3},
+		{"invalid", 3},
+	}
+
+	for _, tc := range testCases {
+		os.Setenv("SINK_MAX_ATTEMPTS", tc.value)
+		assert.Equal(t, tc.expected, getSinkMaxAttempts(), "SINK_MAX_ATTEMPTS=%q", tc.value)
+	}
+}
+
+func TestWebhookSinkClient_DeliverAll(t *testing.T) {
+	useFastSinkRetries(t)
+	ctx := context.Background()
+	entry := newSinkTestEntry()
+
+	t.Run("delivers_signed_payload", func(t *testing.T) {
+		var payload SinkPayload
+		var signature string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			signature = r.Header.Get(SinkSignatureHeader)
+			assert.Equal(t, signSinkPayload("sink-secret", body), signature)
+			assert.NoError(t, json.Unmarshal(body, &payload))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client := &WebhookSinkClient{Client: server.Client(), MaxAttempts: 3}
+		results := client.DeliverAll(ctx, []WebhookSink{{URL: server.URL, Secret: "sink-secret"}}, entry)
+
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Delivered)
+		assert.Equal(t, 1, results[0].Attempts)
+		assert.Equal(t, http.StatusNoContent, results[0].StatusCode)
+		assert.True(t, strings.HasPrefix(signature, "sha256="))
+		assert.Equal(t, SinkEventType, payload.EventType)
+		assert.Equal(t, "sink123", payload.VideoID)
+		assert.Equal(t, sinkChannelID, payload.ChannelID)
+		assert.Equal(t, "Sink Video", payload.Title)
+		assert.Equal(t, dispatchID(entry), payload.DispatchID)
+	})
+
+	t.Run("unsigned_without_secret", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.Header.Get(SinkSignatureHeader))
+		}))
+		defer server.Close()
+
+		client := &WebhookSinkClient{Client: server.Client(), MaxAttempts: 3}
+		results := client.DeliverAll(ctx, []WebhookSink{{URL: server.URL}}, entry)
+		assert.True(t, results[0].Delivered)
+	})
+
+	t.Run("retries_server_errors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := &WebhookSinkClient{Client: server.Client(), MaxAttempts: 3}
+		results := client.DeliverAll(ctx, []WebhookSink{{URL: server.URL}}, entry)
+
+		assert.True(t, results[0].Delivered)
+		assert.Equal(t, 3, results[0].Attempts)
+		assert.NoError(t, results[0].Err)
+	})
+
+	t.Run("gives_up_after_max_attempts", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := &WebhookSinkClient{Client: server.Client(), MaxAttempts: 2}
+		results := client.DeliverAll(ctx, []WebhookSink{{URL: server.URL}}, entry)
+
+		assert.False(t, results[0].Delivered)
+		assert.Equal(t, 2, results[0].Attempts)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		assert.Contains(t, results[0].Err.Error(), "status 502")
+	})
+
+	t.Run("does_not_retry_client_errors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := &WebhookSinkClient{Client: server.Client(), MaxAttempts: 3}
+		results := client.DeliverAll(ctx, []WebhookSink{{URL: server.URL}}, entry)
+
+		assert.False(t, results[0].Delivered)
+		assert.Equal(t, 1, results[0].Attempts)
+		assert.Equal(t, http.StatusNotFound, results[0].StatusCode)
+	})
+
+	t.Run("retries_network_errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		unreachable := server.URL
+		server.Close()
+
+		client := &WebhookSinkClient{Client: &http.Client{Timeout: time.Second}, MaxAttempts: 2}
+		results := client.DeliverAll(ctx, []WebhookSink{{URL: unreachable}}, entry)
+
+		assert.False(t, results[0].Delivered)
+		assert.Equal(t, 2, results[0].Attempts)
+	})
+
+	t.Run("results_follow_sink_order", func(t *testing.T) {
+		ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer ok.Close()
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer failing.Close()
+
+		client := &WebhookSinkClient{Client: &http.Client{Timeout: time.Second}, MaxAttempts: 1}
+		results := client.DeliverAll(ctx, []WebhookSink{{URL: failing.URL}, {URL: ok.URL}}, entry)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, failing.URL, results[0].URL)
+		assert.False(t, results[0].Delivered)
+		assert.Equal(t, ok.URL, results[1].URL)
+		assert.True(t, results[1].Delivered)
+	})
+}
+
+func TestValidateWebhookSinks(t *testing.T) {
+	tooMany := make([]WebhookSink, maxSinksPerChannel+1)
+	for i := range tooMany {
+		tooMany[i] = WebhookSink{URL: fmt.Sprintf("https://example.com/%d", i)}
+	}
+
+	testCases := []struct {
+		name        string
+		sinks       []WebhookSink
+		expectError bool
+	}{
+		{"empty_list", nil, false},
+		{"valid_sinks", []WebhookSink{{URL: "https://example.com/hook", Secret: "s"}, {URL: "http://internal:8080/x"}}, false},
+		{"missing_scheme", []WebhookSink{{URL: "example.com/hook"}}, true},
+		{"unsupported_scheme", []WebhookSink{{URL: "ftp://example.com/hook"}}, true},
+		{"duplicate_url", []WebhookSink{{URL: "https://example.com/hook"}, {URL: "https://example.com/hook"}}, true},
+		{"too_many", tooMany, true},
+		{"chat_sinks", []WebhookSink{{Type: SinkTypeDiscord, URL: "https://discord.com/api/webhooks/1/abc"}, {Type: SinkTypeSlack, URL: "https://hooks.slack.com/services/T/B/abc"}}, false},
+		{"chat_sink_with_secret", []WebhookSink{{Type: SinkTypeSlack, URL: "https://hooks.slack.com/services/T/B/abc", Secret: "s"}}, true},
+		{"unknown_type", []WebhookSink{{Type: "teams", URL: "https://example.com/hook"}}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateWebhookSinks(tc.sinks)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSummarizeSinkResults(t *testing.T) {
+	summary := summarizeSinkResults(newSinkTestEntry(), []SinkResult{
+		{URL: "https://a.example.com", Delivered: true, Attempts: 1},
+		{URL: "https://b.example.com", Attempts: 3, Err: fmt.Errorf("sink returned status 500")},
+	})
+	assert.Equal(t, "webhook sinks: 1/2 delivered, failed: https://b.example.com", summary)
+}
+
+func TestHandleSetSinks(t *testing.T) {
+	put := func(deps *Dependencies, channelID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/subscriptions/"+channelID+"/sinks", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handleSetSinks(deps, channelID)(rec, req)
+		return rec
+	}
+
+	t.Run("sets_sinks_without_exposing_secrets", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.SetState(newSweepTestState(&Subscription{ChannelID: sinkChannelID}))
+
+		rec := put(deps, sinkChannelID, `{"sinks":[{"url":"https://example.com/hook","secret":"top-secret"},{"url":"https://example.org/hook"}]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "top-secret")
+
+		var response WebhookSinksResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, []WebhookSinkInfo{
+			{URL: "https://example.com/hook", Signed: true},
+			{URL: "https://example.org/hook", Signed: false},
+		}, response.Sinks)
+
+		saved := storage.GetState().Subscriptions[sinkChannelID]
+		assert.Equal(t, "top-secret", saved.Sinks[0].Secret)
+	})
+
+	t.Run("empty_list_removes_sinks", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		storage := deps.StorageClient.(*MockStorageClient)
+		storage.SetState(newSweepTestState(&Subscription{
+			ChannelID: sinkChannelID,
+			Sinks:     []WebhookSink{{URL: "https://example.com/hook"}},
+		}))
+
+		rec := put(deps, sinkChannelID, `{"sinks":[]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, storage.GetState().Subscriptions[sinkChannelID].Sinks)
+	})
+
+	t.Run("error_responses", func(t *testing.T) {
+		testCases := []struct {
+			name           string
+			channelID      string
+			body           string
+			loadError      error
+			saveError      error
+			expectedStatus int
+		}{
+			{"invalid_channel", "bad", `{"sinks":[]}`, nil, nil, http.StatusBadRequest},
+			{"invalid_json", sinkChannelID, `not json`, nil, nil, http.StatusBadRequest},
+			{"invalid_url", sinkChannelID, `{"sinks":[{"url":"not a url"}]}`, nil, nil, http.StatusBadRequest},
+			{"not_subscribed", "UC0000000000000000000000", `{"sinks":[]}`, nil, nil, http.StatusNotFound},
+			{"load_failure", sinkChannelID, `{"sinks":[]}`, ErrMockLoadFailure, nil, http.StatusInternalServerError},
+			{"save_failure", sinkChannelID, `{"sinks":[]}`, nil, ErrMockSaveFailure, http.StatusInternalServerError},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				deps := CreateTestDependencies()
+				storage := deps.StorageClient.(*MockStorageClient)
+				storage.SetState(newSweepTestState(&Subscription{ChannelID: sinkChannelID}))
+				storage.LoadError = tc.loadError
+				storage.SaveError = tc.saveError
+
+				assert.Equal(t, tc.expectedStatus, put(deps, tc.channelID, tc.body).Code)
+			})
+		}
+	})
+}
+
+func TestHandleNotification_WebhookSinks(t *testing.T) {
+	useFastSinkRetries(t)
+	os.Setenv("REPO_OWNER", "test-owner")
+	os.Setenv("REPO_NAME", "test-repo")
+	defer func() {
+		os.Unsetenv("REPO_OWNER")
+		os.Unsetenv("REPO_NAME")
+	}()
+
+	now := time.Now()
+	notification := fmt.Sprintf(`<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>sink123</yt:videoId>
+    <yt:channelId>%s</yt:channelId>
+    <title>Sink Video</title>
+    <published>%s</published>
+    <updated>%s</updated>
+  </entry>
+</feed>`, sinkChannelID, now.Add(-5*time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	sinkDeps := func() *Dependencies {
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: sinkChannelID,
+			Sinks:     []WebhookSink{{URL: server.URL}},
+		}))
+		return deps
+	}
+
+	t.Run("delivers_alongside_github", func(t *testing.T) {
+		atomic.StoreInt32(&received, 0)
+		deps := sinkDeps()
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Successfully triggered workflow")
+		assert.Contains(t, rec.Body.String(), "(webhook sinks: 1/1 delivered)")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&received))
+		assert.Equal(t, 1, deps.GitHubClient.(*MockGitHubClient).GetTriggerCallCount())
+	})
+
+	t.Run("delivers_without_github_token", func(t *testing.T) {
+		atomic.StoreInt32(&received, 0)
+		deps := sinkDeps()
+		deps.GitHubClient.(*MockGitHubClient).SetConfigured(false)
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "GitHub token not configured")
+		assert.Contains(t, rec.Body.String(), "(webhook sinks: 1/1 delivered)")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&received))
+	})
+
+	t.Run("sink_failure_reports_multi_status", func(t *testing.T) {
+		os.Setenv("SINK_MAX_ATTEMPTS", "1")
+		defer os.Unsetenv("SINK_MAX_ATTEMPTS")
+
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		deps := CreateTestDependencies()
+		deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+			ChannelID: sinkChannelID,
+			Sinks:     []WebhookSink{{URL: failing.URL}},
+		}))
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, http.StatusMultiStatus, rec.Code)
+		var result NotificationResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, "success", result.Status)
+		assert.Contains(t, result.Message, "webhook sinks: 0/1 delivered, failed: "+failing.URL)
+		require.Len(t, result.Deliveries, 3)
+		failed := result.Deliveries[0]
+		assert.Equal(t, SinkTypeWebhook, failed.Type)
+		assert.Equal(t, failing.URL, failed.Target)
+		assert.Equal(t, DeliveryFailed, failed.Status)
+		assert.Equal(t, 1, failed.Attempts)
+		assert.Contains(t, failed.Error, "500")
+		assert.Equal(t, SinkDelivery{Type: "pubsub", Target: "video events", Status: DeliveryDelivered}, result.Deliveries[1])
+		assert.Equal(t, SinkDelivery{Type: TargetTypeGitHub, Target: "test-owner/test-repo", Status: DeliveryDelivered}, result.Deliveries[2])
+	})
+
+	t.Run("no_sinks_leaves_message_unchanged", func(t *testing.T) {
+		deps := CreateTestDependencies()
+		subscribeTestChannels(deps, sinkChannelID)
+
+		rec := httptest.NewRecorder()
+		handleNotification(deps)(rec, httptest.NewRequest("POST", "/", strings.NewReader(notification)))
+
+		assert.Equal(t, "Successfully triggered workflow for new video: sink123", rec.Body.String())
+	})
+}
+
+func TestHandleGetSubscription_RedactsSinkSecrets(t *testing.T) {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID: sinkChannelID,
+		Sinks:     []WebhookSink{{URL: "https://example.com/hook", Secret: "top-secret"}},
+	}))
+
+	rec := httptest.NewRecorder()
+	handleGetSubscription(deps, sinkChannelID)(rec, httptest.NewRequest("GET", "/subscriptions/"+sinkChannelID, nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "https://example.com/hook")
+	assert.NotContains(t, rec.Body.String(), "top-secret")
+}
@@ -3,15 +3,42 @@ package webhook
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 )
 
+const verificationTestChannel = "UCXuqSBlHAE6Xw-yeJA0Tunw"
+
+// newVerificationTestDeps returns test dependencies with a pending subscription to verificationTestChannel
+func newVerificationTestDeps() *Dependencies {
+	deps := CreateTestDependencies()
+	deps.StorageClient.(*MockStorageClient).SetState(newSweepTestState(&Subscription{
+		ChannelID:   verificationTestChannel,
+		TopicURL:    "https://www.youtube.com/feeds/videos.xml?channel_id=" + verificationTestChannel,
+		Status:      StatusPending,
+		LastRenewal: time.Now(),
+	}))
+	return deps
+}
+
+// verificationRequest builds a hub verification request for channelID
+func verificationRequest(challenge, mode, channelID, leaseSeconds string) *http.Request {
+	query := url.Values{}
+	query.Set("hub.challenge", challenge)
+	query.Set("hub.mode", mode)
+	query.Set("hub.topic", "https://www.youtube.com/feeds/videos.xml?channel_id="+channelID)
+	if leaseSeconds != "" {
+		query.Set("hub.lease_seconds", leaseSeconds)
+	}
+	return httptest.NewRequest("GET", "/?"+query.Encode(), nil)
+}
+
 func TestHandleVerificationChallenge_Success(t *testing.T) {
-	// Create request with challenge parameter
-	req := httptest.NewRequest("GET", "/?hub.challenge=test-challenge-123", nil)
+	req := verificationRequest("test-challenge-123", "subscribe", verificationTestChannel, "")
 	w := httptest.NewRecorder()
 
-	handleVerificationChallenge(w, req)
+	handleVerificationChallenge(newVerificationTestDeps())(w, req)
 
 	resp := w.Result()
 	if resp.StatusCode != http.StatusOK {
@@ -29,7 +56,7 @@ func TestHandleVerificationChallenge_MissingChallenge(t *testing.T) {
 	req := httptest.NewRequest("GET", "/", nil)
 	w := httptest.NewRecorder()
 
-	handleVerificationChallenge(w, req)
+	handleVerificationChallenge(newVerificationTestDeps())(w, req)
 
 	resp := w.Result()
 	if resp.StatusCode != http.StatusBadRequest {
@@ -38,11 +65,10 @@ func TestHandleVerificationChallenge_MissingChallenge(t *testing.T) {
 }
 
 func TestHandleVerificationChallenge_EmptyChallenge(t *testing.T) {
-	// Create request with empty challenge parameter
-	req := httptest.NewRequest("GET", "/?hub.challenge=", nil)
+	req := verificationRequest("", "subscribe", verificationTestChannel, "")
 	w := httptest.NewRecorder()
 
-	handleVerificationChallenge(w, req)
+	handleVerificationChallenge(newVerificationTestDeps())(w, req)
 
 	resp := w.Result()
 	if resp.StatusCode != http.StatusBadRequest {
@@ -53,10 +79,10 @@ func TestHandleVerificationChallenge_EmptyChallenge(t *testing.T) {
 func TestHandleVerificationChallenge_LongChallenge(t *testing.T) {
 	// Test with a longer challenge string
 	longChallenge := "test-challenge-with-very-long-string-abcdefghijklmnopqrstuvwxyz-123456789"
-	req := httptest.NewRequest("GET", "/?hub.challenge="+longChallenge, nil)
+	req := verificationRequest(longChallenge, "subscribe", verificationTestChannel, "")
 	w := httptest.NewRecorder()
 
-	handleVerificationChallenge(w, req)
+	handleVerificationChallenge(newVerificationTestDeps())(w, req)
 
 	resp := w.Result()
 	if resp.StatusCode != http.StatusOK {
@@ -72,10 +98,10 @@ func TestHandleVerificationChallenge_LongChallenge(t *testing.T) {
 func TestHandleVerificationChallenge_SpecialCharacters(t *testing.T) {
 	// Test with characters that are safe in URL query parameters
 	challenge := "test-challenge-with-safe-chars_123"
-	req := httptest.NewRequest("GET", "/?hub.challenge="+challenge, nil)
+	req := verificationRequest(challenge, "subscribe", verificationTestChannel, "")
 	w := httptest.NewRecorder()
 
-	handleVerificationChallenge(w, req)
+	handleVerificationChallenge(newVerificationTestDeps())(w, req)
 
 	resp := w.Result()
 	if resp.StatusCode != http.StatusOK {
@@ -86,4 +112,255 @@ func TestHandleVerificationChallenge_SpecialCharacters(t *testing.T) {
 	if body != challenge {
 		t.Errorf("Expected body '%s', got '%s'", challenge, body)
 	}
-}
\ No newline at end of file
+}
+
+func TestHandleVerificationChallenge_RejectsInvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name           string
+		target         string
+		expectedStatus int
+	}{
+		{
+			name:           "missing_mode",
+			target:         "/?hub.challenge=abc&hub.topic=https://www.youtube.com/feeds/videos.xml?channel_id=" + verificationTestChannel,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown_mode",
+			target:         "/?hub.challenge=abc&hub.mode=bogus&hub.topic=https://www.youtube.com/feeds/videos.xml?channel_id=" + verificationTestChannel,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing_topic",
+			target:         "/?hub.challenge=abc&hub.mode=subscribe",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown_topic",
+			target:         verificationRequest("abc", "subscribe", "UCabcdefghijklmnopqrstuv", "").URL.String(),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "topic_mismatch",
+			target:         "/?hub.challenge=abc&hub.mode=subscribe&hub.topic=" + url.QueryEscape("https://example.com/feed?channel_id="+verificationTestChannel),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unrequested_unsubscribe",
+			target:         verificationRequest("abc", "unsubscribe", verificationTestChannel, "").URL.String(),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleVerificationChallenge(newVerificationTestDeps())(w, httptest.NewRequest("GET", tc.target, nil))
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, w.Code)
+			}
+			if w.Body.String() == "abc" {
+				t.Error("Challenge should not be echoed for rejected verifications")
+			}
+		})
+	}
+}
+
+func TestHandleVerificationChallenge_RecordsLease(t *testing.T) {
+	deps := newVerificationTestDeps()
+	w := httptest.NewRecorder()
+
+	handleVerificationChallenge(deps)(w, verificationRequest("abc", "subscribe", verificationTestChannel, "432000"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[verificationTestChannel]
+	if sub.Status != "verified" {
+		t.Errorf("Expected status 'verified', got '%s'", sub.Status)
+	}
+	if sub.VerifiedAt.IsZero() {
+		t.Error("Expected VerifiedAt to be set")
+	}
+	if sub.LeaseSeconds != 432000 {
+		t.Errorf("Expected lease 432000, got %d", sub.LeaseSeconds)
+	}
+	if expected := sub.VerifiedAt.Add(5 * 24 * time.Hour); !sub.ExpiresAt.Equal(expected) {
+		t.Errorf("Expected expiry %v, got %v", expected, sub.ExpiresAt)
+	}
+}
+
+func TestHandleVerificationChallenge_InvalidLease(t *testing.T) {
+	t.Run("first_verification_uses_configured_lease", func(t *testing.T) {
+		deps := newVerificationTestDeps()
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, verificationRequest("abc", "subscribe", verificationTestChannel, "soon"))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[verificationTestChannel]
+		if sub.LeaseSeconds != getLeaseSeconds() || sub.ExpiresAt.IsZero() {
+			t.Errorf("Expected configured lease, got %d expiring %v", sub.LeaseSeconds, sub.ExpiresAt)
+		}
+	})
+
+	t.Run("reverification_keeps_expiry", func(t *testing.T) {
+		deps := newVerificationTestDeps()
+		storage := deps.StorageClient.(*MockStorageClient)
+		state := storage.GetState()
+		expiresAt := time.Now().Add(6 * time.Hour)
+		state.Subscriptions[verificationTestChannel].Status = StatusActive
+		state.Subscriptions[verificationTestChannel].LeaseSeconds = 86400
+		state.Subscriptions[verificationTestChannel].ExpiresAt = expiresAt
+		storage.SetState(state)
+
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, verificationRequest("abc", "subscribe", verificationTestChannel, ""))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		sub := storage.GetState().Subscriptions[verificationTestChannel]
+		if sub.Status != StatusActive {
+			t.Errorf("Expected active subscription to stay active, got '%s'", sub.Status)
+		}
+		if sub.LeaseSeconds != 86400 || !sub.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("Expected lease and expiry unchanged, got %d and %v", sub.LeaseSeconds, sub.ExpiresAt)
+		}
+	})
+}
+
+func TestHandleVerificationChallenge_ConfirmsRequestedUnsubscribe(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	handleVerificationChallenge(CreateTestDependencies())(w, verificationRequest("abc", "unsubscribe", verificationTestChannel, ""))
+
+	if w.Code != http.StatusOK || w.Body.String() != "abc" {
+		t.Errorf("Expected challenge to be echoed, got %d '%s'", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleVerificationChallenge_StorageErrors(t *testing.T) {
+	t.Run("load_failure", func(t *testing.T) {
+		deps := newVerificationTestDeps()
+		deps.StorageClient.(*MockStorageClient).LoadError = ErrMockLoadFailure
+
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, verificationRequest("abc", "subscribe", verificationTestChannel, ""))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", w.Code)
+		}
+	})
+
+	t.Run("save_failure_still_confirms", func(t *testing.T) {
+		deps := newVerificationTestDeps()
+		deps.StorageClient.(*MockStorageClient).SaveError = ErrMockSaveFailure
+
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, verificationRequest("abc", "subscribe", verificationTestChannel, "3600"))
+
+		if w.Code != http.StatusOK || w.Body.String() != "abc" {
+			t.Errorf("Expected challenge to be echoed, got %d '%s'", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestHandleVerificationChallenge_Denied(t *testing.T) {
+	denial := func(channelID, reason string) *http.Request {
+		query := url.Values{}
+		query.Set("hub.mode", "denied")
+		query.Set("hub.topic", "https://www.youtube.com/feeds/videos.xml?channel_id="+channelID)
+		if reason != "" {
+			query.Set("hub.reason", reason)
+		}
+		return httptest.NewRequest("GET", "/?"+query.Encode(), nil)
+	}
+
+	t.Run("quarantines_tracked_subscription", func(t *testing.T) {
+		t.Setenv("RECOVERY_MAX_ATTEMPTS", "0")
+		deps := newVerificationTestDeps()
+		deps.Metrics = NewMetrics()
+
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, denial(verificationTestChannel, "topic not allowed"))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[verificationTestChannel]
+		if sub.Status != StatusQuarantined || sub.QuarantinedAt.IsZero() {
+			t.Errorf("Expected the subscription to be quarantined, got %q", sub.Status)
+		}
+		if sub.LastRenewalError != "hub denied the subscription: topic not allowed" {
+			t.Errorf("Expected the hub's reason to be recorded, got %q", sub.LastRenewalError)
+		}
+		if sub.ExpiresAt.IsZero() || sub.ExpiresAt.After(time.Now()) {
+			t.Errorf("Expected the lease to end at the denial, got %v", sub.ExpiresAt)
+		}
+		if got := deps.Metrics.CounterValue(metricHubCallbacks, "mode", "denied", "result", "quarantined"); got != 1 {
+			t.Errorf("Expected one quarantining denial counted, got %v", got)
+		}
+	})
+
+	t.Run("acknowledges_untracked_channel", func(t *testing.T) {
+		deps := newVerificationTestDeps()
+		deps.Metrics = NewMetrics()
+
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, denial("UCabcdefghijklmnopqrstuv", ""))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", w.Code)
+		}
+		if got := deps.Metrics.CounterValue(metricHubCallbacks, "mode", "denied", "result", "ignored"); got != 1 {
+			t.Errorf("Expected one ignored denial counted, got %v", got)
+		}
+		if sub := deps.StorageClient.(*MockStorageClient).GetState().Subscriptions[verificationTestChannel]; sub.Status != StatusPending {
+			t.Errorf("Expected other subscriptions untouched, got %q", sub.Status)
+		}
+	})
+
+	t.Run("invalid_topic", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(newVerificationTestDeps())(w, httptest.NewRequest("GET", "/?hub.mode=denied", nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("save_failure", func(t *testing.T) {
+		deps := newVerificationTestDeps()
+		deps.StorageClient.(*MockStorageClient).SaveError = ErrMockSaveFailure
+
+		w := httptest.NewRecorder()
+		handleVerificationChallenge(deps)(w, denial(verificationTestChannel, ""))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500 so the hub retries, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleVerificationChallenge_CountsVerifications(t *testing.T) {
+	deps := newVerificationTestDeps()
+	deps.Metrics = NewMetrics()
+
+	handleVerificationChallenge(deps)(httptest.NewRecorder(), verificationRequest("abc", "subscribe", verificationTestChannel, "3600"))
+	handleVerificationChallenge(deps)(httptest.NewRecorder(), verificationRequest("abc", "unsubscribe", verificationTestChannel, ""))
+	handleVerificationChallenge(deps)(httptest.NewRecorder(), verificationRequest("abc", "unsubscribe", "UCabcdefghijklmnopqrstuv", ""))
+
+	for _, tc := range []struct{ mode, result string }{
+		{"subscribe", "confirmed"},
+		{"unsubscribe", "refused"},
+		{"unsubscribe", "confirmed"},
+	} {
+		if got := deps.Metrics.CounterValue(metricHubCallbacks, "mode", tc.mode, "result", tc.result); got != 1 {
+			t.Errorf("Expected one %s verification %s, got %v", tc.mode, tc.result, got)
+		}
+	}
+}
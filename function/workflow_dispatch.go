@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dispatch modes a repository route can use
+const (
+	DispatchModeRepository = "repository_dispatch"
+	DispatchModeWorkflow   = "workflow_dispatch"
+)
+
+// maxWorkflowInputs is the most inputs GitHub accepts in one workflow_dispatch
+const maxWorkflowInputs = 10
+
+// maxPayloadFields is the most static fields a repository_dispatch route can add. They
+// are nested under client_payload.payload, since GitHub accepts at most 10 top-level
+// client_payload properties and the video fields take 8 of them.
+const maxPayloadFields = 20
+
+// maxPayloadValueLength is the longest static payload value a route can add
+const maxPayloadValueLength = 1024
+
+var (
+	// workflowPattern matches a workflow file name or numeric workflow ID
+	workflowPattern = regexp.MustCompile(`^([A-Za-z0-9_.-]+\.ya?ml|[0-9]+)$`)
+	// refPattern matches branch and tag names
+	refPattern = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+	// inputNamePattern matches workflow_dispatch input names
+	inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+)
+
+// validateDispatchMode checks a route's mode and its settings for that mode: the ref and
+// static payload of a repository_dispatch route, or the workflow, ref and input mapping
+// of a workflow_dispatch route
+func validateDispatchMode(target RepositoryTarget) error {
+	switch target.Mode {
+	case "", DispatchModeRepository:
+		if target.Workflow != "" || len(target.Inputs) > 0 {
+			return fmt.Errorf("workflow and inputs are only used with mode %q", DispatchModeWorkflow)
+		}
+		if target.Ref != "" && !validRef(target.Ref) {
+			return fmt.Errorf("ref must be a branch or tag name")
+		}
+		return validatePayload(target.Payload)
+	case DispatchModeWorkflow:
+		if len(target.Payload) > 0 {
+			return fmt.Errorf("payload is only used with mode %q; map workflow inputs instead", DispatchModeRepository)
+		}
+	default:
+		return fmt.Errorf("mode must be %q or %q", DispatchModeRepository, DispatchModeWorkflow)
+	}
+
+	if !workflowPattern.MatchString(target.Workflow) {
+		return fmt.Errorf("workflow must be a workflow file name (e.g. release.yml) or ID")
+	}
+	if !validRef(target.Ref) {
+		return fmt.Errorf("ref must be a branch or tag name")
+	}
+	if len(target.Inputs) > maxWorkflowInputs {
+		return fmt.Errorf("at most %d workflow inputs can be mapped", maxWorkflowInputs)
+	}
+
+	fields := videoFields(&Entry{}, "")
+	for input, field := range target.Inputs {
+		if !inputNamePattern.MatchString(input) {
+			return fmt.Errorf("invalid workflow input name %q", input)
+		}
+		if _, ok := fields[field]; !ok {
+			return fmt.Errorf("input %s maps unknown video field %q (known fields: %s)", input, field, strings.Join(videoFieldNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// validRef reports whether ref is a usable branch or tag name
+func validRef(ref string) bool {
+	return refPattern.MatchString(ref) && !strings.Contains(ref, "..")
+}
+
+// validatePayload checks a repository_dispatch route's static payload fields
+func validatePayload(payload map[string]string) error {
+	if len(payload) > maxPayloadFields {
+		return fmt.Errorf("at most %d payload fields can be set", maxPayloadFields)
+	}
+	for name, value := range payload {
+		if !inputNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid payload field name %q", name)
+		}
+		if len(value) > maxPayloadValueLength {
+			return fmt.Errorf("payload field %s is longer than %d bytes", name, maxPayloadValueLength)
+		}
+	}
+	return nil
+}
+
+// clientPayloadExtra returns the route's fields added to a repository_dispatch
+// client_payload: its ref and static payload, when set
+func (t RepositoryTarget) clientPayloadExtra() map[string]interface{} {
+	if t.Ref == "" && len(t.Payload) == 0 {
+		return nil
+	}
+	extra := make(map[string]interface{}, 2)
+	if t.Ref != "" {
+		extra["ref"] = t.Ref
+	}
+	if len(t.Payload) > 0 {
+		extra["payload"] = t.Payload
+	}
+	return extra
+}
+
+// videoFieldNames lists the video fields workflow inputs can be mapped from
+func videoFieldNames() []string {
+	var names []string
+	for name := range videoFields(&Entry{}, "") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// workflowInputs maps an entry's video fields to the route's workflow inputs
+func (t RepositoryTarget) workflowInputs(entry *Entry) map[string]string {
+	if len(t.Inputs) == 0 {
+		return nil
+	}
+	fields := videoFields(entry, os.Getenv("ENVIRONMENT"))
+	inputs := make(map[string]string, len(t.Inputs))
+	for input, field := range t.Inputs {
+		inputs[input] = fields[field]
+	}
+	return inputs
+}
+
+// dispatchToRepository sends an entry to one routed repository using the route's dispatch mode
+func dispatchToRepository(ctx context.Context, github GitHubClientInterface, target RepositoryTarget, entry *Entry) error {
+	if target.Mode == DispatchModeWorkflow {
+		return github.DispatchWorkflow(ctx, target.RepoOwner, target.RepoName, target.Workflow, target.Ref, target.workflowInputs(entry))
+	}
+	return github.TriggerWorkflow(ctx, target.RepoOwner, target.RepoName, entry, target.clientPayloadExtra())
+}
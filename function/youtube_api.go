@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// YouTubeAPIClient looks up video metadata with the YouTube Data API
+type YouTubeAPIClient struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+}
+
+// youTubeVideosResponse is the part of a videos.list response that is used
+type youTubeVideosResponse struct {
+	Items []struct {
+		ID      string `json:"id"`
+		Snippet struct {
+			PublishedAt          string `json:"publishedAt"`
+			ChannelID            string `json:"channelId"`
+			Title                string `json:"title"`
+			LiveBroadcastContent string `json:"liveBroadcastContent"`
+		} `json:"snippet"`
+		LiveStreamingDetails struct {
+			ScheduledStartTime string `json:"scheduledStartTime"`
+			ActualStartTime    string `json:"actualStartTime"`
+		} `json:"liveStreamingDetails"`
+	} `json:"items"`
+}
+
+// VideoBroadcast is the premiere or live-stream state of a video
+type VideoBroadcast struct {
+	LiveBroadcastContent string    // BroadcastUpcoming, BroadcastLive or BroadcastNone
+	ScheduledStartTime   time.Time // Zero for regular videos
+	ActualStartTime      time.Time // Zero until the premiere or stream starts
+}
+
+// youTubeChannelsResponse is the part of a channels.list response that is used
+type youTubeChannelsResponse struct {
+	Items []struct {
+		ID      string `json:"id"`
+		Snippet struct {
+			Title string `json:"title"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+// NewYouTubeAPIClient creates a YouTube Data API client from YOUTUBE_API_KEY
+func NewYouTubeAPIClient() *YouTubeAPIClient {
+	baseURL := os.Getenv("YOUTUBE_API_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://www.googleapis.com/youtube/v3"
+	}
+
+	return &YouTubeAPIClient{
+		APIKey:  os.Getenv("YOUTUBE_API_KEY"),
+		BaseURL: baseURL,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsConfigured returns whether the client has an API key
+func (yc *YouTubeAPIClient) IsConfigured() bool {
+	return yc.APIKey != ""
+}
+
+// GetVideo returns the notification entry for a video, rebuilt from its snippet.
+// The API has no update time, so Updated is the publish time.
+func (yc *YouTubeAPIClient) GetVideo(ctx context.Context, videoID string) (*Entry, error) {
+	query := url.Values{}
+	query.Set("part", "snippet")
+	query.Set("id", videoID)
+
+	var videos youTubeVideosResponse
+	if err := yc.get(ctx, "/videos", query, &videos); err != nil {
+		return nil, err
+	}
+	if len(videos.Items) == 0 {
+		return nil, ErrVideoNotFound
+	}
+
+	snippet := videos.Items[0].Snippet
+	return &Entry{
+		VideoID:   videoID,
+		ChannelID: snippet.ChannelID,
+		Title:     snippet.Title,
+		Published: snippet.PublishedAt,
+		Updated:   snippet.PublishedAt,
+		Link:      Link{Href: "https://www.youtube.com/watch?v=" + videoID},
+	}, nil
+}
+
+// GetBroadcast returns whether a video is an upcoming or live premiere or stream,
+// from its snippet and live streaming details
+func (yc *YouTubeAPIClient) GetBroadcast(ctx context.Context, videoID string) (*VideoBroadcast, error) {
+	query := url.Values{}
+	query.Set("part", "snippet,liveStreamingDetails")
+	query.Set("id", videoID)
+
+	var videos youTubeVideosResponse
+	if err := yc.get(ctx, "/videos", query, &videos); err != nil {
+		return nil, err
+	}
+	if len(videos.Items) == 0 {
+		return nil, ErrVideoNotFound
+	}
+
+	video := videos.Items[0]
+	broadcast := &VideoBroadcast{LiveBroadcastContent: video.Snippet.LiveBroadcastContent}
+	if broadcast.LiveBroadcastContent == "" {
+		broadcast.LiveBroadcastContent = BroadcastNone
+	}
+	// Unparseable times are left zero
+	broadcast.ScheduledStartTime, _ = time.Parse(time.RFC3339, video.LiveStreamingDetails.ScheduledStartTime)
+	broadcast.ActualStartTime, _ = time.Parse(time.RFC3339, video.LiveStreamingDetails.ActualStartTime)
+	return broadcast, nil
+}
+
+// GetChannel looks up a channel with one channels.list filter (id, forHandle or
+// forUsername) and returns its channel ID and title
+func (yc *YouTubeAPIClient) GetChannel(ctx context.Context, filter, value string) (string, string, error) {
+	query := url.Values{}
+	query.Set("part", "snippet")
+	query.Set(filter, value)
+
+	var channels youTubeChannelsResponse
+	if err := yc.get(ctx, "/channels", query, &channels); err != nil {
+		return "", "", err
+	}
+	if len(channels.Items) == 0 {
+		return "", "", ErrChannelNotFound
+	}
+	return channels.Items[0].ID, channels.Items[0].Snippet.Title, nil
+}
+
+// get calls one YouTube Data API method and decodes its JSON response into v
+func (yc *YouTubeAPIClient) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	query.Set("key", yc.APIKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, yc.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := yc.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query YouTube API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read YouTube API response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("YouTube API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode YouTube API response: %v", err)
+	}
+	return nil
+}
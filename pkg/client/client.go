@@ -0,0 +1,984 @@
+// Package client is a Go client for the YouTube webhook service's management API, as
+// described by the OpenAPI document the service serves at /openapi.yaml. Responses
+// are decoded into the service's own types from github.com/samsoir/youtube-webhook/function.
+//
+//	c := client.NewClient(baseURL, 30*time.Second).WithAPIKey(apiKey)
+//	c := client.NewClient(baseURL, 30*time.Second).WithSigningSecret(secret)
+//	subscriptions, err := c.ListSubscriptions()
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+// Client provides methods to interact with the YouTube webhook service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a new webhook service client
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// WithAPIKey makes the client send apiKey as a bearer token on every request.
+// An OIDC ID token (e.g. from gcloud auth print-identity-token) works the same way.
+func (c *Client) WithAPIKey(apiKey string) *Client {
+	if apiKey == "" {
+		return c
+	}
+	transport := c.httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	c.httpClient.Transport = &bearerTransport{token: apiKey, base: transport}
+	return c
+}
+
+// bearerTransport adds an Authorization header to each request
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+// RoundTrip sets the bearer token on a copy of the request and sends it
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// WithSigningSecret makes the client sign every request with the service's
+// REQUEST_SIGNING_SECRET instead of sending a credential.
+func (c *Client) WithSigningSecret(secret string) *Client {
+	if secret == "" {
+		return c
+	}
+	transport := c.httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	basePath := ""
+	if parsed, err := url.Parse(c.baseURL); err == nil {
+		basePath = strings.TrimSuffix(parsed.EscapedPath(), "/")
+	}
+	c.httpClient.Transport = &signingTransport{secret: secret, basePath: basePath, base: transport}
+	return c
+}
+
+// signingTransport adds X-Request-Signature and X-Request-Timestamp headers to each request
+type signingTransport struct {
+	secret   string
+	basePath string // Path of the base URL, which the function does not see
+	base     http.RoundTripper
+}
+
+// RoundTrip signs a copy of the request and sends it
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body to sign: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	path := strings.TrimPrefix(req.URL.EscapedPath(), t.basePath)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(webhook.RequestTimestampHeader, timestamp)
+	req.Header.Set(webhook.RequestSignatureHeader, webhook.RequestSignature(t.secret, timestamp, req.Method, path, body))
+	return t.base.RoundTrip(req)
+}
+
+// Subscribe subscribes to a YouTube channel
+func (c *Client) Subscribe(channelID string) (*webhook.APIResponse, error) {
+	return c.subscribe(url.Values{"channel_id": {channelID}}, "")
+}
+
+// SubscribeByHandle subscribes to the YouTube channel with an @handle or channel URL,
+// which the service resolves to a channel ID. The response carries the channel's ID
+// and name.
+func (c *Client) SubscribeByHandle(handle string) (*webhook.APIResponse, error) {
+	return c.subscribe(url.Values{"handle": {handle}}, "")
+}
+
+// SubscribePlaylist subscribes to a YouTube playlist's feed. The subscription is
+// managed by its playlist ID wherever a channel ID is taken, e.g. by Unsubscribe.
+func (c *Client) SubscribePlaylist(playlistID string) (*webhook.APIResponse, error) {
+	return c.subscribe(url.Values{"playlist_id": {playlistID}}, "")
+}
+
+// SubscribeOptions selects the channel or playlist POST /subscribe subscribes to and the
+// hub it uses
+type SubscribeOptions struct {
+	ChannelID  string
+	Handle     string // An @handle or channel URL the service resolves, used instead of ChannelID
+	PlaylistID string // A playlist to subscribe to instead of a channel
+	HubURL     string // Hub to subscribe with instead of the service's HUB_URL
+
+	// IdempotencyKey makes the request safe to retry: the service answers a repeat
+	// with the same key with the first response instead of subscribing again
+	IdempotencyKey string
+}
+
+// SubscribeWithOptions subscribes to the channel or playlist opts selects, with its hub
+// when set
+func (c *Client) SubscribeWithOptions(opts SubscribeOptions) (*webhook.APIResponse, error) {
+	query := url.Values{}
+	if opts.PlaylistID != "" {
+		query.Set("playlist_id", opts.PlaylistID)
+	} else if opts.Handle != "" {
+		query.Set("handle", opts.Handle)
+	} else {
+		query.Set("channel_id", opts.ChannelID)
+	}
+	if opts.HubURL != "" {
+		query.Set("hub_url", opts.HubURL)
+	}
+	return c.subscribe(query, opts.IdempotencyKey)
+}
+
+// subscribe sends POST /subscribe with the given query and, when set, idempotency key
+func (c *Client) subscribe(query url.Values, idempotencyKey string) (*webhook.APIResponse, error) {
+	url := fmt.Sprintf("%s/subscribe?%s", c.baseURL, query.Encode())
+	
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(webhook.IdempotencyKeyHeader, idempotencyKey)
+	}
+	return c.apiRequest(req)
+}
+
+// RestoreSubscription re-subscribes an unsubscribed channel with the configuration it had
+// when it was unsubscribed
+func (c *Client) RestoreSubscription(channelID string) (*webhook.APIResponse, error) {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/subscriptions/%s/restore", c.baseURL, channelID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	return c.apiRequest(req)
+}
+
+// apiRequest sends req and decodes the APIResponse, returning it along with an error for
+// error status codes
+func (c *Client) apiRequest(req *http.Request) (*webhook.APIResponse, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	var apiResp webhook.APIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	// Check for error status codes
+	if resp.StatusCode >= 400 {
+		if apiResp.Message != "" {
+			return &apiResp, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return &apiResp, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	return &apiResp, nil
+}
+
+// IsAlreadySubscribed reports whether a failed subscribe was refused because the channel
+// is already subscribed. Older servers answered with status "conflict" rather than the
+// error envelope's conflict code.
+func IsAlreadySubscribed(resp *webhook.APIResponse) bool {
+	return resp != nil && (resp.Code == webhook.ErrCodeConflict || resp.Status == "conflict")
+}
+
+// Unsubscribe unsubscribes from a YouTube channel
+func (c *Client) Unsubscribe(channelID string) error {
+	url := fmt.Sprintf("%s/unsubscribe?channel_id=%s", c.baseURL, channelID)
+	
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil // Success
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("not subscribed to channel %s", channelID)
+	}
+
+	// Try to parse error response
+	body, _ := io.ReadAll(resp.Body)
+	var apiResp webhook.APIResponse
+	if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+		return fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+	}
+
+	return fmt.Errorf("server returned status %d", resp.StatusCode)
+}
+
+// ListOptions filters, sorts and paginates GET /subscriptions; zero values use the server defaults
+type ListOptions struct {
+	Status    string // pending, verified, active, expiring or expired
+	Sort      string // channel or expiry, prefixed with "-" for descending
+	Limit     int    // Page size; 0 returns every subscription
+	PageToken string // next_page_token from the previous page
+}
+
+// ListSubscriptions lists all subscriptions
+func (c *Client) ListSubscriptions() (*webhook.SubscriptionsListResponse, error) {
+	return c.ListSubscriptionsPage(ListOptions{})
+}
+
+// ListSubscriptionsPage lists one page of subscriptions matching opts
+func (c *Client) ListSubscriptionsPage(opts ListOptions) (*webhook.SubscriptionsListResponse, error) {
+	query := url.Values{}
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.PageToken != "" {
+		query.Set("page_token", opts.PageToken)
+	}
+
+	endpoint := fmt.Sprintf("%s/subscriptions", c.baseURL)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	var listResp webhook.SubscriptionsListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &listResp, nil
+}
+
+// ListExpiringSubscriptions lists the subscriptions whose lease ends within the given
+// window, soonest first. A zero window uses the server's renewal threshold and a zero
+// limit returns them all.
+func (c *Client) ListExpiringSubscriptions(within time.Duration, limit int) (*webhook.ExpiringSubscriptionsResponse, error) {
+	query := url.Values{}
+	if within > 0 {
+		query.Set("within_hours", strconv.FormatFloat(within.Hours(), 'f', -1, 64))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	path := "/subscriptions/expiring"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var expiringResp webhook.ExpiringSubscriptionsResponse
+	if _, err := c.getJSON(path, &expiringResp); err != nil {
+		return nil, err
+	}
+	return &expiringResp, nil
+}
+
+// RenewSubscriptions triggers renewal of expiring subscriptions
+func (c *Client) RenewSubscriptions() (*webhook.RenewalSummaryResponse, error) {
+	return c.RenewChannels(webhook.RenewRequest{})
+}
+
+// RenewChannels triggers renewal of the requested channels, or every expiring
+// subscription when none are given; Force renews them whatever their expiry
+func (c *Client) RenewChannels(renewReq webhook.RenewRequest) (*webhook.RenewalSummaryResponse, error) {
+	resp, err := c.postRenew(renewReq, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	var renewResp webhook.RenewalSummaryResponse
+	if err := json.Unmarshal(body, &renewResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &renewResp, nil
+}
+
+// RenewChannelsStream renews like RenewChannels, asking the service to stream its
+// progress. Each start and result event is passed to progress as it arrives, and the
+// run's summary is returned once it ends.
+func (c *Client) RenewChannelsStream(renewReq webhook.RenewRequest, progress func(webhook.RenewalEvent)) (*webhook.RenewalSummaryResponse, error) {
+	resp, err := c.postRenew(renewReq, webhook.RenewalStreamContentType)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Services from before streaming answer with the summary alone
+	decoder := json.NewDecoder(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), webhook.RenewalStreamContentType) {
+		var renewResp webhook.RenewalSummaryResponse
+		if err := decoder.Decode(&renewResp); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+		return &renewResp, nil
+	}
+	for {
+		var event webhook.RenewalEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("renewal stream ended without a summary")
+			}
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+
+		switch event.Type {
+		case webhook.RenewalEventSummary:
+			if event.Summary == nil {
+				return nil, fmt.Errorf("renewal stream sent an empty summary")
+			}
+			return event.Summary, nil
+		case webhook.RenewalEventError:
+			return nil, fmt.Errorf("server error: %s", event.Message)
+		default:
+			if progress != nil {
+				progress(event)
+			}
+		}
+	}
+}
+
+// postRenew sends a POST /renew request accepting the given content type, or JSON when
+// accept is empty, and returns the 200 response for the caller to read and close
+func (c *Client) postRenew(renewReq webhook.RenewRequest, accept string) (*http.Response, error) {
+	url := fmt.Sprintf("%s/renew", c.baseURL)
+
+	payload := []byte{}
+	if len(renewReq.ChannelIDs) > 0 || renewReq.Force {
+		var err error
+		if payload, err = json.Marshal(renewReq); err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if len(payload) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// LatestRenewalReport returns the report of the most recent renewal run, or nil when no
+// run has been reported yet
+func (c *Client) LatestRenewalReport() (*webhook.RenewalReport, error) {
+	var reportResp webhook.RenewalReportResponse
+	status, err := c.getJSON("/renew/report", &reportResp)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reportResp.Report, nil
+}
+
+// Health checks that the service is alive
+func (c *Client) Health() (*webhook.LivenessResponse, error) {
+	var healthResp webhook.LivenessResponse
+	if _, err := c.getJSON("/healthz", &healthResp); err != nil {
+		return nil, err
+	}
+	return &healthResp, nil
+}
+
+// Diagnostics runs the service's checks of its configuration, storage and GitHub token
+func (c *Client) Diagnostics() (*webhook.DiagnosticsResponse, error) {
+	var diagnostics webhook.DiagnosticsResponse
+	if _, err := c.getJSON("/diagnostics", &diagnostics); err != nil {
+		return nil, err
+	}
+	return &diagnostics, nil
+}
+
+// getJSON sends a GET request for path and decodes the response into out, returning the
+// response status code
+func (c *Client) getJSON(path string, out interface{}) (int, error) {
+	resp, err := c.httpClient.Get(c.baseURL + path)
+	if err != nil {
+		return 0, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return resp.StatusCode, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return resp.StatusCode, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("parsing response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// GetFilter returns a channel's notification filter; the filter is nil when none is set
+func (c *Client) GetFilter(channelID string) (*webhook.FilterResponse, error) {
+	return c.filterRequest("GET", channelID, nil)
+}
+
+// SetFilter replaces a channel's notification filter
+func (c *Client) SetFilter(channelID string, filter webhook.NotificationFilter) (*webhook.FilterResponse, error) {
+	payload, err := json.Marshal(filter)
+	if err != nil {
+		return nil, fmt.Errorf("encoding filter: %w", err)
+	}
+	return c.filterRequest("PUT", channelID, payload)
+}
+
+// ClearFilter removes a channel's notification filter
+func (c *Client) ClearFilter(channelID string) (*webhook.FilterResponse, error) {
+	return c.filterRequest("DELETE", channelID, nil)
+}
+
+// filterRequest sends a request to a channel's /filters endpoint
+func (c *Client) filterRequest(method, channelID string, payload []byte) (*webhook.FilterResponse, error) {
+	url := fmt.Sprintf("%s/subscriptions/%s/filters", c.baseURL, channelID)
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("not subscribed to channel %s", channelID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var filterResp webhook.FilterResponse
+	if err := json.Unmarshal(body, &filterResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &filterResp, nil
+}
+
+// GetChannelConfig returns a channel's lease, filter and sinks
+func (c *Client) GetChannelConfig(channelID string) (*webhook.ChannelConfigResponse, error) {
+	return c.channelConfigRequest("GET", channelID, nil)
+}
+
+// UpdateChannelConfig changes the fields set in patch, leaving the rest of the channel's
+// configuration as it is
+func (c *Client) UpdateChannelConfig(channelID string, patch webhook.ChannelConfigPatch) (*webhook.ChannelConfigResponse, error) {
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("encoding configuration: %w", err)
+	}
+	return c.channelConfigRequest("PATCH", channelID, payload)
+}
+
+// channelConfigRequest sends a request to a channel's /config endpoint
+func (c *Client) channelConfigRequest(method, channelID string, payload []byte) (*webhook.ChannelConfigResponse, error) {
+	url := fmt.Sprintf("%s/subscriptions/%s/config", c.baseURL, channelID)
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("not subscribed to channel %s", channelID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var configResp webhook.ChannelConfigResponse
+	if err := json.Unmarshal(body, &configResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &configResp, nil
+}
+
+// GetHistory returns a channel's processed notifications, newest first. A limit of
+// zero returns the whole stored history.
+func (c *Client) GetHistory(channelID string, limit int) (*webhook.HistoryResponse, error) {
+	endpoint := fmt.Sprintf("%s/subscriptions/%s/history", c.baseURL, channelID)
+	if limit > 0 {
+		endpoint += "?limit=" + strconv.Itoa(limit)
+	}
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var historyResp webhook.HistoryResponse
+	if err := json.Unmarshal(body, &historyResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &historyResp, nil
+}
+
+// EventsOptions selects the events returned by GET /events; zero values use the server defaults
+type EventsOptions struct {
+	ChannelID string // Only this channel's events
+	Since     string // cursor from the previous response; only later events are returned
+	Limit     int    // Maximum number of events
+}
+
+// GetEvents lists the notifications processed across channels, oldest first
+func (c *Client) GetEvents(opts EventsOptions) (*webhook.EventsResponse, error) {
+	query := url.Values{}
+	if opts.ChannelID != "" {
+		query.Set("channel_id", opts.ChannelID)
+	}
+	if opts.Since != "" {
+		query.Set("since", opts.Since)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	endpoint := fmt.Sprintf("%s/events", c.baseURL)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var eventsResp webhook.EventsResponse
+	if err := json.Unmarshal(body, &eventsResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &eventsResp, nil
+}
+
+// ErrEventStreamUnsupported is returned by StreamEvents when the service cannot stream
+// events, as services from before streaming cannot; poll GetEvents instead
+var ErrEventStreamUnsupported = errors.New("service does not stream events")
+
+// StreamEvents streams the notifications the service processes, passing each to handle
+// as it arrives. With opts.Since the events received after that cursor are sent first;
+// opts.Limit is ignored. It returns when the stream ends, ctx is done or handle fails,
+// with the cursor of the last event received, to resume from by streaming again.
+func (c *Client) StreamEvents(ctx context.Context, opts EventsOptions, handle func(webhook.NotificationEvent) error) (string, error) {
+	cursor := opts.Since
+	endpoint := fmt.Sprintf("%s/events", c.baseURL)
+	if opts.ChannelID != "" {
+		endpoint += "?" + url.Values{"channel_id": {opts.ChannelID}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return cursor, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", webhook.EventStreamContentType)
+	if cursor != "" {
+		req.Header.Set("Last-Event-ID", cursor)
+	}
+
+	// The stream stays open far longer than the request timeout allows
+	streaming := *c.httpClient
+	streaming.Timeout = 0
+	resp, err := streaming.Do(req)
+	if err != nil {
+		return cursor, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		message := fmt.Sprintf("server returned status %d", resp.StatusCode)
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			message = fmt.Sprintf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		if resp.StatusCode == http.StatusNotImplemented {
+			return cursor, fmt.Errorf("%w: %s", ErrEventStreamUnsupported, message)
+		}
+		return cursor, errors.New(message)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), webhook.EventStreamContentType) {
+		return cursor, ErrEventStreamUnsupported
+	}
+
+	// Each event is a block of fields ended by a blank line; comments start with a colon
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var id, eventType string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 && (eventType == "" || eventType == webhook.NotificationEventType) {
+				var event webhook.NotificationEvent
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+					return cursor, fmt.Errorf("parsing event: %w", err)
+				}
+				if err := handle(event); err != nil {
+					return cursor, err
+				}
+				if id != "" {
+					cursor = id
+				}
+			}
+			id, eventType, data = "", "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			eventType = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if ctx.Err() != nil {
+		return cursor, ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return cursor, fmt.Errorf("reading stream: %w", err)
+	}
+	return cursor, nil
+}
+
+// ListDeadLetters lists the stored unparseable notifications, newest first
+func (c *Client) ListDeadLetters() (*webhook.DeadLetterListResponse, error) {
+	var listResp webhook.DeadLetterListResponse
+	if err := c.deadLetterRequest("GET", "/deadletters", &listResp); err != nil {
+		return nil, err
+	}
+	return &listResp, nil
+}
+
+// GetDeadLetter returns a stored unparseable notification with its payload
+func (c *Client) GetDeadLetter(id string) (*webhook.DeadLetterResponse, error) {
+	var deadLetterResp webhook.DeadLetterResponse
+	if err := c.deadLetterRequest("GET", "/deadletters/"+url.PathEscape(id), &deadLetterResp); err != nil {
+		return nil, err
+	}
+	return &deadLetterResp, nil
+}
+
+// ReplayDeadLetter processes a stored notification again; it is deleted once processed
+func (c *Client) ReplayDeadLetter(id string) (*webhook.DeadLetterReplayResponse, error) {
+	var replayResp webhook.DeadLetterReplayResponse
+	if err := c.deadLetterRequest("POST", "/deadletters/"+url.PathEscape(id)+"/replay", &replayResp); err != nil {
+		return nil, err
+	}
+	return &replayResp, nil
+}
+
+// deadLetterRequest sends a request to a /deadletters endpoint and decodes the response into out
+func (c *Client) deadLetterRequest(method, path string, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// WithAdminToken makes the client send the service's ADMIN_TOKEN as a bearer token,
+// as the admin endpoints require
+func (c *Client) WithAdminToken(token string) *Client {
+	return c.WithAPIKey(token)
+}
+
+// ExportState downloads the full subscription state, including subscription secrets
+func (c *Client) ExportState() (*webhook.StateExportResponse, error) {
+	var exportResp webhook.StateExportResponse
+	if err := c.stateRequest("GET", "/admin/state", nil, &exportResp); err != nil {
+		return nil, err
+	}
+	return &exportResp, nil
+}
+
+// ImportState uploads a subscription state. mode is webhook.ImportModeReplace or
+// webhook.ImportModeMerge; a dry run only reports what the import would change.
+func (c *Client) ImportState(state *webhook.SubscriptionState, mode string, dryRun bool) (*webhook.StateImportResponse, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("encoding state: %w", err)
+	}
+
+	query := url.Values{}
+	if mode != "" {
+		query.Set("mode", mode)
+	}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	path := "/admin/state"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var importResp webhook.StateImportResponse
+	if err := c.stateRequest("PUT", path, payload, &importResp); err != nil {
+		return nil, err
+	}
+	return &importResp, nil
+}
+
+// ListStateBackups lists the subscription state backups, newest first
+func (c *Client) ListStateBackups() (*webhook.StateBackupListResponse, error) {
+	var listResp webhook.StateBackupListResponse
+	if err := c.stateRequest("GET", "/admin/backups", nil, &listResp); err != nil {
+		return nil, err
+	}
+	return &listResp, nil
+}
+
+// RestoreStateBackup replaces the subscription state with the named backup; a dry run
+// only reports what the restore would change
+func (c *Client) RestoreStateBackup(name string, dryRun bool) (*webhook.StateBackupRestoreResponse, error) {
+	path := "/admin/backups/" + url.PathEscape(name) + "/restore"
+	if dryRun {
+		path += "?dry_run=true"
+	}
+
+	var restoreResp webhook.StateBackupRestoreResponse
+	if err := c.stateRequest("POST", path, nil, &restoreResp); err != nil {
+		return nil, err
+	}
+	return &restoreResp, nil
+}
+
+// stateRequest sends a request to the /admin/state or /admin/backups endpoints and
+// decodes the response into out
+func (c *Client) stateRequest(method, path string, payload []byte, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiResp webhook.APIResponse
+		if err := json.Unmarshal(body, &apiResp); err == nil && apiResp.Message != "" {
+			return fmt.Errorf("server error (%d): %s", resp.StatusCode, apiResp.Message)
+		}
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
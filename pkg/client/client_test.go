@@ -0,0 +1,982 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+)
+
+func TestNewClient(t *testing.T) {
+	baseURL := "https://example.com/"
+	timeout := 30 * time.Second
+	
+	client := NewClient(baseURL, timeout)
+	
+	if client.baseURL != "https://example.com" {
+		t.Errorf("Expected baseURL to be 'https://example.com', got %s", client.baseURL)
+	}
+	
+	if client.httpClient.Timeout != timeout {
+		t.Errorf("Expected timeout to be %v, got %v", timeout, client.httpClient.Timeout)
+	}
+}
+
+func TestClient_Subscribe_Success(t *testing.T) {
+	expectedResponse := webhook.APIResponse{
+		Status:    "success",
+		Message:   "Subscribed successfully",
+		ExpiresAt: "2024-01-22T15:30:00Z",
+	}
+	
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Expected POST method, got %s", r.Method)
+		}
+		
+		if r.URL.Path != "/subscribe" {
+			t.Errorf("Expected path /subscribe, got %s", r.URL.Path)
+		}
+		
+		channelID := r.URL.Query().Get("channel_id")
+		if channelID != "UCXuqSBlHAE6Xw-yeJA0Tunw" {
+			t.Errorf("Expected channel_id UCXuqSBlHAE6Xw-yeJA0Tunw, got %s", channelID)
+		}
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(expectedResponse)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	resp, err := client.Subscribe("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	
+	if resp.Status != expectedResponse.Status {
+		t.Errorf("Expected status %s, got %s", expectedResponse.Status, resp.Status)
+	}
+	
+	if resp.Message != expectedResponse.Message {
+		t.Errorf("Expected message %s, got %s", expectedResponse.Message, resp.Message)
+	}
+	
+	if resp.ExpiresAt != expectedResponse.ExpiresAt {
+		t.Errorf("Expected expires_at %s, got %s", expectedResponse.ExpiresAt, resp.ExpiresAt)
+	}
+}
+
+func TestClient_SubscribeByHandle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle := r.URL.Query().Get("handle"); handle != "https://www.youtube.com/@SomeCreator" {
+			t.Errorf("Expected handle query parameter, got %q", handle)
+		}
+		if r.URL.Query().Has("channel_id") {
+			t.Errorf("Expected no channel_id with a handle, got %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(webhook.APIResponse{
+			Status:      "success",
+			ChannelID:   "UCXuqSBlHAE6Xw-yeJA0Tunw",
+			ChannelName: "Some Creator",
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+	resp, err := client.SubscribeByHandle("https://www.youtube.com/@SomeCreator")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.ChannelID != "UCXuqSBlHAE6Xw-yeJA0Tunw" || resp.ChannelName != "Some Creator" {
+		t.Errorf("Expected the resolved channel, got %+v", resp)
+	}
+}
+
+func TestClient_SubscribeWithOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("handle") != "@SomeCreator" || query.Get("hub_url") != "https://hub.example.com/" || query.Has("channel_id") {
+			t.Errorf("Expected the handle and hub_url query parameters, got %s", r.URL.RawQuery)
+		}
+		if key := r.Header.Get("Idempotency-Key"); key != "retry-1" {
+			t.Errorf("Expected the Idempotency-Key header, got %q", key)
+		}
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "success", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+	resp, err := client.SubscribeWithOptions(SubscribeOptions{Handle: "@SomeCreator", HubURL: "https://hub.example.com/", IdempotencyKey: "retry-1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.ChannelID != "UCXuqSBlHAE6Xw-yeJA0Tunw" {
+		t.Errorf("Expected the subscribed channel, got %+v", resp)
+	}
+}
+
+func TestClient_SubscribePlaylist(t *testing.T) {
+	playlistID := "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscribe" || r.URL.Query().Get("playlist_id") != playlistID || r.URL.Query().Has("channel_id") {
+			t.Errorf("Expected POST /subscribe with playlist_id, got %s %s", r.URL.Path, r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "success", ChannelID: playlistID})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+	resp, err := client.SubscribePlaylist(playlistID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.ChannelID != playlistID {
+		t.Errorf("Expected the subscribed playlist, got %+v", resp)
+	}
+}
+
+func TestClient_Subscribe_Conflict(t *testing.T) {
+	conflictResponse := webhook.APIResponse{
+		Status:    "error",
+		Code:      webhook.ErrCodeConflict,
+		Message:   "Already subscribed",
+		ExpiresAt: "2024-01-22T15:30:00Z",
+	}
+	
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(conflictResponse)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	resp, err := client.Subscribe("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	
+	if err == nil {
+		t.Fatal("Expected error for conflict response, got nil")
+	}
+	
+	if resp == nil {
+		t.Fatal("Expected response to be returned even with error")
+	}
+	
+	if !IsAlreadySubscribed(resp) {
+		t.Errorf("Expected the conflict code, got %+v", resp)
+	}
+}
+
+func TestIsAlreadySubscribed(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resp     *webhook.APIResponse
+		expected bool
+	}{
+		{"conflict code", &webhook.APIResponse{Status: "error", Code: webhook.ErrCodeConflict}, true},
+		{"legacy conflict status", &webhook.APIResponse{Status: "conflict"}, true},
+		{"other error", &webhook.APIResponse{Status: "error", Code: webhook.ErrCodeBadRequest}, false},
+		{"no response", nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsAlreadySubscribed(tc.resp); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestClient_Subscribe_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(webhook.APIResponse{
+			Status:  "error",
+			Message: "Internal server error",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	_, err := client.Subscribe("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	
+	if err == nil {
+		t.Fatal("Expected error for server error response, got nil")
+	}
+	
+	expectedError := "server error (500): Internal server error"
+	if err.Error() != expectedError {
+		t.Errorf("Expected error %s, got %s", expectedError, err.Error())
+	}
+}
+
+func TestClient_Unsubscribe_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			t.Errorf("Expected DELETE method, got %s", r.Method)
+		}
+		
+		if r.URL.Path != "/unsubscribe" {
+			t.Errorf("Expected path /unsubscribe, got %s", r.URL.Path)
+		}
+		
+		channelID := r.URL.Query().Get("channel_id")
+		if channelID != "UCXuqSBlHAE6Xw-yeJA0Tunw" {
+			t.Errorf("Expected channel_id UCXuqSBlHAE6Xw-yeJA0Tunw, got %s", channelID)
+		}
+		
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	err := client.Unsubscribe("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestClient_Unsubscribe_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	err := client.Unsubscribe("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	
+	if err == nil {
+		t.Fatal("Expected error for not found response, got nil")
+	}
+	
+	expectedError := "not subscribed to channel UCXuqSBlHAE6Xw-yeJA0Tunw"
+	if err.Error() != expectedError {
+		t.Errorf("Expected error %s, got %s", expectedError, err.Error())
+	}
+}
+
+func TestClient_RestoreSubscription(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/restore" {
+			t.Errorf("Expected POST /subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/restore, got %s %s", r.Method, r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "success", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", ChannelName: "Restored"})
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(server.URL, 30*time.Second).RestoreSubscription("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.ChannelName != "Restored" {
+		t.Errorf("Expected channel name Restored, got %q", resp.ChannelName)
+	}
+}
+
+func TestClient_RestoreSubscription_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "No removed subscription to restore for this channel"})
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, 30*time.Second).RestoreSubscription("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	if err == nil || !strings.Contains(err.Error(), "No removed subscription") {
+		t.Errorf("Expected the server's message in the error, got %v", err)
+	}
+}
+
+func TestClient_ListSubscriptions_Success(t *testing.T) {
+	expectedResponse := webhook.SubscriptionsListResponse{
+		Subscriptions: []webhook.SubscriptionInfo{
+			{
+				ChannelID:       "UCXuqSBlHAE6Xw-yeJA0Tunw",
+				ExpiresAt:       "2024-01-22T15:30:00Z",
+				Status:          "active",
+				DaysUntilExpiry: 0.9,
+			},
+			{
+				ChannelID:       "UCdQw4w9WgXcQ",
+				ExpiresAt:       "2024-01-23T10:00:00Z",
+				Status:          "active",
+				DaysUntilExpiry: 1.4,
+			},
+		},
+		Total:   2,
+		Active:  2,
+		Expired: 0,
+	}
+	
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("Expected GET method, got %s", r.Method)
+		}
+		
+		if r.URL.Path != "/subscriptions" {
+			t.Errorf("Expected path /subscriptions, got %s", r.URL.Path)
+		}
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(expectedResponse)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	resp, err := client.ListSubscriptions()
+	
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	
+	if len(resp.Subscriptions) != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", len(resp.Subscriptions))
+	}
+	
+	if resp.Total != 2 {
+		t.Errorf("Expected total 2, got %d", resp.Total)
+	}
+	
+	if resp.Active != 2 {
+		t.Errorf("Expected active 2, got %d", resp.Active)
+	}
+}
+
+func TestClient_RenewSubscriptions_Success(t *testing.T) {
+	expectedResponse := webhook.RenewalSummaryResponse{
+		Status:             "success",
+		TotalChecked:       3,
+		RenewalsCandidates: 1,
+		RenewalsSucceeded:  1,
+		RenewalsFailed:     0,
+		Results: []webhook.RenewalResult{
+			{
+				ChannelID:     "UCXuqSBlHAE6Xw-yeJA0Tunw",
+				Success:       true,
+				Message:       "Renewed successfully",
+				AttemptCount:  1,
+			},
+		},
+	}
+	
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Expected POST method, got %s", r.Method)
+		}
+		
+		if r.URL.Path != "/renew" {
+			t.Errorf("Expected path /renew, got %s", r.URL.Path)
+		}
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(expectedResponse)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	resp, err := client.RenewSubscriptions()
+	
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	
+	if resp.TotalChecked != 3 {
+		t.Errorf("Expected checked 3, got %d", resp.TotalChecked)
+	}
+	
+	if resp.RenewalsSucceeded != 1 {
+		t.Errorf("Expected renewed 1, got %d", resp.RenewalsSucceeded)
+	}
+	
+	if len(resp.Results) != 1 {
+		t.Errorf("Expected 1 result, got %d", len(resp.Results))
+	}
+}
+
+func TestClient_RenewChannels(t *testing.T) {
+	var lastBody webhook.RenewRequest
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		lastBody = webhook.RenewRequest{}
+		json.NewDecoder(r.Body).Decode(&lastBody)
+		json.NewEncoder(w).Encode(webhook.RenewalSummaryResponse{Status: "success", Forced: lastBody.Force})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	resp, err := client.RenewChannels(webhook.RenewRequest{ChannelIDs: []string{"UCXuqSBlHAE6Xw-yeJA0Tunw"}, Force: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contentType != "application/json" || !lastBody.Force || len(lastBody.ChannelIDs) != 1 {
+		t.Errorf("Expected JSON renew request, got %q %+v", contentType, lastBody)
+	}
+	if !resp.Forced {
+		t.Errorf("Expected forced response, got %+v", resp)
+	}
+
+	if _, err := client.RenewSubscriptions(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contentType != "" {
+		t.Errorf("Expected no body for a default renewal, got content type %q", contentType)
+	}
+}
+
+func TestClient_RenewChannelsStream(t *testing.T) {
+	var accept string
+	events := []webhook.RenewalEvent{
+		{Type: webhook.RenewalEventStart, Renewals: 1},
+		{Type: webhook.RenewalEventResult, Result: &webhook.RenewalResult{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Success: true}},
+		{Type: webhook.RenewalEventSummary, Summary: &webhook.RenewalSummaryResponse{Status: "success", RenewalsSucceeded: 1}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", webhook.RenewalStreamContentType)
+		for _, event := range events {
+			json.NewEncoder(w).Encode(event)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	var progress []string
+	resp, err := client.RenewChannelsStream(webhook.RenewRequest{}, func(event webhook.RenewalEvent) {
+		progress = append(progress, event.Type)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if accept != webhook.RenewalStreamContentType {
+		t.Errorf("Expected a streamed renewal to be requested, got Accept %q", accept)
+	}
+	if strings.Join(progress, ",") != "start,result" {
+		t.Errorf("Expected start and result progress, got %v", progress)
+	}
+	if resp.RenewalsSucceeded != 1 {
+		t.Errorf("Expected the summary, got %+v", resp)
+	}
+
+	events[2] = webhook.RenewalEvent{Type: webhook.RenewalEventError, Message: "Failed to save subscription state: boom"}
+	if _, err := client.RenewChannelsStream(webhook.RenewRequest{}, nil); err == nil || !strings.Contains(err.Error(), "Failed to save subscription state") {
+		t.Errorf("Expected the stream's error, got %v", err)
+	}
+
+	events = events[:2]
+	if _, err := client.RenewChannelsStream(webhook.RenewRequest{}, nil); err == nil || !strings.Contains(err.Error(), "without a summary") {
+		t.Errorf("Expected a truncated stream to fail, got %v", err)
+	}
+}
+
+func TestClient_RenewSubscriptions_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(webhook.APIResponse{
+			Status:  "error",
+			Message: "Renewal failed",
+		})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, 30*time.Second)
+	_, err := client.RenewSubscriptions()
+	
+	if err == nil {
+		t.Fatal("Expected error for server error response, got nil")
+	}
+	
+	expectedError := "server error (500): Renewal failed"
+	if err.Error() != expectedError {
+		t.Errorf("Expected error %s, got %s", expectedError, err.Error())
+	}
+}
+func TestClient_GetEvents(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		json.NewEncoder(w).Encode(webhook.EventsResponse{
+			Status: "success",
+			Count:  1,
+			Events: []webhook.NotificationEvent{{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", HistoryEntry: webhook.HistoryEntry{VideoID: "video1"}}},
+			Cursor: "2026-10-16T09:00:00Z",
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	resp, err := client.GetEvents(EventsOptions{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Since: "2026-10-16T08:00:00Z", Limit: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if query != "channel_id=UCXuqSBlHAE6Xw-yeJA0Tunw&limit=10&since=2026-10-16T08%3A00%3A00Z" {
+		t.Errorf("Unexpected query %q", query)
+	}
+	if resp.Count != 1 || resp.Events[0].VideoID != "video1" || resp.Cursor != "2026-10-16T09:00:00Z" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	if _, err := client.GetEvents(EventsOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if query != "" {
+		t.Errorf("Expected no query for default options, got %q", query)
+	}
+}
+
+func TestClient_StreamEvents(t *testing.T) {
+	var lastEventID, accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventID, accept = r.Header.Get("Last-Event-ID"), r.Header.Get("Accept")
+		if r.URL.Query().Get("channel_id") == "old" {
+			json.NewEncoder(w).Encode(webhook.EventsResponse{Status: "success"})
+			return
+		}
+		w.Header().Set("Content-Type", webhook.EventStreamContentType)
+		io.WriteString(w, ": heartbeat\n\n")
+		io.WriteString(w, "id: 2026-10-16T09:01:00Z\nevent: notification\ndata: {\"channel_id\":\"UCXuqSBlHAE6Xw-yeJA0Tunw\",\"video_id\":\"video1\"}\n\n")
+		io.WriteString(w, "id: 2026-10-16T09:02:00Z\nevent: other\ndata: {}\n\n")
+		io.WriteString(w, "id: 2026-10-16T09:03:00Z\ndata: {\"channel_id\":\"UCXuqSBlHAE6Xw-yeJA0Tunw\",\n")
+		io.WriteString(w, "data: \"video_id\":\"video2\"}\n\n")
+	}))
+	defer server.Close()
+
+	// The stream outlasts the request timeout
+	client := NewClient(server.URL, time.Nanosecond)
+
+	var videoIDs []string
+	cursor, err := client.StreamEvents(context.Background(), EventsOptions{Since: "2026-10-16T09:00:00Z"}, func(event webhook.NotificationEvent) error {
+		videoIDs = append(videoIDs, event.VideoID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if accept != webhook.EventStreamContentType || lastEventID != "2026-10-16T09:00:00Z" {
+		t.Errorf("Expected a stream resuming after since, got Accept %q and Last-Event-ID %q", accept, lastEventID)
+	}
+	if strings.Join(videoIDs, ",") != "video1,video2" {
+		t.Errorf("Expected the notification events, got %v", videoIDs)
+	}
+	if cursor != "2026-10-16T09:03:00Z" {
+		t.Errorf("Expected the last event's ID as the cursor, got %q", cursor)
+	}
+
+	client = NewClient(server.URL, 30*time.Second)
+	cursor, err = client.StreamEvents(context.Background(), EventsOptions{ChannelID: "old", Since: "2026-10-16T09:00:00Z"}, nil)
+	if !errors.Is(err, ErrEventStreamUnsupported) {
+		t.Errorf("Expected services answering with JSON to be reported, got %v", err)
+	}
+	if cursor != "2026-10-16T09:00:00Z" {
+		t.Errorf("Expected the cursor to be kept, got %q", cursor)
+	}
+}
+
+func TestClient_LatestRenewalReport(t *testing.T) {
+	reported := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/renew/report" {
+			t.Errorf("Expected path /renew/report, got %s", r.URL.Path)
+		}
+		if !reported {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "No renewal run has been reported yet"})
+			return
+		}
+		json.NewEncoder(w).Encode(webhook.RenewalReportResponse{
+			Status: "success",
+			Report: &webhook.RenewalReport{RunID: "run-1", Trigger: webhook.RenewalTriggerScheduler, Succeeded: 3},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	report, err := client.LatestRenewalReport()
+	if err != nil || report != nil {
+		t.Fatalf("Expected no report and no error before the first run, got %+v, %v", report, err)
+	}
+
+	reported = true
+	report, err = client.LatestRenewalReport()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.RunID != "run-1" || report.Succeeded != 3 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestClient_ListExpiringSubscriptions(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions/expiring" {
+			t.Errorf("Expected path /subscriptions/expiring, got %s", r.URL.Path)
+		}
+		query = r.URL.Query()
+		json.NewEncoder(w).Encode(webhook.ExpiringSubscriptionsResponse{
+			Subscriptions: []webhook.SubscriptionInfo{{ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}},
+			Total:         1,
+			WithinHours:   1.5,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	resp, err := client.ListExpiringSubscriptions(90*time.Minute, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if query.Get("within_hours") != "1.5" || query.Get("limit") != "5" {
+		t.Errorf("Expected within_hours=1.5 and limit=5, got %s", query.Encode())
+	}
+	if resp.Total != 1 || len(resp.Subscriptions) != 1 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	if _, err := client.ListExpiringSubscriptions(0, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(query) != 0 {
+		t.Errorf("Expected the server defaults without query parameters, got %s", query.Encode())
+	}
+}
+
+func TestClient_Health(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(webhook.LivenessResponse{Status: webhook.ProbeStatusOK, UptimeSeconds: 42})
+	}))
+	defer server.Close()
+
+	health, err := NewClient(server.URL, 30*time.Second).Health()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if health.Status != webhook.ProbeStatusOK || health.UptimeSeconds != 42 {
+		t.Errorf("Unexpected response %+v", health)
+	}
+
+	server.Close()
+	if _, err := NewClient(server.URL, time.Second).Health(); err == nil {
+		t.Error("Expected an error when the service is unreachable")
+	}
+}
+
+func TestClient_Diagnostics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/diagnostics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(webhook.DiagnosticsResponse{
+			Status:      webhook.ProbeStatusError,
+			CallbackURL: "https://example.run.app",
+			Components:  map[string]webhook.ComponentCheck{"github": {Status: webhook.ProbeStatusError, Message: "GITHUB_TOKEN not set"}},
+		})
+	}))
+	defer server.Close()
+
+	diagnostics, err := NewClient(server.URL, 30*time.Second).Diagnostics()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if diagnostics.CallbackURL != "https://example.run.app" || diagnostics.Components["github"].Message != "GITHUB_TOKEN not set" {
+		t.Errorf("Unexpected response %+v", diagnostics)
+	}
+}
+
+func TestClient_ChannelConfig(t *testing.T) {
+	var lastMethod, lastBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/subscriptions/UC_x5XG1OV2P6uZZ5FSM9Ttw/config" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		lastMethod = r.Method
+		body, _ := io.ReadAll(r.Body)
+		lastBody = string(body)
+		json.NewEncoder(w).Encode(webhook.ChannelConfigResponse{Status: "success", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", LeaseSeconds: 7200})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	resp, err := client.GetChannelConfig("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "GET" || resp.LeaseSeconds != 7200 {
+		t.Errorf("Unexpected %s request or response %+v", lastMethod, resp)
+	}
+
+	lease := 7200
+	if _, err := client.UpdateChannelConfig("UCXuqSBlHAE6Xw-yeJA0Tunw", webhook.ChannelConfigPatch{LeaseSeconds: &lease}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "PATCH" || lastBody != `{"lease_seconds":7200}` {
+		t.Errorf("Expected PATCH with only the lease, got %s %s", lastMethod, lastBody)
+	}
+
+	_, err = client.GetChannelConfig("UC_x5XG1OV2P6uZZ5FSM9Ttw")
+	if err == nil || err.Error() != "not subscribed to channel UC_x5XG1OV2P6uZZ5FSM9Ttw" {
+		t.Errorf("Expected not subscribed error, got %v", err)
+	}
+}
+
+func TestClient_Filters(t *testing.T) {
+	var lastMethod string
+	var lastBody webhook.NotificationFilter
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions/UCXuqSBlHAE6Xw-yeJA0Tunw/filters" {
+			t.Errorf("Expected filters path, got %s", r.URL.Path)
+		}
+		lastMethod = r.Method
+
+		resp := webhook.FilterResponse{Status: "success", ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw"}
+		if r.Method == "PUT" {
+			if err := json.NewDecoder(r.Body).Decode(&lastBody); err != nil {
+				t.Errorf("Expected JSON filter body, got error %v", err)
+			}
+			resp.Filter = &lastBody
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	resp, err := client.SetFilter("UCXuqSBlHAE6Xw-yeJA0Tunw", webhook.NotificationFilter{ExcludeShorts: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "PUT" || !lastBody.ExcludeShorts {
+		t.Errorf("Expected PUT with exclude_shorts, got %s %+v", lastMethod, lastBody)
+	}
+	if resp.Filter == nil || !resp.Filter.ExcludeShorts {
+		t.Errorf("Expected filter in response, got %+v", resp.Filter)
+	}
+
+	resp, err = client.GetFilter("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "GET" || resp.Filter != nil {
+		t.Errorf("Expected GET with no filter, got %s %+v", lastMethod, resp.Filter)
+	}
+
+	if _, err := client.ClearFilter("UCXuqSBlHAE6Xw-yeJA0Tunw"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "DELETE" {
+		t.Errorf("Expected DELETE method, got %s", lastMethod)
+	}
+}
+
+func TestClient_Filters_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "invalid title_pattern"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second)
+
+	_, err := client.GetFilter("UCXuqSBlHAE6Xw-yeJA0Tunw")
+	if err == nil || err.Error() != "not subscribed to channel UCXuqSBlHAE6Xw-yeJA0Tunw" {
+		t.Errorf("Expected not subscribed error, got %v", err)
+	}
+
+	_, err = client.SetFilter("UCXuqSBlHAE6Xw-yeJA0Tunw", webhook.NotificationFilter{TitlePattern: "("})
+	if err == nil || err.Error() != "server error (400): invalid title_pattern" {
+		t.Errorf("Expected server error, got %v", err)
+	}
+}
+
+func TestClient_WithAPIKey(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(webhook.SubscriptionsListResponse{})
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(server.URL, 30*time.Second).WithAPIKey("key-1").ListSubscriptions(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if authorization != "Bearer key-1" {
+		t.Errorf("Expected bearer API key, got %q", authorization)
+	}
+
+	if _, err := NewClient(server.URL, 30*time.Second).WithAPIKey("").ListSubscriptions(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if authorization != "" {
+		t.Errorf("Expected no Authorization header without a key, got %q", authorization)
+	}
+}
+
+func TestClient_WithSigningSecret(t *testing.T) {
+	var signature, expected, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		signature = r.Header.Get(webhook.RequestSignatureHeader)
+		// The function sees paths relative to its URL, without the function name
+		path := strings.TrimPrefix(r.URL.RequestURI(), "/youtube-webhook")
+		expected = webhook.RequestSignature("shared-secret", r.Header.Get(webhook.RequestTimestampHeader), r.Method, path, data)
+		json.NewEncoder(w).Encode(webhook.RenewalSummaryResponse{Status: "success"})
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/youtube-webhook/", 30*time.Second).WithSigningSecret("shared-secret")
+	if _, err := c.RenewChannels(webhook.RenewRequest{ChannelIDs: []string{"UCXuqSBlHAE6Xw-yeJA0Tunw"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if signature == "" || signature != expected {
+		t.Errorf("Expected signature %q, got %q", expected, signature)
+	}
+	if !strings.Contains(body, "UCXuqSBlHAE6Xw-yeJA0Tunw") {
+		t.Errorf("Expected the signed body to reach the server, got %q", body)
+	}
+
+	if _, err := NewClient(server.URL, 30*time.Second).WithSigningSecret("").ListSubscriptions(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if signature != "" {
+		t.Error("Expected no signature without a secret")
+	}
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "Missing API credentials"})
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, 30*time.Second).ListSubscriptions()
+	if err == nil || err.Error() != "server error (401): Missing API credentials" {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
+
+func TestClient_State(t *testing.T) {
+	var lastMethod, lastQuery, authorization string
+	var lastBody webhook.SubscriptionState
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/state" {
+			t.Errorf("Expected state path, got %s", r.URL.Path)
+		}
+		lastMethod, lastQuery = r.Method, r.URL.RawQuery
+		authorization = r.Header.Get("Authorization")
+
+		if r.Method == "PUT" {
+			if err := json.NewDecoder(r.Body).Decode(&lastBody); err != nil {
+				t.Errorf("Expected JSON state body, got error %v", err)
+			}
+			json.NewEncoder(w).Encode(webhook.StateImportResponse{Status: "success", Mode: r.URL.Query().Get("mode"), Added: 1})
+			return
+		}
+		state := &webhook.SubscriptionState{Subscriptions: map[string]*webhook.Subscription{
+			"UCXuqSBlHAE6Xw-yeJA0Tunw": {ChannelID: "UCXuqSBlHAE6Xw-yeJA0Tunw", Status: "active"},
+		}}
+		json.NewEncoder(w).Encode(webhook.StateExportResponse{Status: "success", Count: 1, State: state})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second).WithAdminToken("admin-secret")
+
+	exported, err := client.ExportState()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "GET" || authorization != "Bearer admin-secret" {
+		t.Errorf("Expected GET with admin token, got %s %q", lastMethod, authorization)
+	}
+	if exported.Count != 1 || exported.State.Subscriptions["UCXuqSBlHAE6Xw-yeJA0Tunw"] == nil {
+		t.Errorf("Expected exported subscription, got %+v", exported)
+	}
+
+	imported, err := client.ImportState(exported.State, webhook.ImportModeMerge, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "PUT" || lastQuery != "dry_run=true&mode=merge" {
+		t.Errorf("Expected PUT with mode and dry_run, got %s ?%s", lastMethod, lastQuery)
+	}
+	if len(lastBody.Subscriptions) != 1 || imported.Added != 1 || imported.Mode != "merge" {
+		t.Errorf("Expected state body and import summary, got %+v %+v", lastBody, imported)
+	}
+}
+
+func TestClient_StateBackups(t *testing.T) {
+	var lastMethod, lastPath, lastQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastMethod, lastPath, lastQuery = r.Method, r.URL.Path, r.URL.RawQuery
+		backup := webhook.StateBackup{Name: "state-20250121103000.json", Subscriptions: 2, Version: "2.0"}
+		if r.Method == "POST" {
+			json.NewEncoder(w).Encode(webhook.StateBackupRestoreResponse{
+				StateImportResponse: webhook.StateImportResponse{Status: "success", Mode: "replace", DryRun: true, Removed: 1},
+				Backup:              backup,
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(webhook.StateBackupListResponse{Status: "success", KeepCount: 48, Count: 1, Backups: []webhook.StateBackup{backup}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 30*time.Second).WithAdminToken("admin-secret")
+
+	list, err := client.ListStateBackups()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "GET" || lastPath != "/admin/backups" {
+		t.Errorf("Expected GET /admin/backups, got %s %s", lastMethod, lastPath)
+	}
+	if list.Count != 1 || list.Backups[0].Name != "state-20250121103000.json" {
+		t.Errorf("Expected one backup, got %+v", list)
+	}
+
+	restored, err := client.RestoreStateBackup("state-20250121103000.json", true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastMethod != "POST" || lastPath != "/admin/backups/state-20250121103000.json/restore" || lastQuery != "dry_run=true" {
+		t.Errorf("Expected dry run restore, got %s %s?%s", lastMethod, lastPath, lastQuery)
+	}
+	if !restored.DryRun || restored.Removed != 1 || restored.Backup.Subscriptions != 2 {
+		t.Errorf("Expected restore summary, got %+v", restored)
+	}
+}
+
+func TestClient_State_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(webhook.APIResponse{Status: "error", Message: "invalid subscriptions"})
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, 30*time.Second).ImportState(&webhook.SubscriptionState{}, "", false)
+	if err == nil || err.Error() != "server error (400): invalid subscriptions" {
+		t.Errorf("Expected server error, got %v", err)
+	}
+}
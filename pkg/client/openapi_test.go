@@ -0,0 +1,101 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	webhook "github.com/samsoir/youtube-webhook/function"
+	"gopkg.in/yaml.v3"
+)
+
+// specOperation reports whether the OpenAPI paths document method on a request path,
+// matching "{name}" segments against any single segment
+func specOperation(paths map[string]map[string]interface{}, method, path string) bool {
+	segments := strings.Split(path, "/")
+	for pattern, operations := range paths {
+		if _, ok := operations[strings.ToLower(method)]; !ok {
+			continue
+		}
+		patternSegments := strings.Split(pattern, "/")
+		if len(patternSegments) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, segment := range patternSegments {
+			if segment != segments[i] && !strings.HasPrefix(segment, "{") {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClient_MatchesOpenAPISpec(t *testing.T) {
+	var spec struct {
+		OpenAPI string                            `yaml:"openapi"`
+		Paths   map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(webhook.OpenAPISpec(), &spec); err != nil {
+		t.Fatalf("Expected a valid OpenAPI document, got %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("Expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	// Every request the client can make; responses are not checked here
+	c := NewClient(server.URL, 5*time.Second)
+	channelID := "UCXuqSBlHAE6Xw-yeJA0Tunw"
+	c.Subscribe(channelID)
+	c.SubscribeByHandle("@SomeCreator")
+	c.SubscribeWithOptions(SubscribeOptions{ChannelID: channelID, HubURL: "https://hub.example.com/"})
+	c.Unsubscribe(channelID)
+	c.RestoreSubscription(channelID)
+	c.ListSubscriptionsPage(ListOptions{Limit: 10})
+	c.ListExpiringSubscriptions(6*time.Hour, 10)
+	c.RenewChannels(webhook.RenewRequest{Force: true})
+	c.LatestRenewalReport()
+	c.Health()
+	c.Diagnostics()
+	c.GetFilter(channelID)
+	c.SetFilter(channelID, webhook.NotificationFilter{ExcludeShorts: true})
+	c.ClearFilter(channelID)
+	c.GetChannelConfig(channelID)
+	c.UpdateChannelConfig(channelID, webhook.ChannelConfigPatch{Filter: &webhook.NotificationFilter{}})
+	c.GetHistory(channelID, 5)
+	c.GetEvents(EventsOptions{ChannelID: channelID, Since: "2025-01-21T10:30:00Z", Limit: 10})
+	c.ListDeadLetters()
+	c.GetDeadLetter("20250121T103000Z-3f9a1c0b")
+	c.ReplayDeadLetter("20250121T103000Z-3f9a1c0b")
+	c.ExportState()
+	c.ImportState(&webhook.SubscriptionState{}, webhook.ImportModeMerge, true)
+	c.ListStateBackups()
+	c.RestoreStateBackup("state-20250121103000.json", true)
+
+	if len(requests) != 25 {
+		t.Fatalf("Expected 25 requests, got %d: %v", len(requests), requests)
+	}
+	for _, request := range requests {
+		method, path, _ := strings.Cut(request, " ")
+		if !specOperation(spec.Paths, method, path) {
+			t.Errorf("Client request %s is not documented in the OpenAPI document", request)
+		}
+	}
+}
@@ -6,6 +6,10 @@ locals {
   bucket_name = "gcs-${local.project_sanitized}-${var.function_name}-source"
   # Generate unique suffix for service account to avoid conflicts
   unique_suffix = substr(sha256("${var.project_id}-${var.function_name}-${var.environment}"), 0, 8)
+  # Audience of the ID tokens Cloud Scheduler sends to management endpoints
+  api_audience = local.function_name
+  # Management endpoints require credentials once API keys or a signing secret is configured
+  management_auth = length(var.api_keys) > 0 || var.request_signing_secret != ""
 
   common_labels = merge(var.labels, {
     environment = var.environment
@@ -33,6 +37,9 @@ resource "google_project_service" "required_apis" {
     "run.googleapis.com",
     "eventarc.googleapis.com",
     "storage.googleapis.com",
+    "firestore.googleapis.com",
+    "pubsub.googleapis.com",
+    "cloudtrace.googleapis.com",
     "iam.googleapis.com"
   ])
 
@@ -86,10 +93,10 @@ resource "google_storage_bucket" "subscription_state" {
 
   uniform_bucket_level_access = true
 
-  # Lifecycle management for backup files
+  # Backstop for subscription state backups the function did not prune
   lifecycle_rule {
     condition {
-      age            = 90
+      age            = var.state_backup_retention_days + 1
       matches_prefix = ["subscriptions/backups/"]
     }
     action {
@@ -97,6 +104,17 @@ resource "google_storage_bucket" "subscription_state" {
     }
   }
 
+  # Backstop for dead-lettered notifications the function did not prune
+  lifecycle_rule {
+    condition {
+      age            = var.dead_letter_retention_days + 1
+      matches_prefix = ["deadletter/notifications/"]
+    }
+    action {
+      type = "Delete"
+    }
+  }
+
   versioning {
     enabled = true
   }
@@ -155,6 +173,36 @@ resource "google_storage_bucket_iam_member" "function_sa_subscription_bucket" {
   depends_on = [google_storage_bucket.subscription_state]
 }
 
+# Grant function service account access to Firestore when it holds subscription state
+resource "google_project_iam_member" "function_sa_datastore" {
+  count   = var.storage_backend == "firestore" ? 1 : 0
+  project = var.project_id
+  role    = "roles/datastore.user"
+  member  = "serviceAccount:${google_service_account.function_sa.email}"
+
+  depends_on = [google_project_service.required_apis]
+}
+
+# Grant function service account permission to publish video events when a topic is configured
+resource "google_project_iam_member" "function_sa_pubsub_publisher" {
+  count   = var.events_pubsub_topic != "" ? 1 : 0
+  project = var.project_id
+  role    = "roles/pubsub.publisher"
+  member  = "serviceAccount:${google_service_account.function_sa.email}"
+
+  depends_on = [google_project_service.required_apis]
+}
+
+# Grant function service account permission to export spans when tracing is enabled
+resource "google_project_iam_member" "function_sa_trace_agent" {
+  count   = var.trace_sample_ratio > 0 ? 1 : 0
+  project = var.project_id
+  role    = "roles/cloudtrace.agent"
+  member  = "serviceAccount:${google_service_account.function_sa.email}"
+
+  depends_on = [google_project_service.required_apis]
+}
+
 # Cloud Function (Gen 2)
 resource "google_cloudfunctions2_function" "youtube_webhook" {
   name     = local.function_name
@@ -188,14 +236,75 @@ resource "google_cloudfunctions2_function" "youtube_webhook" {
     service_account_email = google_service_account.function_sa.email
 
     environment_variables = {
-      GITHUB_TOKEN               = var.github_token
-      REPO_OWNER                 = var.repo_owner
-      REPO_NAME                  = var.repo_name
-      ENVIRONMENT                = var.environment
-      SUBSCRIPTION_BUCKET        = google_storage_bucket.subscription_state.name
-      RENEWAL_THRESHOLD_HOURS    = tostring(var.renewal_threshold_hours)
-      MAX_RENEWAL_ATTEMPTS       = tostring(var.max_renewal_attempts)
-      SUBSCRIPTION_LEASE_SECONDS = tostring(var.subscription_lease_seconds)
+      GITHUB_TOKEN                   = var.github_token
+      REPO_OWNER                     = var.repo_owner
+      REPO_NAME                      = var.repo_name
+      ENVIRONMENT                    = var.environment
+      SUBSCRIPTION_BUCKET            = google_storage_bucket.subscription_state.name
+      STORAGE_BACKEND                = var.storage_backend
+      FIRESTORE_COLLECTION           = var.firestore_collection
+      RENEWAL_THRESHOLD_HOURS        = tostring(var.renewal_threshold_hours)
+      MAX_RENEWAL_ATTEMPTS           = tostring(var.max_renewal_attempts)
+      RECOVERY_MAX_ATTEMPTS          = tostring(var.recovery_max_attempts)
+      RENEWAL_BATCH_SIZE             = tostring(var.renewal_batch_size)
+      RENEWAL_JITTER_MS              = tostring(var.renewal_jitter_ms)
+      RENEWAL_CONCURRENCY            = tostring(var.renewal_concurrency)
+      EXPIRY_ALERT_HOURS             = tostring(var.expiry_alert_hours)
+      ALERT_WEBHOOK_URL              = var.alert_webhook_url
+      ALERT_WEBHOOK_SECRET           = var.alert_webhook_secret
+      ALERT_SLACK_WEBHOOK_URL        = var.alert_slack_webhook_url
+      ALERT_EMAIL_TO                 = join(",", var.alert_email_to)
+      ALERT_EMAIL_FROM               = var.alert_email_from
+      ALERT_SMTP_URL                 = var.alert_smtp_url
+      SUBSCRIPTION_LEASE_SECONDS     = tostring(var.subscription_lease_seconds)
+      SWEEP_GRACE_HOURS              = tostring(var.sweep_grace_hours)
+      SWEEP_RETENTION_DAYS           = tostring(var.sweep_retention_days)
+      REMOVED_RETENTION_DAYS         = tostring(var.removed_retention_days)
+      GITHUB_RUN_CORRELATION_SECONDS = tostring(var.github_run_correlation_seconds)
+      ADMIN_TOKEN                    = var.admin_token
+      DEBUG_NOTIFICATIONS_SIZE       = tostring(var.debug_notifications_size)
+      DEBUG_NOTIFICATIONS_PERSIST    = tostring(var.debug_notifications_persist)
+      REQUIRE_HUB_SIGNATURE          = tostring(var.require_hub_signature)
+      REQUIRE_SUBSCRIBED_TOPIC       = tostring(var.require_subscribed_topic)
+      NOTIFICATION_ALLOWED_IPS       = join(",", var.notification_allowed_ips)
+      NOTIFICATION_DENIED_IPS        = join(",", var.notification_denied_ips)
+      TRUST_FORWARDED_FOR            = tostring(var.trust_forwarded_for)
+      SINK_MAX_ATTEMPTS              = tostring(var.sink_max_attempts)
+      PROCESSED_VIDEO_TTL_HOURS      = tostring(var.processed_video_ttl_hours)
+      NOTIFICATION_MAX_BODY_BYTES    = tostring(var.notification_max_body_bytes)
+      NOTIFICATION_HISTORY_SIZE      = tostring(var.notification_history_size)
+      DEAD_LETTER_RETENTION_DAYS     = tostring(var.dead_letter_retention_days)
+      STATE_BACKUP_COUNT             = tostring(var.state_backup_count)
+      STATE_BACKUP_RETENTION_DAYS    = tostring(var.state_backup_retention_days)
+      AUDIT_RETENTION_DAYS           = tostring(var.audit_retention_days)
+      YOUTUBE_API_KEY                = var.youtube_api_key
+      DISCORD_WEBHOOK_URL            = var.discord_webhook_url
+      SLACK_WEBHOOK_URL              = var.slack_webhook_url
+      NEW_VIDEO_STRATEGY             = var.new_video_strategy
+      NEW_VIDEO_MAX_AGE_MINUTES      = tostring(var.new_video_max_age_minutes)
+      NEW_VIDEO_UPDATE_GAP_MINUTES   = tostring(var.new_video_update_gap_minutes)
+      NEW_VIDEO_SHADOW_STRATEGY      = var.new_video_shadow_strategy
+      PREMIERE_HANDLING              = var.premiere_handling
+      GITHUB_API_TIMEOUT_SECONDS     = tostring(var.github_api_timeout_seconds)
+      HUB_REQUEST_TIMEOUT_SECONDS    = tostring(var.hub_request_timeout_seconds)
+      HTTP_MAX_IDLE_CONNS            = tostring(var.http_max_idle_conns)
+      HTTP_MAX_IDLE_CONNS_PER_HOST   = tostring(var.http_max_idle_conns_per_host)
+      HTTP_IDLE_CONN_TIMEOUT_SECONDS = tostring(var.http_idle_conn_timeout_seconds)
+      STATE_CACHE_TTL_SECONDS        = tostring(var.state_cache_ttl_seconds)
+      EVENTS_PUBSUB_TOPIC            = var.events_pubsub_topic
+      TRACE_SAMPLE_RATIO             = tostring(var.trace_sample_ratio)
+      CALLBACK_PATH_TOKEN            = var.callback_path_token
+      NOTIFICATION_LOG_SAMPLE_RATIO  = tostring(var.notification_log_sample_ratio)
+      REQUEST_LOG_REDACT             = tostring(var.request_log_redact)
+      DISPATCH_RETRY_MAX_ATTEMPTS    = tostring(var.dispatch_retry_max_attempts)
+      OUTBOX_MAX_ATTEMPTS            = tostring(var.outbox_max_attempts)
+      FUNCTION_TIMEOUT_SECONDS       = tostring(var.function_timeout)
+      DEADLINE_RESERVE_MS            = tostring(var.deadline_reserve_ms)
+      GOOGLE_CLOUD_PROJECT           = var.project_id
+      API_KEYS                       = join(",", var.api_keys)
+      REQUEST_SIGNING_SECRET         = var.request_signing_secret
+      OIDC_AUDIENCE                  = local.management_auth ? local.api_audience : ""
+      OIDC_ALLOWED_EMAILS            = local.management_auth ? google_service_account.scheduler_sa.email : ""
     }
 
     # Security settings
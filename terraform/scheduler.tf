@@ -63,7 +63,164 @@ resource "google_cloud_scheduler_job" "subscription_renewal" {
 
     oidc_token {
       service_account_email = google_service_account.scheduler_sa.email
-      audience              = google_cloudfunctions2_function.youtube_webhook.url
+      audience              = local.api_audience
+    }
+  }
+
+  depends_on = [
+    google_project_service.scheduler_api,
+    google_cloudfunctions2_function.youtube_webhook,
+    google_cloud_run_service_iam_member.scheduler_invoker
+  ]
+}
+
+# Cloud Scheduler job for sweeping lapsed subscriptions
+resource "google_cloud_scheduler_job" "subscription_sweep" {
+  name        = "youtube-subscription-sweep-${var.environment}"
+  description = "Expire, re-subscribe or purge lapsed YouTube PubSubHubbub subscriptions"
+  schedule    = var.sweep_schedule
+  time_zone   = var.renewal_timezone
+  region      = var.region
+  project     = var.project_id
+
+  retry_config {
+    retry_count          = 3
+    max_retry_duration   = "300s"
+    min_backoff_duration = "30s"
+    max_backoff_duration = "300s"
+    max_doublings        = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloudfunctions2_function.youtube_webhook.url}/sweep"
+
+    headers = {
+      "Content-Type" = "application/json"
+      "User-Agent"   = "Google-Cloud-Scheduler/1.0"
+    }
+
+    oidc_token {
+      service_account_email = google_service_account.scheduler_sa.email
+      audience              = local.api_audience
+    }
+  }
+
+  depends_on = [
+    google_project_service.scheduler_api,
+    google_cloudfunctions2_function.youtube_webhook,
+    google_cloud_run_service_iam_member.scheduler_invoker
+  ]
+}
+
+# Cloud Scheduler job for retrying failed GitHub dispatches
+resource "google_cloud_scheduler_job" "dispatch_retry" {
+  name        = "youtube-dispatch-retry-${var.environment}"
+  description = "Retry failed GitHub dispatches from the retry queue"
+  schedule    = var.retry_schedule
+  time_zone   = var.renewal_timezone
+  region      = var.region
+  project     = var.project_id
+
+  retry_config {
+    retry_count          = 3
+    max_retry_duration   = "300s"
+    min_backoff_duration = "30s"
+    max_backoff_duration = "300s"
+    max_doublings        = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloudfunctions2_function.youtube_webhook.url}/retry"
+
+    headers = {
+      "Content-Type" = "application/json"
+      "User-Agent"   = "Google-Cloud-Scheduler/1.0"
+    }
+
+    oidc_token {
+      service_account_email = google_service_account.scheduler_sa.email
+      audience              = local.api_audience
+    }
+  }
+
+  depends_on = [
+    google_project_service.scheduler_api,
+    google_cloudfunctions2_function.youtube_webhook,
+    google_cloud_run_service_iam_member.scheduler_invoker
+  ]
+}
+
+# Cloud Scheduler job for delivering videos whose dispatch was abandoned mid-flight
+resource "google_cloud_scheduler_job" "outbox_reconcile" {
+  name        = "youtube-outbox-reconcile-${var.environment}"
+  description = "Deliver videos left in the dispatch outbox by an instance that died mid-dispatch"
+  schedule    = var.outbox_schedule
+  time_zone   = var.renewal_timezone
+  region      = var.region
+  project     = var.project_id
+
+  retry_config {
+    retry_count          = 3
+    max_retry_duration   = "300s"
+    min_backoff_duration = "30s"
+    max_backoff_duration = "300s"
+    max_doublings        = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloudfunctions2_function.youtube_webhook.url}/outbox"
+
+    headers = {
+      "Content-Type" = "application/json"
+      "User-Agent"   = "Google-Cloud-Scheduler/1.0"
+    }
+
+    oidc_token {
+      service_account_email = google_service_account.scheduler_sa.email
+      audience              = local.api_audience
+    }
+  }
+
+  depends_on = [
+    google_project_service.scheduler_api,
+    google_cloudfunctions2_function.youtube_webhook,
+    google_cloud_run_service_iam_member.scheduler_invoker
+  ]
+}
+
+# Cloud Scheduler job for dispatching held premieres once they go live
+resource "google_cloud_scheduler_job" "premiere_release" {
+  count       = var.premiere_handling == "delay" ? 1 : 0
+  name        = "youtube-premiere-release-${var.environment}"
+  description = "Dispatch held YouTube premieres that went live"
+  schedule    = var.premiere_schedule
+  time_zone   = var.renewal_timezone
+  region      = var.region
+  project     = var.project_id
+
+  retry_config {
+    retry_count          = 3
+    max_retry_duration   = "300s"
+    min_backoff_duration = "30s"
+    max_backoff_duration = "300s"
+    max_doublings        = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloudfunctions2_function.youtube_webhook.url}/premieres"
+
+    headers = {
+      "Content-Type" = "application/json"
+      "User-Agent"   = "Google-Cloud-Scheduler/1.0"
+    }
+
+    oidc_token {
+      service_account_email = google_service_account.scheduler_sa.email
+      audience              = local.api_audience
     }
   }
 
@@ -80,6 +237,21 @@ output "scheduler_job_name" {
   value       = google_cloud_scheduler_job.subscription_renewal.name
 }
 
+output "sweep_scheduler_job_name" {
+  description = "Name of the Cloud Scheduler job that sweeps lapsed subscriptions"
+  value       = google_cloud_scheduler_job.subscription_sweep.name
+}
+
+output "retry_scheduler_job_name" {
+  description = "Name of the Cloud Scheduler job that retries failed GitHub dispatches"
+  value       = google_cloud_scheduler_job.dispatch_retry.name
+}
+
+output "outbox_scheduler_job_name" {
+  description = "Name of the Cloud Scheduler job that reconciles the dispatch outbox"
+  value       = google_cloud_scheduler_job.outbox_reconcile.name
+}
+
 output "scheduler_service_account" {
   description = "Email of the scheduler service account"
   value       = google_service_account.scheduler_sa.email